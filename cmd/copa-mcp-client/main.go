@@ -3,10 +3,16 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
+	"runtime"
+	"strings"
+	"time"
 
 	// "path"
 
@@ -19,11 +25,65 @@ var (
 	client  *mcp.Client
 	session *mcp.ClientSession
 	ctx     context.Context
+
+	// dockerHost optionally overrides the Docker daemon endpoint used by the server for a call
+	dockerHost string
+
+	// jsonOutput prints the structured result of a tool instead of its content
+	jsonOutput bool
+
+	// resultPath asks the server to write the structured result of a scan or patch to a file
+	resultPath string
+
+	// retryMaxAttempts overrides the server's retry attempts for a scan or patch when non-zero
+	retryMaxAttempts int
+
+	// toolTimeout asks the server to stop the trivy or copa command of a scan or patch when it runs longer
+	toolTimeout time.Duration
+
+	// containerdNamespace asks the server to scan or patch the image in a containerd namespace
+	containerdNamespace string
+
+	// subprocessEnv overrides, as KEY=value, the subprocess environment variables configured on the server
+	subprocessEnv []string
 )
 
 func executeMCPTool(toolName string, args map[string]any) error {
 	fmt.Printf("\n=== Executing %s tool ===\n", toolName)
 
+	if dockerHost != "" && toolName != "version" && toolName != "list-fixed-vulnerabilities" && toolName != "list-vulnerabilities" && toolName != "summarize-report" && toolName != "list-cluster-images" && toolName != "install-dependencies" {
+		args["dockerHost"] = dockerHost
+	}
+
+	if containerdNamespace != "" && (toolName == "scan-container" || strings.HasPrefix(toolName, "patch-")) {
+		args["containerdNamespace"] = containerdNamespace
+	}
+
+	if toolTimeout > 0 && (toolName == "scan-container" || strings.HasPrefix(toolName, "patch-") || toolName == "remediate" || toolName == "scan-registry" || toolName == "generate-sbom") {
+		args["timeoutSeconds"] = int(math.Ceil(toolTimeout.Seconds()))
+	}
+
+	if len(subprocessEnv) > 0 && (toolName == "scan-container" || strings.HasPrefix(toolName, "patch-") || toolName == "remediate") {
+		env := map[string]string{}
+		for _, entry := range subprocessEnv {
+			name, value, found := strings.Cut(entry, "=")
+			if !found {
+				return fmt.Errorf("invalid --env %q: must be KEY=value", entry)
+			}
+			env[name] = value
+		}
+		args["env"] = env
+	}
+
+	if strings.HasPrefix(toolName, "scan-") || strings.HasPrefix(toolName, "patch-") || toolName == "fetch-harbor-report" || toolName == "suggest-base-upgrade" || toolName == "evaluate-image" || toolName == "generate-sbom" || toolName == "diff-sbom" || toolName == "verify-patch" || toolName == "open-image-pr" || toolName == "remediate" {
+		if resultPath != "" {
+			args["resultPath"] = resultPath
+		}
+		if retryMaxAttempts > 0 {
+			args["retry"] = map[string]any{"maxAttempts": retryMaxAttempts}
+		}
+	}
+
 	params := &mcp.CallToolParams{
 		Name:      toolName,
 		Arguments: args,
@@ -35,16 +95,44 @@ func executeMCPTool(toolName string, args map[string]any) error {
 	}
 
 	if res.IsError {
+		category := "unknown"
+		if structured, ok := res.StructuredContent.(map[string]any); ok {
+			if c, ok := structured["category"].(string); ok {
+				category = c
+			}
+			if command, ok := structured["command"].(map[string]any); ok {
+				fmt.Fprintf(os.Stderr, "Failed command: %v (exit code %v, after %v)\n", command["command"], command["exitCode"], command["duration"])
+				if tail, ok := command["stderrTail"].(string); ok && tail != "" {
+					fmt.Fprintf(os.Stderr, "stderr:\n%s\n", tail)
+				}
+			}
+		}
 		for _, c := range res.Content {
 			if text, ok := c.(*mcp.TextContent); ok {
-				return fmt.Errorf("%s tool failed: %s", toolName, text.Text)
+				return fmt.Errorf("%s tool failed [%s error]: %s", toolName, category, text.Text)
 			}
 		}
 		return fmt.Errorf("%s tool failed with unknown error", toolName)
 	}
 
+	if jsonOutput && res.StructuredContent != nil {
+		out, err := json.MarshalIndent(res.StructuredContent, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format structured result: %v", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
 	for _, c := range res.Content {
-		fmt.Printf("Result: %s\n", c.(*mcp.TextContent).Text)
+		switch content := c.(type) {
+		case *mcp.TextContent:
+			fmt.Printf("Result: %s\n", content.Text)
+		case *mcp.ResourceLink:
+			fmt.Printf("Resource: %s (%s)\n", content.URI, content.Name)
+		case *mcp.EmbeddedResource:
+			fmt.Printf("Embedded resource %s:\n%s\n", content.Resource.URI, content.Resource.Text)
+		}
 	}
 	return nil
 }
@@ -62,11 +150,15 @@ func initMCPClient() error {
 	)
 
 	// Try to find the server binary in common locations
+	serverName := "copacetic-mcp-server"
+	if runtime.GOOS == "windows" {
+		serverName += ".exe"
+	}
 	serverPaths := []string{
-		"./bin/copacetic-mcp-server",
-		"../bin/copacetic-mcp-server",
-		"./copacetic-mcp-server",
-		"copacetic-mcp-server",
+		"./bin/" + serverName,
+		"../bin/" + serverName,
+		"./" + serverName,
+		serverName,
 	}
 
 	var serverPath string
@@ -91,7 +183,12 @@ func initMCPClient() error {
 	go func() {
 		scanner := bufio.NewScanner(stderrPipe)
 		for scanner.Scan() {
-			log.Printf("[server stderr] %s", scanner.Text())
+			// Pass Azure Pipelines logging commands through unprefixed, so the agent picks them up
+			if line := scanner.Text(); strings.HasPrefix(line, "##vso[") {
+				fmt.Println(line)
+			} else {
+				log.Printf("[server stderr] %s", line)
+			}
 		}
 		if err := scanner.Err(); err != nil {
 			log.Printf("Error reading server stderr: %v", err)
@@ -131,6 +228,14 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&resultPath, "result-path", "", "Have the server write the structured scan or patch result to this JSON file, in its working directory or relative to it")
+	rootCmd.PersistentFlags().IntVar(&retryMaxAttempts, "retry-max-attempts", 0, "Override the server's retry attempts for a scan or patch (1 disables retries)")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print the structured result of the tool as JSON")
+	rootCmd.PersistentFlags().StringVar(&dockerHost, "docker-host", "", "Docker daemon endpoint used by the server for this call (e.g. tcp://build-host:2376)")
+	rootCmd.PersistentFlags().StringArrayVar(&subprocessEnv, "env", nil, "Override a subprocess environment variable configured on the server for a scan or patch, as KEY=value; repeat for several")
+	rootCmd.PersistentFlags().DurationVar(&toolTimeout, "timeout", 0, "Stop the trivy or copa command of a scan or patch when it runs longer (e.g. 20m); defaults to the server setting")
+	rootCmd.PersistentFlags().StringVar(&containerdNamespace, "containerd-namespace", "", "Scan or patch the image in this containerd namespace of the server's node (e.g. k8s.io)")
+
 	// Version command
 	var versionCmd = &cobra.Command{
 		Use:   "version",
@@ -145,8 +250,14 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 
 	// Scan command
 	var (
-		scanImage     string
-		scanPlatforms []string
+		scanImage         string
+		scanPlatforms     []string
+		scanGitLabReport  string
+		scanReuseAttached bool
+		scanSeverity      []string
+		scanSkipDBUpdate  bool
+		scanOfflineScan   bool
+		scanDBRepository  string
 	)
 	var scanCmd = &cobra.Command{
 		Use:   "scan-container",
@@ -159,6 +270,24 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 			if len(scanPlatforms) > 0 {
 				mcpArgs["platform"] = scanPlatforms
 			}
+			if scanGitLabReport != "" {
+				mcpArgs["gitlabReport"] = scanGitLabReport
+			}
+			if scanReuseAttached {
+				mcpArgs["reuseAttachedReport"] = true
+			}
+			if len(scanSeverity) > 0 {
+				mcpArgs["severity"] = scanSeverity
+			}
+			if cmd.Flags().Changed("skip-db-update") {
+				mcpArgs["skipDbUpdate"] = scanSkipDBUpdate
+			}
+			if cmd.Flags().Changed("offline-scan") {
+				mcpArgs["offlineScan"] = scanOfflineScan
+			}
+			if scanDBRepository != "" {
+				mcpArgs["dbRepository"] = scanDBRepository
+			}
 			if err := executeMCPTool("scan-container", mcpArgs); err != nil {
 				log.Fatalf("Error executing scan-container command: %v", err)
 			}
@@ -166,13 +295,570 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 	}
 	scanCmd.Flags().StringVarP(&scanImage, "image", "i", "", "Container image to scan (required)")
 	scanCmd.Flags().StringSliceVarP(&scanPlatforms, "platform", "p", []string{}, "Target platform(s) for scanning (e.g., linux/amd64,linux/arm64)")
+	scanCmd.Flags().StringVar(&scanGitLabReport, "gitlab-report", "", "Also have the server write the findings to this path, in its working directory or relative to it, as a GitLab container scanning report")
+	scanCmd.Flags().BoolVar(&scanReuseAttached, "reuse-attached-report", false, "Reuse a scan report attached to the image in its registry instead of scanning")
+	scanCmd.Flags().StringSliceVar(&scanSeverity, "severity", nil, "Only report vulnerabilities of these severities, e.g. CRITICAL,HIGH")
+	scanCmd.Flags().BoolVar(&scanSkipDBUpdate, "skip-db-update", false, "Scan with trivy's cached vulnerability database instead of updating it (default: server setting)")
+	scanCmd.Flags().BoolVar(&scanOfflineScan, "offline-scan", false, "Make trivy scan without network requests besides the database download (default: server setting)")
+	scanCmd.Flags().StringVar(&scanDBRepository, "db-repository", "", "OCI repository to download trivy's vulnerability database from (default: server setting)")
 	scanCmd.MarkFlagRequired("image")
 
+	// Pull command
+	var (
+		pullImage    string
+		pullPlatform string
+	)
+	var pullCmd = &cobra.Command{
+		Use:   "pull-image",
+		Short: "Pull a container image",
+		Long:  "Pull a container image into the local Docker daemon",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image": pullImage,
+			}
+			if pullPlatform != "" {
+				mcpArgs["platform"] = pullPlatform
+			}
+			if err := executeMCPTool("pull-image", mcpArgs); err != nil {
+				log.Fatalf("Error executing pull-image command: %v", err)
+			}
+		},
+	}
+	pullCmd.Flags().StringVarP(&pullImage, "image", "i", "", "Container image to pull (required)")
+	pullCmd.Flags().StringVarP(&pullPlatform, "platform", "p", "", "Platform to pull (e.g., linux/arm64)")
+	pullCmd.MarkFlagRequired("image")
+
+	// List fixed vulnerabilities command
+	var (
+		fixedVexPath string
+		fixedOffset  int
+		fixedLimit   int
+	)
+	var listFixedCmd = &cobra.Command{
+		Use:   "list-fixed-vulnerabilities",
+		Short: "List the vulnerabilities fixed by a patch",
+		Long:  "Page through the vulnerabilities fixed according to the OpenVEX document of a report-based patch",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"vexPath": fixedVexPath,
+				"offset":  fixedOffset,
+			}
+			if fixedLimit > 0 {
+				mcpArgs["limit"] = fixedLimit
+			}
+			if err := executeMCPTool("list-fixed-vulnerabilities", mcpArgs); err != nil {
+				log.Fatalf("Error executing list-fixed-vulnerabilities command: %v", err)
+			}
+		},
+	}
+	listFixedCmd.Flags().StringVarP(&fixedVexPath, "vex-path", "", "", "Path to the OpenVEX document returned by patch-vulnerabilities (required)")
+	listFixedCmd.Flags().IntVarP(&fixedOffset, "offset", "", 0, "Number of vulnerabilities to skip")
+	listFixedCmd.Flags().IntVarP(&fixedLimit, "limit", "", 0, "Maximum number of vulnerabilities to list (default 100)")
+	listFixedCmd.MarkFlagRequired("vex-path")
+
+	// List vulnerabilities command
+	var (
+		vulnsReportPath  string
+		vulnsSeverity    []string
+		vulnsFixableOnly bool
+		vulnsPackage     string
+		vulnsSortBy      string
+		vulnsOffset      int
+		vulnsLimit       int
+	)
+	var listVulnsCmd = &cobra.Command{
+		Use:   "list-vulnerabilities",
+		Short: "List the vulnerabilities of a scan report",
+		Long:  "Page through the vulnerabilities of a report from scan-container, filtered by severity, fixability or package and sorted",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"reportPath":  vulnsReportPath,
+				"fixableOnly": vulnsFixableOnly,
+				"package":     vulnsPackage,
+				"sortBy":      vulnsSortBy,
+				"offset":      vulnsOffset,
+			}
+			if len(vulnsSeverity) > 0 {
+				mcpArgs["severity"] = vulnsSeverity
+			}
+			if vulnsLimit > 0 {
+				mcpArgs["limit"] = vulnsLimit
+			}
+			if err := executeMCPTool("list-vulnerabilities", mcpArgs); err != nil {
+				log.Fatalf("Error executing list-vulnerabilities command: %v", err)
+			}
+		},
+	}
+	listVulnsCmd.Flags().StringVarP(&vulnsReportPath, "report-path", "", "", "Report directory returned by scan-container (required)")
+	listVulnsCmd.Flags().StringSliceVarP(&vulnsSeverity, "severity", "", nil, "Only list vulnerabilities of these severities (e.g., CRITICAL,HIGH)")
+	listVulnsCmd.Flags().BoolVarP(&vulnsFixableOnly, "fixable-only", "", false, "Only list vulnerabilities with a fixed version")
+	listVulnsCmd.Flags().StringVarP(&vulnsPackage, "package", "", "", "Only list vulnerabilities of packages whose name contains this")
+	listVulnsCmd.Flags().StringVarP(&vulnsSortBy, "sort-by", "", "severity", "Sort by severity, id or package")
+	listVulnsCmd.Flags().IntVarP(&vulnsOffset, "offset", "", 0, "Number of vulnerabilities to skip")
+	listVulnsCmd.Flags().IntVarP(&vulnsLimit, "limit", "", 0, "Maximum number of vulnerabilities to list (default 100)")
+	listVulnsCmd.MarkFlagRequired("report-path")
+
+	// Summarize report command
+	var (
+		summaryReportPath  string
+		summaryTopPackages int
+	)
+	var summarizeReportCmd = &cobra.Command{
+		Use:   "summarize-report",
+		Short: "Summarize the vulnerabilities of a scan report",
+		Long:  "Count the vulnerabilities of a report from scan-container by severity, fixability, package and platform",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{"reportPath": summaryReportPath}
+			if summaryTopPackages > 0 {
+				mcpArgs["topPackages"] = summaryTopPackages
+			}
+			if err := executeMCPTool("summarize-report", mcpArgs); err != nil {
+				log.Fatalf("Error executing summarize-report command: %v", err)
+			}
+		},
+	}
+	summarizeReportCmd.Flags().StringVarP(&summaryReportPath, "report-path", "", "", "Report directory returned by scan-container (required)")
+	summarizeReportCmd.Flags().IntVarP(&summaryTopPackages, "top-packages", "", 0, "Number of most vulnerable packages to list (default 10)")
+	summarizeReportCmd.MarkFlagRequired("report-path")
+
+	// List cluster images command
+	var (
+		clusterNamespace     string
+		clusterLabelSelector string
+		clusterKubeconfig    string
+		clusterContext       string
+	)
+	var listClusterImagesCmd = &cobra.Command{
+		Use:   "list-cluster-images",
+		Short: "List images running in a Kubernetes cluster",
+		Long:  "List the container images running in a Kubernetes cluster or namespace, with their pod counts",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{}
+			if clusterNamespace != "" {
+				mcpArgs["namespace"] = clusterNamespace
+			}
+			if clusterLabelSelector != "" {
+				mcpArgs["labelSelector"] = clusterLabelSelector
+			}
+			if clusterKubeconfig != "" {
+				mcpArgs["kubeconfig"] = clusterKubeconfig
+			}
+			if clusterContext != "" {
+				mcpArgs["context"] = clusterContext
+			}
+			if err := executeMCPTool("list-cluster-images", mcpArgs); err != nil {
+				log.Fatalf("Error executing list-cluster-images command: %v", err)
+			}
+		},
+	}
+	listClusterImagesCmd.Flags().StringVarP(&clusterNamespace, "namespace", "n", "", "Namespace to list (default all namespaces)")
+	listClusterImagesCmd.Flags().StringVarP(&clusterLabelSelector, "selector", "l", "", "Label selector to filter pods")
+	listClusterImagesCmd.Flags().StringVar(&clusterKubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	listClusterImagesCmd.Flags().StringVar(&clusterContext, "context", "", "Kubeconfig context to use")
+
+	// Scan registry command
+	var (
+		registryHost            string
+		registryRepositories    []string
+		registryTag             string
+		registryMaxRepositories int
+	)
+	var scanRegistryCmd = &cobra.Command{
+		Use:   "scan-registry",
+		Short: "Scan every repository of a registry",
+		Long:  "Scan one tag of each repository in a registry's catalog, or of a list of repositories, and print an aggregated fleet vulnerability report",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{}
+			if registryHost != "" {
+				mcpArgs["registry"] = registryHost
+			}
+			if len(registryRepositories) > 0 {
+				mcpArgs["repositories"] = registryRepositories
+			}
+			if registryTag != "" {
+				mcpArgs["tag"] = registryTag
+			}
+			if registryMaxRepositories > 0 {
+				mcpArgs["maxRepositories"] = registryMaxRepositories
+			}
+			if err := executeMCPTool("scan-registry", mcpArgs); err != nil {
+				log.Fatalf("Error executing scan-registry command: %v", err)
+			}
+		},
+	}
+	scanRegistryCmd.Flags().StringVarP(&registryHost, "registry", "r", "", "Registry whose catalog is scanned (e.g. registry.example.com, http://localhost:5000)")
+	scanRegistryCmd.Flags().StringSliceVar(&registryRepositories, "repository", []string{}, "Repositories to scan instead of the catalog")
+	scanRegistryCmd.Flags().StringVarP(&registryTag, "tag", "t", "", "Tag to scan in each repository (default latest)")
+	scanRegistryCmd.Flags().IntVar(&registryMaxRepositories, "max-repositories", 0, "Maximum number of repositories to scan (default 50)")
+
+	// Fetch Harbor report command
+	var (
+		harborImage string
+		harborURL   string
+	)
+	var fetchHarborReportCmd = &cobra.Command{
+		Use:   "fetch-harbor-report",
+		Short: "Fetch an image's vulnerability report from Harbor",
+		Long:  "Fetch the vulnerability report Harbor already produced for an image and convert it into a report directory for patch-vulnerabilities",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image": harborImage,
+			}
+			if harborURL != "" {
+				mcpArgs["harborUrl"] = harborURL
+			}
+			if err := executeMCPTool("fetch-harbor-report", mcpArgs); err != nil {
+				log.Fatalf("Error executing fetch-harbor-report command: %v", err)
+			}
+		},
+	}
+	fetchHarborReportCmd.Flags().StringVarP(&harborImage, "image", "i", "", "Harbor image reference (e.g. harbor.example.com/library/nginx:1.25)")
+	fetchHarborReportCmd.Flags().StringVar(&harborURL, "harbor-url", "", "Harbor base URL (default https:// and the image's registry host)")
+	fetchHarborReportCmd.MarkFlagRequired("image")
+
+	// Suggest base upgrade command
+	var (
+		baseImage      string
+		baseDockerfile string
+		baseWrite      bool
+		baseCompare    bool
+	)
+	var suggestBaseUpgradeCmd = &cobra.Command{
+		Use:   "suggest-base-upgrade",
+		Short: "Suggest newer tags for a base image or a Dockerfile's base images",
+		Long:  "Suggest newer tags of a base image, or of the base images in a Dockerfile's FROM instructions, and optionally bump the FROM instructions",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"write":   baseWrite,
+				"compare": baseCompare,
+			}
+			if baseImage != "" {
+				mcpArgs["image"] = baseImage
+			}
+			if baseDockerfile != "" {
+				mcpArgs["dockerfile"] = baseDockerfile
+			}
+			if err := executeMCPTool("suggest-base-upgrade", mcpArgs); err != nil {
+				log.Fatalf("Error executing suggest-base-upgrade command: %v", err)
+			}
+		},
+	}
+	suggestBaseUpgradeCmd.Flags().StringVarP(&baseImage, "image", "i", "", "Base image reference to check (e.g. node:18.17-alpine)")
+	suggestBaseUpgradeCmd.Flags().StringVarP(&baseDockerfile, "dockerfile", "f", "", "Dockerfile whose FROM instructions are checked")
+	suggestBaseUpgradeCmd.Flags().BoolVar(&baseWrite, "write", false, "Rewrite the Dockerfile's FROM instructions with the suggested tags")
+	suggestBaseUpgradeCmd.Flags().BoolVar(&baseCompare, "compare", false, "Scan the current and suggested base images and compare their fixable vulnerabilities")
+	suggestBaseUpgradeCmd.MarkFlagsOneRequired("image", "dockerfile")
+	suggestBaseUpgradeCmd.MarkFlagsMutuallyExclusive("image", "dockerfile")
+
+	// Evaluate image command
+	var (
+		evaluateImage             string
+		evaluateReportPath        string
+		evaluateAllowedRegistries []string
+		evaluateRequireSignature  bool
+		evaluateMaxCritical       int
+	)
+	var evaluateImageCmd = &cobra.Command{
+		Use:   "evaluate-image",
+		Short: "Evaluate an image against the admission policies",
+		Long:  "Evaluate an image against the allowed registries, required signature, maximum critical vulnerabilities and Rego policies, and print an allow/deny verdict",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image": evaluateImage,
+			}
+			if evaluateReportPath != "" {
+				mcpArgs["reportPath"] = evaluateReportPath
+			}
+			if len(evaluateAllowedRegistries) > 0 {
+				mcpArgs["allowedRegistries"] = evaluateAllowedRegistries
+			}
+			if cmd.Flags().Changed("require-signature") {
+				mcpArgs["requireSignature"] = evaluateRequireSignature
+			}
+			if cmd.Flags().Changed("max-critical") {
+				mcpArgs["maxCritical"] = evaluateMaxCritical
+			}
+			if err := executeMCPTool("evaluate-image", mcpArgs); err != nil {
+				log.Fatalf("Error executing evaluate-image command: %v", err)
+			}
+		},
+	}
+	evaluateImageCmd.Flags().StringVarP(&evaluateImage, "image", "i", "", "Image reference to evaluate")
+	evaluateImageCmd.Flags().StringVarP(&evaluateReportPath, "report-path", "r", "", "Report directory of a previous scan, instead of scanning the image")
+	evaluateImageCmd.Flags().StringSliceVar(&evaluateAllowedRegistries, "allowed-registry", nil, "Allowed registry host or repository prefix (repeatable; default: the server's)")
+	evaluateImageCmd.Flags().BoolVar(&evaluateRequireSignature, "require-signature", false, "Require a cosign or Notation signature (default: the server's)")
+	evaluateImageCmd.Flags().IntVar(&evaluateMaxCritical, "max-critical", -1, "Maximum fixable CRITICAL vulnerabilities, -1 for no limit (default: the server's)")
+	evaluateImageCmd.MarkFlagRequired("image")
+
+	// Generate SBOM command
+	var (
+		sbomImage  string
+		sbomFormat string
+	)
+	var generateSBOMCmd = &cobra.Command{
+		Use:   "generate-sbom",
+		Short: "Generate an SBOM of an image",
+		Long:  "Generate a CycloneDX or SPDX JSON SBOM of an image with Trivy",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image": sbomImage,
+			}
+			if sbomFormat != "" {
+				mcpArgs["format"] = sbomFormat
+			}
+			if err := executeMCPTool("generate-sbom", mcpArgs); err != nil {
+				log.Fatalf("Error executing generate-sbom command: %v", err)
+			}
+		},
+	}
+	generateSBOMCmd.Flags().StringVarP(&sbomImage, "image", "i", "", "Image reference")
+	generateSBOMCmd.Flags().StringVarP(&sbomFormat, "format", "f", "", "SBOM format: cyclonedx or spdx (default: cyclonedx)")
+	generateSBOMCmd.MarkFlagRequired("image")
+
+	// Diff SBOM command
+	var (
+		diffImage        string
+		diffPatchedImage string
+		diffTag          string
+		diffSBOMDir      string
+	)
+	var diffSBOMCmd = &cobra.Command{
+		Use:   "diff-sbom",
+		Short: "Diff the packages of an image and its patched image",
+		Long:  "Generate SBOMs of an image and its patched image and list the packages the patch upgraded, added or removed",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image": diffImage,
+			}
+			if diffPatchedImage != "" {
+				mcpArgs["patchedImage"] = diffPatchedImage
+			}
+			if diffTag != "" {
+				mcpArgs["patchtag"] = diffTag
+			}
+			if diffSBOMDir != "" {
+				mcpArgs["sbomDir"] = diffSBOMDir
+			}
+			if err := executeMCPTool("diff-sbom", mcpArgs); err != nil {
+				log.Fatalf("Error executing diff-sbom command: %v", err)
+			}
+		},
+	}
+	diffSBOMCmd.Flags().StringVarP(&diffImage, "image", "i", "", "Original image reference")
+	diffSBOMCmd.Flags().StringVarP(&diffPatchedImage, "patched-image", "p", "", "Patched image reference (default: the image copa tags with --tag)")
+	diffSBOMCmd.Flags().StringVarP(&diffTag, "tag", "t", "", "Tag of the patched image, when --patched-image is not set")
+	diffSBOMCmd.Flags().StringVar(&diffSBOMDir, "sbom-dir", "", "Directory to keep both CycloneDX SBOMs in")
+	diffSBOMCmd.MarkFlagRequired("image")
+	diffSBOMCmd.MarkFlagsMutuallyExclusive("patched-image", "tag")
+
+	// Verify patch command
+	var (
+		verifyImage        string
+		verifyPatchedImage string
+		verifyTag          string
+		verifyReportPath   string
+		verifySeverity     []string
+	)
+	var verifyPatchCmd = &cobra.Command{
+		Use:   "verify-patch",
+		Short: "Rescan a patched image and compare it with the scan of its original image",
+		Long:  "Rescan a patched image and list the vulnerabilities of the original image's scan report it fixed, those remaining and any it introduced",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"image":      verifyImage,
+				"reportPath": verifyReportPath,
+			}
+			if verifyPatchedImage != "" {
+				mcpArgs["patchedImage"] = verifyPatchedImage
+			}
+			if verifyTag != "" {
+				mcpArgs["patchtag"] = verifyTag
+			}
+			if len(verifySeverity) > 0 {
+				mcpArgs["severity"] = verifySeverity
+			}
+			if err := executeMCPTool("verify-patch", mcpArgs); err != nil {
+				log.Fatalf("Error executing verify-patch command: %v", err)
+			}
+		},
+	}
+	verifyPatchCmd.Flags().StringVarP(&verifyImage, "image", "i", "", "Original image reference")
+	verifyPatchCmd.Flags().StringVarP(&verifyPatchedImage, "patched-image", "p", "", "Patched image reference (default: the image copa tags with --tag)")
+	verifyPatchCmd.Flags().StringVarP(&verifyTag, "tag", "t", "", "Tag of the patched image, when --patched-image is not set")
+	verifyPatchCmd.Flags().StringVarP(&verifyReportPath, "report-path", "r", "", "Report directory of the scan of the original image")
+	verifyPatchCmd.Flags().StringSliceVar(&verifySeverity, "severity", nil, "Only rescan for vulnerabilities of these severities, e.g. CRITICAL,HIGH")
+	verifyPatchCmd.MarkFlagRequired("image")
+	verifyPatchCmd.MarkFlagRequired("report-path")
+	verifyPatchCmd.MarkFlagsMutuallyExclusive("patched-image", "tag")
+
+	// Patch history command
+	var (
+		historyImage string
+		historySince string
+		historyLimit int
+	)
+	var patchHistoryCmd = &cobra.Command{
+		Use:   "patch-history",
+		Short: "List the patches the server completed",
+		Long:  "List the patches recorded by the server, newest first, optionally for one image or repository",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{}
+			if historyImage != "" {
+				mcpArgs["image"] = historyImage
+			}
+			if historySince != "" {
+				mcpArgs["since"] = historySince
+			}
+			if historyLimit > 0 {
+				mcpArgs["limit"] = historyLimit
+			}
+			if err := executeMCPTool("patch-history", mcpArgs); err != nil {
+				log.Fatalf("Error executing patch-history command: %v", err)
+			}
+		},
+	}
+	patchHistoryCmd.Flags().StringVarP(&historyImage, "image", "i", "", "Only list the patches of this image, or of every tag of this repository")
+	patchHistoryCmd.Flags().StringVar(&historySince, "since", "", "Only list the patches recorded within this duration, e.g. 24h")
+	patchHistoryCmd.Flags().IntVar(&historyLimit, "limit", 0, "Maximum number of patches to list (default 20)")
+
+	// Doctor command
+	var (
+		doctorRegistries []string
+		doctorDockerHost string
+	)
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check whether the server is ready to scan and patch images",
+		Long:  "Check the server's Docker daemon, buildkit, copa and trivy binaries, registry connectivity and disk space",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{}
+			if len(doctorRegistries) > 0 {
+				mcpArgs["registries"] = doctorRegistries
+			}
+			if doctorDockerHost != "" {
+				mcpArgs["dockerHost"] = doctorDockerHost
+			}
+			if err := executeMCPTool("doctor", mcpArgs); err != nil {
+				log.Fatalf("Error executing doctor command: %v", err)
+			}
+		},
+	}
+	doctorCmd.Flags().StringSliceVarP(&doctorRegistries, "registry", "r", nil, "Registry host to check connectivity to; repeat for several (default docker.io)")
+	doctorCmd.Flags().StringVar(&doctorDockerHost, "docker-host", "", "Docker daemon endpoint to check (default the server's DOCKER_HOST)")
+
+	// Open image PR command
+	var (
+		prRepository   string
+		prProvider     string
+		prImage        string
+		prPatchedImage string
+		prFiles        []string
+		prBaseBranch   string
+		prBranch       string
+		prTitle        string
+		prBody         string
+		prDryRun       bool
+	)
+	var openImagePRCmd = &cobra.Command{
+		Use:   "open-image-pr",
+		Short: "Open a pull request bumping an image's references to its patched image",
+		Long:  "Rewrite the references to an image in a GitHub or GitLab repository to its pushed patched image, pinned by digest, and open a pull request",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"repository":   prRepository,
+				"image":        prImage,
+				"patchedImage": prPatchedImage,
+				"dryRun":       prDryRun,
+			}
+			for key, value := range map[string]string{"provider": prProvider, "baseBranch": prBaseBranch, "branch": prBranch, "title": prTitle, "body": prBody} {
+				if value != "" {
+					mcpArgs[key] = value
+				}
+			}
+			if len(prFiles) > 0 {
+				mcpArgs["files"] = prFiles
+			}
+			if err := executeMCPTool("open-image-pr", mcpArgs); err != nil {
+				log.Fatalf("Error executing open-image-pr command: %v", err)
+			}
+		},
+	}
+	openImagePRCmd.Flags().StringVar(&prRepository, "repository", "", "HTTPS URL of the GitHub or GitLab repository")
+	openImagePRCmd.Flags().StringVar(&prProvider, "provider", "", "github or gitlab, for hosts the provider cannot be detected from")
+	openImagePRCmd.Flags().StringVarP(&prImage, "image", "i", "", "Original image reference used in the repository")
+	openImagePRCmd.Flags().StringVarP(&prPatchedImage, "patched-image", "p", "", "Pushed patched image reference")
+	openImagePRCmd.Flags().StringSliceVar(&prFiles, "files", nil, "Glob of the files to search, ** matching any directories (repeatable)")
+	openImagePRCmd.Flags().StringVar(&prBaseBranch, "base-branch", "", "Branch to open the pull request against (default: the repository's default branch)")
+	openImagePRCmd.Flags().StringVar(&prBranch, "branch", "", "Branch to push the change to")
+	openImagePRCmd.Flags().StringVar(&prTitle, "title", "", "Pull request title")
+	openImagePRCmd.Flags().StringVar(&prBody, "body", "", "Pull request body")
+	openImagePRCmd.Flags().BoolVar(&prDryRun, "dry-run", false, "List the references that would be rewritten without pushing")
+	openImagePRCmd.MarkFlagRequired("repository")
+	openImagePRCmd.MarkFlagRequired("image")
+	openImagePRCmd.MarkFlagRequired("patched-image")
+
+	// Remove image command
+	var (
+		removeImages        []string
+		removeForce         bool
+		removePruneDangling bool
+	)
+	var removeImageCmd = &cobra.Command{
+		Use:   "remove-image",
+		Short: "Remove local container images",
+		Long:  "Remove local container images and/or prune dangling images left behind by patching",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{
+				"images":        removeImages,
+				"force":         removeForce,
+				"pruneDangling": removePruneDangling,
+			}
+			if err := executeMCPTool("remove-image", mcpArgs); err != nil {
+				log.Fatalf("Error executing remove-image command: %v", err)
+			}
+		},
+	}
+	removeImageCmd.Flags().StringSliceVarP(&removeImages, "image", "i", []string{}, "Container image(s) to remove")
+	removeImageCmd.Flags().BoolVarP(&removeForce, "force", "f", false, "Force removal of the images")
+	removeImageCmd.Flags().BoolVarP(&removePruneDangling, "prune", "", false, "Also prune dangling images")
+
+	// Registry login command
+	var (
+		loginRegistry string
+		loginUsername string
+	)
+	var registryLoginCmd = &cobra.Command{
+		Use:   "registry-login",
+		Short: "Log the server in to a container registry",
+		Long:  "Log the server in to a container registry with a password or access token read from stdin, for the following pulls, scans and pushes",
+		Run: func(cmd *cobra.Command, args []string) {
+			password, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				log.Fatalf("Error reading the password from stdin: %v", err)
+			}
+			mcpArgs := map[string]any{
+				"registry": loginRegistry,
+				"username": loginUsername,
+				"password": strings.TrimRight(string(password), "\r\n"),
+			}
+			if err := executeMCPTool("registry-login", mcpArgs); err != nil {
+				log.Fatalf("Error executing registry-login command: %v", err)
+			}
+		},
+	}
+	registryLoginCmd.Flags().StringVarP(&loginRegistry, "registry", "r", "", "Registry host to log in to (default Docker Hub)")
+	registryLoginCmd.Flags().StringVarP(&loginUsername, "username", "u", "", "User name (default _token, for registries that accept a token without a user)")
+
 	// Patch Comprehensive command
 	var (
-		comprehensiveImage    string
-		comprehensivePatchTag string
-		comprehensivePush     bool
+		comprehensiveImage       string
+		comprehensivePatchTag    string
+		comprehensiveExportPath  string
+		comprehensivePush        bool
+		comprehensiveDestination string
+		comprehensiveDryRun      bool
+		comprehensiveGitOps      string
+		comprehensiveSmokeTest   string
 	)
 	var patchComprehensiveCmd = &cobra.Command{
 		Use:   "patch-comprehensive",
@@ -184,6 +870,21 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 				"patchtag": comprehensivePatchTag,
 				"push":     comprehensivePush,
 			}
+			if comprehensiveDryRun {
+				mcpArgs["dryRun"] = true
+			}
+			if comprehensiveDestination != "" {
+				mcpArgs["destination"] = comprehensiveDestination
+			}
+			if comprehensiveExportPath != "" {
+				mcpArgs["exportPath"] = comprehensiveExportPath
+			}
+			if comprehensiveGitOps != "" {
+				mcpArgs["gitops"] = comprehensiveGitOps
+			}
+			if comprehensiveSmokeTest != "" {
+				mcpArgs["smokeTest"] = strings.Fields(comprehensiveSmokeTest)
+			}
 			if err := executeMCPTool("patch-comprehensive", mcpArgs); err != nil {
 				log.Fatalf("Error executing patch-comprehensive command: %v", err)
 			}
@@ -191,16 +892,26 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 	}
 	patchComprehensiveCmd.Flags().StringVarP(&comprehensiveImage, "image", "i", "", "Container image to patch (required)")
 	patchComprehensiveCmd.Flags().StringVarP(&comprehensivePatchTag, "patchtag", "t", "", "Tag for the patched image")
+	patchComprehensiveCmd.Flags().StringVarP(&comprehensiveExportPath, "export-path", "o", "", "Save the patched image to a tarball at this path")
 	patchComprehensiveCmd.Flags().BoolVarP(&comprehensivePush, "push", "", false, "Push patched image to registry")
+	patchComprehensiveCmd.Flags().StringVar(&comprehensiveDestination, "destination", "", "Repository, without tag, to give the patched image instead of the source repository, e.g. golden.example.com/team/app")
+	patchComprehensiveCmd.Flags().BoolVar(&comprehensiveDryRun, "dry-run", false, "Print the copa command that would run, without patching")
+	patchComprehensiveCmd.Flags().StringVar(&comprehensiveGitOps, "gitops", "", "Also print the configuration for flux image automation or argocd Image Updater to deploy the patched tag")
+	patchComprehensiveCmd.Flags().StringVar(&comprehensiveSmokeTest, "smoke-test", "", "Command to test the patched image with in a temporary registry before it is pushed; {image} is replaced by its reference")
 	patchComprehensiveCmd.MarkFlagRequired("image")
 	// patchComprehensiveCmd.MarkFlagRequired("patchtag")
 
 	// Patch Platforms command
 	var (
-		platformsImage    string
-		platformsPatchTag string
-		platformsPush     bool
-		targetPlatforms   []string
+		platformsImage       string
+		platformsPatchTag    string
+		platformsExportPath  string
+		platformsPush        bool
+		platformsDestination string
+		platformsDryRun      bool
+		platformsGitOps      string
+		platformsSmokeTest   string
+		targetPlatforms      []string
 	)
 	var patchPlatformsCmd = &cobra.Command{
 		Use:   "patch-platforms",
@@ -213,6 +924,21 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 				"push":     platformsPush,
 				"platform": targetPlatforms,
 			}
+			if platformsDryRun {
+				mcpArgs["dryRun"] = true
+			}
+			if platformsDestination != "" {
+				mcpArgs["destination"] = platformsDestination
+			}
+			if platformsExportPath != "" {
+				mcpArgs["exportPath"] = platformsExportPath
+			}
+			if platformsGitOps != "" {
+				mcpArgs["gitops"] = platformsGitOps
+			}
+			if platformsSmokeTest != "" {
+				mcpArgs["smokeTest"] = strings.Fields(platformsSmokeTest)
+			}
 			if err := executeMCPTool("patch-platform-selective", mcpArgs); err != nil {
 				log.Fatalf("Error executing patch-platforms command: %v", err)
 			}
@@ -220,7 +946,12 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 	}
 	patchPlatformsCmd.Flags().StringVarP(&platformsImage, "image", "i", "", "Container image to patch (required)")
 	patchPlatformsCmd.Flags().StringVarP(&platformsPatchTag, "patchtag", "t", "", "Tag for the patched image (required)")
+	patchPlatformsCmd.Flags().StringVarP(&platformsExportPath, "export-path", "o", "", "Save the patched image to a tarball at this path")
 	patchPlatformsCmd.Flags().BoolVarP(&platformsPush, "push", "", false, "Push patched image to registry")
+	patchPlatformsCmd.Flags().StringVar(&platformsDestination, "destination", "", "Repository, without tag, to give the patched image instead of the source repository, e.g. golden.example.com/team/app")
+	patchPlatformsCmd.Flags().BoolVar(&platformsDryRun, "dry-run", false, "Print the copa command that would run, without patching")
+	patchPlatformsCmd.Flags().StringVar(&platformsGitOps, "gitops", "", "Also print the configuration for flux image automation or argocd Image Updater to deploy the patched tag")
+	patchPlatformsCmd.Flags().StringVar(&platformsSmokeTest, "smoke-test", "", "Command to test the patched image with in a temporary registry before it is pushed; {image} is replaced by its reference")
 	patchPlatformsCmd.Flags().StringSliceVarP(&targetPlatforms, "platform", "p", []string{}, "Target platform(s) for patching (required)")
 	patchPlatformsCmd.MarkFlagRequired("image")
 	patchPlatformsCmd.MarkFlagRequired("patchtag")
@@ -228,10 +959,29 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 
 	// Patch Vulnerabilities command
 	var (
-		vulnImage      string
-		vulnPatchTag   string
-		vulnPush       bool
-		vulnReportPath string
+		vulnImage       string
+		vulnPatchTag    string
+		vulnExportPath  string
+		vulnPush        bool
+		vulnDestination string
+		vulnDryRun      bool
+		vulnReportPath  string
+		vulnVexOutput   string
+		vulnVexFormat   string
+		vulnKeepReport  bool
+		vulnKeepVex     bool
+
+		vulnMaxRemaining         int
+		vulnMaxRemainingSeverity string
+		vulnExcludeCVEs          []string
+		vulnMinSeverity          string
+		vulnSeverity             []string
+		vulnVexNotes             string
+		vulnVexInput             string
+		vulnVexAuthor            string
+		vulnScanRemaining        bool
+		vulnGitOps               string
+		vulnSmokeTest            string
 	)
 	var patchVulnerabilitiesCmd = &cobra.Command{
 		Use:   "patch-vulnerabilities",
@@ -244,6 +994,61 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 				"push":       vulnPush,
 				"reportPath": vulnReportPath,
 			}
+			if vulnDryRun {
+				mcpArgs["dryRun"] = true
+			}
+			if vulnDestination != "" {
+				mcpArgs["destination"] = vulnDestination
+			}
+			if vulnVexOutput != "" {
+				mcpArgs["vexOutput"] = vulnVexOutput
+			}
+			if vulnVexFormat != "" {
+				mcpArgs["vexFormat"] = vulnVexFormat
+			}
+			if vulnVexInput != "" {
+				mcpArgs["vexInput"] = vulnVexInput
+			}
+			if vulnVexNotes != "" {
+				mcpArgs["vexNotes"] = vulnVexNotes
+			}
+			if vulnVexAuthor != "" {
+				mcpArgs["vexAuthor"] = vulnVexAuthor
+			}
+			// Retention is only sent when set so the server default applies otherwise
+			if cmd.Flags().Changed("keep-report") {
+				mcpArgs["keepReport"] = vulnKeepReport
+			}
+			if cmd.Flags().Changed("keep-vex") {
+				mcpArgs["keepVex"] = vulnKeepVex
+			}
+			if cmd.Flags().Changed("max-remaining") {
+				mcpArgs["maxRemaining"] = vulnMaxRemaining
+				if vulnMaxRemainingSeverity != "" {
+					mcpArgs["maxRemainingSeverity"] = vulnMaxRemainingSeverity
+				}
+			}
+			if len(vulnExcludeCVEs) > 0 {
+				mcpArgs["excludeCVEs"] = vulnExcludeCVEs
+			}
+			if vulnMinSeverity != "" {
+				mcpArgs["minSeverity"] = vulnMinSeverity
+			}
+			if len(vulnSeverity) > 0 {
+				mcpArgs["severity"] = vulnSeverity
+			}
+			if vulnExportPath != "" {
+				mcpArgs["exportPath"] = vulnExportPath
+			}
+			if vulnScanRemaining {
+				mcpArgs["scanRemaining"] = true
+			}
+			if vulnGitOps != "" {
+				mcpArgs["gitops"] = vulnGitOps
+			}
+			if vulnSmokeTest != "" {
+				mcpArgs["smokeTest"] = strings.Fields(vulnSmokeTest)
+			}
 			if err := executeMCPTool("patch-report-based", mcpArgs); err != nil {
 				log.Fatalf("Error executing patch-vulnerabilities command: %v", err)
 			}
@@ -251,12 +1056,89 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 	}
 	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnImage, "image", "i", "", "Container image to patch (required)")
 	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnPatchTag, "patchtag", "t", "", "Tag for the patched image (required)")
+	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnExportPath, "export-path", "o", "", "Save the patched image to a tarball at this path")
 	patchVulnerabilitiesCmd.Flags().BoolVarP(&vulnPush, "push", "", false, "Push patched image to registry")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnDestination, "destination", "", "Repository, without tag, to give the patched image instead of the source repository, e.g. golden.example.com/team/app")
+	patchVulnerabilitiesCmd.Flags().BoolVar(&vulnDryRun, "dry-run", false, "Print the copa command that would run, without patching")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnGitOps, "gitops", "", "Also print the configuration for flux image automation or argocd Image Updater to deploy the patched tag")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnSmokeTest, "smoke-test", "", "Command to test the patched image with in a temporary registry before it is pushed; {image} is replaced by its reference")
 	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnReportPath, "report-path", "r", "", "Path to vulnerability report directory (required)")
+	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnVexOutput, "vex-output", "", "", "Write the generated VEX document to this path")
+	patchVulnerabilitiesCmd.Flags().StringVarP(&vulnVexFormat, "vex-format", "", "", "VEX document format: openvex (default) or csaf")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnVexInput, "vex-input", "", "OpenVEX document whose not_affected and fixed statements are skipped when patching")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnVexNotes, "vex-notes", "", "Notes added to every VEX statement, e.g. a change ticket ID")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnVexAuthor, "vex-author", "", "Author recorded in the generated VEX document")
+	patchVulnerabilitiesCmd.Flags().BoolVar(&vulnKeepReport, "keep-report", true, "Keep the scan report directory after patching (defaults to the server setting)")
+	patchVulnerabilitiesCmd.Flags().IntVar(&vulnMaxRemaining, "max-remaining", 0, "Fail if more than this many vulnerabilities at or above --max-remaining-severity remain after patching")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnMaxRemainingSeverity, "max-remaining-severity", "", "Lowest severity counted against --max-remaining: CRITICAL, HIGH, MEDIUM or LOW (default LOW)")
+	patchVulnerabilitiesCmd.Flags().StringVar(&vulnMinSeverity, "min-severity", "", "Only patch vulnerabilities at or above this severity: CRITICAL, HIGH, MEDIUM or LOW")
+	patchVulnerabilitiesCmd.Flags().StringSliceVar(&vulnSeverity, "severity", nil, "Only patch vulnerabilities of these severities, e.g. CRITICAL,HIGH")
+	patchVulnerabilitiesCmd.Flags().StringSliceVar(&vulnExcludeCVEs, "exclude-cve", nil, "Vulnerability ID(s) to leave unpatched, e.g. accepted risks (repeatable or comma-separated)")
+	patchVulnerabilitiesCmd.Flags().BoolVar(&vulnScanRemaining, "scan-remaining", false, "Scan the patched image, including unfixed and language package vulnerabilities, to classify what remains")
+	patchVulnerabilitiesCmd.Flags().BoolVar(&vulnKeepVex, "keep-vex", true, "Keep the generated VEX document after patching (defaults to the server setting)")
 	patchVulnerabilitiesCmd.MarkFlagRequired("image")
 	patchVulnerabilitiesCmd.MarkFlagRequired("patchtag")
 	patchVulnerabilitiesCmd.MarkFlagRequired("report-path")
 
+	// Remediate command
+	var (
+		remediateImage    string
+		remediatePatchTag string
+		remediatePush     bool
+		remediateSign     bool
+		remediateResume   string
+	)
+	var remediateCmd = &cobra.Command{
+		Use:   "remediate",
+		Short: "Scan, patch, verify and optionally push and sign an image",
+		Long:  "Scan an image, patch the vulnerabilities found, rescan and compare the patched image, then optionally push and sign it, resuming a failed remediation with --resume",
+		Run: func(cmd *cobra.Command, args []string) {
+			if remediateImage == "" && remediateResume == "" {
+				log.Fatal("--image or --resume is required")
+			}
+			mcpArgs := map[string]any{"push": remediatePush, "sign": remediateSign}
+			if remediateImage != "" {
+				mcpArgs["image"] = remediateImage
+			}
+			if remediatePatchTag != "" {
+				mcpArgs["patchtag"] = remediatePatchTag
+			}
+			if remediateResume != "" {
+				mcpArgs["resume"] = remediateResume
+			}
+			if err := executeMCPTool("remediate", mcpArgs); err != nil {
+				log.Fatalf("Error executing remediate command: %v", err)
+			}
+		},
+	}
+	remediateCmd.Flags().StringVarP(&remediateImage, "image", "i", "", "Container image to remediate (required unless --resume is set)")
+	remediateCmd.Flags().StringVarP(&remediatePatchTag, "patchtag", "t", "", "Tag for the patched image (default: the source tag suffixed with -patched)")
+	remediateCmd.Flags().BoolVar(&remediatePush, "push", false, "Push the patched image once the rescan verified it")
+	remediateCmd.Flags().BoolVar(&remediateSign, "sign", false, "Sign the pushed image with the server's cosign key (requires --push)")
+	remediateCmd.Flags().StringVar(&remediateResume, "resume", "", "ID of a failed remediation to resume from the stage that failed")
+
+	// Install dependencies command
+	var (
+		installTools []string
+		installForce bool
+	)
+	var installDependenciesCmd = &cobra.Command{
+		Use:   "install-dependencies",
+		Short: "Install pinned copa and trivy releases",
+		Long:  "Download checksum-verified pinned releases of copa and trivy into the server's managed bin directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			mcpArgs := map[string]any{"force": installForce}
+			if len(installTools) > 0 {
+				mcpArgs["tools"] = installTools
+			}
+			if err := executeMCPTool("install-dependencies", mcpArgs); err != nil {
+				log.Fatalf("Error executing install-dependencies command: %v", err)
+			}
+		},
+	}
+	installDependenciesCmd.Flags().StringSliceVarP(&installTools, "tools", "", nil, "Tools to install, copa and/or trivy (default both)")
+	installDependenciesCmd.Flags().BoolVarP(&installForce, "force", "", false, "Download the pinned releases even when already installed")
+
 	// List tools command
 	var listCmd = &cobra.Command{
 		Use:   "list",
@@ -277,9 +1159,28 @@ allowing you to patch container images, scan for vulnerabilities, and manage con
 	// Add all commands to root
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(pullCmd)
+	rootCmd.AddCommand(removeImageCmd)
+	rootCmd.AddCommand(registryLoginCmd)
 	rootCmd.AddCommand(patchComprehensiveCmd)
 	rootCmd.AddCommand(patchPlatformsCmd)
 	rootCmd.AddCommand(patchVulnerabilitiesCmd)
+	rootCmd.AddCommand(listFixedCmd)
+	rootCmd.AddCommand(listVulnsCmd)
+	rootCmd.AddCommand(summarizeReportCmd)
+	rootCmd.AddCommand(listClusterImagesCmd)
+	rootCmd.AddCommand(scanRegistryCmd)
+	rootCmd.AddCommand(fetchHarborReportCmd)
+	rootCmd.AddCommand(suggestBaseUpgradeCmd)
+	rootCmd.AddCommand(evaluateImageCmd)
+	rootCmd.AddCommand(generateSBOMCmd)
+	rootCmd.AddCommand(diffSBOMCmd)
+	rootCmd.AddCommand(verifyPatchCmd)
+	rootCmd.AddCommand(patchHistoryCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(openImagePRCmd)
+	rootCmd.AddCommand(remediateCmd)
+	rootCmd.AddCommand(installDependenciesCmd)
 	rootCmd.AddCommand(listCmd)
 
 	// Execute the root command
@@ -2,10 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/project-copacetic/mcp-server/internal/config"
 	"github.com/project-copacetic/mcp-server/internal/copamcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/install"
+	"github.com/project-copacetic/mcp-server/internal/logging"
+	"github.com/project-copacetic/mcp-server/internal/mock"
+	"github.com/project-copacetic/mcp-server/internal/transcript"
+	"github.com/project-copacetic/mcp-server/internal/types"
 	"github.com/spf13/cobra"
 )
 
@@ -22,20 +34,369 @@ var rootCmd = &cobra.Command{
 	Long: `A Model Context Protocol (MCP) server for automated container image patching using Copacetic and Trivy.
 This server exposes container patching capabilities through the MCP protocol, allowing AI agents and tools to patch container image vulnerabilities programmatically.`,
 	Version: fmt.Sprintf("Version: %s\nCommit: %s\nBuild Date: %s", version, commit, date),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("retry-categories") {
+			categories, err := copaerrors.ParseCategories(strings.Join(retryCategories, ","))
+			if err != nil {
+				return fmt.Errorf("invalid --retry-categories: %w", err)
+			}
+			cfg.Retry.Retryable = categories
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		return logging.Configure(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	},
 }
 
+// cfg holds the server configuration, loaded from the environment and overridden by flags
+var cfg *config.Config
+
+// retryCategories holds the --retry-categories flag until it is parsed into cfg.Retry
+var retryCategories []string
+
 var stdioCmd = &cobra.Command{
 	Use:   "stdio",
 	Short: "Start stdio server",
 	Long:  `Start a server that communicates via standard input/output streams using the Model Context Protocol (MCP).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return copamcp.Run(context.Background(), version)
+		stop, err := startMock()
+		if err != nil {
+			return err
+		}
+		defer stop()
+		return copamcp.Run(context.Background(), version, cfg)
+	},
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled scans without serving a client",
+	Long: `Run the recurring scans (and auto-patches) of the schedule file until interrupted, without an MCP client.
+Results are delivered through the configured webhooks and GitHub step summary.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stopMock, err := startMock()
+		if err != nil {
+			return err
+		}
+		defer stopMock()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return copamcp.RunDaemon(ctx, version, cfg)
+	},
+}
+
+// httpListen holds the --listen flag of the http command
+var httpListen string
+
+var httpCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Start streamable HTTP server",
+	Long: `Start a server that serves the Model Context Protocol (MCP) over streamable HTTP, and HTTP+SSE at /sse for older clients,
+so that it can run remotely instead of being spawned by each client. Clients authenticate with the bearer tokens given with
+--api-token or --api-tokens-file, as scope:token, and may only call the tools of the token's scope (scan, patch-no-push or
+full). Without tokens the server only listens on a loopback address.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stopMock, err := startMock()
+		if err != nil {
+			return err
+		}
+		defer stopMock()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return copamcp.RunHTTP(ctx, version, cfg, httpListen)
+	},
+}
+
+// updateCheck and updateForce hold the flags of the update command
+var updateCheck, updateForce bool
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update the server binary to the latest release",
+	Long: `Download the latest release of the server for this platform from GitHub, verify it against the release's
+checksums and replace the running binary with it. Restart the MCP host afterwards to use the new version.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := install.Executable()
+		if err != nil {
+			return err
+		}
+		result, err := install.Update(cmd.Context(), version, path, updateCheck, updateForce)
+		if err != nil {
+			return err
+		}
+		switch {
+		case result.Updated:
+			fmt.Printf("Updated %s from %s to %s; restart the MCP host to use it\n", result.Path, result.Current, result.Latest)
+		case updateCheck:
+			fmt.Printf("Running %s, the latest release is %s\n", result.Current, result.Latest)
+		default:
+			fmt.Printf("%s is up to date (latest release %s)\n", result.Current, result.Latest)
+		}
+		return nil
+	},
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <transcript>",
+	Short: "Replay the tool calls of a recorded transcript",
+	Long: `Call the tools of a transcript recorded with --transcript in order, and compare each result with the recorded one.
+With --mock the calls are simulated with the stubs; otherwise they run again for real, patching and pushing as recorded.
+Exits with an error when a call failed where it succeeded in the transcript, or the reverse.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := transcript.Load(args[0])
+		if err != nil {
+			return fmt.Errorf("reading the transcript: %w", err)
+		}
+		stop, err := startMock()
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		results, err := copamcp.Replay(cmd.Context(), version, cfg, entries, os.Stdout)
+		if err != nil {
+			return err
+		}
+		regressions := 0
+		for _, r := range results {
+			if r.Regressed() {
+				regressions++
+			}
+		}
+		fmt.Printf("Replayed %d calls, %d regressions\n", len(results), regressions)
+		if regressions > 0 {
+			return fmt.Errorf("%d of %d replayed calls changed outcome", regressions, len(results))
+		}
+		return nil
+	},
+}
+
+// historyParams and historyJSON hold the flags of the history command
+var (
+	historyParams types.PatchHistoryParams
+	historyJSON   bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the recorded patches",
+	Long: `List the patches recorded to the history file, newest first, like the patch-history tool.
+The history is read directly, so this works while no server is running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := copamcp.QueryHistory(cfg, historyParams)
+		if err != nil {
+			return err
+		}
+		if historyJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+		fmt.Println(copamcp.HistoryText(result))
+		return nil
 	},
 }
 
+// doctorParams and doctorJSON hold the flags of the doctor command
+var (
+	doctorParams types.DoctorParams
+	doctorJSON   bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check whether the environment is ready to scan and patch images",
+	Long: `Run the checks of the doctor tool: the Docker daemon, buildkit, the copa and trivy binaries and
+their versions, registry connectivity and the disk space of the working directory.
+Exits with an error when a check failed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stop, err := startMock()
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		report, err := copamcp.RunDoctor(cmd.Context(), cfg, doctorParams)
+		if err != nil {
+			return err
+		}
+		if doctorJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			fmt.Println(copamcp.DoctorText(report))
+		}
+		if !report.Ready {
+			return fmt.Errorf("the environment is not ready")
+		}
+		return nil
+	},
+}
+
+// startMock installs the copa, trivy and docker stubs in mock mode, and returns a function removing them
+func startMock() (func(), error) {
+	if !cfg.Mock {
+		return func() {}, nil
+	}
+	stop, err := mock.Setup()
+	if err != nil {
+		return nil, fmt.Errorf("starting mock mode: %w", err)
+	}
+	slog.Info("Mock mode: copa, trivy and docker are simulated with canned scan reports and patch results", "logger", "copacetic-mcp")
+	return stop, nil
+}
+
 func init() {
+	// In mock mode this binary is also run as copa, trivy and docker
+	mock.RunIfStub()
+
+	var err error
+	if cfg, err = config.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Add flags
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.DockerSockets, "docker-socket", cfg.DockerSockets,
+		"Docker socket path(s) to probe when DOCKER_HOST is unset and the default socket is absent (env: "+config.EnvDockerSockets+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.BuildkitAddr, "buildkit-addr", cfg.BuildkitAddr,
+		"Buildkit address used by copa, e.g. tcp://buildkitd:1234 or docker-container://buildkitd (env: "+config.EnvBuildkitAddr+")")
+	rootCmd.PersistentFlags().DurationVar(&cfg.BuildkitWait, "buildkit-wait", cfg.BuildkitWait,
+		"Maximum time to wait for the buildkit address to become ready before patching (env: "+config.EnvBuildkitWait+")")
+	rootCmd.PersistentFlags().DurationVar(&cfg.ToolTimeout, "tool-timeout", cfg.ToolTimeout,
+		"Maximum time a scan or patch command may run when the call does not set timeoutSeconds, 0 for no limit (env: "+config.EnvToolTimeout+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.KeepReports, "keep-reports", cfg.KeepReports,
+		"Keep scan reports after a report-based patch unless the call sets keepReport (env: "+config.EnvKeepReports+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.KeepVex, "keep-vex", cfg.KeepVex,
+		"Keep generated VEX documents after a patch unless the call sets keepVex (env: "+config.EnvKeepVex+")")
+	rootCmd.PersistentFlags().IntVar(&cfg.Retry.MaxAttempts, "retry-max-attempts", cfg.Retry.MaxAttempts,
+		"Attempts for a scan or patch, including the first; 1 disables retries (env: "+config.EnvRetryMaxAttempts+")")
+	rootCmd.PersistentFlags().DurationVar(&cfg.Retry.Backoff, "retry-backoff", cfg.Retry.Backoff,
+		"Delay before the first retry, doubled for each further retry (env: "+config.EnvRetryBackoff+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.WebhookURLs, "webhook-url", cfg.WebhookURLs,
+		"Webhook URL(s) sent a JSON event when a scan or patch finishes (env: "+config.EnvWebhookURLs+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.SlackWebhookURLs, "slack-webhook-url", cfg.SlackWebhookURLs,
+		"Slack incoming webhook URL(s) notified when a scan or patch finishes (env: "+config.EnvSlackWebhookURLs+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.GitHubSummary, "github-summary", cfg.GitHubSummary,
+		"Append a Markdown summary of each scan and patch to $GITHUB_STEP_SUMMARY in GitHub Actions (env: "+config.EnvGitHubSummary+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.AzurePipelines, "azure-pipelines", cfg.AzurePipelines,
+		"Report scans and patches to Azure Pipelines with logging commands when running in a pipeline (env: "+config.EnvAzurePipelines+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.ScheduleFile, "schedule-file", cfg.ScheduleFile,
+		"JSON file of images to scan, and optionally patch, on cron-like schedules (env: "+config.EnvScheduleFile+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.HarborURL, "harbor-url", cfg.HarborURL,
+		"Harbor base URL for fetch-harbor-report; defaults to the image's registry host (env: "+config.EnvHarborURL+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.HarborUsername, "harbor-username", cfg.HarborUsername,
+		"Harbor user or robot account for fetch-harbor-report; the password is read from "+config.EnvHarborPassword+" (env: "+config.EnvHarborUsername+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.DependencyTrackURL, "dtrack-url", cfg.DependencyTrackURL,
+		"Dependency-Track API server that SBOMs of scanned and patched images are uploaded to; the API key is read from "+config.EnvDependencyTrackAPIKey+" (env: "+config.EnvDependencyTrackURL+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.DependencyTrackProjects, "dtrack-project", cfg.DependencyTrackProjects,
+		"Dependency-Track project of images with a reference prefix, as prefix=project (env: "+config.EnvDependencyTrackProjects+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.DefectDojoURL, "defectdojo-url", cfg.DefectDojoURL,
+		"DefectDojo server that scan findings and post-patch scans are imported into; the API key is read from "+config.EnvDefectDojoAPIKey+" (env: "+config.EnvDefectDojoURL+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.DefectDojoProducts, "defectdojo-product", cfg.DefectDojoProducts,
+		"DefectDojo product of images with a reference prefix, as prefix=product or prefix=product/engagement (env: "+config.EnvDefectDojoProducts+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Policies, "policy", cfg.Policies,
+		"Rego policy file or directory evaluated with opa before each patch, to deny it, force a dry run or require confirmation (env: "+config.EnvPolicies+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.AllowedRegistries, "allowed-registry", cfg.AllowedRegistries,
+		"Registry host or repository prefix allowed by evaluate-image; all registries when unset (env: "+config.EnvAllowedRegistries+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Registries.SourceAllow, "source-allow", cfg.Registries.SourceAllow,
+		"Registry host or repository pattern images may be pulled, scanned or patched from; all registries when unset (env: "+config.EnvSourceAllow+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Registries.SourceDeny, "source-deny", cfg.Registries.SourceDeny,
+		"Registry host or repository pattern images may not come from (env: "+config.EnvSourceDeny+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Registries.PushAllow, "push-allow", cfg.Registries.PushAllow,
+		"Registry host or repository pattern patched images may be pushed to; all registries when unset (env: "+config.EnvPushAllow+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Registries.PushDeny, "push-deny", cfg.Registries.PushDeny,
+		"Registry host or repository pattern patched images may not be pushed to, e.g. docker.io (env: "+config.EnvPushDeny+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.RequireSignature, "require-signature", cfg.RequireSignature,
+		"Make evaluate-image deny images without a cosign or Notation signature (env: "+config.EnvRequireSignature+")")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxCritical, "max-critical", cfg.MaxCritical,
+		"Fixable CRITICAL vulnerabilities allowed by evaluate-image; negative disables the check (env: "+config.EnvMaxCritical+")")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.VerifyKeys, "verify-key", cfg.VerifyKeys,
+		"Cosign public key (file, KMS URI or k8s:// secret) trusted to sign the images that are pulled, scanned or patched (env: "+config.EnvVerifyKeys+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.VerifyIdentity, "verify-identity", cfg.VerifyIdentity,
+		"Regular expression matching the certificate identity of trusted keyless signatures (env: "+config.EnvVerifyIdentity+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.VerifyOIDCIssuer, "verify-oidc-issuer", cfg.VerifyOIDCIssuer,
+		"OIDC issuer of trusted keyless signatures (env: "+config.EnvVerifyOIDCIssuer+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.VerifyAttestation, "verify-attestation", cfg.VerifyAttestation,
+		"Predicate type of an attestation that must also verify, e.g. slsaprovenance (env: "+config.EnvVerifyAttestation+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.SignKey, "sign-key", cfg.SignKey,
+		"Cosign private key (file, KMS URI or k8s:// secret) remediate signs pushed images with; the password is read from COSIGN_PASSWORD (env: "+config.EnvSignKey+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.SmokeTests, "smoke-tests", cfg.SmokeTests,
+		"Allow the patch tools to run smoke test commands against patched images on this host (env: "+config.EnvSmokeTests+")")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxSubprocesses, "max-subprocesses", cfg.MaxSubprocesses,
+		"Copa, trivy and docker subprocesses allowed to run at once across all tool calls; 0 removes the limit (env: "+config.EnvMaxSubprocesses+")")
+	rootCmd.PersistentFlags().IntVar(&cfg.ScanConcurrency, "scan-concurrency", cfg.ScanConcurrency,
+		"Platforms of a multi-platform image scanned at once; 1 scans them one after another (env: "+config.EnvScanConcurrency+")")
+	rootCmd.PersistentFlags().StringArrayVar(&cfg.SubprocessEnv, "subprocess-env", cfg.SubprocessEnv,
+		"KEY=value variable set in the environment of copa, trivy, docker and ctr subprocesses; repeat for several (env: "+config.EnvSubprocessEnv+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.TempDir, "temp-dir", cfg.TempDir,
+		"Directory holding scan reports, VEX documents and working directories (default copa-mcp in the system temporary directory, env: "+config.EnvTempDir+")")
+	rootCmd.PersistentFlags().IntVar(&cfg.TempQuotaMB, "temp-quota-mb", cfg.TempQuotaMB,
+		"Size in MB of the temporary directory above which scans and patches fail; 0 removes the quota (env: "+config.EnvTempQuotaMB+")")
+	rootCmd.PersistentFlags().DurationVar(&cfg.TempMaxAge, "temp-max-age", cfg.TempMaxAge,
+		"Age above which entries of the temporary directory are removed at startup; 0 keeps them (env: "+config.EnvTempMaxAge+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.BinDir, "bin-dir", cfg.BinDir,
+		"Directory install-dependencies installs copa and trivy to, put first on the PATH (default copa-mcp/bin in the user cache directory, env: "+config.EnvBinDir+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.CopaPath, "copa-path", cfg.CopaPath,
+		"copa binary to run instead of the copa found on the PATH (env: "+config.EnvCopaPath+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.TrivyPath, "trivy-path", cfg.TrivyPath,
+		"trivy binary to run instead of the trivy found on the PATH (env: "+config.EnvTrivyPath+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.TrivySkipDBUpdate, "skip-db-update", cfg.TrivySkipDBUpdate,
+		"Scan with trivy's cached vulnerability database instead of updating it (env: "+config.EnvTrivySkipDBUpdate+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.TrivyOfflineScan, "offline-scan", cfg.TrivyOfflineScan,
+		"Make trivy scan without network requests besides the database download (env: "+config.EnvTrivyOfflineScan+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.TrivyDBRepository, "db-repository", cfg.TrivyDBRepository,
+		"OCI repository trivy downloads its vulnerability database from, e.g. a mirror (env: "+config.EnvTrivyDBRepository+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat,
+		"Format of the logs written to stderr: text or json (env: "+config.EnvLogFormat+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel,
+		"Lowest level of the logs written to stderr: debug, info, warn or error (env: "+config.EnvLogLevel+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint,
+		"OTLP/HTTP endpoint of an OpenTelemetry collector to export the server's logs to, e.g. http://otel-collector:4318 (env: "+config.EnvOTLPEndpoint+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.Transcript, "transcript", cfg.Transcript,
+		"JSON Lines file every tool call and its result are appended to, with credentials redacted, for bug reports and 'replay' (env: "+config.EnvTranscript+")")
+	rootCmd.PersistentFlags().StringVar(&cfg.History, "history", cfg.History,
+		"JSON Lines file completed patches are recorded to, for 'patch-history' and the history command; empty disables it (env: "+config.EnvHistory+")")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Mock, "mock", cfg.Mock,
+		"Simulate copa, trivy and docker with canned scan reports and patch results, for demos and host integration tests (env: "+config.EnvMock+")")
+	rootCmd.PersistentFlags().StringSliceVar(&retryCategories, "retry-categories", nil,
+		"Error categories to retry: validation, auth, network, execution, system, policy, timeout (default network, env: "+config.EnvRetryCategories+")")
+
+	httpCmd.Flags().StringVar(&httpListen, "listen", "127.0.0.1:8080", "Address to listen on; other than a loopback address, it requires API tokens")
+	httpCmd.Flags().StringArrayVar(&cfg.APITokens, "api-token", cfg.APITokens,
+		"API token clients authenticate with, as scope:token with scope scan, patch-no-push or full; repeat for several. Prefer "+config.EnvAPITokens+" or --api-tokens-file, which stay out of process listings")
+	httpCmd.Flags().StringVar(&cfg.APITokensFile, "api-tokens-file", cfg.APITokensFile,
+		"File of API tokens clients authenticate with, one scope:token per line (env: "+config.EnvAPITokensFile+")")
+	httpCmd.Flags().StringVar(&cfg.PprofToken, "pprof-token", cfg.PprofToken,
+		"Bearer token to serve the pprof profiles at "+copamcp.DebugPath+" with; empty does not serve them (env: "+config.EnvPprofToken+")")
+
+	historyCmd.Flags().StringVar(&historyParams.Image, "image", "", "Only list the patches of this image, or of every tag of this repository")
+	historyCmd.Flags().StringVar(&historyParams.Since, "since", "", "Only list the patches recorded within this duration, e.g. 24h")
+	historyCmd.Flags().IntVar(&historyParams.Limit, "limit", 0, "Maximum number of patches to list (default 20)")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the result as JSON")
+
+	doctorCmd.Flags().StringSliceVar(&doctorParams.Registries, "registry", nil, "Registry host to check connectivity to; repeat for several (default docker.io)")
+	doctorCmd.Flags().StringVar(&doctorParams.DockerHost, "docker-host", "", "Docker daemon endpoint to check (default DOCKER_HOST)")
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only report the latest release, without updating")
+	updateCmd.Flags().BoolVar(&updateForce, "force", false, "Replace the binary even when it is up to date or a development build")
+
 	// Add subcommands
 	rootCmd.AddCommand(stdioCmd)
+	rootCmd.AddCommand(httpCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
 
 func main() {
@@ -0,0 +1,147 @@
+// Package baseimage finds the base images of a Dockerfile and newer versions of their tags, so that
+// vulnerabilities inherited from an outdated base image can be fixed by bumping its FROM instruction.
+package baseimage
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// From is a FROM instruction of a Dockerfile that names an image
+type From struct {
+	Line  int    // 1-based line of the instruction
+	Image string // The base image reference, as written
+	Stage string // The stage name given with AS, if any
+}
+
+// Bump replaces the base image of the FROM instruction on Line with Image
+type Bump struct {
+	Line  int
+	Image string
+}
+
+// versionTag matches tags made of a version and an optional variant, e.g. 3.18, v1.2.3 or 1.25-alpine3.18
+var versionTag = regexp.MustCompile(`^(v?)(\d+(?:\.\d+)*)((?:[-_].*)?)$`)
+
+// ParseDockerfile returns the FROM instructions of a Dockerfile that pull an image. Instructions
+// that start from scratch or from an earlier stage are left out.
+func ParseDockerfile(data []byte) []From {
+	var froms []From
+	stages := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+
+		var from From
+		for i := 1; i < len(fields); i++ {
+			switch {
+			case strings.HasPrefix(fields[i], "--"):
+			case from.Image == "":
+				from.Image = fields[i]
+			case strings.EqualFold(fields[i], "AS") && i+1 < len(fields):
+				from.Stage = fields[i+1]
+				i++
+			}
+		}
+		external := from.Image != "" && !strings.EqualFold(from.Image, "scratch") && !stages[strings.ToLower(from.Image)]
+		if from.Stage != "" {
+			stages[strings.ToLower(from.Stage)] = true
+		}
+		if external {
+			from.Line = line
+			froms = append(froms, from)
+		}
+	}
+	return froms
+}
+
+// SplitTag splits an image reference into its name and tag. The tag is empty when the reference
+// has none; a digest stays part of the name.
+func SplitTag(image string) (name, tag string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// IsVersion reports whether tag is a version tag that Newer can compare
+func IsVersion(tag string) bool {
+	return versionTag.MatchString(tag)
+}
+
+// Newer returns the tags that are newer versions of tag, newest first. Only tags of the same variant
+// (e.g. -alpine), with as many version components as tag, are considered: 3.18 is followed by 3.19
+// but not by 3.19.1 or 3.19-slim, so that the suggested tag keeps the stability of the current one.
+func Newer(tag string, tags []string) []string {
+	current := versionTag.FindStringSubmatch(tag)
+	if current == nil {
+		return nil
+	}
+	currentVersion := parseVersion(current[2])
+
+	var newer []string
+	for _, candidate := range tags {
+		m := versionTag.FindStringSubmatch(candidate)
+		if m == nil || m[1] != current[1] || m[3] != current[3] {
+			continue
+		}
+		if v := parseVersion(m[2]); len(v) == len(currentVersion) && slices.Compare(v, currentVersion) > 0 {
+			newer = append(newer, candidate)
+		}
+	}
+	slices.SortFunc(newer, func(a, b string) int {
+		return slices.Compare(parseVersion(versionTag.FindStringSubmatch(b)[2]), parseVersion(versionTag.FindStringSubmatch(a)[2]))
+	})
+	return slices.Compact(newer)
+}
+
+// SameMajor returns the first tag of newer, as returned by Newer, that keeps the major version of tag
+func SameMajor(tag string, newer []string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(tag, "v"), ".")
+	for _, candidate := range newer {
+		if m, _, _ := strings.Cut(strings.TrimPrefix(candidate, "v"), "."); m == major {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Rewrite replaces the base images of the FROM instructions named by bumps, keeping the rest of
+// each instruction (flags, stage name, comments) as written
+func Rewrite(data []byte, froms []From, bumps []Bump) []byte {
+	images := map[int]string{}
+	for _, from := range froms {
+		images[from.Line] = from.Image
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, bump := range bumps {
+		image, ok := images[bump.Line]
+		if !ok || bump.Line > len(lines) {
+			continue
+		}
+		line := lines[bump.Line-1]
+		keyword := strings.Index(strings.ToUpper(line), "FROM")
+		lines[bump.Line-1] = line[:keyword] + strings.Replace(line[keyword:], image, bump.Image, 1)
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+func parseVersion(version string) []int {
+	parts := strings.Split(version, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		numbers[i], _ = strconv.Atoi(part)
+	}
+	return numbers
+}
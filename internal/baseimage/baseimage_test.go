@@ -0,0 +1,85 @@
+package baseimage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dockerfile = `# syntax=docker/dockerfile:1
+ARG GO_IMAGE=golang:1.21
+FROM --platform=$BUILDPLATFORM golang:1.21-alpine AS build
+RUN go build -o /app .
+
+FROM build AS test
+from ${GO_IMAGE}
+
+FROM scratch AS empty
+FROM alpine:3.18	AS runtime
+COPY --from=build /app /app
+`
+
+func TestParseDockerfile(t *testing.T) {
+	assert.Equal(t, []From{
+		{Line: 3, Image: "golang:1.21-alpine", Stage: "build"},
+		{Line: 7, Image: "${GO_IMAGE}"},
+		{Line: 10, Image: "alpine:3.18", Stage: "runtime"},
+	}, ParseDockerfile([]byte(dockerfile)))
+}
+
+func TestSplitTag(t *testing.T) {
+	tests := []struct {
+		image string
+		name  string
+		tag   string
+	}{
+		{"alpine:3.18", "alpine", "3.18"},
+		{"localhost:5000/team/app", "localhost:5000/team/app", ""},
+		{"registry.example.com:5000/app:1.0-slim", "registry.example.com:5000/app", "1.0-slim"},
+		{"alpine:3.18@sha256:abc", "alpine:3.18@sha256:abc", ""},
+	}
+	for _, tt := range tests {
+		name, tag := SplitTag(tt.image)
+		assert.Equal(t, tt.name, name, tt.image)
+		assert.Equal(t, tt.tag, tag, tt.image)
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tags := []string{"3.17", "3.18", "3.19", "3.20", "3.9", "3.20.1", "3.21-slim", "4.0", "latest", "edge", "20240101", "3.19"}
+	assert.Equal(t, []string{"4.0", "3.20", "3.19"}, Newer("3.18", tags))
+	assert.Equal(t, []string{"3.21-slim"}, Newer("3.18-slim", tags))
+	assert.Empty(t, Newer("4.0", tags))
+	assert.Empty(t, Newer("latest", tags))
+
+	assert.Equal(t, []string{"v1.10.0", "v1.2.0"}, Newer("v1.1.0", []string{"1.9.0", "v1.2.0", "v1.10.0", "v1.0.0"}))
+}
+
+func TestSameMajor(t *testing.T) {
+	assert.Equal(t, "3.20", SameMajor("3.18", []string{"4.0", "3.20", "3.19"}))
+	assert.Empty(t, SameMajor("18", []string{"22", "20"}))
+}
+
+func TestIsVersion(t *testing.T) {
+	for _, tag := range []string{"3.18", "v1.2.3", "1.25-alpine3.18", "22_bookworm"} {
+		assert.True(t, IsVersion(tag), tag)
+	}
+	for _, tag := range []string{"", "latest", "bookworm", "3.18rc1"} {
+		assert.False(t, IsVersion(tag), tag)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	froms := ParseDockerfile([]byte(dockerfile))
+	rewritten := string(Rewrite([]byte(dockerfile), froms, []Bump{
+		{Line: 3, Image: "golang:1.23-alpine"},
+		{Line: 10, Image: "alpine:3.20"},
+		{Line: 4, Image: "ignored:1.0"},
+	}))
+
+	assert.Contains(t, rewritten, "ARG GO_IMAGE=golang:1.21\n")
+	assert.Contains(t, rewritten, "FROM --platform=$BUILDPLATFORM golang:1.23-alpine AS build\n")
+	assert.Contains(t, rewritten, "FROM alpine:3.20\tAS runtime\n")
+	assert.Contains(t, rewritten, "RUN go build -o /app .\n")
+	assert.Len(t, rewritten, len(dockerfile))
+}
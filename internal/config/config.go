@@ -0,0 +1,668 @@
+// Package config holds server-wide settings for copacetic-mcp.
+// Values are read from environment variables and may be overridden by command-line flags.
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/defectdojo"
+	"github.com/project-copacetic/mcp-server/internal/dtrack"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/history"
+	"github.com/project-copacetic/mcp-server/internal/logging"
+	"github.com/project-copacetic/mcp-server/internal/otlp"
+	"github.com/project-copacetic/mcp-server/internal/registryacl"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+// Environment variables read by Load
+const (
+	// EnvDockerSockets lists additional Docker socket paths to probe, separated by the OS path list separator
+	EnvDockerSockets = "COPA_MCP_DOCKER_SOCKETS"
+	// EnvBuildkitAddr is the buildkit address passed to copa via --addr
+	EnvBuildkitAddr = "COPA_MCP_BUILDKIT_ADDR"
+	// EnvBuildkitWait is how long to wait for buildkit to become ready (e.g. 30s)
+	EnvBuildkitWait = "COPA_MCP_BUILDKIT_WAIT"
+	// EnvToolTimeout is how long a scan or patch command may run when the call does not set timeoutSeconds (e.g. 30m, 0 for no limit)
+	EnvToolTimeout = "COPA_MCP_TOOL_TIMEOUT"
+	// EnvKeepReports is the default for keeping scan reports after a report-based patch (true/false)
+	EnvKeepReports = "COPA_MCP_KEEP_REPORTS"
+	// EnvKeepVex is the default for keeping generated VEX documents after a patch (true/false)
+	EnvKeepVex = "COPA_MCP_KEEP_VEX"
+	// EnvRetryMaxAttempts is the number of attempts for a scan or patch, including the first (1 disables retries)
+	EnvRetryMaxAttempts = "COPA_MCP_RETRY_MAX_ATTEMPTS"
+	// EnvRetryBackoff is the delay before the first retry, doubled for each further retry (e.g. 5s)
+	EnvRetryBackoff = "COPA_MCP_RETRY_BACKOFF"
+	// EnvRetryCategories lists the error categories that are retried, separated by commas (e.g. network,execution)
+	EnvRetryCategories = "COPA_MCP_RETRY_CATEGORIES"
+	// EnvWebhookURLs lists webhook URLs sent a JSON event when a scan or patch finishes, separated by commas
+	EnvWebhookURLs = "COPA_MCP_WEBHOOK_URLS"
+	// EnvSlackWebhookURLs lists Slack incoming webhook URLs notified when a scan or patch finishes, separated by commas
+	EnvSlackWebhookURLs = "COPA_MCP_SLACK_WEBHOOK_URLS"
+	// EnvGitHubSummary enables writing scan and patch summaries to the GitHub Actions step summary (true/false)
+	EnvGitHubSummary = "COPA_MCP_GITHUB_SUMMARY"
+	// EnvAzurePipelines enables Azure Pipelines logging commands and summaries when running in Azure Pipelines (true/false)
+	EnvAzurePipelines = "COPA_MCP_AZURE_PIPELINES"
+	// EnvHarborURL is the Harbor base URL used to fetch existing vulnerability reports
+	EnvHarborURL = "COPA_MCP_HARBOR_URL"
+	// EnvHarborUsername is the Harbor user or robot account used to fetch vulnerability reports
+	EnvHarborUsername = "COPA_MCP_HARBOR_USERNAME"
+	// EnvHarborPassword is the password or secret of EnvHarborUsername. It has no flag, to keep it out of process listings.
+	EnvHarborPassword = "COPA_MCP_HARBOR_PASSWORD"
+	// EnvDependencyTrackURL is the Dependency-Track API server URL that SBOMs of scanned and patched images are uploaded to
+	EnvDependencyTrackURL = "COPA_MCP_DTRACK_URL"
+	// EnvDependencyTrackAPIKey is the Dependency-Track API key. It has no flag, to keep it out of process listings.
+	EnvDependencyTrackAPIKey = "COPA_MCP_DTRACK_API_KEY"
+	// EnvDependencyTrackProjects maps image reference prefixes to Dependency-Track projects, as prefix=project separated by commas
+	EnvDependencyTrackProjects = "COPA_MCP_DTRACK_PROJECTS"
+	// EnvDefectDojoURL is the DefectDojo URL that scan findings and post-patch scans are imported into
+	EnvDefectDojoURL = "COPA_MCP_DEFECTDOJO_URL"
+	// EnvDefectDojoAPIKey is the DefectDojo API v2 key. It has no flag, to keep it out of process listings.
+	EnvDefectDojoAPIKey = "COPA_MCP_DEFECTDOJO_API_KEY"
+	// EnvDefectDojoProducts maps image reference prefixes to DefectDojo products, as prefix=product[/engagement] separated by commas
+	EnvDefectDojoProducts = "COPA_MCP_DEFECTDOJO_PRODUCTS"
+	// EnvPolicies lists Rego policy files or directories gating patches, separated by the OS path list separator
+	EnvPolicies = "COPA_MCP_POLICIES"
+	// EnvAllowedRegistries lists the registries, or repository prefixes, 'evaluate-image' allows, separated by commas
+	EnvAllowedRegistries = "COPA_MCP_ALLOWED_REGISTRIES"
+	// EnvSourceAllow lists the registry patterns images may be pulled, scanned or patched from, separated by commas (e.g. registry.corp.internal/*)
+	EnvSourceAllow = "COPA_MCP_SOURCE_ALLOW"
+	// EnvSourceDeny lists the registry patterns images may not come from, separated by commas
+	EnvSourceDeny = "COPA_MCP_SOURCE_DENY"
+	// EnvPushAllow lists the registry patterns patched images may be pushed to, separated by commas
+	EnvPushAllow = "COPA_MCP_PUSH_ALLOW"
+	// EnvPushDeny lists the registry patterns patched images may not be pushed to, separated by commas (e.g. docker.io)
+	EnvPushDeny = "COPA_MCP_PUSH_DENY"
+	// EnvRequireSignature makes 'evaluate-image' deny images without a cosign or Notation signature (true/false)
+	EnvRequireSignature = "COPA_MCP_REQUIRE_SIGNATURE"
+	// EnvMaxCritical is the number of fixable CRITICAL vulnerabilities above which 'evaluate-image' denies an image
+	EnvMaxCritical = "COPA_MCP_MAX_CRITICAL"
+	// EnvVerifyKeys lists the cosign public keys (files, KMS URIs or k8s:// secrets) trusted to sign images, separated by commas
+	EnvVerifyKeys = "COPA_MCP_VERIFY_KEYS"
+	// EnvVerifyIdentity is a regular expression matching the certificate identity of trusted keyless signatures
+	EnvVerifyIdentity = "COPA_MCP_VERIFY_IDENTITY"
+	// EnvVerifyOIDCIssuer is the OIDC issuer of trusted keyless signatures (e.g. https://token.actions.githubusercontent.com)
+	EnvVerifyOIDCIssuer = "COPA_MCP_VERIFY_OIDC_ISSUER"
+	// EnvVerifyAttestation is the predicate type of an attestation that must also verify (e.g. slsaprovenance)
+	EnvVerifyAttestation = "COPA_MCP_VERIFY_ATTESTATION"
+	// EnvSignKey is the cosign private key (file, KMS URI or k8s:// secret) 'remediate' signs pushed images with
+	EnvSignKey = "COPA_MCP_SIGN_KEY"
+	// EnvGitHubToken is the GitHub token 'open-image-pr' pushes branches and opens pull requests with. It has no flag, to keep it out of process listings.
+	EnvGitHubToken = "COPA_MCP_GITHUB_TOKEN"
+	// EnvGitLabToken is the GitLab token 'open-image-pr' pushes branches and opens merge requests with. It has no flag, to keep it out of process listings.
+	EnvGitLabToken = "COPA_MCP_GITLAB_TOKEN"
+	// EnvSmokeTests allows the patch tools to run smoke test commands against patched images on the server (true/false)
+	EnvSmokeTests = "COPA_MCP_SMOKE_TESTS"
+	// EnvMock simulates copa, trivy and docker with canned scan reports and patch results (true/false)
+	EnvMock = "COPA_MCP_MOCK"
+	// EnvMaxSubprocesses is the number of copa, trivy and docker subprocesses that may run at once (0 for no limit)
+	EnvMaxSubprocesses = "COPA_MCP_MAX_SUBPROCESSES"
+	// EnvScanConcurrency is the number of platforms of a multi-platform image scanned at once (1 scans them one after another)
+	EnvScanConcurrency = "COPA_MCP_SCAN_CONCURRENCY"
+	// EnvSubprocessEnv lists KEY=value variables set in the environment of copa, trivy, docker and ctr subprocesses, separated by commas (e.g. TRIVY_CACHE_DIR=/cache,NO_PROXY=localhost,.internal)
+	EnvSubprocessEnv = "COPA_MCP_SUBPROCESS_ENV"
+	// EnvTempDir is the directory holding scan reports, VEX documents and working directories (default copa-mcp in the system temporary directory)
+	EnvTempDir = "COPA_MCP_TEMP_DIR"
+	// EnvTempQuotaMB is the size in MB of EnvTempDir above which scans and patches fail instead of writing more (0 for no quota)
+	EnvTempQuotaMB = "COPA_MCP_TEMP_QUOTA_MB"
+	// EnvTempMaxAge is the age (e.g. 24h) above which entries of EnvTempDir are removed when the server starts (0 keeps them)
+	EnvTempMaxAge = "COPA_MCP_TEMP_MAX_AGE"
+	// EnvBinDir is the directory 'install-dependencies' installs copa and trivy to, put first on the PATH (default copa-mcp/bin in the user cache directory)
+	EnvBinDir = "COPA_MCP_BIN_DIR"
+	// EnvCopaPath is the copa binary the server runs (default copa on the PATH)
+	EnvCopaPath = "COPA_MCP_COPA_PATH"
+	// EnvTrivyPath is the trivy binary the server runs (default trivy on the PATH)
+	EnvTrivyPath = "COPA_MCP_TRIVY_PATH"
+	// EnvTrivySkipDBUpdate makes trivy scan with its cached vulnerability database instead of updating it (true/false)
+	EnvTrivySkipDBUpdate = "COPA_MCP_TRIVY_SKIP_DB_UPDATE"
+	// EnvTrivyOfflineScan makes trivy scan without network requests besides the database download (true/false)
+	EnvTrivyOfflineScan = "COPA_MCP_TRIVY_OFFLINE_SCAN"
+	// EnvTrivyDBRepository is the OCI repository trivy downloads its vulnerability database from (e.g. registry.corp.internal/aquasec/trivy-db:2)
+	EnvTrivyDBRepository = "COPA_MCP_TRIVY_DB_REPOSITORY"
+	// EnvLogFormat is the format of the server's logs on stderr: text or json
+	EnvLogFormat = "COPA_MCP_LOG_FORMAT"
+	// EnvLogLevel is the lowest level of the server's logs on stderr: debug, info, warn or error
+	EnvLogLevel = "COPA_MCP_LOG_LEVEL"
+	// EnvOTLPEndpoint is the OTLP/HTTP endpoint of an OpenTelemetry collector the server's logs are exported to (e.g. http://otel-collector:4318)
+	EnvOTLPEndpoint = "COPA_MCP_OTLP_ENDPOINT"
+	// EnvOTLPHeaders lists headers sent with each log export, as key=value separated by commas. It has no flag, to keep credentials out of process listings.
+	EnvOTLPHeaders = "COPA_MCP_OTLP_HEADERS"
+	// EnvScheduleFile is the path of a JSON file of recurring scan jobs run by the scheduler
+	EnvScheduleFile = "COPA_MCP_SCHEDULE_FILE"
+	// EnvTranscript is the path of a JSON Lines file every tool call and its result are appended to, redacted, for 'replay'
+	EnvTranscript = "COPA_MCP_TRANSCRIPT"
+	// EnvHistory is the path of the JSON Lines file completed patches are recorded to, for 'patch-history' (default copa-mcp/history.jsonl in $XDG_STATE_HOME, empty disables it)
+	EnvHistory = "COPA_MCP_HISTORY"
+	// EnvAPITokens lists the API tokens the http command accepts, as scope:token separated by commas (e.g. scan:abc123)
+	EnvAPITokens = "COPA_MCP_API_TOKENS"
+	// EnvAPITokensFile is the path of a file of API tokens the http command accepts, one scope:token per line
+	EnvAPITokensFile = "COPA_MCP_API_TOKENS_FILE"
+	// EnvPprofToken is the bearer token the http command serves the pprof profiles at /debug/pprof/ with, empty to not serve them
+	EnvPprofToken = "COPA_MCP_PPROF_TOKEN"
+)
+
+// Defaults applied by Load
+const (
+	DefaultBuildkitWait    = 30 * time.Second
+	DefaultKeepReports     = true
+	DefaultKeepVex         = true
+	DefaultAzurePipelines  = true
+	DefaultMaxCritical     = -1
+	DefaultMaxSubprocesses = 4
+	DefaultScanConcurrency = 4
+	DefaultTempMaxAge      = 24 * time.Hour
+	DefaultLogFormat       = "text"
+	DefaultLogLevel        = "info"
+)
+
+// Config holds server-wide settings
+type Config struct {
+	// DockerSockets are socket paths probed, in order, before the built-in
+	// candidates when DOCKER_HOST is unset and the default socket is absent
+	DockerSockets []string
+
+	// BuildkitAddr is the address of a buildkit instance used by copa
+	// (e.g. tcp://buildkitd:1234, docker-container://buildkitd). Empty uses the Docker daemon's buildkit.
+	BuildkitAddr string
+
+	// BuildkitWait is the maximum time to wait for BuildkitAddr to accept connections before patching
+	BuildkitWait time.Duration
+
+	// ToolTimeout is how long the trivy or copa command of a scan or patch may run before it is
+	// stopped, when the call does not set timeoutSeconds. 0 sets no limit.
+	ToolTimeout time.Duration
+
+	// KeepReports is the default for whether scan reports created by 'scan-container'
+	// are kept after a report-based patch, when the call does not set keepReport
+	KeepReports bool
+
+	// KeepVex is the default for whether generated VEX documents are kept after a patch,
+	// when the call does not set keepVex
+	KeepVex bool
+
+	// Retry is the default retry policy for scans and patches, when the call does not override it
+	Retry copaerrors.RetryPolicy
+
+	// WebhookURLs are sent the outcome and structured result of each finished scan or patch as JSON
+	WebhookURLs []string
+
+	// SlackWebhookURLs are Slack incoming webhooks sent a summary of each finished scan or patch
+	SlackWebhookURLs []string
+
+	// GitHubSummary appends a Markdown summary of each scan and patch to the file named by
+	// GITHUB_STEP_SUMMARY, when running as a GitHub Actions step
+	GitHubSummary bool
+
+	// AzurePipelines reports each scan and patch to Azure Pipelines, when running in a pipeline:
+	// findings and failures as issues of the run and a Markdown summary attached to the run
+	AzurePipelines bool
+
+	// HarborURL is the Harbor base URL for 'fetch-harbor-report'. Empty derives it from the image's registry host.
+	HarborURL string
+
+	// HarborUsername and HarborPassword authenticate to Harbor, e.g. as a robot account. Empty uses anonymous access.
+	HarborUsername string
+	HarborPassword string
+
+	// DependencyTrackURL is the Dependency-Track API server that an SBOM of each scanned and patched
+	// image is uploaded to. Empty disables uploads.
+	DependencyTrackURL string
+
+	// DependencyTrackAPIKey authenticates the uploads; its team needs the BOM_UPLOAD and PROJECT_CREATION_UPLOAD permissions
+	DependencyTrackAPIKey string
+
+	// DependencyTrackProjects map image reference prefixes to project names, as prefix=project.
+	// Images without a mapping use their repository as the project name.
+	DependencyTrackProjects []string
+
+	// DefectDojoURL is the DefectDojo server that the findings of each scan, and a scan of each
+	// patched image, are imported into. Empty disables imports.
+	DefectDojoURL string
+
+	// DefectDojoAPIKey authenticates the imports
+	DefectDojoAPIKey string
+
+	// DefectDojoProducts map image reference prefixes to products and engagements, as
+	// prefix=product or prefix=product/engagement. Images without a mapping use their repository as the product.
+	DefectDojoProducts []string
+
+	// Policies are Rego files or directories evaluated with the opa CLI before each patch. Their
+	// data.copa.patch decision can deny the patch, force a dry run or require the user's confirmation.
+	Policies []string
+
+	// AllowedRegistries are the registry hosts or repository prefixes 'evaluate-image' allows by
+	// default. Empty allows every registry.
+	AllowedRegistries []string
+
+	// Registries restrict the registries images are pulled, scanned and patched from, and the
+	// registries patched images are pushed to, for every tool call
+	Registries registryacl.Rules
+
+	// RequireSignature makes 'evaluate-image' deny unsigned images by default
+	RequireSignature bool
+
+	// MaxCritical is the default number of fixable CRITICAL vulnerabilities 'evaluate-image' allows.
+	// Negative disables the check.
+	MaxCritical int
+
+	// VerifyKeys are the cosign public keys trusted to sign images. With VerifyKeys or VerifyIdentity
+	// set, images are verified with the cosign CLI before they are pulled, scanned or patched.
+	VerifyKeys []string
+
+	// VerifyIdentity is a regular expression matching the certificate identity of trusted keyless
+	// signatures, issued by VerifyOIDCIssuer
+	VerifyIdentity   string
+	VerifyOIDCIssuer string
+
+	// VerifyAttestation is the predicate type of an attestation that must also verify, by the same signer
+	VerifyAttestation string
+
+	// SignKey is the cosign private key 'remediate' signs the patched images it pushes with, when
+	// asked to. cosign reads the password of an encrypted key from COSIGN_PASSWORD. Empty disables signing.
+	SignKey string
+
+	// GitHubToken and GitLabToken authenticate 'open-image-pr' to push bump branches and open pull
+	// requests; they need write access to the repositories' contents and pull requests. Empty limits
+	// the tool to dry runs against public repositories.
+	GitHubToken string
+	GitLabToken string
+
+	// SmokeTests allows the smokeTest commands of the patch tools. They run on the server with its
+	// permissions and its Docker daemon, so they are disabled by default.
+	SmokeTests bool
+
+	// Mock replaces copa, trivy and docker with stubs returning canned scan reports and patch
+	// results, to try the server, or test MCP hosts against it, on machines without them
+	Mock bool
+
+	// MaxSubprocesses limits the copa patches, trivy scans and docker pulls, pushes and saves running at
+	// once across all tool calls; further ones wait for a free slot. 0 removes the limit.
+	MaxSubprocesses int
+
+	// ScanConcurrency is the number of platforms of a multi-platform image scanned at once. The
+	// scans also wait for subprocess slots, so MaxSubprocesses caps them too.
+	ScanConcurrency int
+
+	// SubprocessEnv are KEY=value variables set in the environment of the copa, trivy, docker and
+	// ctr subprocesses, over the ones inherited from the server, e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG
+	// or proxy settings. A tool call can override their values, but not set other variables.
+	SubprocessEnv []string
+
+	// TempDir holds the scan reports, VEX documents and working directories the server creates.
+	// Empty uses a copa-mcp directory in the system temporary directory.
+	TempDir string
+
+	// TempQuotaMB bounds the size of TempDir: once it is reached, scans and patches fail instead of
+	// creating more reports and working directories. 0 removes the quota.
+	TempQuotaMB int
+
+	// TempMaxAge is the age above which the entries of TempDir, such as the reports and working
+	// directories left behind by crashed runs, are removed when the server starts. 0 keeps them.
+	TempMaxAge time.Duration
+
+	// BinDir is the managed bin directory 'install-dependencies' installs the pinned copa and trivy
+	// releases to. When it exists, it is put first on the PATH at startup. Empty uses copa-mcp/bin
+	// in the user cache directory.
+	BinDir string
+
+	// CopaPath and TrivyPath are the copa and trivy binaries the server runs, instead of those
+	// found on the PATH. Empty looks them up on the PATH.
+	CopaPath  string
+	TrivyPath string
+
+	// TrivySkipDBUpdate, TrivyOfflineScan and TrivyDBRepository control how trivy gets its
+	// vulnerability database, for air-gapped environments: scan with the cached database, make
+	// no other network requests, or download the database from a mirror. A scan-container call
+	// can override them.
+	TrivySkipDBUpdate bool
+	TrivyOfflineScan  bool
+	TrivyDBRepository string
+
+	// LogFormat is the format of the server's logs on stderr, text or json (one JSON object per
+	// record, for log aggregators)
+	LogFormat string
+
+	// LogLevel is the lowest level of the logs written to stderr: debug, info, warn or error. The
+	// messages of tool calls are sent to clients as MCP logging notifications whatever the level.
+	LogLevel string
+
+	// OTLPEndpoint is the OTLP/HTTP endpoint of an OpenTelemetry collector that the server's logs,
+	// and the log events of its tool calls, are exported to. Empty disables the export.
+	OTLPEndpoint string
+
+	// OTLPHeaders are sent with each export as key=value, e.g. to authenticate to the collector
+	OTLPHeaders []string
+
+	// ScheduleFile is a JSON file of images to scan, and optionally patch, on cron-like schedules.
+	// Empty disables the scheduler.
+	ScheduleFile string
+
+	// Transcript is a JSON Lines file every tool call and its result are appended to, with
+	// credentials redacted, to attach to bug reports or replay. Empty disables recording.
+	Transcript string
+
+	// History is a JSON Lines file every completed patch is appended to, for 'patch-history' and
+	// the history command. Load defaults it to history.DefaultPath; empty disables recording.
+	History string
+
+	// APITokens and the tokens of APITokensFile, as scope:token, are the bearer tokens clients of
+	// the http command authenticate with; the scope (scan, patch-no-push or full) limits the tools
+	// a token may call. Without any, the http command only listens on loopback addresses.
+	APITokens     []string
+	APITokensFile string
+
+	// PprofToken is the bearer token the http command serves the pprof profiles at /debug/pprof/
+	// with. Empty does not serve them.
+	PprofToken string
+}
+
+// Default returns the configuration used when no environment variables or flags are set
+func Default() *Config {
+	return &Config{
+		BuildkitWait:    DefaultBuildkitWait,
+		KeepReports:     DefaultKeepReports,
+		KeepVex:         DefaultKeepVex,
+		Retry:           copaerrors.DefaultRetryPolicy,
+		AzurePipelines:  DefaultAzurePipelines,
+		MaxCritical:     DefaultMaxCritical,
+		MaxSubprocesses: DefaultMaxSubprocesses,
+		ScanConcurrency: DefaultScanConcurrency,
+		TempMaxAge:      DefaultTempMaxAge,
+		LogFormat:       DefaultLogFormat,
+		LogLevel:        DefaultLogLevel,
+	}
+}
+
+// Load reads the configuration from the environment
+func Load() (*Config, error) {
+	cfg := Default()
+	cfg.DockerSockets = splitList(os.Getenv(EnvDockerSockets))
+	cfg.BuildkitAddr = os.Getenv(EnvBuildkitAddr)
+	cfg.ScheduleFile = os.Getenv(EnvScheduleFile)
+	cfg.Transcript = os.Getenv(EnvTranscript)
+	if path, ok := os.LookupEnv(EnvHistory); ok {
+		cfg.History = path
+	} else if path, err := history.DefaultPath(); err == nil {
+		cfg.History = path
+	}
+	cfg.TempDir = os.Getenv(EnvTempDir)
+	cfg.SubprocessEnv = splitEnvList(os.Getenv(EnvSubprocessEnv))
+	cfg.BinDir = os.Getenv(EnvBinDir)
+	cfg.CopaPath = os.Getenv(EnvCopaPath)
+	cfg.TrivyPath = os.Getenv(EnvTrivyPath)
+	cfg.TrivyDBRepository = os.Getenv(EnvTrivyDBRepository)
+	cfg.Policies = splitList(os.Getenv(EnvPolicies))
+	cfg.AllowedRegistries = splitCommaList(os.Getenv(EnvAllowedRegistries))
+	cfg.Registries.SourceAllow = splitCommaList(os.Getenv(EnvSourceAllow))
+	cfg.Registries.SourceDeny = splitCommaList(os.Getenv(EnvSourceDeny))
+	cfg.Registries.PushAllow = splitCommaList(os.Getenv(EnvPushAllow))
+	cfg.Registries.PushDeny = splitCommaList(os.Getenv(EnvPushDeny))
+	cfg.VerifyKeys = splitCommaList(os.Getenv(EnvVerifyKeys))
+	cfg.VerifyIdentity = os.Getenv(EnvVerifyIdentity)
+	cfg.VerifyOIDCIssuer = os.Getenv(EnvVerifyOIDCIssuer)
+	cfg.VerifyAttestation = os.Getenv(EnvVerifyAttestation)
+	cfg.SignKey = os.Getenv(EnvSignKey)
+	cfg.HarborURL = os.Getenv(EnvHarborURL)
+	cfg.HarborUsername = os.Getenv(EnvHarborUsername)
+	cfg.HarborPassword = os.Getenv(EnvHarborPassword)
+	cfg.DependencyTrackURL = os.Getenv(EnvDependencyTrackURL)
+	cfg.DependencyTrackAPIKey = os.Getenv(EnvDependencyTrackAPIKey)
+	cfg.DependencyTrackProjects = splitCommaList(os.Getenv(EnvDependencyTrackProjects))
+	cfg.DefectDojoURL = os.Getenv(EnvDefectDojoURL)
+	cfg.DefectDojoAPIKey = os.Getenv(EnvDefectDojoAPIKey)
+	cfg.DefectDojoProducts = splitCommaList(os.Getenv(EnvDefectDojoProducts))
+	cfg.GitHubToken = os.Getenv(EnvGitHubToken)
+	cfg.GitLabToken = os.Getenv(EnvGitLabToken)
+	cfg.WebhookURLs = splitCommaList(os.Getenv(EnvWebhookURLs))
+	cfg.SlackWebhookURLs = splitCommaList(os.Getenv(EnvSlackWebhookURLs))
+	if format := os.Getenv(EnvLogFormat); format != "" {
+		cfg.LogFormat = format
+	}
+	if level := os.Getenv(EnvLogLevel); level != "" {
+		cfg.LogLevel = level
+	}
+	cfg.OTLPEndpoint = os.Getenv(EnvOTLPEndpoint)
+	cfg.OTLPHeaders = splitCommaList(os.Getenv(EnvOTLPHeaders))
+	cfg.APITokens = splitCommaList(os.Getenv(EnvAPITokens))
+	cfg.APITokensFile = os.Getenv(EnvAPITokensFile)
+	cfg.PprofToken = os.Getenv(EnvPprofToken)
+
+	var err error
+	if _, err = dtrack.ParseProjects(cfg.DependencyTrackProjects); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvDependencyTrackProjects, err)
+	}
+	if _, err = defectdojo.ParseMappings(cfg.DefectDojoProducts); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvDefectDojoProducts, err)
+	}
+	if cfg.BuildkitWait, err = durationFromEnv(EnvBuildkitWait, DefaultBuildkitWait); err != nil {
+		return nil, err
+	}
+	if cfg.ToolTimeout, err = durationFromEnv(EnvToolTimeout, 0); err != nil {
+		return nil, err
+	}
+	if cfg.KeepReports, err = boolFromEnv(EnvKeepReports, DefaultKeepReports); err != nil {
+		return nil, err
+	}
+	if cfg.KeepVex, err = boolFromEnv(EnvKeepVex, DefaultKeepVex); err != nil {
+		return nil, err
+	}
+	if cfg.GitHubSummary, err = boolFromEnv(EnvGitHubSummary, false); err != nil {
+		return nil, err
+	}
+	if cfg.AzurePipelines, err = boolFromEnv(EnvAzurePipelines, DefaultAzurePipelines); err != nil {
+		return nil, err
+	}
+	if cfg.RequireSignature, err = boolFromEnv(EnvRequireSignature, false); err != nil {
+		return nil, err
+	}
+	if cfg.SmokeTests, err = boolFromEnv(EnvSmokeTests, false); err != nil {
+		return nil, err
+	}
+	if cfg.Mock, err = boolFromEnv(EnvMock, false); err != nil {
+		return nil, err
+	}
+	if cfg.TrivySkipDBUpdate, err = boolFromEnv(EnvTrivySkipDBUpdate, false); err != nil {
+		return nil, err
+	}
+	if cfg.TrivyOfflineScan, err = boolFromEnv(EnvTrivyOfflineScan, false); err != nil {
+		return nil, err
+	}
+	if cfg.MaxSubprocesses, err = intFromEnv(EnvMaxSubprocesses, DefaultMaxSubprocesses, 0); err != nil {
+		return nil, err
+	}
+	if cfg.ScanConcurrency, err = intFromEnv(EnvScanConcurrency, DefaultScanConcurrency, 1); err != nil {
+		return nil, err
+	}
+	if cfg.TempQuotaMB, err = intFromEnv(EnvTempQuotaMB, 0, 0); err != nil {
+		return nil, err
+	}
+	if cfg.TempMaxAge, err = durationFromEnv(EnvTempMaxAge, DefaultTempMaxAge); err != nil {
+		return nil, err
+	}
+	if cfg.MaxCritical, err = intFromEnv(EnvMaxCritical, DefaultMaxCritical, 0); err != nil {
+		return nil, err
+	}
+	if cfg.Retry.MaxAttempts, err = intFromEnv(EnvRetryMaxAttempts, copaerrors.DefaultRetryPolicy.MaxAttempts, 1); err != nil {
+		return nil, err
+	}
+	if cfg.Retry.Backoff, err = durationFromEnv(EnvRetryBackoff, copaerrors.DefaultRetryPolicy.Backoff); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv(EnvRetryCategories); value != "" {
+		if cfg.Retry.Retryable, err = copaerrors.ParseCategories(value); err != nil {
+			return nil, fmt.Errorf("invalid %s=%q: %w", EnvRetryCategories, value, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Validate checks settings that may also be set by flags after Load
+func (c *Config) Validate() error {
+	if _, err := dtrack.ParseProjects(c.DependencyTrackProjects); err != nil {
+		return err
+	}
+	if _, err := defectdojo.ParseMappings(c.DefectDojoProducts); err != nil {
+		return err
+	}
+	for _, path := range c.Policies {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid policy path: %w", err)
+		}
+	}
+	if err := c.Registries.Validate(); err != nil {
+		return fmt.Errorf("invalid registry rules: %w", err)
+	}
+	if (c.VerifyIdentity == "") != (c.VerifyOIDCIssuer == "") {
+		return fmt.Errorf("keyless verification needs both an identity (%s) and an OIDC issuer (%s)", EnvVerifyIdentity, EnvVerifyOIDCIssuer)
+	}
+	if _, err := regexp.Compile(c.VerifyIdentity); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvVerifyIdentity, err)
+	}
+	if c.MaxSubprocesses < 0 {
+		return fmt.Errorf("invalid %s=%d: must be at least 0", EnvMaxSubprocesses, c.MaxSubprocesses)
+	}
+	if c.ScanConcurrency < 1 {
+		return fmt.Errorf("invalid %s=%d: must be at least 1", EnvScanConcurrency, c.ScanConcurrency)
+	}
+	if _, err := subprocess.ParseEnv(c.SubprocessEnv); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvSubprocessEnv, err)
+	}
+	if c.TempQuotaMB < 0 {
+		return fmt.Errorf("invalid %s=%d: must be at least 0", EnvTempQuotaMB, c.TempQuotaMB)
+	}
+	if c.ToolTimeout < 0 {
+		return fmt.Errorf("invalid %s=%s: must not be negative", EnvToolTimeout, c.ToolTimeout)
+	}
+	if c.TempMaxAge < 0 {
+		return fmt.Errorf("invalid %s=%s: must not be negative", EnvTempMaxAge, c.TempMaxAge)
+	}
+	if err := logging.ValidateFormat(c.LogFormat); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvLogFormat, err)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvLogLevel, err)
+	}
+	if c.OTLPEndpoint != "" {
+		if err := otlp.ValidateEndpoint(c.OTLPEndpoint); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvOTLPEndpoint, err)
+		}
+	}
+	if _, err := otlp.ParseHeaders(c.OTLPHeaders); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvOTLPHeaders, err)
+	}
+	for env, path := range map[string]string{EnvCopaPath: c.CopaPath, EnvTrivyPath: c.TrivyPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := exec.LookPath(path); err != nil {
+			return fmt.Errorf("invalid %s: %w", env, err)
+		}
+	}
+	if err := trivy.ValidateDBRepository(c.TrivyDBRepository); err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvTrivyDBRepository, err)
+	}
+	if c.Transcript != "" {
+		if info, err := os.Stat(filepath.Dir(c.Transcript)); err != nil || !info.IsDir() {
+			return fmt.Errorf("invalid %s: directory does not exist: %s", EnvTranscript, filepath.Dir(c.Transcript))
+		}
+	}
+	if c.VerifyAttestation != "" && len(c.VerifyKeys) == 0 && c.VerifyIdentity == "" {
+		return fmt.Errorf("%s needs a key (%s) or an identity (%s) to verify the attestation with", EnvVerifyAttestation, EnvVerifyKeys, EnvVerifyIdentity)
+	}
+	return c.Retry.Validate()
+}
+
+// durationFromEnv parses a duration from the environment variable key, returning def when unset
+func durationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s=%q: %w", key, value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s=%q: must not be negative", key, value)
+	}
+	return d, nil
+}
+
+// boolFromEnv parses a boolean from the environment variable key, returning def when unset
+func boolFromEnv(key string, def bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s=%q: must be true or false", key, value)
+	}
+	return b, nil
+}
+
+// intFromEnv parses an integer of at least min from the environment variable key, returning def when unset
+func intFromEnv(key string, def, min int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s=%q: must be an integer", key, value)
+	}
+	if n < min {
+		return 0, fmt.Errorf("invalid %s=%q: must be at least %d", key, value, min)
+	}
+	return n, nil
+}
+
+// splitList splits a path list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range filepath.SplitList(value) {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitCommaList splits a comma-separated list, for values such as URLs that may contain the path list separator
+func splitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitEnvList splits comma-separated KEY=value entries. An item without '=' continues the value of
+// the previous entry, so that values such as NO_PROXY=localhost,.internal keep their commas.
+func splitEnvList(value string) []string {
+	var entries []string
+	for _, item := range strings.Split(value, ",") {
+		if len(entries) > 0 && !strings.Contains(item, "=") {
+			entries[len(entries)-1] += "," + item
+			continue
+		}
+		if item = strings.TrimSpace(item); item != "" {
+			entries = append(entries, item)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/registryacl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv(EnvDockerSockets, "")
+	t.Setenv(EnvBuildkitAddr, "")
+	t.Setenv(EnvBuildkitWait, "")
+	t.Setenv(EnvToolTimeout, "")
+	t.Setenv(EnvKeepReports, "")
+	t.Setenv(EnvKeepVex, "")
+	t.Setenv(EnvRetryMaxAttempts, "")
+	t.Setenv(EnvRetryBackoff, "")
+	t.Setenv(EnvRetryCategories, "")
+	t.Setenv(EnvWebhookURLs, "")
+	t.Setenv(EnvSlackWebhookURLs, "")
+	t.Setenv(EnvGitHubSummary, "")
+	t.Setenv(EnvAzurePipelines, "")
+	t.Setenv(EnvScheduleFile, "")
+	t.Setenv(EnvPolicies, "")
+	t.Setenv(EnvAllowedRegistries, "")
+	t.Setenv(EnvSourceAllow, "")
+	t.Setenv(EnvSourceDeny, "")
+	t.Setenv(EnvPushAllow, "")
+	t.Setenv(EnvPushDeny, "")
+	t.Setenv(EnvRequireSignature, "")
+	t.Setenv(EnvSmokeTests, "")
+	t.Setenv(EnvMock, "")
+	t.Setenv(EnvMaxSubprocesses, "")
+	t.Setenv(EnvScanConcurrency, "")
+	t.Setenv(EnvTrivySkipDBUpdate, "")
+	t.Setenv(EnvTrivyOfflineScan, "")
+	t.Setenv(EnvTrivyDBRepository, "")
+	t.Setenv(EnvSubprocessEnv, "")
+	t.Setenv(EnvTempDir, "")
+	t.Setenv(EnvTempQuotaMB, "")
+	t.Setenv(EnvTempMaxAge, "")
+	t.Setenv(EnvBinDir, "")
+	t.Setenv(EnvMaxCritical, "")
+	t.Setenv(EnvVerifyKeys, "")
+	t.Setenv(EnvVerifyIdentity, "")
+	t.Setenv(EnvVerifyOIDCIssuer, "")
+	t.Setenv(EnvVerifyAttestation, "")
+	t.Setenv(EnvSignKey, "")
+	t.Setenv(EnvHarborURL, "")
+	t.Setenv(EnvHarborUsername, "")
+	t.Setenv(EnvHarborPassword, "")
+	t.Setenv(EnvDependencyTrackURL, "")
+	t.Setenv(EnvDependencyTrackAPIKey, "")
+	t.Setenv(EnvDependencyTrackProjects, "")
+	t.Setenv(EnvDefectDojoURL, "")
+	t.Setenv(EnvDefectDojoAPIKey, "")
+	t.Setenv(EnvDefectDojoProducts, "")
+	t.Setenv(EnvGitHubToken, "")
+	t.Setenv(EnvGitLabToken, "")
+	t.Setenv(EnvOTLPEndpoint, "")
+	t.Setenv(EnvLogFormat, "")
+	t.Setenv(EnvLogLevel, "")
+	t.Setenv(EnvOTLPHeaders, "")
+	t.Setenv(EnvTranscript, "")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Empty(t, cfg.DockerSockets)
+	assert.Empty(t, cfg.BuildkitAddr)
+	assert.Equal(t, DefaultBuildkitWait, cfg.BuildkitWait)
+	assert.Zero(t, cfg.ToolTimeout)
+	assert.Equal(t, DefaultKeepReports, cfg.KeepReports)
+	assert.Equal(t, DefaultKeepVex, cfg.KeepVex)
+	assert.Equal(t, copaerrors.DefaultRetryPolicy, cfg.Retry)
+	assert.Empty(t, cfg.WebhookURLs)
+	assert.Empty(t, cfg.SlackWebhookURLs)
+	assert.False(t, cfg.GitHubSummary)
+	assert.True(t, cfg.AzurePipelines)
+	assert.Empty(t, cfg.ScheduleFile)
+	assert.Empty(t, cfg.Policies)
+	assert.Empty(t, cfg.AllowedRegistries)
+	assert.False(t, cfg.Registries.Enabled())
+	assert.False(t, cfg.RequireSignature)
+	assert.False(t, cfg.SmokeTests)
+	assert.False(t, cfg.Mock)
+	assert.Equal(t, DefaultMaxSubprocesses, cfg.MaxSubprocesses)
+	assert.Equal(t, DefaultScanConcurrency, cfg.ScanConcurrency)
+	assert.False(t, cfg.TrivySkipDBUpdate)
+	assert.False(t, cfg.TrivyOfflineScan)
+	assert.Empty(t, cfg.TrivyDBRepository)
+	assert.Empty(t, cfg.SubprocessEnv)
+	assert.Empty(t, cfg.TempDir)
+	assert.Zero(t, cfg.TempQuotaMB)
+	assert.Equal(t, DefaultTempMaxAge, cfg.TempMaxAge)
+	assert.Empty(t, cfg.BinDir)
+	assert.Equal(t, DefaultMaxCritical, cfg.MaxCritical)
+	assert.Empty(t, cfg.VerifyKeys)
+	assert.Empty(t, cfg.VerifyIdentity)
+	assert.Empty(t, cfg.VerifyOIDCIssuer)
+	assert.Empty(t, cfg.VerifyAttestation)
+	assert.Empty(t, cfg.SignKey)
+	assert.Empty(t, cfg.HarborURL)
+	assert.Empty(t, cfg.HarborUsername)
+	assert.Empty(t, cfg.HarborPassword)
+	assert.Empty(t, cfg.DependencyTrackURL)
+	assert.Empty(t, cfg.DependencyTrackProjects)
+	assert.Empty(t, cfg.DefectDojoURL)
+	assert.Empty(t, cfg.DefectDojoProducts)
+	assert.Empty(t, cfg.GitHubToken)
+	assert.Empty(t, cfg.GitLabToken)
+	assert.Empty(t, cfg.OTLPEndpoint)
+	assert.Equal(t, DefaultLogFormat, cfg.LogFormat)
+	assert.Equal(t, DefaultLogLevel, cfg.LogLevel)
+	assert.Empty(t, cfg.OTLPHeaders)
+	assert.Empty(t, cfg.Transcript)
+}
+
+func TestLoad_FromEnv(t *testing.T) {
+	sockets := strings.Join([]string{"/a/docker.sock", "", "/b/docker.sock"}, string(filepath.ListSeparator))
+	t.Setenv(EnvDockerSockets, sockets)
+	t.Setenv(EnvBuildkitAddr, "tcp://buildkitd:1234")
+	t.Setenv(EnvBuildkitWait, "2m")
+	t.Setenv(EnvToolTimeout, "45m")
+	t.Setenv(EnvKeepReports, "false")
+	t.Setenv(EnvKeepVex, "0")
+	t.Setenv(EnvRetryMaxAttempts, "5")
+	t.Setenv(EnvRetryBackoff, "10s")
+	t.Setenv(EnvRetryCategories, "network,execution")
+	t.Setenv(EnvWebhookURLs, "https://ci.example.com/hook, ,https://audit.example.com/hook")
+	t.Setenv(EnvSlackWebhookURLs, "https://hooks.slack.com/services/T0/B0/x")
+	t.Setenv(EnvGitHubSummary, "true")
+	t.Setenv(EnvAzurePipelines, "false")
+	t.Setenv(EnvScheduleFile, "/etc/copa-mcp/schedule.json")
+	t.Setenv(EnvAllowedRegistries, "registry.example.com, ghcr.io/org/")
+	t.Setenv(EnvSourceAllow, "registry.corp.internal/*, docker.io/library")
+	t.Setenv(EnvSourceDeny, "registry.corp.internal/sandbox")
+	t.Setenv(EnvPushAllow, "registry.corp.internal/*")
+	t.Setenv(EnvPushDeny, "docker.io")
+	t.Setenv(EnvRequireSignature, "true")
+	t.Setenv(EnvSmokeTests, "true")
+	t.Setenv(EnvMock, "true")
+	t.Setenv(EnvMaxSubprocesses, "0")
+	t.Setenv(EnvScanConcurrency, "1")
+	t.Setenv(EnvTrivySkipDBUpdate, "true")
+	t.Setenv(EnvTrivyOfflineScan, "true")
+	t.Setenv(EnvTrivyDBRepository, "registry.corp.internal/aquasec/trivy-db:2")
+	t.Setenv(EnvSubprocessEnv, "TRIVY_CACHE_DIR=/var/cache/trivy, HTTPS_PROXY=http://proxy:3128,NO_PROXY=localhost,.internal")
+	t.Setenv(EnvTempDir, "/var/lib/copa-mcp")
+	t.Setenv(EnvTempQuotaMB, "2048")
+	t.Setenv(EnvTempMaxAge, "0")
+	t.Setenv(EnvBinDir, "/opt/copa-mcp/bin")
+	t.Setenv(EnvMaxCritical, "0")
+	t.Setenv(EnvVerifyKeys, "/etc/copa-mcp/cosign.pub,awskms:///alias/copa")
+	t.Setenv(EnvVerifyIdentity, "^https://github.com/org/")
+	t.Setenv(EnvVerifyOIDCIssuer, "https://token.actions.githubusercontent.com")
+	t.Setenv(EnvVerifyAttestation, "slsaprovenance")
+	t.Setenv(EnvSignKey, "awskms:///alias/copa-sign")
+	t.Setenv(EnvPolicies, strings.Join([]string{"/etc/copa-mcp/policies", "/opt/push.rego"}, string(os.PathListSeparator)))
+	t.Setenv(EnvHarborURL, "https://harbor.example.com")
+	t.Setenv(EnvHarborUsername, "robot$copa")
+	t.Setenv(EnvHarborPassword, "secret")
+	t.Setenv(EnvDependencyTrackURL, "https://dtrack.example.com")
+	t.Setenv(EnvDependencyTrackAPIKey, "odt_key")
+	t.Setenv(EnvDependencyTrackProjects, "ghcr.io/org/api=api-service, ghcr.io/org/=platform")
+	t.Setenv(EnvDefectDojoURL, "https://defectdojo.example.com")
+	t.Setenv(EnvDefectDojoAPIKey, "dojo_key")
+	t.Setenv(EnvDefectDojoProducts, "ghcr.io/org/api=API/Container patching")
+	t.Setenv(EnvGitHubToken, "ghp_token")
+	t.Setenv(EnvGitLabToken, "glpat_token")
+	t.Setenv(EnvOTLPEndpoint, "http://otel-collector:4318")
+	t.Setenv(EnvLogFormat, "json")
+	t.Setenv(EnvAPITokens, "scan:abc, full:def")
+	t.Setenv(EnvPprofToken, "ghi")
+	t.Setenv(EnvLogLevel, "debug")
+	t.Setenv(EnvOTLPHeaders, "Authorization=Bearer abc, X-Scope-OrgID=team")
+	t.Setenv(EnvTranscript, "/var/log/copa-mcp/transcript.jsonl")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/a/docker.sock", "/b/docker.sock"}, cfg.DockerSockets)
+	assert.Equal(t, "tcp://buildkitd:1234", cfg.BuildkitAddr)
+	assert.Equal(t, 2*time.Minute, cfg.BuildkitWait)
+	assert.Equal(t, 45*time.Minute, cfg.ToolTimeout)
+	assert.False(t, cfg.KeepReports)
+	assert.False(t, cfg.KeepVex)
+	assert.Equal(t, copaerrors.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     10 * time.Second,
+		Retryable:   []copaerrors.Category{copaerrors.CategoryNetwork, copaerrors.CategoryExecution},
+	}, cfg.Retry)
+	assert.Equal(t, []string{"https://ci.example.com/hook", "https://audit.example.com/hook"}, cfg.WebhookURLs)
+	assert.Equal(t, []string{"https://hooks.slack.com/services/T0/B0/x"}, cfg.SlackWebhookURLs)
+	assert.True(t, cfg.GitHubSummary)
+	assert.False(t, cfg.AzurePipelines)
+	assert.Equal(t, "/etc/copa-mcp/schedule.json", cfg.ScheduleFile)
+	assert.Equal(t, []string{"/etc/copa-mcp/policies", "/opt/push.rego"}, cfg.Policies)
+	assert.Equal(t, []string{"registry.example.com", "ghcr.io/org/"}, cfg.AllowedRegistries)
+	assert.Equal(t, registryacl.Rules{
+		SourceAllow: []string{"registry.corp.internal/*", "docker.io/library"},
+		SourceDeny:  []string{"registry.corp.internal/sandbox"},
+		PushAllow:   []string{"registry.corp.internal/*"},
+		PushDeny:    []string{"docker.io"},
+	}, cfg.Registries)
+	assert.True(t, cfg.RequireSignature)
+	assert.True(t, cfg.SmokeTests)
+	assert.True(t, cfg.Mock)
+	assert.Equal(t, 0, cfg.MaxSubprocesses)
+	assert.Equal(t, 1, cfg.ScanConcurrency)
+	assert.True(t, cfg.TrivySkipDBUpdate)
+	assert.True(t, cfg.TrivyOfflineScan)
+	assert.Equal(t, "registry.corp.internal/aquasec/trivy-db:2", cfg.TrivyDBRepository)
+	assert.Equal(t, []string{"TRIVY_CACHE_DIR=/var/cache/trivy", "HTTPS_PROXY=http://proxy:3128", "NO_PROXY=localhost,.internal"}, cfg.SubprocessEnv)
+	assert.Equal(t, "/var/lib/copa-mcp", cfg.TempDir)
+	assert.Equal(t, 2048, cfg.TempQuotaMB)
+	assert.Zero(t, cfg.TempMaxAge)
+	assert.Equal(t, "/opt/copa-mcp/bin", cfg.BinDir)
+	assert.Equal(t, 0, cfg.MaxCritical)
+	assert.Equal(t, []string{"/etc/copa-mcp/cosign.pub", "awskms:///alias/copa"}, cfg.VerifyKeys)
+	assert.Equal(t, "^https://github.com/org/", cfg.VerifyIdentity)
+	assert.Equal(t, "https://token.actions.githubusercontent.com", cfg.VerifyOIDCIssuer)
+	assert.Equal(t, "slsaprovenance", cfg.VerifyAttestation)
+	assert.Equal(t, "awskms:///alias/copa-sign", cfg.SignKey)
+	assert.Equal(t, "https://harbor.example.com", cfg.HarborURL)
+	assert.Equal(t, "robot$copa", cfg.HarborUsername)
+	assert.Equal(t, "secret", cfg.HarborPassword)
+	assert.Equal(t, "https://dtrack.example.com", cfg.DependencyTrackURL)
+	assert.Equal(t, "odt_key", cfg.DependencyTrackAPIKey)
+	assert.Equal(t, []string{"ghcr.io/org/api=api-service", "ghcr.io/org/=platform"}, cfg.DependencyTrackProjects)
+	assert.Equal(t, "https://defectdojo.example.com", cfg.DefectDojoURL)
+	assert.Equal(t, "dojo_key", cfg.DefectDojoAPIKey)
+	assert.Equal(t, []string{"ghcr.io/org/api=API/Container patching"}, cfg.DefectDojoProducts)
+	assert.Equal(t, "ghp_token", cfg.GitHubToken)
+	assert.Equal(t, "glpat_token", cfg.GitLabToken)
+	assert.Equal(t, "http://otel-collector:4318", cfg.OTLPEndpoint)
+	assert.Equal(t, "json", cfg.LogFormat)
+	assert.Equal(t, []string{"scan:abc", "full:def"}, cfg.APITokens)
+	assert.Equal(t, "ghi", cfg.PprofToken)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, []string{"Authorization=Bearer abc", "X-Scope-OrgID=team"}, cfg.OTLPHeaders)
+	assert.Equal(t, "/var/log/copa-mcp/transcript.jsonl", cfg.Transcript)
+}
+
+func TestLoad_History(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	t.Setenv(EnvHistory, "")
+	require.NoError(t, os.Unsetenv(EnvHistory))
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(state, "copa-mcp", "history.jsonl"), cfg.History)
+
+	t.Setenv(EnvHistory, "")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.History, "an empty value disables the history")
+
+	t.Setenv(EnvHistory, "/var/lib/copa-mcp/history.jsonl")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/copa-mcp/history.jsonl", cfg.History)
+}
+
+func TestLoad_InvalidDuration(t *testing.T) {
+	for _, value := range []string{"soon", "-5s"} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv(EnvBuildkitWait, value)
+
+			_, err := Load()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), EnvBuildkitWait)
+		})
+	}
+}
+
+func TestLoad_InvalidDependencyTrackProjects(t *testing.T) {
+	t.Setenv(EnvDependencyTrackProjects, "ghcr.io/org/api")
+
+	_, err := Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), EnvDependencyTrackProjects)
+}
+
+func TestLoad_InvalidDefectDojoProducts(t *testing.T) {
+	t.Setenv(EnvDefectDojoProducts, "ghcr.io/org/api=")
+
+	_, err := Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), EnvDefectDojoProducts)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.DependencyTrackProjects = []string{"=platform"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.DefectDojoProducts = []string{"ghcr.io/org"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.MaxSubprocesses = -1
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.ScanConcurrency = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.TrivyDBRepository = "mirror.example.com/trivy-db:2 --insecure"
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.TempQuotaMB = -1
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.ToolTimeout = -time.Second
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.SubprocessEnv = []string{"TRIVY_CACHE_DIR=/cache", "NO_PROXY"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.Policies = []string{t.TempDir()}
+	assert.NoError(t, cfg.Validate())
+	cfg.Policies = append(cfg.Policies, filepath.Join(t.TempDir(), "missing.rego"))
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.CopaPath = filepath.Join(t.TempDir(), "copa")
+	assert.ErrorContains(t, cfg.Validate(), EnvCopaPath)
+
+	cfg = Default()
+	cfg.Registries.PushDeny = []string{"docker.io/[library"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.VerifyIdentity = "^https://github.com/org/"
+	assert.Error(t, cfg.Validate())
+	cfg.VerifyOIDCIssuer = "https://token.actions.githubusercontent.com"
+	assert.NoError(t, cfg.Validate())
+	cfg.VerifyIdentity = "(unclosed"
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.VerifyAttestation = "slsaprovenance"
+	assert.Error(t, cfg.Validate())
+	cfg.VerifyKeys = []string{"cosign.pub"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.OTLPEndpoint = "otel-collector:4318"
+	assert.Error(t, cfg.Validate())
+	cfg.OTLPEndpoint = "http://otel-collector:4318"
+	assert.NoError(t, cfg.Validate())
+	cfg.OTLPHeaders = []string{"Authorization"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.LogFormat = "logfmt"
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.LogLevel = "verbose"
+	assert.Error(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.Transcript = filepath.Join(t.TempDir(), "missing", "transcript.jsonl")
+	assert.Error(t, cfg.Validate())
+	cfg.Transcript = filepath.Join(t.TempDir(), "transcript.jsonl")
+	assert.NoError(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.Retry.MaxAttempts = 0
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoad_InvalidBool(t *testing.T) {
+	t.Setenv(EnvKeepVex, "sometimes")
+
+	_, err := Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), EnvKeepVex)
+}
+
+func TestLoad_InvalidRetry(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{EnvRetryMaxAttempts, "0"},
+		{EnvRetryMaxAttempts, "many"},
+		{EnvRetryBackoff, "-1s"},
+		{EnvRetryCategories, "network,flaky"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+
+			_, err := Load()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.key)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package containerd moves images between a containerd namespace and the Docker daemon copa
+// patches in, with the ctr CLI, so that a server running as a node agent can patch the images the
+// kubelet already pulled into the k8s.io namespace without a registry round trip. ctr connects to
+// the containerd socket at CONTAINERD_ADDRESS, /run/containerd/containerd.sock by default.
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// KubernetesNamespace is the namespace the kubelet's CRI plugin stores images in
+const KubernetesNamespace = "k8s.io"
+
+// namespaceRegexp matches a containerd namespace, as validated by containerd itself
+var namespaceRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(?:[._-][A-Za-z0-9]+)*$`)
+
+// ValidateNamespace checks that namespace is empty or a valid containerd namespace
+func ValidateNamespace(namespace string) error {
+	if namespace == "" || namespaceRegexp.MatchString(namespace) {
+		return nil
+	}
+	return copaerrors.NewValidationError(fmt.Sprintf("invalid containerd namespace: %s", namespace), nil,
+		fmt.Sprintf("containerd namespaces are alphanumeric, separated by '.', '_' or '-', e.g. %s", KubernetesNamespace))
+}
+
+// Normalize returns the fully qualified form containerd stores image under, e.g.
+// docker.io/library/nginx:1.25 for nginx:1.25. A reference without a tag or digest gets latest.
+func Normalize(image string) string {
+	name, rest := image, ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, rest = name[:i], name[i:]+rest
+	} else if rest == "" {
+		rest = ":latest"
+	}
+
+	domain, path, found := strings.Cut(name, "/")
+	switch {
+	case !found:
+		name = "docker.io/library/" + name
+	case !strings.ContainsAny(domain, ".:") && domain != "localhost":
+		name = "docker.io/" + name
+	case domain == "index.docker.io":
+		name = "docker.io/" + path
+	}
+	if strings.HasPrefix(name, "docker.io/") && !strings.Contains(strings.TrimPrefix(name, "docker.io/"), "/") {
+		name = "docker.io/library/" + strings.TrimPrefix(name, "docker.io/")
+	}
+	return name + rest
+}
+
+// Export writes image from namespace to a tarball at path, for 'docker load'. Only the content
+// present in the namespace for the node's platform is exported.
+func Export(ctx context.Context, namespace, image, path string) error {
+	return run(ctx, fmt.Sprintf("exporting %s from containerd namespace %s failed", image, namespace),
+		"--namespace", namespace, "images", "export", path, Normalize(image))
+}
+
+// Import imports the images of the tarball at path, e.g. written by 'docker save', into namespace
+func Import(ctx context.Context, namespace, path string) error {
+	return run(ctx, fmt.Sprintf("importing into containerd namespace %s failed", namespace),
+		"--namespace", namespace, "images", "import", path)
+}
+
+func run(ctx context.Context, msg string, args ...string) error {
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, "ctr", args...)
+	cmd.Env = subprocess.Environ(ctx)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		out := strings.TrimSpace(string(output))
+		if errors.Is(err, exec.ErrNotFound) {
+			return copaerrors.NewSystemError("ctr not found", err, "install containerd's ctr CLI and make sure it is on the server's PATH")
+		}
+		return copaerrors.New(copaerrors.Classify(out, copaerrors.CategoryExecution), msg, fmt.Errorf("%w\n%s", err, out))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package containerd
+
+import (
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNamespace(t *testing.T) {
+	for _, namespace := range []string{"", "k8s.io", "moby", "team_a-b"} {
+		assert.NoError(t, ValidateNamespace(namespace), namespace)
+	}
+	for _, namespace := range []string{"k8s io", ".hidden", "a/b", "ns-"} {
+		assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(ValidateNamespace(namespace)), namespace)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	for image, want := range map[string]string{
+		"nginx":                           "docker.io/library/nginx:latest",
+		"nginx:1.25":                      "docker.io/library/nginx:1.25",
+		"bitnami/redis:7":                 "docker.io/bitnami/redis:7",
+		"docker.io/nginx:1.25":            "docker.io/library/nginx:1.25",
+		"index.docker.io/library/nginx":   "docker.io/library/nginx:latest",
+		"ghcr.io/org/app:v1":              "ghcr.io/org/app:v1",
+		"localhost:5000/app":              "localhost:5000/app:latest",
+		"localhost/app:1":                 "localhost/app:1",
+		"registry.k8s.io/pause@" + digest: "registry.k8s.io/pause@" + digest,
+		"nginx:1.25@" + digest:            "docker.io/library/nginx:1.25@" + digest,
+	} {
+		assert.Equal(t, want, Normalize(image), image)
+	}
+}
@@ -0,0 +1,76 @@
+package copa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// scanReportPrefix is the prefix of report directories created by the trivy scanner in the working directory
+const scanReportPrefix = "reports-"
+
+// WithRetention sets whether the scan report and generated VEX document are kept after the call,
+// for each of them the call's parameters did not set explicitly
+func (c *CLI) WithRetention(keepReport, keepVex bool) *CLI {
+	if c.keepReport == nil {
+		c.keepReport = &keepReport
+	}
+	if c.keepVex == nil {
+		c.keepVex = &keepVex
+	}
+	return c
+}
+
+// KeepVex reports whether the VEX document returned by Run remains on disk after Cleanup.
+// A document written to vexOutput is always kept.
+func (c *CLI) KeepVex() bool {
+	return c.vexOutput != "" || keep(c.keepVex)
+}
+
+// Cleanup removes the intermediate artifacts of a successful patch that the caller chose not to keep.
+// Only scan reports created by 'scan-container' are removed; user-provided report directories are left alone.
+// A filtered copy of the report is always removed.
+func (c *CLI) Cleanup() error {
+	if !c.KeepVex() || c.vexOutput != "" {
+		if err := c.removeVexDir(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.removeFilteredReport(); err != nil {
+		return err
+	}
+
+	if !keep(c.keepReport) && isScanReportDir(c.reportPath) {
+		if err := os.RemoveAll(c.reportPath); err != nil {
+			return fmt.Errorf("failed to remove scan report %s: %w", c.reportPath, err)
+		}
+	}
+
+	return nil
+}
+
+// removeVexDir removes the temporary directory copa wrote its VEX output to, if one was created
+func (c *CLI) removeVexDir() error {
+	if c.vexDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(c.vexDir); err != nil {
+		return fmt.Errorf("failed to remove vex directory %s: %w", c.vexDir, err)
+	}
+	c.vexDir = ""
+	return nil
+}
+
+// keep dereferences a retention setting, keeping artifacts when it was never set
+func keep(setting *bool) bool {
+	return setting == nil || *setting
+}
+
+// isScanReportDir reports whether path is a report directory created by the trivy scanner
+func isScanReportDir(path string) bool {
+	return workdir.Contains(path) && strings.HasPrefix(filepath.Base(path), scanReportPrefix)
+}
@@ -0,0 +1,94 @@
+package copa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func newScanReportDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := workdir.MkdirTemp(scanReportPrefix + "*")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestWithRetention_ParamsOverrideDefaults(t *testing.T) {
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", KeepVex: boolPtr(true)}
+
+	cli := New(params, true).WithRetention(false, false)
+
+	assert.False(t, keep(cli.keepReport))
+	assert.True(t, keep(cli.keepVex))
+	assert.True(t, cli.KeepVex())
+}
+
+func TestCleanup_RemovesUnkeptArtifacts(t *testing.T) {
+	reportDir := newScanReportDir(t)
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: reportDir}
+
+	cli := New(params, true).WithRetention(false, false).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	vexDir := cli.vexDir
+	require.NotEmpty(t, vexDir)
+
+	require.NoError(t, cli.Cleanup())
+
+	assert.NoDirExists(t, vexDir)
+	assert.NoDirExists(t, reportDir)
+}
+
+func TestCleanup_KeepsArtifacts(t *testing.T) {
+	reportDir := newScanReportDir(t)
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: reportDir}
+
+	cli := New(params, true).WithRetention(true, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { os.RemoveAll(cli.vexDir) })
+
+	require.NoError(t, cli.Cleanup())
+
+	assert.DirExists(t, cli.vexDir)
+	assert.DirExists(t, reportDir)
+}
+
+func TestCleanup_LeavesUserReportDir(t *testing.T) {
+	reportDir := t.TempDir()
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: reportDir}
+
+	cli := New(params, true).WithRetention(false, false).BuildWithReport()
+	require.NoError(t, cli.Cleanup())
+
+	assert.DirExists(t, reportDir)
+}
+
+func TestCleanup_KeepsVexOutput(t *testing.T) {
+	vexOutput := filepath.Join(t.TempDir(), "vex.json")
+	require.NoError(t, os.WriteFile(vexOutput, []byte("{}"), 0o600))
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: t.TempDir(), VexOutput: vexOutput}
+
+	cli := New(params, true).WithRetention(true, false).BuildWithReport()
+	assert.True(t, cli.KeepVex())
+	require.NoError(t, cli.Cleanup())
+
+	assert.FileExists(t, vexOutput)
+}
+
+func TestIsScanReportDir(t *testing.T) {
+	assert.True(t, isScanReportDir(filepath.Join(workdir.Root(), "reports-123")))
+	assert.False(t, isScanReportDir(filepath.Join(workdir.Root(), "vex-123")))
+	assert.False(t, isScanReportDir(filepath.Join(os.TempDir(), "reports-123")))
+	assert.False(t, isScanReportDir("/home/user/reports-123"))
+	assert.False(t, isScanReportDir(""))
+}
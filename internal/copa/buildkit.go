@@ -0,0 +1,105 @@
+package copa
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+)
+
+const (
+	buildkitProbeTimeout     = 5 * time.Second
+	buildkitInitialBackoff   = 500 * time.Millisecond
+	buildkitMaxProbeInterval = 5 * time.Second
+)
+
+// buildkitProber checks once whether buildkit at addr accepts connections
+type buildkitProber func(ctx context.Context, addr, dockerHost string) error
+
+// probeBuildkit dials tcp:// and unix:// addresses and checks that docker-container://
+// instances are running. Other schemes (e.g. kube-pod://) are assumed ready.
+func probeBuildkit(ctx context.Context, addr, dockerHost string) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("invalid buildkit address %q: %w", addr, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, buildkitProbeTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	switch u.Scheme {
+	case "tcp":
+		conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "unix":
+		conn, err := dialer.DialContext(ctx, "unix", u.Path)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "docker-container":
+		cmd := exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.State.Running}}", u.Host)
+		cmd.Env = docker.Env(ctx, dockerHost)
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to inspect buildkit container %s: %w", u.Host, err)
+		}
+		if strings.TrimSpace(string(output)) != "true" {
+			return fmt.Errorf("buildkit container %s is not running", u.Host)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// CheckBuildkit checks once whether the buildkit instance at addr is ready, as patches do before
+// starting copa
+func CheckBuildkit(ctx context.Context, addr, dockerHost string) error {
+	return probeBuildkit(ctx, addr, dockerHost)
+}
+
+// waitForBuildkit probes addr until it is ready or timeout elapses, backing off between attempts
+func waitForBuildkit(ctx context.Context, addr, dockerHost string, timeout time.Duration, probe buildkitProber) error {
+	deadline := time.Now().Add(timeout)
+	interval := buildkitInitialBackoff
+	attempt := 0
+
+	for {
+		attempt++
+		err := probe(ctx, addr, dockerHost)
+		if err == nil {
+			if attempt > 1 {
+				joblog.Logf(ctx, "info", "copa", "buildkit at %s is ready after %d attempts", addr, attempt)
+			}
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return copaerrors.NewSystemError(fmt.Sprintf("buildkit at %s is not ready after waiting %s", addr, timeout), err,
+				"ensure buildkitd is running and reachable from the server",
+				"increase the wait with --buildkit-wait if buildkitd is slow to start")
+		}
+
+		joblog.Logf(ctx, "info", "copa", "Waiting for buildkit at %s (attempt %d): %v", addr, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval = min(interval*2, buildkitMaxProbeInterval)
+	}
+}
@@ -0,0 +1,56 @@
+package copa
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeBuildkit_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := "tcp://" + listener.Addr().String()
+	assert.NoError(t, probeBuildkit(context.Background(), addr, ""))
+
+	listener.Close()
+	assert.Error(t, probeBuildkit(context.Background(), addr, ""))
+}
+
+func TestProbeBuildkit_UnknownSchemeAssumedReady(t *testing.T) {
+	assert.NoError(t, probeBuildkit(context.Background(), "kube-pod://buildkitd", ""))
+}
+
+func TestWaitForBuildkit_ReadyAfterRetries(t *testing.T) {
+	calls := 0
+	probe := func(ctx context.Context, addr, dockerHost string) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	err := waitForBuildkit(context.Background(), "tcp://buildkitd:1234", "", 10*time.Second, probe)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForBuildkit_Timeout(t *testing.T) {
+	probe := func(ctx context.Context, addr, dockerHost string) error {
+		return fmt.Errorf("connection refused")
+	}
+
+	err := waitForBuildkit(context.Background(), "tcp://buildkitd:1234", "", 0, probe)
+
+	assert.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "connection refused")
+}
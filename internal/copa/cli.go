@@ -1,37 +1,64 @@
 package copa
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/project-copacetic/mcp-server/internal/containerd"
 	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
 	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
 )
 
 const (
 	defaultVexFile = "vex.json"
+
+	// TagPattern matches a valid image tag, as opposed to a full image reference
+	TagPattern = `^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`
 )
 
+var tagRegexp = regexp.MustCompile(TagPattern)
+
 // TODO: improve error handling
 // ExecutionResult holds the result of command execution
 type ExecutionResult struct {
 	ExitCode                int
-	Output                  string
-	Error                   string
+	Output                  string // End of copa's stdout, at most 64 KiB
+	Error                   string // End of copa's stderr, at most 64 KiB
 	Duration                time.Duration
 	VexPath                 string // Only populated for report-based patching
+	PatchedImage            string
+	ExportPath              string // Only populated when the patched image was exported to a tarball
+	ContainerdNamespace     string // Only populated when the patched image was imported into a containerd namespace
 	UpdatedPackageCount     int
 	FixedVulnerabilityCount int
+	Platforms               []types.PlatformResult // Only populated when the patched platforms are known
+	Severity                *types.SeveritySummary // Only populated for report-based patching
+	Remaining               *types.RemainingVulns  // Only populated for report-based patching
+	FixedVulnerabilities    []types.FixedVulnerability
+	PeakTempDiskBytes       int64    // Peak drop in free space on the temp filesystem while copa ran
+	PatchedImageBytes       int64    // Size of the patched image in the local daemon, 0 if unknown or pushed
+	ExportedBytes           int64    // Size of the exported tarball, 0 if not exported
+	Warnings                []string // Non-fatal problems encountered while patching
+	PushPending             bool     // The patched image is in the local daemon and still has to be pushed, see WithDeferredPush
+	Command                 []string // The copa command and its arguments, run unless this was a dry run
 }
 
 // CopaSupportedPlatforms lists all platforms that Copa can patch
@@ -48,16 +75,43 @@ var CopaSupportedPlatforms = []string{
 }
 
 type CLI struct {
-	copaPath   string
-	dryRun     bool
-	image      string
-	tag        string
-	platforms  []string
-	push       bool
-	reportPath string
-	vexPath    string
-	cmd        *exec.Cmd   // Current command being built
-	dockerAuth docker.Auth // Dependency injection for docker authentication
+	copaPath          string
+	dryRun            bool
+	image             string
+	tag               string
+	platforms         []string
+	push              bool
+	destination       string                 // Repository the patched image is retagged into, empty for the source repository
+	deferPush         bool                   // Leave a pushed image in the local daemon for the caller to push, see WithDeferredPush
+	outputLog         func(line string)      // Called with each line of copa's output, see WithOutputLog
+	retry             copaerrors.RetryPolicy // Policy of the push to a destination repository, see WithRetry
+	reportPath        string
+	vexPath           string
+	dockerHost        string // Docker daemon endpoint for this call, empty to inherit DOCKER_HOST
+	buildkitAddr      string // Buildkit address passed to copa via --addr, empty for the Docker daemon
+	buildkitWait      time.Duration
+	timeout           time.Duration   // copa is stopped when it runs longer, 0 for no limit
+	exportPath        string          // Path to save the patched image tarball to, empty to skip export
+	containerdNS      string          // containerd namespace the image is read from and the patched image imported into, empty for none
+	vexOutput         string          // Path to write the VEX document to, empty for a temporary file
+	vexFormat         string          // Format of the VEX document returned to the caller, openvex or csaf
+	vexDir            string          // Temporary directory created for copa's VEX output, removed by Cleanup
+	keepReport        *bool           // Keep the scan report directory after patching, nil until WithRetention
+	keepVex           *bool           // Keep the generated VEX document after the call, nil until WithRetention
+	maxRemaining      *int            // Vulnerability budget checked by CheckBudget, nil for no budget
+	budgetSeverity    string          // Lowest severity counted against maxRemaining
+	excludeCVEs       []string        // Vulnerabilities removed from the report before patching
+	minSeverity       string          // Vulnerabilities below this severity are removed from the report before patching
+	severities        []string        // Upper-cased severities of the vulnerabilities kept in the report, empty for all
+	filteredReportDir string          // Temporary directory holding the filtered report, removed by Cleanup
+	vexInput          string          // OpenVEX document whose not_affected and fixed statements are removed from the report
+	triaged           map[string]bool // Upper-cased IDs of the vulnerabilities triaged by vexInput
+	vexNotes          string          // Notes added to every statement of the generated VEX document
+	vexAuthor         string          // Author recorded in the generated VEX document, empty for copa's default
+	cmd               *exec.Cmd       // Current command being built
+	buildErr          error           // Error encountered while building the command
+	dockerAuth        docker.Auth     // Dependency injection for docker authentication
+	warnings          []string        // Non-fatal problems, returned in ExecutionResult.Warnings
 }
 
 type PatchParamsConstraint interface {
@@ -68,29 +122,68 @@ type PatchParamsConstraint interface {
 
 // NOTE: use generic for param types to assist the agent with populating the correct values.
 func New[T PatchParamsConstraint](params T, dryRun bool) *CLI {
-	var image, tag, reportPath string
+	var image, tag, destination, reportPath, dockerHost, exportPath, containerdNS, vexOutput, vexFormat string
 	var platforms []string
 	var push bool
+	var keepReport, keepVex *bool
+	var maxRemaining *int
+	var budgetSeverity string
+	var excludeCVEs []string
+	var minSeverity string
+	var severities []string
+	var vexInput, vexNotes, vexAuthor string
+	var timeoutSeconds int
 
 	// Extract common fields using type switch
 	switch p := any(params).(type) {
 	case types.ReportBasedPatchParams:
-		image, tag, push, reportPath = p.Image, p.Tag, p.Push, p.ReportPath
+		image, tag, push, reportPath, dockerHost, exportPath = p.Image, p.Tag, p.Push, p.ReportPath, p.DockerHost, p.ExportPath
+		containerdNS, destination = p.ContainerdNamespace, p.Destination
+		vexOutput, vexFormat = p.VexOutput, p.VexFormat
+		keepReport, keepVex = p.KeepReport, p.KeepVex
+		maxRemaining, budgetSeverity = p.MaxRemaining, strings.ToUpper(p.MaxRemainingSeverity)
+		excludeCVEs, minSeverity = p.ExcludeCVEs, strings.ToUpper(p.MinSeverity)
+		for _, severity := range p.Severity {
+			severities = append(severities, strings.ToUpper(severity))
+		}
+		vexInput, vexNotes, vexAuthor = p.VexInput, p.VexNotes, p.VexAuthor
+		timeoutSeconds = p.TimeoutSeconds
 	case types.PlatformSelectivePatchParams:
-		image, tag, push, platforms = p.Image, p.Tag, p.Push, p.Platform
+		image, tag, push, platforms, dockerHost, exportPath = p.Image, p.Tag, p.Push, p.Platform, p.DockerHost, p.ExportPath
+		containerdNS, destination = p.ContainerdNamespace, p.Destination
+		timeoutSeconds = p.TimeoutSeconds
 	case types.ComprehensivePatchParams:
-		image, tag, push = p.Image, p.Tag, p.Push
+		image, tag, push, dockerHost, exportPath = p.Image, p.Tag, p.Push, p.DockerHost, p.ExportPath
+		containerdNS, destination = p.ContainerdNamespace, p.Destination
+		timeoutSeconds = p.TimeoutSeconds
 	}
 
 	return &CLI{
-		copaPath:   "copa",
-		dryRun:     dryRun,
-		image:      image,
-		tag:        tag,
-		platforms:  platforms,
-		push:       push,
-		reportPath: reportPath,
-		dockerAuth: &docker.AuthImpl{}, // Default to real implementation
+		copaPath:       copaexec.Path(copaexec.Copa),
+		dryRun:         dryRun,
+		image:          image,
+		tag:            tag,
+		platforms:      platforms,
+		push:           push,
+		destination:    destination,
+		reportPath:     reportPath,
+		dockerHost:     dockerHost,
+		exportPath:     exportPath,
+		containerdNS:   containerdNS,
+		vexOutput:      vexOutput,
+		vexFormat:      vexFormat,
+		keepReport:     keepReport,
+		keepVex:        keepVex,
+		maxRemaining:   maxRemaining,
+		budgetSeverity: budgetSeverity,
+		excludeCVEs:    excludeCVEs,
+		minSeverity:    minSeverity,
+		severities:     severities,
+		vexInput:       vexInput,
+		vexNotes:       vexNotes,
+		vexAuthor:      vexAuthor,
+		timeout:        time.Duration(timeoutSeconds) * time.Second,
+		dockerAuth:     &docker.AuthImpl{}, // Default to real implementation
 	}
 }
 
@@ -101,15 +194,49 @@ func NewWithDockerAuth[T PatchParamsConstraint](params T, dryRun bool, dockerAut
 	return cli
 }
 
+// WithBuildkit configures copa to use the buildkit instance at addr, waiting up to wait for it to become ready
+func (c *CLI) WithBuildkit(addr string, wait time.Duration) *CLI {
+	c.buildkitAddr = addr
+	c.buildkitWait = wait
+	return c
+}
+
+// WithDeferredPush, when deferPush is set, patches into the local daemon even when the image is to be
+// pushed, leaving the push to the caller (e.g. after testing the patched image). Run sets PushPending
+// in the result when the image still has to be pushed.
+func (c *CLI) WithDeferredPush(deferPush bool) *CLI {
+	c.deferPush = deferPush
+	return c
+}
+
+// WithOutputLog calls log with each line of copa's stdout and stderr as copa writes it. The result
+// keeps only the end of the output.
+func (c *CLI) WithOutputLog(log func(line string)) *CLI {
+	c.outputLog = log
+	return c
+}
+
+// WithRetry retries the push of the patched image to a destination repository under policy, so
+// that a transient registry failure does not patch the image again. The push copa makes itself,
+// without a destination, is retried with the patch.
+func (c *CLI) WithRetry(policy copaerrors.RetryPolicy) *CLI {
+	c.retry = policy
+	return c
+}
+
 func (c *CLI) Build() *CLI {
 	args := []string{"patch"}
 	args = append(args, "--image", c.image)
 
-	if c.tag != "" {
-		args = append(args, "--tag", c.tag)
+	if c.buildkitAddr != "" {
+		args = append(args, "--addr", c.buildkitAddr)
+	}
+
+	if tag := patchTag(c.image, c.tag); tag != "" {
+		args = append(args, "--tag", tag)
 	}
 
-	if c.push {
+	if c.copaPushes() {
 		args = append(args, "--push")
 	}
 
@@ -134,34 +261,53 @@ func (c *CLI) BuildWithReport() *CLI {
 	c = c.Build()
 
 	if c.reportPath != "" {
-		c.cmd.Args = append(c.cmd.Args, "--report", c.reportPath)
+		reportPath := c.reportPath
+		// A missing report is left for validateCommand to report
+		if _, err := os.Stat(c.reportPath); err == nil && c.filtersReport() {
+			if reportPath, err = c.filterReport(); err != nil {
+				c.buildErr = copaerrors.NewValidationError("filtering the vulnerability report failed", err)
+			}
+		}
+		c.cmd.Args = append(c.cmd.Args, "--report", reportPath)
+	}
+
+	if err := c.setupVexDir(); err != nil {
+		c.buildErr = copaerrors.NewSystemError("creating vex temp dir failed", err)
 	}
 
 	return c
 }
 
+// setupAuth logs in to the registry of REGISTRY_TOKEN, when set. The login only provides the
+// credentials: whether the patched image is pushed is the caller's push, which the tool's push
+// rules, scopes and policy were checked against.
 func (c *CLI) setupAuth() error {
-	// Check if we need remote patching (push to registry)
-	remotePatch, err := c.dockerAuth.SetupRegistryAuthFromEnv()
-	if err != nil {
-		return fmt.Errorf("failed to authenticate to registry: %w", err)
+	if _, err := c.dockerAuth.SetupRegistryAuthFromEnv(); err != nil {
+		return copaerrors.NewAuthError("failed to authenticate to registry", err,
+			"check REGISTRY_TOKEN and REGISTRY_HOST")
 	}
-
-	if remotePatch && c.cmd != nil && !slices.Contains(c.cmd.Args, "--push") {
-		c.push = true
-		c.cmd.Args = append(c.cmd.Args, "--push")
-	}
-
 	return nil
 }
 
+// copaPushes reports whether copa pushes the patched image itself. An image patched for another
+// destination is patched into the local daemon and pushed once retagged, see retag.
+func (c *CLI) copaPushes() bool {
+	return c.push && !c.deferPush && c.destination == ""
+}
+
 func (c *CLI) setupVexDir() error {
 	if c.reportPath != "" {
-		path, err := os.MkdirTemp(os.TempDir(), "vex-*")
-		if err != nil {
-			return err
+		// copa always writes OpenVEX, so a CSAF document is converted from a temporary one
+		if c.vexOutput != "" && c.vexFormat != VexFormatCSAF {
+			c.vexPath = c.vexOutput
+		} else {
+			path, err := workdir.MkdirTemp("vex-*")
+			if err != nil {
+				return err
+			}
+			c.vexDir = path
+			c.vexPath = filepath.Join(path, defaultVexFile)
 		}
-		c.vexPath = filepath.Join(path, defaultVexFile)
 		c.cmd.Args = append(c.cmd.Args, "--output", c.vexPath)
 	}
 	return nil
@@ -172,25 +318,81 @@ func (c *CLI) validateCommand() error {
 		return fmt.Errorf("no command built - call a Build method first")
 	}
 
+	if c.buildErr != nil {
+		return c.buildErr
+	}
+
 	if c.image == "" {
-		return fmt.Errorf("image is required")
+		return copaerrors.NewValidationError("image is required", nil)
+	}
+
+	if err := ValidateTag(c.tag); err != nil {
+		return err
+	}
+
+	if err := docker.ValidateHost(c.dockerHost); err != nil {
+		return err
+	}
+
+	if err := ValidateDestination(c.destination); err != nil {
+		return err
 	}
 
 	// Validate platforms if specified
 	if len(c.platforms) > 0 {
 		supportedPlatforms := FilterSupportedPlatforms(c.platforms)
 		if len(supportedPlatforms) == 0 {
-			return fmt.Errorf("no supported platforms found in: %v", c.platforms)
+			return copaerrors.NewValidationError(fmt.Sprintf("no supported platforms found in: %v", c.platforms), nil,
+				fmt.Sprintf("supported platforms: %s", strings.Join(CopaSupportedPlatforms, ", ")))
 		}
 		if len(supportedPlatforms) != len(c.platforms) {
-			fmt.Fprintf(os.Stderr, "Warning: some platforms not supported by Copa, using: %v\n", supportedPlatforms)
+			c.warn("some platforms are not supported by Copa and were skipped, using: %v", supportedPlatforms)
+		}
+	}
+
+	if c.containerdNS != "" {
+		if err := containerd.ValidateNamespace(c.containerdNS); err != nil {
+			return err
+		}
+		if c.push {
+			return copaerrors.NewValidationError("containerdNamespace cannot be combined with push: the patched image is imported into the namespace from the local daemon", nil)
+		}
+	}
+
+	// Validate export path if specified
+	if c.exportPath != "" {
+		if c.push {
+			return copaerrors.NewValidationError("exportPath cannot be combined with push: the patched image is only exported from the local daemon", nil)
+		}
+		if info, err := os.Stat(filepath.Dir(c.exportPath)); err != nil || !info.IsDir() {
+			return copaerrors.NewValidationError(fmt.Sprintf("export directory does not exist: %s", filepath.Dir(c.exportPath)), nil)
+		}
+	}
+
+	if err := ValidateVexFormat(c.vexFormat); err != nil {
+		return err
+	}
+
+	if err := c.validateBudget(); err != nil {
+		return err
+	}
+
+	if err := c.validateMinSeverity(); err != nil {
+		return err
+	}
+
+	// Validate VEX output path if specified
+	if c.vexOutput != "" {
+		if info, err := os.Stat(filepath.Dir(c.vexOutput)); err != nil || !info.IsDir() {
+			return copaerrors.NewValidationError(fmt.Sprintf("vex output directory does not exist: %s", filepath.Dir(c.vexOutput)), nil)
 		}
 	}
 
 	// Validate report path if specified
 	if c.reportPath != "" {
 		if _, err := os.Stat(c.reportPath); os.IsNotExist(err) {
-			return fmt.Errorf("report path does not exist: %s", c.reportPath)
+			return copaerrors.NewValidationError(fmt.Sprintf("report path does not exist: %s", c.reportPath), nil,
+				"run 'scan-container' first and pass the report directory it returns")
 		}
 	}
 
@@ -203,23 +405,49 @@ func (c *CLI) execute(ctx context.Context) (*ExecutionResult, error) {
 	}
 
 	startTime := time.Now()
-	result := &ExecutionResult{}
+	result := &ExecutionResult{Command: slices.Clone(c.cmd.Args)}
 
 	if c.dryRun {
-		fmt.Fprintf(os.Stderr, "[DRY RUN] %s %s\n", c.cmd.Path, strings.Join(c.cmd.Args[1:], " "))
+		joblog.Logf(ctx, "info", "copa", "[DRY RUN] %s %s", c.cmd.Path, strings.Join(c.cmd.Args[1:], " "))
 		result.Duration = time.Since(startTime)
 		return result, nil
 	}
 
-	var stdout, stderr bytes.Buffer
-	c.cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
-	c.cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
-
-	c.cmd = exec.CommandContext(ctx, c.cmd.Path, c.cmd.Args[1:]...)
-
-	fmt.Fprintf(os.Stderr, "Executing: %s %s\n", c.cmd.Path, strings.Join(c.cmd.Args[1:], " "))
-
-	err := c.cmd.Run()
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Waiting for a slot counts neither against the timeout nor as part of the patch duration
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	c.cmd = copaexec.Group(exec.CommandContext(ctx, c.cmd.Path, c.cmd.Args[1:]...))
+	c.cmd.Env = docker.Env(ctx, c.dockerHost)
+
+	// The server's stdout carries the MCP protocol, so copa's output is logged line by line at
+	// debug level, to the session of the call when WithOutputLog set one
+	stdout, stderr := newTailWriter(outputTailBytes), newTailWriter(outputTailBytes)
+	log := c.outputLog
+	if log == nil {
+		log = func(line string) { slog.Debug(line, "logger", "copa") }
+	}
+	// A writer per stream, as exec copies stdout and stderr concurrently
+	lines := []*lineWriter{{log: log}, {log: log}}
+	c.cmd.Stdout = io.MultiWriter(stdout, lines[0])
+	c.cmd.Stderr = io.MultiWriter(stderr, lines[1])
+
+	startTime = time.Now()
+	joblog.Logf(ctx, "info", "copa", "Executing: %s %s", c.cmd.Path, strings.Join(c.cmd.Args[1:], " "))
+
+	sampler := startDiskSampler(workdir.Root(), diskSampleInterval)
+	err = c.cmd.Run()
+	result.PeakTempDiskBytes = sampler.Stop()
+	release()
+	for _, w := range lines {
+		w.Flush()
+	}
 
 	result.Duration = time.Since(startTime)
 	result.Output = stdout.String()
@@ -227,39 +455,233 @@ func (c *CLI) execute(ctx context.Context) (*ExecutionResult, error) {
 	result.VexPath = c.vexPath
 
 	if err != nil {
+		result.ExitCode = -1
 		if exitError, ok := err.(*exec.ExitError); ok {
 			result.ExitCode = exitError.ExitCode()
 		}
-		return result, fmt.Errorf("command execution failed: %w", err)
+		if c.timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result, copaerrors.NewTimeoutError(fmt.Sprintf("copa did not finish within %s", c.timeout), err,
+				"pass a larger timeoutSeconds, or raise the server's --tool-timeout, for large images or slow registries").
+				WithCommand(c.cmd.Args, result.ExitCode, result.Error, result.Duration)
+		}
+		if copaexec.Canceled(ctx) {
+			// Run removes the temporary VEX directory; a document copa was writing to vexOutput is partial
+			if c.vexPath == c.vexOutput && c.vexOutput != "" {
+				os.Remove(c.vexOutput)
+			}
+			return result, copaerrors.NewSystemError("copa was stopped because the request was cancelled", ctx.Err()).
+				WithCommand(c.cmd.Args, result.ExitCode, result.Error, result.Duration)
+		}
+		return result, copaerrors.New(copaerrors.Classify(result.Error, copaerrors.CategoryExecution), "command execution failed", err).
+			WithCommand(c.cmd.Args, result.ExitCode, result.Error, result.Duration)
 	}
 
 	return result, nil
 }
 
-func (c *CLI) Run(ctx context.Context) (*ExecutionResult, error) {
+func (c *CLI) Run(ctx context.Context) (result *ExecutionResult, err error) {
+	defer func() {
+		// A failed patch leaves nothing worth keeping in the temporary VEX directory
+		if err != nil {
+			c.removeVexDir()
+			c.removeFilteredReport()
+		}
+		if result != nil {
+			result.Warnings = c.warnings
+		}
+	}()
+
 	if err := c.validateCommand(); err != nil {
 		return nil, fmt.Errorf("command validation failed: %w", err)
 	}
+	if slices.Contains(c.cmd.Args, "--platform") {
+		if err := toolchain.Require(ctx, toolchain.CopaPlatform); err != nil {
+			return nil, fmt.Errorf("command validation failed: %w", err)
+		}
+	}
+
+	if c.containerdNS != "" && !c.dryRun {
+		if err := c.loadFromContainerd(ctx); err != nil {
+			return nil, fmt.Errorf("loading the image from containerd failed: %w", err)
+		}
+	}
+
+	if err := c.preflight(ctx); err != nil {
+		return nil, fmt.Errorf("preflight check failed: %w", err)
+	}
 
 	if err := c.setupAuth(); err != nil {
 		return nil, fmt.Errorf("authentication setup failed: %w", err)
 	}
 
-	if err := c.setupVexDir(); err != nil {
-		return nil, fmt.Errorf("creating vex temp dir failed: %w", err)
+	if c.buildkitAddr != "" && !c.dryRun {
+		if err := waitForBuildkit(ctx, c.buildkitAddr, c.dockerHost, c.buildkitWait, probeBuildkit); err != nil {
+			return nil, fmt.Errorf("buildkit readiness check failed: %w", err)
+		}
 	}
 
-	result, err := c.execute(ctx)
+	result, err = c.execute(ctx)
 	if err != nil {
 		return result, fmt.Errorf("execution failed: %w", err)
 	}
 
+	result.PatchedImage = DestinationRef(c.image, c.tag, c.destination)
+	if c.destination != "" && !c.dryRun {
+		if err := c.retag(ctx, result); err != nil {
+			return result, fmt.Errorf("pushing the patched image to %s failed: %w", c.destination, err)
+		}
+	}
+	result.PushPending = c.push && c.deferPush && !c.dryRun
+	if (!c.push || c.deferPush) && !c.dryRun {
+		// Only a metric, so a failed inspect leaves the size unknown
+		if size, err := docker.ImageSize(ctx, c.dockerHost, result.PatchedImage); err == nil {
+			result.PatchedImageBytes = size
+		}
+	}
+
+	if c.vexPath != "" && !c.dryRun {
+		c.summarizeVex(result)
+	}
+
+	if platforms, err := c.platformResults(result); err != nil {
+		c.warn("collecting platform results failed: %v", err)
+	} else {
+		result.Platforms = platforms
+	}
+
+	if c.vexFormat == VexFormatCSAF && c.vexPath != "" && !c.dryRun {
+		csafPath := c.vexOutput
+		if csafPath == "" {
+			csafPath = strings.TrimSuffix(c.vexPath, filepath.Ext(c.vexPath)) + ".csaf.json"
+		}
+		if err := writeCSAF(c.vexPath, csafPath); err != nil {
+			return result, fmt.Errorf("converting vex doc to csaf failed: %w", err)
+		}
+		result.VexPath = csafPath
+	}
+
+	if c.exportPath != "" && !c.dryRun {
+		if err := docker.Save(ctx, c.dockerHost, result.PatchedImage, c.exportPath); err != nil {
+			return result, fmt.Errorf("exporting patched image failed: %w", err)
+		}
+		result.ExportPath = c.exportPath
+		if info, err := os.Stat(c.exportPath); err == nil {
+			result.ExportedBytes = info.Size()
+		}
+	}
+
+	if c.containerdNS != "" && !c.dryRun {
+		if err := c.importToContainerd(ctx, result.PatchedImage); err != nil {
+			return result, fmt.Errorf("importing the patched image into containerd failed: %w", err)
+		}
+		result.ContainerdNamespace = c.containerdNS
+	}
+
+	return result, nil
+}
+
+// warn records a non-fatal problem to return with the result, and logs it
+func (c *CLI) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	c.warnings = append(c.warnings, msg)
+	slog.Warn(msg, "logger", "copa")
+}
+
+// summarizeVex merges the VEX documents copa wrote and summarizes them into result. The image has
+// already been patched at this point, so problems with the documents are warnings rather than failures.
+func (c *CLI) summarizeVex(result *ExecutionResult) {
+	if err := mergeVexDocuments(c.vexPath); err != nil {
+		c.warn("merging per-platform vex documents failed: %v", err)
+	}
+
+	if c.vexNotes != "" || c.vexAuthor != "" {
+		if err := annotateVexDocument(c.vexPath, c.vexNotes, c.vexAuthor); err != nil {
+			c.warn("adding notes to vex document failed: %v", err)
+		}
+	}
+
+	var err error
 	result.FixedVulnerabilityCount, result.UpdatedPackageCount, err = c.parseVexDoc(c.vexPath)
 	if err != nil {
-		return result, fmt.Errorf("parsing vex doc failed: %w", err)
+		c.warn("parsing vex document failed, vulnerability counts are unavailable: %v", err)
+		return
 	}
 
-	return result, nil
+	result.FixedVulnerabilities, err = ListFixedVulnerabilities(c.vexPath)
+	if err != nil {
+		c.warn("listing fixed vulnerabilities failed: %v", err)
+		return
+	}
+
+	if c.reportPath != "" {
+		severities, err := trivy.Severities(c.reportPath)
+		if err != nil {
+			c.warn("summarizing fixed vulnerabilities by severity failed: %v", err)
+			return
+		}
+		// Vulnerabilities triaged by vexInput do not apply to the image, so they are not counted as remaining
+		for id := range severities {
+			if c.triaged[strings.ToUpper(id)] {
+				delete(severities, id)
+			}
+		}
+		result.Severity = summarizeSeverity(severities, result.FixedVulnerabilities)
+
+		findings, err := trivy.ReportFindings(c.reportPath)
+		if err != nil {
+			c.warn("classifying remaining vulnerabilities failed: %v", err)
+			return
+		}
+		result.Remaining = c.ClassifyRemaining(findings, result.FixedVulnerabilities, types.RemainingSourceReport)
+	}
+}
+
+// PatchedImageRef returns the reference copa gives the patched image: the source
+// repository with tag, or the source tag suffixed with "-patched" when tag is empty. An image
+// referenced only by digest has no tag to suffix, so its patched tag is named after the digest,
+// e.g. alpine@sha256:abc... is patched into alpine:sha256-abc...-patched.
+func PatchedImageRef(image, tag string) string {
+	repo, sourceTag := image, "latest"
+	tag = patchTag(image, tag)
+
+	// Strip any digest, copa tags the patched image by name
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	// A colon after the last slash separates the tag (a colon before it is a registry port)
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, sourceTag = repo[:i], repo[i+1:]
+	}
+
+	if tag == "" {
+		tag = sourceTag + "-patched"
+	}
+	return repo + ":" + tag
+}
+
+// patchTag returns the tag passed to copa for image: tag, or, when it is empty and image is
+// referenced only by digest, which copa cannot derive a tag from, the digest with its algorithm
+// separated by '-' (as cosign names signature tags) and suffixed with "-patched"
+func patchTag(image, tag string) string {
+	name, digest, found := strings.Cut(image, "@")
+	if tag != "" || !found || strings.LastIndex(name, ":") > strings.LastIndex(name, "/") {
+		return tag
+	}
+	return strings.Replace(digest, ":", "-", 1) + "-patched"
+}
+
+// ValidateTag checks that tag is a tag name rather than a full image reference. An empty tag is
+// valid and lets copa derive the patched tag from the source image.
+func ValidateTag(tag string) error {
+	if tag == "" || tagRegexp.MatchString(tag) {
+		return nil
+	}
+
+	hint := "tags may contain letters, digits, '_', '.' and '-', must not start with '.' or '-', and are at most 128 characters"
+	if i := strings.LastIndex(tag, ":"); i >= 0 {
+		hint = fmt.Sprintf("pass only the tag name, e.g. %q instead of %q", tag[i+1:], tag)
+	}
+	return copaerrors.NewValidationError(fmt.Sprintf("invalid patch tag: %s", tag), nil, hint)
 }
 
 // IsPlatformSupported checks if the given platform is supported by Copa for patching
@@ -292,17 +714,11 @@ func (c *CLI) parseVexDoc(path string) (numFixedVulns, updatedPackageCount int,
 		return 0, 0, nil
 	}
 
-	vexData, err := os.ReadFile(path)
+	doc, err := readVexDocument(path)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	var doc vex.VEX
-
-	if err := json.Unmarshal(vexData, &doc); err != nil {
-		return 0, 0, err
-	}
-
 	for _, stmt := range doc.Statements {
 		if stmt.Status == vex.StatusFixed {
 			numFixedVulns++
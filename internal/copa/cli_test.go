@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
 	"github.com/project-copacetic/mcp-server/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -118,6 +123,24 @@ func (suite *CLITestSuite) TestBuild_WithPush() {
 	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
 }
 
+func (suite *CLITestSuite) TestBuild_WithDestination() {
+	// An image patched for another repository is pushed once retagged, not by copa
+	suite.cli.push = true
+	suite.cli.destination = "golden.example.com/team/alpine"
+	suite.cli.Build()
+
+	expectedArgs := []string{"patch", "--image", "alpine:3.17", "--tag", "patched"}
+	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
+}
+
+func (suite *CLITestSuite) TestBuild_WithDeferredPush() {
+	suite.cli.push = true
+	suite.cli.WithDeferredPush(true).Build()
+
+	expectedArgs := []string{"patch", "--image", "alpine:3.17", "--tag", "patched"}
+	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
+}
+
 func (suite *CLITestSuite) TestBuild_WithoutTag() {
 	suite.cli.tag = ""
 	suite.cli.Build()
@@ -126,6 +149,22 @@ func (suite *CLITestSuite) TestBuild_WithoutTag() {
 	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
 }
 
+func (suite *CLITestSuite) TestBuild_DigestWithoutTag() {
+	suite.cli.image = "alpine@sha256:abc"
+	suite.cli.tag = ""
+	suite.cli.Build()
+
+	expectedArgs := []string{"patch", "--image", "alpine@sha256:abc", "--tag", "sha256-abc-patched"}
+	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
+}
+
+func (suite *CLITestSuite) TestBuild_WithBuildkit() {
+	suite.cli.WithBuildkit("tcp://buildkitd:1234", time.Second).Build()
+
+	expectedArgs := []string{"patch", "--image", "alpine:3.17", "--addr", "tcp://buildkitd:1234", "--tag", "patched"}
+	suite.Equal(expectedArgs, suite.cli.cmd.Args[1:])
+}
+
 // Test BuildWithPlatforms method
 func (suite *CLITestSuite) TestBuildWithPlatforms() {
 	suite.cli.platforms = []string{"linux/amd64", "linux/arm64"}
@@ -171,6 +210,17 @@ func (suite *CLITestSuite) TestBuildWithReport() {
 	suite.NotEmpty(suite.cli.vexPath)
 }
 
+func (suite *CLITestSuite) TestBuildWithReport_VexOutput() {
+	vexOutput := filepath.Join(suite.T().TempDir(), "patched.vex.json")
+	suite.cli.reportPath = "/tmp/test-report"
+	suite.cli.vexOutput = vexOutput
+
+	suite.cli.BuildWithReport()
+
+	suite.Equal(vexOutput, suite.cli.vexPath)
+	suite.Contains(suite.cli.cmd.Args, vexOutput)
+}
+
 func (suite *CLITestSuite) TestBuildWithReport_EmptyReportPath() {
 	suite.cli.reportPath = ""
 
@@ -190,6 +240,30 @@ func (suite *CLITestSuite) TestValidateCommand_Success() {
 	suite.NoError(err)
 }
 
+func (suite *CLITestSuite) TestValidateCommand_UnsupportedPlatformsWarn() {
+	suite.cli.platforms = []string{"linux/amd64", "windows/amd64"}
+	suite.cli.BuildWithPlatforms()
+
+	err := suite.cli.validateCommand()
+
+	suite.NoError(err)
+	suite.Require().Len(suite.cli.warnings, 1)
+	suite.Contains(suite.cli.warnings[0], "not supported by Copa")
+}
+
+func (suite *CLITestSuite) TestSummarizeVex_InvalidDocumentWarns() {
+	suite.cli.vexPath = filepath.Join(suite.T().TempDir(), "vex.json")
+	suite.Require().NoError(os.WriteFile(suite.cli.vexPath, []byte("not json"), 0o600))
+	result := &ExecutionResult{}
+
+	suite.cli.summarizeVex(result)
+
+	suite.Zero(result.FixedVulnerabilityCount)
+	suite.Nil(result.FixedVulnerabilities)
+	suite.Require().NotEmpty(suite.cli.warnings)
+	suite.Contains(suite.cli.warnings[len(suite.cli.warnings)-1], "parsing vex document failed")
+}
+
 func (suite *CLITestSuite) TestValidateCommand_NoCommandBuilt() {
 	// Don't call Build()
 
@@ -219,6 +293,27 @@ func (suite *CLITestSuite) TestValidateCommand_UnsupportedPlatforms() {
 	suite.Contains(err.Error(), "no supported platforms found")
 }
 
+func (suite *CLITestSuite) TestValidateCommand_InvalidTag() {
+	suite.cli.tag = "alpine:patched"
+	suite.cli.Build()
+
+	err := suite.cli.validateCommand()
+
+	suite.Error(err)
+	suite.Contains(err.Error(), "invalid patch tag")
+	suite.Contains(err.Error(), `"patched" instead of "alpine:patched"`)
+}
+
+func (suite *CLITestSuite) TestValidateCommand_InvalidDockerHost() {
+	suite.cli.dockerHost = "http://build-host:2375"
+	suite.cli.Build()
+
+	err := suite.cli.validateCommand()
+
+	suite.Error(err)
+	suite.Contains(err.Error(), "unsupported docker host scheme")
+}
+
 func (suite *CLITestSuite) TestValidateCommand_ReportPathNotExists() {
 	suite.cli.reportPath = "/nonexistent/path"
 	suite.cli.Build()
@@ -243,6 +338,27 @@ func (suite *CLITestSuite) TestValidateCommand_ReportPathExists() {
 	suite.NoError(err)
 }
 
+func (suite *CLITestSuite) TestValidateCommand_ExportWithPush() {
+	suite.cli.exportPath = filepath.Join(suite.T().TempDir(), "patched.tar")
+	suite.cli.push = true
+	suite.cli.Build()
+
+	err := suite.cli.validateCommand()
+
+	suite.Error(err)
+	suite.Contains(err.Error(), "cannot be combined with push")
+}
+
+func (suite *CLITestSuite) TestValidateCommand_ExportDirNotExists() {
+	suite.cli.exportPath = "/nonexistent/dir/patched.tar"
+	suite.cli.Build()
+
+	err := suite.cli.validateCommand()
+
+	suite.Error(err)
+	suite.Contains(err.Error(), "export directory does not exist")
+}
+
 // Test execute method with dry run
 func (suite *CLITestSuite) TestExecute_DryRun() {
 	suite.cli.dryRun = true
@@ -281,40 +397,45 @@ func (suite *CLITestSuite) TestRun_ValidationFails() {
 	suite.Contains(err.Error(), "command validation failed")
 }
 
-func (suite *CLITestSuite) TestRun_WithMockDockerAuth_PushFlagAdded() {
-	// Create mock docker auth
+func (suite *CLITestSuite) TestRun_WithMockDockerAuth_LoginDoesNotPush() {
 	mockAuth := new(MockDockerAuth)
 	mockAuth.On("SetupRegistryAuthFromEnv").Return(true, nil)
 
-	// Create CLI with mock docker auth
 	params := types.ComprehensivePatchParams{
 		Image: "alpine:3.17",
 		Tag:   "test-patched",
-		Push:  false, // Initially false
+		Push:  false,
 	}
 	cli := NewWithDockerAuth(params, true, mockAuth) // Use dry run to avoid actual execution
-
-	// Build the command first
 	cli.Build()
 
-	// Verify --push is not initially in the command
+	result, err := cli.Run(context.Background())
+	suite.NoError(err)
+	suite.NotNil(result)
+	mockAuth.AssertExpectations(suite.T())
+
+	// REGISTRY_TOKEN only logs in; the push checked against the push rules is the caller's
+	suite.False(cli.push)
 	suite.NotContains(cli.cmd.Args, "--push")
+}
 
-	ctx := context.Background()
-	result, err := cli.Run(ctx) // Use the cli instance with mock, not suite.cli
+func (suite *CLITestSuite) TestRun_WithMockDockerAuth_DeferredPush() {
+	mockAuth := new(MockDockerAuth)
+	mockAuth.On("SetupRegistryAuthFromEnv").Return(true, nil)
 
-	// Verify the mock was called
-	mockAuth.AssertExpectations(suite.T())
+	params := types.ComprehensivePatchParams{Image: "alpine:3.17", Tag: "test-patched", Push: true}
+	cli := NewWithDockerAuth(params, true, mockAuth).WithDeferredPush(true)
+	cli.Build()
 
-	// Should succeed since it's a dry run
+	result, err := cli.Run(context.Background())
 	suite.NoError(err)
-	suite.NotNil(result)
+	mockAuth.AssertExpectations(suite.T())
 
-	// Verify that push was set to true
+	// The image is still pushed, but the caller does so after copa loads it locally
 	suite.True(cli.push)
-
-	// Verify --push flag was added to command arguments
-	suite.Contains(cli.cmd.Args, "--push")
+	suite.NotContains(cli.cmd.Args, "--push")
+	// A dry run leaves nothing to push
+	suite.False(result.PushPending)
 }
 
 func (suite *CLITestSuite) TestRun_WithMockDockerAuth_NoPushWhenAuthFalse() {
@@ -633,3 +754,100 @@ func (suite *CLITestSuite) TestExecute_ContextCancellation() {
 	}
 	suite.NotNil(result)
 }
+
+func TestExecute_CancelledRemovesPartialVex(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as copa")
+	}
+	dir := t.TempDir()
+	vexOutput := filepath.Join(dir, "vex.json")
+	copa := filepath.Join(dir, "copa")
+	require.NoError(t, os.WriteFile(copa, []byte("#!/bin/sh\necho '{' > "+vexOutput+"\nsleep 60\n"), 0o755))
+
+	cli := New(types.ReportBasedPatchParams{Image: "nginx:1.25", ReportPath: dir, VexOutput: vexOutput}, false)
+	cli.copaPath = copa
+	cli.vexPath = vexOutput
+	cli.Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, err := os.Stat(vexOutput); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	_, err := cli.execute(ctx)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "request was cancelled")
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+	assert.NoFileExists(t, vexOutput)
+}
+
+func TestPatchedImageRef(t *testing.T) {
+	tests := []struct {
+		image    string
+		tag      string
+		expected string
+	}{
+		{"alpine:3.17", "patched", "alpine:patched"},
+		{"alpine:3.17", "", "alpine:3.17-patched"},
+		{"alpine", "", "alpine:latest-patched"},
+		{"localhost:5000/app:v1", "secure", "localhost:5000/app:secure"},
+		{"localhost:5000/app", "", "localhost:5000/app:latest-patched"},
+		{"ghcr.io/org/app:v1@sha256:abc", "fixed", "ghcr.io/org/app:fixed"},
+		{"ghcr.io/org/app:v1@sha256:abc", "", "ghcr.io/org/app:v1-patched"},
+		{"ghcr.io/org/app@sha256:abc", "", "ghcr.io/org/app:sha256-abc-patched"},
+		{"localhost:5000/app@sha256:abc", "fixed", "localhost:5000/app:fixed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image+"/"+tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PatchedImageRef(tt.image, tt.tag))
+		})
+	}
+}
+
+func TestDestinationRef(t *testing.T) {
+	assert.Equal(t, "alpine:3.17-patched", DestinationRef("alpine:3.17", "", ""))
+	assert.Equal(t, "golden.example.com/team/alpine:3.17-patched", DestinationRef("quarantine.example.com/alpine:3.17", "", "golden.example.com/team/alpine"))
+	assert.Equal(t, "localhost:5000/app:secure", DestinationRef("ghcr.io/org/app@sha256:abc", "secure", "localhost:5000/app"))
+}
+
+func TestValidateDestination(t *testing.T) {
+	assert.NoError(t, ValidateDestination(""))
+	assert.NoError(t, ValidateDestination("localhost:5000/team/app"))
+	assert.Error(t, ValidateDestination("golden.example.com/app:patched"))
+	assert.Error(t, ValidateDestination("golden.example.com/app@sha256:abc"))
+	assert.Error(t, ValidateDestination("--insecure"))
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		wantErr bool
+	}{
+		{tag: ""},
+		{tag: "patched"},
+		{tag: "v1.0-secure"},
+		{tag: "3.17_patched"},
+		{tag: "alpine:patched", wantErr: true},
+		{tag: "registry:5000/alpine:patched", wantErr: true},
+		{tag: "-patched", wantErr: true},
+		{tag: strings.Repeat("a", 129), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			err := ValidateTag(tt.tag)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
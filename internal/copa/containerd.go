@@ -0,0 +1,44 @@
+package copa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/project-copacetic/mcp-server/internal/containerd"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// loadFromContainerd copies the image from the containerd namespace into the Docker daemon, where
+// copa patches it instead of pulling it from its registry
+func (c *CLI) loadFromContainerd(ctx context.Context) error {
+	return c.viaTarball(func(path string) error {
+		if err := containerd.Export(ctx, c.containerdNS, c.image, path); err != nil {
+			return err
+		}
+		return docker.Load(ctx, c.dockerHost, path)
+	})
+}
+
+// importToContainerd copies the patched image from the Docker daemon into the containerd
+// namespace, for the node's pods to run it
+func (c *CLI) importToContainerd(ctx context.Context, image string) error {
+	return c.viaTarball(func(path string) error {
+		if err := docker.Save(ctx, c.dockerHost, image, path); err != nil {
+			return err
+		}
+		return containerd.Import(ctx, c.containerdNS, path)
+	})
+}
+
+// viaTarball calls copy with the path of a temporary tarball, removed when copy returns
+func (c *CLI) viaTarball(copy func(path string) error) error {
+	dir, err := workdir.MkdirTemp("containerd-*")
+	if err != nil {
+		return copaerrors.NewSystemError("failed to create a directory for the image tarball", err)
+	}
+	defer os.RemoveAll(dir)
+	return copy(filepath.Join(dir, "image.tar"))
+}
@@ -0,0 +1,327 @@
+package copa
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Supported VEX output formats
+const (
+	VexFormatOpenVEX = "openvex"
+	VexFormatCSAF    = "csaf"
+)
+
+// csafDocument is the subset of the CSAF 2.0 VEX profile produced from OpenVEX documents
+type csafDocument struct {
+	Document        csafDocumentMeta    `json:"document"`
+	ProductTree     csafProductTree     `json:"product_tree"`
+	Vulnerabilities []csafVulnerability `json:"vulnerabilities"`
+}
+
+type csafDocumentMeta struct {
+	Category    string        `json:"category"`
+	CSAFVersion string        `json:"csaf_version"`
+	Publisher   csafPublisher `json:"publisher"`
+	Title       string        `json:"title"`
+	Tracking    csafTracking  `json:"tracking"`
+}
+
+type csafPublisher struct {
+	Category  string `json:"category"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type csafTracking struct {
+	ID                 string         `json:"id"`
+	Status             string         `json:"status"`
+	Version            string         `json:"version"`
+	InitialReleaseDate string         `json:"initial_release_date"`
+	CurrentReleaseDate string         `json:"current_release_date"`
+	RevisionHistory    []csafRevision `json:"revision_history"`
+	Generator          *csafGenerator `json:"generator,omitempty"`
+}
+
+type csafRevision struct {
+	Date    string `json:"date"`
+	Number  string `json:"number"`
+	Summary string `json:"summary"`
+}
+
+type csafGenerator struct {
+	Engine csafEngine `json:"engine"`
+}
+
+type csafEngine struct {
+	Name string `json:"name"`
+}
+
+type csafProductTree struct {
+	FullProductNames []csafFullProductName `json:"full_product_names,omitempty"`
+	Relationships    []csafRelationship    `json:"relationships,omitempty"`
+}
+
+type csafFullProductName struct {
+	Name                        string                 `json:"name"`
+	ProductID                   string                 `json:"product_id"`
+	ProductIdentificationHelper *csafProductIdentifier `json:"product_identification_helper,omitempty"`
+}
+
+type csafProductIdentifier struct {
+	PURL string `json:"purl,omitempty"`
+}
+
+type csafRelationship struct {
+	Category                  string              `json:"category"`
+	FullProductName           csafFullProductName `json:"full_product_name"`
+	ProductReference          string              `json:"product_reference"`
+	RelatesToProductReference string              `json:"relates_to_product_reference"`
+}
+
+type csafVulnerability struct {
+	CVE           string            `json:"cve,omitempty"`
+	IDs           []csafID          `json:"ids,omitempty"`
+	Notes         []csafNote        `json:"notes,omitempty"`
+	ProductStatus csafProductStatus `json:"product_status"`
+	Flags         []csafFlag        `json:"flags,omitempty"`
+	Threats       []csafThreat      `json:"threats,omitempty"`
+	Remediations  []csafRemediation `json:"remediations,omitempty"`
+}
+
+type csafID struct {
+	SystemName string `json:"system_name"`
+	Text       string `json:"text"`
+}
+
+type csafNote struct {
+	Category string `json:"category"`
+	Text     string `json:"text"`
+}
+
+type csafProductStatus struct {
+	Fixed              []string `json:"fixed,omitempty"`
+	KnownAffected      []string `json:"known_affected,omitempty"`
+	KnownNotAffected   []string `json:"known_not_affected,omitempty"`
+	UnderInvestigation []string `json:"under_investigation,omitempty"`
+}
+
+type csafFlag struct {
+	Label      string   `json:"label"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+type csafThreat struct {
+	Category   string   `json:"category"`
+	Details    string   `json:"details"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+type csafRemediation struct {
+	Category   string   `json:"category"`
+	Details    string   `json:"details"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+// ValidateVexFormat checks that format is a supported VEX output format
+func ValidateVexFormat(format string) error {
+	switch format {
+	case "", VexFormatOpenVEX, VexFormatCSAF:
+		return nil
+	default:
+		return copaerrors.NewValidationError(fmt.Sprintf("unsupported vex format: %s", format), nil,
+			fmt.Sprintf("use %q (default) or %q", VexFormatOpenVEX, VexFormatCSAF))
+	}
+}
+
+// convertToCSAF converts an OpenVEX document into a CSAF 2.0 VEX document. Products become
+// full product names and subcomponents are related to them as "default_component_of".
+func convertToCSAF(doc *vex.VEX) *csafDocument {
+	timestamp := time.Now().UTC()
+	if doc.Timestamp != nil {
+		timestamp = doc.Timestamp.UTC()
+	}
+	date := timestamp.Format(time.RFC3339)
+
+	author := doc.Author
+	if author == "" {
+		author = vex.DefaultAuthor
+	}
+
+	csaf := &csafDocument{
+		Document: csafDocumentMeta{
+			Category:    "csaf_vex",
+			CSAFVersion: "2.0",
+			Publisher: csafPublisher{
+				Category:  "other",
+				Name:      author,
+				Namespace: "https://github.com/project-copacetic/copacetic",
+			},
+			Title: "Copacetic patch VEX",
+			Tracking: csafTracking{
+				ID:                 doc.ID,
+				Status:             "final",
+				Version:            fmt.Sprintf("%d", max(doc.Version, 1)),
+				InitialReleaseDate: date,
+				CurrentReleaseDate: date,
+				RevisionHistory:    []csafRevision{{Date: date, Number: "1", Summary: "Initial version"}},
+			},
+		},
+	}
+	if doc.Tooling != "" {
+		csaf.Document.Tracking.Generator = &csafGenerator{Engine: csafEngine{Name: doc.Tooling}}
+	}
+
+	products := map[string]csafFullProductName{}
+	relationships := map[string]csafRelationship{}
+	vulns := map[string]*csafVulnerability{}
+	var vulnOrder []string
+
+	for _, stmt := range doc.Statements {
+		var productIDs []string
+		for _, product := range stmt.Products {
+			productID := componentID(product.Component)
+			products[productID] = fullProductName(product.Component)
+
+			if len(product.Subcomponents) == 0 {
+				productIDs = append(productIDs, productID)
+				continue
+			}
+			for _, sub := range product.Subcomponents {
+				subID := componentID(sub.Component)
+				products[subID] = fullProductName(sub.Component)
+
+				relID := subID + ":" + productID
+				relationships[relID] = csafRelationship{
+					Category: "default_component_of",
+					FullProductName: csafFullProductName{
+						Name:      fmt.Sprintf("%s as a component of %s", subID, productID),
+						ProductID: relID,
+					},
+					ProductReference:          subID,
+					RelatesToProductReference: productID,
+				}
+				productIDs = append(productIDs, relID)
+			}
+		}
+
+		name := string(stmt.Vulnerability.Name)
+		v, ok := vulns[name]
+		if !ok {
+			v = newCSAFVulnerability(stmt.Vulnerability)
+			vulns[name] = v
+			vulnOrder = append(vulnOrder, name)
+		}
+		applyStatement(v, stmt, productIDs)
+	}
+
+	for _, id := range sortedKeys(products) {
+		csaf.ProductTree.FullProductNames = append(csaf.ProductTree.FullProductNames, products[id])
+	}
+	for _, id := range sortedKeys(relationships) {
+		csaf.ProductTree.Relationships = append(csaf.ProductTree.Relationships, relationships[id])
+	}
+	for _, name := range vulnOrder {
+		csaf.Vulnerabilities = append(csaf.Vulnerabilities, *vulns[name])
+	}
+
+	return csaf
+}
+
+// newCSAFVulnerability maps the vulnerability identifier to the CSAF cve field, or to ids for non-CVE identifiers
+func newCSAFVulnerability(vuln vex.Vulnerability) *csafVulnerability {
+	v := &csafVulnerability{}
+	name := string(vuln.Name)
+	if strings.HasPrefix(name, "CVE-") {
+		v.CVE = name
+	} else {
+		system, _, _ := strings.Cut(name, "-")
+		v.IDs = []csafID{{SystemName: system, Text: name}}
+	}
+	if vuln.Description != "" {
+		v.Notes = []csafNote{{Category: "description", Text: vuln.Description}}
+	}
+	return v
+}
+
+// applyStatement records the statement's status, justification and remediation for productIDs
+func applyStatement(v *csafVulnerability, stmt vex.Statement, productIDs []string) {
+	switch stmt.Status {
+	case vex.StatusFixed:
+		v.ProductStatus.Fixed = append(v.ProductStatus.Fixed, productIDs...)
+	case vex.StatusAffected:
+		v.ProductStatus.KnownAffected = append(v.ProductStatus.KnownAffected, productIDs...)
+		if stmt.ActionStatement != "" {
+			v.Remediations = append(v.Remediations, csafRemediation{Category: "mitigation", Details: stmt.ActionStatement, ProductIDs: productIDs})
+		}
+	case vex.StatusNotAffected:
+		v.ProductStatus.KnownNotAffected = append(v.ProductStatus.KnownNotAffected, productIDs...)
+		if stmt.Justification != "" {
+			v.Flags = append(v.Flags, csafFlag{Label: string(stmt.Justification), ProductIDs: productIDs})
+		}
+		if stmt.ImpactStatement != "" {
+			v.Threats = append(v.Threats, csafThreat{Category: "impact", Details: stmt.ImpactStatement, ProductIDs: productIDs})
+		}
+	case vex.StatusUnderInvestigation:
+		v.ProductStatus.UnderInvestigation = append(v.ProductStatus.UnderInvestigation, productIDs...)
+	}
+
+	if stmt.StatusNotes != "" {
+		v.Notes = append(v.Notes, csafNote{Category: "details", Text: stmt.StatusNotes})
+	}
+}
+
+// componentID returns the identifier used for a component in the CSAF product tree
+func componentID(c vex.Component) string {
+	if c.ID != "" {
+		return c.ID
+	}
+	if purl := c.Identifiers[vex.PURL]; purl != "" {
+		return purl
+	}
+	return "unknown"
+}
+
+func fullProductName(c vex.Component) csafFullProductName {
+	id := componentID(c)
+	product := csafFullProductName{Name: id, ProductID: id}
+
+	purl := c.Identifiers[vex.PURL]
+	if purl == "" && strings.HasPrefix(c.ID, "pkg:") {
+		purl = c.ID
+	}
+	if purl != "" {
+		product.ProductIdentificationHelper = &csafProductIdentifier{PURL: purl}
+	}
+	return product
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeCSAF converts the OpenVEX document at vexPath to CSAF and writes it to csafPath
+func writeCSAF(vexPath, csafPath string) error {
+	doc, err := readVexDocument(vexPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(convertToCSAF(doc), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal csaf document: %w", err)
+	}
+
+	return os.WriteFile(csafPath, data, 0o644)
+}
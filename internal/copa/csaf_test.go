@@ -0,0 +1,108 @@
+package copa
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertToCSAF(t *testing.T) {
+	doc := vex.New()
+	doc.ID = "https://openvex.dev/docs/public/vex-123"
+	doc.Author = "Project Copacetic"
+	doc.Statements = []vex.Statement{
+		{
+			Vulnerability: vex.Vulnerability{Name: "CVE-2023-0001"},
+			Products: []vex.Product{{
+				Component:     vex.Component{ID: "pkg:oci/alpine"},
+				Subcomponents: []vex.Subcomponent{{Component: vex.Component{ID: "pkg:apk/alpine/openssl"}}},
+			}},
+			Status: vex.StatusFixed,
+		},
+		{
+			Vulnerability: vex.Vulnerability{Name: "GHSA-xxxx-yyyy-zzzz"},
+			Products:      []vex.Product{{Component: vex.Component{ID: "pkg:oci/alpine"}}},
+			Status:        vex.StatusNotAffected,
+			Justification: vex.VulnerableCodeNotPresent,
+		},
+	}
+
+	csaf := convertToCSAF(&doc)
+
+	assert.Equal(t, "csaf_vex", csaf.Document.Category)
+	assert.Equal(t, "2.0", csaf.Document.CSAFVersion)
+	assert.Equal(t, doc.ID, csaf.Document.Tracking.ID)
+	assert.Equal(t, "Project Copacetic", csaf.Document.Publisher.Name)
+
+	require.Len(t, csaf.ProductTree.FullProductNames, 2)
+	require.Len(t, csaf.ProductTree.Relationships, 1)
+	rel := csaf.ProductTree.Relationships[0]
+	assert.Equal(t, "default_component_of", rel.Category)
+	assert.Equal(t, "pkg:apk/alpine/openssl", rel.ProductReference)
+	assert.Equal(t, "pkg:oci/alpine", rel.RelatesToProductReference)
+
+	require.Len(t, csaf.Vulnerabilities, 2)
+	fixed := csaf.Vulnerabilities[0]
+	assert.Equal(t, "CVE-2023-0001", fixed.CVE)
+	assert.Equal(t, []string{rel.FullProductName.ProductID}, fixed.ProductStatus.Fixed)
+
+	notAffected := csaf.Vulnerabilities[1]
+	assert.Empty(t, notAffected.CVE)
+	assert.Equal(t, []csafID{{SystemName: "GHSA", Text: "GHSA-xxxx-yyyy-zzzz"}}, notAffected.IDs)
+	assert.Equal(t, []string{"pkg:oci/alpine"}, notAffected.ProductStatus.KnownNotAffected)
+	require.Len(t, notAffected.Flags, 1)
+	assert.Equal(t, "vulnerable_code_not_present", notAffected.Flags[0].Label)
+}
+
+func TestWriteCSAF(t *testing.T) {
+	dir := t.TempDir()
+	vexPath := filepath.Join(dir, "vex.json")
+	csafPath := filepath.Join(dir, "vex.csaf.json")
+	writeVexDocument(t, vexPath, "CVE-2023-0001", "pkg:oci/alpine")
+
+	require.NoError(t, writeCSAF(vexPath, csafPath))
+
+	data, err := os.ReadFile(csafPath)
+	require.NoError(t, err)
+	var csaf csafDocument
+	require.NoError(t, json.Unmarshal(data, &csaf))
+	assert.Equal(t, "csaf_vex", csaf.Document.Category)
+	require.Len(t, csaf.Vulnerabilities, 1)
+	assert.Equal(t, "CVE-2023-0001", csaf.Vulnerabilities[0].CVE)
+}
+
+func TestValidateCommand_InvalidVexFormat(t *testing.T) {
+	params := types.ReportBasedPatchParams{
+		Image:      "alpine:3.17",
+		Tag:        "patched",
+		ReportPath: t.TempDir(),
+		VexFormat:  "cyclonedx",
+	}
+
+	err := New(params, true).BuildWithReport().validateCommand()
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestBuildWithReport_CSAFUsesTemporaryOpenVEX(t *testing.T) {
+	vexOutput := filepath.Join(t.TempDir(), "vex.csaf.json")
+	params := types.ReportBasedPatchParams{
+		Image:      "alpine:3.17",
+		Tag:        "patched",
+		ReportPath: t.TempDir(),
+		VexOutput:  vexOutput,
+		VexFormat:  VexFormatCSAF,
+	}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	assert.NotEqual(t, vexOutput, cli.vexPath)
+	assert.Contains(t, cli.cmd.Args, cli.vexPath)
+}
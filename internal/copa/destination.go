@@ -0,0 +1,66 @@
+package copa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+)
+
+// DestinationRef returns the reference of the patched image of image: PatchedImageRef, or when
+// destination is set, the same tag in the destination repository
+func DestinationRef(image, tag, destination string) string {
+	ref := PatchedImageRef(image, tag)
+	if destination == "" {
+		return ref
+	}
+	return destination + ref[strings.LastIndex(ref, ":"):]
+}
+
+// ValidateDestination checks that destination, when set, is a repository without tag or digest.
+// The tag of the patched image is set by the patch tag, as for the source repository.
+func ValidateDestination(destination string) error {
+	if destination == "" {
+		return nil
+	}
+	if strings.HasPrefix(destination, "-") || strings.ContainsFunc(destination, unicode.IsSpace) {
+		return copaerrors.NewValidationError(fmt.Sprintf("invalid destination repository: %q", destination), nil)
+	}
+	if strings.Contains(destination, "@") || strings.LastIndex(destination, ":") > strings.LastIndex(destination, "/") {
+		return copaerrors.NewValidationError(fmt.Sprintf("destination must be a repository without tag or digest: %s", destination), nil,
+			"pass the repository as destination, e.g. golden.example.com/team/app, and the tag as patchtag")
+	}
+	return nil
+}
+
+// retag gives the image copa patched into the local daemon its destination reference, removing
+// the reference in the source repository, and pushes it unless the push is left to the caller
+func (c *CLI) retag(ctx context.Context, result *ExecutionResult) error {
+	if local := PatchedImageRef(c.image, c.tag); local != result.PatchedImage {
+		if err := docker.Tag(ctx, c.dockerHost, local, result.PatchedImage); err != nil {
+			return err
+		}
+		// Only the reference is removed, the destination keeps the image
+		if _, err := docker.RemoveImages(ctx, c.dockerHost, []string{local}, false); err != nil {
+			c.warn("removing the patched image's reference in the source repository failed: %v", err)
+		}
+	}
+	if !c.push || c.deferPush {
+		return nil
+	}
+	policy := c.retry
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return copaerrors.Retry(ctx, policy, func() error {
+		return docker.Push(ctx, c.dockerHost, result.PatchedImage)
+	}, func(attempt int, delay time.Duration, err error) {
+		joblog.Logf(ctx, "warning", "copa", "push %d of %d of %s failed (%s), retrying in %s: %v",
+			attempt, policy.MaxAttempts, result.PatchedImage, copaerrors.CategoryOf(err), delay, err)
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package copa
+
+import "syscall"
+
+// AvailableDiskSpace returns the number of bytes available to unprivileged users on the filesystem containing dir
+func AvailableDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package copa
+
+import "golang.org/x/sys/windows"
+
+// AvailableDiskSpace returns the number of bytes available to the current user on the volume containing dir
+func AvailableDiskSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &freeBytesAvailable, nil, nil); err != nil {
+		return 0, err
+	}
+	return freeBytesAvailable, nil
+}
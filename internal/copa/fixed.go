@@ -0,0 +1,56 @@
+package copa
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// ListFixedVulnerabilities returns the vulnerabilities the OpenVEX document at vexPath marks as
+// fixed, sorted by ID, with the packages updated to fix each. Statements for the same vulnerability
+// on several platforms are combined.
+func ListFixedVulnerabilities(vexPath string) ([]types.FixedVulnerability, error) {
+	doc, err := readVexDocument(vexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]*types.FixedVulnerability{}
+	for _, stmt := range doc.Statements {
+		if stmt.Status != vex.StatusFixed {
+			continue
+		}
+
+		id := string(stmt.Vulnerability.Name)
+		vuln, ok := byID[id]
+		if !ok {
+			vuln = &types.FixedVulnerability{ID: id}
+			byID[id] = vuln
+		}
+		for _, alias := range stmt.Vulnerability.Aliases {
+			vuln.Aliases = appendUnique(vuln.Aliases, string(alias))
+		}
+		for _, product := range stmt.Products {
+			for _, sub := range product.Subcomponents {
+				vuln.Packages = appendUnique(vuln.Packages, componentID(sub.Component))
+			}
+		}
+	}
+
+	fixed := make([]types.FixedVulnerability, 0, len(byID))
+	for _, vuln := range byID {
+		sort.Strings(vuln.Packages)
+		fixed = append(fixed, *vuln)
+	}
+	sort.Slice(fixed, func(i, j int) bool { return fixed[i].ID < fixed[j].ID })
+	return fixed, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,32 @@
+package copa
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFixedVulnerabilities(t *testing.T) {
+	dir := t.TempDir()
+	vexPath := filepath.Join(dir, "vex.json")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-amd64.json"), "CVE-2023-0002", "pkg:oci/alpine?platform=linux/amd64")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-arm64.json"), "CVE-2023-0002", "pkg:oci/alpine?platform=linux/arm64")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-386.json"), "CVE-2023-0001", "pkg:oci/alpine?platform=linux/386")
+	require.NoError(t, mergeVexDocuments(vexPath))
+
+	fixed, err := ListFixedVulnerabilities(vexPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.FixedVulnerability{
+		{ID: "CVE-2023-0001", Packages: []string{"pkg:apk/alpine/openssl"}},
+		{ID: "CVE-2023-0002", Packages: []string{"pkg:apk/alpine/openssl"}},
+	}, fixed)
+}
+
+func TestListFixedVulnerabilities_MissingDocument(t *testing.T) {
+	_, err := ListFixedVulnerabilities(filepath.Join(t.TempDir(), "vex.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package copa
+
+import (
+	"sync"
+	"time"
+)
+
+// diskSampleInterval is how often free space in the temp directory is sampled while copa runs
+const diskSampleInterval = time.Second
+
+// diskSampler tracks the peak drop in free space on the filesystem of a directory while a command
+// runs. Other processes writing to the same filesystem are included, so the result is an upper bound.
+type diskSampler struct {
+	dir      string
+	baseline uint64
+	lowest   uint64
+	stop     chan struct{}
+	done     sync.WaitGroup
+}
+
+// startDiskSampler samples free space in dir every interval until Stop is called. It returns nil
+// when free space cannot be determined, and a nil sampler reports no usage.
+func startDiskSampler(dir string, interval time.Duration) *diskSampler {
+	baseline, err := AvailableDiskSpace(dir)
+	if err != nil {
+		return nil
+	}
+
+	s := &diskSampler{dir: dir, baseline: baseline, lowest: baseline, stop: make(chan struct{})}
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+	return s
+}
+
+func (s *diskSampler) sample() {
+	if available, err := AvailableDiskSpace(s.dir); err == nil && available < s.lowest {
+		s.lowest = available
+	}
+}
+
+// Stop stops sampling and returns the peak disk usage in bytes
+func (s *diskSampler) Stop() int64 {
+	if s == nil {
+		return 0
+	}
+	close(s.stop)
+	s.done.Wait()
+	s.sample()
+	return int64(s.baseline - s.lowest)
+}
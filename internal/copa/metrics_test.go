@@ -0,0 +1,25 @@
+package copa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskSampler(t *testing.T) {
+	sampler := startDiskSampler(t.TempDir(), 10*time.Millisecond)
+	require.NotNil(t, sampler)
+
+	time.Sleep(30 * time.Millisecond)
+
+	assert.GreaterOrEqual(t, sampler.Stop(), int64(0))
+}
+
+func TestDiskSampler_Unavailable(t *testing.T) {
+	sampler := startDiskSampler("/nonexistent/dir", time.Millisecond)
+
+	assert.Nil(t, sampler)
+	assert.Zero(t, sampler.Stop())
+}
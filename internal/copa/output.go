@@ -0,0 +1,100 @@
+package copa
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+const (
+	// outputTailBytes bounds the end of copa's stdout and stderr kept for the result, so a verbose
+	// run on a large image does not hold its whole output in memory
+	outputTailBytes = 64 << 10
+	// maxLogLineBytes bounds a line passed to the output log; longer lines are split
+	maxLogLineBytes = 4096
+)
+
+// tailWriter keeps the last max bytes written to it
+type tailWriter struct {
+	max int
+	buf []byte
+	cut bool // Earlier output was dropped
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= w.max {
+		w.buf = append(w.buf[:0], p[len(p)-w.max:]...)
+		w.cut = w.cut || len(p) > w.max
+		return n, nil
+	}
+	// Compacting only once the buffer doubles keeps writes amortized O(len(p))
+	if len(w.buf)+len(p) > 2*w.max {
+		w.buf = append(w.buf[:0], w.buf[len(w.buf)-(w.max-len(p)):]...)
+		w.cut = true
+	}
+	w.buf = append(w.buf, p...)
+	return n, nil
+}
+
+// String returns the kept output, without a partial UTF-8 sequence left at its start by the cut
+func (w *tailWriter) String() string {
+	out := w.buf
+	if len(out) > w.max {
+		out = out[len(out)-w.max:]
+	}
+	if w.cut || len(w.buf) > w.max {
+		for len(out) > 0 && !utf8.RuneStart(out[0]) {
+			out = out[1:]
+		}
+	}
+	return string(out)
+}
+
+// lineWriter calls log with each line written to it, without its line ending. Call Flush for a
+// last line without one.
+type lineWriter struct {
+	log     func(line string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		end := i
+		if i < 0 {
+			end = len(p)
+		}
+		w.partial = append(w.partial, p[:end]...)
+		for len(w.partial) >= maxLogLineBytes {
+			w.emit(w.partial[:maxLogLineBytes])
+			w.partial = append(w.partial[:0], w.partial[maxLogLineBytes:]...)
+		}
+		if i < 0 {
+			break
+		}
+		w.emit(w.partial)
+		w.partial = w.partial[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush logs the last line if it had no line ending
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.emit(w.partial)
+		w.partial = w.partial[:0]
+	}
+}
+
+func (w *lineWriter) emit(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) > 0 {
+		w.log(string(line))
+	}
+}
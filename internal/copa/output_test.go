@@ -0,0 +1,53 @@
+package copa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailWriter(t *testing.T) {
+	w := newTailWriter(8)
+	assert.Empty(t, w.String())
+
+	w.Write([]byte("abc"))
+	w.Write([]byte("defgh"))
+	assert.Equal(t, "abcdefgh", w.String())
+
+	for i := 0; i < 10; i++ {
+		w.Write([]byte("0123"))
+	}
+	assert.Equal(t, "01230123", w.String())
+	assert.LessOrEqual(t, len(w.buf), 16, "the buffer stays bounded")
+
+	w.Write([]byte("a much longer write"))
+	assert.Equal(t, "er write", w.String())
+}
+
+func TestTailWriter_CutsWholeRunes(t *testing.T) {
+	w := newTailWriter(5)
+	w.Write([]byte("xx"))
+	w.Write([]byte("ééé"))
+	assert.Equal(t, "éé", w.String())
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{log: func(line string) { lines = append(lines, line) }}
+
+	w.Write([]byte("Updating packages\r\nopenssl 3.1.4-r0"))
+	w.Write([]byte(" -> 3.1.4-r5\n\npushing"))
+	assert.Equal(t, []string{"Updating packages", "openssl 3.1.4-r0 -> 3.1.4-r5"}, lines)
+
+	w.Flush()
+	assert.Equal(t, []string{"Updating packages", "openssl 3.1.4-r0 -> 3.1.4-r5", "pushing"}, lines)
+}
+
+func TestLineWriter_SplitsLongLines(t *testing.T) {
+	var lines []string
+	w := &lineWriter{log: func(line string) { lines = append(lines, line) }}
+
+	w.Write([]byte(strings.Repeat("x", maxLogLineBytes+10) + "\n"))
+	assert.Equal(t, []string{strings.Repeat("x", maxLogLineBytes), strings.Repeat("x", 10)}, lines)
+}
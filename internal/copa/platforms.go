@@ -0,0 +1,113 @@
+package copa
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// singleReportFile is the report written by the trivy scanner when no platforms are requested
+const singleReportFile = "report.json"
+
+// platformResults reports the outcome of each platform of a multi-platform patch: the platforms
+// requested for platform-selective patches, or the platforms scanned for report-based patches.
+// It returns nil when the patched platforms are not known, as for comprehensive patches.
+func (c *CLI) platformResults(result *ExecutionResult) ([]types.PlatformResult, error) {
+	platforms := c.platforms
+	if len(platforms) == 0 && c.reportPath != "" {
+		var err error
+		if platforms, err = reportPlatforms(c.reportPath); err != nil {
+			return nil, err
+		}
+	}
+	if len(platforms) == 0 {
+		return nil, nil
+	}
+
+	fixed := map[string]int{}
+	if c.vexPath != "" && !c.dryRun {
+		var err error
+		if fixed, err = fixedByPlatform(c.vexPath); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]types.PlatformResult, 0, len(platforms))
+	for _, platform := range platforms {
+		if !IsPlatformSupported(platform) {
+			results = append(results, types.PlatformResult{Platform: platform, Status: types.PlatformStatusSkipped})
+			continue
+		}
+		results = append(results, types.PlatformResult{
+			Platform:     platform,
+			PatchedImage: result.PatchedImage,
+			Status:       types.PlatformStatusPatched,
+			FixedVulns:   fixed[platform],
+			Duration:     result.Duration.Round(time.Second).String(),
+		})
+	}
+	return results, nil
+}
+
+// reportPlatforms returns the platforms scanned into reportPath, derived from the per-platform
+// report file names (e.g. linux-arm-v7.json). A single-platform report has no platforms.
+func reportPlatforms(reportPath string) ([]string, error) {
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var platforms []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" || name == singleReportFile {
+			continue
+		}
+		platforms = append(platforms, strings.ReplaceAll(strings.TrimSuffix(name, ".json"), "-", "/"))
+	}
+	return platforms, nil
+}
+
+// fixedByPlatform counts the fixed vulnerabilities in the VEX document at path for each platform,
+// using the platform qualifier of the product purls copa writes for multi-platform patches
+func fixedByPlatform(path string) (map[string]int, error) {
+	doc, err := readVexDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, stmt := range doc.Statements {
+		if stmt.Status != vex.StatusFixed {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, product := range stmt.Products {
+			platform := purlPlatform(product.ID)
+			if platform == "" || seen[platform] {
+				continue
+			}
+			seen[platform] = true
+			counts[platform]++
+		}
+	}
+	return counts, nil
+}
+
+// purlPlatform returns the platform qualifier of a package URL, or "" if it has none
+func purlPlatform(purl string) string {
+	_, query, ok := strings.Cut(purl, "?")
+	if !ok {
+		return ""
+	}
+	qualifiers, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	return qualifiers.Get("platform")
+}
@@ -0,0 +1,71 @@
+package copa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurlPlatform(t *testing.T) {
+	assert.Equal(t, "linux/amd64", purlPlatform("pkg:oci/alpine?platform=linux/amd64"))
+	assert.Equal(t, "linux/arm/v7", purlPlatform("pkg:oci/alpine?repository_url=docker.io/library/alpine&platform=linux%2Farm%2Fv7"))
+	assert.Empty(t, purlPlatform("pkg:oci/alpine"))
+}
+
+func TestReportPlatforms(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"linux-amd64.json", "linux-arm-v7.json", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600))
+	}
+
+	platforms, err := reportPlatforms(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm/v7"}, platforms)
+
+	single := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(single, singleReportFile), []byte("{}"), 0o600))
+	platforms, err = reportPlatforms(single)
+	require.NoError(t, err)
+	assert.Empty(t, platforms)
+}
+
+func TestFixedByPlatform(t *testing.T) {
+	dir := t.TempDir()
+	vexPath := filepath.Join(dir, "vex.json")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-amd64.json"), "CVE-2023-0001", "pkg:oci/alpine?platform=linux/amd64")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-arm64.json"), "CVE-2023-0002", "pkg:oci/alpine?platform=linux/arm64")
+	require.NoError(t, mergeVexDocuments(vexPath))
+
+	counts, err := fixedByPlatform(vexPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"linux/amd64": 1, "linux/arm64": 1}, counts)
+}
+
+func TestPlatformResults_SkipsUnsupportedPlatforms(t *testing.T) {
+	params := types.PlatformSelectivePatchParams{
+		Image:    "alpine:3.17",
+		Tag:      "patched",
+		Platform: []string{"linux/amd64", "windows/amd64"},
+	}
+	cli := New(params, true)
+
+	results, err := cli.platformResults(&ExecutionResult{PatchedImage: "alpine:patched", Duration: 90 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, []types.PlatformResult{
+		{Platform: "linux/amd64", PatchedImage: "alpine:patched", Status: types.PlatformStatusPatched, Duration: "1m30s"},
+		{Platform: "windows/amd64", Status: types.PlatformStatusSkipped},
+	}, results)
+}
+
+func TestPlatformResults_UnknownPlatforms(t *testing.T) {
+	cli := New(types.ComprehensivePatchParams{Image: "alpine:3.17", Tag: "patched"}, true)
+
+	results, err := cli.platformResults(&ExecutionResult{})
+	require.NoError(t, err)
+	assert.Nil(t, results)
+}
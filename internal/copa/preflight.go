@@ -0,0 +1,77 @@
+package copa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+const (
+	// diskSpaceFactor is applied to the image size to estimate the space needed while patching.
+	// Buildkit unpacks the image layers, writes the patch layer and exports the result, so the
+	// working set is a small multiple of the original image.
+	diskSpaceFactor = 3
+
+	// MinDiskSpace is required when the image size cannot be determined (e.g. remote images), and
+	// is the least space 'doctor' expects in the working directory
+	MinDiskSpace uint64 = 1 << 30 // 1 GiB
+)
+
+// estimateRequiredSpace estimates the disk space needed to patch the image
+func estimateRequiredSpace(ctx context.Context, dockerHost, image string) uint64 {
+	size, err := docker.ImageSize(ctx, dockerHost, image)
+	if err != nil || size <= 0 {
+		// Image is not local (or docker is unavailable), fall back to the minimum
+		return MinDiskSpace
+	}
+
+	return max(uint64(size)*diskSpaceFactor, MinDiskSpace)
+}
+
+// checkDiskSpace verifies that dir has at least required bytes available
+func checkDiskSpace(dir string, required uint64) error {
+	available, err := AvailableDiskSpace(dir)
+	if err != nil {
+		return copaerrors.NewSystemError(fmt.Sprintf("failed to determine available disk space in %s", dir), err)
+	}
+
+	if available < required {
+		return copaerrors.NewSystemError(fmt.Sprintf("insufficient disk space in %s: %s available, at least %s required to patch the image",
+			dir, FormatBytes(available), FormatBytes(required)), nil,
+			"free up disk space (e.g. 'docker system prune')",
+			"point TMPDIR to a larger volume")
+	}
+
+	return nil
+}
+
+// preflight checks host resources before copa is started so that patching
+// fails fast instead of running out of space mid-patch
+func (c *CLI) preflight(ctx context.Context) error {
+	if c.dryRun {
+		return nil
+	}
+
+	dir, err := workdir.Dir()
+	if err != nil {
+		return err
+	}
+	return checkDiskSpace(dir, estimateRequiredSpace(ctx, c.dockerHost, c.image))
+}
+
+// FormatBytes renders a byte count in a human readable form (e.g. 1.5 GiB)
+func FormatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
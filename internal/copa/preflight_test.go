@@ -0,0 +1,53 @@
+package copa
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, checkDiskSpace(dir, 1))
+
+	err := checkDiskSpace(dir, math.MaxUint64)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient disk space")
+}
+
+func TestCheckDiskSpace_InvalidDir(t *testing.T) {
+	err := checkDiskSpace("/nonexistent/dir/for/preflight", 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to determine available disk space")
+}
+
+func TestEstimateRequiredSpace_UnknownImage(t *testing.T) {
+	required := estimateRequiredSpace(context.Background(), "", "nonexistent/image:definitely-not-local")
+	assert.Equal(t, MinDiskSpace, required)
+}
+
+func TestPreflight_DryRunSkipped(t *testing.T) {
+	cli := New(types.ComprehensivePatchParams{Image: "alpine:3.17"}, true)
+	assert.NoError(t, cli.preflight(context.Background()))
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    uint64
+		expected string
+	}{
+		{512, "512 B"},
+		{1024, "1.0 KiB"},
+		{1536 * 1024 * 1024, "1.5 GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatBytes(tt.bytes))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package copa
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// maxRemainingIDs caps the vulnerability IDs listed for each class of remaining vulnerabilities
+const maxRemainingIDs = 50
+
+// ClassifyRemaining sorts the findings the patch did not fix by why they remain: excluded by the
+// call's excludeCVEs, minSeverity or severity, in a language library, without a fix in the distribution, or
+// with a fix that was not applied. Findings triaged by vexInput do not apply to the image and are
+// skipped. source is the origin of the findings, types.RemainingSourceReport or types.RemainingSourceScan.
+func (c *CLI) ClassifyRemaining(findings []trivy.Finding, fixed []types.FixedVulnerability, source string) *types.RemainingVulns {
+	fixedIDs := map[string]bool{}
+	for _, vuln := range fixed {
+		for _, id := range append([]string{vuln.ID}, vuln.Aliases...) {
+			fixedIDs[strings.ToUpper(id)] = true
+		}
+	}
+	excluded := map[string]bool{}
+	for _, id := range c.excludeCVEs {
+		excluded[strings.ToUpper(strings.TrimSpace(id))] = true
+	}
+
+	// A vulnerability found in several packages or platforms is classified once, by its first finding
+	remaining := &types.RemainingVulns{Source: source}
+	seen := map[string]bool{}
+	var noFix, library, excludedIDs, notApplied []string
+	for _, f := range findings {
+		id := strings.ToUpper(f.ID)
+		if seen[id] || fixedIDs[id] || c.triaged[id] {
+			continue
+		}
+		seen[id] = true
+		switch {
+		case excluded[id] || c.filtersSeverity(f.Severity):
+			excludedIDs = append(excludedIDs, f.ID)
+		case f.Library:
+			library = append(library, f.ID)
+		case f.FixedVersion == "":
+			noFix = append(noFix, f.ID)
+		default:
+			notApplied = append(notApplied, f.ID)
+		}
+	}
+
+	remaining.NoFixAvailable = remainingClass(noFix)
+	remaining.Library = remainingClass(library)
+	remaining.Excluded = remainingClass(excludedIDs)
+	remaining.NotApplied = remainingClass(notApplied)
+	return remaining
+}
+
+// remainingClass counts ids and lists at most maxRemainingIDs of them, sorted
+func remainingClass(ids []string) types.RemainingClass {
+	slices.Sort(ids)
+	return types.RemainingClass{Count: len(ids), IDs: ids[:min(len(ids), maxRemainingIDs)]}
+}
@@ -0,0 +1,48 @@
+package copa
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRemaining(t *testing.T) {
+	c := &CLI{
+		excludeCVEs: []string{" cve-2023-0005 "},
+		minSeverity: "MEDIUM",
+		triaged:     map[string]bool{"CVE-2023-0007": true},
+	}
+	findings := []trivy.Finding{
+		{ID: "CVE-2023-0001", Severity: "CRITICAL", FixedVersion: "1.1"},
+		{ID: "CVE-2023-0002", Severity: "HIGH", FixedVersion: "2.1"},
+		{ID: "CVE-2023-0002", Severity: "HIGH", FixedVersion: "2.1"},
+		{ID: "CVE-2023-0003", Severity: "HIGH"},
+		{ID: "GHSA-aaaa", Severity: "CRITICAL", FixedVersion: "4.17.21", Library: true},
+		{ID: "CVE-2023-0005", Severity: "CRITICAL", FixedVersion: "5.1"},
+		{ID: "CVE-2023-0006", Severity: "LOW", FixedVersion: "6.1"},
+		{ID: "CVE-2023-0007", Severity: "HIGH", FixedVersion: "7.1"},
+	}
+	fixed := []types.FixedVulnerability{{ID: "CVE-2023-0001"}}
+
+	assert.Equal(t, &types.RemainingVulns{
+		Source:         types.RemainingSourceScan,
+		NoFixAvailable: types.RemainingClass{Count: 1, IDs: []string{"CVE-2023-0003"}},
+		Library:        types.RemainingClass{Count: 1, IDs: []string{"GHSA-aaaa"}},
+		Excluded:       types.RemainingClass{Count: 2, IDs: []string{"CVE-2023-0005", "CVE-2023-0006"}},
+		NotApplied:     types.RemainingClass{Count: 1, IDs: []string{"CVE-2023-0002"}},
+	}, c.ClassifyRemaining(findings, fixed, types.RemainingSourceScan))
+}
+
+func TestClassifyRemaining_CapsIDs(t *testing.T) {
+	var findings []trivy.Finding
+	for i := range maxRemainingIDs + 10 {
+		findings = append(findings, trivy.Finding{ID: fmt.Sprintf("CVE-2023-%04d", i), Severity: "HIGH"})
+	}
+
+	remaining := (&CLI{}).ClassifyRemaining(findings, nil, types.RemainingSourceReport)
+	assert.Equal(t, maxRemainingIDs+10, remaining.NoFixAvailable.Count)
+	assert.Len(t, remaining.NoFixAvailable.IDs, maxRemainingIDs)
+}
@@ -0,0 +1,72 @@
+package copa
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// filteredReportPrefix is the prefix of the temporary directories holding filtered copies of scan reports
+const filteredReportPrefix = "report-filtered-"
+
+// filtersReport reports whether the scan report must be filtered before it is passed to copa
+func (c *CLI) filtersReport() bool {
+	return len(c.excludeCVEs) > 0 || c.minSeverity != "" || len(c.severities) > 0 || c.vexInput != ""
+}
+
+// filterReport writes a copy of the scan report without the vulnerabilities that should not be
+// patched to a temporary directory, leaving the caller's report untouched, and returns its path
+func (c *CLI) filterReport() (string, error) {
+	dir, err := workdir.MkdirTemp(filteredReportPrefix + "*")
+	if err != nil {
+		return "", err
+	}
+	c.filteredReportDir = dir
+
+	excluded := make(map[string]bool, len(c.excludeCVEs))
+	for _, id := range c.excludeCVEs {
+		excluded[strings.ToUpper(strings.TrimSpace(id))] = true
+	}
+
+	// Vulnerabilities already triaged elsewhere are neither patched nor reported again
+	triaged := map[string]bool{}
+	if c.vexInput != "" {
+		if triaged, err = triagedVulnerabilities(c.vexInput); err != nil {
+			return "", fmt.Errorf("failed to read vexInput: %w", err)
+		}
+		c.triaged = triaged
+	}
+
+	dropped, err := trivy.FilterReport(c.reportPath, dir, func(id, severity string) bool {
+		if c.filtersSeverity(severity) {
+			return false
+		}
+		return !excluded[strings.ToUpper(id)] && !triaged[strings.ToUpper(id)]
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, id := range dropped {
+		delete(excluded, strings.ToUpper(id))
+	}
+	if len(excluded) > 0 {
+		c.warn("excluded vulnerabilities not found in the report: %s", strings.Join(sortedKeys(excluded), ", "))
+	}
+	return dir, nil
+}
+
+// removeFilteredReport removes the filtered copy of the scan report, if one was created
+func (c *CLI) removeFilteredReport() error {
+	if c.filteredReportDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(c.filteredReportDir); err != nil {
+		return fmt.Errorf("failed to remove filtered report %s: %w", c.filteredReportDir, err)
+	}
+	c.filteredReportDir = ""
+	return nil
+}
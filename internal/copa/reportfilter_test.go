@@ -0,0 +1,157 @@
+package copa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeReport(t *testing.T, dir string) {
+	t.Helper()
+
+	report := `{"Results":[{"Vulnerabilities":[` +
+		`{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL"},` +
+		`{"VulnerabilityID":"CVE-2023-0002","Severity":"MEDIUM"},` +
+		`{"VulnerabilityID":"CVE-2023-0003","Severity":"LOW"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(report), 0o600))
+}
+
+func TestBuildWithReport_ExcludeCVEs(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	params := types.ReportBasedPatchParams{
+		Image:       "alpine:3.17",
+		Tag:         "patched",
+		ReportPath:  reportDir,
+		ExcludeCVEs: []string{"cve-2023-0002", "CVE-2023-9999"},
+	}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { cli.Cleanup() })
+
+	filteredDir := cli.filteredReportDir
+	require.NotEmpty(t, filteredDir)
+	assert.Contains(t, cli.cmd.Args, filteredDir)
+	assert.NotContains(t, cli.cmd.Args, reportDir)
+
+	severities, err := trivy.Severities(filteredDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0001": "CRITICAL", "CVE-2023-0003": "LOW"}, severities)
+
+	require.Len(t, cli.warnings, 1)
+	assert.Contains(t, cli.warnings[0], "CVE-2023-9999")
+
+	require.NoError(t, cli.Cleanup())
+	assert.NoDirExists(t, filteredDir)
+	assert.DirExists(t, reportDir)
+}
+
+func TestBuildWithReport_NoFilter(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: reportDir}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { cli.Cleanup() })
+
+	assert.Empty(t, cli.filteredReportDir)
+	assert.Contains(t, cli.cmd.Args, reportDir)
+}
+
+func TestBuildWithReport_MinSeverity(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	params := types.ReportBasedPatchParams{
+		Image:       "alpine:3.17",
+		Tag:         "patched",
+		ReportPath:  reportDir,
+		MinSeverity: "medium",
+		ExcludeCVEs: []string{"CVE-2023-0003"},
+	}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { cli.Cleanup() })
+	require.NoError(t, cli.validateCommand())
+
+	severities, err := trivy.Severities(cli.filteredReportDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0001": "CRITICAL", "CVE-2023-0002": "MEDIUM"}, severities)
+	// The excluded vulnerability was in the report, even though minSeverity also dropped it
+	assert.Empty(t, cli.warnings)
+}
+
+func TestBuildWithReport_Severity(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	params := types.ReportBasedPatchParams{
+		Image:      "alpine:3.17",
+		Tag:        "patched",
+		ReportPath: reportDir,
+		Severity:   []string{"critical", "LOW"},
+	}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { cli.Cleanup() })
+
+	severities, err := trivy.Severities(cli.filteredReportDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0001": "CRITICAL", "CVE-2023-0003": "LOW"}, severities)
+}
+
+func TestValidateMinSeverity(t *testing.T) {
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", MinSeverity: "severe"}
+
+	err := New(params, true).validateMinSeverity()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported minSeverity: SEVERE")
+}
+
+func TestAtOrAbove(t *testing.T) {
+	assert.True(t, atOrAbove("CRITICAL", "HIGH"))
+	assert.True(t, atOrAbove("HIGH", "HIGH"))
+	assert.False(t, atOrAbove("MEDIUM", "HIGH"))
+	assert.False(t, atOrAbove("UNKNOWN", "LOW"))
+}
+
+func TestBuildWithReport_VexInput(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	vexInput := filepath.Join(t.TempDir(), "triage.json")
+	writeVexDocument(t, vexInput, "CVE-2023-0001", "pkg:oci/alpine")
+	params := types.ReportBasedPatchParams{Image: "alpine:3.17", Tag: "patched", ReportPath: reportDir, VexInput: vexInput}
+
+	cli := New(params, true).BuildWithReport()
+	require.NoError(t, cli.buildErr)
+	t.Cleanup(func() { cli.Cleanup() })
+
+	severities, err := trivy.Severities(cli.filteredReportDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0002": "MEDIUM", "CVE-2023-0003": "LOW"}, severities)
+	assert.Equal(t, map[string]bool{"CVE-2023-0001": true}, cli.triaged)
+}
+
+func TestBuildWithReport_MissingVexInput(t *testing.T) {
+	reportDir := t.TempDir()
+	writeReport(t, reportDir)
+	params := types.ReportBasedPatchParams{
+		Image:      "alpine:3.17",
+		Tag:        "patched",
+		ReportPath: reportDir,
+		VexInput:   filepath.Join(t.TempDir(), "missing.json"),
+	}
+
+	cli := New(params, true).BuildWithReport()
+	t.Cleanup(func() { cli.Cleanup() })
+
+	require.Error(t, cli.buildErr)
+	assert.Contains(t, cli.buildErr.Error(), "vexInput")
+}
@@ -0,0 +1,138 @@
+package copa
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Severities lists the severities that can be budgeted with maxRemaining or used as minSeverity, from highest to lowest
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+// defaultBudgetSeverity counts every known severity against maxRemaining
+const defaultBudgetSeverity = "LOW"
+
+// summarizeSeverity counts the vulnerabilities of a scan report by severity, split into those in
+// fixed and those that remain. severities maps the report's vulnerability IDs to their severity.
+// The severity of each fixed vulnerability found in the report is recorded in fixed.
+func summarizeSeverity(severities map[string]string, fixed []types.FixedVulnerability) *types.SeveritySummary {
+	fixedIDs := map[string]bool{}
+	for i, vuln := range fixed {
+		for _, id := range append([]string{vuln.ID}, vuln.Aliases...) {
+			fixedIDs[id] = true
+			if severity, ok := severities[id]; ok && fixed[i].Severity == "" {
+				fixed[i].Severity = severity
+			}
+		}
+	}
+
+	summary := &types.SeveritySummary{}
+	for id, severity := range severities {
+		if fixedIDs[id] {
+			countSeverity(&summary.Fixed, severity)
+		} else {
+			countSeverity(&summary.Remaining, severity)
+		}
+	}
+	return summary
+}
+
+// countSeverity adds one vulnerability of the given trivy severity to counts
+func countSeverity(counts *types.SeverityCounts, severity string) {
+	switch severity {
+	case "CRITICAL":
+		counts.Critical++
+	case "HIGH":
+		counts.High++
+	case "MEDIUM":
+		counts.Medium++
+	case "LOW":
+		counts.Low++
+	default:
+		counts.Unknown++
+	}
+}
+
+// validateBudget checks the vulnerability budget parameters
+func (c *CLI) validateBudget() error {
+	if c.maxRemaining == nil {
+		if c.budgetSeverity != "" {
+			return copaerrors.NewValidationError("maxRemainingSeverity requires maxRemaining", nil)
+		}
+		return nil
+	}
+	if *c.maxRemaining < 0 {
+		return copaerrors.NewValidationError("maxRemaining must not be negative", nil)
+	}
+	if c.budgetSeverity != "" && !slices.Contains(Severities, c.budgetSeverity) {
+		return copaerrors.NewValidationError(fmt.Sprintf("unsupported maxRemainingSeverity: %s", c.budgetSeverity), nil,
+			fmt.Sprintf("use one of %s", strings.Join(Severities, ", ")))
+	}
+	return nil
+}
+
+// validateMinSeverity checks the minimum severity of the vulnerabilities to patch
+func (c *CLI) validateMinSeverity() error {
+	if c.minSeverity != "" && !slices.Contains(Severities, c.minSeverity) {
+		return copaerrors.NewValidationError(fmt.Sprintf("unsupported minSeverity: %s", c.minSeverity), nil,
+			fmt.Sprintf("use one of %s", strings.Join(Severities, ", ")))
+	}
+	return nil
+}
+
+// filtersSeverity reports whether vulnerabilities of severity are removed from the report by
+// minSeverity or severities. An empty severity is trivy's UNKNOWN.
+func (c *CLI) filtersSeverity(severity string) bool {
+	if c.minSeverity != "" && !atOrAbove(severity, c.minSeverity) {
+		return true
+	}
+	if severity == "" {
+		severity = "UNKNOWN"
+	}
+	return len(c.severities) > 0 && !slices.Contains(c.severities, severity)
+}
+
+// atOrAbove reports whether severity is at or above threshold. Unknown severities are below every threshold.
+func atOrAbove(severity, threshold string) bool {
+	i := slices.Index(Severities, severity)
+	return i >= 0 && i <= slices.Index(Severities, threshold)
+}
+
+// CheckBudget returns a policy error if more vulnerabilities at or above the budget severity
+// remain after the patch than maxRemaining allows. It is a no-op when no budget was set.
+func (c *CLI) CheckBudget(result *ExecutionResult) error {
+	if c.maxRemaining == nil || result.Severity == nil {
+		return nil
+	}
+
+	severity := c.budgetSeverity
+	if severity == "" {
+		severity = defaultBudgetSeverity
+	}
+
+	remaining := countAtOrAbove(result.Severity.Remaining, severity)
+	if remaining <= *c.maxRemaining {
+		return nil
+	}
+	return copaerrors.NewPolicyError(
+		fmt.Sprintf("vulnerability budget exceeded: %d vulnerabilities at or above %s remain after patching %s, at most %d allowed",
+			remaining, severity, result.PatchedImage, *c.maxRemaining), nil,
+		"the patched image was created; rebuild from an updated base image to fix the remaining vulnerabilities",
+		"raise maxRemaining or maxRemainingSeverity if the remaining vulnerabilities are accepted")
+}
+
+// countAtOrAbove counts the vulnerabilities at or above severity
+func countAtOrAbove(counts types.SeverityCounts, severity string) int {
+	bySeverity := []int{counts.Critical, counts.High, counts.Medium, counts.Low}
+	total := 0
+	for i, s := range Severities {
+		total += bySeverity[i]
+		if s == severity {
+			break
+		}
+	}
+	return total
+}
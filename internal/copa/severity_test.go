@@ -0,0 +1,85 @@
+package copa
+
+import (
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeSeverity(t *testing.T) {
+	severities := map[string]string{
+		"CVE-2023-0001": "CRITICAL",
+		"CVE-2023-0002": "HIGH",
+		"CVE-2023-0003": "LOW",
+		"GHSA-aaaa":     "MEDIUM",
+	}
+	fixed := []types.FixedVulnerability{
+		{ID: "CVE-2023-0001"},
+		{ID: "CVE-2023-0004", Aliases: []string{"GHSA-aaaa"}},
+	}
+
+	summary := summarizeSeverity(severities, fixed)
+
+	assert.Equal(t, types.SeveritySummary{
+		Fixed:     types.SeverityCounts{Critical: 1, Medium: 1},
+		Remaining: types.SeverityCounts{High: 1, Low: 1},
+	}, *summary)
+	assert.Equal(t, "CRITICAL", fixed[0].Severity)
+	assert.Equal(t, "MEDIUM", fixed[1].Severity)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCheckBudget(t *testing.T) {
+	result := &ExecutionResult{
+		PatchedImage: "alpine:patched",
+		Severity: &types.SeveritySummary{
+			Remaining: types.SeverityCounts{Critical: 1, High: 2, Medium: 3, Low: 4, Unknown: 5},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		maxRemaining *int
+		severity     string
+		wantErr      bool
+	}{
+		{name: "no budget"},
+		{name: "all severities within budget", maxRemaining: intPtr(10)},
+		{name: "all severities over budget", maxRemaining: intPtr(9), wantErr: true},
+		{name: "high and above within budget", maxRemaining: intPtr(3), severity: "HIGH"},
+		{name: "critical over budget", maxRemaining: intPtr(0), severity: "CRITICAL", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := &CLI{maxRemaining: tt.maxRemaining, budgetSeverity: tt.severity}
+
+			err := cli.CheckBudget(result)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, copaerrors.CategoryPolicy, copaerrors.CategoryOf(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	assert.NoError(t, (&CLI{}).validateBudget())
+	assert.NoError(t, (&CLI{maxRemaining: intPtr(0), budgetSeverity: "HIGH"}).validateBudget())
+	assert.Error(t, (&CLI{maxRemaining: intPtr(-1)}).validateBudget())
+	assert.Error(t, (&CLI{maxRemaining: intPtr(1), budgetSeverity: "SEVERE"}).validateBudget())
+	assert.Error(t, (&CLI{budgetSeverity: "HIGH"}).validateBudget())
+}
+
+func TestNew_BudgetSeverityIsCaseInsensitive(t *testing.T) {
+	cli := New(types.ReportBasedPatchParams{Image: "alpine:3.17", MaxRemaining: intPtr(0), MaxRemainingSeverity: "high"}, true)
+
+	assert.Equal(t, "HIGH", cli.budgetSeverity)
+}
@@ -0,0 +1,145 @@
+package copa
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openvex/go-vex/pkg/vex"
+)
+
+// findVexDocuments returns the VEX documents written for vexPath. For multi-platform
+// patches copa may write one document per platform next to vexPath (e.g. vex-linux-amd64.json),
+// so any JSON file sharing vexPath's base name is included.
+func findVexDocuments(vexPath string) ([]string, error) {
+	ext := filepath.Ext(vexPath)
+	base := strings.TrimSuffix(filepath.Base(vexPath), ext)
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(vexPath), globEscape(base)+"*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// globEscape escapes glob metacharacters in a file name
+func globEscape(name string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	return replacer.Replace(name)
+}
+
+// readVexDocument reads and parses an OpenVEX document
+func readVexDocument(path string) (*vex.VEX, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc vex.VEX
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// mergeVexDocuments consolidates the per-platform VEX documents for vexPath into a
+// single OpenVEX document written to vexPath. It is a no-op when only one document exists.
+func mergeVexDocuments(vexPath string) error {
+	paths, err := findVexDocuments(vexPath)
+	if err != nil {
+		return err
+	}
+	if len(paths) <= 1 {
+		return nil
+	}
+
+	docs := make([]*vex.VEX, 0, len(paths))
+	for _, path := range paths {
+		doc, err := readVexDocument(path)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	merged, err := vex.MergeDocumentsWithOptions(&vex.MergeOptions{
+		Author:     docs[0].Author,
+		AuthorRole: docs[0].AuthorRole,
+	}, docs)
+	if err != nil {
+		return fmt.Errorf("failed to merge vex documents: %w", err)
+	}
+	merged.Tooling = docs[0].Tooling
+
+	f, err := os.Create(vexPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := merged.ToJSON(f); err != nil {
+		return fmt.Errorf("failed to write merged vex document: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Merged %d per-platform VEX documents into %s", len(docs), vexPath), "logger", "copa")
+	return nil
+}
+
+// annotateVexDocument adds notes to every statement of the OpenVEX document at vexPath and,
+// when author is set, records it as the document author. Notes are appended to any status notes
+// copa already wrote, so that e.g. a change ticket ID can be traced from each statement.
+func annotateVexDocument(vexPath, notes, author string) error {
+	doc, err := readVexDocument(vexPath)
+	if err != nil {
+		return err
+	}
+
+	if author != "" {
+		doc.Author = author
+	}
+	if notes != "" {
+		for i := range doc.Statements {
+			stmt := &doc.Statements[i]
+			if stmt.StatusNotes == "" {
+				stmt.StatusNotes = notes
+			} else {
+				stmt.StatusNotes += "\n" + notes
+			}
+		}
+	}
+
+	f, err := os.Create(vexPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := doc.ToJSON(f); err != nil {
+		return fmt.Errorf("failed to write annotated vex document: %w", err)
+	}
+	return nil
+}
+
+// triagedVulnerabilities returns the IDs, upper-cased, of the vulnerabilities that the OpenVEX
+// document at path marks as not_affected or fixed, including their aliases
+func triagedVulnerabilities(path string) (map[string]bool, error) {
+	doc, err := readVexDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	triaged := map[string]bool{}
+	for _, stmt := range doc.Statements {
+		if stmt.Status != vex.StatusNotAffected && stmt.Status != vex.StatusFixed {
+			continue
+		}
+		triaged[strings.ToUpper(string(stmt.Vulnerability.Name))] = true
+		for _, alias := range stmt.Vulnerability.Aliases {
+			triaged[strings.ToUpper(string(alias))] = true
+		}
+	}
+	return triaged, nil
+}
@@ -0,0 +1,107 @@
+package copa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeVexDocument(t *testing.T, path, vulnID, product string) {
+	t.Helper()
+
+	doc := vex.New()
+	doc.ID = "https://openvex.dev/docs/" + filepath.Base(path)
+	doc.Author = "Project Copacetic"
+	doc.Statements = []vex.Statement{{
+		Vulnerability: vex.Vulnerability{Name: vex.VulnerabilityID(vulnID)},
+		Products: []vex.Product{{
+			Component:     vex.Component{ID: product},
+			Subcomponents: []vex.Subcomponent{{Component: vex.Component{ID: "pkg:apk/alpine/openssl"}}},
+		}},
+		Status: vex.StatusFixed,
+	}}
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, doc.ToJSON(f))
+}
+
+func TestMergeVexDocuments(t *testing.T) {
+	dir := t.TempDir()
+	vexPath := filepath.Join(dir, "vex.json")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-amd64.json"), "CVE-2023-0001", "pkg:oci/alpine?platform=linux/amd64")
+	writeVexDocument(t, filepath.Join(dir, "vex-linux-arm64.json"), "CVE-2023-0001", "pkg:oci/alpine?platform=linux/arm64")
+	// Unrelated files in the same directory are not merged
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte("{}"), 0o600))
+
+	require.NoError(t, mergeVexDocuments(vexPath))
+
+	merged, err := readVexDocument(vexPath)
+	require.NoError(t, err)
+	assert.Len(t, merged.Statements, 2)
+	assert.Equal(t, "Project Copacetic", merged.Author)
+}
+
+func TestMergeVexDocuments_SingleDocumentUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	vexPath := filepath.Join(dir, "vex.json")
+	writeVexDocument(t, vexPath, "CVE-2023-0001", "pkg:oci/alpine")
+	before, err := os.ReadFile(vexPath)
+	require.NoError(t, err)
+
+	require.NoError(t, mergeVexDocuments(vexPath))
+
+	after, err := os.ReadFile(vexPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestAnnotateVexDocument(t *testing.T) {
+	vexPath := filepath.Join(t.TempDir(), "vex.json")
+	writeVexDocument(t, vexPath, "CVE-2023-0001", "pkg:oci/alpine")
+
+	require.NoError(t, annotateVexDocument(vexPath, "change CHG-1234", "platform-team"))
+
+	doc, err := readVexDocument(vexPath)
+	require.NoError(t, err)
+	assert.Equal(t, "platform-team", doc.Author)
+	require.Len(t, doc.Statements, 1)
+	assert.Equal(t, "change CHG-1234", doc.Statements[0].StatusNotes)
+
+	// Notes are appended to existing notes, and the author is kept when not set
+	require.NoError(t, annotateVexDocument(vexPath, "approved by security", ""))
+
+	doc, err = readVexDocument(vexPath)
+	require.NoError(t, err)
+	assert.Equal(t, "platform-team", doc.Author)
+	assert.Equal(t, "change CHG-1234\napproved by security", doc.Statements[0].StatusNotes)
+}
+
+func TestAnnotateVexDocument_MissingDocument(t *testing.T) {
+	err := annotateVexDocument(filepath.Join(t.TempDir(), "vex.json"), "notes", "")
+	assert.Error(t, err)
+}
+
+func TestTriagedVulnerabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "triage.json")
+	doc := vex.New()
+	doc.Statements = []vex.Statement{
+		{Vulnerability: vex.Vulnerability{Name: "CVE-2023-0001", Aliases: []vex.VulnerabilityID{"GHSA-xxxx"}}, Status: vex.StatusNotAffected},
+		{Vulnerability: vex.Vulnerability{Name: "cve-2023-0002"}, Status: vex.StatusFixed},
+		{Vulnerability: vex.Vulnerability{Name: "CVE-2023-0003"}, Status: vex.StatusAffected},
+		{Vulnerability: vex.Vulnerability{Name: "CVE-2023-0004"}, Status: vex.StatusUnderInvestigation},
+	}
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, doc.ToJSON(f))
+	f.Close()
+
+	triaged, err := triagedVulnerabilities(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"CVE-2023-0001": true, "GHSA-XXXX": true, "CVE-2023-0002": true}, triaged)
+}
@@ -0,0 +1,117 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+const (
+	// envAzurePipelines is set to True by Azure Pipelines agents
+	envAzurePipelines = "TF_BUILD"
+	// envAgentTempDirectory is the agent's temporary directory, cleaned after each job
+	envAgentTempDirectory = "AGENT_TEMPDIRECTORY"
+)
+
+// azureOutput receives the Azure Pipelines logging commands. Stdout carries the MCP protocol, so
+// they are written to stderr, which the agent also scans for commands.
+var azureOutput io.Writer = os.Stderr
+
+// withAzurePipelines wraps h so that, when running in Azure Pipelines, each result is reported with
+// logging commands: findings, remaining vulnerabilities, warnings and failures as issues of the
+// run, and the Markdown summary of the result as an attachment of the run's summary page
+func withAzurePipelines[In any](enabled bool, tool string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if !enabled {
+		return h
+	}
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		res, out, err := h(ctx, req, params)
+		if err != nil || res == nil || !strings.EqualFold(os.Getenv(envAzurePipelines), "true") {
+			return res, out, err
+		}
+
+		for _, issue := range azureIssues(tool, res.StructuredContent) {
+			fmt.Fprintln(azureOutput, issue)
+		}
+		if summary := markdownSummary(tool, res.StructuredContent); summary != "" {
+			path, err := writeAzureSummary(summary)
+			if err != nil {
+				logf("warning", "failed to write Azure Pipelines summary: %v", err)
+			} else {
+				fmt.Fprintf(azureOutput, "##vso[task.uploadsummary]%s\n", path)
+			}
+		}
+		return res, out, nil
+	}
+}
+
+// azureIssues returns the task.logissue commands reporting the structured result of tool
+func azureIssues(tool string, result any) []string {
+	var issues []string
+	warn := func(format string, args ...any) {
+		issues = append(issues, "##vso[task.logissue type=warning]"+azureEscape(fmt.Sprintf(format, args...)))
+	}
+
+	switch r := result.(type) {
+	case *trivy.ScanResult:
+		if r.VulnCount > 0 {
+			warn("%s: %s has %d fixable vulnerabilities (report: %s)", tool, r.Image, r.VulnCount, r.ReportPath)
+		}
+		for _, w := range r.Warnings {
+			warn("%s: %s", tool, w)
+		}
+	case types.PatchResult:
+		if r.Severity != nil {
+			remaining := r.Severity.Remaining
+			if total := remaining.Critical + remaining.High + remaining.Medium + remaining.Low + remaining.Unknown; total > 0 {
+				warn("%s: %d fixable vulnerabilities remain in %s (%s)", tool, total, strings.Join(r.PatchedImage, ", "), formatSeverityCounts(remaining))
+			}
+		}
+		for _, w := range r.Warnings {
+			warn("%s: %s", tool, w)
+		}
+	case types.Remediation:
+		for _, w := range r.Warnings {
+			warn("%s: %s", tool, w)
+		}
+	case types.ToolError:
+		message := fmt.Sprintf("%s failed (%s): %s", tool, r.Category, r.Message)
+		if r.Recovery != "" {
+			message += " Recovery: " + r.Recovery
+		}
+		issues = append(issues, "##vso[task.logissue type=error]"+azureEscape(message))
+	}
+	return issues
+}
+
+// writeAzureSummary writes a Markdown summary to a new file in the agent's temporary directory
+func writeAzureSummary(summary string) (string, error) {
+	var f *os.File
+	var err error
+	if dir := os.Getenv(envAgentTempDirectory); dir != "" {
+		f, err = os.CreateTemp(dir, "copa-mcp-summary-*.md")
+	} else {
+		f, err = workdir.CreateTemp("copa-mcp-summary-*.md")
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(summary); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// azureEscape escapes the message of a logging command, which must fit on one line
+func azureEscape(message string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
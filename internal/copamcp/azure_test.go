@@ -0,0 +1,96 @@
+package copamcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureIssues(t *testing.T) {
+	t.Run("scan", func(t *testing.T) {
+		issues := azureIssues(ToolScanContainer, &trivy.ScanResult{
+			Image: "alpine:3.18", ReportPath: "/tmp/reports-1", VulnCount: 12, Warnings: []string{"100% of\nplatforms"},
+		})
+		assert.Equal(t, []string{
+			"##vso[task.logissue type=warning]scan-container: alpine:3.18 has 12 fixable vulnerabilities (report: /tmp/reports-1)",
+			"##vso[task.logissue type=warning]scan-container: 100%AZP25 of%0Aplatforms",
+		}, issues)
+
+		assert.Empty(t, azureIssues(ToolScanContainer, &trivy.ScanResult{Image: "alpine:3.18"}))
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		issues := azureIssues(ToolPatchReportBased, types.PatchResult{
+			OriginalImage: "alpine:3.18",
+			PatchedImage:  []string{"alpine:3.18-patched"},
+			Severity: &types.SeveritySummary{
+				Fixed:     types.SeverityCounts{Critical: 1},
+				Remaining: types.SeverityCounts{High: 1, Low: 2},
+			},
+		})
+		assert.Equal(t, []string{
+			"##vso[task.logissue type=warning]patch-report-based: 3 fixable vulnerabilities remain in alpine:3.18-patched (CRITICAL 0, HIGH 1, MEDIUM 0, LOW 2)",
+		}, issues)
+
+		assert.Empty(t, azureIssues(ToolPatchReportBased, types.PatchResult{OriginalImage: "alpine:3.18"}))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		issues := azureIssues(ToolPatchComprehensive, types.ToolError{
+			Category: "network", Message: "registry unreachable", Recovery: "retry after a short delay",
+		})
+		assert.Equal(t, []string{
+			"##vso[task.logissue type=error]patch-comprehensive failed (network): registry unreachable Recovery: retry after a short delay",
+		}, issues)
+	})
+}
+
+func TestWithAzurePipelines(t *testing.T) {
+	var out bytes.Buffer
+	azureOutput = &out
+	t.Cleanup(func() { azureOutput = os.Stderr })
+	dir := t.TempDir()
+	t.Setenv(envAzurePipelines, "True")
+	t.Setenv(envAgentTempDirectory, dir)
+
+	h := withAzurePipelines(true, ToolPatchReportBased, resultFileHandler)
+	for _, fail := range []bool{false, true} {
+		_, _, err := h(context.Background(), nil, resultFileParams{Fail: fail})
+		require.NoError(t, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "##vso[task.uploadsummary]"+dir))
+	assert.True(t, strings.HasPrefix(lines[1], "##vso[task.logissue type=error]patch-report-based failed (execution): copa failed"))
+	assert.True(t, strings.HasPrefix(lines[2], "##vso[task.uploadsummary]"+dir))
+
+	data, err := os.ReadFile(strings.TrimPrefix(lines[0], "##vso[task.uploadsummary]"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "### :white_check_mark: patch-report-based: `alpine:3.17`")
+}
+
+func TestWithAzurePipelines_NotInPipeline(t *testing.T) {
+	var out bytes.Buffer
+	azureOutput = &out
+	t.Cleanup(func() { azureOutput = os.Stderr })
+	dir := t.TempDir()
+	t.Setenv(envAzurePipelines, "")
+	t.Setenv(envAgentTempDirectory, dir)
+
+	_, _, err := withAzurePipelines(true, ToolPatchReportBased, resultFileHandler)(context.Background(), nil, resultFileParams{Fail: true})
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
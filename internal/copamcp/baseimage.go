@@ -0,0 +1,187 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/baseimage"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// maxNewerTags caps the newer tags listed for a base image
+const maxNewerTags = 10
+
+// SuggestBaseUpgrade suggests newer tags for a base image, or for the base images of a Dockerfile,
+// as a remediation of vulnerabilities inherited from an outdated base image. With write set, the
+// Dockerfile's FROM instructions are bumped to the suggested tags.
+func (t *tools) SuggestBaseUpgrade(ctx context.Context, req *mcp.CallToolRequest, params types.BaseUpgradeParams) (*mcp.CallToolResult, any, error) {
+	if (params.Image == "") == (params.Dockerfile == "") {
+		return errorResult(copaerrors.NewValidationError("set either image or dockerfile", nil)), nil, nil
+	}
+	if params.Write && params.Dockerfile == "" {
+		return errorResult(copaerrors.NewValidationError("write requires dockerfile", nil)), nil, nil
+	}
+	if params.Compare {
+		if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+			return errorResult(err), nil, nil
+		}
+	}
+
+	var dockerfile []byte
+	froms := []baseimage.From{{Image: params.Image}}
+	if params.Dockerfile != "" {
+		var err error
+		if dockerfile, err = os.ReadFile(params.Dockerfile); err != nil {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("failed to read Dockerfile %s", params.Dockerfile), err)), nil, nil
+		}
+		if froms = baseimage.ParseDockerfile(dockerfile); len(froms) == 0 {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("Dockerfile %s has no FROM instruction that pulls an image", params.Dockerfile), nil)), nil, nil
+		}
+	}
+
+	result := types.BaseUpgradeResult{Dockerfile: params.Dockerfile, Upgrades: []types.BaseUpgrade{}}
+	var bumps []baseimage.Bump
+	for _, from := range froms {
+		upgrade, err := t.suggestUpgrade(ctx, req, params, from)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		if upgrade.Suggested != "" {
+			bumps = append(bumps, baseimage.Bump{Line: from.Line, Image: upgrade.Suggested})
+		}
+		result.Upgrades = append(result.Upgrades, upgrade)
+	}
+
+	if params.Write && len(bumps) > 0 {
+		info, err := os.Stat(params.Dockerfile)
+		if err == nil {
+			err = os.WriteFile(params.Dockerfile, baseimage.Rewrite(dockerfile, froms, bumps), info.Mode().Perm())
+		}
+		if err != nil {
+			return errorResult(copaerrors.NewSystemError(fmt.Sprintf("failed to rewrite Dockerfile %s", params.Dockerfile), err)), nil, nil
+		}
+		result.Rewritten = true
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: baseUpgradeMessage(result)}},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// suggestUpgrade looks up the newer tags of the base image of from and, with compare set, scans
+// the current and suggested images
+func (t *tools) suggestUpgrade(ctx context.Context, req *mcp.CallToolRequest, params types.BaseUpgradeParams, from baseimage.From) (types.BaseUpgrade, error) {
+	upgrade := types.BaseUpgrade{Image: from.Image, Line: from.Line, Stage: from.Stage}
+	name, tag := baseimage.SplitTag(from.Image)
+	switch {
+	case strings.Contains(from.Image, "$"):
+		upgrade.Reason = "the image is set by a build argument"
+		return upgrade, nil
+	case strings.Contains(name, "@"):
+		upgrade.Reason = "the image is pinned by digest; update the digest with the image's newer release"
+		return upgrade, nil
+	case !baseimage.IsVersion(tag):
+		upgrade.Reason = "the tag is not a version; pin a version tag to receive upgrade suggestions"
+		return upgrade, nil
+	}
+
+	var tags []string
+	err := t.retry(ctx, req, params.Retry, func() (err error) {
+		tags, err = registry.Tags(ctx, from.Image)
+		return err
+	})
+	if err != nil {
+		return upgrade, fmt.Errorf("listing the tags of %s failed: %w", name, err)
+	}
+
+	newer := baseimage.Newer(tag, tags)
+	if len(newer) == 0 {
+		upgrade.Reason = "no newer tag of the same variant"
+		return upgrade, nil
+	}
+	upgrade.Suggested = name + ":" + newer[0]
+	if sameMajor := baseimage.SameMajor(tag, newer); sameMajor != "" && sameMajor != newer[0] {
+		upgrade.SameMajor = name + ":" + sameMajor
+	}
+	upgrade.Newer = newer[:min(len(newer), maxNewerTags)]
+
+	if params.Compare {
+		if upgrade.CurrentVulns, err = t.countBaseVulns(ctx, req, params, from.Image); err != nil {
+			return upgrade, err
+		}
+		if upgrade.SuggestedVulns, err = t.countBaseVulns(ctx, req, params, upgrade.Suggested); err != nil {
+			return upgrade, err
+		}
+	}
+	return upgrade, nil
+}
+
+// countBaseVulns scans a base image and counts its fixable vulnerabilities
+func (t *tools) countBaseVulns(ctx context.Context, req *mcp.CallToolRequest, params types.BaseUpgradeParams, image string) (*int, error) {
+	if err := t.verifyImage(ctx, req, params.Retry, image); err != nil {
+		return nil, err
+	}
+
+	var report []byte
+	err := t.retry(ctx, req, params.Retry, func() (err error) {
+		report, err = trivy.ScanJSON(ctx, params.DockerHost, image)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s failed: %w", image, err)
+	}
+	count, err := trivy.CountVulnerabilities(report)
+	if err != nil {
+		return nil, copaerrors.NewExecutionError(fmt.Sprintf("failed to parse the scan report of %s", image), err)
+	}
+	return &count, nil
+}
+
+func baseUpgradeMessage(result types.BaseUpgradeResult) string {
+	var msg strings.Builder
+	if result.Dockerfile != "" {
+		msg.WriteString(fmt.Sprintf("Base image upgrades for %s:\n", result.Dockerfile))
+	} else {
+		msg.WriteString("Base image upgrade:\n")
+	}
+
+	suggested := 0
+	for _, upgrade := range result.Upgrades {
+		if upgrade.Line > 0 {
+			msg.WriteString(fmt.Sprintf("\nline %d: ", upgrade.Line))
+		} else {
+			msg.WriteString("\n")
+		}
+		if upgrade.Suggested == "" {
+			msg.WriteString(fmt.Sprintf("%s - no upgrade: %s\n", upgrade.Image, upgrade.Reason))
+			continue
+		}
+		suggested++
+		msg.WriteString(fmt.Sprintf("%s -> %s\n", upgrade.Image, upgrade.Suggested))
+		if upgrade.SameMajor != "" {
+			msg.WriteString(fmt.Sprintf(" newest without a major version change: %s\n", upgrade.SameMajor))
+		}
+		if upgrade.CurrentVulns != nil && upgrade.SuggestedVulns != nil {
+			msg.WriteString(fmt.Sprintf(" fixable vulnerabilities: %d -> %d\n", *upgrade.CurrentVulns, *upgrade.SuggestedVulns))
+		}
+	}
+
+	msg.WriteString("\n=== NEXT STEPS ===")
+	switch {
+	case suggested == 0:
+		msg.WriteString("\nNo base image upgrade is available; use 'scan-container' + 'patch-report-based' to patch the image instead.")
+	case result.Rewritten:
+		msg.WriteString(fmt.Sprintf("\nThe FROM instructions of %s were updated. Rebuild the image and scan it with 'scan-container'; patch any remaining vulnerabilities with 'patch-report-based'.", result.Dockerfile))
+	default:
+		msg.WriteString("\nBump the FROM instructions to the suggested images (or call again with dockerfile and write set), rebuild, and scan the new image with 'scan-container'. Base image upgrades complement patching: they also update language runtimes and fix vulnerabilities that have no package update.")
+	}
+	return msg.String()
+}
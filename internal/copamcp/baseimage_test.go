@@ -0,0 +1,65 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestBaseUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"tags": []string{"3.18", "3.19", "3.20", "4.1", "latest"}})
+	}))
+	defer srv.Close()
+	registry := strings.TrimPrefix(srv.URL, "http://")
+
+	path := filepath.Join(t.TempDir(), "Dockerfile")
+	dockerfile := "FROM " + registry + "/base:3.18 AS build\nFROM " + registry + "/base:latest\nFROM scratch\n"
+	require.NoError(t, os.WriteFile(path, []byte(dockerfile), 0o600))
+
+	tt := &tools{cfg: config.Default()}
+	res, _, err := tt.SuggestBaseUpgrade(context.Background(), nil, types.BaseUpgradeParams{Dockerfile: path, Write: true})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	result := res.StructuredContent.(types.BaseUpgradeResult)
+	require.Len(t, result.Upgrades, 2)
+	assert.Equal(t, types.BaseUpgrade{
+		Image:     registry + "/base:3.18",
+		Line:      1,
+		Stage:     "build",
+		Suggested: registry + "/base:4.1",
+		SameMajor: registry + "/base:3.20",
+		Newer:     []string{"4.1", "3.20", "3.19"},
+	}, result.Upgrades[0])
+	assert.Empty(t, result.Upgrades[1].Suggested)
+	assert.NotEmpty(t, result.Upgrades[1].Reason)
+	assert.True(t, result.Rewritten)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "FROM "+registry+"/base:4.1 AS build\nFROM "+registry+"/base:latest\nFROM scratch\n", string(data))
+}
+
+func TestSuggestBaseUpgrade_Validation(t *testing.T) {
+	tt := &tools{cfg: config.Default()}
+	for _, params := range []types.BaseUpgradeParams{
+		{},
+		{Image: "alpine:3.18", Dockerfile: "Dockerfile"},
+		{Image: "alpine:3.18", Write: true},
+	} {
+		res, _, err := tt.SuggestBaseUpgrade(context.Background(), nil, params)
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+	}
+}
@@ -0,0 +1,40 @@
+package copamcp
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// DebugPath is the path NewDebugHandler must be mounted at
+const DebugPath = "/debug/pprof/"
+
+// NewDebugHandler serves the net/http/pprof profiles of the process, so that operators can profile
+// the CPU and memory of a server handling heavy batch patching, when mounted at DebugPath. Profiles
+// expose the process internals, so every request must authenticate with "Authorization: Bearer
+// <token>"; an empty token is rejected.
+func NewDebugHandler(token string) (http.Handler, error) {
+	if token == "" {
+		return nil, copaerrors.NewValidationError("a token is required to serve the debug endpoints", nil,
+			"generate a random token and pass it to the operators allowed to profile the server")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(DebugPath, pprof.Index)
+	mux.HandleFunc(DebugPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(DebugPath+"profile", pprof.Profile)
+	mux.HandleFunc(DebugPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(DebugPath+"trace", pprof.Trace)
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="copa-mcp debug"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
@@ -0,0 +1,81 @@
+package copamcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+// defectdojoTimeout bounds the imports of one scan, or the verification scan and import of one patch
+const defectdojoTimeout = 15 * time.Minute
+
+// exportScan imports the reports of a scan of image into DefectDojo. A report of several platforms
+// is imported as one test per platform. The reports are read before returning, as they may be
+// removed by a later patch; the imports run in the background and failures are only logged.
+func (t *tools) exportScan(image, reportPath string) {
+	if !t.defectdojo.Enabled() {
+		return
+	}
+
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		logf("warning", "failed to read the scan report of %s for DefectDojo: %v", image, err)
+		return
+	}
+	reports := map[string][]byte{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(reportPath, entry.Name()))
+		if err != nil {
+			logf("warning", "failed to read the scan report of %s for DefectDojo: %v", image, err)
+			return
+		}
+		reports[defectdojoTitle(image, entry.Name())] = data
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), defectdojoTimeout)
+		defer cancel()
+		for title, report := range reports {
+			if err := t.defectdojo.Reimport(ctx, image, title, report); err != nil {
+				logf("warning", "failed to import the findings of %s into DefectDojo: %v", image, err)
+			}
+		}
+	}()
+}
+
+// exportPatch scans the patched image and reimports the report into the test of the original
+// image in DefectDojo, which closes the findings the patch fixed. It runs in the background and
+// failures are only logged.
+func (t *tools) exportPatch(dockerHost, image, patchedImage string) {
+	if !t.defectdojo.Enabled() {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), defectdojoTimeout)
+		defer cancel()
+		report, err := trivy.ScanJSON(ctx, dockerHost, patchedImage)
+		if err == nil {
+			err = t.defectdojo.Reimport(ctx, image, image, report)
+		}
+		if err != nil {
+			logf("warning", "failed to import the verification scan of %s into DefectDojo: %v", patchedImage, err)
+		}
+	}()
+}
+
+// defectdojoTitle returns the test title of a report file: the image, followed by the platform
+// for the per-platform reports of a multi-platform scan
+func defectdojoTitle(image, file string) string {
+	if file == "report.json" {
+		return image
+	}
+	return image + " " + strings.Replace(strings.TrimSuffix(file, ".json"), "-", "/", 2)
+}
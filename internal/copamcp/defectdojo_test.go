@@ -0,0 +1,13 @@
+package copamcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefectDojoTitle(t *testing.T) {
+	assert.Equal(t, "nginx:1.25", defectdojoTitle("nginx:1.25", "report.json"))
+	assert.Equal(t, "nginx:1.25 linux/amd64", defectdojoTitle("nginx:1.25", "linux-amd64.json"))
+	assert.Equal(t, "nginx:1.25 linux/arm/v7", defectdojoTitle("nginx:1.25", "linux-arm-v7.json"))
+}
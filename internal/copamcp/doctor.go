@@ -0,0 +1,202 @@
+package copamcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// Statuses of a doctor check
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkFailed  = "failed"
+)
+
+// defaultDoctorRegistries are checked when the call names no registry
+var defaultDoctorRegistries = []string{"docker.io"}
+
+// Doctor checks whether the environment of the server is ready to scan and patch images, so that
+// agents can diagnose a broken setup before attempting a patch. Failed checks are reported in the
+// result rather than as a failed call.
+func (t *tools) Doctor(ctx context.Context, req *mcp.CallToolRequest, params types.DoctorParams) (*mcp.CallToolResult, any, error) {
+	if err := validate.Doctor(params).Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	report := diagnose(ctx, t.cfg, params)
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: DoctorText(report)}},
+		StructuredContent: report,
+	}, nil, nil
+}
+
+// RunDoctor runs the checks of 'doctor' without a server, for the doctor command, once the
+// Docker socket, working directory and binaries are set up as a server sets them up
+func RunDoctor(ctx context.Context, cfg *config.Config, params types.DoctorParams) (types.DoctorReport, error) {
+	if err := validate.Doctor(params).Err(); err != nil {
+		return types.DoctorReport{}, err
+	}
+	docker.ConfigureHost(cfg.DockerSockets)
+	// The environment was validated with the rest of the configuration
+	subprocessEnv, _ := subprocess.ParseEnv(cfg.SubprocessEnv)
+	subprocess.SetEnv(subprocessEnv)
+	workdir.Configure(cfg.TempDir, int64(cfg.TempQuotaMB)<<20)
+	useBinDir(cfg)
+	useBinaries(cfg)
+	return diagnose(ctx, cfg, params), nil
+}
+
+// diagnose runs every check; the environment is ready when none failed
+func diagnose(ctx context.Context, cfg *config.Config, params types.DoctorParams) types.DoctorReport {
+	checks := []types.DoctorCheck{
+		checkDocker(ctx, params.DockerHost),
+		checkBuildkit(ctx, cfg.BuildkitAddr, params.DockerHost),
+		checkBinary(ctx, copaexec.Copa),
+		checkBinary(ctx, copaexec.Trivy),
+		checkFeatures(ctx),
+	}
+	registries := params.Registries
+	if len(registries) == 0 {
+		registries = defaultDoctorRegistries
+	}
+	for _, r := range registries {
+		checks = append(checks, checkRegistry(ctx, r))
+	}
+	checks = append(checks, checkDisk(cfg.TempQuotaMB))
+
+	report := types.DoctorReport{Ready: true, Checks: checks}
+	for _, c := range checks {
+		if c.Status == checkFailed {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// errorCheck reports err as the outcome of the check name, with the hints of a categorized error
+func errorCheck(name, status string, err error) types.DoctorCheck {
+	check := types.DoctorCheck{Name: name, Status: status, Detail: err.Error()}
+	var ce *copaerrors.CopaceticError
+	if errors.As(err, &ce) {
+		check.Detail, check.Hints = ce.Message, ce.Hints
+		if ce.Err != nil {
+			check.Detail += ": " + ce.Err.Error()
+		}
+	}
+	return check
+}
+
+func checkDocker(ctx context.Context, dockerHost string) types.DoctorCheck {
+	if err := docker.CheckDaemon(ctx, dockerHost); err != nil {
+		return errorCheck("docker", checkFailed, err)
+	}
+	return types.DoctorCheck{Name: "docker", Status: checkOK, Detail: "the Docker daemon is reachable"}
+}
+
+func checkBuildkit(ctx context.Context, addr, dockerHost string) types.DoctorCheck {
+	if addr == "" {
+		return types.DoctorCheck{Name: "buildkit", Status: checkOK, Detail: "copa uses the buildkit built into the Docker daemon"}
+	}
+	if err := copa.CheckBuildkit(ctx, addr, dockerHost); err != nil {
+		return types.DoctorCheck{Name: "buildkit", Status: checkFailed, Detail: fmt.Sprintf("buildkit at %s is not ready: %v", addr, err),
+			Hints: []string{"start the buildkit instance, or fix " + config.EnvBuildkitAddr}}
+	}
+	return types.DoctorCheck{Name: "buildkit", Status: checkOK, Detail: fmt.Sprintf("buildkit at %s is ready", addr)}
+}
+
+func checkBinary(ctx context.Context, tool string) types.DoctorCheck {
+	path, err := copaexec.LookPath(tool)
+	if err != nil {
+		return types.DoctorCheck{Name: tool, Status: checkFailed, Detail: fmt.Sprintf("%s not found: %v", tool, err),
+			Hints: []string{copaexec.NotFoundHint(tool)}}
+	}
+	v, ok := toolchain.Installed(ctx, tool)
+	if !ok {
+		return types.DoctorCheck{Name: tool, Status: checkWarning, Detail: fmt.Sprintf("found %s at %s, but could not determine its version", tool, path),
+			Hints: []string{fmt.Sprintf("check that '%s --version' runs", path)}}
+	}
+	return types.DoctorCheck{Name: tool, Status: checkOK, Detail: fmt.Sprintf("%s %s at %s", tool, v, path)}
+}
+
+func checkFeatures(ctx context.Context) types.DoctorCheck {
+	missing := toolchain.Missing(ctx)
+	if len(missing) > 0 {
+		return types.DoctorCheck{Name: "features", Status: checkWarning, Detail: strings.Join(missing, "; "),
+			Hints: []string{"install newer releases with 'install-dependencies'"}}
+	}
+	return types.DoctorCheck{Name: "features", Status: checkOK, Detail: "the installed releases support every feature the tools use"}
+}
+
+func checkRegistry(ctx context.Context, host string) types.DoctorCheck {
+	name := "registry:" + registry.Host(host)
+	if err := registry.Ping(ctx, host); err != nil {
+		return errorCheck(name, checkFailed, err)
+	}
+	return types.DoctorCheck{Name: name, Status: checkOK, Detail: fmt.Sprintf("%s is reachable", registry.Host(host))}
+}
+
+// checkDisk checks the space available to the working directory, and its quota when it has one
+func checkDisk(quotaMB int) types.DoctorCheck {
+	dir, err := workdir.Dir()
+	if err != nil {
+		return errorCheck("disk", checkFailed, err)
+	}
+	available, err := copa.AvailableDiskSpace(dir)
+	if err != nil {
+		return types.DoctorCheck{Name: "disk", Status: checkWarning, Detail: fmt.Sprintf("failed to determine the space available in %s: %v", dir, err)}
+	}
+	detail := fmt.Sprintf("%s available in %s", copa.FormatBytes(available), dir)
+	if available < copa.MinDiskSpace {
+		return types.DoctorCheck{Name: "disk", Status: checkFailed, Detail: fmt.Sprintf("%s, at least %s is needed to patch", detail, copa.FormatBytes(copa.MinDiskSpace)),
+			Hints: []string{"free up disk space (e.g. 'docker system prune')", "set " + config.EnvTempDir + " to a larger volume"}}
+	}
+	if quotaMB > 0 {
+		used, err := workdir.Usage()
+		if err != nil {
+			return types.DoctorCheck{Name: "disk", Status: checkWarning, Detail: fmt.Sprintf("%s; failed to measure the working directory: %v", detail, err)}
+		}
+		detail += fmt.Sprintf(", %d of its %d MB quota used", used>>20, quotaMB)
+		if used >= int64(quotaMB)<<20 {
+			return types.DoctorCheck{Name: "disk", Status: checkFailed, Detail: detail,
+				Hints: []string{"remove the scan reports and VEX documents no longer needed, or raise " + config.EnvTempQuotaMB}}
+		}
+	}
+	return types.DoctorCheck{Name: "disk", Status: checkOK, Detail: detail}
+}
+
+// DoctorText describes the checks of r, one per line, followed by their hints
+func DoctorText(r types.DoctorReport) string {
+	var failed, warnings int
+	var msg strings.Builder
+	for _, c := range r.Checks {
+		switch c.Status {
+		case checkFailed:
+			failed++
+		case checkWarning:
+			warnings++
+		}
+		msg.WriteString(fmt.Sprintf("\n [%s] %s: %s", c.Status, c.Name, c.Detail))
+		for _, hint := range c.Hints {
+			msg.WriteString("\n     - " + hint)
+		}
+	}
+	outcome := "ready"
+	if !r.Ready {
+		outcome = "NOT ready"
+	}
+	return fmt.Sprintf("Environment %s: %d checks, %d failed, %d warnings", outcome, len(r.Checks), failed, warnings) + msg.String()
+}
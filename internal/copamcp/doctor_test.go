@@ -0,0 +1,60 @@
+package copamcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDisk(t *testing.T) {
+	dir := t.TempDir()
+	workdir.Configure(dir, 1<<20)
+	t.Cleanup(func() { workdir.Configure("", 0) })
+
+	check := checkDisk(1)
+	assert.Equal(t, checkOK, check.Status, check.Detail)
+	assert.Contains(t, check.Detail, "0 of its 1 MB quota used")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), make([]byte, 1<<20), 0o600))
+	check = checkDisk(1)
+	assert.Equal(t, checkFailed, check.Status)
+	assert.NotEmpty(t, check.Hints)
+}
+
+func TestCheckRegistry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	check := checkRegistry(context.Background(), srv.URL)
+	assert.Equal(t, checkOK, check.Status, check.Detail)
+	assert.Equal(t, "registry:"+srv.Listener.Addr().String(), check.Name)
+
+	srv.Close()
+	check = checkRegistry(context.Background(), srv.URL)
+	assert.Equal(t, checkFailed, check.Status)
+	assert.Contains(t, check.Detail, "failed to reach registry")
+	assert.NotEmpty(t, check.Hints)
+}
+
+func TestErrorCheck(t *testing.T) {
+	check := errorCheck("docker", checkFailed, copaerrors.NewSystemError("docker daemon is not reachable", assert.AnError, "start Docker"))
+	assert.Equal(t, "docker daemon is not reachable: "+assert.AnError.Error(), check.Detail, "hints are not repeated in the detail")
+	assert.Equal(t, []string{"start Docker"}, check.Hints)
+}
+
+func TestDoctorText(t *testing.T) {
+	text := DoctorText(types.DoctorReport{Checks: []types.DoctorCheck{
+		{Name: "docker", Status: checkOK, Detail: "the Docker daemon is reachable"},
+		{Name: "trivy", Status: checkFailed, Detail: "trivy not found", Hints: []string{"call 'install-dependencies'"}},
+		{Name: "features", Status: checkWarning, Detail: "copa --platform needs copa 0.10.0 or later"},
+	}})
+	assert.Contains(t, text, "Environment NOT ready: 3 checks, 1 failed, 1 warnings")
+	assert.Contains(t, text, "[failed] trivy: trivy not found\n     - call 'install-dependencies'")
+}
@@ -0,0 +1,34 @@
+package copamcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+// dtrackTimeout bounds generating and uploading the SBOMs of one scan or patch
+const dtrackTimeout = 15 * time.Minute
+
+// uploadSBOMs generates an SBOM, with its vulnerabilities, of each image and uploads it to
+// Dependency-Track. Uploads run in the background so that they do not delay the result, and
+// failures are only logged.
+func (t *tools) uploadSBOMs(dockerHost string, images ...string) {
+	if !t.dtrack.Enabled() || len(images) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dtrackTimeout)
+		defer cancel()
+		for _, image := range images {
+			bom, err := trivy.SBOM(ctx, dockerHost, image)
+			if err == nil {
+				_, err = t.dtrack.UploadBOM(ctx, image, bom)
+			}
+			if err != nil {
+				logf("warning", "failed to upload the SBOM of %s to Dependency-Track: %v", image, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,47 @@
+package copamcp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// errorResult converts a tool failure into an MCP error result whose structured
+// content carries the error category, hints, a suggested recovery strategy and
+// the details of the failed command, if any
+func errorResult(err error) *mcp.CallToolResult {
+	category := copaerrors.CategoryOf(err)
+	if category == "" {
+		category = copaerrors.CategoryExecution
+	}
+
+	toolErr := types.ToolError{
+		Category: string(category),
+		Message:  err.Error(),
+		Recovery: copaerrors.Recovery(category),
+	}
+	var ce *copaerrors.CopaceticError
+	if errors.As(err, &ce) {
+		toolErr.Hints = ce.Hints
+	}
+	for _, p := range copaerrors.ProblemsOf(err) {
+		toolErr.Problems = append(toolErr.Problems, types.ParamProblem{Field: p.Field, Message: p.Message})
+	}
+	if cmd := copaerrors.CommandOf(err); cmd != nil {
+		toolErr.Command = &types.CommandFailure{
+			Command:    cmd.Line,
+			ExitCode:   cmd.ExitCode,
+			StderrTail: cmd.StderrTail,
+			Duration:   cmd.Duration.Round(time.Millisecond).String(),
+		}
+	}
+
+	return &mcp.CallToolResult{
+		IsError:           true,
+		Content:           []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+		StructuredContent: toolErr,
+	}
+}
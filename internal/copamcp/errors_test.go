@@ -0,0 +1,65 @@
+package copamcp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResult_Categorized(t *testing.T) {
+	err := fmt.Errorf("patching failed: %w", copaerrors.NewAuthError("failed to authenticate to registry", nil, "check REGISTRY_TOKEN"))
+
+	res := errorResult(err)
+
+	assert.True(t, res.IsError)
+	toolErr, ok := res.StructuredContent.(types.ToolError)
+	require.True(t, ok)
+	assert.Equal(t, "auth", toolErr.Category)
+	assert.Equal(t, []string{"check REGISTRY_TOKEN"}, toolErr.Hints)
+	assert.Equal(t, copaerrors.Recovery(copaerrors.CategoryAuth), toolErr.Recovery)
+	assert.Contains(t, toolErr.Message, "patching failed")
+}
+
+func TestErrorResult_UncategorizedDefaultsToExecution(t *testing.T) {
+	res := errorResult(fmt.Errorf("something went wrong"))
+
+	toolErr, ok := res.StructuredContent.(types.ToolError)
+	require.True(t, ok)
+	assert.Equal(t, "execution", toolErr.Category)
+	assert.Empty(t, toolErr.Hints)
+}
+
+func TestErrorResult_CommandDetails(t *testing.T) {
+	cmdErr := copaerrors.NewExecutionError("command execution failed", fmt.Errorf("exit status 1")).
+		WithCommand([]string{"copa", "patch", "--image", "alpine:3.17"}, 1, "error: no patchable packages\n", 1500*time.Millisecond)
+
+	res := errorResult(fmt.Errorf("patching failed: %w", cmdErr))
+
+	toolErr, ok := res.StructuredContent.(types.ToolError)
+	require.True(t, ok)
+	require.NotNil(t, toolErr.Command)
+	assert.Equal(t, "copa patch --image alpine:3.17", toolErr.Command.Command)
+	assert.Equal(t, 1, toolErr.Command.ExitCode)
+	assert.Equal(t, "error: no patchable packages", toolErr.Command.StderrTail)
+	assert.Equal(t, "1.5s", toolErr.Command.Duration)
+}
+
+func TestErrorResult_Problems(t *testing.T) {
+	err := copaerrors.NewInvalidParamsError([]copaerrors.Problem{
+		{Field: "image", Message: "image is required"},
+		{Field: "exportPath", Message: "exportPath cannot be combined with push"},
+	})
+
+	toolErr, ok := errorResult(err).StructuredContent.(types.ToolError)
+	require.True(t, ok)
+	assert.Equal(t, "validation", toolErr.Category)
+	assert.Equal(t, []types.ParamProblem{
+		{Field: "image", Message: "image is required"},
+		{Field: "exportPath", Message: "exportPath cannot be combined with push"},
+	}, toolErr.Problems)
+}
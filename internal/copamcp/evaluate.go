@@ -0,0 +1,188 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Verdicts of 'evaluate-image'
+const (
+	verdictAllow = "allow"
+	verdictDeny  = "deny"
+)
+
+// EvaluateImage evaluates an image against the admission policies: allowed registries, a required
+// signature, a maximum of critical vulnerabilities and the Rego policies at data.copa.image. A denied
+// image is a successful call with an allowed of false; only failures to evaluate return an error.
+func (t *tools) EvaluateImage(ctx context.Context, req *mcp.CallToolRequest, params types.EvaluateImageParams) (*mcp.CallToolResult, any, error) {
+	if params.Image == "" {
+		return errorResult(copaerrors.NewValidationError("image parameter is required", nil)), nil, nil
+	}
+
+	allowedRegistries := t.cfg.AllowedRegistries
+	if len(params.AllowedRegistries) > 0 {
+		allowedRegistries = params.AllowedRegistries
+	}
+	requireSignature := t.cfg.RequireSignature
+	if params.RequireSignature != nil {
+		requireSignature = *params.RequireSignature
+	}
+	maxCritical := t.cfg.MaxCritical
+	if params.MaxCritical != nil {
+		maxCritical = *params.MaxCritical
+	}
+	// Rego policies may look at any part of the input, so it is collected in full when they are configured
+	rego := t.policy.Enabled()
+
+	verdict := types.ImageVerdict{Image: params.Image, Checks: []types.PolicyCheck{}}
+	check := func(name string, passed bool, detail string) {
+		verdict.Checks = append(verdict.Checks, types.PolicyCheck{Name: name, Passed: passed, Detail: detail})
+		if !passed {
+			verdict.Reasons = append(verdict.Reasons, detail)
+		}
+	}
+
+	input := policy.ImageInput{Image: params.Image, Registry: policy.Registry(params.Image)}
+	if len(allowedRegistries) > 0 {
+		if allowedRegistry(params.Image, allowedRegistries) {
+			check("registry", true, fmt.Sprintf("%s is an allowed registry", input.Registry))
+		} else {
+			check("registry", false, fmt.Sprintf("%s is not from an allowed registry (%s)", params.Image, strings.Join(allowedRegistries, ", ")))
+		}
+	}
+
+	if requireSignature || rego {
+		var signature *registry.Signature
+		err := t.retry(ctx, req, params.Retry, func() (err error) {
+			signature, err = registry.FindSignature(ctx, params.Image)
+			return err
+		})
+		if err != nil {
+			return errorResult(fmt.Errorf("looking up the signature of %s failed: %w", params.Image, err)), nil, nil
+		}
+		if signature != nil {
+			input.Signed, input.Signature, verdict.Signature = true, signature.Kind, signature.Kind
+		}
+		if requireSignature {
+			if input.Signed {
+				check("signature", true, fmt.Sprintf("a %s signature is attached", signature.Kind))
+			} else {
+				check("signature", false, "no cosign or Notation signature is attached to the image")
+			}
+		}
+	}
+
+	if maxCritical >= 0 || rego || params.ReportPath != "" {
+		severity, err := t.imageSeverity(ctx, req, params)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		input.Severity, verdict.Severity = severity, severity
+		if maxCritical >= 0 {
+			check("critical", severity.Critical <= maxCritical,
+				fmt.Sprintf("%d fixable CRITICAL vulnerabilities, at most %d allowed", severity.Critical, maxCritical))
+		}
+	}
+
+	if rego {
+		decision, err := t.policy.Evaluate(ctx, policy.ImageQuery, input)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		if len(decision.Deny) == 0 {
+			check("rego", true, "allowed by "+policy.ImageQuery)
+		}
+		for _, reason := range decision.Deny {
+			check("rego", false, reason)
+		}
+	}
+
+	verdict.Allowed = len(verdict.Reasons) == 0
+	verdict.Verdict = verdictDeny
+	if verdict.Allowed {
+		verdict.Verdict = verdictAllow
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: verdictMessage(verdict)}},
+		StructuredContent: verdict,
+	}, nil, nil
+}
+
+// imageSeverity counts the fixable vulnerabilities of the image by severity, from the given
+// report directory or from a new scan
+func (t *tools) imageSeverity(ctx context.Context, req *mcp.CallToolRequest, params types.EvaluateImageParams) (*types.SeverityCounts, error) {
+	var severities map[string]string
+	if params.ReportPath != "" {
+		var err error
+		if severities, err = trivy.Severities(params.ReportPath); err != nil {
+			return nil, copaerrors.NewValidationError(fmt.Sprintf("failed to read the report directory %s", params.ReportPath), err)
+		}
+	} else {
+		if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+			return nil, err
+		}
+		if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+			return nil, err
+		}
+		var report []byte
+		err := t.retry(ctx, req, params.Retry, func() (err error) {
+			report, err = trivy.ScanJSON(ctx, params.DockerHost, params.Image)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s failed: %w", params.Image, err)
+		}
+		if severities, err = trivy.ReportSeverities(report); err != nil {
+			return nil, copaerrors.NewExecutionError(fmt.Sprintf("failed to parse the scan report of %s", params.Image), err)
+		}
+	}
+
+	counts := &types.SeverityCounts{}
+	for _, severity := range severities {
+		countSeverity(counts, severity)
+	}
+	return counts, nil
+}
+
+// allowedRegistry reports whether image comes from one of the allowed registry hosts or repository prefixes
+func allowedRegistry(image string, allowed []string) bool {
+	host := policy.Registry(image)
+	for _, entry := range allowed {
+		entry = strings.TrimSuffix(entry, "/")
+		if entry == host || strings.HasPrefix(image, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func verdictMessage(verdict types.ImageVerdict) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("Verdict for %s: %s\n", verdict.Image, strings.ToUpper(verdict.Verdict)))
+	if len(verdict.Checks) == 0 {
+		msg.WriteString("\nNo admission checks are configured; pass allowedRegistries, requireSignature or maxCritical, or configure them on the server.")
+		return msg.String()
+	}
+	for _, c := range verdict.Checks {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		msg.WriteString(fmt.Sprintf("\n %s %s: %s", status, c.Name, c.Detail))
+	}
+	if !verdict.Allowed {
+		msg.WriteString("\n\n=== NEXT STEPS ===")
+		msg.WriteString("\nDo not deploy or promote the image. Patch its vulnerabilities with 'scan-container' + 'patch-report-based', sign the patched image, or use an image from an allowed registry.")
+	}
+	return msg.String()
+}
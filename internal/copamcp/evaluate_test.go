@@ -0,0 +1,76 @@
+package copamcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateImage(t *testing.T) {
+	// A registry without referrers or cosign signature tags: the image is unsigned
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	image := strings.TrimPrefix(srv.URL, "http://") + "/team/app@sha256:0123456789abcdef"
+
+	reportPath := t.TempDir()
+	report := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL"},{"VulnerabilityID":"CVE-2023-0002","Severity":"HIGH"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(reportPath, "report.json"), []byte(report), 0o600))
+
+	cfg := config.Default()
+	cfg.AllowedRegistries = []string{strings.TrimPrefix(srv.URL, "http://")}
+	cfg.MaxCritical = 1
+	tt := &tools{cfg: cfg}
+
+	res, _, err := tt.EvaluateImage(context.Background(), nil, types.EvaluateImageParams{Image: image, ReportPath: reportPath})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+	verdict := res.StructuredContent.(types.ImageVerdict)
+	assert.True(t, verdict.Allowed)
+	assert.Equal(t, "allow", verdict.Verdict)
+	assert.Equal(t, &types.SeverityCounts{Critical: 1, High: 1}, verdict.Severity)
+	assert.Len(t, verdict.Checks, 2)
+
+	requireSignature, maxCritical := true, 0
+	res, _, err = tt.EvaluateImage(context.Background(), nil, types.EvaluateImageParams{
+		Image:             image,
+		ReportPath:        reportPath,
+		AllowedRegistries: []string{"registry.example.com"},
+		RequireSignature:  &requireSignature,
+		MaxCritical:       &maxCritical,
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+	verdict = res.StructuredContent.(types.ImageVerdict)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, "deny", verdict.Verdict)
+	assert.Len(t, verdict.Reasons, 3)
+	for _, c := range verdict.Checks {
+		assert.False(t, c.Passed, c.Name)
+	}
+	assert.Contains(t, res.Content[0].(*mcp.TextContent).Text, "FAIL signature")
+}
+
+func TestAllowedRegistry(t *testing.T) {
+	assert.True(t, allowedRegistry("alpine:3.18", []string{"docker.io"}))
+	assert.True(t, allowedRegistry("myacr.azurecr.io/app:1.0", []string{"ghcr.io", "myacr.azurecr.io"}))
+	assert.True(t, allowedRegistry("ghcr.io/team/app:1.0", []string{"ghcr.io/team/"}))
+	assert.False(t, allowedRegistry("ghcr.io/other/app:1.0", []string{"ghcr.io/team"}))
+	assert.False(t, allowedRegistry("ghcr.io.evil.com/app:1.0", []string{"ghcr.io"}))
+}
+
+func TestEvaluateImage_Validation(t *testing.T) {
+	tt := &tools{cfg: config.Default()}
+	res, _, err := tt.EvaluateImage(context.Background(), nil, types.EvaluateImageParams{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
@@ -0,0 +1,194 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+const (
+	// defaultFleetTag is the tag scanned in each repository by 'scan-registry'
+	defaultFleetTag = "latest"
+	// defaultMaxRepositories caps the repositories scanned by 'scan-registry'
+	defaultMaxRepositories = 50
+)
+
+// ScanRegistry scans one tag of each repository in a registry's catalog, or in a list of
+// repositories, and aggregates the results into a fleet vulnerability report
+func (t *tools) ScanRegistry(ctx context.Context, req *mcp.CallToolRequest, params types.ScanRegistryParams) (*mcp.CallToolResult, any, error) {
+	if params.Registry == "" && len(params.Repositories) == 0 {
+		return errorResult(copaerrors.NewValidationError("registry or repositories is required", nil)), nil, nil
+	}
+	if params.MaxRepositories < 0 {
+		return errorResult(copaerrors.NewValidationError("maxRepositories must not be negative", nil)), nil, nil
+	}
+	if params.TimeoutSeconds < 0 {
+		return errorResult(copaerrors.NewValidationError("timeoutSeconds must not be negative", nil)), nil, nil
+	}
+	tag := params.Tag
+	if tag == "" {
+		tag = defaultFleetTag
+	}
+	limit := params.MaxRepositories
+	if limit == 0 {
+		limit = defaultMaxRepositories
+	}
+
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	report := &types.FleetReport{Registry: params.Registry, Tag: tag, Images: []types.FleetImage{}}
+	repositories := params.Repositories
+	if len(repositories) == 0 {
+		var err error
+		if repositories, report.Truncated, err = registry.Catalog(ctx, params.Registry, limit); err != nil {
+			return errorResult(fmt.Errorf("listing the registry catalog failed: %w", err)), nil, nil
+		}
+	} else if len(repositories) > limit {
+		repositories, report.Truncated = repositories[:limit], true
+	}
+
+	for i, repository := range repositories {
+		if err := ctx.Err(); err != nil {
+			return errorResult(copaerrors.NewExecutionError("registry scan cancelled", err)), nil, nil
+		}
+
+		image := repository + ":" + tag
+		if params.Registry != "" {
+			image = registry.Host(params.Registry) + "/" + image
+		}
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
+			Data:   fmt.Sprintf("Scanning %s (%d of %d)", image, i+1, len(repositories)),
+			Level:  "info",
+			Logger: "trivy",
+		})
+
+		result := t.scanFleetImage(ctx, req, image, params)
+		if result.Error != "" {
+			report.Failed++
+		} else {
+			report.Scanned++
+		}
+		if result.Severity != nil {
+			addSeverityCounts(&report.Totals, *result.Severity)
+		}
+		report.Images = append(report.Images, result)
+	}
+	sortFleet(report.Images)
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: fleetMessage(report)}},
+		StructuredContent: report,
+	}, nil, nil
+}
+
+// scanFleetImage scans a single image of a fleet; a failed scan is recorded in the result rather than failing the report
+func (t *tools) scanFleetImage(ctx context.Context, req *mcp.CallToolRequest, image string, params types.ScanRegistryParams) types.FleetImage {
+	if err := t.verifyImage(ctx, req, params.Retry, image); err != nil {
+		return types.FleetImage{Image: image, Error: err.Error()}
+	}
+
+	var scan *trivy.ScanResult
+	err := t.retry(ctx, req, params.Retry, func() (err error) {
+		scan, err = trivy.Scan(ctx, req.Session, trivy.ScanParams{Image: image, DockerHost: params.DockerHost,
+			TimeoutSeconds: t.timeoutSeconds(params.TimeoutSeconds)})
+		return err
+	})
+	if err != nil {
+		return types.FleetImage{Image: image, Error: err.Error()}
+	}
+
+	result := types.FleetImage{Image: image, VulnCount: scan.VulnCount, ReportPath: scan.ReportPath}
+	if severities, err := trivy.Severities(scan.ReportPath); err == nil {
+		counts := &types.SeverityCounts{}
+		for _, severity := range severities {
+			countSeverity(counts, severity)
+		}
+		result.Severity = counts
+	}
+	return result
+}
+
+// countSeverity adds one vulnerability of the given trivy severity to counts
+func countSeverity(counts *types.SeverityCounts, severity string) {
+	switch severity {
+	case "CRITICAL":
+		counts.Critical++
+	case "HIGH":
+		counts.High++
+	case "MEDIUM":
+		counts.Medium++
+	case "LOW":
+		counts.Low++
+	default:
+		counts.Unknown++
+	}
+}
+
+func addSeverityCounts(total *types.SeverityCounts, c types.SeverityCounts) {
+	total.Critical += c.Critical
+	total.High += c.High
+	total.Medium += c.Medium
+	total.Low += c.Low
+	total.Unknown += c.Unknown
+}
+
+// sortFleet orders images by their most severe vulnerabilities, with failed scans last
+func sortFleet(images []types.FleetImage) {
+	key := func(image types.FleetImage) []int {
+		if image.Severity == nil {
+			return []int{0, 0, 0, 0, image.VulnCount}
+		}
+		s := image.Severity
+		return []int{s.Critical, s.High, s.Medium, s.Low, image.VulnCount}
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if (images[i].Error == "") != (images[j].Error == "") {
+			return images[i].Error == ""
+		}
+		a, b := key(images[i]), key(images[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] > b[k]
+			}
+		}
+		return images[i].Image < images[j].Image
+	})
+}
+
+// fleetMessage formats a fleet report as text
+func fleetMessage(report *types.FleetReport) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("Registry scan completed: %d images scanned, %d failed\n", report.Scanned, report.Failed))
+	msg.WriteString(fmt.Sprintf("Fixable vulnerabilities across the fleet: %s\n", formatSeverityCounts(report.Totals)))
+	if report.Truncated {
+		msg.WriteString("Only the first repositories were scanned; raise maxRepositories to scan more.\n")
+	}
+
+	for _, image := range report.Images {
+		switch {
+		case image.Error != "":
+			msg.WriteString(fmt.Sprintf("\n %s: scan failed: %s", image.Image, image.Error))
+		case image.Severity != nil:
+			msg.WriteString(fmt.Sprintf("\n %s: %d fixable (%s), report: %s", image.Image, image.VulnCount, formatSeverityCounts(*image.Severity), image.ReportPath))
+		default:
+			msg.WriteString(fmt.Sprintf("\n %s: %d fixable, report: %s", image.Image, image.VulnCount, image.ReportPath))
+		}
+	}
+
+	if report.Scanned > 0 {
+		msg.WriteString("\n\n=== NEXT STEPS ===")
+		msg.WriteString("\nTo patch an image, use the 'patch-report-based' tool with its report directory.")
+	}
+	return msg.String()
+}
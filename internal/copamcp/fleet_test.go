@@ -0,0 +1,58 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortFleet(t *testing.T) {
+	images := []types.FleetImage{
+		{Image: "failed", Error: "scan failed"},
+		{Image: "clean", Severity: &types.SeverityCounts{}},
+		{Image: "high", VulnCount: 5, Severity: &types.SeverityCounts{High: 5}},
+		{Image: "critical", VulnCount: 1, Severity: &types.SeverityCounts{Critical: 1}},
+		{Image: "unknown-severity", VulnCount: 2},
+	}
+	sortFleet(images)
+
+	var order []string
+	for _, image := range images {
+		order = append(order, image.Image)
+	}
+	assert.Equal(t, []string{"critical", "high", "unknown-severity", "clean", "failed"}, order)
+}
+
+func TestFleetMessage(t *testing.T) {
+	msg := fleetMessage(&types.FleetReport{
+		Tag: "latest",
+		Images: []types.FleetImage{
+			{Image: "registry.example.com/app:latest", VulnCount: 2, Severity: &types.SeverityCounts{Critical: 1, Low: 1}, ReportPath: "/tmp/reports-1"},
+			{Image: "registry.example.com/db:latest", Error: "manifest unknown"},
+		},
+		Scanned:   1,
+		Failed:    1,
+		Totals:    types.SeverityCounts{Critical: 1, Low: 1},
+		Truncated: true,
+	})
+
+	assert.Contains(t, msg, "1 images scanned, 1 failed")
+	assert.Contains(t, msg, "CRITICAL 1, HIGH 0, MEDIUM 0, LOW 1")
+	assert.Contains(t, msg, "raise maxRepositories")
+	assert.Contains(t, msg, "registry.example.com/app:latest: 2 fixable")
+	assert.Contains(t, msg, "registry.example.com/db:latest: scan failed: manifest unknown")
+	assert.Contains(t, msg, "patch-report-based")
+}
+
+func TestScanRegistry_RequiresRegistryOrRepositories(t *testing.T) {
+	tl := &tools{cfg: &config.Config{}}
+	res, _, err := tl.ScanRegistry(context.Background(), &mcp.CallToolRequest{}, types.ScanRegistryParams{})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Equal(t, "validation", res.StructuredContent.(types.ToolError).Category)
+}
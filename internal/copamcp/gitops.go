@@ -0,0 +1,76 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/gitops"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// gitOpsSnippets renders the configuration for the image automation tool to deploy a patched image.
+// The automation watches a registry, so an image that was not pushed only adds a warning.
+func gitOpsSnippets(tool string, push bool, result *copa.ExecutionResult) *types.GitOpsSnippets {
+	if tool == "" || result.PatchedImage == "" {
+		return nil
+	}
+	if !push {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s was not pushed: push it for %s to deploy it", result.PatchedImage, tool))
+	}
+	return gitops.Render(tool, result.PatchedImage)
+}
+
+// gitOpsMessage appends the image automation configuration to a patch result's text
+func gitOpsMessage(snippets *types.GitOpsSnippets) string {
+	if snippets == nil {
+		return ""
+	}
+	var msg strings.Builder
+	msg.WriteString("\n\n=== GITOPS ===")
+	switch snippets.Tool {
+	case gitops.Flux:
+		msg.WriteString("\nAdd to the cluster's Flux configuration:\n")
+		msg.WriteString(snippets.Manifests)
+		msg.WriteString("\nand use this image field, with its setter marker, in the deployment manifests:\n")
+		msg.WriteString(snippets.Marker)
+	case gitops.ArgoCD:
+		msg.WriteString("\nAdd these annotations to the Argo CD Application that deploys the image:")
+		for _, key := range slices.Sorted(maps.Keys(snippets.Annotations)) {
+			msg.WriteString(fmt.Sprintf("\n  %s: %s", key, snippets.Annotations[key]))
+		}
+	}
+	return msg.String()
+}
+
+// pinReference resolves the tag of a pushed patched image to its digest, so that deployments can
+// pin exactly the image that was patched. The image is already pushed, so a failed lookup only
+// adds a warning.
+func (t *tools) pinReference(ctx context.Context, req *mcp.CallToolRequest, override *types.RetryParams, push bool, result *copa.ExecutionResult) *types.PinnedReference {
+	if !push || result.PatchedImage == "" {
+		return nil
+	}
+	var digest string
+	err := t.retry(ctx, req, override, func() (err error) {
+		digest, err = registry.Digest(ctx, result.PatchedImage)
+		return err
+	})
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to resolve the digest of %s to pin it: %v", result.PatchedImage, err))
+		return nil
+	}
+	return gitops.Pin(result.PatchedImage, digest)
+}
+
+// pinnedMessage appends the digest-pinned reference of a pushed image to a patch result's text
+func pinnedMessage(pinned *types.PinnedReference) string {
+	if pinned == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\n=== PINNED ===\n%s\nDeploy it with:\n  %s\nor in a compose file:\n%s", pinned.Reference, pinned.Kubectl, pinned.Compose)
+}
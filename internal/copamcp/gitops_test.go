@@ -0,0 +1,66 @@
+package copamcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitOpsSnippets(t *testing.T) {
+	assert.Nil(t, gitOpsSnippets("", true, &copa.ExecutionResult{PatchedImage: "nginx:1.25-patched"}))
+
+	result := &copa.ExecutionResult{PatchedImage: "ghcr.io/org/nginx:1.25-patched"}
+	snippets := gitOpsSnippets("argocd", false, result)
+	require.NotNil(t, snippets)
+	assert.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "was not pushed")
+
+	msg := gitOpsMessage(snippets)
+	assert.Contains(t, msg, "=== GITOPS ===")
+	assert.Contains(t, msg, "argocd-image-updater.argoproj.io/image-list: nginx=ghcr.io/org/nginx:1.25-patched")
+
+	msg = gitOpsMessage(gitOpsSnippets("flux", true, result))
+	assert.Contains(t, msg, "kind: ImagePolicy")
+	assert.Contains(t, msg, `# {"$imagepolicy": "flux-system:nginx"}`)
+	assert.Len(t, result.Warnings, 1)
+}
+
+func TestPinReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("b", 64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/org/nginx/manifests/1.25-patched" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer srv.Close()
+	registry := strings.TrimPrefix(srv.URL, "http://")
+	tt := &tools{cfg: config.Default()}
+	once := 1
+	retry := &types.RetryParams{MaxAttempts: &once}
+
+	result := &copa.ExecutionResult{PatchedImage: registry + "/org/nginx:1.25-patched"}
+	assert.Nil(t, tt.pinReference(context.Background(), nil, retry, false, result))
+
+	pinned := tt.pinReference(context.Background(), nil, retry, true, result)
+	require.NotNil(t, pinned)
+	assert.Equal(t, registry+"/org/nginx@"+digest, pinned.Reference)
+	assert.Empty(t, result.Warnings)
+	msg := pinnedMessage(pinned)
+	assert.Contains(t, msg, "=== PINNED ===")
+	assert.Contains(t, msg, "kubectl set image deployment/nginx nginx="+registry+"/org/nginx@"+digest)
+
+	result = &copa.ExecutionResult{PatchedImage: registry + "/org/missing:1.0"}
+	assert.Nil(t, tt.pinReference(context.Background(), nil, retry, true, result))
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "failed to resolve the digest")
+}
@@ -0,0 +1,91 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/harbor"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// FetchHarborReport converts the vulnerability report Harbor already produced for an image into a
+// report directory for 'patch-report-based', instead of scanning the image again
+func (t *tools) FetchHarborReport(ctx context.Context, req *mcp.CallToolRequest, params types.HarborReportParams) (*mcp.CallToolResult, any, error) {
+	if params.Image == "" {
+		return errorResult(copaerrors.NewValidationError("image parameter is required", nil)), nil, nil
+	}
+	ref, err := harbor.ParseReference(params.Image)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	settings := harbor.Settings{URL: t.cfg.HarborURL, Username: t.cfg.HarborUsername, Password: t.cfg.HarborPassword}
+	if params.HarborURL != "" {
+		settings.URL = params.HarborURL
+	}
+
+	start := time.Now()
+	var report *harbor.Report
+	err = t.retry(ctx, req, params.Retry, func() (err error) {
+		report, err = harbor.FetchReport(ctx, settings, ref)
+		return err
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("fetching the Harbor vulnerability report failed: %w", err)), nil, nil
+	}
+
+	// Harbor's report does not name the distribution, which copa needs to pick a package manager
+	osID, osVersion, err := docker.OSRelease(ctx, params.DockerHost, params.Image)
+	if err != nil {
+		return errorResult(fmt.Errorf("detecting the distribution of %s failed: %w", params.Image, err)), nil, nil
+	}
+
+	reportDir, err := workdir.MkdirTemp("reports-*")
+	if err != nil {
+		return errorResult(copaerrors.NewSystemError("failed to create temporary report directory", err)), nil, nil
+	}
+	vulnCount, err := harbor.WriteTrivyReport(report, params.Image, osID, osVersion, reportDir)
+	if err != nil {
+		os.RemoveAll(reportDir)
+		return errorResult(copaerrors.NewSystemError("failed to write the converted report", err)), nil, nil
+	}
+
+	result := &trivy.ScanResult{
+		Image:         params.Image,
+		ReportPath:    reportDir,
+		VulnCount:     vulnCount,
+		Platforms:     []string{},
+		ScanCompleted: true,
+		Duration:      time.Since(start).Round(time.Millisecond).String(),
+	}
+	link := t.addReportResource(params.Image, reportDir)
+	result.ReportURI = link.URI
+
+	var resultMsg strings.Builder
+	resultMsg.WriteString(fmt.Sprintf("Fetched Harbor vulnerability report for image: %s\n", params.Image))
+	if report.Scanner.Name != "" {
+		resultMsg.WriteString(fmt.Sprintf("Scanned by %s %s at %s\n", report.Scanner.Name, report.Scanner.Version, report.GeneratedAt))
+	}
+	resultMsg.WriteString(fmt.Sprintf("Distribution: %s %s\n", osID, osVersion))
+	resultMsg.WriteString(fmt.Sprintf("Fixable vulnerabilities: %d\n", vulnCount))
+	resultMsg.WriteString(fmt.Sprintf("Report directory: %s\n", reportDir))
+	resultMsg.WriteString(fmt.Sprintf("Report resource: %s\n", result.ReportURI))
+	resultMsg.WriteString("\n=== NEXT STEPS ===")
+	resultMsg.WriteString("\nTo patch these vulnerabilities, use the 'patch-report-based' tool with the above report directory path.")
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: resultMsg.String()}, link},
+		StructuredContent: result,
+	}, nil, nil
+}
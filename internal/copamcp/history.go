@@ -0,0 +1,138 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/history"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+)
+
+// defaultHistoryLimit is the number of patches 'patch-history' returns when the call sets no limit
+const defaultHistoryLimit = 20
+
+// withHistory records the patches completed by calls to tool to the history file at path. Dry
+// runs and failed calls are not recorded.
+func withHistory[In any](path, tool string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if path == "" {
+		return h
+	}
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		res, out, err := h(ctx, req, params)
+		if err != nil || res == nil || res.IsError {
+			return res, out, err
+		}
+		record, ok := patchRecord(tool, res.StructuredContent)
+		if !ok {
+			return res, out, err
+		}
+		if err := history.Append(path, record); err != nil {
+			logf("warning", "failed to record the patch of %s to the history %s: %v", record.Image, path, err)
+		}
+		return res, out, nil
+	}
+}
+
+// patchRecord returns the history record of the structured result of a call to tool, and false
+// when the call did not patch an image
+func patchRecord(tool string, result any) (types.PatchRecord, bool) {
+	record := types.PatchRecord{Time: time.Now().UTC().Format(time.RFC3339), Tool: tool}
+	switch r := result.(type) {
+	case types.PatchResult:
+		if r.DryRun || len(r.PatchedImage) == 0 {
+			return record, false
+		}
+		record.Image, record.PatchedImages = r.OriginalImage, r.PatchedImage
+		record.NumFixedVulns, record.UpdatedPackageCount = r.NumFixedVulns, r.UpdatedPackageCount
+		if r.Severity != nil {
+			record.Fixed, record.Remaining = &r.Severity.Fixed, &r.Severity.Remaining
+		}
+		if r.Pinned != nil {
+			record.Digest = r.Pinned.Digest
+		}
+	case types.Remediation:
+		if r.DryRun || r.PatchedImage == "" {
+			return record, false
+		}
+		record.Image, record.PatchedImages = r.Image, []string{r.PatchedImage}
+		record.NumFixedVulns, record.UpdatedPackageCount = len(r.Fixed), r.UpdatedPackageCount
+		record.Remaining = r.After
+		if r.Pinned != nil {
+			record.Digest = r.Pinned.Digest
+		}
+	default:
+		return record, false
+	}
+	return record, true
+}
+
+// PatchHistory lists the patches recorded to the history, newest first
+func (t *tools) PatchHistory(ctx context.Context, req *mcp.CallToolRequest, params types.PatchHistoryParams) (*mcp.CallToolResult, any, error) {
+	result, err := QueryHistory(t.cfg, params)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: HistoryText(result)}},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// QueryHistory returns the patches recorded to the history of cfg that params selects
+func QueryHistory(cfg *config.Config, params types.PatchHistoryParams) (types.PatchHistory, error) {
+	result := types.PatchHistory{Patches: []types.PatchRecord{}, Path: cfg.History}
+	if err := validate.PatchHistory(params).Err(); err != nil {
+		return result, err
+	}
+	if cfg.History == "" {
+		return result, copaerrors.NewValidationError("the patch history is disabled", nil,
+			fmt.Sprintf("ask an operator to set %s on the server to the file to record patches to", config.EnvHistory))
+	}
+
+	filter := history.Filter{Image: params.Image}
+	if params.Since != "" {
+		since, _ := time.ParseDuration(params.Since)
+		filter.Since = time.Now().Add(-since)
+	}
+	records, warnings, err := history.Query(cfg.History, filter)
+	if err != nil {
+		return result, copaerrors.NewSystemError("failed to read the patch history", err)
+	}
+	result.Total, result.Warnings = len(records), warnings
+	if limit := cmp.Or(params.Limit, defaultHistoryLimit); len(records) > limit {
+		records = records[:limit]
+	}
+	if records != nil {
+		result.Patches = records
+	}
+	return result, nil
+}
+
+// HistoryText describes the patches of h, one per line
+func HistoryText(h types.PatchHistory) string {
+	var msg strings.Builder
+	if h.Total == 0 {
+		msg.WriteString(fmt.Sprintf("No patches recorded in %s", h.Path))
+	} else {
+		msg.WriteString(fmt.Sprintf("%d of %d recorded patches, newest first (%s):", len(h.Patches), h.Total, h.Path))
+	}
+	for _, r := range h.Patches {
+		msg.WriteString(fmt.Sprintf("\n %s %s -> %s: %d vulnerabilities fixed, %d packages updated (%s)",
+			r.Time, r.Image, strings.Join(r.PatchedImages, ", "), r.NumFixedVulns, r.UpdatedPackageCount, r.Tool))
+		if r.Digest != "" {
+			msg.WriteString(" digest " + r.Digest)
+		}
+	}
+	for _, w := range h.Warnings {
+		msg.WriteString("\nWarning: " + w)
+	}
+	return msg.String()
+}
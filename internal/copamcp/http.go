@@ -0,0 +1,122 @@
+package copamcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// SSEPath is the path of the HTTP+SSE transport of clients predating the streamable HTTP one
+const SSEPath = "/sse"
+
+// shutdownTimeout bounds the wait for in-flight requests when an HTTP server stops
+const shutdownTimeout = 10 * time.Second
+
+// RunHTTP starts the MCP server on addr, e.g. "127.0.0.1:8080", and the scheduler when a schedule
+// file is configured. Clients connect over the streamable HTTP transport at any path but SSEPath,
+// which serves the HTTP+SSE transport. With the API tokens of cfg, clients must authenticate with
+// one and may only call the tools of its scope; without any, addr must be a loopback address. The
+// pprof profiles are served at DebugPath when cfg has a pprof token.
+func RunHTTP(ctx context.Context, version string, cfg *config.Config, addr string) error {
+	tokens, err := ParseAPITokens(cfg.APITokens, cfg.APITokensFile)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 && !isLoopback(addr) {
+		return copaerrors.NewValidationError(fmt.Sprintf("refusing to serve every tool without authentication on %s", addr), nil,
+			fmt.Sprintf("set API tokens with --api-token, --api-tokens-file or %s, or listen on a loopback address such as 127.0.0.1:8080", config.EnvAPITokens))
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer listener.Close()
+	return serve(ctx, version, cfg, func(ctx context.Context, server *mcp.Server) error {
+		handler, err := httpHandler(server, tokens, cfg.PprofToken)
+		if err != nil {
+			return err
+		}
+		logf("info", "Serving MCP over streamable HTTP at http://%s/ and HTTP+SSE at http://%s%s", listener.Addr(), listener.Addr(), SSEPath)
+		if len(tokens) == 0 {
+			logf("warning", "Clients are not authenticated; every local user may call every tool")
+		}
+		return serveHTTP(ctx, listener, handler)
+	})
+}
+
+// httpHandler serves server over the streamable HTTP transport, and the HTTP+SSE one at SSEPath.
+// With tokens, both require one of them; the HTTP+SSE transport does not pass the token to the
+// tool calls, so it needs a token of ScopeFull. The pprof profiles are served at DebugPath when
+// pprofToken is set.
+func httpHandler(server *mcp.Server, tokens []APIToken, pprofToken string) (http.Handler, error) {
+	getServer := func(*http.Request) *mcp.Server { return server }
+	var sse, streamable http.Handler = mcp.NewSSEHandler(getServer), mcp.NewStreamableHTTPHandler(getServer, nil)
+	if len(tokens) > 0 {
+		var err error
+		if sse, err = RequireTokens(requireFullScope(sse), tokens); err != nil {
+			return nil, err
+		}
+		if streamable, err = RequireTokens(streamable, tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(SSEPath, sse)
+	mux.Handle("/", streamable)
+	if pprofToken != "" {
+		debug, err := NewDebugHandler(pprofToken)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle(DebugPath, debug)
+	}
+	return mux, nil
+}
+
+// isLoopback reports whether addr, host:port, only listens on the loopback interface. An empty
+// host listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveHTTP serves handler on listener until ctx is done, then waits up to shutdownTimeout for
+// in-flight requests before closing the remaining connections, e.g. open SSE streams
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
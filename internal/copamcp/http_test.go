@@ -0,0 +1,138 @@
+package copamcp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler_ServesBothTransports(t *testing.T) {
+	handler, err := httpHandler(NewServer("test", &config.Config{}), nil, "")
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx := context.Background()
+	for name, transport := range map[string]mcp.Transport{
+		"streamable": &mcp.StreamableClientTransport{Endpoint: srv.URL + "/mcp"},
+		"sse":        &mcp.SSEClientTransport{Endpoint: srv.URL + SSEPath},
+	} {
+		client := mcp.NewClient(&mcp.Implementation{Name: "remote", Version: "test"}, nil)
+		session, err := client.Connect(ctx, transport, nil)
+		require.NoError(t, err, name)
+
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: ToolWorkflowGuide, Arguments: map[string]any{}})
+		require.NoError(t, err, name)
+		assert.False(t, res.IsError, name)
+		session.Close()
+	}
+}
+
+func TestServeHTTP_StopsWithContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	handler, err := httpHandler(NewServer("test", &config.Config{}), nil, "")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveHTTP(ctx, listener, handler)
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(shutdownTimeout):
+		t.Fatal("serveHTTP did not return after its context was done")
+	}
+}
+
+func TestHTTPHandler_Tokens(t *testing.T) {
+	tokens := []APIToken{{Token: "scan-token", Scope: ScopeScan}, {Token: "full-token", Scope: ScopeFull}}
+	handler, err := httpHandler(NewServer("test", &config.Config{}), tokens, "pprof-token")
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	status := func(path, token string) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusUnauthorized, status("/mcp", ""))
+	assert.Equal(t, http.StatusUnauthorized, status(SSEPath, ""))
+	// The HTTP+SSE transport cannot check the scope of each tool call
+	assert.Equal(t, http.StatusForbidden, status(SSEPath, "scan-token"))
+	assert.Equal(t, http.StatusUnauthorized, status(DebugPath, "full-token"), "the profiles have a token of their own")
+	assert.Equal(t, http.StatusOK, status(DebugPath, "pprof-token"))
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "dashboard", Version: "test"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   srv.URL + "/mcp",
+		HTTPClient: &http.Client{Transport: bearer("scan-token")},
+	}, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: ToolWorkflowGuide, Arguments: map[string]any{}})
+	require.NoError(t, err)
+	assert.False(t, res.IsError)
+	res, err = session.CallTool(ctx, &mcp.CallToolParams{Name: ToolInstallDependencies, Arguments: map[string]any{}})
+	require.NoError(t, err)
+	assert.True(t, res.IsError, "a scan token cannot install binaries")
+}
+
+func TestParseAPITokens(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(file, []byte("# dashboards\nscan:abc\n\nfull:def\n"), 0o600))
+
+	tokens, err := ParseAPITokens([]string{"patch-no-push:ghi"}, file)
+	require.NoError(t, err)
+	assert.Equal(t, []APIToken{{Token: "ghi", Scope: ScopePatchNoPush}, {Token: "abc", Scope: ScopeScan}, {Token: "def", Scope: ScopeFull}}, tokens)
+
+	_, err = ParseAPITokens([]string{"abc"}, "")
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "abc", "a token missing its scope is not echoed")
+	_, err = ParseAPITokens([]string{"admin:abc"}, "")
+	assert.Error(t, err)
+	_, err = ParseAPITokens(nil, filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestRunHTTP_RefusesPublicAddressWithoutTokens(t *testing.T) {
+	err := RunHTTP(context.Background(), "test", &config.Config{}, ":0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without authentication")
+
+	for addr, loopback := range map[string]bool{"127.0.0.1:8080": true, "[::1]:8080": true, "localhost:8080": true, ":8080": false, "0.0.0.0:8080": false, "10.0.0.5:8080": false} {
+		assert.Equal(t, loopback, isLoopback(addr), addr)
+	}
+}
+
+// bearer adds an Authorization header to every request
+type bearer string
+
+func (b bearer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+string(b))
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,184 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/gitpr"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// tokenEnv names the environment variable of each provider's token
+var tokenEnv = map[string]string{
+	gitpr.ProviderGitHub: config.EnvGitHubToken,
+	gitpr.ProviderGitLab: config.EnvGitLabToken,
+}
+
+// OpenImagePR rewrites the references to an image in the files of a Git repository to its patched
+// image, pinned by digest, and opens a pull request with the change, so that the patch reaches the
+// deployments that use the image
+func (t *tools) OpenImagePR(ctx context.Context, req *mcp.CallToolRequest, params types.ImagePRParams) (*mcp.CallToolResult, any, error) {
+	if params.Repository == "" || params.Image == "" || params.PatchedImage == "" {
+		return errorResult(copaerrors.NewValidationError("repository, image and patchedImage parameters are required", nil)), nil, nil
+	}
+	// Without a tag or digest, the reference would match every mention of the repository name
+	if name := params.Image[strings.LastIndex(params.Image, "/")+1:]; !strings.ContainsAny(name, ":@") {
+		return errorResult(copaerrors.NewValidationError(fmt.Sprintf("image %s must include a tag or digest", params.Image), nil,
+			"pass the exact reference the repository uses, e.g. nginx:1.25")), nil, nil
+	}
+	files := params.Files
+	if len(files) == 0 {
+		files = gitpr.DefaultFiles
+	}
+	if err := gitpr.ValidateGlobs(files); err != nil {
+		return errorResult(err), nil, nil
+	}
+	repo, err := gitpr.ParseRepository(params.Repository, params.Provider)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	if !params.DryRun && !t.gitpr.CanPush(repo) {
+		return errorResult(copaerrors.NewValidationError(fmt.Sprintf("no %s token is configured to push to %s", repo.Provider, repo.Path), nil,
+			fmt.Sprintf("ask an operator to set %s on the server, or call with dryRun to preview the change", tokenEnv[repo.Provider]))), nil, nil
+	}
+
+	var digest string
+	err = t.retry(ctx, req, params.Retry, func() (err error) {
+		digest, err = registry.Digest(ctx, params.PatchedImage)
+		return err
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("resolving the digest of %s failed (push the patched image first): %w", params.PatchedImage, err)), nil, nil
+	}
+	result := types.ImagePR{
+		Repository:   params.Repository,
+		Image:        params.Image,
+		PatchedImage: pinDigest(params.PatchedImage, digest),
+		DryRun:       params.DryRun,
+		Files:        []types.ImageReferenceFile{},
+	}
+
+	tmp, err := workdir.MkdirTemp("copa-mcp-pr-")
+	if err != nil {
+		return errorResult(copaerrors.NewSystemError("failed to create a directory for the clone", err)), nil, nil
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "repo")
+	err = t.retry(ctx, req, params.Retry, func() (err error) {
+		// A failed attempt may leave a partial clone behind
+		os.RemoveAll(dir)
+		result.BaseBranch, err = t.gitpr.Clone(ctx, repo, params.BaseBranch, dir)
+		return err
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("cloning %s failed: %w", params.Repository, err)), nil, nil
+	}
+
+	changes, err := gitpr.Rewrite(dir, files, params.Image, result.PatchedImage)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		result.Files = append(result.Files, types.ImageReferenceFile{Path: c.Path, Replacements: c.Replacements})
+		paths = append(paths, c.Path)
+	}
+	if len(changes) == 0 || params.DryRun {
+		return &mcp.CallToolResult{
+			Content:           []mcp.Content{&mcp.TextContent{Text: imagePRMessage(result)}},
+			StructuredContent: result,
+		}, nil, nil
+	}
+
+	result.Branch = params.Branch
+	if result.Branch == "" {
+		result.Branch = bumpBranch(params.PatchedImage, digest)
+	}
+	title := params.Title
+	if title == "" {
+		title = fmt.Sprintf("Use patched image %s", params.PatchedImage)
+	}
+	body := params.Body
+	if body == "" {
+		body = imagePRBody(result)
+	}
+	// Pushing is not retried: the branch exists after a push that failed late
+	if err := t.gitpr.Push(ctx, repo, dir, result.Branch, title+"\n\n"+imagePRBody(result), paths); err != nil {
+		return errorResult(fmt.Errorf("pushing branch %s to %s failed: %w", result.Branch, params.Repository, err)), nil, nil
+	}
+
+	var opened *gitpr.Opened
+	err = t.retry(ctx, req, params.Retry, func() (err error) {
+		opened, err = t.gitpr.OpenPullRequest(ctx, repo, gitpr.PullRequest{Title: title, Body: body, Head: result.Branch, Base: result.BaseBranch})
+		return err
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("opening a pull request from %s failed: %w", result.Branch, err)), nil, nil
+	}
+	result.Number, result.URL = opened.Number, opened.URL
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: imagePRMessage(result)}},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// pinDigest pins image to digest, keeping its tag for readability
+func pinDigest(image, digest string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+	return image + "@" + digest
+}
+
+// bumpBranch names the branch of a bump after the patched image's repository and digest, so that
+// bumps to different images, or to later patches, do not collide
+func bumpBranch(image, digest string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	name = name[strings.LastIndex(name, "/")+1:]
+	short := strings.TrimPrefix(digest, "sha256:")
+	return fmt.Sprintf("copa/%s-%s", name, short[:min(len(short), 12)])
+}
+
+// imagePRBody describes a bump in the default pull request body and in the commit message
+func imagePRBody(result types.ImagePR) string {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Replaces `%s` with the patched image `%s`.\n\n", result.Image, result.PatchedImage))
+	for _, f := range result.Files {
+		body.WriteString(fmt.Sprintf("- %s (%d)\n", f.Path, f.Replacements))
+	}
+	body.WriteString("\nThe image was patched with Copacetic.")
+	return body.String()
+}
+
+func imagePRMessage(result types.ImagePR) string {
+	var msg strings.Builder
+	switch {
+	case len(result.Files) == 0:
+		msg.WriteString(fmt.Sprintf("No references to %s found in %s (branch %s)", result.Image, result.Repository, result.BaseBranch))
+	case result.DryRun:
+		msg.WriteString(fmt.Sprintf("Dry run: would replace %s with %s in %s (branch %s)", result.Image, result.PatchedImage, result.Repository, result.BaseBranch))
+	default:
+		msg.WriteString(fmt.Sprintf("Opened pull request #%d: %s\nReplaced %s with %s on branch %s", result.Number, result.URL, result.Image, result.PatchedImage, result.Branch))
+	}
+	for _, f := range result.Files {
+		msg.WriteString(fmt.Sprintf("\n - %s: %d references", f.Path, f.Replacements))
+	}
+	if len(result.Files) == 0 {
+		msg.WriteString("\n\nCheck that image is the exact reference the repository uses, and widen files if the references are in other files.")
+	}
+	return msg.String()
+}
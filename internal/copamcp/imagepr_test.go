@@ -0,0 +1,45 @@
+package copamcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/gitpr"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenImagePR_Validation(t *testing.T) {
+	tt := &tools{cfg: config.Default(), gitpr: gitpr.New("", "")}
+	tests := []struct {
+		name   string
+		params types.ImagePRParams
+		want   string
+	}{
+		{"missing repository", types.ImagePRParams{Image: "nginx:1.25", PatchedImage: "ghcr.io/org/nginx:1.25-patched"}, "required"},
+		{"untagged image", types.ImagePRParams{Repository: "https://github.com/org/deploy", Image: "nginx", PatchedImage: "ghcr.io/org/nginx:1.25-patched"}, "tag or digest"},
+		{"invalid glob", types.ImagePRParams{Repository: "https://github.com/org/deploy", Image: "nginx:1.25", PatchedImage: "ghcr.io/org/nginx:1.25-patched", Files: []string{"[.yaml"}}, "invalid file glob"},
+		{"no token", types.ImagePRParams{Repository: "https://gitlab.com/group/deploy", Image: "nginx:1.25", PatchedImage: "ghcr.io/org/nginx:1.25-patched"}, config.EnvGitLabToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, _, err := tt.OpenImagePR(context.Background(), nil, test.params)
+			require.NoError(t, err)
+			require.True(t, res.IsError)
+			toolErr := res.StructuredContent.(types.ToolError)
+			assert.Equal(t, "validation", toolErr.Category)
+			assert.Contains(t, toolErr.Message+" "+strings.Join(toolErr.Hints, " "), test.want)
+		})
+	}
+}
+
+func TestBumpBranch(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	assert.Equal(t, "copa/nginx-0123456789ab", bumpBranch("ghcr.io/org/nginx:1.25-patched", digest))
+	assert.Equal(t, "copa/app-0123456789ab", bumpBranch("localhost:5000/app@"+digest, digest))
+	assert.Equal(t, "ghcr.io/org/nginx:1.25-patched@"+digest, pinDigest("ghcr.io/org/nginx:1.25-patched", digest))
+	assert.Equal(t, "ghcr.io/org/nginx@"+digest, pinDigest("ghcr.io/org/nginx@"+digest, digest))
+}
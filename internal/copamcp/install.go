@@ -0,0 +1,66 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/install"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// InstallDependencies downloads the pinned copa and trivy releases into the managed bin directory
+// and puts it first on the PATH, so the following calls use them
+func (t *tools) InstallDependencies(ctx context.Context, req *mcp.CallToolRequest, params types.InstallDependenciesParams) (*mcp.CallToolResult, any, error) {
+	names := params.Tools
+	if len(names) == 0 {
+		names = install.Tools
+	}
+	for _, name := range names {
+		if !slices.Contains(install.Tools, name) {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("unknown tool: %s", name), nil,
+				fmt.Sprintf("install one of: %s", strings.Join(install.Tools, ", ")))), nil, nil
+		}
+	}
+
+	dir, err := install.Dir(t.cfg.BinDir)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	result := types.DependencyInstall{BinDir: dir}
+	var msg strings.Builder
+	for _, name := range names {
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
+			Data:   fmt.Sprintf("Installing %s into %s", name, dir),
+			Level:  "info",
+			Logger: "install",
+		})
+		installed, err := install.Install(ctx, dir, name, params.Force)
+		if err != nil {
+			return errorResult(fmt.Errorf("installing %s failed: %w", name, err)), nil, nil
+		}
+		result.Tools = append(result.Tools, *installed)
+		if installed.AlreadyInstalled {
+			fmt.Fprintf(&msg, "%s %s is already installed at %s\n", installed.Name, installed.Version, installed.Path)
+		} else {
+			fmt.Fprintf(&msg, "Installed %s %s at %s (sha256 %s)\n", installed.Name, installed.Version, installed.Path, installed.SHA256)
+		}
+		if copaexec.Configured(name) {
+			fmt.Fprintf(&msg, "Warning: the server runs %s from %s, set with --%s-path, rather than the installed release\n", name, copaexec.Path(name), name)
+		}
+	}
+	// Mock mode keeps its stubs first on the PATH
+	if !t.cfg.Mock {
+		install.UsePath(dir)
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: msg.String()}},
+		StructuredContent: result,
+	}, nil, nil
+}
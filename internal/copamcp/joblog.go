@@ -0,0 +1,97 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+)
+
+const (
+	jobsURI         = "copa://jobs"
+	jobLogURIPrefix = "copa://jobs/"
+	jobLogURISuffix = "/log"
+	jobsMIMEType    = "application/json"
+)
+
+// withJobLog runs h as a job of tool, buffering the events it logs so that a client attaching
+// later can replay them from the job's log resource. The job's ID is logged when it starts.
+func withJobLog[In any](tool string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		ctx, job := joblog.Start(ctx, tool)
+		var session *mcp.ServerSession
+		if req != nil {
+			session = req.Session
+			if req.Params != nil {
+				job.SetProgressToken(req.Params.GetProgressToken())
+			}
+		}
+		ctx = joblog.WithSession(ctx, session)
+		joblog.Log(ctx, session, &mcp.LoggingMessageParams{
+			Data:   fmt.Sprintf("Started job %s, replay its log from %s", job.ID(), jobLogURI(job.ID())),
+			Level:  "info",
+			Logger: "copacetic-mcp",
+		})
+
+		res, out, err := h(ctx, req, params)
+		job.Finish(err != nil || res == nil || res.IsError)
+		return res, out, err
+	}
+}
+
+// jobLogURI returns the URI of the log resource of the job with id
+func jobLogURI(id string) string {
+	return jobLogURIPrefix + id + jobLogURISuffix
+}
+
+// addJobResources registers the list of recent jobs and the template of their log resources
+func addJobResources(server *mcp.Server) {
+	server.AddResource(&mcp.Resource{
+		URI:         jobsURI,
+		Name:        "jobs",
+		Description: "Recent scan and patch jobs of the server, with the number of events buffered for replay",
+		MIMEType:    jobsMIMEType,
+	}, jobsHandler)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: jobLogURIPrefix + "{id}" + jobLogURISuffix,
+		Name:        "job-log",
+		Description: fmt.Sprintf("Log events of a recent job, to replay what happened before the client attached; the last %d events of each of the last %d jobs are kept", joblog.MaxEvents, joblog.MaxJobs),
+		MIMEType:    jobsMIMEType,
+	}, jobLogHandler)
+}
+
+// jobsHandler serves the recent jobs
+func jobsHandler(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return jsonResource(req.Params.URI, joblog.List())
+}
+
+// jobLogHandler serves the buffered events of a job
+func jobLogHandler(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id := strings.TrimSuffix(strings.TrimPrefix(req.Params.URI, jobLogURIPrefix), jobLogURISuffix)
+	job, ok := joblog.Get(id)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	return jsonResource(req.Params.URI, struct {
+		joblog.Summary
+		Log []joblog.Event `json:"log"`
+	}{job.Summary(), job.Events(0)})
+}
+
+// jsonResource returns v as the JSON contents of the resource at uri
+func jsonResource(uri string, v any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{
+			URI:      uri,
+			MIMEType: jobsMIMEType,
+			Text:     string(data),
+		}},
+	}, nil
+}
@@ -0,0 +1,54 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithJobLog_ReplaysEvents(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	addJobResources(server)
+
+	var id string
+	handler := withJobLog("scan-container", func(ctx context.Context, req *mcp.CallToolRequest, params struct{}) (*mcp.CallToolResult, any, error) {
+		job, ok := joblog.FromContext(ctx)
+		require.True(t, ok)
+		id = job.ID()
+		joblog.Log(ctx, nil, &mcp.LoggingMessageParams{Data: "Scanning alpine:3.19", Level: "info", Logger: "trivy"})
+		return errorResult(assert.AnError), nil, nil
+	})
+	_, _, err := handler(ctx, nil, struct{}{})
+	require.NoError(t, err)
+
+	session, err := Connect(ctx, server)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: jobLogURI(id)})
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	var log struct {
+		joblog.Summary
+		Log []joblog.Event `json:"log"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Contents[0].Text), &log))
+	assert.Equal(t, id, log.ID)
+	assert.True(t, log.Failed)
+	require.Len(t, log.Log, 2)
+	assert.Contains(t, log.Log[0].Message, "Started job "+id)
+	assert.Equal(t, "Scanning alpine:3.19", log.Log[1].Message)
+
+	res, err = session.ReadResource(ctx, &mcp.ReadResourceParams{URI: jobsURI})
+	require.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, id)
+
+	_, err = session.ReadResource(ctx, &mcp.ReadResourceParams{URI: jobLogURI("missing-1")})
+	assert.Error(t, err)
+}
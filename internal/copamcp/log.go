@@ -0,0 +1,31 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/logging"
+	"github.com/project-copacetic/mcp-server/internal/otlp"
+)
+
+// logf writes a message of the server, outside of any tool call, to the server's logger and
+// exports it at level, an MCP logging level, to the configured OTLP collector
+func logf(level, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	slog.Log(context.Background(), logging.Level(level), msg, "logger", "copacetic-mcp")
+	otlp.Emit(otlp.Record{Level: level, Body: msg, Attributes: map[string]string{"logger": "copacetic-mcp"}})
+}
+
+// flushTimeout bounds the export of the pending logs when the server stops
+const flushTimeout = 5 * time.Second
+
+// flushLogs exports the logs still pending when the server stops
+func flushLogs() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := otlp.Flush(ctx); err != nil {
+		slog.Warn(fmt.Sprintf("failed to export the remaining logs: %v", err), "logger", "copacetic-mcp")
+	}
+}
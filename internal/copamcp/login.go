@@ -0,0 +1,35 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+)
+
+// RegistryLogin runs docker login with the given credentials, so that the following pulls, scans
+// and pushes of the session can reach a private registry without restarting the server
+func (t *tools) RegistryLogin(ctx context.Context, req *mcp.CallToolRequest, params types.RegistryLoginParams) (*mcp.CallToolResult, any, error) {
+	if err := validate.RegistryLogin(params).Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	registry := cmp.Or(params.Registry, "docker.io")
+	username := cmp.Or(params.Username, docker.TokenUsername)
+	if err := docker.Login(ctx, params.DockerHost, registry, username, params.Password); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	msg := fmt.Sprintf("Logged in to %s as %s\n", registry, username) +
+		"The credentials are stored in the server's Docker config and used by the following pulls, scans and pushes.\n"
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: msg}},
+	}, nil, nil
+}
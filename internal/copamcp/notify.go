@@ -0,0 +1,58 @@
+package copamcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/notify"
+)
+
+// notifyTimeout bounds the delivery of an event to all webhooks
+const notifyTimeout = 30 * time.Second
+
+// withNotify wraps h so that the outcome of each call is sent to the notifier's webhooks.
+// Events are delivered in the background so that a slow webhook does not delay the result,
+// and delivery failures are only logged.
+func withNotify[In any](n *notify.Notifier, tool string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if !n.Enabled() {
+		return h
+	}
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		res, out, err := h(ctx, req, params)
+		if err != nil || res == nil {
+			return res, out, err
+		}
+
+		event := notify.Event{
+			Tool:       tool,
+			Status:     notify.StatusSucceeded,
+			Summary:    resultText(res),
+			Result:     res.StructuredContent,
+			FinishedAt: time.Now().UTC(),
+		}
+		if res.IsError {
+			event.Status = notify.StatusFailed
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := n.Send(ctx, event); err != nil {
+				logf("warning", "failed to notify webhooks of %s: %v", tool, err)
+			}
+		}()
+		return res, out, nil
+	}
+}
+
+// resultText returns the text of the first text content of res
+func resultText(res *mcp.CallToolResult) string {
+	for _, content := range res.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNotify(t *testing.T) {
+	events := make(chan notify.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	defer server.Close()
+
+	n := notify.New(notify.Webhooks([]string{server.URL}, nil))
+	h := withNotify(n, ToolPatchReportBased, resultFileHandler)
+
+	for _, fail := range []bool{false, true} {
+		res, _, err := h(context.Background(), nil, resultFileParams{Fail: fail})
+		require.NoError(t, err)
+		assert.Equal(t, fail, res.IsError)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, ToolPatchReportBased, event.Tool)
+			if fail {
+				assert.Equal(t, notify.StatusFailed, event.Status)
+				assert.Contains(t, event.Summary, "copa failed")
+			} else {
+				assert.Equal(t, notify.StatusSucceeded, event.Status)
+				assert.NotNil(t, event.Result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook was not called")
+		}
+	}
+}
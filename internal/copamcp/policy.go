@@ -0,0 +1,116 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// policyDryRunWarning is added to the result of a patch the policies turned into a dry run
+const policyDryRunWarning = "the patch policies forced a dry run: the copa command was not run and no image was patched or pushed"
+
+// patchInput describes a patch of image into patchedImage to the policies. reportPath, when set,
+// is the scan report whose fixable vulnerabilities are counted by severity.
+func patchInput(tool, image, patchedImage string, push bool, exportPath string, platforms []string, reportPath string) policy.Input {
+	input := policy.Input{
+		Tool:         tool,
+		Image:        image,
+		Registry:     policy.Registry(image),
+		PatchedImage: patchedImage,
+		Push:         push,
+		ExportPath:   exportPath,
+		Platforms:    platforms,
+	}
+	if push {
+		input.PushTarget = input.PatchedImage
+	}
+	if reportPath != "" {
+		if severities, err := trivy.Severities(reportPath); err == nil {
+			counts := &types.SeverityCounts{}
+			for _, severity := range severities {
+				countSeverity(counts, severity)
+			}
+			input.Severity = counts
+		}
+	}
+	return input
+}
+
+// verifyImage checks that image comes from an allowed registry, and verifies its signature with
+// cosign, before it is pulled, scanned or patched. The signature is not verified when no trusted
+// signer is configured.
+func (t *tools) verifyImage(ctx context.Context, req *mcp.CallToolRequest, retry *types.RetryParams, image string) error {
+	if err := t.cfg.Registries.CheckSource(image); err != nil {
+		return err
+	}
+	if !t.verifier.Enabled() {
+		return nil
+	}
+	return t.retry(ctx, req, retry, func() error {
+		return t.verifier.Verify(ctx, image)
+	})
+}
+
+// checkPush checks that patchedImage may be pushed to its registry, when push is set
+func (t *tools) checkPush(patchedImage string, push bool) error {
+	if !push {
+		return nil
+	}
+	return t.cfg.Registries.CheckPush(patchedImage)
+}
+
+// checkPolicy evaluates the patch policies and reports whether the patch must run as a dry run.
+// A denied patch, or one the user did not confirm, fails with a policy error.
+func (t *tools) checkPolicy(ctx context.Context, req *mcp.CallToolRequest, input policy.Input) (dryRun bool, err error) {
+	if !t.policy.Enabled() {
+		return false, nil
+	}
+
+	decision, err := t.policy.Evaluate(ctx, policy.PatchQuery, input)
+	if err != nil {
+		return false, err
+	}
+	if len(decision.Deny) > 0 {
+		return false, copaerrors.NewPolicyError(fmt.Sprintf("patching %s was denied by policy: %s", input.Image, strings.Join(decision.Deny, "; ")), nil,
+			"change the request (e.g. the push target) so that the policy allows it, or ask an operator to review the policy")
+	}
+	if len(decision.Confirm) > 0 {
+		if err := confirmPatch(ctx, req, input, decision.Confirm); err != nil {
+			return false, err
+		}
+	}
+	return decision.DryRun, nil
+}
+
+// confirmPatch asks the user to confirm a patch with an elicitation request
+func confirmPatch(ctx context.Context, req *mcp.CallToolRequest, input policy.Input, reasons []string) error {
+	message := fmt.Sprintf("Patching %s requires confirmation: %s", input.Image, strings.Join(reasons, "; "))
+	if req == nil || req.Session == nil || req.Session.InitializeParams() == nil ||
+		req.Session.InitializeParams().Capabilities == nil || req.Session.InitializeParams().Capabilities.Elicitation == nil {
+		return copaerrors.NewPolicyError(message, nil, "use an MCP client that supports elicitation to confirm the patch")
+	}
+
+	res, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+		Message: message + ". Proceed?",
+		RequestedSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"confirm": {Type: "boolean", Description: "patch " + input.Image},
+			},
+		},
+	})
+	if err != nil {
+		return copaerrors.NewPolicyError(message, err, "use an MCP client that supports elicitation to confirm the patch")
+	}
+	if res.Action != "accept" || res.Content["confirm"] == false {
+		return copaerrors.NewPolicyError(fmt.Sprintf("patching %s was not confirmed", input.Image), nil)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchInput(t *testing.T) {
+	input := patchInput(ToolPatchComprehensive, "ghcr.io/org/app:1.0", "ghcr.io/org/app:1.0-patched", true, "", []string{"linux/amd64"}, "")
+	assert.Equal(t, policy.Input{
+		Tool:         ToolPatchComprehensive,
+		Image:        "ghcr.io/org/app:1.0",
+		Registry:     "ghcr.io",
+		PatchedImage: "ghcr.io/org/app:1.0-patched",
+		Push:         true,
+		PushTarget:   "ghcr.io/org/app:1.0-patched",
+		Platforms:    []string{"linux/amd64"},
+	}, input)
+}
+
+func TestCheckPolicy_Disabled(t *testing.T) {
+	tt := &tools{cfg: config.Default(), policy: policy.New(nil)}
+	dryRun, err := tt.checkPolicy(context.Background(), nil, policy.Input{Image: "nginx:1.25"})
+	require.NoError(t, err)
+	assert.False(t, dryRun)
+}
+
+// confirmWith calls confirmPatch from a tool handler of a client answering elicitations with action
+func confirmWith(t *testing.T, action string) error {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	var confirmErr error
+	mcp.AddTool(server, &mcp.Tool{Name: "confirm"}, func(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+		confirmErr = confirmPatch(ctx, req, policy.Input{Image: "nginx:1.25"}, []string{"production image"})
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	var opts *mcp.ClientOptions
+	if action != "" {
+		opts = &mcp.ClientOptions{ElicitationHandler: func(context.Context, *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			return &mcp.ElicitResult{Action: action, Content: map[string]any{"confirm": true}}, nil
+		}}
+	}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, opts).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "confirm", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	return confirmErr
+}
+
+func TestConfirmPatch(t *testing.T) {
+	assert.NoError(t, confirmWith(t, "accept"))
+
+	for _, action := range []string{"decline", ""} {
+		err := confirmWith(t, action)
+		require.Error(t, err, action)
+		assert.Equal(t, copaerrors.CategoryPolicy, copaerrors.CategoryOf(err), action)
+	}
+}
@@ -0,0 +1,425 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/gitops"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+const (
+	// remediationPrefix is the prefix of the working directory of a remediation, followed by its ID
+	remediationPrefix = "remediate-"
+	// remediationStateFile holds the parameters and report of a remediation in its working directory
+	remediationStateFile = "state.json"
+)
+
+// remediationStages are the stages of a remediation, in the order they run
+var remediationStages = []string{types.StageScan, types.StagePatch, types.StageRescan, types.StageCompare, types.StagePush, types.StageSign}
+
+// remediation is a run of 'remediate', saved to its working directory after each stage so that a
+// failed run can be resumed from the stage that failed
+type remediation struct {
+	dir    string
+	Params types.RemediateParams `json:"params"`
+	Report types.Remediation     `json:"report"`
+}
+
+// Remediate scans an image, patches the vulnerabilities found, rescans the patched image and
+// compares both scans, then optionally pushes and signs the patched image. Each stage is saved
+// when it finishes, so a failed remediation resumed by its ID skips the stages already completed.
+func (t *tools) Remediate(ctx context.Context, req *mcp.CallToolRequest, params types.RemediateParams) (*mcp.CallToolResult, any, error) {
+	v := validate.Remediate(params)
+	v.Check("retry", t.validateRetry(params.Retry))
+	v.Check("env", subprocess.CheckOverrides(params.Env))
+	if params.Sign && !t.signer.Enabled() {
+		v.Check("sign", copaerrors.NewValidationError("no signing key is configured", nil,
+			fmt.Sprintf("ask an operator to set %s on the server, or call without sign", config.EnvSignKey)))
+	}
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	run, err := t.openRemediation(params)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	defer t.remediations.Delete(run.Report.ID)
+
+	ctx = subprocess.WithEnv(ctx, run.Params.Env)
+	if err := docker.CheckDaemon(ctx, run.Params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	// Each stage reports its progress, which the progress of the scans within it would not increase
+	stageCtx := joblog.WithoutProgress(ctx)
+	for i := range run.Report.Stages {
+		stage := &run.Report.Stages[i]
+		if stage.Status != types.StageStatusPending {
+			continue
+		}
+		message := fmt.Sprintf("remediation %s of %s: %s", run.Report.ID, run.Report.Image, stage.Name)
+		joblog.Progress(ctx, req.Session, float64(i), float64(len(run.Report.Stages)), message)
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{Data: "Starting " + message, Level: "info", Logger: "copacetic-mcp"})
+
+		start := time.Now()
+		status, detail, err := t.runStage(stageCtx, req, run, stage.Name)
+		stage.Status, stage.Detail, stage.Duration = status, detail, time.Since(start).Round(time.Millisecond).String()
+		if err != nil {
+			stage.Status, stage.Error = types.StageStatusFailed, err.Error()
+		}
+		if saveErr := run.save(); saveErr != nil && err == nil {
+			err = copaerrors.NewSystemError("failed to save the remediation state", saveErr)
+		}
+		if err != nil {
+			return errorResult(fmt.Errorf("remediation %s of %s failed at the %s stage, call 'remediate' with resume: %s to retry from it: %w",
+				run.Report.ID, run.Report.Image, stage.Name, run.Report.ID, err)), nil, nil
+		}
+	}
+	joblog.Progress(ctx, req.Session, float64(len(run.Report.Stages)), float64(len(run.Report.Stages)), fmt.Sprintf("remediation %s of %s finished", run.Report.ID, run.Report.Image))
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: remediationMessage(run.Report)}},
+		StructuredContent: run.Report,
+	}, nil, nil
+}
+
+// openRemediation creates the working directory of a new remediation, or loads the state of the
+// remediation params resumes. The remediation is reserved until the caller deletes it from
+// t.remediations, so that it is not resumed twice at once.
+func (t *tools) openRemediation(params types.RemediateParams) (*remediation, error) {
+	if params.Resume == "" {
+		dir, err := workdir.MkdirTemp(remediationPrefix + "*")
+		if err != nil {
+			return nil, copaerrors.NewSystemError("failed to create the remediation's working directory", err)
+		}
+		run := &remediation{dir: dir, Params: params}
+		run.Report = types.Remediation{ID: strings.TrimPrefix(filepath.Base(dir), remediationPrefix), Image: params.Image}
+		for _, name := range remediationStages {
+			run.Report.Stages = append(run.Report.Stages, types.RemediationStage{Name: name, Status: types.StageStatusPending})
+		}
+		t.remediations.Store(run.Report.ID, true)
+		if err := run.save(); err != nil {
+			t.remediations.Delete(run.Report.ID)
+			return nil, copaerrors.NewSystemError("failed to save the remediation state", err)
+		}
+		return run, nil
+	}
+
+	run := &remediation{dir: filepath.Join(workdir.Root(), remediationPrefix+params.Resume)}
+	data, err := os.ReadFile(filepath.Join(run.dir, remediationStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("remediation %s not found", params.Resume), nil,
+			"the working directory of an old remediation may have been removed; start a new remediation without resume")
+	}
+	if err == nil {
+		err = json.Unmarshal(data, run)
+	}
+	if err != nil {
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("failed to load remediation %s", params.Resume), err)
+	}
+
+	v := &validate.Validator{}
+	if params.Image != "" && params.Image != run.Params.Image {
+		v.Add("image", "remediation %s is of %s, not %s", params.Resume, run.Params.Image, params.Image)
+	}
+	if params.Tag != "" && params.Tag != run.Params.Tag {
+		v.Add("patchtag", "remediation %s patches to tag %q, not %q", params.Resume, run.Params.Tag, params.Tag)
+	}
+	// Repeating push and sign keeps the scope the call needs the same as the resumed run's
+	if params.Push != run.Params.Push || params.Sign != run.Params.Sign {
+		v.Add("push", "remediation %s was started with push %t and sign %t; resume it with the same values", params.Resume, run.Params.Push, run.Params.Sign)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if _, running := t.remediations.LoadOrStore(params.Resume, true); running {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("remediation %s is already running", params.Resume), nil, "wait for the running call to finish")
+	}
+
+	// The settings of the call apply to the stages it runs
+	run.Params.Retry, run.Params.Env, run.Params.ResultPath = params.Retry, params.Env, params.ResultPath
+	run.Params.TimeoutSeconds = params.TimeoutSeconds
+	if params.DockerHost != "" {
+		run.Params.DockerHost = params.DockerHost
+	}
+	for i := range run.Report.Stages {
+		if stage := &run.Report.Stages[i]; stage.Status == types.StageStatusFailed {
+			stage.Status, stage.Error = types.StageStatusPending, ""
+		}
+	}
+	return run, nil
+}
+
+// save writes the state of r to its working directory. The environment overrides may carry
+// credentials, so they are not saved; a resumed call passes its own.
+func (r *remediation) save() error {
+	saved := *r
+	saved.Params.Env = nil
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, remediationStateFile), data, 0o600)
+}
+
+// runStage runs the stage name of run, returning its status and what it did
+func (t *tools) runStage(ctx context.Context, req *mcp.CallToolRequest, run *remediation, name string) (status, detail string, err error) {
+	switch name {
+	case types.StageScan:
+		return t.remediateScan(ctx, req, run)
+	case types.StagePatch:
+		return t.remediatePatch(ctx, req, run)
+	case types.StageRescan:
+		return t.remediateRescan(ctx, req, run)
+	case types.StageCompare:
+		return remediateCompare(run)
+	case types.StagePush:
+		return t.remediatePush(ctx, req, run)
+	case types.StageSign:
+		return t.remediateSign(ctx, req, run)
+	}
+	return "", "", fmt.Errorf("unknown stage %s", name)
+}
+
+// remediateScan verifies the image and scans it for fixable vulnerabilities
+func (t *tools) remediateScan(ctx context.Context, req *mcp.CallToolRequest, run *remediation) (string, string, error) {
+	p, report := run.Params, &run.Report
+	if err := t.verifyImage(ctx, req, p.Retry, p.Image); err != nil {
+		return "", "", err
+	}
+	scan, err := t.scanImage(ctx, req, p, p.Image)
+	if err != nil {
+		return "", "", err
+	}
+	t.uploadSBOMs(p.DockerHost, p.Image)
+	t.exportScan(p.Image, scan.ReportPath)
+
+	report.ReportPath = scan.ReportPath
+	report.Warnings = append(report.Warnings, scan.Warnings...)
+	severities, err := trivy.Severities(scan.ReportPath)
+	if err != nil {
+		return "", "", copaerrors.NewSystemError("failed to read the scan report", err)
+	}
+	report.Before = severityCounts(severities)
+	if len(severities) == 0 {
+		report.Verified = true
+		run.skip(types.StagePatch, "the image has no fixable vulnerabilities")
+	}
+	return types.StageStatusCompleted, fmt.Sprintf("found %d fixable vulnerabilities", len(severities)), nil
+}
+
+// remediatePatch patches the vulnerabilities of the scan report. The patched image stays in the
+// local daemon until it is verified, so a dry run skips every later stage.
+func (t *tools) remediatePatch(ctx context.Context, req *mcp.CallToolRequest, run *remediation) (string, string, error) {
+	p, report := run.Params, &run.Report
+	patchedImage := copa.PatchedImageRef(p.Image, p.Tag)
+	if err := t.checkPush(patchedImage, p.Push); err != nil {
+		return "", "", err
+	}
+	policyDryRun, err := t.checkPolicy(ctx, req, patchInput(ToolRemediate, p.Image, patchedImage, p.Push, "", nil, report.ReportPath))
+	if err != nil {
+		return "", "", err
+	}
+
+	patchParams := types.ReportBasedPatchParams{Image: p.Image, Tag: p.Tag, ReportPath: report.ReportPath, DockerHost: p.DockerHost,
+		TimeoutSeconds: t.timeoutSeconds(p.TimeoutSeconds)}
+	var result *copa.ExecutionResult
+	err = t.retry(ctx, req, p.Retry, func() (err error) {
+		// The report and VEX document are kept with the remediation, for the comparison and its report
+		result, err = copa.New(patchParams, policyDryRun).
+			WithBuildkit(t.cfg.BuildkitAddr, t.cfg.BuildkitWait).
+			WithRetention(true, true).
+			WithOutputLog(copaOutputLog(ctx, req)).
+			BuildWithReport().
+			Run(ctx)
+		return err
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("patching failed: %w", err)
+	}
+	t.afterPatch(p.DockerHost, p.Image, result, policyDryRun)
+
+	report.PatchedImage = result.PatchedImage
+	report.VexPath = result.VexPath
+	report.UpdatedPackageCount = result.UpdatedPackageCount
+	report.Warnings = append(report.Warnings, result.Warnings...)
+	if policyDryRun {
+		report.DryRun = true
+		run.skip(types.StageRescan, "the patch was a dry run")
+		return types.StageStatusCompleted, "dry run, nothing was patched", nil
+	}
+	return types.StageStatusCompleted, fmt.Sprintf("patched %s, %d packages updated", result.PatchedImage, result.UpdatedPackageCount), nil
+}
+
+// remediateRescan scans the patched image in the local daemon
+func (t *tools) remediateRescan(ctx context.Context, req *mcp.CallToolRequest, run *remediation) (string, string, error) {
+	scan, err := t.scanImage(ctx, req, run.Params, run.Report.PatchedImage)
+	if err != nil {
+		return "", "", err
+	}
+	run.Report.RescanReportPath = scan.ReportPath
+	run.Report.Warnings = append(run.Report.Warnings, scan.Warnings...)
+	return types.StageStatusCompleted, fmt.Sprintf("found %d fixable vulnerabilities in %s", scan.VulnCount, run.Report.PatchedImage), nil
+}
+
+// remediateCompare compares the scans of the image and the patched image. The patched image fails
+// the comparison when it has vulnerabilities the image did not have, or when the patch fixed none.
+func remediateCompare(run *remediation) (string, string, error) {
+	report := &run.Report
+	before, err := trivy.Severities(report.ReportPath)
+	if err != nil {
+		return "", "", copaerrors.NewSystemError("failed to read the scan report of the image", err)
+	}
+	after, err := trivy.Severities(report.RescanReportPath)
+	if err != nil {
+		return "", "", copaerrors.NewSystemError("failed to read the scan report of the patched image", err)
+	}
+
+	report.Fixed, report.Remaining, report.Introduced = compareSeverities(before, after)
+	report.After = severityCounts(after)
+
+	if len(report.Introduced) > 0 {
+		return "", "", copaerrors.NewPolicyError(fmt.Sprintf("the patched image %s has %d vulnerabilities the image did not have: %s",
+			report.PatchedImage, len(report.Introduced), strings.Join(report.Introduced, ", ")), nil,
+			"review the packages the patch updated with 'diff-sbom' before deploying the patched image")
+	}
+	if len(report.Fixed) == 0 {
+		return "", "", copaerrors.NewPolicyError(fmt.Sprintf("the patch fixed none of the %d fixable vulnerabilities of %s", len(before), report.Image), nil,
+			"the package repositories may not offer the fixed versions yet; retry later with resume")
+	}
+	report.Verified = len(report.Remaining) == 0
+	if !report.Verified {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d fixable vulnerabilities remain in the patched image: %s", len(report.Remaining), strings.Join(report.Remaining, ", ")))
+	}
+	return types.StageStatusCompleted, fmt.Sprintf("fixed %d, remaining %d, introduced 0", len(report.Fixed), len(report.Remaining)), nil
+}
+
+// remediatePush pushes the verified patched image and pins it to its digest. The digest is only
+// required to sign the image, so without sign a failed lookup is a warning.
+func (t *tools) remediatePush(ctx context.Context, req *mcp.CallToolRequest, run *remediation) (string, string, error) {
+	p, report := run.Params, &run.Report
+	if !p.Push {
+		return types.StageStatusSkipped, "push was not requested", nil
+	}
+	err := t.retry(ctx, req, p.Retry, func() error {
+		return docker.Push(ctx, p.DockerHost, report.PatchedImage)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var digest string
+	err = t.retry(ctx, req, p.Retry, func() (err error) {
+		digest, err = registry.Digest(ctx, report.PatchedImage)
+		return err
+	})
+	if err != nil {
+		if p.Sign {
+			return "", "", fmt.Errorf("failed to resolve the digest of %s to sign it: %w", report.PatchedImage, err)
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to resolve the digest of %s to pin it: %v", report.PatchedImage, err))
+		return types.StageStatusCompleted, "pushed " + report.PatchedImage, nil
+	}
+	report.Pinned = gitops.Pin(report.PatchedImage, digest)
+	return types.StageStatusCompleted, "pushed " + report.Pinned.Reference, nil
+}
+
+// remediateSign signs the digest of the pushed image
+func (t *tools) remediateSign(ctx context.Context, req *mcp.CallToolRequest, run *remediation) (string, string, error) {
+	if !run.Params.Sign {
+		return types.StageStatusSkipped, "sign was not requested", nil
+	}
+	err := t.retry(ctx, req, run.Params.Retry, func() error {
+		return t.signer.Sign(ctx, run.Report.Pinned.Reference)
+	})
+	if err != nil {
+		return "", "", err
+	}
+	run.Report.Signed = true
+	return types.StageStatusCompleted, "signed " + run.Report.Pinned.Reference, nil
+}
+
+// skip marks the pending stages of r from name on skipped, for reason
+func (r *remediation) skip(name, reason string) {
+	from := slices.Index(remediationStages, name)
+	for i := range r.Report.Stages {
+		if stage := &r.Report.Stages[i]; i >= from && stage.Status == types.StageStatusPending {
+			stage.Status, stage.Detail = types.StageStatusSkipped, reason
+		}
+	}
+}
+
+// scanImage scans image for fixable vulnerabilities with the settings of a remediation
+func (t *tools) scanImage(ctx context.Context, req *mcp.CallToolRequest, p types.RemediateParams, image string) (*trivy.ScanResult, error) {
+	var result *trivy.ScanResult
+	err := t.retry(ctx, req, p.Retry, func() (err error) {
+		result, err = trivy.Scan(ctx, req.Session, trivy.ScanParams{Image: image, DockerHost: p.DockerHost, Env: p.Env,
+			TimeoutSeconds: t.timeoutSeconds(p.TimeoutSeconds)})
+		return err
+	})
+	return result, err
+}
+
+// severityCounts counts the vulnerabilities of severities, keyed by ID, by severity
+func severityCounts(severities map[string]string) *types.SeverityCounts {
+	counts := &types.SeverityCounts{}
+	for _, severity := range severities {
+		countSeverity(counts, severity)
+	}
+	return counts
+}
+
+// remediationMessage describes a finished remediation
+func remediationMessage(r types.Remediation) string {
+	var msg strings.Builder
+	outcome := "verified"
+	switch {
+	case r.DryRun:
+		outcome = "dry run"
+	case !r.Verified:
+		outcome = "patched, not fully verified"
+	}
+	msg.WriteString(fmt.Sprintf("Remediation %s of %s: %s", r.ID, r.Image, outcome))
+	for _, stage := range r.Stages {
+		msg.WriteString(fmt.Sprintf("\n %s: %s", stage.Name, stage.Status))
+		if stage.Detail != "" {
+			msg.WriteString(" (" + stage.Detail + ")")
+		}
+	}
+	if r.Before != nil {
+		msg.WriteString(fmt.Sprintf("\n before: %s", formatSeverityCounts(*r.Before)))
+	}
+	if r.After != nil {
+		msg.WriteString(fmt.Sprintf("\n after: %s", formatSeverityCounts(*r.After)))
+	}
+	if len(r.Fixed) > 0 {
+		msg.WriteString(fmt.Sprintf("\n fixed vulnerabilities: %s", strings.Join(r.Fixed, ", ")))
+	}
+	if r.VexPath != "" {
+		msg.WriteString(fmt.Sprintf("\n vex document: %s", r.VexPath))
+	}
+	if r.Signed {
+		msg.WriteString(fmt.Sprintf("\n signed: %s", r.Pinned.Reference))
+	}
+	return msg.String() + warningsMessage(r.Warnings) + pinnedMessage(r.Pinned)
+}
@@ -0,0 +1,52 @@
+package copamcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeReport writes a Trivy report of the vulnerabilities ids, all HIGH, to a new report directory
+func writeReport(t *testing.T, ids ...string) string {
+	t.Helper()
+	var vulns []map[string]string
+	for _, id := range ids {
+		vulns = append(vulns, map[string]string{"VulnerabilityID": id, "PkgName": "openssl", "Severity": "HIGH"})
+	}
+	data, err := json.Marshal(map[string]any{"Results": []map[string]any{{"Target": "alpine", "Vulnerabilities": vulns}}})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), data, 0o600))
+	return dir
+}
+
+func TestRemediateCompare(t *testing.T) {
+	run := &remediation{Report: types.Remediation{
+		Image:            "alpine:3.18",
+		ReportPath:       writeReport(t, "CVE-1", "CVE-2", "CVE-3"),
+		RescanReportPath: writeReport(t, "CVE-2"),
+	}}
+	status, _, err := remediateCompare(run)
+	require.NoError(t, err)
+	assert.Equal(t, types.StageStatusCompleted, status)
+	assert.Equal(t, []string{"CVE-1", "CVE-3"}, run.Report.Fixed)
+	assert.Equal(t, []string{"CVE-2"}, run.Report.Remaining)
+	assert.False(t, run.Report.Verified, "fixable vulnerabilities remain")
+	assert.Equal(t, 1, run.Report.After.High)
+
+	run.Report.RescanReportPath = writeReport(t, "CVE-4")
+	_, _, err = remediateCompare(run)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 vulnerabilities the image did not have: CVE-4")
+	assert.Equal(t, []string{"CVE-4"}, run.Report.Introduced)
+
+	run.Report.RescanReportPath = writeReport(t, "CVE-1", "CVE-2", "CVE-3")
+	_, _, err = remediateCompare(run)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fixed none of the 3 fixable vulnerabilities")
+}
@@ -0,0 +1,113 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+)
+
+// defaultTopPackages is the number of packages 'summarize-report' returns when the call sets none
+const defaultTopPackages = 10
+
+// SummarizeReport summarizes the vulnerabilities of a scan report by severity, fixability, package
+// and platform, so that agents can size up an image without paging through its vulnerabilities
+func (t *tools) SummarizeReport(ctx context.Context, req *mcp.CallToolRequest, params types.SummarizeReportParams) (*mcp.CallToolResult, any, error) {
+	if err := validate.SummarizeReport(params).Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	reports, err := trivy.ReportPlatformFindings(params.ReportPath)
+	if err != nil {
+		return errorResult(copaerrors.NewValidationError("failed to read the scan report", err,
+			"pass the report directory returned by 'scan-container'")), nil, nil
+	}
+
+	summary := summarizeReport(reports, cmp.Or(params.TopPackages, defaultTopPackages))
+	summary.ReportPath = params.ReportPath
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: reportSummaryMessage(summary)}},
+		StructuredContent: summary,
+	}, nil, nil
+}
+
+// summarizeReport summarizes the findings of each platform of a report, and of the report as a
+// whole, counting a vulnerability of a package reported for several platforms once
+func summarizeReport(reports []trivy.PlatformFindings, topPackages int) types.ReportSummary {
+	summary := types.ReportSummary{TopPackages: []types.PackageSummary{}, Platforms: []types.PlatformSummary{}}
+	var findings []trivy.Finding
+	for _, r := range reports {
+		platform := types.PlatformSummary{Platform: r.Platform}
+		for _, vuln := range filterVulnerabilities(r.Findings, nil, false, "") {
+			platform.Total++
+			countSeverity(&platform.Counts, vuln.Severity)
+			if vuln.FixedVersion != "" {
+				platform.Fixable++
+			} else {
+				platform.Unfixable++
+			}
+		}
+		summary.Platforms = append(summary.Platforms, platform)
+		findings = append(findings, r.Findings...)
+	}
+
+	packages := map[string]*types.PackageSummary{}
+	for _, vuln := range filterVulnerabilities(findings, nil, false, "") {
+		summary.Total++
+		countSeverity(&summary.Counts, vuln.Severity)
+		pkg := packages[vuln.Package]
+		if pkg == nil {
+			pkg = &types.PackageSummary{Package: vuln.Package}
+			packages[vuln.Package] = pkg
+		}
+		pkg.Vulnerabilities++
+		countSeverity(&pkg.Counts, vuln.Severity)
+		if vuln.FixedVersion != "" {
+			summary.Fixable++
+			pkg.Fixable++
+		} else {
+			summary.Unfixable++
+		}
+	}
+
+	for _, pkg := range packages {
+		summary.TopPackages = append(summary.TopPackages, *pkg)
+	}
+	// Most vulnerabilities first, then the most severe ones, then by name so that ties are stable
+	slices.SortFunc(summary.TopPackages, func(a, b types.PackageSummary) int {
+		return cmp.Or(cmp.Compare(b.Vulnerabilities, a.Vulnerabilities),
+			cmp.Compare(b.Counts.Critical, a.Counts.Critical),
+			cmp.Compare(b.Counts.High, a.Counts.High),
+			cmp.Compare(b.Counts.Medium, a.Counts.Medium),
+			cmp.Compare(a.Package, b.Package))
+	})
+	if len(summary.TopPackages) > topPackages {
+		summary.TopPackages = summary.TopPackages[:topPackages]
+	}
+	return summary
+}
+
+// reportSummaryMessage describes the summary of a report
+func reportSummaryMessage(s types.ReportSummary) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("%d vulnerabilities in %s (%s): %d fixable, %d unfixable", s.Total, s.ReportPath, formatSeverityCounts(s.Counts), s.Fixable, s.Unfixable))
+	if len(s.TopPackages) > 0 {
+		msg.WriteString("\nMost vulnerable packages:")
+		for _, pkg := range s.TopPackages {
+			msg.WriteString(fmt.Sprintf("\n %s: %d (%s), %d fixable", pkg.Package, pkg.Vulnerabilities, formatSeverityCounts(pkg.Counts), pkg.Fixable))
+		}
+	}
+	if len(s.Platforms) > 1 {
+		msg.WriteString("\nPlatforms:")
+		for _, p := range s.Platforms {
+			msg.WriteString(fmt.Sprintf("\n %s: %d (%s), %d fixable", p.Platform, p.Total, formatSeverityCounts(p.Counts), p.Fixable))
+		}
+	}
+	return msg.String()
+}
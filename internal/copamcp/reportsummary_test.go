@@ -0,0 +1,43 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeReport(t *testing.T) {
+	tl := &tools{}
+	res, _, err := tl.SummarizeReport(context.Background(), nil, types.SummarizeReportParams{ReportPath: writeVulnerabilitiesReport(t), TopPackages: 2})
+	require.NoError(t, err)
+	require.False(t, res.IsError, res.Content)
+	summary, ok := res.StructuredContent.(types.ReportSummary)
+	require.True(t, ok)
+
+	// The findings of both platforms count once in the totals
+	assert.Equal(t, 4, summary.Total)
+	assert.Equal(t, types.SeverityCounts{Critical: 1, High: 1, Medium: 1, Low: 1}, summary.Counts)
+	assert.Equal(t, 3, summary.Fixable)
+	assert.Equal(t, 1, summary.Unfixable)
+
+	require.Len(t, summary.TopPackages, 2)
+	assert.Equal(t, "openssl", summary.TopPackages[0].Package)
+	assert.Equal(t, "busybox", summary.TopPackages[1].Package)
+	assert.Equal(t, 0, summary.TopPackages[1].Fixable)
+
+	require.Len(t, summary.Platforms, 2)
+	assert.Equal(t, "linux/amd64", summary.Platforms[0].Platform)
+	assert.Equal(t, "linux/arm64", summary.Platforms[1].Platform)
+	assert.Equal(t, 4, summary.Platforms[1].Total)
+	assert.Equal(t, 1, summary.Platforms[1].Unfixable)
+}
+
+func TestSummarizeReport_MissingReport(t *testing.T) {
+	tl := &tools{}
+	res, _, err := tl.SummarizeReport(context.Background(), nil, types.SummarizeReportParams{ReportPath: "/nonexistent/reports"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
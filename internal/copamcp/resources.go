@@ -0,0 +1,229 @@
+package copamcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+const (
+	vexURIPrefix    = "copa://vex/"
+	vexMIMEType     = "application/json"
+	reportURIPrefix = "copa://reports/"
+	reportMIMEType  = "application/json"
+	sbomURIPrefix   = "copa://sboms/"
+)
+
+// maxResources caps the artifacts registered as resources, removing the oldest first, so that a
+// long-running server does not list every report it ever produced
+const maxResources = 200
+
+// sbomMIMETypes maps each SBOM format to the media type of its JSON documents
+var sbomMIMETypes = map[string]string{
+	trivy.SBOMFormatCycloneDX: "application/vnd.cyclonedx+json",
+	trivy.SBOMFormatSPDX:      "application/spdx+json",
+}
+
+// artifactID derives a stable resource ID from an artifact path: the temporary directory name
+// (e.g. "vex-123456"), unique already, or the file name without extension followed by a short hash
+// of its absolute path (e.g. "patched-1a2b3c4d5e6f"), so that same-named files of different
+// directories do not share a resource
+func artifactID(path string) string {
+	dir := filepath.Base(filepath.Dir(path))
+	if strings.HasPrefix(dir, "vex-") || strings.HasPrefix(dir, "reports-") {
+		return dir
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + "-" + hex.EncodeToString(sum[:6])
+}
+
+// resourceRegistry tracks the artifact resources a server registered, oldest first
+type resourceRegistry struct {
+	mu    sync.Mutex
+	paths map[string]string // Artifact path of each URI
+	order []string
+}
+
+// add records the resource uri of the artifact at path, and returns the URIs of the resources to
+// remove: those whose artifact was removed, e.g. by the retention of the reports and VEX documents,
+// and the oldest beyond limit
+func (r *resourceRegistry) add(uri, path string, limit int) []string {
+	if r.paths == nil {
+		r.paths = make(map[string]string)
+	}
+	r.order = slices.DeleteFunc(r.order, func(u string) bool { return u == uri })
+	r.paths[uri] = path
+
+	var removed []string
+	kept := r.order[:0]
+	for _, u := range r.order {
+		if _, err := os.Stat(r.paths[u]); os.IsNotExist(err) {
+			removed = append(removed, u)
+			delete(r.paths, u)
+		} else {
+			kept = append(kept, u)
+		}
+	}
+	r.order = append(kept, uri)
+	for len(r.order) > limit {
+		removed = append(removed, r.order[0])
+		delete(r.paths, r.order[0])
+		r.order = r.order[1:]
+	}
+	return removed
+}
+
+// addResource registers the resource of the artifact at path with the server, removing the
+// resources add drops
+func (t *tools) addResource(resource *mcp.Resource, path string, handler mcp.ResourceHandler) {
+	t.resources.mu.Lock()
+	defer t.resources.mu.Unlock()
+	t.server.AddResource(resource, handler)
+	if removed := t.resources.add(resource.URI, path, maxResources); len(removed) > 0 {
+		t.server.RemoveResources(removed...)
+	}
+}
+
+// fileResourceHandler serves the file at path as the contents of the resource
+func fileResourceHandler(path, mimeType string) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, mcp.ResourceNotFoundError(req.Params.URI)
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{
+				URI:      req.Params.URI,
+				MIMEType: mimeType,
+				Text:     string(data),
+			}},
+		}, nil
+	}
+}
+
+// reportResourceHandler serves the JSON reports of the report directory dir, one content per
+// file. The contents of a multi-platform report are named after the resource and their platform,
+// e.g. copa://reports/reports-123456/linux-arm64.
+func reportResourceHandler(dir string) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err == nil && len(files) == 0 {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+
+		result := &mcp.ReadResourceResult{}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			uri := req.Params.URI
+			if len(files) > 1 {
+				uri += "/" + strings.TrimSuffix(filepath.Base(file), ".json")
+			}
+			result.Contents = append(result.Contents, &mcp.ResourceContents{URI: uri, MIMEType: reportMIMEType, Text: string(data)})
+		}
+		return result, nil
+	}
+}
+
+// addReportResource registers the scan report directory dir as an MCP resource, for clients that
+// cannot read the server's filesystem, and returns a link to it
+func (t *tools) addReportResource(image, dir string) *mcp.ResourceLink {
+	uri := reportURIPrefix + filepath.Base(dir)
+	t.addResource(&mcp.Resource{
+		URI:         uri,
+		Name:        filepath.Base(dir),
+		Description: fmt.Sprintf("Trivy vulnerability report of %s (%s)", image, dir),
+		MIMEType:    reportMIMEType,
+	}, dir, reportResourceHandler(dir))
+
+	return &mcp.ResourceLink{
+		URI:      uri,
+		Name:     filepath.Base(dir),
+		MIMEType: reportMIMEType,
+	}
+}
+
+// addSBOMResource registers the SBOM of image in format at path as an MCP resource, named after
+// its directory, and returns a link to it
+func (t *tools) addSBOMResource(image, format, path string, size int64) *mcp.ResourceLink {
+	uri := sbomURIPrefix + filepath.Base(filepath.Dir(path))
+	mimeType := sbomMIMETypes[format]
+	t.addResource(&mcp.Resource{
+		URI:         uri,
+		Name:        filepath.Base(path),
+		Description: fmt.Sprintf("%s SBOM of %s (%s)", format, image, path),
+		MIMEType:    mimeType,
+		Size:        size,
+	}, path, fileResourceHandler(path, mimeType))
+
+	return &mcp.ResourceLink{
+		URI:      uri,
+		Name:     filepath.Base(path),
+		MIMEType: mimeType,
+		Size:     &size,
+	}
+}
+
+// addVexResource registers the VEX document at path as an MCP resource and returns
+// content that embeds the document and links to the resource
+func (t *tools) addVexResource(path string) ([]mcp.Content, error) {
+	embedded, err := embedVex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := embedded.Resource.URI
+	size := int64(len(embedded.Resource.Text))
+	t.addResource(&mcp.Resource{
+		URI:         uri,
+		Name:        filepath.Base(path),
+		Description: fmt.Sprintf("VEX document generated by copa (%s)", path),
+		MIMEType:    vexMIMEType,
+		Size:        size,
+	}, path, fileResourceHandler(path, vexMIMEType))
+
+	return []mcp.Content{
+		&mcp.ResourceLink{
+			URI:      uri,
+			Name:     filepath.Base(path),
+			MIMEType: vexMIMEType,
+			Size:     &size,
+		},
+		embedded,
+	}, nil
+}
+
+// embedVex returns the VEX document at path as embedded content, for documents
+// that are not kept on disk and so cannot be served as a resource
+func embedVex(path string) (*mcp.EmbeddedResource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vex document: %w", err)
+	}
+
+	return &mcp.EmbeddedResource{
+		Resource: &mcp.ResourceContents{
+			URI:      vexURIPrefix + artifactID(path),
+			MIMEType: vexMIMEType,
+			Text:     string(data),
+		},
+	}, nil
+}
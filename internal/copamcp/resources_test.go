@@ -0,0 +1,83 @@
+package copamcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactID(t *testing.T) {
+	assert.Equal(t, "vex-123456", artifactID("/tmp/vex-123456/vex.json"))
+	assert.Regexp(t, `^patched-[0-9a-f]{12}$`, artifactID("/home/user/out/patched.json"))
+	assert.Equal(t, artifactID("/home/user/out/patched.json"), artifactID("/home/user/out/../out/patched.json"), "the ID is stable")
+
+	// Same-named files of different directories get different IDs
+	assert.NotEqual(t, artifactID("/a/vex.json"), artifactID("/b/vex.json"))
+}
+
+func TestResourceRegistry(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, []byte(`{}`), 0o600))
+		return p
+	}
+	var r resourceRegistry
+
+	assert.Empty(t, r.add("copa://vex/a", path("a.json"), 2))
+	assert.Empty(t, r.add("copa://vex/b", path("b.json"), 2))
+	assert.Empty(t, r.add("copa://vex/a", path("a.json"), 2), "re-adding a resource makes it the newest")
+	assert.Equal(t, []string{"copa://vex/b"}, r.add("copa://vex/c", path("c.json"), 2), "the oldest is removed beyond the limit")
+
+	// The resources of removed artifacts are removed
+	require.NoError(t, os.Remove(filepath.Join(dir, "a.json")))
+	assert.Equal(t, []string{"copa://vex/a"}, r.add("copa://vex/d", path("d.json"), 2))
+	assert.Equal(t, []string{"copa://vex/c", "copa://vex/d"}, r.order)
+}
+
+func TestFileResourceHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vex.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"statements":[]}`), 0o600))
+
+	handler := fileResourceHandler(path, vexMIMEType)
+	res, err := handler(context.Background(), &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "copa://vex/test"}})
+
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Equal(t, "copa://vex/test", res.Contents[0].URI)
+	assert.Equal(t, `{"statements":[]}`, res.Contents[0].Text)
+
+	require.NoError(t, os.Remove(path))
+	_, err = handler(context.Background(), &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "copa://vex/test"}})
+	assert.Error(t, err)
+}
+
+func TestReportResourceHandler(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(`{"Results":[]}`), 0o600))
+	req := &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "copa://reports/test"}}
+
+	res, err := reportResourceHandler(dir)(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Equal(t, "copa://reports/test", res.Contents[0].URI)
+	assert.Equal(t, `{"Results":[]}`, res.Contents[0].Text)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "report.json")))
+	for _, platform := range []string{"linux-amd64", "linux-arm64"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, platform+".json"), []byte(`{}`), 0o600))
+	}
+	res, err = reportResourceHandler(dir)(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 2)
+	assert.Equal(t, "copa://reports/test/linux-amd64", res.Contents[0].URI)
+	assert.Equal(t, "copa://reports/test/linux-arm64", res.Contents[1].URI)
+
+	_, err = reportResourceHandler(filepath.Join(dir, "removed"))(context.Background(), req)
+	assert.Error(t, err)
+}
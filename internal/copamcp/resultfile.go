@@ -0,0 +1,53 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// withResultFile wraps h so that the structured content of its result, or of its error,
+// is also written as JSON to the resultPath parameter, when one is set. The path must be in the
+// server's working directory, or is relative to it.
+func withResultFile[In any](h mcp.ToolHandlerFor[In, any], resultPath func(In) string) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		path := resultPath(params)
+		if path == "" {
+			return h(ctx, req, params)
+		}
+
+		// Checked up front so that a long-running operation does not fail only at the end
+		path, err := workdir.OutputPath(path)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("result directory does not exist: %s", filepath.Dir(path)), nil)), nil, nil
+		}
+
+		res, out, err := h(ctx, req, params)
+		if err != nil || res == nil || res.StructuredContent == nil {
+			return res, out, err
+		}
+
+		if err := writeResultFile(path, res.StructuredContent); err != nil {
+			return errorResult(copaerrors.NewSystemError("failed to write result file", err)), nil, nil
+		}
+		return res, out, nil
+	}
+}
+
+// writeResultFile writes v to path as indented JSON
+func writeResultFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,93 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type resultFileParams struct {
+	ResultPath string
+	Fail       bool
+}
+
+func resultFileHandler(ctx context.Context, req *mcp.CallToolRequest, params resultFileParams) (*mcp.CallToolResult, any, error) {
+	if params.Fail {
+		return errorResult(copaerrors.NewExecutionError("copa failed", nil)), nil, nil
+	}
+	return &mcp.CallToolResult{StructuredContent: types.PatchResult{OriginalImage: "alpine:3.17"}}, nil, nil
+}
+
+func TestWithResultFile(t *testing.T) {
+	root := t.TempDir()
+	workdir.Configure(root, 0)
+	t.Cleanup(func() { workdir.Configure("", 0) })
+	h := withResultFile(resultFileHandler, func(p resultFileParams) string { return p.ResultPath })
+
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(root, "result.json")
+		res, _, err := h(context.Background(), nil, resultFileParams{ResultPath: path})
+		require.NoError(t, err)
+		assert.False(t, res.IsError)
+
+		var written types.PatchResult
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &written))
+		assert.Equal(t, "alpine:3.17", written.OriginalImage)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		path := filepath.Join(root, "error.json")
+		res, _, err := h(context.Background(), nil, resultFileParams{ResultPath: path, Fail: true})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+
+		var written types.ToolError
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &written))
+		assert.Equal(t, "execution", written.Category)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		res, _, err := h(context.Background(), nil, resultFileParams{ResultPath: filepath.Join(root, "missing", "result.json")})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+		assert.Equal(t, "validation", res.StructuredContent.(types.ToolError).Category)
+	})
+
+	t.Run("relative path", func(t *testing.T) {
+		res, _, err := h(context.Background(), nil, resultFileParams{ResultPath: "relative.json"})
+		require.NoError(t, err)
+		assert.False(t, res.IsError)
+		assert.FileExists(t, filepath.Join(root, "relative.json"))
+	})
+
+	t.Run("outside the working directory", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "result.json")
+		for _, path := range []string{"../result.json", outside} {
+			res, _, err := h(context.Background(), nil, resultFileParams{ResultPath: path})
+			require.NoError(t, err)
+			assert.True(t, res.IsError, path)
+			assert.Equal(t, "validation", res.StructuredContent.(types.ToolError).Category)
+		}
+		assert.NoFileExists(t, outside)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "result.json"))
+	})
+
+	t.Run("no result path", func(t *testing.T) {
+		res, _, err := h(context.Background(), nil, resultFileParams{})
+		require.NoError(t, err)
+		assert.False(t, res.IsError)
+	})
+}
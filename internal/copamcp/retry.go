@@ -0,0 +1,79 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// retryPolicy applies a call's retry overrides to the server's policy
+func retryPolicy(base copaerrors.RetryPolicy, override *types.RetryParams) (copaerrors.RetryPolicy, error) {
+	if override == nil {
+		return base, nil
+	}
+
+	policy := base
+	if override.MaxAttempts != nil {
+		policy.MaxAttempts = *override.MaxAttempts
+	}
+	if override.Backoff != "" {
+		backoff, err := time.ParseDuration(override.Backoff)
+		if err != nil {
+			return policy, copaerrors.NewValidationError(fmt.Sprintf("invalid retry backoff %q", override.Backoff), err, "use a Go duration such as 10s or 1m")
+		}
+		policy.Backoff = backoff
+	}
+	if override.Categories != nil {
+		categories, err := copaerrors.ParseCategories(strings.Join(override.Categories, ","))
+		if err != nil {
+			return policy, copaerrors.NewValidationError("invalid retry categories", err)
+		}
+		policy.Retryable = categories
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, copaerrors.NewValidationError("invalid retry policy", err)
+	}
+	return policy, nil
+}
+
+// validateRetry checks a call's retry overrides before anything runs
+func (t *tools) validateRetry(override *types.RetryParams) error {
+	_, err := t.callRetryPolicy(override)
+	return err
+}
+
+// callRetryPolicy returns the server's retry policy with the call's overrides, for the tools that
+// share one policy between their retried steps and the steps retrying on their own, e.g. the push
+// of a patch
+func (t *tools) callRetryPolicy(override *types.RetryParams) (copaerrors.RetryPolicy, error) {
+	return retryPolicy(t.cfg.Retry, override)
+}
+
+// retry runs fn under the server's retry policy with the call's overrides, logging each retry to the client
+func (t *tools) retry(ctx context.Context, req *mcp.CallToolRequest, override *types.RetryParams, fn func() error) error {
+	policy, err := t.callRetryPolicy(override)
+	if err != nil {
+		return err
+	}
+	return t.retryWith(ctx, req, policy, fn)
+}
+
+// retryWith runs fn under policy, logging each retry to the client
+func (t *tools) retryWith(ctx context.Context, req *mcp.CallToolRequest, policy copaerrors.RetryPolicy, fn func() error) error {
+	return copaerrors.Retry(ctx, policy, fn, func(attempt int, delay time.Duration, err error) {
+		if req == nil || req.Session == nil {
+			return
+		}
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
+			Data:   fmt.Sprintf("attempt %d of %d failed (%s), retrying in %s: %v", attempt, policy.MaxAttempts, copaerrors.CategoryOf(err), delay, err),
+			Level:  "warning",
+			Logger: "copacetic-mcp",
+		})
+	})
+}
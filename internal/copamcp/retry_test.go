@@ -0,0 +1,51 @@
+package copamcp
+
+import (
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	base := copaerrors.RetryPolicy{MaxAttempts: 3, Backoff: 5 * time.Second, Retryable: []copaerrors.Category{copaerrors.CategoryNetwork}}
+	one := 1
+
+	tests := []struct {
+		name     string
+		override *types.RetryParams
+		want     copaerrors.RetryPolicy
+		wantErr  bool
+	}{
+		{name: "no override", want: base},
+		{
+			name:     "fail fast",
+			override: &types.RetryParams{MaxAttempts: &one},
+			want:     copaerrors.RetryPolicy{MaxAttempts: 1, Backoff: 5 * time.Second, Retryable: base.Retryable},
+		},
+		{
+			name:     "backoff and categories",
+			override: &types.RetryParams{Backoff: "30s", Categories: []string{"network", "execution"}},
+			want: copaerrors.RetryPolicy{MaxAttempts: 3, Backoff: 30 * time.Second,
+				Retryable: []copaerrors.Category{copaerrors.CategoryNetwork, copaerrors.CategoryExecution}},
+		},
+		{name: "invalid backoff", override: &types.RetryParams{Backoff: "later"}, wantErr: true},
+		{name: "invalid category", override: &types.RetryParams{Categories: []string{"flaky"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := retryPolicy(base, tt.override)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
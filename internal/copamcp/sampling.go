@@ -0,0 +1,96 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+)
+
+const (
+	// maxFixedInPrompt caps the fixed vulnerabilities described to the model drafting a pull request description
+	maxFixedInPrompt = 100
+	// descriptionMaxTokens bounds the length of a drafted pull request description
+	descriptionMaxTokens = 1024
+)
+
+// descriptionSystemPrompt instructs the client's model how to write a pull request description
+const descriptionSystemPrompt = "You write pull request descriptions for infrastructure repositories. " +
+	"Given the facts of a container image patch, write a concise Markdown description for a pull request " +
+	"that updates deployments to the patched image: a one-line summary, the new image reference, the " +
+	"vulnerabilities fixed (by severity, with IDs) and the packages updated. Use only the facts given; " +
+	"do not invent vulnerabilities, versions or references. Reply with the description only."
+
+// draftDescription asks the client's model, through MCP sampling, to draft a pull request description
+// of a patch. The image is already patched, so a failure only adds a warning to the result.
+func draftDescription(ctx context.Context, req *mcp.CallToolRequest, image string, result *copa.ExecutionResult) string {
+	if req == nil || req.Session == nil || req.Session.InitializeParams() == nil ||
+		req.Session.InitializeParams().Capabilities == nil || req.Session.InitializeParams().Capabilities.Sampling == nil {
+		result.Warnings = append(result.Warnings, "no pull request description was drafted: the MCP client does not support sampling")
+		return ""
+	}
+
+	res, err := req.Session.CreateMessage(ctx, &mcp.CreateMessageParams{
+		SystemPrompt: descriptionSystemPrompt,
+		Messages:     []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: patchFacts(image, result)}}},
+		MaxTokens:    descriptionMaxTokens,
+	})
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("drafting the pull request description failed: %v", err))
+		return ""
+	}
+	text, ok := res.Content.(*mcp.TextContent)
+	if !ok || strings.TrimSpace(text.Text) == "" {
+		result.Warnings = append(result.Warnings, "drafting the pull request description failed: the model returned no text")
+		return ""
+	}
+	return strings.TrimSpace(text.Text)
+}
+
+// patchFacts describes a patch to the model drafting its pull request description
+func patchFacts(image string, result *copa.ExecutionResult) string {
+	var facts strings.Builder
+	facts.WriteString(fmt.Sprintf("Original image: %s\n", image))
+	facts.WriteString(fmt.Sprintf("Patched image: %s\n", result.PatchedImage))
+	if result.ExportPath != "" {
+		facts.WriteString(fmt.Sprintf("Exported to: %s\n", result.ExportPath))
+	}
+	for _, p := range result.Platforms {
+		facts.WriteString(fmt.Sprintf("Platform %s: %s\n", p.Platform, p.Status))
+	}
+	facts.WriteString(fmt.Sprintf("Packages updated: %d\n", result.UpdatedPackageCount))
+	facts.WriteString(fmt.Sprintf("Vulnerabilities fixed: %d\n", result.FixedVulnerabilityCount))
+	if result.Severity != nil {
+		facts.WriteString(fmt.Sprintf("Fixed by severity: %s\n", formatSeverityCounts(result.Severity.Fixed)))
+		facts.WriteString(fmt.Sprintf("Remaining by severity: %s\n", formatSeverityCounts(result.Severity.Remaining)))
+	}
+
+	fixed, truncated := capFixed(result.FixedVulnerabilities, maxFixedInPrompt)
+	for _, vuln := range fixed {
+		facts.WriteString("- " + vuln.ID)
+		if vuln.Severity != "" {
+			facts.WriteString(" (" + vuln.Severity + ")")
+		}
+		if len(vuln.Packages) > 0 {
+			facts.WriteString(": " + strings.Join(vuln.Packages, ", "))
+		}
+		facts.WriteString("\n")
+	}
+	if truncated {
+		facts.WriteString(fmt.Sprintf("- and %d more\n", len(result.FixedVulnerabilities)-maxFixedInPrompt))
+	}
+	if result.VexPath == "" {
+		facts.WriteString("No vulnerability report was used: the patch updated every OS package with an available update.\n")
+	}
+	return facts.String()
+}
+
+// descriptionMessage appends a drafted pull request description to a patch result's text
+func descriptionMessage(description string) string {
+	if description == "" {
+		return ""
+	}
+	return "\n\n=== PULL REQUEST DESCRIPTION ===\n" + description
+}
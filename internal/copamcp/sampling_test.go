@@ -0,0 +1,69 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// draftWith calls draftDescription from a tool handler of a client answering sampling requests
+// with reply, or of a client without sampling support when reply is empty
+func draftWith(t *testing.T, reply string, result *copa.ExecutionResult) (string, string) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	var description string
+	mcp.AddTool(server, &mcp.Tool{Name: "draft"}, func(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+		description = draftDescription(ctx, req, "nginx:1.25", result)
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	var prompt string
+	var opts *mcp.ClientOptions
+	if reply != "" {
+		opts = &mcp.ClientOptions{CreateMessageHandler: func(_ context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			prompt = req.Params.Messages[0].Content.(*mcp.TextContent).Text
+			return &mcp.CreateMessageResult{Role: "assistant", Model: "test", Content: &mcp.TextContent{Text: reply}}, nil
+		}}
+	}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, opts).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "draft", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	return description, prompt
+}
+
+func TestDraftDescription(t *testing.T) {
+	result := &copa.ExecutionResult{
+		PatchedImage:            "nginx:1.25-patched",
+		VexPath:                 "/tmp/vex.json",
+		UpdatedPackageCount:     2,
+		FixedVulnerabilityCount: 1,
+		FixedVulnerabilities:    []types.FixedVulnerability{{ID: "CVE-2023-0464", Severity: "HIGH", Packages: []string{"pkg:deb/debian/openssl@3.0.9"}}},
+	}
+
+	description, prompt := draftWith(t, " ## Patch nginx:1.25\n", result)
+	assert.Equal(t, "## Patch nginx:1.25", description)
+	assert.Contains(t, prompt, "Patched image: nginx:1.25-patched")
+	assert.Contains(t, prompt, "- CVE-2023-0464 (HIGH): pkg:deb/debian/openssl@3.0.9")
+	assert.Empty(t, result.Warnings)
+
+	description, _ = draftWith(t, "", result)
+	assert.Empty(t, description)
+	assert.Len(t, result.Warnings, 1)
+}
+
+func TestDescriptionMessage(t *testing.T) {
+	assert.Empty(t, descriptionMessage(""))
+	assert.Equal(t, "\n\n=== PULL REQUEST DESCRIPTION ===\n## Patch", descriptionMessage("## Patch"))
+}
@@ -0,0 +1,201 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/sbom"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// maxChangesInText caps the package changes listed in the text of a 'diff-sbom' result
+const maxChangesInText = 25
+
+// DiffSBOM generates CycloneDX SBOMs of an image and its patched image and diffs their packages,
+// as evidence of what a patch changed beyond the fixed vulnerabilities
+func (t *tools) DiffSBOM(ctx context.Context, req *mcp.CallToolRequest, params types.SBOMDiffParams) (*mcp.CallToolResult, any, error) {
+	if params.Image == "" {
+		return errorResult(copaerrors.NewValidationError("image parameter is required", nil)), nil, nil
+	}
+	if params.SBOMDir != "" {
+		if info, err := os.Stat(params.SBOMDir); err != nil || !info.IsDir() {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("sbom directory does not exist: %s", params.SBOMDir), nil)), nil, nil
+		}
+	}
+	patchedImage := params.PatchedImage
+	if patchedImage == "" {
+		patchedImage = copa.PatchedImageRef(params.Image, params.Tag)
+	}
+
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+	// The patched image is built locally from the original, so only the original is verified
+	if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	result := types.SBOMDiff{Image: params.Image, PatchedImage: patchedImage}
+	before, path, err := t.imagePackages(ctx, req, params, params.Image)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	result.ImageSBOM = path
+	after, path, err := t.imagePackages(ctx, req, params, patchedImage)
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+	result.PatchedSBOM = path
+
+	diff := sbom.Compare(before, after)
+	result.Unchanged = diff.Unchanged
+	result.Upgraded = make([]types.PackageChange, 0, len(diff.Changed))
+	for _, c := range diff.Changed {
+		result.Upgraded = append(result.Upgraded, types.PackageChange{Name: c.Name, Type: c.Type, From: c.From, To: c.Version})
+	}
+	result.Added = packageVersions(diff.Added)
+	result.Removed = packageVersions(diff.Removed)
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: sbomDiffMessage(result)}},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// sbomExtensions are the file extensions of the SBOMs written by 'generate-sbom'
+var sbomExtensions = map[string]string{
+	trivy.SBOMFormatCycloneDX: ".cdx.json",
+	trivy.SBOMFormatSPDX:      ".spdx.json",
+}
+
+// GenerateSBOM writes an SBOM of an image with Trivy into the server's temporary directory and
+// serves it as an MCP resource, as evidence of the image's contents for compliance workflows
+func (t *tools) GenerateSBOM(ctx context.Context, req *mcp.CallToolRequest, params types.SBOMParams) (*mcp.CallToolResult, any, error) {
+	if err := validate.SBOM(params).Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	params.TimeoutSeconds = t.timeoutSeconds(params.TimeoutSeconds)
+	format := cmp.Or(params.Format, trivy.SBOMFormatCycloneDX)
+
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+	if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	dir, err := workdir.MkdirTemp("sbom-*")
+	if err != nil {
+		return errorResult(copaerrors.NewSystemError("failed to create a directory for the SBOM", err)), nil, nil
+	}
+	path := filepath.Join(dir, sbomFileName(params.Image)+sbomExtensions[format])
+	err = t.retry(ctx, req, params.Retry, func() error {
+		return trivy.GenerateSBOM(ctx, params.DockerHost, params.Image, format, path, time.Duration(params.TimeoutSeconds)*time.Second)
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return errorResult(fmt.Errorf("generating the SBOM of %s failed: %w", params.Image, err)), nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return errorResult(copaerrors.NewExecutionError(fmt.Sprintf("trivy did not write the SBOM of %s", params.Image), err)), nil, nil
+	}
+
+	link := t.addSBOMResource(params.Image, format, path, info.Size())
+	result := types.SBOM{Image: params.Image, Format: format, SBOMPath: path, SBOMURI: link.URI, Size: info.Size()}
+	msg := fmt.Sprintf("Generated the %s SBOM of %s (%d bytes)\nSBOM: %s\nSBOM resource: %s\n", format, params.Image, result.Size, path, link.URI)
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: msg}, link},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// sbomFileName derives a file name from image, e.g. ghcr.io_org_app_1.0 for ghcr.io/org/app:1.0
+func sbomFileName(image string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)
+}
+
+// imagePackages generates the SBOM of image and returns its packages, and the SBOM's path when it
+// is kept in params.SBOMDir
+func (t *tools) imagePackages(ctx context.Context, req *mcp.CallToolRequest, params types.SBOMDiffParams, image string) ([]sbom.Package, string, error) {
+	var bom []byte
+	err := t.retry(ctx, req, params.Retry, func() (err error) {
+		bom, err = trivy.SBOM(ctx, params.DockerHost, image)
+		return err
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("generating the SBOM of %s failed: %w", image, err)
+	}
+	packages, err := sbom.Parse(bom)
+	if err != nil {
+		return nil, "", copaerrors.NewExecutionError(fmt.Sprintf("failed to parse the SBOM of %s", image), err)
+	}
+
+	var path string
+	if params.SBOMDir != "" {
+		path = filepath.Join(params.SBOMDir, sbomFileName(image)+sbomExtensions[trivy.SBOMFormatCycloneDX])
+		if err := os.WriteFile(path, bom, 0o644); err != nil {
+			return nil, "", copaerrors.NewSystemError(fmt.Sprintf("failed to write the SBOM of %s", image), err)
+		}
+	}
+	return packages, path, nil
+}
+
+func packageVersions(packages []sbom.Package) []types.PackageVersion {
+	versions := make([]types.PackageVersion, 0, len(packages))
+	for _, p := range packages {
+		versions = append(versions, types.PackageVersion{Name: p.Name, Type: p.Type, Version: p.Version})
+	}
+	return versions
+}
+
+func sbomDiffMessage(result types.SBOMDiff) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("SBOM diff of %s -> %s\n", result.Image, result.PatchedImage))
+	msg.WriteString(fmt.Sprintf("Upgraded: %d, added: %d, removed: %d, unchanged: %d\n",
+		len(result.Upgraded), len(result.Added), len(result.Removed), result.Unchanged))
+
+	listed := 0
+	for _, c := range result.Upgraded {
+		if listed++; listed > maxChangesInText {
+			break
+		}
+		msg.WriteString(fmt.Sprintf("\n ~ %s %s -> %s", c.Name, c.From, c.To))
+	}
+	for _, p := range result.Added {
+		if listed++; listed > maxChangesInText {
+			break
+		}
+		msg.WriteString(fmt.Sprintf("\n + %s %s", p.Name, p.Version))
+	}
+	for _, p := range result.Removed {
+		if listed++; listed > maxChangesInText {
+			break
+		}
+		msg.WriteString(fmt.Sprintf("\n - %s %s", p.Name, p.Version))
+	}
+	if total := len(result.Upgraded) + len(result.Added) + len(result.Removed); total > maxChangesInText {
+		msg.WriteString(fmt.Sprintf("\n ... and %d more changes in the structured result", total-maxChangesInText))
+	}
+
+	if result.ImageSBOM != "" {
+		msg.WriteString(fmt.Sprintf("\n\nSBOMs: %s, %s", result.ImageSBOM, result.PatchedSBOM))
+	}
+	if len(result.Upgraded)+len(result.Added)+len(result.Removed) == 0 {
+		msg.WriteString("\n\nThe images have the same packages; check that the patched image reference is correct and that the patch fixed anything.")
+	}
+	return msg.String()
+}
@@ -0,0 +1,41 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSBOM_Validation(t *testing.T) {
+	tt := &tools{cfg: config.Default()}
+	for _, params := range []types.SBOMDiffParams{
+		{},
+		{Image: "alpine:3.18", SBOMDir: filepath.Join(t.TempDir(), "missing")},
+	} {
+		res, _, err := tt.DiffSBOM(context.Background(), nil, params)
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+	}
+}
+
+func TestSBOMDiffMessage(t *testing.T) {
+	result := types.SBOMDiff{Image: "alpine:3.18", PatchedImage: "alpine:3.18-patched", Unchanged: 10}
+	for i := range maxChangesInText + 5 {
+		result.Upgraded = append(result.Upgraded, types.PackageChange{Name: fmt.Sprintf("pkg%d", i), From: "1.0", To: "1.1"})
+	}
+	result.Added = []types.PackageVersion{{Name: "libnew", Version: "2.0"}}
+
+	msg := sbomDiffMessage(result)
+	assert.Contains(t, msg, "Upgraded: 30, added: 1, removed: 0, unchanged: 10")
+	assert.Contains(t, msg, " ~ pkg0 1.0 -> 1.1")
+	assert.NotContains(t, msg, "libnew")
+	assert.Contains(t, msg, "and 6 more changes")
+
+	assert.Contains(t, sbomDiffMessage(types.SBOMDiff{Image: "a", PatchedImage: "b"}), "same packages")
+}
@@ -0,0 +1,162 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// scanFlight is a scan in progress, whose result is shared by the identical scans requested while it runs
+type scanFlight struct {
+	done    chan struct{}
+	waiters int
+	copies  chan sharedScan // One for each waiter, sent before done is closed
+}
+
+// sharedScan is the result of a scan for a call that waited for it
+type sharedScan struct {
+	result *trivy.ScanResult
+	err    error
+}
+
+// scanGroup coalesces identical concurrent scans, such as two sessions, or an agent's retries,
+// scanning the same image, into one trivy execution. The zero value is ready to use.
+type scanGroup struct {
+	mu      sync.Mutex
+	flights map[string]*scanFlight
+}
+
+// do runs scan, unless a scan with the same key is already running, in which case it waits for
+// that scan and returns its result, with its own copy of the report directory, and shared set. A
+// scan canceled by its own caller is not shared: the calls waiting for it scan again.
+func (g *scanGroup) do(ctx context.Context, key string, scan func() (*trivy.ScanResult, error)) (result *trivy.ScanResult, shared bool, err error) {
+	for {
+		g.mu.Lock()
+		if f, ok := g.flights[key]; ok {
+			f.waiters++
+			g.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				g.mu.Lock()
+				if g.flights[key] == f {
+					f.waiters--
+					g.mu.Unlock()
+					return nil, false, ctx.Err()
+				}
+				g.mu.Unlock()
+				// A copy of the report is already being made for this call
+				go func() {
+					<-f.done
+					if c := <-f.copies; c.result != nil {
+						os.RemoveAll(c.result.ReportPath)
+					}
+				}()
+				return nil, false, ctx.Err()
+			}
+			c := <-f.copies
+			if (errors.Is(c.err, context.Canceled) || errors.Is(c.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+				continue
+			}
+			return c.result, true, c.err
+		}
+
+		f := &scanFlight{done: make(chan struct{})}
+		if g.flights == nil {
+			g.flights = map[string]*scanFlight{}
+		}
+		g.flights[key] = f
+		g.mu.Unlock()
+
+		result, err = scan()
+
+		g.mu.Lock()
+		delete(g.flights, key)
+		waiters := f.waiters
+		g.mu.Unlock()
+
+		// The copies are made before the waiters are released, so that the report cannot be
+		// removed by a patch of this call first
+		f.copies = make(chan sharedScan, waiters)
+		for range waiters {
+			if err != nil {
+				f.copies <- sharedScan{err: err}
+				continue
+			}
+			c, copyErr := shareScan(result)
+			f.copies <- sharedScan{result: c, err: copyErr}
+		}
+		close(f.done)
+		return result, false, err
+	}
+}
+
+// scanKey identifies the scans of 'scan-container' producing the same report
+func scanKey(params trivy.ScanParams) string {
+	platforms := slices.Clone(params.Platform)
+	slices.Sort(platforms)
+	key, _ := json.Marshal([]any{params.Image, platforms, params.DockerHost, params.ReuseAttachedReport, params.GitLabReport, params.ContainerdNamespace, params.Env, params.Severity, params.DBOptions()})
+	return string(key)
+}
+
+// shareScan returns a copy of a scan result shared with another call, with its own copy of the
+// report directory, since a report-based patch may remove the directory it used
+func shareScan(result *trivy.ScanResult) (*trivy.ScanResult, error) {
+	reportDir, err := workdir.MkdirTemp("reports-*")
+	if err != nil {
+		return nil, copaerrors.NewSystemError("failed to create temporary report directory", err)
+	}
+	if err := copyReports(result.ReportPath, reportDir); err != nil {
+		os.RemoveAll(reportDir)
+		return nil, copaerrors.NewSystemError("failed to copy the report of the shared scan", err)
+	}
+
+	shared := *result
+	shared.ReportPath = reportDir
+	shared.Warnings = append(slices.Clone(result.Warnings), "the same scan was already running for another call, so its result was reused")
+	return &shared, nil
+}
+
+// copyReports copies the files of the report directory src to dst
+func copyReports(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := copyFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return fmt.Errorf("copying %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
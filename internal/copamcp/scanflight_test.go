@@ -0,0 +1,141 @@
+package copamcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForWaiters waits until n calls wait for the scan with key
+func waitForWaiters(t *testing.T, g *scanGroup, key string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		f, ok := g.flights[key]
+		return ok && f.waiters == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestScanGroup_SharesConcurrentScans(t *testing.T) {
+	var g scanGroup
+	reportDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(reportDir, "report.json"), []byte(`{"Results":[]}`), 0o600))
+
+	var scans atomic.Int32
+	release := make(chan struct{})
+	scan := func() (*trivy.ScanResult, error) {
+		scans.Add(1)
+		<-release
+		return &trivy.ScanResult{Image: "alpine:3.19", ReportPath: reportDir, VulnCount: 3}, nil
+	}
+
+	type outcome struct {
+		result *trivy.ScanResult
+		shared bool
+		err    error
+	}
+	leader, follower := make(chan outcome, 1), make(chan outcome, 1)
+	go func() {
+		result, shared, err := g.do(context.Background(), "key", scan)
+		leader <- outcome{result, shared, err}
+	}()
+	require.Eventually(t, func() bool { return scans.Load() == 1 }, time.Second, time.Millisecond)
+	go func() {
+		result, shared, err := g.do(context.Background(), "key", scan)
+		follower <- outcome{result, shared, err}
+	}()
+	waitForWaiters(t, &g, "key", 1)
+	close(release)
+
+	first, second := <-leader, <-follower
+	require.NoError(t, first.err)
+	require.NoError(t, second.err)
+	assert.EqualValues(t, 1, scans.Load(), "the image is scanned once")
+	assert.False(t, first.shared)
+	assert.True(t, second.shared)
+	assert.Equal(t, reportDir, first.result.ReportPath)
+	assert.Equal(t, 3, second.result.VulnCount)
+	assert.NotEqual(t, reportDir, second.result.ReportPath, "each call gets its own report directory")
+	t.Cleanup(func() { os.RemoveAll(second.result.ReportPath) })
+	assert.FileExists(t, filepath.Join(second.result.ReportPath, "report.json"))
+	assert.NotEmpty(t, second.result.Warnings)
+
+	// Once finished, the scan is not reused
+	_, shared, err := g.do(context.Background(), "key", func() (*trivy.ScanResult, error) {
+		return &trivy.ScanResult{ReportPath: reportDir}, nil
+	})
+	require.NoError(t, err)
+	assert.False(t, shared)
+}
+
+func TestScanGroup_SharesErrors(t *testing.T) {
+	var g scanGroup
+	release := make(chan struct{})
+	failure := errors.New("trivy command failed")
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, _, err := g.do(context.Background(), "key", func() (*trivy.ScanResult, error) {
+				<-release
+				return nil, failure
+			})
+			errs <- err
+		}()
+	}
+	waitForWaiters(t, &g, "key", 1)
+	close(release)
+
+	assert.ErrorIs(t, <-errs, failure)
+	assert.ErrorIs(t, <-errs, failure)
+}
+
+func TestScanGroup_CanceledScanIsNotShared(t *testing.T) {
+	var g scanGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(ctx, "key", func() (*trivy.ScanResult, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		leader <- err
+	}()
+	<-started
+
+	follower := make(chan bool, 1)
+	go func() {
+		result, shared, err := g.do(context.Background(), "key", func() (*trivy.ScanResult, error) {
+			return &trivy.ScanResult{Image: "alpine:3.19"}, nil
+		})
+		follower <- err == nil && !shared && result.Image == "alpine:3.19"
+	}()
+	waitForWaiters(t, &g, "key", 1)
+	cancel()
+
+	assert.ErrorIs(t, <-leader, context.Canceled)
+	assert.True(t, <-follower, "the waiting call scans itself")
+}
+
+func TestScanKey(t *testing.T) {
+	params := trivy.ScanParams{Image: "nginx:1.25", Platform: []string{"linux/arm64", "linux/amd64"}}
+	reordered := trivy.ScanParams{Image: "nginx:1.25", Platform: []string{"linux/amd64", "linux/arm64"}}
+	assert.Equal(t, scanKey(params), scanKey(reordered))
+
+	otherHost := params
+	otherHost.DockerHost = "tcp://build-host:2376"
+	assert.NotEqual(t, scanKey(params), scanKey(otherHost))
+	assert.NotEqual(t, scanKey(params), scanKey(trivy.ScanParams{Image: "nginx:1.25"}))
+}
@@ -0,0 +1,189 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/schedule"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+const (
+	scheduleURIPrefix = "copa://schedule/"
+	scheduleMIMEType  = "application/json"
+)
+
+// scheduledRun is the outcome of the latest run of a scheduled job, served as the copa://schedule/<job> resource
+type scheduledRun struct {
+	Job        string    `json:"job"`
+	Image      string    `json:"image"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	NextRun    time.Time `json:"nextRun,omitzero"`
+
+	Scan *trivy.ScanResult `json:"scan,omitempty"`
+	// PatchTriggeredBy counts the vulnerabilities at or above the job's patch threshold
+	PatchTriggeredBy int                `json:"patchTriggeredBy,omitempty"`
+	Patch            *types.PatchResult `json:"patch,omitempty"`
+	Error            *types.ToolError   `json:"error,omitempty"`
+}
+
+// scheduler runs scheduled jobs through an in-process client session, so that scheduled scans
+// and patches behave like tool calls, including retries, notifications and step summaries
+type scheduler struct {
+	session *mcp.ClientSession
+
+	mu   sync.Mutex
+	runs map[string]*scheduledRun
+}
+
+// runScheduler registers a resource for each job and runs the jobs until ctx is done
+func runScheduler(ctx context.Context, server *mcp.Server, jobs []schedule.Job) error {
+	session, err := Connect(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to connect the scheduler: %w", err)
+	}
+	defer session.Close()
+
+	s := &scheduler{session: session, runs: map[string]*scheduledRun{}}
+	for _, job := range jobs {
+		server.AddResource(&mcp.Resource{
+			URI:         scheduleURIPrefix + job.Name,
+			Name:        job.Name,
+			Description: fmt.Sprintf("Latest scheduled scan of %s (%s)", job.Image, job.Schedule),
+			MIMEType:    scheduleMIMEType,
+		}, s.resourceHandler(job.Name))
+		logf("info", "Scheduled job %s: scanning %s on %q, next run at %s", job.Name, job.Image, job.Schedule, job.Next(time.Now()).Format(time.RFC3339))
+	}
+
+	schedule.New(jobs, s.run).Run(ctx)
+	return nil
+}
+
+// run scans the job's image and, when the job has a patch policy and the scan trips its threshold, patches it
+func (s *scheduler) run(ctx context.Context, job schedule.Job) {
+	run := &scheduledRun{Job: job.Name, Image: job.Image, StartedAt: time.Now()}
+	defer func() {
+		run.FinishedAt = time.Now()
+		run.NextRun = job.Next(run.FinishedAt)
+		s.store(run)
+	}()
+
+	var scan trivy.ScanResult
+	if run.Error = s.call(ctx, ToolScanContainer, trivy.ScanParams{Image: job.Image, Platform: job.Platform}, &scan); run.Error != nil {
+		logf("error", "Scheduled scan of %s failed: %s", job.Image, run.Error.Message)
+		return
+	}
+	run.Scan = &scan
+	logf("info", "Scheduled scan of %s found %d vulnerabilities", job.Image, scan.VulnCount)
+
+	if job.Patch == nil {
+		return
+	}
+	severities, err := trivy.Severities(scan.ReportPath)
+	if err != nil {
+		run.Error = toolError(copaerrors.NewSystemError("failed to read the scan report", err))
+		return
+	}
+	if run.PatchTriggeredBy = job.Patch.Triggered(severities); run.PatchTriggeredBy == 0 {
+		return
+	}
+
+	// The report belongs to the stored run, so the patch must not remove it
+	keepReport := true
+	var patch types.PatchResult
+	if run.Error = s.call(ctx, ToolPatchReportBased, types.ReportBasedPatchParams{
+		Image:      job.Image,
+		Tag:        job.Patch.Tag,
+		Push:       job.Patch.Push,
+		ReportPath: scan.ReportPath,
+		KeepReport: &keepReport,
+	}, &patch); run.Error != nil {
+		logf("error", "Scheduled patch of %s failed: %s", job.Image, run.Error.Message)
+		return
+	}
+	run.Patch = &patch
+	logf("info", "Scheduled patch of %s fixed %d vulnerabilities", job.Image, patch.NumFixedVulns)
+}
+
+// call calls a tool and decodes its structured result into out, returning the structured error of a failed call
+func (s *scheduler) call(ctx context.Context, name string, args, out any) *types.ToolError {
+	res, err := s.session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		return toolError(err)
+	}
+
+	if res.IsError {
+		var toolErr types.ToolError
+		if err := decodeStructured(res.StructuredContent, &toolErr); err != nil {
+			return toolError(err)
+		}
+		return &toolErr
+	}
+	if err := decodeStructured(res.StructuredContent, out); err != nil {
+		return toolError(err)
+	}
+	return nil
+}
+
+// store replaces the job's latest run, removing the report directory of the run it replaces
+func (s *scheduler) store(run *scheduledRun) {
+	s.mu.Lock()
+	previous := s.runs[run.Job]
+	s.runs[run.Job] = run
+	s.mu.Unlock()
+
+	if previous != nil && previous.Scan != nil && (run.Scan == nil || previous.Scan.ReportPath != run.Scan.ReportPath) {
+		os.RemoveAll(previous.Scan.ReportPath)
+	}
+}
+
+// resourceHandler serves the latest run of a job as JSON
+func (s *scheduler) resourceHandler(job string) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		s.mu.Lock()
+		run := s.runs[job]
+		var data []byte
+		var err error
+		if run != nil {
+			data, err = json.MarshalIndent(run, "", "  ")
+		}
+		s.mu.Unlock()
+
+		if run == nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode scheduled run: %w", err)
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{
+				URI:      req.Params.URI,
+				MIMEType: scheduleMIMEType,
+				Text:     string(data),
+			}},
+		}, nil
+	}
+}
+
+// toolError converts an error raised outside a tool handler into a structured tool error
+func toolError(err error) *types.ToolError {
+	toolErr := errorResult(err).StructuredContent.(types.ToolError)
+	return &toolErr
+}
+
+// decodeStructured decodes the structured content of a tool result, as received by a client, into out
+func decodeStructured(content, out any) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,71 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_CallReturnsToolError(t *testing.T) {
+	ctx := context.Background()
+	session, err := Connect(ctx, NewServer("test", &config.Config{}))
+	require.NoError(t, err)
+	defer session.Close()
+
+	s := &scheduler{session: session, runs: map[string]*scheduledRun{}}
+	var scan trivy.ScanResult
+
+	// Failed tool call
+	toolErr := s.call(ctx, ToolScanContainer, trivy.ScanParams{Image: "alpine:3.18", GitLabReport: filepath.Join("nonexistent", "report.json")}, &scan)
+	require.NotNil(t, toolErr)
+	assert.Equal(t, "validation", toolErr.Category)
+	assert.Contains(t, toolErr.Message, "gitlab report directory does not exist")
+
+	// Arguments rejected by the input schema
+	toolErr = s.call(ctx, ToolScanContainer, trivy.ScanParams{}, &scan)
+	require.NotNil(t, toolErr)
+	assert.Contains(t, toolErr.Message, "image")
+}
+
+func TestScheduler_StoreReplacesReport(t *testing.T) {
+	s := &scheduler{runs: map[string]*scheduledRun{}}
+	first, second := t.TempDir(), t.TempDir()
+
+	s.store(&scheduledRun{Job: "nginx", Scan: &trivy.ScanResult{ReportPath: first}})
+	s.store(&scheduledRun{Job: "nginx", Scan: &trivy.ScanResult{ReportPath: second}})
+
+	_, err := os.Stat(first)
+	assert.True(t, os.IsNotExist(err), "report of the replaced run should be removed")
+	_, err = os.Stat(second)
+	assert.NoError(t, err)
+}
+
+func TestScheduler_ResourceHandler(t *testing.T) {
+	s := &scheduler{runs: map[string]*scheduledRun{}}
+	handler := s.resourceHandler("nginx")
+	req := &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: scheduleURIPrefix + "nginx"}}
+
+	_, err := handler(context.Background(), req)
+	assert.Error(t, err, "no run yet")
+
+	s.store(&scheduledRun{Job: "nginx", Image: "nginx:1.25", Scan: &trivy.ScanResult{Image: "nginx:1.25", VulnCount: 3}, PatchTriggeredBy: 1})
+	res, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Equal(t, scheduleMIMEType, res.Contents[0].MIMEType)
+
+	var run scheduledRun
+	require.NoError(t, json.Unmarshal([]byte(res.Contents[0].Text), &run))
+	assert.Equal(t, "nginx:1.25", run.Image)
+	assert.Equal(t, 3, run.Scan.VulnCount)
+	assert.Equal(t, 1, run.PatchTriggeredBy)
+	assert.NotContains(t, res.Contents[0].Text, "nextRun")
+}
@@ -0,0 +1,78 @@
+package copamcp
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+// inputSchema infers the input schema for T and adds the constraints that struct tags cannot
+// express, so that agents see valid values up front and invalid arguments are rejected before execution
+func inputSchema[T any]() *jsonschema.Schema {
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		// Only reachable with a parameter type that cannot be described, which is a programming error
+		panic(fmt.Sprintf("input schema for %T: %v", *new(T), err))
+	}
+
+	for name, prop := range schema.Properties {
+		switch name {
+		case "image":
+			prop.MinLength = jsonschema.Ptr(1)
+		case "patchtag":
+			prop.Pattern = copa.TagPattern
+		case "platform":
+			if prop.Items != nil {
+				prop.Items.Enum = stringEnum(copa.CopaSupportedPlatforms...)
+			}
+			if slices.Contains(schema.Required, name) {
+				prop.MinItems = jsonschema.Ptr(1)
+			}
+		case "vexFormat":
+			prop.Enum = stringEnum(copa.VexFormatOpenVEX, copa.VexFormatCSAF)
+		case "format":
+			prop.Enum = stringEnum(trivy.SBOMFormats...)
+		case "maxRemaining", "maxRepositories":
+			prop.Minimum = jsonschema.Ptr(0.0)
+		case "maxRemainingSeverity", "minSeverity":
+			prop.Enum = stringEnum(copa.Severities...)
+		case "retry":
+			if attempts := prop.Properties["maxAttempts"]; attempts != nil {
+				attempts.Minimum = jsonschema.Ptr(1.0)
+			}
+			if categories := prop.Properties["categories"]; categories != nil && categories.Items != nil {
+				categories.Items.Enum = stringEnum(categoryNames()...)
+			}
+		}
+	}
+	return schema
+}
+
+// outputSchema infers the published output schema for T
+func outputSchema[T any]() *jsonschema.Schema {
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		panic(fmt.Sprintf("output schema for %T: %v", *new(T), err))
+	}
+	return schema
+}
+
+func categoryNames() []string {
+	names := make([]string, len(copaerrors.Categories))
+	for i, c := range copaerrors.Categories {
+		names[i] = string(c)
+	}
+	return names
+}
+
+func stringEnum(values ...string) []any {
+	enum := make([]any, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+	return enum
+}
@@ -0,0 +1,85 @@
+package copamcp
+
+import (
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputSchema_Constraints(t *testing.T) {
+	schema := inputSchema[types.PlatformSelectivePatchParams]()
+
+	assert.Equal(t, copa.TagPattern, schema.Properties["patchtag"].Pattern)
+	assert.Len(t, schema.Properties["platform"].Items.Enum, len(copa.CopaSupportedPlatforms))
+	assert.Equal(t, 1, *schema.Properties["platform"].MinItems)
+	assert.Contains(t, schema.Required, "image")
+	assert.NotContains(t, schema.Required, "push")
+	assert.NotContains(t, schema.Required, "dockerHost")
+}
+
+func TestInputSchema_OptionalPlatforms(t *testing.T) {
+	schema := inputSchema[trivy.ScanParams]()
+
+	assert.NotEmpty(t, schema.Properties["platform"].Items.Enum)
+	assert.Nil(t, schema.Properties["platform"].MinItems)
+}
+
+func TestInputSchema_Validation(t *testing.T) {
+	resolved, err := inputSchema[types.ReportBasedPatchParams]().Resolve(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			args: map[string]any{"image": "alpine:3.17", "patchtag": "patched", "reportPath": "/tmp/reports-1"},
+		},
+		{
+			name:    "full image reference as tag",
+			args:    map[string]any{"image": "alpine:3.17", "patchtag": "alpine:patched", "reportPath": "/tmp/reports-1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown vex format",
+			args:    map[string]any{"image": "alpine:3.17", "patchtag": "patched", "reportPath": "/tmp/reports-1", "vexFormat": "spdx"},
+			wantErr: true,
+		},
+		{
+			name: "retry override",
+			args: map[string]any{"image": "alpine:3.17", "patchtag": "patched", "reportPath": "/tmp/reports-1", "retry": map[string]any{"maxAttempts": 5, "categories": []any{"network", "execution"}}},
+		},
+		{
+			name:    "zero retry attempts",
+			args:    map[string]any{"image": "alpine:3.17", "patchtag": "patched", "reportPath": "/tmp/reports-1", "retry": map[string]any{"maxAttempts": 0}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown retry category",
+			args:    map[string]any{"image": "alpine:3.17", "patchtag": "patched", "reportPath": "/tmp/reports-1", "retry": map[string]any{"categories": []any{"flaky"}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty image",
+			args:    map[string]any{"image": "", "patchtag": "patched", "reportPath": "/tmp/reports-1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved.Validate(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
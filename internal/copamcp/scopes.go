@@ -0,0 +1,147 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Scope limits the tools a client authenticated with an API token may call
+type Scope string
+
+// Scopes of API tokens, from the most to the least restricted
+const (
+	// ScopeScan allows the read-only tools: scans, vulnerability listings and suggestions
+	ScopeScan Scope = "scan"
+	// ScopePatchNoPush also allows patching, pulling and removing images, without pushing them
+	ScopePatchNoPush Scope = "patch-no-push"
+	// ScopeFull allows every tool
+	ScopeFull Scope = "full"
+)
+
+// Scopes lists the scopes, from the most to the least restricted
+var Scopes = []Scope{ScopeScan, ScopePatchNoPush, ScopeFull}
+
+// ParseScope returns the scope named s
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(Scopes, scope) {
+		return "", copaerrors.NewValidationError(fmt.Sprintf("unknown token scope: %s", s), nil,
+			fmt.Sprintf("use one of %s", joinScopes(Scopes)))
+	}
+	return scope, nil
+}
+
+func joinScopes(scopes []Scope) string {
+	names := make([]string, len(scopes))
+	for i, s := range scopes {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// toolScopes maps each tool to the least scope allowed to call it. Tools missing from the map,
+// such as 'open-image-pr', 'install-dependencies' and 'registry-login', need ScopeFull.
+var toolScopes = map[string]Scope{
+	ToolVersion:                  ScopeScan,
+	ToolWorkflowGuide:            ScopeScan,
+	ToolScanContainer:            ScopeScan,
+	ToolFetchHarborReport:        ScopeScan,
+	ToolListFixedVulnerabilities: ScopeScan,
+	ToolListVulnerabilities:      ScopeScan,
+	ToolSummarizeReport:          ScopeScan,
+	ToolListClusterImages:        ScopeScan,
+	ToolScanRegistry:             ScopeScan,
+	ToolSuggestBaseUpgrade:       ScopeScan,
+	ToolEvaluateImage:            ScopeScan,
+	ToolDiffSBOM:                 ScopeScan,
+	ToolGenerateSBOM:             ScopeScan,
+	ToolVerifyPatch:              ScopeScan,
+	ToolPatchHistory:             ScopeScan,
+	ToolDoctor:                   ScopeScan,
+	ToolPullImage:                ScopePatchNoPush,
+	ToolRemoveImage:              ScopePatchNoPush,
+	ToolPatchComprehensive:       ScopePatchNoPush,
+	ToolPatchPlatformSelective:   ScopePatchNoPush,
+	ToolPatchReportBased:         ScopePatchNoPush,
+	ToolRemediate:                ScopePatchNoPush,
+}
+
+// requiredScope returns the least scope allowed to call tool with the raw arguments args. Pushing
+// a patched image needs ScopeFull, and rewriting a Dockerfile or writing a result file or GitLab
+// report on the server ScopePatchNoPush.
+func requiredScope(tool string, args json.RawMessage) Scope {
+	if name, ok := toolAliases[tool]; ok {
+		tool = name
+	}
+	scope, ok := toolScopes[tool]
+	if !ok {
+		return ScopeFull
+	}
+
+	var flags struct {
+		Push         bool   `json:"push"`
+		Write        bool   `json:"write"`
+		ResultPath   string `json:"resultPath"`
+		GitLabReport string `json:"gitlabReport"`
+	}
+	if len(args) > 0 {
+		// Malformed arguments are rejected by the tool itself
+		json.Unmarshal(args, &flags)
+	}
+	switch {
+	case flags.Push && scope == ScopePatchNoPush:
+		return ScopeFull
+	case flags.Write && tool == ToolSuggestBaseUpgrade:
+		return ScopePatchNoPush
+	case (flags.ResultPath != "" || flags.GitLabReport != "") && scope == ScopeScan:
+		return ScopePatchNoPush
+	}
+	return scope
+}
+
+// allows reports whether s includes required. The empty scope allows nothing.
+func (s Scope) allows(required Scope) bool {
+	return s != "" && slices.Index(Scopes, s) >= slices.Index(Scopes, required)
+}
+
+// requireScope rejects the tool calls not allowed by the scopes of the caller's API token. Calls
+// without a token, over stdio or in-process, are not restricted; the HTTP handler rejects requests
+// without one.
+func requireScope(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok || call.Extra == nil || call.Extra.TokenInfo == nil {
+			return next(ctx, method, req)
+		}
+
+		granted := tokenScope(call.Extra.TokenInfo.Scopes)
+		required := requiredScope(call.Params.Name, call.Params.Arguments)
+		if !granted.allows(required) {
+			msg := fmt.Sprintf("the API token's %q scope does not allow calling '%s' with these arguments, which needs %q", granted, call.Params.Name, required)
+			if granted == "" {
+				msg = fmt.Sprintf("the API token has none of the scopes %s", joinScopes(Scopes))
+			}
+			return errorResult(copaerrors.NewAuthError(msg, nil,
+				fmt.Sprintf("call '%s' with a token of the %q scope", call.Params.Name, required))), nil
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// tokenScope returns the least restricted of scopes, ignoring unknown ones, or "" when none is
+// known, which allows no tool
+func tokenScope(scopes []string) Scope {
+	var granted Scope
+	for _, s := range scopes {
+		if scope := Scope(s); slices.Contains(Scopes, scope) && scope.allows(granted) {
+			granted = scope
+		}
+	}
+	return granted
+}
@@ -0,0 +1,46 @@
+package copamcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		tool string
+		args string
+		want Scope
+	}{
+		{ToolScanContainer, `{"image":"alpine:3.19"}`, ScopeScan},
+		{ToolSuggestBaseUpgrade, `{"dockerfile":"Dockerfile"}`, ScopeScan},
+		{ToolSuggestBaseUpgrade, `{"dockerfile":"Dockerfile","write":true}`, ScopePatchNoPush},
+		{ToolPatchReportBased, `{"image":"alpine:3.19"}`, ScopePatchNoPush},
+		{ToolPatchReportBased, `{"image":"alpine:3.19","push":true}`, ScopeFull},
+		{"patch-vulnerabilities", `{"push":true}`, ScopeFull},
+		{"patch-platforms", ``, ScopePatchNoPush},
+		{ToolOpenImagePR, `{}`, ScopeFull},
+		{ToolInstallDependencies, `{}`, ScopeFull},
+		{ToolRemediate, `{"image":"alpine:3.19"}`, ScopePatchNoPush},
+		{ToolRemediate, `{"resume":"123","push":true,"sign":true}`, ScopeFull},
+		{ToolPatchComprehensive, `not json`, ScopePatchNoPush},
+		{ToolScanContainer, `{"image":"alpine:3.19","resultPath":"/tmp/result.json"}`, ScopePatchNoPush},
+		{ToolScanContainer, `{"image":"alpine:3.19","gitlabReport":"gl-container-scanning-report.json"}`, ScopePatchNoPush},
+		{ToolGenerateSBOM, `{"image":"alpine:3.19","resultPath":""}`, ScopeScan},
+		{ToolPatchReportBased, `{"image":"alpine:3.19","resultPath":"/tmp/result.json","push":true}`, ScopeFull},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, requiredScope(tt.tool, json.RawMessage(tt.args)), "%s %s", tt.tool, tt.args)
+	}
+}
+
+func TestTokenScope(t *testing.T) {
+	assert.Equal(t, ScopeFull, tokenScope([]string{"scan", "full"}))
+	assert.Equal(t, ScopePatchNoPush, tokenScope([]string{"other", "patch-no-push"}))
+	assert.Equal(t, Scope(""), tokenScope([]string{"admin"}))
+
+	assert.True(t, ScopeFull.allows(ScopePatchNoPush))
+	assert.False(t, ScopeScan.allows(ScopePatchNoPush))
+	assert.False(t, Scope("").allows(ScopeScan))
+}
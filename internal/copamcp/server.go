@@ -2,11 +2,62 @@ package copamcp
 
 import (
 	"context"
+	"fmt"
+	"time"
+
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/cosign"
+	"github.com/project-copacetic/mcp-server/internal/defectdojo"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	"github.com/project-copacetic/mcp-server/internal/dtrack"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/gitpr"
+	"github.com/project-copacetic/mcp-server/internal/install"
+	"github.com/project-copacetic/mcp-server/internal/notify"
+	"github.com/project-copacetic/mcp-server/internal/otlp"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+	"github.com/project-copacetic/mcp-server/internal/schedule"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/transcript"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// Tool names registered by NewServer
+const (
+	ToolVersion                  = "version"
+	ToolWorkflowGuide            = "workflow-guide"
+	ToolScanContainer            = "scan-container"
+	ToolPullImage                = "pull-image"
+	ToolRemoveImage              = "remove-image"
+	ToolRegistryLogin            = "registry-login"
+	ToolPatchComprehensive       = "patch-comprehensive"
+	ToolPatchPlatformSelective   = "patch-platform-selective"
+	ToolPatchReportBased         = "patch-report-based"
+	ToolListFixedVulnerabilities = "list-fixed-vulnerabilities"
+	ToolListVulnerabilities      = "list-vulnerabilities"
+	ToolSummarizeReport          = "summarize-report"
+	ToolListClusterImages        = "list-cluster-images"
+	ToolScanRegistry             = "scan-registry"
+	ToolFetchHarborReport        = "fetch-harbor-report"
+	ToolSuggestBaseUpgrade       = "suggest-base-upgrade"
+	ToolEvaluateImage            = "evaluate-image"
+	ToolDiffSBOM                 = "diff-sbom"
+	ToolGenerateSBOM             = "generate-sbom"
+	ToolVerifyPatch              = "verify-patch"
+	ToolPatchHistory             = "patch-history"
+	ToolDoctor                   = "doctor"
+	ToolOpenImagePR              = "open-image-pr"
+	ToolInstallDependencies      = "install-dependencies"
+	ToolRemediate                = "remediate"
 )
 
 // NewServer creates and configures the MCP server with all tools
-func NewServer(version string) *mcp.Server {
+func NewServer(version string, cfg *config.Config) *mcp.Server {
 	if version == "" {
 		version = "dev"
 	}
@@ -16,45 +67,390 @@ func NewServer(version string) *mcp.Server {
 		Version: version,
 	}, nil)
 
+	// The project and product mappings were validated with the rest of the configuration
+	projects, _ := dtrack.ParseProjects(cfg.DependencyTrackProjects)
+	products, _ := defectdojo.ParseMappings(cfg.DefectDojoProducts)
+	t := &tools{
+		cfg:        cfg,
+		server:     server,
+		dtrack:     dtrack.New(cfg.DependencyTrackURL, cfg.DependencyTrackAPIKey, projects),
+		defectdojo: defectdojo.New(cfg.DefectDojoURL, cfg.DefectDojoAPIKey, products),
+		policy:     policy.New(cfg.Policies),
+		verifier: cosign.New(cosign.Options{
+			Keys:        cfg.VerifyKeys,
+			Identity:    cfg.VerifyIdentity,
+			Issuer:      cfg.VerifyOIDCIssuer,
+			Attestation: cfg.VerifyAttestation,
+		}),
+		signer: cosign.NewSigner(cfg.SignKey),
+		gitpr:  gitpr.New(cfg.GitHubToken, cfg.GitLabToken),
+	}
+	notifier := notify.New(notify.Webhooks(cfg.WebhookURLs, cfg.SlackWebhookURLs))
+	// The limit, environment, working directory and log exporter are process-wide, shared by every server of the process
+	subprocess.SetLimit(cfg.MaxSubprocesses)
+	trivy.SetPlatformConcurrency(cfg.ScanConcurrency)
+	trivy.SetDBOptions(trivy.DBOptions{SkipDBUpdate: cfg.TrivySkipDBUpdate, OfflineScan: cfg.TrivyOfflineScan, DBRepository: cfg.TrivyDBRepository})
+	subprocessEnv, _ := subprocess.ParseEnv(cfg.SubprocessEnv)
+	subprocess.SetEnv(subprocessEnv)
+	workdir.Configure(cfg.TempDir, int64(cfg.TempQuotaMB)<<20)
+	// The headers were validated with the rest of the configuration
+	headers, _ := otlp.ParseHeaders(cfg.OTLPHeaders)
+	otlp.Configure(cfg.OTLPEndpoint, headers, version)
+
 	// Register tools
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "version",
-		Description: "Copacetic automated container patching",
-	}, Version)
+	addTool(server, &mcp.Tool{
+		Name:         ToolVersion,
+		Description:  "Copacetic automated container patching",
+		OutputSchema: outputSchema[types.Ver](),
+	}, t.Version)
 
 	// Workflow guidance tool
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "workflow-guide",
+	addTool(server, &mcp.Tool{
+		Name:        ToolWorkflowGuide,
 		Description: "Get guidance on which Copacetic tools to use for different container patching scenarios",
-	}, WorkflowGuide)
+	}, t.WorkflowGuide)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolScanContainer,
+		Description:  "Scan container image for vulnerabilities using Trivy - creates vulnerability reports required for report-based patching",
+		InputSchema:  inputSchema[trivy.ScanParams](),
+		OutputSchema: outputSchema[trivy.ScanResult](),
+	}, operation(cfg, notifier, ToolScanContainer, t.ScanContainer, func(p trivy.ScanParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolFetchHarborReport,
+		Description:  "Fetch the vulnerability report Harbor already produced for an image and convert it into a report directory for 'patch-report-based' - use instead of 'scan-container' for Harbor images to avoid scanning twice",
+		InputSchema:  inputSchema[types.HarborReportParams](),
+		OutputSchema: outputSchema[trivy.ScanResult](),
+	}, operation(cfg, notifier, ToolFetchHarborReport, t.FetchHarborReport, func(p types.HarborReportParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolSuggestBaseUpgrade,
+		Description:  "Suggest newer tags of a base image, or of the base images in a Dockerfile's FROM instructions, and optionally bump the FROM instructions - use when scan findings come from an outdated base image, as a complement to patching",
+		InputSchema:  inputSchema[types.BaseUpgradeParams](),
+		OutputSchema: outputSchema[types.BaseUpgradeResult](),
+	}, operation(cfg, notifier, ToolSuggestBaseUpgrade, t.SuggestBaseUpgrade, func(p types.BaseUpgradeParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolEvaluateImage,
+		Description:  "Evaluate an image against the admission policies (allowed registries, required signature, maximum critical vulnerabilities, Rego policies) and return an allow/deny verdict with reasons - use as an admission check before deploying or promoting an image",
+		InputSchema:  inputSchema[types.EvaluateImageParams](),
+		OutputSchema: outputSchema[types.ImageVerdict](),
+	}, operation(cfg, notifier, ToolEvaluateImage, t.EvaluateImage, func(p types.EvaluateImageParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolGenerateSBOM,
+		Description:  "Generate a CycloneDX or SPDX SBOM of an image with Trivy and return its path and an MCP resource URI - use for compliance evidence of an image's packages, e.g. before and after patching",
+		InputSchema:  inputSchema[types.SBOMParams](),
+		OutputSchema: outputSchema[types.SBOM](),
+	}, operation(cfg, notifier, ToolGenerateSBOM, t.GenerateSBOM, func(p types.SBOMParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolDiffSBOM,
+		Description:  "Generate SBOMs of an image and its patched image and diff their packages (upgraded, added, removed) - use after patching for evidence of exactly what changed",
+		InputSchema:  inputSchema[types.SBOMDiffParams](),
+		OutputSchema: outputSchema[types.SBOMDiff](),
+	}, operation(cfg, notifier, ToolDiffSBOM, t.DiffSBOM, func(p types.SBOMDiffParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolVerifyPatch,
+		Description:  "Rescan a patched image and compare its vulnerabilities with the scan report of the original image, returning the fixed, remaining and introduced vulnerabilities - use after 'patch-report-based' as proof that the patch removed the vulnerabilities",
+		InputSchema:  inputSchema[types.VerifyPatchParams](),
+		OutputSchema: outputSchema[types.PatchVerification](),
+	}, operation(cfg, notifier, ToolVerifyPatch, t.VerifyPatch, func(p types.VerifyPatchParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolPatchHistory,
+		Description:  "List the patches the server completed, newest first, with the patched tags, pushed digest and vulnerability counts of each - use to find when and how an image was last patched",
+		InputSchema:  inputSchema[types.PatchHistoryParams](),
+		OutputSchema: outputSchema[types.PatchHistory](),
+	}, t.PatchHistory)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolDoctor,
+		Description:  "Check whether the server is ready to scan and patch images: the Docker daemon, buildkit, the copa and trivy binaries and their versions, registry connectivity and the disk space of the working directory - use first when setting up, or when scans or patches fail for environmental reasons",
+		InputSchema:  inputSchema[types.DoctorParams](),
+		OutputSchema: outputSchema[types.DoctorReport](),
+	}, t.Doctor)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolOpenImagePR,
+		Description:  "Rewrite the references to an image in a GitHub or GitLab repository (e.g. Kubernetes manifests, Helm values) to its pushed patched image pinned by digest, and open a pull request - use after patching and pushing to roll the patch out to deployments",
+		InputSchema:  inputSchema[types.ImagePRParams](),
+		OutputSchema: outputSchema[types.ImagePR](),
+	}, operation(cfg, notifier, ToolOpenImagePR, t.OpenImagePR, func(p types.ImagePRParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:        ToolPullImage,
+		Description: "Pull a container image (optionally for a specific platform) into the local Docker daemon - useful before local scanning/patching and for pre-warming large images",
+	}, withJobLog(ToolPullImage, t.PullImage))
+
+	addTool(server, &mcp.Tool{
+		Name:        ToolRemoveImage,
+		Description: "Remove local container images and/or prune dangling images created during patching - use to clean up after batch operations",
+	}, t.RemoveImage)
+
+	addTool(server, &mcp.Tool{
+		Name:        ToolRegistryLogin,
+		Description: "Log in to a container registry with a user name and password or access token, storing the credentials for the server's following pulls, scans and pushes - use when a pull or push fails with an auth error",
+	}, t.RegistryLogin)
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "scan-container",
-		Description: "Scan container image for vulnerabilities using Trivy - creates vulnerability reports required for report-based patching",
-	}, ScanContainer)
+	addTool(server, &mcp.Tool{
+		Name:         ToolPatchComprehensive,
+		Description:  "Comprehensively patch all container image platforms with Copa - patches all available platforms WITHOUT vulnerability scanning. Use ONLY when you want to patch all platforms regardless of vulnerabilities. For vulnerability-based patching, use 'scan-container' + 'patch-report-based'.",
+		InputSchema:  inputSchema[types.ComprehensivePatchParams](),
+		OutputSchema: outputSchema[types.PatchResult](),
+	}, operation(cfg, notifier, ToolPatchComprehensive, t.PatchComprehensive, func(p types.ComprehensivePatchParams) string { return p.ResultPath }))
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "patch-comprehensive",
-		Description: "Comprehensively patch all container image platforms with Copa - patches all available platforms WITHOUT vulnerability scanning. Use ONLY when you want to patch all platforms regardless of vulnerabilities. For vulnerability-based patching, use 'scan-container' + 'patch-vulnerabilities'.",
-	}, PatchComprehensive)
+	addTool(server, &mcp.Tool{
+		Name:         ToolPatchPlatformSelective,
+		Description:  "Patch specific container image platforms with Copa - patches only the specified platforms WITHOUT vulnerability scanning. Use ONLY when you want to patch specific platforms regardless of vulnerabilities. For vulnerability-based patching, use 'scan-container' + 'patch-report-based'.",
+		InputSchema:  inputSchema[types.PlatformSelectivePatchParams](),
+		OutputSchema: outputSchema[types.PatchResult](),
+	}, operation(cfg, notifier, ToolPatchPlatformSelective, t.PatchPlatformSelective, func(p types.PlatformSelectivePatchParams) string { return p.ResultPath }))
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "patch-platform-selective",
-		Description: "Patch specific container image platforms with Copa - patches only the specified platforms WITHOUT vulnerability scanning. Use ONLY when you want to patch specific platforms regardless of vulnerabilities. For vulnerability-based patching, use 'scan-container' + 'patch-vulnerabilities'.",
-	}, PatchPlatformSelective)
+	addTool(server, &mcp.Tool{
+		Name:         ToolPatchReportBased,
+		Description:  "Patch container image vulnerabilities using a pre-generated vulnerability report from 'scan-container' tool - requires running 'scan-container' first. This is the RECOMMENDED approach for vulnerability-based patching.",
+		InputSchema:  inputSchema[types.ReportBasedPatchParams](),
+		OutputSchema: outputSchema[types.PatchResult](),
+	}, operation(cfg, notifier, ToolPatchReportBased, t.PatchReportBased, func(p types.ReportBasedPatchParams) string { return p.ResultPath }))
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "patch-report-based",
-		Description: "Patch container image vulnerabilities using a pre-generated vulnerability report from 'scan-container' tool - requires running 'scan-container' first. This is the RECOMMENDED approach for vulnerability-based patching.",
-	}, PatchReportBased)
+	addTool(server, &mcp.Tool{
+		Name:         ToolRemediate,
+		Description:  "Remediate an image in one call: scan it, patch the vulnerabilities found, rescan the patched image and compare both scans, then optionally push and sign it - returns a consolidated report of every stage. A failed remediation can be resumed by its ID from the stage that failed",
+		InputSchema:  inputSchema[types.RemediateParams](),
+		OutputSchema: outputSchema[types.Remediation](),
+	}, operation(cfg, notifier, ToolRemediate, t.Remediate, func(p types.RemediateParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolListFixedVulnerabilities,
+		Description:  "Page through the vulnerabilities fixed by a report-based patch, with the packages updated for each - use when the patch result's fixed vulnerability list was truncated",
+		OutputSchema: outputSchema[types.FixedVulnerabilityPage](),
+	}, t.ListFixedVulnerabilities)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolListVulnerabilities,
+		Description:  "Page through the vulnerabilities of a report from 'scan-container', filtered by severity, fixability or package and sorted by severity, ID or package - use to explore images with too many findings to read at once",
+		OutputSchema: outputSchema[types.VulnerabilityPage](),
+	}, t.ListVulnerabilities)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolSummarizeReport,
+		Description:  "Summarize a report from 'scan-container': vulnerability counts by severity, fixable and unfixable counts, the most vulnerable packages and a breakdown per platform - use to size up an image before listing or patching its vulnerabilities",
+		InputSchema:  inputSchema[types.SummarizeReportParams](),
+		OutputSchema: outputSchema[types.ReportSummary](),
+	}, t.SummarizeReport)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolListClusterImages,
+		Description:  "List the container images running in a Kubernetes cluster or namespace (using kubectl with the kubeconfig or in-cluster service account), with their pod counts - a starting point to scan and patch what is actually deployed",
+		OutputSchema: outputSchema[types.ClusterImageList](),
+	}, t.ListClusterImages)
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolScanRegistry,
+		Description:  "Scan the latest tag (or a given tag) of every repository in a registry's catalog, or of a list of repositories, and return an aggregated fleet vulnerability report with a report directory per image for 'patch-report-based'",
+		InputSchema:  inputSchema[types.ScanRegistryParams](),
+		OutputSchema: outputSchema[types.FleetReport](),
+	}, operation(cfg, notifier, ToolScanRegistry, t.ScanRegistry, func(p types.ScanRegistryParams) string { return p.ResultPath }))
+
+	addTool(server, &mcp.Tool{
+		Name:         ToolInstallDependencies,
+		Description:  "Download checksum-verified pinned releases of copa and trivy into the server's managed bin directory and use them for the following calls - use when 'version' or a scan or patch reports that copa or trivy is not installed, or too old",
+		OutputSchema: outputSchema[types.DependencyInstall](),
+	}, withJobLog(ToolInstallDependencies, t.InstallDependencies))
+
+	addJobResources(server)
+	server.AddReceivingMiddleware(requireScope)
+	if cfg.Transcript != "" {
+		// Added last, so that the calls rejected for their token's scope are recorded too
+		if rec, err := transcript.Create(cfg.Transcript); err != nil {
+			logf("warning", "tool calls are not recorded, failed to open the transcript: %v", err)
+		} else {
+			server.AddReceivingMiddleware(recordTranscript(rec))
+		}
+	}
 
 	return server
 }
 
-// Run starts the MCP server
-func Run(ctx context.Context, version string) error {
-	server := NewServer(version)
-	return server.Run(ctx, &mcp.StdioTransport{})
+// operation wraps the handler of a scan or patch tool with the job log buffering its events, and
+// the optional outputs of a finished operation: webhook notifications, the GitHub Actions step
+// summary, Azure Pipelines logging commands and the result file
+func operation[In any](cfg *config.Config, n *notify.Notifier, tool string, h mcp.ToolHandlerFor[In, any], resultPath func(In) string) mcp.ToolHandlerFor[In, any] {
+	return withJobLog(tool, withResultFile(withAzurePipelines(cfg.AzurePipelines, tool, withStepSummary(cfg.GitHubSummary, tool, withHistory(cfg.History, tool, withNotify(n, tool, h)))), resultPath))
+}
+
+// toolAliases maps deprecated tool names, still used by older clients and prompts, to the current tool names
+var toolAliases = map[string]string{
+	"patch-platforms":       ToolPatchPlatformSelective,
+	"patch-vulnerabilities": ToolPatchReportBased,
+}
+
+// addTool registers a tool on the server, along with any deprecated aliases of its name
+func addTool[In any](server *mcp.Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, any]) {
+	mcp.AddTool(server, tool, handler)
+
+	for alias, name := range toolAliases {
+		if name != tool.Name {
+			continue
+		}
+		aliased := *tool
+		aliased.Name = alias
+		aliased.Description = fmt.Sprintf("Deprecated alias of '%s'. %s", name, tool.Description)
+		mcp.AddTool(server, &aliased, handler)
+	}
+}
+
+// Run starts the MCP server on stdio, and the scheduler when a schedule file is configured
+func Run(ctx context.Context, version string, cfg *config.Config) error {
+	return serve(ctx, version, cfg, func(ctx context.Context, server *mcp.Server) error {
+		return server.Run(ctx, &mcp.StdioTransport{})
+	})
+}
+
+// serve starts a server with cfg, and the scheduler when a schedule file is configured, and
+// passes it to run to serve clients until ctx is done
+func serve(ctx context.Context, version string, cfg *config.Config, run func(context.Context, *mcp.Server) error) error {
+	if host := docker.ConfigureHost(cfg.DockerSockets); host != "" {
+		logf("info", "Default Docker socket not found, using DOCKER_HOST=%s", host)
+	}
+
+	server := NewServer(version, cfg)
+	defer flushLogs()
+	sweepWorkdir(cfg)
+	useBinDir(cfg)
+	useBinaries(cfg)
+	detectToolchain(ctx)
+	if cfg.ScheduleFile != "" {
+		jobs, err := schedule.LoadJobs(cfg.ScheduleFile)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			if err := runScheduler(ctx, server, jobs); err != nil {
+				logf("error", "Scheduler stopped: %v", err)
+			}
+		}()
+	}
+	return run(ctx, server)
+}
+
+// RunDaemon runs the scheduled jobs of the configured schedule file until ctx is done, without
+// serving a client. Results are delivered through the configured webhooks and step summary.
+func RunDaemon(ctx context.Context, version string, cfg *config.Config) error {
+	if cfg.ScheduleFile == "" {
+		return fmt.Errorf("daemon mode requires a schedule file")
+	}
+	jobs, err := schedule.LoadJobs(cfg.ScheduleFile)
+	if err != nil {
+		return err
+	}
+
+	if host := docker.ConfigureHost(cfg.DockerSockets); host != "" {
+		logf("info", "Default Docker socket not found, using DOCKER_HOST=%s", host)
+	}
+	server := NewServer(version, cfg)
+	defer flushLogs()
+	sweepWorkdir(cfg)
+	useBinDir(cfg)
+	useBinaries(cfg)
+	detectToolchain(ctx)
+	return runScheduler(ctx, server, jobs)
+}
+
+// maxSweepLines bounds the entries sweepWorkdir lists at startup
+const maxSweepLines = 20
+
+// sweepWorkdir lists the reports, VEX documents and working directories earlier runs left behind,
+// including those crashed runs never removed, and removes those older than cfg.TempMaxAge. It runs
+// once NewServer configured the directory.
+func sweepWorkdir(cfg *config.Config) {
+	entries, err := workdir.Sweep(cfg.TempMaxAge)
+	var removed int
+	var freed int64
+	for i, e := range entries {
+		if e.Removed {
+			removed++
+			freed += e.Size
+		}
+		if i < maxSweepLines {
+			action := "kept"
+			if e.Removed {
+				action = "removed"
+			}
+			logf("info", "Found %s (%s, modified %s ago): %s", e.Path, copa.FormatBytes(uint64(e.Size)),
+				time.Since(e.ModTime).Round(time.Minute), action)
+		}
+	}
+	if len(entries) > maxSweepLines {
+		logf("info", "... and %d more", len(entries)-maxSweepLines)
+	}
+	if len(entries) > 0 {
+		logf("info", "Removed %d of %d entries left by earlier runs (%s freed)", removed, len(entries), copa.FormatBytes(uint64(freed)))
+	}
+	if removed < len(entries) && cfg.TempMaxAge == 0 {
+		logf("info", "Set %s to remove entries left by earlier runs", config.EnvTempMaxAge)
+	}
+	if err != nil {
+		logf("error", "Failed to clean up %s: %v", workdir.Root(), err)
+	}
+}
+
+// useBinDir puts the managed bin directory, when 'install-dependencies' created it, first on the
+// PATH. Mock mode keeps its stubs first instead.
+func useBinDir(cfg *config.Config) {
+	if cfg.Mock {
+		return
+	}
+	if dir, err := install.Dir(cfg.BinDir); err == nil {
+		install.UsePath(dir)
+	}
+}
+
+// useBinaries makes the tools run the copa and trivy binaries configured with --copa-path and
+// --trivy-path. Mock mode runs its stubs instead.
+func useBinaries(cfg *config.Config) {
+	if cfg.Mock {
+		return
+	}
+	copaexec.SetPath(copaexec.Copa, cfg.CopaPath)
+	copaexec.SetPath(copaexec.Trivy, cfg.TrivyPath)
+}
+
+// detectToolchain detects the installed copa and trivy releases once at startup, reporting where
+// each was found and the features of the matrix they lack. Tool calls reuse the detected versions.
+func detectToolchain(ctx context.Context) {
+	for _, tool := range []string{copaexec.Copa, copaexec.Trivy} {
+		path, err := copaexec.LookPath(tool)
+		if err != nil {
+			logf("warning", "%s not found: %s", tool, copaexec.NotFoundHint(tool))
+			continue
+		}
+		if v, ok := toolchain.Installed(ctx, tool); ok {
+			logf("info", "Found %s %s at %s", tool, v, path)
+		} else {
+			logf("warning", "found %s at %s, but could not determine its version", tool, path)
+		}
+	}
+	for _, missing := range toolchain.Missing(ctx) {
+		logf("warning", "Unsupported feature: %s", missing)
+	}
+}
+
+// Connect connects an in-process client to server, for calling its tools without a transport
+func Connect(ctx context.Context, server *mcp.Server) (*mcp.ClientSession, error) {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, err
+	}
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "copacetic-mcp-embedded", Version: "dev"}, nil)
+	return client.Connect(ctx, clientTransport, nil)
 }
 
 // getWorkflowGuidance provides guidance on which tool to use for different scenarios
@@ -65,15 +461,19 @@ func getWorkflowGuidance() string {
 Choose the right tool for your use case:
 
 1. VULNERABILITY-BASED PATCHING (Recommended):
-   Step 1: scan-container (scan for vulnerabilities)
-   Step 2: patch-vulnerabilities (patch only found vulnerabilities)
+   Step 1: scan-container (scan for vulnerabilities; set reuseAttachedReport to
+           reuse a report the build pipeline attached to the image instead)
+   Step 2: patch-report-based (patch only found vulnerabilities)
+   Step 3: verify-patch (rescan the patched image against the report of step 1)
+   Or in one call: remediate (scan, patch, rescan and compare, then optionally
+           push and sign; resume a failed remediation with its ID)
    
 2. PLATFORM-SPECIFIC PATCHING (without vulnerability scanning):
-   Use: patch-platforms (specify which platforms to patch)
+   Use: patch-platform-selective (specify which platforms to patch)
    
 3. COMPREHENSIVE PATCHING (without vulnerability scanning):
    Use: patch-comprehensive (patch all available platforms)
 
-IMPORTANT: Do NOT mix approaches. If you scan first, use patch-vulnerabilities.
-If you want platform-specific patching without scanning, use patch-platforms.`
+IMPORTANT: Do NOT mix approaches. If you scan first, use patch-report-based.
+If you want platform-specific patching without scanning, use patch-platform-selective.`
 }
@@ -0,0 +1,93 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer_PublishesOutputSchemas(t *testing.T) {
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	server := NewServer("test", &config.Config{})
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.ListTools(ctx, nil)
+	require.NoError(t, err)
+
+	outputs := map[string][]string{
+		"version":                  {"version"},
+		"scan-container":           {"image", "reportPath", "vulnCount"},
+		"patch-comprehensive":      {"originalImage", "patchedImage", "numFixedVulns"},
+		"patch-platform-selective": {"originalImage", "patchedImage", "platforms"},
+		"patch-report-based":       {"originalImage", "patchedImage", "severity"},
+		"list-vulnerabilities":     {"vulnerabilities", "total", "counts"},
+		"summarize-report":         {"total", "counts", "fixable", "topPackages", "platforms"},
+		"list-cluster-images":      {"images", "pods"},
+		"scan-registry":            {"images", "totals", "scanned"},
+		"fetch-harbor-report":      {"reportPath", "vulnCount"},
+		"suggest-base-upgrade":     {"upgrades"},
+		"evaluate-image":           {"verdict", "allowed", "checks"},
+		"diff-sbom":                {"upgraded", "added", "removed"},
+		"verify-patch":             {"verified", "fixed", "remaining", "introduced"},
+		"patch-history":            {"patches", "total"},
+		"doctor":                   {"ready", "checks"},
+		"open-image-pr":            {"patchedImage", "files", "url"},
+		"install-dependencies":     {"binDir", "tools"},
+		"remediate":                {"id", "verified", "stages"},
+	}
+	for _, tool := range res.Tools {
+		properties, ok := outputs[tool.Name]
+		if !ok {
+			continue
+		}
+		require.NotNil(t, tool.OutputSchema, tool.Name)
+		for _, property := range properties {
+			assert.Contains(t, tool.OutputSchema.Properties, property, tool.Name)
+		}
+		delete(outputs, tool.Name)
+	}
+	assert.Empty(t, outputs, "tools not registered")
+}
+
+func TestNewServer_RegistersToolAliases(t *testing.T) {
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	server := NewServer("test", &config.Config{})
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.ListTools(ctx, nil)
+	require.NoError(t, err)
+
+	tools := make(map[string]*mcp.Tool, len(res.Tools))
+	for _, tool := range res.Tools {
+		tools[tool.Name] = tool
+	}
+	for alias, name := range toolAliases {
+		require.Contains(t, tools, alias)
+		require.Contains(t, tools, name)
+		assert.Contains(t, tools[alias].Description, "Deprecated alias of '"+name+"'")
+		assert.Equal(t, tools[name].InputSchema, tools[alias].InputSchema, alias)
+		assert.Equal(t, tools[name].OutputSchema, tools[alias].OutputSchema, alias)
+	}
+}
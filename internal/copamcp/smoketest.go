@@ -0,0 +1,64 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/smoketest"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// validateSmokeTest checks, before patching, that a smoke test can run: the operator allows smoke
+// tests and at most one platform is patched, since copa only loads single-platform images locally
+func (t *tools) validateSmokeTest(command, platforms []string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	if !t.cfg.SmokeTests {
+		return copaerrors.NewValidationError("smoke tests are disabled on this server", nil,
+			fmt.Sprintf("ask an operator to set %s=true, or patch without smokeTest", config.EnvSmokeTests))
+	}
+	if len(platforms) > 1 {
+		return copaerrors.NewValidationError("smoke tests need a single-platform patch", nil, "patch one platform at a time")
+	}
+	return nil
+}
+
+// smokeTest runs command against the patched image in a temporary registry and, when it passes,
+// completes the push copa deferred. An image that fails the test is left in the local daemon only.
+func (t *tools) smokeTest(ctx context.Context, req *mcp.CallToolRequest, retry *types.RetryParams, dockerHost string, command []string, result *copa.ExecutionResult) (*types.SmokeTestResult, error) {
+	if len(result.Platforms) > 1 {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("smoke tests need a single-platform patch, %s has %d platforms", result.PatchedImage, len(result.Platforms)), nil,
+			"patch one platform at a time with 'patch-platform-selective'")
+	}
+
+	smoke, err := smoketest.Run(ctx, dockerHost, result.PatchedImage, command)
+	if err != nil {
+		return nil, fmt.Errorf("smoke test of %s failed, the patched image was not pushed: %w", result.PatchedImage, err)
+	}
+
+	if result.PushPending {
+		err = t.retry(ctx, req, retry, func() error {
+			return docker.Push(ctx, dockerHost, result.PatchedImage)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("pushing %s after it passed its smoke test failed: %w", result.PatchedImage, err)
+		}
+		result.PushPending = false
+	}
+	return smoke, nil
+}
+
+// smokeTestMessage describes the smoke test a patched image passed
+func smokeTestMessage(smoke *types.SmokeTestResult) string {
+	if smoke == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n smoke test passed in %s: %s", smoke.Duration, strings.Join(smoke.Command, " "))
+}
@@ -0,0 +1,51 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSmokeTest(t *testing.T) {
+	command := []string{"docker", "run", "--rm", "{image}", "nginx", "-t"}
+	tt := &tools{cfg: config.Default()}
+
+	assert.NoError(t, tt.validateSmokeTest(nil, nil))
+
+	err := tt.validateSmokeTest(command, nil)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "disabled")
+
+	tt.cfg.SmokeTests = true
+	assert.NoError(t, tt.validateSmokeTest(command, []string{"linux/amd64"}))
+	assert.Error(t, tt.validateSmokeTest(command, []string{"linux/amd64", "linux/arm64"}))
+}
+
+func TestSmokeTest_MultiPlatform(t *testing.T) {
+	cfg := config.Default()
+	cfg.SmokeTests = true
+	tt := &tools{cfg: cfg}
+	result := &copa.ExecutionResult{
+		PatchedImage: "nginx:1.25-patched",
+		PushPending:  true,
+		Platforms:    []types.PlatformResult{{Platform: "linux/amd64"}, {Platform: "linux/arm64"}},
+	}
+
+	_, err := tt.smokeTest(context.Background(), nil, nil, "", []string{"true"}, result)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	assert.True(t, result.PushPending, "an image that was not tested is not pushed")
+}
+
+func TestSmokeTestMessage(t *testing.T) {
+	assert.Empty(t, smokeTestMessage(nil))
+	assert.Equal(t, "\n smoke test passed in 2s: docker run --rm localhost:5001/nginx:patched nginx -t",
+		smokeTestMessage(&types.SmokeTestResult{Command: []string{"docker", "run", "--rm", "localhost:5001/nginx:patched", "nginx", "-t"}, Duration: "2s"}))
+}
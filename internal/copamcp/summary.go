@@ -0,0 +1,160 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// envGitHubStepSummary is the file GitHub Actions renders as the Markdown summary of a job step
+const envGitHubStepSummary = "GITHUB_STEP_SUMMARY"
+
+// maxFixedInSummary caps the fixed vulnerabilities listed in a step summary
+const maxFixedInSummary = 25
+
+// withStepSummary wraps h so that a Markdown summary of each result is appended to the
+// GitHub Actions step summary, when enabled and running in GitHub Actions
+func withStepSummary[In any](enabled bool, tool string, h mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	if !enabled {
+		return h
+	}
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params In) (*mcp.CallToolResult, any, error) {
+		res, out, err := h(ctx, req, params)
+		path := os.Getenv(envGitHubStepSummary)
+		if err != nil || res == nil || path == "" {
+			return res, out, err
+		}
+
+		if summary := markdownSummary(tool, res.StructuredContent); summary != "" {
+			if err := appendFile(path, summary); err != nil {
+				logf("warning", "failed to write GitHub step summary: %v", err)
+			}
+		}
+		return res, out, nil
+	}
+}
+
+// markdownSummary renders the structured result of tool as Markdown
+func markdownSummary(tool string, result any) string {
+	switch r := result.(type) {
+	case *trivy.ScanResult:
+		return scanSummary(tool, r)
+	case types.PatchResult:
+		return patchSummary(tool, r)
+	case types.Remediation:
+		return remediationSummary(tool, r)
+	case types.ToolError:
+		return errorSummary(tool, r)
+	}
+	return ""
+}
+
+func scanSummary(tool string, r *trivy.ScanResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### :mag: %s: `%s`\n\n", tool, r.Image)
+	b.WriteString("| Platforms | Fixable vulnerabilities | Report | Duration |\n|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %s | %d | `%s` | %s |\n", strings.Join(r.Platforms, ", "), r.VulnCount, r.ReportPath, r.Duration)
+	writeWarnings(&b, r.Warnings)
+	return b.String()
+}
+
+func patchSummary(tool string, r types.PatchResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### :white_check_mark: %s: `%s`\n\n", tool, r.OriginalImage)
+	b.WriteString("| Patched image | Vulnerabilities fixed | Packages updated |\n|---|---|---|\n")
+	fmt.Fprintf(&b, "| `%s` | %d | %d |\n", strings.Join(r.PatchedImage, "`, `"), r.NumFixedVulns, r.UpdatedPackageCount)
+
+	if r.Severity != nil {
+		b.WriteString("\n| Severity | Fixed | Remaining |\n|---|---|---|\n")
+		fixed, remaining := r.Severity.Fixed, r.Severity.Remaining
+		fmt.Fprintf(&b, "| CRITICAL | %d | %d |\n", fixed.Critical, remaining.Critical)
+		fmt.Fprintf(&b, "| HIGH | %d | %d |\n", fixed.High, remaining.High)
+		fmt.Fprintf(&b, "| MEDIUM | %d | %d |\n", fixed.Medium, remaining.Medium)
+		fmt.Fprintf(&b, "| LOW | %d | %d |\n", fixed.Low, remaining.Low)
+		if fixed.Unknown+remaining.Unknown > 0 {
+			fmt.Fprintf(&b, "| UNKNOWN | %d | %d |\n", fixed.Unknown, remaining.Unknown)
+		}
+	}
+
+	if len(r.FixedVulnerabilities) > 0 {
+		shown, truncated := capFixed(r.FixedVulnerabilities, maxFixedInSummary)
+		b.WriteString("\n<details><summary>Fixed vulnerabilities</summary>\n\n")
+		b.WriteString("| Vulnerability | Severity | Packages |\n|---|---|---|\n")
+		for _, vuln := range shown {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", vuln.ID, vuln.Severity, strings.Join(vuln.Packages, "<br>"))
+		}
+		if truncated || r.FixedVulnerabilitiesTruncated {
+			fmt.Fprintf(&b, "\nand %d more\n", r.NumFixedVulns-len(shown))
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	writeWarnings(&b, r.Warnings)
+	return b.String()
+}
+
+func remediationSummary(tool string, r types.Remediation) string {
+	var b strings.Builder
+	icon := ":white_check_mark:"
+	if !r.Verified {
+		icon = ":warning:"
+	}
+	fmt.Fprintf(&b, "### %s %s: `%s`\n\n", icon, tool, r.Image)
+	b.WriteString("| Stage | Status | Detail | Duration |\n|---|---|---|---|\n")
+	for _, stage := range r.Stages {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", stage.Name, stage.Status, stage.Detail, stage.Duration)
+	}
+	if r.Before != nil && r.After != nil {
+		b.WriteString("\n| Severity | Before | After |\n|---|---|---|\n")
+		fmt.Fprintf(&b, "| CRITICAL | %d | %d |\n", r.Before.Critical, r.After.Critical)
+		fmt.Fprintf(&b, "| HIGH | %d | %d |\n", r.Before.High, r.After.High)
+		fmt.Fprintf(&b, "| MEDIUM | %d | %d |\n", r.Before.Medium, r.After.Medium)
+		fmt.Fprintf(&b, "| LOW | %d | %d |\n", r.Before.Low, r.After.Low)
+	}
+	if r.Pinned != nil {
+		fmt.Fprintf(&b, "\nPushed `%s`\n", r.Pinned.Reference)
+	}
+	writeWarnings(&b, r.Warnings)
+	return b.String()
+}
+
+func errorSummary(tool string, e types.ToolError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### :x: %s failed (%s)\n\n```\n%s\n```\n", tool, e.Category, e.Message)
+	if e.Command != nil {
+		fmt.Fprintf(&b, "\nCommand `%s` exited with code %d after %s.\n", e.Command.Command, e.Command.ExitCode, e.Command.Duration)
+	}
+	if e.Recovery != "" {
+		fmt.Fprintf(&b, "\n**Recovery:** %s\n", e.Recovery)
+	}
+	return b.String()
+}
+
+func writeWarnings(b *strings.Builder, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	b.WriteString("\n**Warnings:**\n")
+	for _, w := range warnings {
+		fmt.Fprintf(b, "- %s\n", w)
+	}
+}
+
+// appendFile appends text, followed by a blank line, to the file at path
+func appendFile(path, text string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,102 @@
+package copamcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownSummary(t *testing.T) {
+	t.Run("scan", func(t *testing.T) {
+		summary := markdownSummary(ToolScanContainer, &trivy.ScanResult{
+			Image: "alpine:3.18", ReportPath: "/tmp/reports-1", VulnCount: 12, Platforms: []string{"linux/amd64"}, Duration: "3s",
+		})
+		assert.Contains(t, summary, "### :mag: scan-container: `alpine:3.18`")
+		assert.Contains(t, summary, "| linux/amd64 | 12 | `/tmp/reports-1` | 3s |")
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		summary := markdownSummary(ToolPatchReportBased, types.PatchResult{
+			OriginalImage:       "alpine:3.18",
+			PatchedImage:        []string{"alpine:3.18-patched"},
+			NumFixedVulns:       2,
+			UpdatedPackageCount: 1,
+			Severity: &types.SeveritySummary{
+				Fixed:     types.SeverityCounts{Critical: 1, High: 1},
+				Remaining: types.SeverityCounts{Low: 3},
+			},
+			FixedVulnerabilities: []types.FixedVulnerability{
+				{ID: "CVE-2023-0001", Severity: "CRITICAL", Packages: []string{"pkg:apk/alpine/openssl"}},
+				{ID: "CVE-2023-0002", Severity: "HIGH", Packages: []string{"pkg:apk/alpine/openssl"}},
+			},
+			Warnings: []string{"some platforms were skipped"},
+		})
+		assert.Contains(t, summary, "| `alpine:3.18-patched` | 2 | 1 |")
+		assert.Contains(t, summary, "| CRITICAL | 1 | 0 |")
+		assert.Contains(t, summary, "| LOW | 0 | 3 |")
+		assert.NotContains(t, summary, "UNKNOWN")
+		assert.Contains(t, summary, "| CVE-2023-0001 | CRITICAL | pkg:apk/alpine/openssl |")
+		assert.NotContains(t, summary, "more")
+		assert.Contains(t, summary, "- some platforms were skipped")
+	})
+
+	t.Run("remediation", func(t *testing.T) {
+		summary := markdownSummary(ToolRemediate, types.Remediation{
+			Image:    "alpine:3.18",
+			Verified: true,
+			Stages:   []types.RemediationStage{{Name: types.StageScan, Status: types.StageStatusCompleted, Detail: "found 2 fixable vulnerabilities", Duration: "3s"}},
+			Before:   &types.SeverityCounts{Critical: 1, High: 1},
+			After:    &types.SeverityCounts{},
+		})
+		assert.Contains(t, summary, "### :white_check_mark: remediate: `alpine:3.18`")
+		assert.Contains(t, summary, "| scan | completed | found 2 fixable vulnerabilities | 3s |")
+		assert.Contains(t, summary, "| CRITICAL | 1 | 0 |")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		summary := markdownSummary(ToolPatchComprehensive, types.ToolError{
+			Category: "network",
+			Message:  "registry unreachable",
+			Recovery: "retry after a short delay",
+			Command:  &types.CommandFailure{Command: "copa patch --image alpine", ExitCode: 1, Duration: "2s"},
+		})
+		assert.Contains(t, summary, "### :x: patch-comprehensive failed (network)")
+		assert.Contains(t, summary, "registry unreachable")
+		assert.Contains(t, summary, "Command `copa patch --image alpine` exited with code 1 after 2s.")
+	})
+
+	t.Run("other", func(t *testing.T) {
+		assert.Empty(t, markdownSummary(ToolVersion, types.Ver{Version: "1.0"}))
+	})
+}
+
+func TestWithStepSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv(envGitHubStepSummary, path)
+
+	h := withStepSummary(true, ToolPatchReportBased, resultFileHandler)
+	for _, fail := range []bool{false, true} {
+		_, _, err := h(context.Background(), nil, resultFileParams{Fail: fail})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "### :white_check_mark: patch-report-based: `alpine:3.17`")
+	assert.Contains(t, string(data), "### :x: patch-report-based failed (execution)")
+}
+
+func TestWithStepSummary_Disabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv(envGitHubStepSummary, path)
+
+	_, _, err := withStepSummary(false, ToolPatchReportBased, resultFileHandler)(context.Background(), nil, resultFileParams{})
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+}
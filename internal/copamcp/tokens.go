@@ -0,0 +1,119 @@
+package copamcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// APIToken is a bearer token accepted by the HTTP transports, limited to the tools of its scope
+type APIToken struct {
+	Token   string
+	Scope   Scope
+	Expires time.Time // Zero for a token that does not expire
+}
+
+// neverExpires stands for the expiry of tokens without one, which the bearer token middleware requires
+const neverExpires = 24 * time.Hour
+
+// ParseAPITokens parses values and the lines of file, when set, as scope:token, e.g. scan:abc123.
+// Blank lines and lines starting with # are skipped in file.
+func ParseAPITokens(values []string, file string) ([]APIToken, error) {
+	lines := values
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, copaerrors.NewValidationError("failed to read the API tokens file", err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, copaerrors.NewValidationError("failed to read the API tokens file", err)
+		}
+	}
+
+	tokens := make([]APIToken, 0, len(lines))
+	for i, line := range lines {
+		scope, token, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || token == "" {
+			// The token itself is not echoed, it may be a valid one missing its scope
+			return nil, copaerrors.NewValidationError(fmt.Sprintf("API token %d is not scope:token", i+1), nil,
+				fmt.Sprintf("prefix each token with its scope, one of %s, e.g. scan:<token>", joinScopes(Scopes)))
+		}
+		parsed, err := ParseScope(scope)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, APIToken{Token: token, Scope: parsed})
+	}
+	return tokens, nil
+}
+
+// RequireTokens requires "Authorization: Bearer <token>" with one of tokens on every request to
+// handler. The token's scope is attached to the request, so that each tool call is checked
+// against it (see requireScope).
+func RequireTokens(handler http.Handler, tokens []APIToken) (http.Handler, error) {
+	if len(tokens) == 0 {
+		return nil, copaerrors.NewValidationError("at least one API token is required", nil,
+			"issue tokens with NewAPIToken, or use NewHTTPHandler behind your own authentication")
+	}
+	hashes := make([][sha256.Size]byte, len(tokens))
+	scopes := make([]Scope, len(tokens))
+	for i, token := range tokens {
+		if token.Token == "" {
+			return nil, copaerrors.NewValidationError(fmt.Sprintf("API token %d is empty", i+1), nil)
+		}
+		scope, err := ParseScope(string(token.Scope))
+		if err != nil {
+			return nil, err
+		}
+		hashes[i], scopes[i] = sha256.Sum256([]byte(token.Token)), scope
+	}
+
+	verify := func(ctx context.Context, bearer string, req *http.Request) (*auth.TokenInfo, error) {
+		// Tokens are compared by hash, in constant time, so that neither their contents nor their
+		// lengths leak through timing
+		hash := sha256.Sum256([]byte(bearer))
+		match := -1
+		for i := range hashes {
+			if subtle.ConstantTimeCompare(hash[:], hashes[i][:]) == 1 {
+				match = i
+			}
+		}
+		if match < 0 {
+			return nil, auth.ErrInvalidToken
+		}
+		expires := tokens[match].Expires
+		if expires.IsZero() {
+			expires = time.Now().Add(neverExpires)
+		}
+		return &auth.TokenInfo{Scopes: []string{string(scopes[match])}, Expiration: expires}, nil
+	}
+	return auth.RequireBearerToken(verify, nil)(handler), nil
+}
+
+// requireFullScope rejects the requests to handler whose token is not of ScopeFull. It guards the
+// transports that do not pass the token to the tool calls, whose scope requireScope cannot check.
+func requireFullScope(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := auth.TokenInfoFromContext(r.Context()); info == nil || tokenScope(info.Scopes) != ScopeFull {
+			http.Error(w, fmt.Sprintf("the HTTP+SSE transport needs a token of the %q scope; use the streamable HTTP transport", ScopeFull), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -3,126 +3,766 @@ package copamcp
 import (
 	"context"
 	"fmt"
-	"log"
-	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
 	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/cosign"
+	"github.com/project-copacetic/mcp-server/internal/defectdojo"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	"github.com/project-copacetic/mcp-server/internal/dtrack"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/gitpr"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/kube"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
 	"github.com/project-copacetic/mcp-server/internal/trivy"
 	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
 )
 
 const (
-	dryRun = false
+	// maxFixedInResult caps the fixed vulnerabilities listed in a patch result; the full list is paged with 'list-fixed-vulnerabilities'
+	maxFixedInResult = 50
+	// maxFixedInText caps the fixed vulnerability IDs listed in a patch result's text
+	maxFixedInText = 10
+	// defaultFixedPageLimit and maxFixedPageLimit bound the page size of 'list-fixed-vulnerabilities' and 'list-vulnerabilities'
+	defaultFixedPageLimit = 100
+	maxFixedPageLimit     = 1000
 )
 
+// tools implements the MCP tool handlers using the server configuration
+type tools struct {
+	cfg        *config.Config
+	server     *mcp.Server
+	dtrack     *dtrack.Client
+	defectdojo *defectdojo.Client
+	policy     *policy.Engine
+	verifier   *cosign.Verifier
+	signer     *cosign.Signer
+	gitpr      *gitpr.Client
+	scans      scanGroup
+	// resources tracks the artifacts registered as resources, to remove the stale ones
+	resources resourceRegistry
+	// remediations holds the IDs of the remediations running, so that one is not resumed twice at once
+	remediations sync.Map
+}
+
 // PatchComprehensive performs comprehensive patching of all available platforms
 // NOTE: This tool patches ALL available platforms WITHOUT vulnerability scanning
-// If you want to patch based on vulnerability scan results, use 'scan-container' followed by 'patch-vulnerabilities' instead
-func PatchComprehensive(ctx context.Context, req *mcp.CallToolRequest, params types.ComprehensivePatchParams) (*mcp.CallToolResult, any, error) {
-	copa := copa.New(params, dryRun)
-	_, err := copa.
-		Build().
-		Run(ctx)
+// If you want to patch based on vulnerability scan results, use 'scan-container' followed by 'patch-report-based' instead
+func (t *tools) PatchComprehensive(ctx context.Context, req *mcp.CallToolRequest, params types.ComprehensivePatchParams) (*mcp.CallToolResult, any, error) {
+	v := validate.Comprehensive(params)
+	v.Check("smokeTest", t.validateSmokeTest(params.SmokeTest, nil))
+	// The patch and its push share the call's retry policy
+	retry, err := t.callRetryPolicy(params.Retry)
+	v.Check("retry", err)
+	v.Check("env", subprocess.CheckOverrides(params.Env))
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	ctx = subprocess.WithEnv(ctx, params.Env)
+	params.TimeoutSeconds = t.timeoutSeconds(params.TimeoutSeconds)
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+	patchedImage := copa.DestinationRef(params.Image, params.Tag, params.Destination)
+	if err := t.checkPush(patchedImage, params.Push); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	policyDryRun, err := t.checkPolicy(ctx, req, patchInput(ToolPatchComprehensive, params.Image, patchedImage, params.Push, params.ExportPath, nil, ""))
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	// A command can only be run once, so each attempt builds a fresh one
+	var result *copa.ExecutionResult
+	err = t.retryWith(ctx, req, retry, func() (err error) {
+		result, err = copa.New(params, params.DryRun || policyDryRun).WithBuildkit(t.cfg.BuildkitAddr, t.cfg.BuildkitWait).
+			WithDeferredPush(len(params.SmokeTest) > 0).
+			WithRetry(retry).
+			WithOutputLog(copaOutputLog(ctx, req)).
+			Build().
+			Run(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("patching failed: %w", err)
+		return errorResult(fmt.Errorf("patching failed: %w", err)), nil, nil
+	}
+	var smoke *types.SmokeTestResult
+	if len(params.SmokeTest) > 0 && !params.DryRun && !policyDryRun {
+		if smoke, err = t.smokeTest(ctx, req, params.Retry, params.DockerHost, params.SmokeTest, result); err != nil {
+			return errorResult(err), nil, nil
+		}
+	}
+	if !params.DryRun {
+		t.afterPatch(params.DockerHost, params.Image, result, policyDryRun)
+	}
+	var description string
+	var snippets *types.GitOpsSnippets
+	var pinned *types.PinnedReference
+	if !params.DryRun && !policyDryRun {
+		if params.DraftDescription {
+			description = draftDescription(ctx, req, params.Image, result)
+		}
+		pinned = t.pinReference(ctx, req, params.Retry, params.Push, result)
+		snippets = gitOpsSnippets(params.GitOps, params.Push, result)
 	}
 
-	successMsg := fmt.Sprintf("successful patched: %s", params.Image)
+	successMsg := patchedMessage(params.Image, params.DryRun || policyDryRun, result) + exportMessage(result) + metricsMessage(result) + platformMessage(result.Platforms) + smokeTestMessage(smoke) + warningsMessage(result.Warnings) + descriptionMessage(description) + pinnedMessage(pinned) + gitOpsMessage(snippets)
+	structured := patchResult(params.Image, result)
+	structured.DryRun = params.DryRun || policyDryRun
+	structured.Description = description
+	structured.Pinned = pinned
+	structured.GitOps = snippets
+	structured.SmokeTest = smoke
 	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: successMsg}},
+		Content:           []mcp.Content{&mcp.TextContent{Text: successMsg}},
+		StructuredContent: structured,
 	}, nil, nil
 }
 
 // PatchPlatforms performs platform-selective patching
 // NOTE: This tool should only be used when NO vulnerability scanning is desired and specific platforms need patching
-// If you want to patch based on vulnerability scan results, use 'patch-vulnerabilities' instead
-func PatchPlatformSelective(ctx context.Context, req *mcp.CallToolRequest, params types.PlatformSelectivePatchParams) (*mcp.CallToolResult, any, error) {
+// If you want to patch based on vulnerability scan results, use 'patch-report-based' instead
+func (t *tools) PatchPlatformSelective(ctx context.Context, req *mcp.CallToolRequest, params types.PlatformSelectivePatchParams) (*mcp.CallToolResult, any, error) {
+	v := validate.PlatformSelective(params)
+	v.Check("smokeTest", t.validateSmokeTest(params.SmokeTest, params.Platform))
+	// The patch and its push share the call's retry policy
+	retry, err := t.callRetryPolicy(params.Retry)
+	v.Check("retry", err)
+	v.Check("env", subprocess.CheckOverrides(params.Env))
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	ctx = subprocess.WithEnv(ctx, params.Env)
+	params.TimeoutSeconds = t.timeoutSeconds(params.TimeoutSeconds)
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+	patchedImage := copa.DestinationRef(params.Image, params.Tag, params.Destination)
+	if err := t.checkPush(patchedImage, params.Push); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	policyDryRun, err := t.checkPolicy(ctx, req, patchInput(ToolPatchPlatformSelective, params.Image, patchedImage, params.Push, params.ExportPath, params.Platform, ""))
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
 
-	copa := copa.New(params, dryRun)
-	_, err := copa.
-		BuildWithPlatforms().
-		Run(ctx)
+	var result *copa.ExecutionResult
+	err = t.retryWith(ctx, req, retry, func() (err error) {
+		result, err = copa.New(params, params.DryRun || policyDryRun).WithBuildkit(t.cfg.BuildkitAddr, t.cfg.BuildkitWait).
+			WithDeferredPush(len(params.SmokeTest) > 0).
+			WithRetry(retry).
+			WithOutputLog(copaOutputLog(ctx, req)).
+			BuildWithPlatforms().
+			Run(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("platform patch failed: %w", err)
+		return errorResult(fmt.Errorf("platform patch failed: %w", err)), nil, nil
+	}
+	var smoke *types.SmokeTestResult
+	if len(params.SmokeTest) > 0 && !params.DryRun && !policyDryRun {
+		if smoke, err = t.smokeTest(ctx, req, params.Retry, params.DockerHost, params.SmokeTest, result); err != nil {
+			return errorResult(err), nil, nil
+		}
+	}
+	if !params.DryRun {
+		t.afterPatch(params.DockerHost, params.Image, result, policyDryRun)
+	}
+	var description string
+	var snippets *types.GitOpsSnippets
+	var pinned *types.PinnedReference
+	if !params.DryRun && !policyDryRun {
+		if params.DraftDescription {
+			description = draftDescription(ctx, req, params.Image, result)
+		}
+		pinned = t.pinReference(ctx, req, params.Retry, params.Push, result)
+		snippets = gitOpsSnippets(params.GitOps, params.Push, result)
 	}
 
-	successMsg := fmt.Sprintf("successful patched: %s", params.Image)
+	successMsg := patchedMessage(params.Image, params.DryRun || policyDryRun, result) + exportMessage(result) + metricsMessage(result) + platformMessage(result.Platforms) + smokeTestMessage(smoke) + warningsMessage(result.Warnings) + descriptionMessage(description) + pinnedMessage(pinned) + gitOpsMessage(snippets)
+	structured := patchResult(params.Image, result)
+	structured.DryRun = params.DryRun || policyDryRun
+	structured.Description = description
+	structured.Pinned = pinned
+	structured.GitOps = snippets
+	structured.SmokeTest = smoke
 	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: successMsg}},
+		Content:           []mcp.Content{&mcp.TextContent{Text: successMsg}},
+		StructuredContent: structured,
 	}, nil, nil
 }
 
 // PatchVulnerabilities performs report-based patching using an existing vulnerability report
 // NOTE: This tool requires that 'scan-container' has been run first to generate the vulnerability report
-func PatchReportBased(ctx context.Context, req *mcp.CallToolRequest, params types.ReportBasedPatchParams) (*mcp.CallToolResult, any, error) {
-	copa := copa.New(params, dryRun)
-	result, err := copa.
-		BuildWithReport().
-		Run(ctx)
+func (t *tools) PatchReportBased(ctx context.Context, req *mcp.CallToolRequest, params types.ReportBasedPatchParams) (*mcp.CallToolResult, any, error) {
+	v := validate.ReportBased(params)
+	v.Check("smokeTest", t.validateSmokeTest(params.SmokeTest, nil))
+	// The patch and its push share the call's retry policy
+	retry, err := t.callRetryPolicy(params.Retry)
+	v.Check("retry", err)
+	v.Check("env", subprocess.CheckOverrides(params.Env))
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	ctx = subprocess.WithEnv(ctx, params.Env)
+	params.TimeoutSeconds = t.timeoutSeconds(params.TimeoutSeconds)
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	if err := t.verifyImage(ctx, req, params.Retry, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+	patchedImage := copa.DestinationRef(params.Image, params.Tag, params.Destination)
+	if err := t.checkPush(patchedImage, params.Push); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	policyDryRun, err := t.checkPolicy(ctx, req, patchInput(ToolPatchReportBased, params.Image, patchedImage, params.Push, params.ExportPath, nil, params.ReportPath))
+	if err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	var (
+		patcher *copa.CLI
+		result  *copa.ExecutionResult
+	)
+	err = t.retryWith(ctx, req, retry, func() (err error) {
+		patcher = copa.New(params, params.DryRun || policyDryRun).
+			WithBuildkit(t.cfg.BuildkitAddr, t.cfg.BuildkitWait).
+			WithRetention(t.cfg.KeepReports, t.cfg.KeepVex).
+			WithDeferredPush(len(params.SmokeTest) > 0).
+			WithRetry(retry).
+			WithOutputLog(copaOutputLog(ctx, req))
+		result, err = patcher.
+			BuildWithReport().
+			Run(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("patching failed: %w", err)
+		return errorResult(fmt.Errorf("patching failed: %w", err)), nil, nil
+	}
+	var smoke *types.SmokeTestResult
+	if len(params.SmokeTest) > 0 && !params.DryRun && !policyDryRun {
+		if smoke, err = t.smokeTest(ctx, req, params.Retry, params.DockerHost, params.SmokeTest, result); err != nil {
+			// Intermediate artifacts are not kept for an image that was not pushed
+			_ = patcher.Cleanup()
+			return errorResult(err), nil, nil
+		}
+	}
+	if !params.DryRun {
+		t.afterPatch(params.DockerHost, params.Image, result, policyDryRun)
+	}
+	if params.ScanRemaining && !params.DryRun && !policyDryRun {
+		t.scanRemaining(ctx, req, params, patcher, result)
+	}
+
+	var description string
+	var snippets *types.GitOpsSnippets
+	var pinned *types.PinnedReference
+	if !params.DryRun && !policyDryRun {
+		if params.DraftDescription {
+			description = draftDescription(ctx, req, params.Image, result)
+		}
+		pinned = t.pinReference(ctx, req, params.Retry, params.Push, result)
+		snippets = gitOpsSnippets(params.GitOps, params.Push, result)
+	}
+
+	successMsg := patchedMessage(params.Image, params.DryRun || policyDryRun, result) + fmt.Sprintf("\n vulnerabilities fixed: %d packages updated: %d", result.FixedVulnerabilityCount, result.UpdatedPackageCount) +
+		severityMessage(result.Severity) + remainingMessage(result.Remaining) + fixedMessage(result.FixedVulnerabilities) + exportMessage(result) + metricsMessage(result) + platformMessage(result.Platforms) + smokeTestMessage(smoke) + warningsMessage(result.Warnings)
+	structured := patchResult(params.Image, result)
+	structured.DryRun = params.DryRun || policyDryRun
+	structured.ReportPath = params.ReportPath
+	structured.Description = description
+	structured.Pinned = pinned
+	structured.GitOps = snippets
+	structured.SmokeTest = smoke
+	var content []mcp.Content
+	if result.VexPath != "" {
+		if patcher.KeepVex() {
+			content, err = t.addVexResource(result.VexPath)
+			successMsg += fmt.Sprintf("\n vex document: %s", result.VexPath)
+			structured.VexPath = result.VexPath
+			structured.VexURI = vexURIPrefix + artifactID(result.VexPath)
+		} else {
+			var embedded *mcp.EmbeddedResource
+			embedded, err = embedVex(result.VexPath)
+			content = []mcp.Content{embedded}
+		}
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+	}
+
+	// Intermediate artifacts are removed only after the VEX document has been read into the result.
+	// The image is already patched, so leftover files are reported rather than failing the call.
+	if err := patcher.Cleanup(); err != nil {
+		warning := fmt.Sprintf("failed to clean up patch artifacts: %v", err)
+		structured.Warnings = append(structured.Warnings, warning)
+		successMsg += warningsMessage([]string{warning})
+	}
+
+	successMsg += descriptionMessage(description) + pinnedMessage(pinned) + gitOpsMessage(snippets)
+
+	if err := patcher.CheckBudget(result); err != nil {
+		return errorResult(err), nil, nil
 	}
 
-	successMsg := fmt.Sprintf("successful patched: %s\n vulnerabilities fixed: %d packages updated: %d", params.Image, result.FixedVulnerabilityCount, result.UpdatedPackageCount)
 	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: successMsg}},
+		Content:           append([]mcp.Content{&mcp.TextContent{Text: successMsg}}, content...),
+		StructuredContent: structured,
+	}, nil, nil
+}
+
+// timeoutSeconds returns the time limit, in seconds, of a call's trivy or copa command: the call's
+// seconds when set, otherwise the server's ToolTimeout rounded up to a whole second
+func (t *tools) timeoutSeconds(seconds int) int {
+	if seconds > 0 || t.cfg.ToolTimeout <= 0 {
+		return seconds
+	}
+	return int((t.cfg.ToolTimeout + time.Second - 1) / time.Second)
+}
+
+// copaOutputLog logs each line of copa's output to the session at debug level, so clients can
+// follow a long patch
+func copaOutputLog(ctx context.Context, req *mcp.CallToolRequest) func(line string) {
+	if req == nil || req.Session == nil {
+		return nil
+	}
+	return func(line string) {
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
+			Data:   line,
+			Level:  "debug",
+			Logger: "copa",
+		})
+	}
+}
+
+// afterPatch reports a finished patch to the configured integrations. The patched image of a dry
+// run forced by the policies does not exist, so it is only flagged in the result's warnings.
+func (t *tools) afterPatch(dockerHost, image string, result *copa.ExecutionResult, policyDryRun bool) {
+	if policyDryRun {
+		result.Warnings = append(result.Warnings, policyDryRunWarning)
+		return
+	}
+	t.uploadSBOMs(dockerHost, result.PatchedImage)
+	t.exportPatch(dockerHost, image, result.PatchedImage)
+}
+
+// patchedMessage is the first line of the text result of a patch, with the copa command of a dry run
+func patchedMessage(image string, dryRun bool, result *copa.ExecutionResult) string {
+	if dryRun {
+		return fmt.Sprintf("dry run, nothing was patched: %s\n copa command: %s", image, strings.Join(result.Command, " "))
+	}
+	return fmt.Sprintf("successful patched: %s", image)
+}
+
+// metricsMessage describes how long the patch took and the disk space it used
+func metricsMessage(result *copa.ExecutionResult) string {
+	msg := fmt.Sprintf("\n patch duration: %s", result.Duration.Round(time.Second))
+	if result.PeakTempDiskBytes > 0 {
+		msg += fmt.Sprintf(", peak temp disk usage: %s", copa.FormatBytes(uint64(result.PeakTempDiskBytes)))
+	}
+	return msg
+}
+
+// warningsMessage lists the non-fatal problems encountered while patching
+func warningsMessage(warnings []string) string {
+	var msg strings.Builder
+	for _, w := range warnings {
+		msg.WriteString(fmt.Sprintf("\n warning: %s", w))
+	}
+	return msg.String()
+}
+
+// exportMessage describes where the patched image was exported or imported to, if it was
+func exportMessage(result *copa.ExecutionResult) string {
+	var msg string
+	if result.ExportPath != "" {
+		msg += fmt.Sprintf("\n patched image %s exported to: %s", result.PatchedImage, result.ExportPath)
+	}
+	if result.ContainerdNamespace != "" {
+		msg += fmt.Sprintf("\n patched image %s imported into containerd namespace: %s", result.PatchedImage, result.ContainerdNamespace)
+	}
+	return msg
+}
+
+// patchResult summarizes a successful patch for the tool's structured content
+func patchResult(image string, result *copa.ExecutionResult) types.PatchResult {
+	patch := types.PatchResult{
+		OriginalImage:       image,
+		PatchedImage:        []string{result.PatchedImage},
+		ExportPath:          result.ExportPath,
+		ContainerdNamespace: result.ContainerdNamespace,
+		NumFixedVulns:       result.FixedVulnerabilityCount,
+		UpdatedPackageCount: result.UpdatedPackageCount,
+		VexGenerated:        result.VexPath != "",
+		Platforms:           result.Platforms,
+		Severity:            result.Severity,
+		Remaining:           result.Remaining,
+		Warnings:            result.Warnings,
+		Command:             result.Command,
+		Metrics: &types.PatchMetrics{
+			PatchDuration:     result.Duration.Round(time.Millisecond).String(),
+			PatchedImageBytes: result.PatchedImageBytes,
+			ExportedBytes:     result.ExportedBytes,
+			PeakTempDiskBytes: result.PeakTempDiskBytes,
+		},
+	}
+	patch.FixedVulnerabilities, patch.FixedVulnerabilitiesTruncated = capFixed(result.FixedVulnerabilities, maxFixedInResult)
+	return patch
+}
+
+// capFixed returns at most limit vulnerabilities and whether the list was truncated
+func capFixed(fixed []types.FixedVulnerability, limit int) ([]types.FixedVulnerability, bool) {
+	if len(fixed) <= limit {
+		return fixed, false
+	}
+	return fixed[:limit], true
+}
+
+// fixedMessage lists the IDs of the fixed vulnerabilities, capped at maxFixedInText
+func fixedMessage(fixed []types.FixedVulnerability) string {
+	if len(fixed) == 0 {
+		return ""
+	}
+
+	shown, truncated := capFixed(fixed, maxFixedInText)
+	ids := make([]string, len(shown))
+	for i, vuln := range shown {
+		ids[i] = vuln.ID
+	}
+	msg := fmt.Sprintf("\n fixed vulnerabilities: %s", strings.Join(ids, ", "))
+	if truncated {
+		msg += fmt.Sprintf(" and %d more", len(fixed)-len(shown))
+	}
+	return msg
+}
+
+// severityMessage describes the fixed and remaining vulnerabilities by severity
+func severityMessage(summary *types.SeveritySummary) string {
+	if summary == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n fixed by severity: %s\n remaining by severity: %s", formatSeverityCounts(summary.Fixed), formatSeverityCounts(summary.Remaining))
+}
+
+// scanRemaining scans the patched image for every package type and classifies the vulnerabilities
+// that remain from that scan. The image is already patched, so a failed scan is only a warning.
+func (t *tools) scanRemaining(ctx context.Context, req *mcp.CallToolRequest, params types.ReportBasedPatchParams, patcher *copa.CLI, result *copa.ExecutionResult) {
+	var report []byte
+	err := t.retry(ctx, req, params.Retry, func() (err error) {
+		report, err = trivy.ScanAllJSON(ctx, params.DockerHost, result.PatchedImage)
+		return err
+	})
+	var findings []trivy.Finding
+	if err == nil {
+		findings, err = trivy.Findings(report)
+	}
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("scanning the patched image for remaining vulnerabilities failed: %v", err))
+		return
+	}
+	result.Remaining = patcher.ClassifyRemaining(findings, result.FixedVulnerabilities, types.RemainingSourceScan)
+}
+
+// remainingMessage summarizes why vulnerabilities remain after a report-based patch
+func remainingMessage(remaining *types.RemainingVulns) string {
+	if remaining == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n remaining (from %s): no fix available %d, language/library %d, excluded %d, fix not applied %d",
+		remaining.Source, remaining.NoFixAvailable.Count, remaining.Library.Count, remaining.Excluded.Count, remaining.NotApplied.Count)
+}
+
+func formatSeverityCounts(c types.SeverityCounts) string {
+	counts := fmt.Sprintf("CRITICAL %d, HIGH %d, MEDIUM %d, LOW %d", c.Critical, c.High, c.Medium, c.Low)
+	if c.Unknown > 0 {
+		counts += fmt.Sprintf(", UNKNOWN %d", c.Unknown)
+	}
+	return counts
+}
+
+// platformMessage lists the outcome of each platform of a multi-platform patch
+func platformMessage(platforms []types.PlatformResult) string {
+	var msg strings.Builder
+	for _, p := range platforms {
+		if p.Status != types.PlatformStatusPatched {
+			msg.WriteString(fmt.Sprintf("\n %s: %s", p.Platform, p.Status))
+			continue
+		}
+		msg.WriteString(fmt.Sprintf("\n %s: %s (%d vulnerabilities fixed)", p.Platform, p.Status, p.FixedVulns))
+	}
+	return msg.String()
+}
+
+// ListFixedVulnerabilities pages through the vulnerabilities an OpenVEX document marks as fixed,
+// for patch results whose fixed vulnerability list was truncated
+func (t *tools) ListFixedVulnerabilities(ctx context.Context, req *mcp.CallToolRequest, params types.ListFixedVulnerabilitiesParams) (*mcp.CallToolResult, any, error) {
+	if params.VexPath == "" {
+		return errorResult(copaerrors.NewValidationError("vexPath parameter is required", nil)), nil, nil
+	}
+	if params.Offset < 0 {
+		return errorResult(copaerrors.NewValidationError("offset must not be negative", nil)), nil, nil
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultFixedPageLimit
+	}
+	limit = min(limit, maxFixedPageLimit)
+
+	fixed, err := copa.ListFixedVulnerabilities(params.VexPath)
+	if err != nil {
+		return errorResult(copaerrors.NewValidationError("failed to read vex document", err,
+			"pass the OpenVEX document path returned by 'patch-report-based' (kept with keepVex)")), nil, nil
+	}
+
+	page := types.FixedVulnerabilityPage{Total: len(fixed), Vulnerabilities: []types.FixedVulnerability{}}
+	if params.Offset < len(fixed) {
+		end := min(params.Offset+limit, len(fixed))
+		page.Vulnerabilities = fixed[params.Offset:end]
+		if end < len(fixed) {
+			page.NextOffset = end
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("fixed vulnerabilities %d-%d of %d", min(params.Offset+1, len(fixed)), params.Offset+len(page.Vulnerabilities), len(fixed)))
+	for _, vuln := range page.Vulnerabilities {
+		msg.WriteString(fmt.Sprintf("\n %s", vuln.ID))
+		if vuln.Severity != "" {
+			msg.WriteString(fmt.Sprintf(" [%s]", vuln.Severity))
+		}
+		if len(vuln.Packages) > 0 {
+			msg.WriteString(fmt.Sprintf(": %s", strings.Join(vuln.Packages, ", ")))
+		}
+	}
+	if page.NextOffset > 0 {
+		msg.WriteString(fmt.Sprintf("\nnext page: offset %d", page.NextOffset))
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: msg.String()}},
+		StructuredContent: page,
 	}, nil, nil
 }
 
 // ScanContainer performs vulnerability scanning on a container image using Trivy
-func ScanContainer(ctx context.Context, req *mcp.CallToolRequest, args trivy.ScanParams) (*mcp.CallToolResult, any, error) {
-	// Input validation
-	if args.Image == "" {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{&mcp.TextContent{Text: "image parameter is required"}},
-		}, nil, fmt.Errorf("image parameter is required")
+func (t *tools) ScanContainer(ctx context.Context, req *mcp.CallToolRequest, args trivy.ScanParams) (*mcp.CallToolResult, any, error) {
+	v := validate.Scan(args)
+	v.Check("retry", t.validateRetry(args.Retry))
+	v.Check("env", subprocess.CheckOverrides(args.Env))
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
 	}
+	ctx = subprocess.WithEnv(ctx, args.Env)
+	args.TimeoutSeconds = t.timeoutSeconds(args.TimeoutSeconds)
 
-	req.Session.Log(ctx, &mcp.LoggingMessageParams{
+	// trivy reads the images of a containerd namespace without the Docker daemon
+	if args.ContainerdNamespace == "" {
+		if err := docker.CheckDaemon(ctx, args.DockerHost); err != nil {
+			return errorResult(err), nil, nil
+		}
+	}
+
+	if err := t.verifyImage(ctx, req, args.Retry, args.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
 		Data:   fmt.Sprintf("Starting vulnerability scan for image: %s", args.Image),
 		Level:  "info",
 		Logger: "trivy",
 	})
 
-	// Perform the vulnerability scan
-	scanResult, err := trivy.Scan(ctx, req.Session, args)
+	// Perform the vulnerability scan, or wait for the same scan requested by another call
+	scanResult, shared, err := t.scans.do(ctx, scanKey(args), func() (result *trivy.ScanResult, err error) {
+		err = t.retry(ctx, req, args.Retry, func() (err error) {
+			result, err = trivy.Scan(ctx, req.Session, args)
+			return err
+		})
+		return result, err
+	})
 	if err != nil {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Vulnerability scan failed: %v", err)}},
-		}, nil, err
+		return errorResult(fmt.Errorf("vulnerability scan failed: %w", err)), nil, nil
+	}
+	// The call that ran a shared scan already reported it to the integrations
+	if !shared {
+		t.uploadSBOMs(args.DockerHost, args.Image)
+		t.exportScan(args.Image, scanResult.ReportPath)
 	}
 
+	link := t.addReportResource(args.Image, scanResult.ReportPath)
+	scanResult.ReportURI = link.URI
+
 	// Format the scan results with clearer workflow guidance
 	var resultMsg strings.Builder
 	resultMsg.WriteString(fmt.Sprintf("Vulnerability scan completed for image: %s\n", scanResult.Image))
 	resultMsg.WriteString(fmt.Sprintf("Total vulnerabilities found: %d\n", scanResult.VulnCount))
-	resultMsg.WriteString(fmt.Sprintf("Scanned platforms: %s\n", strings.Join(scanResult.Platforms, ", ")))
+	if scanResult.AttachedReport != "" {
+		resultMsg.WriteString(fmt.Sprintf("Reused the scan report attached to the image: %s\n", scanResult.AttachedReport))
+	} else {
+		resultMsg.WriteString(fmt.Sprintf("Scanned platforms: %s\n", strings.Join(scanResult.Platforms, ", ")))
+	}
 	resultMsg.WriteString(fmt.Sprintf("Report directory: %s\n", scanResult.ReportPath))
+	resultMsg.WriteString(fmt.Sprintf("Report resource: %s\n", scanResult.ReportURI))
+	resultMsg.WriteString(fmt.Sprintf("Scan duration: %s\n", scanResult.Duration))
+	if scanResult.GitLabReport != "" {
+		resultMsg.WriteString(fmt.Sprintf("GitLab container scanning report: %s\n", scanResult.GitLabReport))
+	}
+	for _, w := range scanResult.Warnings {
+		resultMsg.WriteString(fmt.Sprintf("Warning: %s\n", w))
+	}
 	resultMsg.WriteString("\n=== NEXT STEPS ===")
-	resultMsg.WriteString("\nTo patch vulnerabilities found in this scan, use the 'patch-vulnerabilities' tool with the above report directory path.")
-	resultMsg.WriteString("\n\nNOTE: Do NOT use 'patch-platforms' or 'patch-comprehensive' if you want to patch based on these scan results.")
+	resultMsg.WriteString("\nTo patch vulnerabilities found in this scan, use the 'patch-report-based' tool with the above report directory path.")
+	resultMsg.WriteString("\nTo review the vulnerabilities page by page, use the 'list-vulnerabilities' tool with the same report directory path.")
+	resultMsg.WriteString("\n\nNOTE: Do NOT use 'patch-platform-selective' or 'patch-comprehensive' if you want to patch based on these scan results.")
 	resultMsg.WriteString("\nThose tools are for patching WITHOUT vulnerability scanning.")
 
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: resultMsg.String()}, link},
+		StructuredContent: scanResult,
+	}, nil, nil
+}
+
+// PullImage pulls an image into the local Docker daemon, logging docker's progress to the session
+func (t *tools) PullImage(ctx context.Context, req *mcp.CallToolRequest, params types.PullImageParams) (*mcp.CallToolResult, any, error) {
+	if params.Image == "" {
+		return errorResult(copaerrors.NewValidationError("image parameter is required", nil)), nil, nil
+	}
+
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	if err := t.verifyImage(ctx, req, nil, params.Image); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	err := docker.Pull(ctx, params.DockerHost, params.Image, params.Platform, func(line string) {
+		joblog.Log(ctx, req.Session, &mcp.LoggingMessageParams{
+			Data:   line,
+			Level:  "info",
+			Logger: "docker",
+		})
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("pull failed: %w", err)), nil, nil
+	}
+
+	successMsg := fmt.Sprintf("successfully pulled: %s", params.Image)
+	if params.Platform != "" {
+		successMsg += fmt.Sprintf(" (%s)", params.Platform)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: successMsg}},
+	}, nil, nil
+}
+
+// ListClusterImages lists the images running in a Kubernetes cluster, as a starting point for scanning and patching
+func (t *tools) ListClusterImages(ctx context.Context, req *mcp.CallToolRequest, params types.ListClusterImagesParams) (*mcp.CallToolResult, any, error) {
+	list, err := kube.ListImages(ctx, params)
+	if err != nil {
+		return errorResult(fmt.Errorf("listing cluster images failed: %w", err)), nil, nil
+	}
+
+	scope := "all namespaces"
+	if params.Namespace != "" {
+		scope = "namespace " + params.Namespace
+	}
+	var resultMsg strings.Builder
+	resultMsg.WriteString(fmt.Sprintf("Found %d images in %d pods in %s", len(list.Images), list.Pods, scope))
+	for _, image := range list.Images {
+		resultMsg.WriteString(fmt.Sprintf("\n %s: %d pods (%s)", image.Image, image.Pods, strings.Join(image.Namespaces, ", ")))
+	}
+	if len(list.Images) > 0 {
+		resultMsg.WriteString("\n\nUse 'scan-container' on these images to find vulnerabilities to patch.")
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: resultMsg.String()}},
+		StructuredContent: list,
+	}, nil, nil
+}
+
+// RemoveImage removes local images and optionally prunes dangling images left behind by patching
+func (t *tools) RemoveImage(ctx context.Context, req *mcp.CallToolRequest, params types.RemoveImageParams) (*mcp.CallToolResult, any, error) {
+	if len(params.Images) == 0 && !params.PruneDangling {
+		return errorResult(copaerrors.NewValidationError("at least one image or pruneDangling is required", nil)), nil, nil
+	}
+
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	var resultMsg strings.Builder
+	if len(params.Images) > 0 {
+		output, err := docker.RemoveImages(ctx, params.DockerHost, params.Images, params.Force)
+		if err != nil {
+			return errorResult(fmt.Errorf("remove failed: %w", err)), nil, nil
+		}
+		resultMsg.WriteString(fmt.Sprintf("Removed images: %s\n%s\n", strings.Join(params.Images, ", "), output))
+	}
+
+	if params.PruneDangling {
+		output, err := docker.PruneDanglingImages(ctx, params.DockerHost)
+		if err != nil {
+			return errorResult(fmt.Errorf("prune failed: %w", err)), nil, nil
+		}
+		resultMsg.WriteString(fmt.Sprintf("Pruned dangling images:\n%s\n", output))
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: resultMsg.String()}},
 	}, nil, nil
 }
 
-func Version(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, any, error) {
-	cmd := exec.Command("copa", "--version")
+func (t *tools) Version(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, any, error) {
+	if _, err := copaexec.LookPath(copaexec.Copa); err != nil {
+		return errorResult(copaerrors.NewSystemError("copa CLI not found", err,
+			"install Copacetic (https://project-copacetic.github.io/copacetic/website/installation)", copaexec.NotFoundHint(copaexec.Copa))), nil, nil
+	}
+
+	cmd := copaexec.Command(ctx, copaexec.Copa, "--version")
 	output, err := cmd.Output()
 	if err != nil {
-		log.Fatal(err)
+		return errorResult(copaerrors.NewExecutionError("copa --version failed", err)), nil, nil
 	}
 	version := string(output)
+	ver := types.Ver{Version: strings.TrimSpace(version), Missing: toolchain.Missing(ctx)}
+	if v, ok := toolchain.Installed(ctx, copaexec.Trivy); ok {
+		ver.Trivy = v.String()
+		version += fmt.Sprintf("trivy version %s\n", ver.Trivy)
+	}
+	if len(ver.Missing) > 0 {
+		version += "Unsupported features:\n  - " + strings.Join(ver.Missing, "\n  - ") + "\n"
+	}
 	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: version}},
+		Content:           []mcp.Content{&mcp.TextContent{Text: version}},
+		StructuredContent: ver,
 	}, nil, nil
 }
 
-func WorkflowGuide(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, any, error) {
+func (t *tools) WorkflowGuide(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, any, error) {
 	guidance := getWorkflowGuidance()
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: guidance}},
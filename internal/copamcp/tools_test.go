@@ -0,0 +1,127 @@
+package copamcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformMessage(t *testing.T) {
+	msg := platformMessage([]types.PlatformResult{
+		{Platform: "linux/amd64", Status: types.PlatformStatusPatched, FixedVulns: 3},
+		{Platform: "windows/amd64", Status: types.PlatformStatusSkipped},
+	})
+
+	assert.Equal(t, "\n linux/amd64: patched (3 vulnerabilities fixed)\n windows/amd64: skipped", msg)
+	assert.Empty(t, platformMessage(nil))
+}
+
+func TestSeverityMessage(t *testing.T) {
+	msg := severityMessage(&types.SeveritySummary{
+		Fixed:     types.SeverityCounts{Critical: 2, High: 5, Medium: 3, Low: 1},
+		Remaining: types.SeverityCounts{High: 1, Unknown: 2},
+	})
+
+	assert.Equal(t, "\n fixed by severity: CRITICAL 2, HIGH 5, MEDIUM 3, LOW 1\n remaining by severity: CRITICAL 0, HIGH 1, MEDIUM 0, LOW 0, UNKNOWN 2", msg)
+	assert.Empty(t, severityMessage(nil))
+}
+
+func TestRemainingMessage(t *testing.T) {
+	msg := remainingMessage(&types.RemainingVulns{
+		Source:         types.RemainingSourceScan,
+		NoFixAvailable: types.RemainingClass{Count: 4},
+		Library:        types.RemainingClass{Count: 2},
+		NotApplied:     types.RemainingClass{Count: 1},
+	})
+
+	assert.Equal(t, "\n remaining (from scan): no fix available 4, language/library 2, excluded 0, fix not applied 1", msg)
+	assert.Empty(t, remainingMessage(nil))
+}
+
+func TestFixedMessage(t *testing.T) {
+	fixed := make([]types.FixedVulnerability, 12)
+	for i := range fixed {
+		fixed[i].ID = fmt.Sprintf("CVE-2023-%04d", i)
+	}
+
+	msg := fixedMessage(fixed)
+
+	assert.Contains(t, msg, "CVE-2023-0000, CVE-2023-0001")
+	assert.NotContains(t, msg, "CVE-2023-0010")
+	assert.True(t, strings.HasSuffix(msg, "and 2 more"))
+	assert.Empty(t, fixedMessage(nil))
+}
+
+func TestCapFixed(t *testing.T) {
+	fixed := make([]types.FixedVulnerability, 3)
+
+	capped, truncated := capFixed(fixed, 2)
+	assert.Len(t, capped, 2)
+	assert.True(t, truncated)
+
+	capped, truncated = capFixed(fixed, 3)
+	assert.Len(t, capped, 3)
+	assert.False(t, truncated)
+}
+
+func TestListFixedVulnerabilities_Paging(t *testing.T) {
+	doc := vex.New()
+	for i := range 5 {
+		doc.Statements = append(doc.Statements, vex.Statement{
+			Vulnerability: vex.Vulnerability{Name: vex.VulnerabilityID(fmt.Sprintf("CVE-2023-%04d", i))},
+			Products:      []vex.Product{{Component: vex.Component{ID: "pkg:oci/alpine"}}},
+			Status:        vex.StatusFixed,
+		})
+	}
+	vexPath := filepath.Join(t.TempDir(), "vex.json")
+	f, err := os.Create(vexPath)
+	require.NoError(t, err)
+	require.NoError(t, doc.ToJSON(f))
+	require.NoError(t, f.Close())
+
+	tl := &tools{}
+	res, _, err := tl.ListFixedVulnerabilities(context.Background(), nil, types.ListFixedVulnerabilitiesParams{VexPath: vexPath, Offset: 2, Limit: 2})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	page, ok := res.StructuredContent.(types.FixedVulnerabilityPage)
+	require.True(t, ok)
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, 4, page.NextOffset)
+	require.Len(t, page.Vulnerabilities, 2)
+	assert.Equal(t, "CVE-2023-0002", page.Vulnerabilities[0].ID)
+
+	res, _, err = tl.ListFixedVulnerabilities(context.Background(), nil, types.ListFixedVulnerabilitiesParams{VexPath: vexPath, Offset: 4})
+	require.NoError(t, err)
+	page = res.StructuredContent.(types.FixedVulnerabilityPage)
+	assert.Len(t, page.Vulnerabilities, 1)
+	assert.Zero(t, page.NextOffset)
+}
+
+func TestWarningsMessage(t *testing.T) {
+	assert.Empty(t, warningsMessage(nil))
+	assert.Equal(t, "\n warning: a\n warning: b", warningsMessage([]string{"a", "b"}))
+}
+
+func TestPatchResult_Warnings(t *testing.T) {
+	result := patchResult("alpine:3.18", &copa.ExecutionResult{PatchedImage: "alpine:3.18-patched", Warnings: []string{"skipped windows/amd64"}})
+	assert.Equal(t, []string{"skipped windows/amd64"}, result.Warnings)
+}
+
+func TestMetricsMessage(t *testing.T) {
+	msg := metricsMessage(&copa.ExecutionResult{Duration: 90 * time.Second, PeakTempDiskBytes: 3 << 30})
+	assert.Equal(t, "\n patch duration: 1m30s, peak temp disk usage: 3.0 GiB", msg)
+
+	msg = metricsMessage(&copa.ExecutionResult{Duration: 2 * time.Second})
+	assert.Equal(t, "\n patch duration: 2s", msg)
+}
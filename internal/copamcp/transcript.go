@@ -0,0 +1,146 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/transcript"
+)
+
+// recordTranscript appends every tool call and its result to the transcript of rec
+func recordTranscript(rec *transcript.Recorder) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			call, ok := req.(*mcp.CallToolRequest)
+			if !ok {
+				return next(ctx, method, req)
+			}
+
+			start := time.Now()
+			res, err := next(ctx, method, req)
+			entry := transcript.Entry{
+				Time:      start.UTC(),
+				Tool:      call.Params.Name,
+				Arguments: call.Params.Arguments,
+				Duration:  time.Since(start).Round(time.Millisecond).String(),
+			}
+			switch result := res.(type) {
+			case *mcp.CallToolResult:
+				entry.IsError = result.IsError
+				entry.Text = resultText(result)
+				if result.StructuredContent != nil {
+					entry.Structured, _ = json.Marshal(result.StructuredContent)
+				}
+			case nil:
+				entry.IsError = true
+				if err != nil {
+					entry.Text = err.Error()
+				}
+			}
+			if err := rec.Record(entry); err != nil {
+				logf("warning", "failed to record '%s' to the transcript %s: %v", call.Params.Name, rec.Path(), err)
+			}
+			return res, err
+		}
+	}
+}
+
+// ReplayResult is the outcome of replaying a recorded tool call
+type ReplayResult struct {
+	Entry   transcript.Entry
+	IsError bool     // Whether the replayed call failed
+	Changed []string // Top-level fields of the structured result whose values changed
+}
+
+// Regressed reports whether the replayed call failed where the recorded one succeeded, or the reverse
+func (r ReplayResult) Regressed() bool {
+	return r.IsError != r.Entry.IsError
+}
+
+// Replay calls the tools of entries in order on a server with cfg, and compares each result with
+// the recorded one. In mock mode the calls are simulated by the stubs, otherwise they run again
+// for real. Each result is written to out as it completes.
+func Replay(ctx context.Context, version string, cfg *config.Config, entries []transcript.Entry, out io.Writer) ([]ReplayResult, error) {
+	replayCfg := *cfg
+	replayCfg.Transcript = ""
+	session, err := Connect(ctx, NewServer(version, &replayCfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the server: %w", err)
+	}
+	defer session.Close()
+
+	results := make([]ReplayResult, 0, len(entries))
+	for _, entry := range entries {
+		var args map[string]any
+		if len(entry.Arguments) > 0 {
+			if err := json.Unmarshal(entry.Arguments, &args); err != nil {
+				return results, fmt.Errorf("call %d: invalid arguments: %w", entry.Seq, err)
+			}
+		}
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: entry.Tool, Arguments: args})
+		result := ReplayResult{Entry: entry, IsError: err != nil || res.IsError}
+		if err == nil && !res.IsError && !entry.IsError {
+			result.Changed = changedFields(entry.Structured, res.StructuredContent)
+		}
+		results = append(results, result)
+		fmt.Fprintln(out, replayLine(result))
+	}
+	return results, nil
+}
+
+// volatileFields are the fields of structured results expected to differ between runs
+var volatileFields = []string{"duration", "metrics", "reportPath", "vexPath", "sbomPath", "sbomUri", "started", "finished", "time"}
+
+// changedFields returns the top-level fields of the recorded structured result whose values differ
+// in the replayed one, ignoring the volatile fields
+func changedFields(recorded json.RawMessage, replayed any) []string {
+	if len(recorded) == 0 || replayed == nil {
+		return nil
+	}
+	var before, after map[string]any
+	if json.Unmarshal(recorded, &before) != nil {
+		return nil
+	}
+	data, err := json.Marshal(replayed)
+	if err != nil || json.Unmarshal(transcript.Redact(data), &after) != nil {
+		return nil
+	}
+
+	var changed []string
+	for _, key := range slices.Sorted(maps.Keys(before)) {
+		if !slices.Contains(volatileFields, key) && !reflect.DeepEqual(before[key], after[key]) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// replayLine describes the outcome of a replayed call
+func replayLine(r ReplayResult) string {
+	outcome := func(isError bool) string {
+		if isError {
+			return "error"
+		}
+		return "ok"
+	}
+	line := fmt.Sprintf("#%d %s: %s", r.Entry.Seq, r.Entry.Tool, outcome(r.IsError))
+	switch {
+	case r.Regressed():
+		line += fmt.Sprintf(" (REGRESSION: recorded %s)", outcome(r.Entry.IsError))
+	case len(r.Changed) > 0:
+		line += fmt.Sprintf(" (changed: %s)", strings.Join(r.Changed, ", "))
+	}
+	if r.Entry.Redactions() {
+		line += " [arguments had credentials redacted]"
+	}
+	return line
+}
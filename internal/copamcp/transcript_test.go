@@ -0,0 +1,65 @@
+package copamcp
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/transcript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript_RecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Transcript = filepath.Join(t.TempDir(), "transcript.jsonl")
+
+	session, err := Connect(ctx, NewServer("test", cfg))
+	require.NoError(t, err)
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: ToolWorkflowGuide, Arguments: map[string]any{}})
+	require.NoError(t, err)
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: ToolScanContainer, Arguments: map[string]any{"image": "Not An Image"}})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	session.Close()
+
+	entries, err := transcript.Load(cfg.Transcript)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, ToolWorkflowGuide, entries[0].Tool)
+	assert.False(t, entries[0].IsError)
+	assert.Contains(t, entries[0].Text, "WORKFLOW GUIDANCE")
+	assert.Equal(t, ToolScanContainer, entries[1].Tool)
+	assert.True(t, entries[1].IsError)
+	assert.JSONEq(t, `{"image":"Not An Image"}`, string(entries[1].Arguments))
+
+	var out strings.Builder
+	results, err := Replay(ctx, "test", cfg, entries, &out)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		assert.False(t, r.Regressed(), r.Entry.Tool)
+	}
+	assert.Equal(t, "#1 workflow-guide: ok\n#2 scan-container: error\n", out.String())
+
+	// Replaying does not record to the transcript
+	entries, err = transcript.Load(cfg.Transcript)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries[1].IsError = false
+	results, err = Replay(ctx, "test", cfg, entries[1:], &out)
+	require.NoError(t, err)
+	assert.True(t, results[0].Regressed())
+}
+
+func TestChangedFields(t *testing.T) {
+	recorded := json.RawMessage(`{"patchedImage":["nginx:1.25-patched"],"numFixedVulns":3,"metrics":{"patchDuration":"1s"}}`)
+	assert.Empty(t, changedFields(recorded, map[string]any{"patchedImage": []string{"nginx:1.25-patched"}, "numFixedVulns": 3, "metrics": map[string]any{"patchDuration": "2s"}}))
+	assert.Equal(t, []string{"numFixedVulns"}, changedFields(recorded, map[string]any{"patchedImage": []string{"nginx:1.25-patched"}, "numFixedVulns": 2}))
+}
@@ -0,0 +1,135 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/validate"
+)
+
+// VerifyPatch rescans a patched image and compares its vulnerabilities with the scan report of the
+// original image, as proof of what a patch fixed. A patched image that is not verified is not a
+// failed call: isError is only set when the image could not be rescanned.
+func (t *tools) VerifyPatch(ctx context.Context, req *mcp.CallToolRequest, params types.VerifyPatchParams) (*mcp.CallToolResult, any, error) {
+	v := validate.VerifyPatch(params)
+	v.Check("retry", t.validateRetry(params.Retry))
+	v.Check("env", subprocess.CheckOverrides(params.Env))
+	if err := v.Err(); err != nil {
+		return errorResult(err), nil, nil
+	}
+	ctx = subprocess.WithEnv(ctx, params.Env)
+	patchedImage := cmp.Or(params.PatchedImage, copa.PatchedImageRef(params.Image, params.Tag))
+
+	before, err := trivy.Severities(params.ReportPath)
+	if err != nil {
+		return errorResult(copaerrors.NewValidationError(fmt.Sprintf("failed to read the scan report %s", params.ReportPath), err,
+			"pass the report directory 'scan-container' returned for the original image")), nil, nil
+	}
+	if err := docker.CheckDaemon(ctx, params.DockerHost); err != nil {
+		return errorResult(err), nil, nil
+	}
+	if err := t.cfg.Registries.CheckSource(patchedImage); err != nil {
+		return errorResult(err), nil, nil
+	}
+
+	var scan *trivy.ScanResult
+	scanParams := trivy.ScanParams{
+		Image:          patchedImage,
+		DockerHost:     params.DockerHost,
+		Severity:       params.Severity,
+		TimeoutSeconds: t.timeoutSeconds(params.TimeoutSeconds),
+		Env:            params.Env,
+	}
+	err = t.retry(ctx, req, params.Retry, func() (err error) {
+		scan, err = trivy.Scan(ctx, req.Session, scanParams)
+		return err
+	})
+	if err != nil {
+		return errorResult(fmt.Errorf("rescanning %s failed: %w", patchedImage, err)), nil, nil
+	}
+	after, err := trivy.Severities(scan.ReportPath)
+	if err != nil {
+		return errorResult(copaerrors.NewSystemError("failed to read the rescan report", err)), nil, nil
+	}
+	link := t.addReportResource(patchedImage, scan.ReportPath)
+
+	result := types.PatchVerification{
+		Image:            params.Image,
+		PatchedImage:     patchedImage,
+		Before:           *severityCounts(before),
+		After:            *severityCounts(after),
+		ReportPath:       params.ReportPath,
+		RescanReportPath: scan.ReportPath,
+		RescanReportURI:  link.URI,
+		Warnings:         scan.Warnings,
+	}
+	result.Fixed, result.Remaining, result.Introduced = compareSeverities(before, after)
+	result.Verified = len(result.Remaining) == 0 && len(result.Introduced) == 0
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: verificationMessage(result)}, link},
+		StructuredContent: result,
+	}, nil, nil
+}
+
+// compareSeverities splits the vulnerabilities of a scan before a patch into those the scan after
+// it no longer found and those it still found, and lists those only found after it, each sorted
+func compareSeverities(before, after map[string]string) (fixed, remaining, introduced []string) {
+	fixed, remaining, introduced = []string{}, []string{}, []string{}
+	for _, id := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[id]; ok {
+			remaining = append(remaining, id)
+		} else {
+			fixed = append(fixed, id)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(after)) {
+		if _, ok := before[id]; !ok {
+			introduced = append(introduced, id)
+		}
+	}
+	return fixed, remaining, introduced
+}
+
+// verificationMessage describes the outcome of 'verify-patch'
+func verificationMessage(r types.PatchVerification) string {
+	var msg strings.Builder
+	outcome := "verified"
+	if !r.Verified {
+		outcome = "NOT verified"
+	}
+	msg.WriteString(fmt.Sprintf("Patch verification of %s -> %s: %s\n", r.Image, r.PatchedImage, outcome))
+	msg.WriteString(fmt.Sprintf("Fixed: %d, remaining: %d, introduced: %d\n", len(r.Fixed), len(r.Remaining), len(r.Introduced)))
+	msg.WriteString(fmt.Sprintf("Before: %s\n", formatSeverityCounts(r.Before)))
+	msg.WriteString(fmt.Sprintf("After: %s\n", formatSeverityCounts(r.After)))
+	for _, list := range []struct {
+		name string
+		ids  []string
+	}{{"Remaining", r.Remaining}, {"Introduced", r.Introduced}, {"Fixed", r.Fixed}} {
+		if len(list.ids) == 0 {
+			continue
+		}
+		ids := list.ids
+		if len(ids) > maxChangesInText {
+			ids = append(ids[:maxChangesInText:maxChangesInText], fmt.Sprintf("... and %d more in the structured result", len(list.ids)-maxChangesInText))
+		}
+		msg.WriteString(fmt.Sprintf("%s vulnerabilities: %s\n", list.name, strings.Join(ids, ", ")))
+	}
+	msg.WriteString(fmt.Sprintf("Rescan report: %s\n", r.RescanReportPath))
+	msg.WriteString(fmt.Sprintf("Rescan report resource: %s", r.RescanReportURI))
+	if len(r.Introduced) > 0 {
+		msg.WriteString("\n\nThe patched image has vulnerabilities the original did not have; review the packages the patch changed with 'diff-sbom' before deploying it.")
+	}
+	return msg.String() + warningsMessage(r.Warnings)
+}
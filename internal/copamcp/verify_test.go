@@ -0,0 +1,36 @@
+package copamcp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSeverities(t *testing.T) {
+	fixed, remaining, introduced := compareSeverities(
+		map[string]string{"CVE-3": "LOW", "CVE-1": "HIGH", "CVE-2": "HIGH"},
+		map[string]string{"CVE-2": "HIGH", "CVE-4": "CRITICAL"})
+	assert.Equal(t, []string{"CVE-1", "CVE-3"}, fixed)
+	assert.Equal(t, []string{"CVE-2"}, remaining)
+	assert.Equal(t, []string{"CVE-4"}, introduced)
+
+	fixed, remaining, introduced = compareSeverities(nil, nil)
+	assert.NotNil(t, fixed, "empty lists are serialized as []")
+	assert.Empty(t, remaining)
+	assert.Empty(t, introduced)
+}
+
+func TestVerificationMessage(t *testing.T) {
+	r := types.PatchVerification{Image: "alpine:3.19", PatchedImage: "alpine:3.19-patched", Introduced: []string{"CVE-9"}}
+	for i := range maxChangesInText + 2 {
+		r.Remaining = append(r.Remaining, fmt.Sprintf("CVE-%d", i))
+	}
+	msg := verificationMessage(r)
+	assert.Contains(t, msg, "alpine:3.19 -> alpine:3.19-patched: NOT verified")
+	assert.Contains(t, msg, "... and 2 more in the structured result")
+	assert.Contains(t, msg, "Introduced vulnerabilities: CVE-9")
+	assert.Contains(t, msg, "'diff-sbom'")
+	assert.Len(t, r.Remaining, maxChangesInText+2, "the result's list is not truncated")
+}
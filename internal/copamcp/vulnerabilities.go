@@ -0,0 +1,153 @@
+package copamcp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Sort orders of 'list-vulnerabilities'
+const (
+	sortBySeverity = "severity"
+	sortByID       = "id"
+	sortByPackage  = "package"
+)
+
+// ListVulnerabilities pages through the vulnerabilities of a scan report, filtered and sorted, so
+// that agents can explore the findings of large images without reading the whole report
+func (t *tools) ListVulnerabilities(ctx context.Context, req *mcp.CallToolRequest, params types.ListVulnerabilitiesParams) (*mcp.CallToolResult, any, error) {
+	if params.ReportPath == "" {
+		return errorResult(copaerrors.NewValidationError("reportPath parameter is required", nil,
+			"run 'scan-container' first and pass the report directory it returns")), nil, nil
+	}
+	if params.Offset < 0 {
+		return errorResult(copaerrors.NewValidationError("offset must not be negative", nil)), nil, nil
+	}
+	severities := map[string]bool{}
+	for _, severity := range params.Severity {
+		severity = strings.ToUpper(severity)
+		if !slices.Contains(copa.Severities, severity) && severity != "UNKNOWN" {
+			return errorResult(copaerrors.NewValidationError(fmt.Sprintf("unknown severity: %s", severity), nil,
+				fmt.Sprintf("use one of %s, UNKNOWN", strings.Join(copa.Severities, ", ")))), nil, nil
+		}
+		severities[severity] = true
+	}
+	sortBy := cmp.Or(strings.ToLower(params.SortBy), sortBySeverity)
+	if !slices.Contains([]string{sortBySeverity, sortByID, sortByPackage}, sortBy) {
+		return errorResult(copaerrors.NewValidationError(fmt.Sprintf("unknown sort order: %s", params.SortBy), nil,
+			"sort by severity, id or package")), nil, nil
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultFixedPageLimit
+	}
+	limit = min(limit, maxFixedPageLimit)
+
+	findings, err := trivy.ReportFindings(params.ReportPath)
+	if err != nil {
+		return errorResult(copaerrors.NewValidationError("failed to read the scan report", err,
+			"pass the report directory returned by 'scan-container'")), nil, nil
+	}
+
+	vulns := filterVulnerabilities(findings, severities, params.FixableOnly, params.Package)
+	sortVulnerabilities(vulns, sortBy)
+
+	page := types.VulnerabilityPage{Total: len(vulns), Vulnerabilities: []types.Vulnerability{}}
+	for _, vuln := range vulns {
+		countSeverity(&page.Counts, vuln.Severity)
+	}
+	if params.Offset < len(vulns) {
+		end := min(params.Offset+limit, len(vulns))
+		page.Vulnerabilities = vulns[params.Offset:end]
+		if end < len(vulns) {
+			page.NextOffset = end
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: vulnerabilityPageMessage(page, params.Offset)}},
+		StructuredContent: page,
+	}, nil, nil
+}
+
+// filterVulnerabilities returns the findings matching the filters, listing a vulnerability of a
+// package reported for several platforms once. An empty severities matches every severity.
+func filterVulnerabilities(findings []trivy.Finding, severities map[string]bool, fixableOnly bool, pkg string) []types.Vulnerability {
+	seen := map[string]bool{}
+	var vulns []types.Vulnerability
+	for _, f := range findings {
+		severity := f.Severity
+		if !slices.Contains(copa.Severities, severity) {
+			severity = "UNKNOWN"
+		}
+		if len(severities) > 0 && !severities[severity] {
+			continue
+		}
+		if fixableOnly && f.FixedVersion == "" {
+			continue
+		}
+		if pkg != "" && !strings.Contains(f.Package, pkg) {
+			continue
+		}
+		key := f.ID + "\x00" + f.Package + "\x00" + f.InstalledVersion
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		vulns = append(vulns, types.Vulnerability{
+			ID:               f.ID,
+			Package:          f.Package,
+			InstalledVersion: f.InstalledVersion,
+			FixedVersion:     f.FixedVersion,
+			Severity:         severity,
+			Title:            f.Title,
+		})
+	}
+	return vulns
+}
+
+// sortVulnerabilities sorts vulns by sortBy, then by ID and package, so that pages are stable
+func sortVulnerabilities(vulns []types.Vulnerability, sortBy string) {
+	rank := func(severity string) int {
+		if i := slices.Index(copa.Severities, severity); i >= 0 {
+			return i
+		}
+		return len(copa.Severities)
+	}
+	slices.SortStableFunc(vulns, func(a, b types.Vulnerability) int {
+		var c int
+		switch sortBy {
+		case sortBySeverity:
+			c = cmp.Compare(rank(a.Severity), rank(b.Severity))
+		case sortByPackage:
+			c = cmp.Compare(a.Package, b.Package)
+		}
+		return cmp.Or(c, cmp.Compare(a.ID, b.ID), cmp.Compare(a.Package, b.Package), cmp.Compare(a.InstalledVersion, b.InstalledVersion))
+	})
+}
+
+func vulnerabilityPageMessage(page types.VulnerabilityPage, offset int) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("vulnerabilities %d-%d of %d (%s)", min(offset+1, page.Total), offset+len(page.Vulnerabilities), page.Total, formatSeverityCounts(page.Counts)))
+	for _, vuln := range page.Vulnerabilities {
+		msg.WriteString(fmt.Sprintf("\n %s [%s] %s %s", vuln.ID, vuln.Severity, vuln.Package, vuln.InstalledVersion))
+		if vuln.FixedVersion != "" {
+			msg.WriteString(fmt.Sprintf(" -> %s", vuln.FixedVersion))
+		}
+		if vuln.Title != "" {
+			msg.WriteString(fmt.Sprintf(": %s", vuln.Title))
+		}
+	}
+	if page.NextOffset > 0 {
+		msg.WriteString(fmt.Sprintf("\nnext page: offset %d", page.NextOffset))
+	}
+	return msg.String()
+}
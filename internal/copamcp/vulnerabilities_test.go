@@ -0,0 +1,127 @@
+package copamcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vulnerabilitiesReport = `{
+  "Results": [
+    {
+      "Class": "os-pkgs",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-0003", "PkgName": "zlib", "InstalledVersion": "1.2.13", "FixedVersion": "1.3", "Severity": "MEDIUM"},
+        {"VulnerabilityID": "CVE-2023-0001", "PkgName": "openssl", "InstalledVersion": "3.0.8", "FixedVersion": "3.0.9", "Severity": "CRITICAL", "Title": "openssl: buffer overflow"},
+        {"VulnerabilityID": "CVE-2023-0002", "PkgName": "busybox", "InstalledVersion": "1.36.0", "Severity": "HIGH"},
+        {"VulnerabilityID": "CVE-2023-0004", "PkgName": "libssl3", "InstalledVersion": "3.0.8", "FixedVersion": "3.0.9", "Severity": "LOW"}
+      ]
+    }
+  ]
+}`
+
+func writeVulnerabilitiesReport(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-amd64.json"), []byte(vulnerabilitiesReport), 0o600))
+	// The same findings for another platform are listed once
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-arm64.json"), []byte(vulnerabilitiesReport), 0o600))
+	return dir
+}
+
+func listVulnerabilities(t *testing.T, params types.ListVulnerabilitiesParams) types.VulnerabilityPage {
+	t.Helper()
+	tl := &tools{}
+	res, _, err := tl.ListVulnerabilities(context.Background(), nil, params)
+	require.NoError(t, err)
+	require.False(t, res.IsError, res.Content)
+	page, ok := res.StructuredContent.(types.VulnerabilityPage)
+	require.True(t, ok)
+	return page
+}
+
+func vulnerabilityIDs(page types.VulnerabilityPage) []string {
+	var ids []string
+	for _, vuln := range page.Vulnerabilities {
+		ids = append(ids, vuln.ID)
+	}
+	return ids
+}
+
+func TestListVulnerabilities_SortsBySeverity(t *testing.T) {
+	page := listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: writeVulnerabilitiesReport(t)})
+
+	assert.Equal(t, 4, page.Total)
+	assert.Equal(t, types.SeverityCounts{Critical: 1, High: 1, Medium: 1, Low: 1}, page.Counts)
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0002", "CVE-2023-0003", "CVE-2023-0004"}, vulnerabilityIDs(page))
+	assert.Equal(t, types.Vulnerability{
+		ID: "CVE-2023-0001", Package: "openssl", InstalledVersion: "3.0.8", FixedVersion: "3.0.9", Severity: "CRITICAL", Title: "openssl: buffer overflow",
+	}, page.Vulnerabilities[0])
+	assert.Zero(t, page.NextOffset)
+}
+
+func TestListVulnerabilities_Filters(t *testing.T) {
+	dir := writeVulnerabilitiesReport(t)
+
+	page := listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: dir, Severity: []string{"critical", "HIGH"}})
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0002"}, vulnerabilityIDs(page))
+
+	page = listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: dir, FixableOnly: true, SortBy: "package"})
+	assert.Equal(t, []string{"CVE-2023-0004", "CVE-2023-0001", "CVE-2023-0003"}, vulnerabilityIDs(page))
+	assert.Equal(t, 3, page.Total)
+
+	page = listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: dir, Package: "ssl", SortBy: "id"})
+	assert.Equal(t, []string{"CVE-2023-0001", "CVE-2023-0004"}, vulnerabilityIDs(page))
+	assert.Equal(t, types.SeverityCounts{Critical: 1, Low: 1}, page.Counts)
+}
+
+func TestListVulnerabilities_Paging(t *testing.T) {
+	dir := writeVulnerabilitiesReport(t)
+
+	page := listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: dir, SortBy: "id", Offset: 1, Limit: 2})
+	assert.Equal(t, []string{"CVE-2023-0002", "CVE-2023-0003"}, vulnerabilityIDs(page))
+	assert.Equal(t, 3, page.NextOffset)
+	assert.Equal(t, 4, page.Total)
+
+	page = listVulnerabilities(t, types.ListVulnerabilitiesParams{ReportPath: dir, Offset: 10})
+	assert.Empty(t, page.Vulnerabilities)
+	assert.Equal(t, 4, page.Total)
+}
+
+func TestListVulnerabilities_InvalidParams(t *testing.T) {
+	dir := writeVulnerabilitiesReport(t)
+	tl := &tools{}
+	for name, params := range map[string]types.ListVulnerabilitiesParams{
+		"no report":        {},
+		"missing report":   {ReportPath: filepath.Join(dir, "missing")},
+		"negative offset":  {ReportPath: dir, Offset: -1},
+		"unknown severity": {ReportPath: dir, Severity: []string{"SEVERE"}},
+		"unknown sort":     {ReportPath: dir, SortBy: "score"},
+	} {
+		res, _, err := tl.ListVulnerabilities(context.Background(), nil, params)
+		require.NoError(t, err, name)
+		assert.True(t, res.IsError, name)
+	}
+}
+
+func TestVulnerabilityPageMessage(t *testing.T) {
+	page := types.VulnerabilityPage{
+		Vulnerabilities: []types.Vulnerability{
+			{ID: "CVE-2023-0001", Package: "openssl", InstalledVersion: "3.0.8", FixedVersion: "3.0.9", Severity: "CRITICAL", Title: "buffer overflow"},
+			{ID: "CVE-2023-0002", Package: "busybox", InstalledVersion: "1.36.0", Severity: "HIGH"},
+		},
+		Total:      3,
+		Counts:     types.SeverityCounts{Critical: 1, High: 2},
+		NextOffset: 2,
+	}
+
+	assert.Equal(t, "vulnerabilities 1-2 of 3 (CRITICAL 1, HIGH 2, MEDIUM 0, LOW 0)\n"+
+		" CVE-2023-0001 [CRITICAL] openssl 3.0.8 -> 3.0.9: buffer overflow\n"+
+		" CVE-2023-0002 [HIGH] busybox 1.36.0\n"+
+		"next page: offset 2", vulnerabilityPageMessage(page, 0))
+}
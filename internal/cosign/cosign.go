@@ -0,0 +1,152 @@
+// Package cosign verifies the signatures and attestations of images with the cosign CLI before the
+// server pulls, scans or patches them, so that only images signed by trusted keys or keyless
+// identities are processed, and signs the patched images 'remediate' pushes. The cosign CLI
+// resolves registry credentials, the Sigstore trust root and KMS keys exactly as it does for users.
+package cosign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// Options are the trusted signers of images
+type Options struct {
+	// Keys are public key references: files, KMS URIs (e.g. awskms://...) or k8s:// secrets
+	Keys []string
+	// Identity is a regular expression matching the certificate identity of keyless signatures,
+	// issued by Issuer
+	Identity string
+	Issuer   string
+	// Attestation is a predicate type (e.g. slsaprovenance) of an attestation that must also verify
+	Attestation string
+}
+
+// Verifier verifies images against the trusted signers
+type Verifier struct {
+	cosignPath string
+	opts       Options
+}
+
+// New creates a verifier for the trusted signers in opts
+func New(opts Options) *Verifier {
+	return &Verifier{cosignPath: "cosign", opts: opts}
+}
+
+// Enabled reports whether any trusted signer is configured
+func (v *Verifier) Enabled() bool {
+	return v != nil && (len(v.opts.Keys) > 0 || v.opts.Identity != "")
+}
+
+// Verify checks that image is signed, and attested when an attestation type is configured, by
+// one of the trusted signers. An image no signer verifies fails with a policy error.
+func (v *Verifier) Verify(ctx context.Context, image string) error {
+	if !v.Enabled() {
+		return nil
+	}
+
+	var failures []string
+	var last *copaerrors.CopaceticError
+	for _, signer := range v.signers() {
+		err := v.run(ctx, append([]string{"verify"}, append(signer, image)...))
+		if err == nil && v.opts.Attestation != "" {
+			err = v.run(ctx, append([]string{"verify-attestation", "--type", v.opts.Attestation}, append(signer, image)...))
+		}
+		if err == nil {
+			return nil
+		}
+		var copaErr *copaerrors.CopaceticError
+		if !errors.As(err, &copaErr) || copaErr.Category == copaerrors.CategorySystem {
+			return err
+		}
+		failures = append(failures, copaErr.Error())
+		last = copaErr
+	}
+
+	// Registry failures are reported as such, so that they can be retried; anything else means
+	// that no trusted signature was found
+	if last.Category != copaerrors.CategoryPolicy {
+		return last
+	}
+	err := copaerrors.NewPolicyError(fmt.Sprintf("%s is not signed by a trusted signer", image), errors.New(strings.Join(failures, "\n")),
+		"use an image signed with one of the configured keys or identities, or ask an operator to trust its signer")
+	err.Command = last.Command
+	return err
+}
+
+// signers returns the cosign arguments selecting each trusted signer
+func (v *Verifier) signers() [][]string {
+	var signers [][]string
+	for _, key := range v.opts.Keys {
+		signers = append(signers, []string{"--key", key})
+	}
+	if v.opts.Identity != "" {
+		signers = append(signers, []string{"--certificate-identity-regexp", v.opts.Identity, "--certificate-oidc-issuer", v.opts.Issuer})
+	}
+	return signers
+}
+
+// run runs cosign with args, discarding the verified payloads it prints
+func (v *Verifier) run(ctx context.Context, args []string) error {
+	return run(ctx, v.cosignPath, args, copaerrors.CategoryPolicy)
+}
+
+// Signer signs patched images with a key, e.g. once they are pushed and verified
+type Signer struct {
+	cosignPath string
+	key        string
+}
+
+// NewSigner creates a signer for key, a private key file, KMS URI or k8s:// secret. The password
+// of an encrypted key is read by cosign from COSIGN_PASSWORD.
+func NewSigner(key string) *Signer {
+	return &Signer{cosignPath: "cosign", key: key}
+}
+
+// Enabled reports whether a signing key is configured
+func (s *Signer) Enabled() bool {
+	return s != nil && s.key != ""
+}
+
+// Sign signs image, which should be a digest reference, and pushes the signature to its registry
+func (s *Signer) Sign(ctx context.Context, image string) error {
+	if !s.Enabled() {
+		return copaerrors.NewValidationError("no signing key is configured", nil, "set COPA_MCP_SIGN_KEY on the server")
+	}
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return run(ctx, s.cosignPath, []string{"sign", "--yes", "--key", s.key, image}, copaerrors.CategoryExecution)
+}
+
+// run runs cosign with args, classifying its failures as fallback unless their output says otherwise
+func run(ctx context.Context, cosignPath string, args []string, fallback copaerrors.Category) error {
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, cosignPath, args...)
+	cmd.Env = subprocess.Environ(ctx)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return copaerrors.NewSystemError("cosign not found", err, "install cosign and make sure it is on the server's PATH")
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		message := strings.TrimSpace(stderr.String())
+		return copaerrors.New(copaerrors.Classify(message, fallback), fmt.Sprintf("cosign %s failed", args[0]),
+			fmt.Errorf("%w\n%s", err, message)).
+			WithCommand(cmd.Args, exitCode, message, time.Since(start))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package cosign
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnabled(t *testing.T) {
+	var v *Verifier
+	assert.False(t, v.Enabled())
+	assert.False(t, New(Options{Attestation: "slsaprovenance"}).Enabled())
+	assert.True(t, New(Options{Keys: []string{"cosign.pub"}}).Enabled())
+	assert.True(t, New(Options{Identity: "^https://github.com/org/", Issuer: "https://token.actions.githubusercontent.com"}).Enabled())
+}
+
+func TestSigners(t *testing.T) {
+	v := New(Options{
+		Keys:     []string{"cosign.pub", "awskms:///alias/copa"},
+		Identity: "^https://github.com/org/",
+		Issuer:   "https://token.actions.githubusercontent.com",
+	})
+	assert.Equal(t, [][]string{
+		{"--key", "cosign.pub"},
+		{"--key", "awskms:///alias/copa"},
+		{"--certificate-identity-regexp", "^https://github.com/org/", "--certificate-oidc-issuer", "https://token.actions.githubusercontent.com"},
+	}, v.signers())
+}
+
+func TestVerify_Disabled(t *testing.T) {
+	assert.NoError(t, New(Options{}).Verify(context.Background(), "nginx:1.25"))
+}
+
+func TestVerify_CosignNotFound(t *testing.T) {
+	v := New(Options{Keys: []string{"cosign.pub"}})
+	v.cosignPath = "copa-mcp-missing-cosign"
+
+	err := v.Verify(context.Background(), "nginx:1.25")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+}
+
+func TestVerify_Untrusted(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("false not available")
+	}
+	// 'false' fails like cosign does for an image without a signature by the signer
+	v := New(Options{Keys: []string{"a.pub", "b.pub"}})
+	v.cosignPath = "false"
+
+	err := v.Verify(context.Background(), "nginx:1.25")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryPolicy, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "nginx:1.25 is not signed by a trusted signer")
+
+	var copaErr *copaerrors.CopaceticError
+	require.ErrorAs(t, err, &copaErr)
+	require.NotNil(t, copaErr.Command)
+	assert.Contains(t, copaErr.Command.Line, "b.pub")
+}
+
+func TestSign(t *testing.T) {
+	var s *Signer
+	assert.False(t, s.Enabled())
+	err := NewSigner("").Sign(context.Background(), "ghcr.io/org/app@sha256:abc")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("false not available")
+	}
+	s = NewSigner("cosign.key")
+	s.cosignPath = "false"
+	err = s.Sign(context.Background(), "ghcr.io/org/app@sha256:abc")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryExecution, copaerrors.CategoryOf(err))
+	assert.Equal(t, "false sign --yes --key cosign.key ghcr.io/org/app@sha256:abc", copaerrors.CommandOf(err).Line)
+}
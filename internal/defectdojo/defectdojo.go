@@ -0,0 +1,167 @@
+// Package defectdojo imports scan findings into DefectDojo, so that the vulnerabilities found in
+// images, and their closure by patches, are tracked in the organization's vulnerability management.
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+const (
+	// DefaultEngagement is the engagement of images whose mapping does not name one
+	DefaultEngagement = "Copacetic"
+	// DefaultProductType is the product type of products created by an import
+	DefaultProductType = "Container images"
+
+	// scanType is the DefectDojo parser of Trivy JSON reports
+	scanType = "Trivy Scan"
+	// requestTimeout bounds an import; DefectDojo processes the findings before responding
+	requestTimeout = 5 * time.Minute
+)
+
+// Mapping maps the images whose reference starts with Prefix to a DefectDojo product and engagement
+type Mapping struct {
+	Prefix     string
+	Product    string
+	Engagement string
+}
+
+// ParseMappings parses mappings of the form prefix=product or prefix=product/engagement,
+// e.g. ghcr.io/org/api=API/Container patching
+func ParseMappings(mappings []string) ([]Mapping, error) {
+	parsed := make([]Mapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		prefix, target, found := strings.Cut(mapping, "=")
+		product, engagement, _ := strings.Cut(target, "/")
+		m := Mapping{Prefix: strings.TrimSpace(prefix), Product: strings.TrimSpace(product), Engagement: strings.TrimSpace(engagement)}
+		if !found || m.Prefix == "" || m.Product == "" {
+			return nil, fmt.Errorf("invalid product mapping %q: must be prefix=product or prefix=product/engagement", mapping)
+		}
+		if m.Engagement == "" {
+			m.Engagement = DefaultEngagement
+		}
+		parsed = append(parsed, m)
+	}
+	return parsed, nil
+}
+
+// Client imports scan reports into a DefectDojo server
+type Client struct {
+	url      string
+	apiKey   string
+	mappings []Mapping
+	http     *http.Client
+}
+
+// New creates a client for the DefectDojo server at url, authenticating with the API v2 key apiKey.
+// mappings map image references to products and engagements; see Mapping.
+func New(url, apiKey string, mappings []Mapping) *Client {
+	return &Client{
+		url:      strings.TrimSuffix(url, "/"),
+		apiKey:   apiKey,
+		mappings: mappings,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Enabled reports whether a DefectDojo server is configured
+func (c *Client) Enabled() bool {
+	return c != nil && c.url != ""
+}
+
+// Context returns the product and engagement of image. They come from the longest matching
+// mapping; otherwise the product is the image repository and the engagement DefaultEngagement.
+func (c *Client) Context(image string) (product, engagement string) {
+	product, engagement = image, DefaultEngagement
+	if i := strings.Index(product, "@"); i >= 0 {
+		product = product[:i]
+	} else if i := strings.LastIndex(product, ":"); i > strings.LastIndex(product, "/") {
+		product = product[:i]
+	}
+
+	matched := ""
+	for _, m := range c.mappings {
+		if strings.HasPrefix(image, m.Prefix) && len(m.Prefix) > len(matched) {
+			matched, product, engagement = m.Prefix, m.Product, m.Engagement
+		}
+	}
+	return product, engagement
+}
+
+// Reimport imports a Trivy JSON report of image into the test titled title, in the product and
+// engagement of image. The product, engagement and test are created if needed. Findings of the
+// test missing from the report are closed, so reimporting a scan of the patched image records
+// the vulnerabilities the patch fixed.
+func (c *Client) Reimport(ctx context.Context, image, title string, report []byte) error {
+	product, engagement := c.Context(image)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for key, value := range map[string]string{
+		"scan_type":           scanType,
+		"product_name":        product,
+		"engagement_name":     engagement,
+		"product_type_name":   DefaultProductType,
+		"test_title":          title,
+		"auto_create_context": "true",
+		"close_old_findings":  "true",
+		"active":              "true",
+		"verified":            "true",
+		"scan_date":           time.Now().UTC().Format("2006-01-02"),
+	} {
+		form.WriteField(key, value)
+	}
+	part, err := form.CreateFormFile("file", "report.json")
+	if err != nil {
+		return copaerrors.NewSystemError("failed to encode DefectDojo import", err)
+	}
+	part.Write(report)
+	if err := form.Close(); err != nil {
+		return copaerrors.NewSystemError("failed to encode DefectDojo import", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/api/v2/reimport-scan/", &body)
+	if err != nil {
+		return copaerrors.NewValidationError(fmt.Sprintf("invalid DefectDojo URL: %s", c.url), err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Token "+c.apiKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return copaerrors.NewNetworkError("failed to reach DefectDojo", err, "check the DefectDojo URL and network connectivity")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return statusError(resp, fmt.Sprintf("DefectDojo rejected the findings of %s (product %s, engagement %s)", image, product, engagement))
+	}
+	return nil
+}
+
+// statusError converts an unexpected DefectDojo response into a categorized error
+func statusError(resp *http.Response, message string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return copaerrors.NewAuthError(message, err, "set COPA_MCP_DEFECTDOJO_API_KEY to a valid API v2 key")
+	case resp.StatusCode == http.StatusForbidden:
+		return copaerrors.NewAuthError(message, err, "the API key's user needs permission to import scans and to create products and engagements")
+	case resp.StatusCode == http.StatusBadRequest:
+		return copaerrors.NewValidationError(message, err)
+	case resp.StatusCode == http.StatusNotFound:
+		return copaerrors.NewValidationError(message, err, "check the DefectDojo URL")
+	case resp.StatusCode >= 500:
+		return copaerrors.NewNetworkError(message, err)
+	}
+	return copaerrors.NewExecutionError(message, err)
+}
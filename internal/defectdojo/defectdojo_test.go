@@ -0,0 +1,120 @@
+package defectdojo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMappings(t *testing.T) {
+	mappings, err := ParseMappings([]string{"ghcr.io/org/api=API/Container patching", "ghcr.io/org/ = Platform"})
+	require.NoError(t, err)
+	assert.Equal(t, []Mapping{
+		{Prefix: "ghcr.io/org/api", Product: "API", Engagement: "Container patching"},
+		{Prefix: "ghcr.io/org/", Product: "Platform", Engagement: DefaultEngagement},
+	}, mappings)
+
+	for _, mapping := range []string{"ghcr.io/org", "=API", "ghcr.io/org=", "ghcr.io/org=/engagement"} {
+		_, err := ParseMappings([]string{mapping})
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestContext(t *testing.T) {
+	c := New("https://defectdojo.example.com", "key", []Mapping{
+		{Prefix: "ghcr.io/org/", Product: "Platform", Engagement: "Images"},
+		{Prefix: "ghcr.io/org/api", Product: "API", Engagement: "Container patching"},
+	})
+
+	tests := []struct {
+		image      string
+		product    string
+		engagement string
+	}{
+		{"ghcr.io/org/api:1.2", "API", "Container patching"},
+		{"ghcr.io/org/web:3.0", "Platform", "Images"},
+		{"registry.example.com:5000/team/app:1.0", "registry.example.com:5000/team/app", DefaultEngagement},
+		{"nginx@sha256:abc", "nginx", DefaultEngagement},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			product, engagement := c.Context(tt.image)
+			assert.Equal(t, tt.product, product)
+			assert.Equal(t, tt.engagement, engagement)
+		})
+	}
+}
+
+func TestReimport(t *testing.T) {
+	var fields map[string]string
+	var authorization, report string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/reimport-scan/", r.URL.Path)
+		require.Equal(t, http.MethodPost, r.Method)
+		authorization = r.Header.Get("Authorization")
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		fields = map[string]string{}
+		for key, values := range r.MultipartForm.Value {
+			fields[key] = values[0]
+		}
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		report = string(data)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"test": 7}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", "secret", nil)
+	err := c.Reimport(context.Background(), "ghcr.io/org/api:1.2", "ghcr.io/org/api:1.2 linux/arm64", []byte(`{"SchemaVersion":2}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Token secret", authorization)
+	assert.Equal(t, `{"SchemaVersion":2}`, report)
+	assert.Equal(t, "Trivy Scan", fields["scan_type"])
+	assert.Equal(t, "ghcr.io/org/api", fields["product_name"])
+	assert.Equal(t, DefaultEngagement, fields["engagement_name"])
+	assert.Equal(t, DefaultProductType, fields["product_type_name"])
+	assert.Equal(t, "ghcr.io/org/api:1.2 linux/arm64", fields["test_title"])
+	assert.Equal(t, "true", fields["auto_create_context"])
+	assert.Equal(t, "true", fields["close_old_findings"])
+}
+
+func TestReimport_Errors(t *testing.T) {
+	tests := []struct {
+		status   int
+		category copaerrors.Category
+	}{
+		{http.StatusUnauthorized, copaerrors.CategoryAuth},
+		{http.StatusForbidden, copaerrors.CategoryAuth},
+		{http.StatusBadRequest, copaerrors.CategoryValidation},
+		{http.StatusNotFound, copaerrors.CategoryValidation},
+		{http.StatusBadGateway, copaerrors.CategoryNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := New(srv.URL, "secret", nil).Reimport(context.Background(), "nginx:1.25", "nginx:1.25", []byte(`{}`))
+			require.Error(t, err)
+			assert.Equal(t, tt.category, copaerrors.CategoryOf(err))
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var c *Client
+	assert.False(t, c.Enabled())
+	assert.False(t, New("", "", nil).Enabled())
+	assert.True(t, New("https://defectdojo.example.com", "key", nil).Enabled())
+}
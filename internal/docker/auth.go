@@ -1,10 +1,13 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
 )
 
 // Auth interface for registry authentication operations
@@ -22,25 +25,41 @@ func (d *AuthImpl) SetupRegistryAuthFromEnv() (bool, error) {
 // LoginWithToken authenticates to a registry using a token via docker login
 func LoginWithToken(registry, token string) (bool, error) {
 	if token == "" {
-		return false, fmt.Errorf("token cannot be empty")
+		return false, copaerrors.NewValidationError("token cannot be empty", nil)
+	}
+	if err := Login(context.Background(), "", registry, TokenUsername, token); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
+// TokenUsername is the user name logged in with a token that is not tied to one, as registries
+// such as GHCR and ACR accept
+const TokenUsername = "_token"
+
+// Login runs docker login against the daemon at host, storing the credentials of username for
+// registry in the Docker config the following docker, copa and trivy commands read. An empty
+// registry logs in to Docker Hub.
+func Login(ctx context.Context, host, registry, username, password string) error {
 	// Default to Docker Hub if no registry specified
 	if registry == "" {
 		registry = "docker.io"
 	}
 
 	// Use docker login with --password-stdin for security
-	cmd := exec.Command("docker", "login", registry, "-u", "_token", "--password-stdin")
-	cmd.Stdin = strings.NewReader(token)
+	cmd := exec.CommandContext(ctx, "docker", "login", registry, "-u", username, "--password-stdin")
+	cmd.Stdin = strings.NewReader(password)
+	// Log in with the server's DOCKER_CONFIG, where copa and docker push look for the credentials
+	cmd.Env = Env(ctx, host)
 
 	// Capture both stdout and stderr for better error reporting
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return false, fmt.Errorf("docker login failed: %v\nOutput: %s", err, string(output))
+		return copaerrors.New(copaerrors.Classify(string(output), copaerrors.CategoryAuth), "docker login failed",
+			fmt.Errorf("%v\nOutput: %s", err, string(output)),
+			fmt.Sprintf("verify the credentials are valid for %s", registry))
 	}
-
-	return true, nil
+	return nil
 }
 
 // SetupRegistryAuthFromEnv reads registry token from environment and runs docker login
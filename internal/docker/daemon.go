@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+const (
+	daemonCheckTimeout = 10 * time.Second
+	defaultSocketPath  = "/var/run/docker.sock"
+	// defaultPipeHost is the endpoint the docker CLI uses on Windows, served by Docker Desktop
+	defaultPipeHost = "npipe:////./pipe/docker_engine"
+)
+
+// CheckDaemon verifies that the Docker daemon at host (or DOCKER_HOST when host is empty)
+// is reachable, returning a system error with remediation hints when it is not
+func CheckDaemon(ctx context.Context, host string) error {
+	if err := ValidateHost(host); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, daemonCheckTimeout)
+	defer cancel()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return copaerrors.NewSystemError("docker CLI not found", err,
+			"install Docker (https://docs.docker.com/get-docker/) and ensure 'docker' is in the server's PATH")
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}")
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("no response after %s", daemonCheckTimeout)
+	} else if msg := strings.TrimSpace(string(output)); msg != "" {
+		err = fmt.Errorf("%s", msg)
+	}
+
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	return copaerrors.NewSystemError("docker daemon is not reachable", err, daemonHints(host)...)
+}
+
+// daemonHints returns remediation hints based on the configured DOCKER_HOST
+func daemonHints(dockerHost string) []string {
+	if dockerHost == "" {
+		return localDaemonHints(runtime.GOOS)
+	}
+
+	u, err := url.Parse(dockerHost)
+	if err != nil || u.Scheme == "" {
+		return []string{fmt.Sprintf("DOCKER_HOST=%q is not a valid URL; use a value like %s or tcp://host:2376", dockerHost, exampleHost(runtime.GOOS))}
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if _, err := os.Stat(u.Path); err != nil {
+			return []string{fmt.Sprintf("DOCKER_HOST points to %s, which does not exist; start the daemon or correct DOCKER_HOST", u.Path)}
+		}
+		return []string{
+			fmt.Sprintf("DOCKER_HOST points to %s; ensure the daemon behind it is running", u.Path),
+			"ensure the server user has permission to access the socket",
+		}
+	case "npipe":
+		return []string{
+			fmt.Sprintf("DOCKER_HOST=%s; ensure Docker Desktop, or the daemon serving the pipe, is running", dockerHost),
+			"ensure the server user has permission to access the pipe (e.g. is in the 'docker-users' group)",
+		}
+	case "tcp", "ssh":
+		return []string{
+			fmt.Sprintf("DOCKER_HOST=%s; ensure the remote daemon is running and reachable from this host", dockerHost),
+			"check TLS settings (DOCKER_TLS_VERIFY, DOCKER_CERT_PATH) if the daemon requires TLS",
+		}
+	default:
+		return []string{fmt.Sprintf("DOCKER_HOST scheme %q is not supported; use unix://, tcp://, ssh:// or npipe://", u.Scheme)}
+	}
+}
+
+// localDaemonHints returns remediation hints for the default daemon of the goos host
+func localDaemonHints(goos string) []string {
+	if goos == "windows" {
+		return []string{
+			fmt.Sprintf("start the Docker daemon by launching Docker Desktop, which the docker CLI reaches through %s", defaultPipeHost),
+			"for a daemon running in WSL or on another host, set DOCKER_HOST (e.g. tcp://localhost:2375), or set COPA_MCP_BUILDKIT_ADDR to a remote buildkit",
+		}
+	}
+
+	hints := []string{"start the Docker daemon (e.g. 'sudo systemctl start docker' or launch Docker Desktop)"}
+	if _, err := os.Stat(defaultSocketPath); err != nil {
+		hints = append(hints, fmt.Sprintf("the default socket %s does not exist; if you use rootless Docker, Colima or Podman, set DOCKER_HOST to its socket", defaultSocketPath))
+	} else {
+		hints = append(hints, fmt.Sprintf("ensure the server user has permission to access %s (e.g. add it to the 'docker' group)", defaultSocketPath))
+	}
+	return hints
+}
+
+// exampleHost returns an example endpoint of the local daemon of the goos host
+func exampleHost(goos string) string {
+	if goos == "windows" {
+		return defaultPipeHost
+	}
+	return "unix://" + defaultSocketPath
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonHints(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerHost string
+		contains   string
+	}{
+		{"default socket", "", "start the Docker daemon"},
+		{"missing unix socket", "unix:///nonexistent/docker.sock", "does not exist"},
+		{"remote tcp", "tcp://10.0.0.1:2376", "remote daemon"},
+		{"named pipe", "npipe:////./pipe/docker_engine", "Docker Desktop"},
+		{"invalid value", "not-a-url", "not a valid URL"},
+		{"unsupported scheme", "http://localhost:2375", "not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := daemonHints(tt.dockerHost)
+			assert.NotEmpty(t, hints)
+			assert.Contains(t, hints[0], tt.contains)
+		})
+	}
+}
+
+func TestLocalDaemonHints(t *testing.T) {
+	hints := localDaemonHints("windows")
+	assert.Contains(t, hints[0], "Docker Desktop")
+	assert.Contains(t, hints[0], defaultPipeHost)
+	assert.NotContains(t, strings.Join(hints, "\n"), defaultSocketPath)
+
+	hints = localDaemonHints("linux")
+	assert.Contains(t, hints[0], "start the Docker daemon")
+	assert.Contains(t, hints[1], defaultSocketPath)
+}
+
+func TestExampleHost(t *testing.T) {
+	assert.Equal(t, "npipe:////./pipe/docker_engine", exampleHost("windows"))
+	assert.Equal(t, "unix:///var/run/docker.sock", exampleHost("darwin"))
+}
+
+func TestValidateHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{"", false},
+		{"unix:///var/run/docker.sock", false},
+		{"tcp://build-host:2376", false},
+		{"ssh://user@build-host", false},
+		{"npipe:////./pipe/docker_engine", false},
+		{"build-host:2376", true},
+		{"http://build-host:2375", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := ValidateHost(tt.host)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, Env(ctx, ""))
+
+	env := Env(ctx, "tcp://build-host:2376")
+	assert.Equal(t, "DOCKER_HOST=tcp://build-host:2376", env[len(env)-1])
+
+	subprocess.SetEnv(map[string]string{"TRIVY_CACHE_DIR": "/cache"})
+	t.Cleanup(func() { subprocess.SetEnv(nil) })
+	env = Env(ctx, "")
+	assert.Equal(t, "TRIVY_CACHE_DIR=/cache", env[len(env)-1])
+	env = Env(ctx, "tcp://build-host:2376")
+	assert.Equal(t, []string{"TRIVY_CACHE_DIR=/cache", "DOCKER_HOST=tcp://build-host:2376"}, env[len(env)-2:])
+}
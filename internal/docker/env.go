@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// Env returns the environment for docker, copa and trivy subprocesses started for ctx targeting
+// the daemon at host, with the variables of subprocess.Environ. An empty host and no variables
+// return nil so that subprocesses inherit the server environment.
+func Env(ctx context.Context, host string) []string {
+	env := subprocess.Environ(ctx)
+	if host == "" {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(env, "DOCKER_HOST="+host)
+}
+
+// ValidateHost checks that host is a Docker endpoint URL supported by the docker CLI
+func ValidateHost(host string) error {
+	if host == "" {
+		return nil
+	}
+
+	u, err := url.Parse(host)
+	if err != nil || u.Scheme == "" {
+		return copaerrors.NewValidationError(fmt.Sprintf("invalid docker host %q", host), err,
+			fmt.Sprintf("use a value like %s, tcp://host:2376 or ssh://user@host", exampleHost(runtime.GOOS)))
+	}
+
+	switch u.Scheme {
+	case "unix", "tcp", "ssh", "npipe":
+		return nil
+	default:
+		return copaerrors.NewValidationError(fmt.Sprintf("unsupported docker host scheme %q", u.Scheme), nil,
+			"use unix://, tcp://, ssh:// or npipe://")
+	}
+}
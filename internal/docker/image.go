@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// ImageSize returns the size in bytes of an image present in the Docker daemon at host
+func ImageSize(ctx context.Context, host, image string) (int64, error) {
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Size}}", image)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse size of image %s: %w", image, err)
+	}
+
+	return size, nil
+}
+
+// Pull pulls image into the Docker daemon at host, calling progress for each line of
+// docker's output. An empty platform pulls the daemon's default platform.
+func Pull(ctx context.Context, host, image, platform string, progress func(line string)) error {
+	args := []string{"pull"}
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
+	args = append(args, image)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Env = Env(ctx, host)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start docker pull: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if progress != nil {
+			progress(scanner.Text())
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return copaerrors.New(copaerrors.Classify(stderr.String(), copaerrors.CategoryExecution),
+			fmt.Sprintf("docker pull %s failed", image), fmt.Errorf("%w\n%s", err, strings.TrimSpace(stderr.String())))
+	}
+
+	return nil
+}
+
+// RemoveImages removes images from the Docker daemon at host, returning docker's output
+func RemoveImages(ctx context.Context, host string, images []string, force bool) (string, error) {
+	args := []string{"image", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, images...)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", copaerrors.NewExecutionError("docker image rm failed", fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// PruneDanglingImages removes untagged images (such as intermediate images left behind
+// by patching) from the Docker daemon at host, returning docker's summary
+func PruneDanglingImages(ctx context.Context, host string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "image", "prune", "--force")
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", copaerrors.NewExecutionError("docker image prune failed", fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Save writes image from the Docker daemon at host to a tarball at path. The archive
+// can be loaded with 'docker load' and, with Docker 25+, is also a valid OCI image layout.
+func Save(ctx context.Context, host, image, path string) error {
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, "docker", "save", "--output", path, image)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// A partial tarball, e.g. of a cancelled save, cannot be loaded
+		os.Remove(path)
+		return copaerrors.NewExecutionError(fmt.Sprintf("docker save %s failed", image), fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+
+	return nil
+}
+
+// Tag tags image in the Docker daemon at host as target
+func Tag(ctx context.Context, host, image, target string) error {
+	cmd := exec.CommandContext(ctx, "docker", "tag", image, target)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return copaerrors.NewExecutionError(fmt.Sprintf("docker tag %s failed", image), fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+
+	return nil
+}
+
+// Push pushes image from the Docker daemon at host to its registry
+func Push(ctx context.Context, host, image string) error {
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, "docker", "push", image)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		return copaerrors.New(copaerrors.Classify(msg, copaerrors.CategoryExecution),
+			fmt.Sprintf("docker push %s failed", image), fmt.Errorf("%w\n%s", err, msg))
+	}
+
+	return nil
+}
+
+// Load loads the images of the tarball at path, e.g. written by 'docker save' or 'ctr images
+// export', into the Docker daemon at host
+func Load(ctx context.Context, host, path string) error {
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, "docker", "load", "--input", path)
+	cmd.Env = Env(ctx, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return copaerrors.NewExecutionError("docker load failed", fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// OSRelease returns the ID and VERSION_ID fields of /etc/os-release in image, e.g. "debian" and "12".
+// The image is pulled into the daemon at host if it is not present; no container is started.
+func OSRelease(ctx context.Context, host, image string) (id, version string, err error) {
+	// The command is never run, so it need not exist in the image
+	create := exec.CommandContext(ctx, "docker", "create", image, "sh")
+	create.Env = Env(ctx, host)
+	var stderr strings.Builder
+	create.Stderr = &stderr
+	// Creating the container may pull the image
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	output, err := create.Output()
+	release()
+	if err != nil {
+		return "", "", copaerrors.New(copaerrors.Classify(stderr.String(), copaerrors.CategoryExecution),
+			fmt.Sprintf("failed to create a container from %s", image), fmt.Errorf("%w\n%s", err, strings.TrimSpace(stderr.String())))
+	}
+	container := strings.TrimSpace(string(output))
+	defer func() {
+		rm := exec.CommandContext(context.WithoutCancel(ctx), "docker", "rm", container)
+		rm.Env = Env(ctx, host)
+		rm.Run()
+	}()
+
+	// -L follows the usual symlink to /usr/lib/os-release; the file is streamed as a tar archive
+	cp := exec.CommandContext(ctx, "docker", "cp", "-L", container+":/etc/os-release", "-")
+	cp.Env = Env(ctx, host)
+	stderr.Reset()
+	cp.Stderr = &stderr
+	archive, err := cp.Output()
+	if err != nil {
+		return "", "", copaerrors.NewValidationError(fmt.Sprintf("failed to read /etc/os-release from %s", image),
+			fmt.Errorf("%w\n%s", err, strings.TrimSpace(stderr.String())),
+			"copa can only patch images based on a Linux distribution")
+	}
+
+	tr := tar.NewReader(bytes.NewReader(archive))
+	if _, err := tr.Next(); err != nil {
+		return "", "", copaerrors.NewExecutionError(fmt.Sprintf("failed to read /etc/os-release from %s", image), err)
+	}
+	id, version = parseOSRelease(tr)
+	if id == "" {
+		return "", "", copaerrors.NewValidationError(fmt.Sprintf("/etc/os-release in %s does not name the distribution", image), nil)
+	}
+	return id, version, nil
+}
+
+// parseOSRelease returns the ID and VERSION_ID fields of an os-release file
+func parseOSRelease(r io.Reader) (id, version string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			id = value
+		case "VERSION_ID":
+			version = value
+		}
+	}
+	return id, version
+}
@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		id      string
+		version string
+	}{
+		{"debian", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nNAME=\"Debian GNU/Linux\"\nVERSION_ID=\"12\"\nID=debian\n", "debian", "12"},
+		{"alpine", "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.20.3\n", "alpine", "3.20.3"},
+		{"single quotes and comments", "# comment\nID='rhel'\nID_LIKE=\"fedora\"\nVERSION_ID='9.4'\n", "rhel", "9.4"},
+		{"no version", "ID=arch\n", "arch", ""},
+		{"empty", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, version := parseOSRelease(strings.NewReader(tt.content))
+			assert.Equal(t, tt.id, id)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// defaultSocketCandidates returns well-known Docker-compatible socket locations used by
+// rootless dockerd, Docker Desktop, Colima, Podman machine and Rancher Desktop
+func defaultSocketCandidates() []string {
+	var candidates []string
+
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates,
+			filepath.Join(runtimeDir, "docker.sock"),
+			filepath.Join(runtimeDir, "podman", "podman.sock"),
+		)
+	}
+	if uid := os.Getuid(); uid > 0 {
+		candidates = append(candidates, fmt.Sprintf("/run/user/%d/docker.sock", uid))
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".colima", "default", "docker.sock"),
+			filepath.Join(home, ".colima", "docker.sock"),
+			filepath.Join(home, ".rd", "docker.sock"),
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "podman.sock"),
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "qemu", "podman.sock"),
+		)
+	}
+
+	return candidates
+}
+
+// DiscoverSocket returns the first candidate path that is a unix socket
+func DiscoverSocket(candidates []string) (string, bool) {
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// ConfigureHost points DOCKER_HOST at an alternative socket when DOCKER_HOST is unset
+// and the default socket is absent. The configured sockets are probed before the
+// built-in candidates. The setting is inherited by docker, copa and trivy subprocesses.
+// It returns the discovered host, or an empty string if nothing was changed.
+func ConfigureHost(sockets []string) string {
+	if runtime.GOOS == "windows" || os.Getenv("DOCKER_HOST") != "" {
+		return ""
+	}
+
+	if _, err := os.Stat(defaultSocketPath); err == nil {
+		return ""
+	}
+
+	socket, ok := DiscoverSocket(append(sockets, defaultSocketCandidates()...))
+	if !ok {
+		return ""
+	}
+
+	host := "unix://" + socket
+	os.Setenv("DOCKER_HOST", host)
+	return host
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package docker
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	// A regular file must not be mistaken for a socket
+	regular := filepath.Join(dir, "regular.sock")
+	require.NoError(t, os.WriteFile(regular, nil, 0o600))
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	found, ok := DiscoverSocket([]string{filepath.Join(dir, "missing.sock"), regular, socket})
+	assert.True(t, ok)
+	assert.Equal(t, socket, found)
+}
+
+func TestDiscoverSocket_NoneFound(t *testing.T) {
+	_, ok := DiscoverSocket([]string{"/nonexistent/docker.sock"})
+	assert.False(t, ok)
+}
+
+func TestConfigureHost_RespectsDockerHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2376")
+
+	assert.Empty(t, ConfigureHost(nil))
+	assert.Equal(t, "tcp://10.0.0.1:2376", os.Getenv("DOCKER_HOST"))
+}
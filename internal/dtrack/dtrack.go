@@ -0,0 +1,146 @@
+// Package dtrack uploads SBOMs to OWASP Dependency-Track, so that the images scanned and patched
+// by the server show up in the organization's vulnerability management.
+package dtrack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// requestTimeout bounds a BOM upload
+const requestTimeout = 2 * time.Minute
+
+// Project maps the images whose reference starts with Prefix to a Dependency-Track project
+type Project struct {
+	Prefix string
+	Name   string
+}
+
+// ParseProjects parses project mappings of the form prefix=project, e.g. ghcr.io/org/api=api-service
+func ParseProjects(mappings []string) ([]Project, error) {
+	projects := make([]Project, 0, len(mappings))
+	for _, mapping := range mappings {
+		prefix, name, found := strings.Cut(mapping, "=")
+		prefix, name = strings.TrimSpace(prefix), strings.TrimSpace(name)
+		if !found || prefix == "" || name == "" {
+			return nil, fmt.Errorf("invalid project mapping %q: must be prefix=project", mapping)
+		}
+		projects = append(projects, Project{Prefix: prefix, Name: name})
+	}
+	return projects, nil
+}
+
+// Client uploads BOMs to a Dependency-Track server
+type Client struct {
+	url      string
+	apiKey   string
+	projects []Project
+	http     *http.Client
+}
+
+// New creates a client for the Dependency-Track API server at url, authenticating with apiKey.
+// projects map image references to project names; see Project.
+func New(url, apiKey string, projects []Project) *Client {
+	return &Client{
+		url:      strings.TrimSuffix(url, "/"),
+		apiKey:   apiKey,
+		projects: projects,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Enabled reports whether a Dependency-Track server is configured
+func (c *Client) Enabled() bool {
+	return c != nil && c.url != ""
+}
+
+// Project returns the Dependency-Track project name and version of image. The name comes from the
+// longest matching project mapping, and defaults to the image repository; the version is the tag
+// or digest of the image.
+func (c *Client) Project(image string) (name, version string) {
+	name, version = image, "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, version = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, version = name[:i], name[i+1:]
+	}
+
+	matched := ""
+	for _, project := range c.projects {
+		if strings.HasPrefix(image, project.Prefix) && len(project.Prefix) > len(matched) {
+			matched, name = project.Prefix, project.Name
+		}
+	}
+	return name, version
+}
+
+// UploadBOM uploads a CycloneDX BOM of image to its project, creating the project and version
+// if needed. Dependency-Track processes the BOM asynchronously; the returned token identifies
+// the processing task.
+func (c *Client) UploadBOM(ctx context.Context, image string, bom []byte) (token string, err error) {
+	name, version := c.Project(image)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("autoCreate", "true")
+	form.WriteField("projectName", name)
+	form.WriteField("projectVersion", version)
+	part, err := form.CreateFormFile("bom", "bom.json")
+	if err != nil {
+		return "", copaerrors.NewSystemError("failed to encode BOM upload", err)
+	}
+	part.Write(bom)
+	if err := form.Close(); err != nil {
+		return "", copaerrors.NewSystemError("failed to encode BOM upload", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/api/v1/bom", &body)
+	if err != nil {
+		return "", copaerrors.NewValidationError(fmt.Sprintf("invalid Dependency-Track URL: %s", c.url), err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", copaerrors.NewNetworkError("failed to reach Dependency-Track", err, "check the Dependency-Track URL and network connectivity")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp, fmt.Sprintf("Dependency-Track rejected the BOM of %s (project %s %s)", image, name, version))
+	}
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", copaerrors.NewExecutionError("failed to parse the Dependency-Track response", err)
+	}
+	return result.Token, nil
+}
+
+// statusError converts an unexpected Dependency-Track response into a categorized error
+func statusError(resp *http.Response, message string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return copaerrors.NewAuthError(message, err, "set COPA_MCP_DTRACK_API_KEY to a valid API key")
+	case resp.StatusCode == http.StatusForbidden:
+		return copaerrors.NewAuthError(message, err, "grant the API key's team the BOM_UPLOAD and PROJECT_CREATION_UPLOAD permissions")
+	case resp.StatusCode == http.StatusNotFound:
+		return copaerrors.NewValidationError(message, err, "check the Dependency-Track API server URL")
+	case resp.StatusCode >= 500:
+		return copaerrors.NewNetworkError(message, err)
+	}
+	return copaerrors.NewExecutionError(message, err)
+}
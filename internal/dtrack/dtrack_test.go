@@ -0,0 +1,110 @@
+package dtrack
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProjects(t *testing.T) {
+	projects, err := ParseProjects([]string{"ghcr.io/org/api=api-service", " ghcr.io/org/ = platform "})
+	require.NoError(t, err)
+	assert.Equal(t, []Project{{"ghcr.io/org/api", "api-service"}, {"ghcr.io/org/", "platform"}}, projects)
+
+	for _, mapping := range []string{"ghcr.io/org", "=project", "ghcr.io/org="} {
+		_, err := ParseProjects([]string{mapping})
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestProject(t *testing.T) {
+	c := New("https://dtrack.example.com", "key", []Project{
+		{Prefix: "ghcr.io/org/", Name: "platform"},
+		{Prefix: "ghcr.io/org/api", Name: "api-service"},
+	})
+
+	tests := []struct {
+		image   string
+		name    string
+		version string
+	}{
+		{"ghcr.io/org/api:1.2", "api-service", "1.2"},
+		{"ghcr.io/org/web:3.0-patched", "platform", "3.0-patched"},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000/team/app", "latest"},
+		{"nginx@sha256:abc", "nginx", "sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			name, version := c.Project(tt.image)
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func TestUploadBOM(t *testing.T) {
+	var fields map[string]string
+	var apiKey, bom string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/bom", r.URL.Path)
+		require.Equal(t, http.MethodPost, r.Method)
+		apiKey = r.Header.Get("X-Api-Key")
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		fields = map[string]string{}
+		for key, values := range r.MultipartForm.Value {
+			fields[key] = values[0]
+		}
+		file, _, err := r.FormFile("bom")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		bom = string(data)
+		w.Write([]byte(`{"token":"task-1"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", "secret", nil)
+	token, err := c.UploadBOM(context.Background(), "ghcr.io/org/api:1.2-patched", []byte(`{"bomFormat":"CycloneDX"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", token)
+	assert.Equal(t, "secret", apiKey)
+	assert.Equal(t, map[string]string{"autoCreate": "true", "projectName": "ghcr.io/org/api", "projectVersion": "1.2-patched"}, fields)
+	assert.Equal(t, `{"bomFormat":"CycloneDX"}`, bom)
+}
+
+func TestUploadBOM_Errors(t *testing.T) {
+	tests := []struct {
+		status   int
+		category copaerrors.Category
+	}{
+		{http.StatusUnauthorized, copaerrors.CategoryAuth},
+		{http.StatusForbidden, copaerrors.CategoryAuth},
+		{http.StatusNotFound, copaerrors.CategoryValidation},
+		{http.StatusServiceUnavailable, copaerrors.CategoryNetwork},
+		{http.StatusBadRequest, copaerrors.CategoryExecution},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			_, err := New(srv.URL, "secret", nil).UploadBOM(context.Background(), "nginx:1.25", []byte(`{}`))
+			require.Error(t, err)
+			assert.Equal(t, tt.category, copaerrors.CategoryOf(err))
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var c *Client
+	assert.False(t, c.Enabled())
+	assert.False(t, New("", "", nil).Enabled())
+	assert.True(t, New("https://dtrack.example.com", "key", nil).Enabled())
+}
@@ -0,0 +1,115 @@
+// Package e2e runs the MCP server in-process for end-to-end tests of the tool flows. The server is
+// connected to an in-memory client, and the copa, trivy and docker executables it runs are replaced
+// by the stubs of package mock, which write canned reports, VEX documents and command output, so
+// that the tests run in CI without Docker, copa or Trivy installed.
+//
+// The stubs are the test binary itself, so a test package using the harness must call Main from
+// its TestMain:
+//
+//	func TestMain(m *testing.M) { e2e.Main(m) }
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copamcp"
+	"github.com/project-copacetic/mcp-server/internal/mock"
+)
+
+// Main runs the tests of m or, when the test binary was started as one of the stubs, the stub
+func Main(m *testing.M) {
+	mock.RunIfStub()
+	os.Exit(m.Run())
+}
+
+// Harness is an in-process server with an in-memory client session, running the stub executables
+type Harness struct {
+	t       testing.TB
+	dir     string
+	Session *mcp.ClientSession
+}
+
+// New starts a server with cfg, or the default configuration when cfg is nil, whose copa, trivy
+// and docker are the stubs. The environment changes are undone, and the session is closed, when
+// the test ends; tests using the harness cannot run in parallel.
+func New(t testing.TB, cfg *config.Config) *Harness {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := mock.Install(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(mock.DirEnv, dir)
+	// Registry logins and other daemons are configured by the environment, which must not leak into the tests
+	t.Setenv("REGISTRY_TOKEN", "")
+	t.Setenv("DOCKER_HOST", "")
+	for _, name := range mock.Executables {
+		t.Setenv(mock.FailEnvPrefix+strings.ToUpper(name), "")
+	}
+
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := copamcp.Connect(ctx, copamcp.NewServer("e2e", cfg))
+	if err != nil {
+		cancel()
+		t.Fatalf("connecting to the server: %v", err)
+	}
+	t.Cleanup(func() {
+		session.Close()
+		cancel()
+	})
+
+	return &Harness{t: t, dir: dir, Session: session}
+}
+
+// CallTool calls the tool name with args, failing the test on a protocol error. Tool errors are
+// returned in the result, with IsError set.
+func (h *Harness) CallTool(name string, args any) *mcp.CallToolResult {
+	h.t.Helper()
+	result, err := h.Session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		h.t.Fatalf("calling %s: %v", name, err)
+	}
+	return result
+}
+
+// Decode decodes the structured content of result into v
+func (h *Harness) Decode(result *mcp.CallToolResult, v any) {
+	h.t.Helper()
+	data, err := json.Marshal(result.StructuredContent)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		h.t.Fatalf("decoding the structured content: %v", err)
+	}
+}
+
+// Fail makes the stub name exit 1 with stderr on its next invocations, until the test ends
+func (h *Harness) Fail(name, stderr string) {
+	h.t.Setenv(mock.FailEnvPrefix+strings.ToUpper(name), stderr)
+}
+
+// FailOnce makes the first run of the command of the stub name, e.g. docker push, fail with stderr
+func (h *Harness) FailOnce(name, command, stderr string) {
+	h.t.Setenv(mock.FailOnceEnvPrefix+strings.ToUpper(name+"_"+command), stderr)
+}
+
+// Calls returns the arguments of each invocation of the stub name, in order
+func (h *Harness) Calls(name string) [][]string {
+	h.t.Helper()
+	calls, err := mock.Calls(h.dir, name)
+	if err != nil {
+		h.t.Fatalf("reading the stub calls: %v", err)
+	}
+	return calls
+}
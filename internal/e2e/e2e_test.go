@@ -0,0 +1,652 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copamcp"
+	"github.com/project-copacetic/mcp-server/internal/mock"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) { Main(m) }
+
+func TestVersion(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolVersion, map[string]any{})
+	require.False(t, result.IsError)
+	var ver types.Ver
+	h.Decode(result, &ver)
+	assert.Equal(t, mock.CopaVersion, ver.Version)
+	assert.Equal(t, "0.60.0", ver.Trivy)
+	assert.Empty(t, ver.Missing)
+}
+
+func TestScanThenPatchReportBased(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+	var scan trivy.ScanResult
+	h.Decode(result, &scan)
+	assert.Equal(t, len(mock.Vulnerabilities), scan.VulnCount)
+	require.DirExists(t, scan.ReportPath)
+	res, err := h.Session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: scan.ReportURI})
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Contains(t, res.Contents[0].Text, "CVE-2024-0001")
+	scanned := slices.ContainsFunc(h.Calls("trivy"), func(args []string) bool {
+		return len(args) > 0 && args[0] == "image" && argAfter(args, "--pkg-types") == "os"
+	})
+	assert.True(t, scanned, "trivy releases with --pkg-types are not passed the deprecated --vuln-type")
+
+	result = h.CallTool(copamcp.ToolPatchReportBased, map[string]any{
+		"image":       "alpine:3.19",
+		"patchtag":    "3.19-patched",
+		"reportPath":  scan.ReportPath,
+		"excludeCVEs": []string{"CVE-2024-0003"},
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.Equal(t, []string{"alpine:3.19-patched"}, patch.PatchedImage)
+	assert.Equal(t, 2, patch.NumFixedVulns)
+	require.NotNil(t, patch.Severity)
+	assert.Equal(t, 1, patch.Severity.Fixed.Critical)
+	assert.Equal(t, 1, patch.Severity.Remaining.Medium)
+	assert.True(t, patch.VexGenerated)
+	res, err = h.Session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: patch.VexURI})
+	require.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, "CVE-2024-0001")
+	require.NotNil(t, patch.Metrics)
+	assert.EqualValues(t, mock.ImageSize, patch.Metrics.PatchedImageBytes)
+
+	patches := h.Calls("copa")
+	require.Len(t, patches, 1)
+	assert.Equal(t, "alpine:3.19", argAfter(patches[0], "--image"))
+	assert.Equal(t, "3.19-patched", argAfter(patches[0], "--tag"))
+	assert.NotContains(t, patches[0], "--push")
+}
+
+func TestScanThenPatchReportBased_Severity(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19", "severity": []string{"critical", "high"}})
+	require.False(t, result.IsError, text(result))
+	var scan trivy.ScanResult
+	h.Decode(result, &scan)
+	scans := h.Calls("trivy")
+	require.NotEmpty(t, scans)
+	assert.Equal(t, "CRITICAL,HIGH", argAfter(scans[len(scans)-1], "--severity"))
+
+	result = h.CallTool(copamcp.ToolPatchReportBased, map[string]any{
+		"image":      "alpine:3.19",
+		"patchtag":   "3.19-patched",
+		"reportPath": scan.ReportPath,
+		"severity":   []string{"CRITICAL"},
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	require.NotNil(t, patch.Remaining)
+	assert.Equal(t, []string{"CVE-2024-0002", "CVE-2024-0003"}, patch.Remaining.Excluded.IDs)
+}
+
+func TestPatchComprehensive_DryRun(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{
+		"image":    "nginx:1.25",
+		"patchtag": "1.25-patched",
+		"dryRun":   true,
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.True(t, patch.DryRun)
+	assert.Equal(t, []string{"copa", "patch", "--image", "nginx:1.25", "--tag", "1.25-patched"}, patch.Command)
+	assert.Contains(t, text(result), "copa command: copa patch --image nginx:1.25 --tag 1.25-patched")
+	assert.Empty(t, h.Calls("copa"), "a dry run does not run copa")
+}
+
+func TestPatchComprehensive_Export(t *testing.T) {
+	h := New(t, nil)
+	exportPath := filepath.Join(t.TempDir(), "patched.tar")
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{
+		"image":      "nginx:1.25",
+		"patchtag":   "1.25-patched",
+		"exportPath": exportPath,
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.Equal(t, exportPath, patch.ExportPath)
+	assert.FileExists(t, exportPath)
+
+	saved := slices.ContainsFunc(h.Calls("docker"), func(args []string) bool {
+		return len(args) > 0 && args[0] == "save" && slices.Contains(args, "nginx:1.25-patched")
+	})
+	assert.True(t, saved, "the patched image is exported with docker save")
+}
+
+func TestPatchComprehensive_ContainerdNamespace(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{
+		"image":               "nginx:1.25",
+		"patchtag":            "1.25-patched",
+		"containerdNamespace": "k8s.io",
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.Equal(t, "k8s.io", patch.ContainerdNamespace)
+
+	ctr := h.Calls("ctr")
+	require.Len(t, ctr, 2)
+	assert.Equal(t, []string{"--namespace", "k8s.io", "images", "export"}, ctr[0][:4])
+	assert.Equal(t, "docker.io/library/nginx:1.25", ctr[0][len(ctr[0])-1])
+	assert.Equal(t, []string{"--namespace", "k8s.io", "images", "import"}, ctr[1][:4])
+
+	var docker []string
+	for _, args := range h.Calls("docker") {
+		if args[0] == "load" || args[0] == "save" {
+			docker = append(docker, args[0])
+		}
+	}
+	assert.Equal(t, []string{"load", "save"}, docker, "the image is loaded into the daemon before patching and saved after")
+}
+
+func TestPatchComprehensive_CopaFailure(t *testing.T) {
+	h := New(t, nil)
+	h.Fail("copa", "Error: unsupported image OS: distroless")
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "gcr.io/distroless/static", "patchtag": "patched"})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "execution", toolErr.Category)
+	require.NotNil(t, toolErr.Command)
+	assert.Equal(t, 1, toolErr.Command.ExitCode)
+	assert.Contains(t, toolErr.Command.StderrTail, "unsupported image OS")
+}
+
+func TestScanContainer_SubprocessEnv(t *testing.T) {
+	cfg := config.Default()
+	cfg.SubprocessEnv = []string{mock.FailEnvPrefix + "TRIVY="}
+	h := New(t, cfg)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+
+	// The call's value reaches trivy in place of the server's
+	result = h.CallTool(copamcp.ToolScanContainer, map[string]any{
+		"image": "alpine:3.20",
+		"env":   map[string]string{mock.FailEnvPrefix + "TRIVY": "FATAL unsupported cache directory"},
+	})
+	require.True(t, result.IsError)
+	assert.Contains(t, text(result), "unsupported cache directory")
+
+	// Variables the server does not set cannot be injected
+	result = h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19", "env": map[string]string{"LD_PRELOAD": "/tmp/x.so"}})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "validation", toolErr.Category)
+	assert.Contains(t, text(result), "LD_PRELOAD")
+	calls := h.Calls("trivy")
+	last := calls[len(calls)-1]
+	assert.Equal(t, "alpine:3.20", last[len(last)-1], "nothing is scanned with an injected variable")
+}
+
+func TestTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.SubprocessEnv = []string{mock.DelayEnvPrefix + "TRIVY=", mock.DelayEnvPrefix + "COPA="}
+	cfg.ToolTimeout = time.Minute
+	h := New(t, cfg)
+
+	// The server default leaves room for the stubs
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+
+	result = h.CallTool(copamcp.ToolScanContainer, map[string]any{
+		"image":          "alpine:3.19",
+		"timeoutSeconds": 1,
+		"env":            map[string]string{mock.DelayEnvPrefix + "TRIVY": "10s"},
+	})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "timeout", toolErr.Category)
+	assert.Contains(t, text(result), "did not finish within 1s")
+
+	result = h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{
+		"image":          "alpine:3.19",
+		"patchtag":       "patched",
+		"timeoutSeconds": 1,
+		"env":            map[string]string{mock.DelayEnvPrefix + "COPA": "10s"},
+	})
+	require.True(t, result.IsError)
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "timeout", toolErr.Category)
+	require.NotNil(t, toolErr.Command)
+	assert.Contains(t, toolErr.Command.Command, "copa patch")
+}
+
+func TestTimeout_ServerDefault(t *testing.T) {
+	cfg := config.Default()
+	cfg.SubprocessEnv = []string{mock.DelayEnvPrefix + "COPA="}
+	cfg.ToolTimeout = time.Second
+	h := New(t, cfg)
+
+	result := h.CallTool(copamcp.ToolRemediate, map[string]any{
+		"image": "alpine:3.19",
+		"env":   map[string]string{mock.DelayEnvPrefix + "COPA": "10s"},
+	})
+	require.True(t, result.IsError)
+	assert.Contains(t, text(result), "failed at the patch stage")
+	assert.Contains(t, text(result), "copa did not finish within 1s")
+
+	// The fleet scan has no environment overrides, so the delay is the server's
+	cfg = config.Default()
+	cfg.SubprocessEnv = []string{mock.DelayEnvPrefix + "TRIVY=10s"}
+	cfg.ToolTimeout = time.Second
+	cfg.Retry.MaxAttempts = 1
+	h = New(t, cfg)
+
+	result = h.CallTool(copamcp.ToolScanRegistry, map[string]any{"repositories": []string{"docker.io/library/nginx"}})
+	require.False(t, result.IsError, text(result))
+	var report types.FleetReport
+	h.Decode(result, &report)
+	require.Len(t, report.Images, 1)
+	assert.Contains(t, report.Images[0].Error, "did not finish within 1s")
+
+	result = h.CallTool(copamcp.ToolGenerateSBOM, map[string]any{"image": "alpine:3.19"})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "timeout", toolErr.Category)
+	assert.Contains(t, text(result), "trivy SBOM of alpine:3.19 did not finish within 1s")
+}
+
+func TestRegistryRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Registries.SourceAllow = []string{"localhost:5000/*", "docker.io/library"}
+	cfg.Registries.PushDeny = []string{"docker.io"}
+	// Pinning the pushed image fails fast against the unreachable registry
+	cfg.Retry.MaxAttempts = 1
+	h := New(t, cfg)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "ghcr.io/org/app:1.0"})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "policy", toolErr.Category)
+	assert.Contains(t, text(result), "images from ghcr.io are not allowed")
+
+	result = h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "nginx:1.25", "patchtag": "patched", "push": true})
+	require.True(t, result.IsError)
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "policy", toolErr.Category)
+	assert.Contains(t, text(result), "pushing nginx:patched is not allowed: denied by docker.io")
+	assert.Empty(t, h.Calls("copa"), "nothing is patched before the rules pass")
+
+	result = h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "localhost:5000/team/app:1.0", "patchtag": "patched", "push": true})
+	require.False(t, result.IsError, text(result))
+}
+
+func TestPatchReportBased_DestinationPush(t *testing.T) {
+	cfg := config.Default()
+	cfg.Registries.PushDeny = []string{"docker.io"}
+	// Pinning the pushed image fails fast against the unreachable registry
+	cfg.Retry.MaxAttempts = 1
+	h := New(t, cfg)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+	var scan trivy.ScanResult
+	h.Decode(result, &scan)
+
+	// The destination registry is checked against the push rules before anything is patched
+	result = h.CallTool(copamcp.ToolPatchReportBased, map[string]any{
+		"image":       "alpine:3.19",
+		"patchtag":    "3.19-patched",
+		"reportPath":  scan.ReportPath,
+		"destination": "docker.io/golden/alpine",
+		"push":        true,
+	})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "policy", toolErr.Category)
+	assert.Empty(t, h.Calls("copa"), "nothing is patched before the rules pass")
+
+	result = h.CallTool(copamcp.ToolPatchReportBased, map[string]any{
+		"image":       "alpine:3.19",
+		"patchtag":    "3.19-patched",
+		"reportPath":  scan.ReportPath,
+		"destination": "localhost:5000/golden/alpine",
+		"push":        true,
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.Equal(t, []string{"localhost:5000/golden/alpine:3.19-patched"}, patch.PatchedImage)
+
+	patches := h.Calls("copa")
+	require.Len(t, patches, 1)
+	assert.NotContains(t, patches[0], "--push", "copa leaves the push to the destination")
+	var sequence [][]string
+	for _, args := range h.Calls("docker") {
+		if len(args) > 0 && (args[0] == "tag" || args[0] == "push") {
+			sequence = append(sequence, args)
+		}
+	}
+	assert.Equal(t, [][]string{
+		{"tag", "alpine:3.19-patched", "localhost:5000/golden/alpine:3.19-patched"},
+		{"push", "localhost:5000/golden/alpine:3.19-patched"},
+	}, sequence)
+}
+
+func TestPatchComprehensive_DestinationPushRetried(t *testing.T) {
+	h := New(t, nil)
+	h.FailOnce("docker", "push", "received unexpected HTTP status: 504 Gateway Timeout")
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{
+		"image":       "nginx:1.25",
+		"patchtag":    "patched",
+		"destination": "localhost:5000/golden/nginx",
+		"push":        true,
+		"retry":       map[string]any{"backoff": "10ms"},
+	})
+	require.False(t, result.IsError, text(result))
+	var patch types.PatchResult
+	h.Decode(result, &patch)
+	assert.Equal(t, []string{"localhost:5000/golden/nginx:patched"}, patch.PatchedImage)
+
+	assert.Len(t, h.Calls("copa"), 1, "only the failed push is retried, not the patch")
+	var pushes int
+	for _, args := range h.Calls("docker") {
+		if len(args) > 0 && args[0] == "push" {
+			pushes++
+		}
+	}
+	assert.Equal(t, 2, pushes)
+}
+
+func TestRegistryRules_RegistryTokenDoesNotPush(t *testing.T) {
+	cfg := config.Default()
+	cfg.Registries.PushDeny = []string{"docker.io"}
+	h := New(t, cfg)
+	t.Setenv("REGISTRY_TOKEN", "s3cret")
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "nginx:1.25", "patchtag": "patched", "push": false})
+	require.False(t, result.IsError, text(result))
+
+	patches := h.Calls("copa")
+	require.Len(t, patches, 1)
+	assert.NotContains(t, patches[0], "--push", "logging in with REGISTRY_TOKEN does not push past the push rules")
+	assert.True(t, slices.ContainsFunc(h.Calls("docker"), func(args []string) bool {
+		return len(args) > 0 && args[0] == "login"
+	}), "the token is still used to log in")
+}
+
+func TestScanContainer_DaemonUnavailable(t *testing.T) {
+	h := New(t, nil)
+	h.Fail("docker", "Cannot connect to the Docker daemon at unix:///var/run/docker.sock")
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "system", toolErr.Category)
+	assert.Empty(t, h.Calls("trivy"), "nothing is scanned without a daemon")
+}
+
+func TestGenerateSBOM(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolGenerateSBOM, map[string]any{"image": "ghcr.io/org/app:1.0"})
+	require.False(t, result.IsError, text(result))
+	var sbom types.SBOM
+	h.Decode(result, &sbom)
+	assert.Equal(t, "cyclonedx", sbom.Format)
+	assert.FileExists(t, sbom.SBOMPath)
+	res, err := h.Session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: sbom.SBOMURI})
+	require.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, `"bomFormat": "CycloneDX"`)
+
+	result = h.CallTool(copamcp.ToolGenerateSBOM, map[string]any{"image": "ghcr.io/org/app:1.0", "format": "spdx"})
+	require.False(t, result.IsError, text(result))
+	h.Decode(result, &sbom)
+	res, err = h.Session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: sbom.SBOMURI})
+	require.NoError(t, err)
+	assert.Equal(t, "application/spdx+json", res.Contents[0].MIMEType)
+	assert.Contains(t, res.Contents[0].Text, `"spdxVersion": "SPDX-2.3"`)
+	calls := h.Calls("trivy")
+	assert.Equal(t, "spdx-json", argAfter(calls[len(calls)-1], "--format"))
+}
+
+func TestRegistryLogin(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolRegistryLogin, map[string]any{"registry": "ghcr.io", "password": "s3cret"})
+	require.False(t, result.IsError, text(result))
+	assert.Contains(t, text(result), "Logged in to ghcr.io as _token")
+	calls := h.Calls("docker")
+	login := calls[len(calls)-1]
+	assert.Equal(t, []string{"login", "ghcr.io", "-u", "_token", "--password-stdin"}, login)
+
+	// Arguments that docker would read as flags are rejected before it runs
+	result = h.CallTool(copamcp.ToolRegistryLogin, map[string]any{"registry": "--help", "username": "ci bot", "password": ""})
+	require.True(t, result.IsError)
+	var toolErr types.ToolError
+	h.Decode(result, &toolErr)
+	assert.Equal(t, "validation", toolErr.Category)
+	assert.Len(t, toolErr.Problems, 3)
+	assert.Len(t, h.Calls("docker"), len(calls))
+}
+
+func TestVerifyPatch(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolScanContainer, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+	var scan trivy.ScanResult
+	h.Decode(result, &scan)
+
+	// Before the patch, the rescan still finds every vulnerability of the report
+	result = h.CallTool(copamcp.ToolVerifyPatch, map[string]any{"image": "alpine:3.19", "patchedImage": "alpine:3.19", "reportPath": scan.ReportPath})
+	require.False(t, result.IsError, text(result))
+	var verification types.PatchVerification
+	h.Decode(result, &verification)
+	assert.False(t, verification.Verified)
+	assert.Empty(t, verification.Fixed)
+	assert.Len(t, verification.Remaining, len(mock.Vulnerabilities))
+	assert.Contains(t, text(result), "NOT verified")
+
+	result = h.CallTool(copamcp.ToolPatchReportBased, map[string]any{"image": "alpine:3.19", "patchtag": "3.19-patched", "reportPath": scan.ReportPath})
+	require.False(t, result.IsError, text(result))
+	// Without patchedImage, the image copa tagged with the default tag is rescanned
+	result = h.CallTool(copamcp.ToolVerifyPatch, map[string]any{"image": "alpine:3.19", "reportPath": scan.ReportPath})
+	require.False(t, result.IsError, text(result))
+	h.Decode(result, &verification)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, "alpine:3.19-patched", verification.PatchedImage)
+	assert.Equal(t, []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"}, verification.Fixed)
+	assert.Empty(t, verification.Remaining)
+	assert.Empty(t, verification.Introduced)
+	assert.Equal(t, 1, verification.Before.Critical)
+	assert.Equal(t, types.SeverityCounts{}, verification.After)
+	res, err := h.Session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: verification.RescanReportURI})
+	require.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, "alpine:3.19-patched")
+}
+
+func TestPatchHistory(t *testing.T) {
+	cfg := config.Default()
+	cfg.History = filepath.Join(t.TempDir(), "state", "history.jsonl")
+	h := New(t, cfg)
+
+	result := h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "nginx:1.25", "patchtag": "1.25-patched", "dryRun": true})
+	require.False(t, result.IsError, text(result))
+	result = h.CallTool(copamcp.ToolPatchComprehensive, map[string]any{"image": "nginx:1.25", "patchtag": "1.25-patched"})
+	require.False(t, result.IsError, text(result))
+	result = h.CallTool(copamcp.ToolRemediate, map[string]any{"image": "alpine:3.19"})
+	require.False(t, result.IsError, text(result))
+
+	result = h.CallTool(copamcp.ToolPatchHistory, map[string]any{})
+	require.False(t, result.IsError, text(result))
+	var history types.PatchHistory
+	h.Decode(result, &history)
+	require.Equal(t, 2, history.Total, "dry runs are not recorded")
+	assert.Equal(t, copamcp.ToolRemediate, history.Patches[0].Tool, "newest first")
+	assert.Equal(t, []string{"alpine:3.19-patched"}, history.Patches[0].PatchedImages)
+	assert.Equal(t, 3, history.Patches[0].NumFixedVulns)
+	assert.Equal(t, "nginx:1.25", history.Patches[1].Image)
+	assert.Equal(t, []string{"nginx:1.25-patched"}, history.Patches[1].PatchedImages)
+
+	result = h.CallTool(copamcp.ToolPatchHistory, map[string]any{"image": "nginx", "since": "1h", "limit": 1})
+	require.False(t, result.IsError, text(result))
+	h.Decode(result, &history)
+	require.Len(t, history.Patches, 1)
+	assert.Equal(t, "nginx:1.25", history.Patches[0].Image)
+
+	// A corrupt line, e.g. truncated by a crash, is skipped with a warning
+	f, err := os.OpenFile(cfg.History, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2026-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	result = h.CallTool(copamcp.ToolPatchHistory, map[string]any{})
+	require.False(t, result.IsError, text(result))
+	h.Decode(result, &history)
+	assert.Equal(t, 2, history.Total)
+	require.Len(t, history.Warnings, 1)
+	assert.Contains(t, history.Warnings[0], "history.jsonl:3")
+	assert.Contains(t, text(result), "Warning: skipped")
+
+	// The history is disabled when the server has no history file
+	result = New(t, nil).CallTool(copamcp.ToolPatchHistory, map[string]any{})
+	require.True(t, result.IsError)
+	assert.Contains(t, text(result), config.EnvHistory)
+}
+
+func TestDoctor(t *testing.T) {
+	h := New(t, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	result := h.CallTool(copamcp.ToolDoctor, map[string]any{"registries": []string{srv.URL}})
+	require.False(t, result.IsError, text(result))
+	var report types.DoctorReport
+	h.Decode(result, &report)
+	assert.True(t, report.Ready, text(result))
+	statuses := map[string]string{}
+	for _, c := range report.Checks {
+		statuses[c.Name] = c.Status
+	}
+	for _, name := range []string{"docker", "buildkit", "copa", "trivy", "registry:" + srv.Listener.Addr().String(), "disk"} {
+		assert.Equal(t, "ok", statuses[name], name)
+	}
+	assert.Contains(t, statuses, "features")
+
+	// An unreachable registry fails its check without failing the call
+	srv.Close()
+	result = h.CallTool(copamcp.ToolDoctor, map[string]any{"registries": []string{srv.URL}})
+	require.False(t, result.IsError, text(result))
+	h.Decode(result, &report)
+	assert.False(t, report.Ready)
+	assert.Contains(t, text(result), "Environment NOT ready")
+}
+
+func TestRemediate(t *testing.T) {
+	h := New(t, nil)
+
+	result := h.CallTool(copamcp.ToolRemediate, map[string]any{"image": "alpine:3.19", "patchtag": "3.19-patched"})
+	require.False(t, result.IsError, text(result))
+	var report types.Remediation
+	h.Decode(result, &report)
+	assert.True(t, report.Verified)
+	assert.Equal(t, "alpine:3.19-patched", report.PatchedImage)
+	assert.Equal(t, []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"}, report.Fixed)
+	assert.Empty(t, report.Remaining)
+	assert.Empty(t, report.Introduced)
+	require.NotNil(t, report.Before)
+	require.NotNil(t, report.After)
+	assert.Equal(t, 1, report.Before.Critical)
+	assert.Equal(t, types.SeverityCounts{}, *report.After)
+	assert.NotEmpty(t, report.VexPath)
+
+	var statuses []string
+	for _, stage := range report.Stages {
+		statuses = append(statuses, stage.Name+":"+stage.Status)
+	}
+	assert.Equal(t, []string{"scan:completed", "patch:completed", "rescan:completed", "compare:completed", "push:skipped", "sign:skipped"}, statuses)
+}
+
+func TestRemediate_Resume(t *testing.T) {
+	h := New(t, nil)
+	h.Fail("copa", "Error: failed to solve: apk update failed")
+
+	result := h.CallTool(copamcp.ToolRemediate, map[string]any{"image": "alpine:3.19"})
+	require.True(t, result.IsError)
+	id := regexp.MustCompile(`resume: (\d+)`).FindStringSubmatch(text(result))
+	require.Len(t, id, 2, text(result))
+	assert.Contains(t, text(result), "failed at the patch stage")
+
+	// A resumed remediation must repeat push, which decides the scope of the call
+	result = h.CallTool(copamcp.ToolRemediate, map[string]any{"resume": id[1], "push": true})
+	require.True(t, result.IsError)
+	assert.Contains(t, text(result), "resume it with the same values")
+
+	h.Fail("copa", "")
+	result = h.CallTool(copamcp.ToolRemediate, map[string]any{"resume": id[1]})
+	require.False(t, result.IsError, text(result))
+	var report types.Remediation
+	h.Decode(result, &report)
+	assert.Equal(t, id[1], report.ID)
+	assert.True(t, report.Verified)
+	assert.Equal(t, "alpine:3.19-patched", report.PatchedImage)
+
+	var scans []string
+	for _, args := range h.Calls("trivy") {
+		if args[0] == "image" {
+			scans = append(scans, args[len(args)-1])
+		}
+	}
+	assert.Equal(t, []string{"alpine:3.19", "alpine:3.19-patched"}, scans, "the completed scan is not run again")
+}
+
+func text(result *mcp.CallToolResult) string {
+	for _, c := range result.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			return t.Text
+		}
+	}
+	return ""
+}
+
+// argAfter returns the argument following flag in args
+func argAfter(args []string, flag string) string {
+	if i := slices.Index(args, flag); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed operations are retried. Only errors whose category is
+// listed in Retryable are retried; all other errors are returned immediately.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts, including the first; 1 disables retries
+	Backoff     time.Duration // Delay before the second attempt, doubled for each further attempt
+	Retryable   []Category    // Error categories worth retrying
+}
+
+// DefaultRetryPolicy retries transient network and registry failures
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     5 * time.Second,
+	Retryable:   []Category{CategoryNetwork},
+}
+
+// Categories lists every error category, in the order they are documented
+var Categories = []Category{CategoryValidation, CategoryAuth, CategoryNetwork, CategoryExecution, CategorySystem, CategoryPolicy, CategoryTimeout}
+
+// ParseCategories parses a comma-separated list of error categories, e.g. "network,execution"
+func ParseCategories(value string) ([]Category, error) {
+	var categories []Category
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		category := Category(name)
+		if !slices.Contains(Categories, category) {
+			return nil, fmt.Errorf("unknown error category %q, valid categories: %s", name, joinCategories(Categories))
+		}
+		if !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+// Validate checks that the policy is usable
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("retry max attempts must be at least 1, got %d", p.MaxAttempts)
+	}
+	if p.Backoff < 0 {
+		return fmt.Errorf("retry backoff must not be negative, got %s", p.Backoff)
+	}
+	return nil
+}
+
+// IsRetryable reports whether err belongs to one of the policy's retryable categories, and was
+// not returned by a Retry that already exhausted its attempts
+func (p RetryPolicy) IsRetryable(err error) bool {
+	var exhausted *exhaustedError
+	return err != nil && !errors.As(err, &exhausted) && slices.Contains(p.Retryable, CategoryOf(err))
+}
+
+// exhaustedError marks the error of the last attempt of a Retry, so that a Retry around the
+// operation that returned it, e.g. a tool call around its push, does not multiply the attempts
+type exhaustedError struct {
+	error
+}
+
+func (e *exhaustedError) Unwrap() error { return e.error }
+
+// Retry calls fn until it succeeds, fails with an error the policy does not retry, or the
+// attempts are exhausted, and returns the last error. onRetry, if not nil, is called before
+// each retry with the failed attempt number, the delay and the error.
+func Retry(ctx context.Context, p RetryPolicy, fn func() error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	delay := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !p.IsRetryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt > 1 {
+				return &exhaustedError{err}
+			}
+			return err
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func joinCategories(categories []Category) string {
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
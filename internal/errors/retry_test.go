@@ -0,0 +1,117 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Retryable: []Category{CategoryNetwork}}
+	networkErr := NewNetworkError("registry unreachable", nil)
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive attempts; nil once exhausted
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", errs: nil, wantCalls: 1},
+		{name: "recovers after retry", errs: []error{networkErr}, wantCalls: 2},
+		{name: "attempts exhausted", errs: []error{networkErr, networkErr, networkErr, networkErr}, wantCalls: 3, wantErr: true},
+		{name: "not retryable", errs: []error{NewValidationError("bad tag", nil)}, wantCalls: 1, wantErr: true},
+		{name: "uncategorized", errs: []error{fmt.Errorf("boom")}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, retries := 0, 0
+			err := Retry(context.Background(), policy, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}, func(int, time.Duration, error) { retries++ })
+
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantCalls-1, retries)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestRetry_BackoffDoubles(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond, Retryable: []Category{CategoryNetwork}}
+
+	var delays []time.Duration
+	err := Retry(context.Background(), policy, func() error {
+		return NewNetworkError("registry unreachable", nil)
+	}, func(_ int, delay time.Duration, _ error) { delays = append(delays, delay) })
+
+	assert.Error(t, err)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}, delays)
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Hour, Retryable: []Category{CategoryNetwork}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := Retry(ctx, policy, func() error {
+		calls++
+		return NewNetworkError("registry unreachable", nil)
+	}, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_ExhaustedNotRetriedAgain(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, Retryable: []Category{CategoryNetwork}}
+
+	calls := 0
+	err := Retry(context.Background(), policy, func() error {
+		// An operation retrying a step of its own, e.g. a patch pushing its image
+		return Retry(context.Background(), policy, func() error {
+			calls++
+			return NewNetworkError("push failed", nil)
+		}, nil)
+	}, nil)
+
+	require.Error(t, err)
+	assert.Equal(t, 2, calls, "the outer retry does not repeat the exhausted inner one")
+	assert.Equal(t, CategoryNetwork, CategoryOf(err))
+	assert.Equal(t, "push failed", err.Error())
+	assert.False(t, policy.IsRetryable(err))
+
+	// A single attempt leaves the error retryable by the caller
+	err = Retry(context.Background(), RetryPolicy{MaxAttempts: 1, Retryable: policy.Retryable}, func() error {
+		return NewNetworkError("push failed", nil)
+	}, nil)
+	assert.True(t, policy.IsRetryable(err))
+}
+
+func TestParseCategories(t *testing.T) {
+	categories, err := ParseCategories(" Network, execution,,network")
+	require.NoError(t, err)
+	assert.Equal(t, []Category{CategoryNetwork, CategoryExecution}, categories)
+
+	categories, err = ParseCategories("")
+	require.NoError(t, err)
+	assert.Empty(t, categories)
+
+	_, err = ParseCategories("network,flaky")
+	assert.ErrorContains(t, err, "flaky")
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	assert.NoError(t, DefaultRetryPolicy.Validate())
+	assert.Error(t, RetryPolicy{MaxAttempts: 0}.Validate())
+	assert.Error(t, RetryPolicy{MaxAttempts: 1, Backoff: -time.Second}.Validate())
+}
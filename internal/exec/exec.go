@@ -0,0 +1,94 @@
+// Package exec locates the copa and trivy CLIs the server runs. By default each is looked up by
+// name on the PATH, which 'install-dependencies' and mock mode prepend their directories to;
+// --copa-path and --trivy-path pin a binary instead. Import it as copaexec next to os/exec.
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// The CLIs located by the package
+const (
+	Copa  = "copa"
+	Trivy = "trivy"
+)
+
+var (
+	mu sync.RWMutex
+	// paths holds the configured binary of each tool; a tool without one is looked up on the PATH
+	paths = map[string]string{}
+)
+
+// SetPath makes tool run the binary at path, or the tool found on the PATH when path is empty
+func SetPath(tool, path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		delete(paths, tool)
+		return
+	}
+	paths[tool] = path
+}
+
+// Path returns the configured binary of tool, or its name to look up on the PATH
+func Path(tool string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if path, ok := paths[tool]; ok {
+		return path
+	}
+	return tool
+}
+
+// Configured reports whether tool runs a configured binary rather than the one on the PATH
+func Configured(tool string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := paths[tool]
+	return ok
+}
+
+// LookPath returns the path of the binary tool runs, failing when it is not found or not executable
+func LookPath(tool string) (string, error) {
+	path, err := exec.LookPath(Path(tool))
+	if err != nil && Configured(tool) {
+		return "", fmt.Errorf("%s binary %s: %w", tool, Path(tool), err)
+	}
+	return path, err
+}
+
+// gracePeriod is the time a cancelled command has to stop before it is killed
+const gracePeriod = 5 * time.Second
+
+// Command returns the command running tool with args, stopped with its subprocesses when ctx is cancelled
+func Command(ctx context.Context, tool string, args ...string) *exec.Cmd {
+	return Group(exec.CommandContext(ctx, Path(tool), args...))
+}
+
+// Group makes cmd, created with exec.CommandContext, stop along with the processes it starts when
+// its context is cancelled: they are asked to stop, and killed when still running after a grace
+// period. Wait returns at the latest a grace period later, even when a process keeps its output open.
+func Group(cmd *exec.Cmd) *exec.Cmd {
+	setGroup(cmd)
+	cmd.WaitDelay = 2 * gracePeriod
+	return cmd
+}
+
+// Canceled reports whether a command run with ctx failed because ctx was cancelled, rather than
+// because it timed out or failed by itself
+func Canceled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// NotFoundHint tells how to make tool available to the server
+func NotFoundHint(tool string) string {
+	if Configured(tool) {
+		return fmt.Sprintf("check the path given with --%s-path, %s", tool, Path(tool))
+	}
+	return fmt.Sprintf("ensure '%s' is on the server's PATH, pass its location with --%s-path, or call 'install-dependencies'", tool, tool)
+}
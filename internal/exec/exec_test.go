@@ -0,0 +1,49 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	t.Cleanup(func() { SetPath(Copa, "") })
+
+	assert.Equal(t, "copa", Path(Copa))
+	assert.False(t, Configured(Copa))
+	assert.Contains(t, NotFoundHint(Copa), "--copa-path")
+
+	SetPath(Copa, "/opt/copa/bin/copa")
+	assert.Equal(t, "/opt/copa/bin/copa", Path(Copa))
+	assert.True(t, Configured(Copa))
+	assert.Equal(t, "trivy", Path(Trivy), "each tool has its own path")
+	assert.Equal(t, "/opt/copa/bin/copa", Command(context.Background(), Copa, "--version").Args[0])
+	assert.Contains(t, NotFoundHint(Copa), "/opt/copa/bin/copa")
+
+	SetPath(Copa, "")
+	assert.Equal(t, "copa", Path(Copa))
+}
+
+func TestLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables are found by extension on Windows")
+	}
+	t.Cleanup(func() { SetPath(Trivy, "") })
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trivy-0.65")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755))
+
+	SetPath(Trivy, path)
+	found, err := LookPath(Trivy)
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+
+	SetPath(Trivy, filepath.Join(dir, "missing"))
+	_, err = LookPath(Trivy)
+	assert.ErrorContains(t, err, "trivy binary "+filepath.Join(dir, "missing"))
+}
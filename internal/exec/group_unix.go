@@ -0,0 +1,32 @@
+//go:build !windows
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setGroup starts cmd in a process group of its own, and makes cancelling its context ask the
+// whole group to stop with SIGTERM, then kill it with SIGKILL after gracePeriod. copa and trivy
+// start helper processes of their own, which would otherwise keep running after the CLI exits.
+func setGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		time.AfterFunc(gracePeriod, func() { _ = syscall.Kill(-pgid, syscall.SIGKILL) })
+		return nil
+	}
+}
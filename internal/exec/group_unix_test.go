@@ -0,0 +1,45 @@
+//go:build !windows
+
+package exec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup_StopsSubprocessesOnCancel(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	// The shell waits on a child of its own, as copa waits on buildkit's helpers
+	cmd := Group(exec.CommandContext(ctx, "sh", "-c", `sleep 60 & echo $! > "$0"; wait`, pidFile))
+	require.NoError(t, cmd.Start())
+
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(pidFile)
+		return err == nil && len(strings.TrimSpace(string(data))) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	start := time.Now()
+	assert.Error(t, cmd.Wait())
+	assert.Less(t, time.Since(start), gracePeriod, "SIGTERM stops the group without waiting for the kill")
+	assert.True(t, Canceled(ctx))
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return syscall.Kill(pid, 0) != nil
+	}, 5*time.Second, 10*time.Millisecond, "the child of the cancelled command is stopped too")
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package exec
+
+import "os/exec"
+
+// setGroup leaves cmd to the default cancellation, which kills the process: Windows has no
+// process group signals, and its console processes are not detached from the server
+func setGroup(cmd *exec.Cmd) {}
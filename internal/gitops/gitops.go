@@ -0,0 +1,134 @@
+// Package gitops renders the configuration that lets GitOps image automation deploy a patched
+// image: ImageRepository and ImagePolicy manifests for Flux image automation, or Application
+// annotations for Argo CD Image Updater. It is the alternative to pull requests from the server
+// for teams whose automation already watches their registries.
+package gitops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Image automation tools snippets are rendered for
+const (
+	Flux   = "flux"
+	ArgoCD = "argocd"
+)
+
+const (
+	// fluxNamespace is the namespace of the Flux image automation controllers
+	fluxNamespace = "flux-system"
+	// annotationPrefix prefixes the annotations read by Argo CD Image Updater
+	annotationPrefix = "argocd-image-updater.argoproj.io/"
+)
+
+// invalidName matches the characters not allowed in Kubernetes resource names and Image Updater aliases
+var invalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ValidateTool checks that tool is empty or a supported image automation tool
+func ValidateTool(tool string) error {
+	switch tool {
+	case "", Flux, ArgoCD:
+		return nil
+	}
+	return copaerrors.NewValidationError(fmt.Sprintf("unsupported gitops tool: %s", tool), nil, "set gitops to flux or argocd")
+}
+
+// Render returns the configuration for tool to deploy image, a patched image reference with a tag.
+// Copa patches an image again under the same tag, so the tag is tracked by digest: Flux reflects the
+// digest of the tag into the manifests and Image Updater uses its digest update strategy, so that
+// each new patch is rolled out.
+func Render(tool, image string) *types.GitOpsSnippets {
+	repository, tag := splitTag(image)
+	name := resourceName(repository)
+
+	switch tool {
+	case Flux:
+		return &types.GitOpsSnippets{
+			Tool:      Flux,
+			Manifests: fluxManifests(name, repository, tag),
+			Marker:    fmt.Sprintf(`image: %s # {"$imagepolicy": "%s:%s"}`, image, fluxNamespace, name),
+		}
+	case ArgoCD:
+		return &types.GitOpsSnippets{
+			Tool: ArgoCD,
+			Annotations: map[string]string{
+				annotationPrefix + "image-list":              name + "=" + image,
+				annotationPrefix + name + ".update-strategy": "digest",
+			},
+		}
+	}
+	return nil
+}
+
+func fluxManifests(name, repository, tag string) string {
+	return fmt.Sprintf(`apiVersion: image.toolkit.fluxcd.io/v1beta2
+kind: ImageRepository
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  image: %[3]s
+  interval: 5m
+---
+apiVersion: image.toolkit.fluxcd.io/v1beta2
+kind: ImagePolicy
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  imageRepositoryRef:
+    name: %[1]s
+  filterTags:
+    pattern: '^%[4]s$'
+  policy:
+    alphabetical:
+      order: asc
+  digestReflectionPolicy: Always
+  interval: 5m
+`, name, fluxNamespace, repository, regexp.QuoteMeta(tag))
+}
+
+// splitTag splits an image reference into its repository and tag, defaulting to latest
+func splitTag(image string) (repository, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// resourceName derives a Kubernetes resource name, also used as the Image Updater alias, from the
+// last path component of repository
+func resourceName(repository string) string {
+	name := strings.ToLower(repository[strings.LastIndex(repository, "/")+1:])
+	name = strings.Trim(invalidName.ReplaceAllString(name, "-"), "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		return "image"
+	}
+	return name
+}
+
+// Pin returns image, a pushed image reference with a tag, pinned to digest along with commands
+// deploying it. The deployment, container and service are named after the image's repository.
+func Pin(image, digest string) *types.PinnedReference {
+	repository, _ := splitTag(image)
+	reference := repository + "@" + digest
+	name := resourceName(repository)
+	return &types.PinnedReference{
+		Image:     image,
+		Digest:    digest,
+		Reference: reference,
+		Kubectl:   fmt.Sprintf("kubectl set image deployment/%[1]s %[1]s=%[2]s", name, reference),
+		Compose:   fmt.Sprintf("services:\n  %s:\n    image: %s\n", name, reference),
+	}
+}
@@ -0,0 +1,58 @@
+package gitops
+
+import (
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_Flux(t *testing.T) {
+	snippets := Render(Flux, "ghcr.io/org/My_App:1.25-patched")
+	require.NotNil(t, snippets)
+	assert.Equal(t, Flux, snippets.Tool)
+	assert.Contains(t, snippets.Manifests, "  name: my-app\n  namespace: flux-system\n")
+	assert.Contains(t, snippets.Manifests, "  image: ghcr.io/org/My_App\n")
+	assert.Contains(t, snippets.Manifests, `pattern: '^1\.25-patched$'`)
+	assert.Contains(t, snippets.Manifests, "digestReflectionPolicy: Always")
+	assert.Equal(t, `image: ghcr.io/org/My_App:1.25-patched # {"$imagepolicy": "flux-system:my-app"}`, snippets.Marker)
+	assert.Empty(t, snippets.Annotations)
+}
+
+func TestRender_ArgoCD(t *testing.T) {
+	snippets := Render(ArgoCD, "localhost:5000/nginx:1.25-patched")
+	require.NotNil(t, snippets)
+	assert.Equal(t, map[string]string{
+		"argocd-image-updater.argoproj.io/image-list":            "nginx=localhost:5000/nginx:1.25-patched",
+		"argocd-image-updater.argoproj.io/nginx.update-strategy": "digest",
+	}, snippets.Annotations)
+	assert.Empty(t, snippets.Manifests)
+}
+
+func TestValidateTool(t *testing.T) {
+	for _, tool := range []string{"", Flux, ArgoCD} {
+		assert.NoError(t, ValidateTool(tool))
+	}
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(ValidateTool("jenkins-x")))
+}
+
+func TestSplitTag(t *testing.T) {
+	repository, tag := splitTag("localhost:5000/nginx")
+	assert.Equal(t, "localhost:5000/nginx", repository)
+	assert.Equal(t, "latest", tag)
+	repository, tag = splitTag("nginx:1.25-patched@sha256:abc")
+	assert.Equal(t, "nginx", repository)
+	assert.Equal(t, "1.25-patched", tag)
+}
+
+func TestPin(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	pinned := Pin("ghcr.io/org/My_App:1.25-patched", digest)
+	assert.Equal(t, "ghcr.io/org/My_App:1.25-patched", pinned.Image)
+	assert.Equal(t, digest, pinned.Digest)
+	assert.Equal(t, "ghcr.io/org/My_App@"+digest, pinned.Reference)
+	assert.Equal(t, "kubectl set image deployment/my-app my-app=ghcr.io/org/My_App@"+digest, pinned.Kubectl)
+	assert.Equal(t, "services:\n  my-app:\n    image: ghcr.io/org/My_App@"+digest+"\n", pinned.Compose)
+}
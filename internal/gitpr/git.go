@@ -0,0 +1,141 @@
+package gitpr
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+const (
+	// requestTimeout bounds a provider API request
+	requestTimeout = time.Minute
+	// committerName and committerEmail author the commits of bumps, unless the server's environment
+	// sets GIT_AUTHOR_* and GIT_COMMITTER_*
+	committerName  = "copa-mcp"
+	committerEmail = "copa-mcp@noreply.localhost"
+)
+
+// Client clones repositories, pushes bump branches and opens pull requests
+type Client struct {
+	gitPath string
+	tokens  map[string]string
+	http    *http.Client
+}
+
+// New creates a client authenticating to GitHub with githubToken and to GitLab with gitlabToken.
+// Either may be empty to clone public repositories without pushing.
+func New(githubToken, gitlabToken string) *Client {
+	return &Client{
+		gitPath: "git",
+		tokens:  map[string]string{ProviderGitHub: githubToken, ProviderGitLab: gitlabToken},
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// CanPush reports whether a token is configured for the provider of repo
+func (c *Client) CanPush(repo Repository) bool {
+	return c.tokens[repo.Provider] != ""
+}
+
+// Clone makes a shallow clone of branch of repo into dir, and returns the branch cloned: the
+// repository's default branch when branch is empty
+func (c *Client) Clone(ctx context.Context, repo Repository, branch, dir string) (string, error) {
+	args := []string{"clone", "--depth", "1"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	if _, err := c.git(ctx, repo, "", append(args, repo.CloneURL, dir)...); err != nil {
+		return "", err
+	}
+	if branch != "" {
+		return branch, nil
+	}
+	head, err := c.git(ctx, repo, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	return strings.TrimSpace(head), err
+}
+
+// Push commits the changes to files on a new branch of the clone in dir and pushes the branch
+func (c *Client) Push(ctx context.Context, repo Repository, dir, branch, message string, files []string) error {
+	steps := [][]string{
+		{"checkout", "-b", branch},
+		append([]string{"add", "--"}, files...),
+		{"commit", "-m", message},
+		{"push", "origin", branch},
+	}
+	for _, args := range steps {
+		if _, err := c.git(ctx, repo, dir, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// git runs git with args in dir. The provider token is passed as an HTTP header through the
+// environment, so that it is neither written to the clone's configuration nor visible in process listings.
+func (c *Client) git(ctx context.Context, repo Repository, dir string, args ...string) (string, error) {
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, c.gitPath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if token := c.tokens[repo.Provider]; token != "" {
+		user := "x-access-token"
+		if repo.Provider == ProviderGitLab {
+			user = "oauth2"
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+		cmd.Env = append(cmd.Env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+	}
+	for _, identity := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		if os.Getenv(identity) == "" {
+			cmd.Env = append(cmd.Env, identity+"="+committerName)
+		}
+	}
+	for _, identity := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		if os.Getenv(identity) == "" {
+			cmd.Env = append(cmd.Env, identity+"="+committerEmail)
+		}
+	}
+
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", copaerrors.NewSystemError("git not found", err, "install git and make sure it is on the server's PATH")
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		message := strings.TrimSpace(stderr.String())
+		category := copaerrors.Classify(message, copaerrors.CategoryExecution)
+		var hints []string
+		if gitAuthFailure(message) {
+			category = copaerrors.CategoryAuth
+			hints = append(hints, fmt.Sprintf("check that the %s token is set and can read and push to %s", repo.Provider, repo.Path))
+		}
+		return "", copaerrors.New(category, fmt.Sprintf("git %s failed", args[0]), fmt.Errorf("%w\n%s", err, message), hints...).
+			WithCommand(cmd.Args, exitCode, message, time.Since(start))
+	}
+	return stdout.String(), nil
+}
+
+// gitAuthFailure reports whether git output shows that the remote rejected, or asked for, credentials
+func gitAuthFailure(output string) bool {
+	lower := strings.ToLower(output)
+	for _, pattern := range []string{"authentication failed", "could not read username", "returned error: 403", "permission to"} {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
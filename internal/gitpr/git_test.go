@@ -0,0 +1,64 @@
+package gitpr
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// originRepository creates a bare repository with a main branch holding a deployment of nginx:1.25
+func originRepository(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	origin, work := filepath.Join(t.TempDir(), "origin.git"), t.TempDir()
+	c := New("", "")
+	for _, args := range [][]string{{"init", "--bare", "--initial-branch", "main", origin}, {"clone", origin, work}} {
+		_, err := c.git(context.Background(), Repository{}, "", args...)
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(work, "deployment.yaml"), []byte("image: nginx:1.25\n"), 0o644))
+	for _, args := range [][]string{{"checkout", "-b", "main"}, {"add", "."}, {"commit", "-m", "Deploy nginx"}, {"push", "origin", "main"}} {
+		_, err := c.git(context.Background(), Repository{}, work, args...)
+		require.NoError(t, err)
+	}
+	return origin
+}
+
+func TestCloneAndPush(t *testing.T) {
+	repo := Repository{Provider: ProviderGitHub, Path: "org/deploy", CloneURL: originRepository(t)}
+	c := New("", "")
+	dir := filepath.Join(t.TempDir(), "clone")
+
+	base, err := c.Clone(context.Background(), repo, "", dir)
+	require.NoError(t, err)
+	assert.Equal(t, "main", base)
+
+	changes, err := Rewrite(dir, DefaultFiles, "nginx:1.25", patched)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.NoError(t, c.Push(context.Background(), repo, dir, "copa/nginx", "Patch nginx:1.25", []string{changes[0].Path}))
+
+	out, err := c.git(context.Background(), repo, "", "--git-dir", repo.CloneURL, "show", "copa/nginx:deployment.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "image: "+patched+"\n", out)
+}
+
+func TestClone_MissingBranch(t *testing.T) {
+	repo := Repository{Provider: ProviderGitHub, Path: "org/deploy", CloneURL: originRepository(t)}
+	_, err := New("", "").Clone(context.Background(), repo, "release", filepath.Join(t.TempDir(), "clone"))
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryExecution, copaerrors.CategoryOf(err))
+}
+
+func TestGitAuthFailure(t *testing.T) {
+	assert.True(t, gitAuthFailure("fatal: could not read Username for 'https://github.com': terminal prompts disabled"))
+	assert.True(t, gitAuthFailure("remote: Permission to org/deploy.git denied to bot."))
+	assert.False(t, gitAuthFailure("fatal: Remote branch release not found in upstream origin"))
+}
@@ -0,0 +1,241 @@
+// Package gitpr bumps the references to an image in a Git repository to its patched image and opens
+// a pull request (GitHub) or merge request (GitLab) with the change, closing the loop from patching
+// an image to deploying it. Git operations use the git CLI; pull requests use the provider's REST API.
+package gitpr
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Providers of the repositories pull requests are opened on
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// DefaultFiles are the files searched for image references when no globs are given
+var DefaultFiles = []string{"**/*.yaml", "**/*.yml", "**/*.json", "**/Dockerfile*", "**/*.tf"}
+
+// Repository is a Git repository hosted on GitHub or GitLab
+type Repository struct {
+	Provider string
+	// Path is the owner and name on GitHub, or the full project path on GitLab, e.g. group/sub/app
+	Path     string
+	CloneURL string
+	APIURL   string
+}
+
+// ParseRepository parses the HTTPS URL, or host/path, of a repository. The provider is detected from
+// the host (github.com, or a host containing gitlab) unless provider is set, e.g. for GitHub Enterprise.
+func ParseRepository(repository, provider string) (Repository, error) {
+	raw := repository
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return Repository{}, copaerrors.NewValidationError(fmt.Sprintf("invalid repository URL: %s", repository), err,
+			"use the repository's HTTPS URL without credentials, e.g. https://github.com/org/deploy")
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(repoPath, "/") < 1 {
+		return Repository{}, copaerrors.NewValidationError(fmt.Sprintf("invalid repository URL: %s", repository), nil,
+			"the URL must name the owner (or group) and the repository, e.g. https://github.com/org/deploy")
+	}
+
+	switch {
+	case provider != "":
+	case u.Host == "github.com":
+		provider = ProviderGitHub
+	case strings.Contains(u.Host, "gitlab"):
+		provider = ProviderGitLab
+	default:
+		return Repository{}, copaerrors.NewValidationError(fmt.Sprintf("cannot tell the provider of %s", repository), nil,
+			"set provider to github or gitlab")
+	}
+
+	repo := Repository{Provider: provider, Path: repoPath, CloneURL: fmt.Sprintf("%s://%s/%s.git", u.Scheme, u.Host, repoPath)}
+	switch provider {
+	case ProviderGitHub:
+		repo.APIURL = fmt.Sprintf("%s://%s/api/v3", u.Scheme, u.Host)
+		if u.Host == "github.com" {
+			repo.APIURL = "https://api.github.com"
+		}
+	case ProviderGitLab:
+		repo.APIURL = fmt.Sprintf("%s://%s/api/v4", u.Scheme, u.Host)
+	default:
+		return Repository{}, copaerrors.NewValidationError(fmt.Sprintf("unsupported provider: %s", provider), nil, "set provider to github or gitlab")
+	}
+	return repo, nil
+}
+
+// FileChange is a file whose image references were rewritten
+type FileChange struct {
+	Path         string // slash-separated, relative to the repository root
+	Replacements int
+}
+
+// Rewrite replaces the references to image in the files of dir matching globs with replacement,
+// and returns the files changed. See ReplaceReferences for the references that are matched.
+func Rewrite(dir string, globs []string, image, replacement string) ([]FileChange, error) {
+	var changes []FileChange
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !d.Type().IsRegular() || !matchAny(globs, rel) {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rewritten, n := ReplaceReferences(data, image, replacement)
+		if n == 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, rewritten, info.Mode().Perm()); err != nil {
+			return err
+		}
+		changes = append(changes, FileChange{Path: rel, Replacements: n})
+		return nil
+	})
+	if err != nil {
+		return nil, copaerrors.NewSystemError("failed to rewrite the image references", err)
+	}
+	return changes, nil
+}
+
+// ReplaceReferences replaces the references to image in data with replacement and returns the
+// number replaced. Docker Hub images also match their fully qualified forms (docker.io/library/...),
+// and a digest pinning a matched reference is replaced along with it. Longer references that merely
+// start with image, e.g. nginx:1.25.3 for nginx:1.25, are left alone.
+func ReplaceReferences(data []byte, image, replacement string) ([]byte, int) {
+	forms := referenceForms(image)
+	quoted := make([]string, len(forms))
+	for i, form := range forms {
+		quoted[i] = regexp.QuoteMeta(form)
+	}
+	re := regexp.MustCompile(`(^|[^A-Za-z0-9._/-])(` + strings.Join(quoted, "|") + `)(@sha256:[a-f0-9]{64})?($|[^A-Za-z0-9._/:@-])`)
+
+	n := 0
+	var out bytes.Buffer
+	last := 0
+	// Matches consume their boundary characters, so adjacent references are found by resuming the
+	// search at the trailing boundary
+	for start := 0; ; {
+		m := re.FindSubmatchIndex(data[start:])
+		if m == nil {
+			break
+		}
+		refStart, refEnd := start+m[4], start+m[5]
+		if m[6] >= 0 {
+			refEnd = start + m[7]
+		}
+		out.Write(data[last:refStart])
+		out.WriteString(replacement)
+		last, start, n = refEnd, refEnd, n+1
+	}
+	if n == 0 {
+		return data, 0
+	}
+	out.Write(data[last:])
+	return out.Bytes(), n
+}
+
+// referenceForms returns the ways image may be written in a file, longest first
+func referenceForms(image string) []string {
+	forms := []string{image}
+	name := image
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	host, rest, found := strings.Cut(image, "/")
+	switch {
+	case !found || (!strings.ContainsAny(host, ".:") && host != "localhost"):
+		// A Docker Hub image written without its registry
+		if !strings.Contains(name, "/") {
+			forms = append(forms, "docker.io/library/"+image, "index.docker.io/library/"+image, "library/"+image)
+		}
+		forms = append(forms, "docker.io/"+image, "index.docker.io/"+image)
+	case host == "docker.io" || host == "index.docker.io":
+		forms = append(forms, rest)
+		if short, ok := strings.CutPrefix(rest, "library/"); ok {
+			forms = append(forms, short)
+		}
+	}
+	// Longest first, so that docker.io/library/nginx:1.25 is not matched as library/nginx:1.25
+	slices.SortStableFunc(forms, func(a, b string) int { return len(b) - len(a) })
+	return forms
+}
+
+// matchAny reports whether the slash-separated path matches one of the globs. A ** segment matches
+// any number of directories; other segments follow path.Match.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if matchSegments(strings.Split(glob, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(glob, name []string) bool {
+	if len(glob) == 0 {
+		return len(name) == 0
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(glob[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(glob[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(glob[1:], name[1:])
+}
+
+// ValidateGlobs checks that globs are valid patterns
+func ValidateGlobs(globs []string) error {
+	for _, glob := range globs {
+		for _, segment := range strings.Split(glob, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return copaerrors.NewValidationError(fmt.Sprintf("invalid file glob: %s", glob), err)
+			}
+		}
+	}
+	return nil
+}
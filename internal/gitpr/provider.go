@@ -0,0 +1,103 @@
+package gitpr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// PullRequest is a pull request, or merge request, to open
+type PullRequest struct {
+	Title string
+	Body  string
+	Head  string // branch with the changes
+	Base  string // branch to merge into
+}
+
+// Opened is an opened pull request
+type Opened struct {
+	Number int
+	URL    string
+}
+
+// OpenPullRequest opens pr on repo with the provider's REST API
+func (c *Client) OpenPullRequest(ctx context.Context, repo Repository, pr PullRequest) (*Opened, error) {
+	var endpoint string
+	var payload any
+	switch repo.Provider {
+	case ProviderGitHub:
+		endpoint = fmt.Sprintf("%s/repos/%s/pulls", repo.APIURL, repo.Path)
+		payload = map[string]any{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base}
+	case ProviderGitLab:
+		endpoint = fmt.Sprintf("%s/projects/%s/merge_requests", repo.APIURL, url.PathEscape(repo.Path))
+		payload = map[string]any{"title": pr.Title, "description": pr.Body, "source_branch": pr.Head,
+			"target_branch": pr.Base, "remove_source_branch": true}
+	default:
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("unsupported provider: %s", repo.Provider), nil)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, copaerrors.NewSystemError("failed to encode the pull request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("invalid API URL: %s", repo.APIURL), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if repo.Provider == ProviderGitHub {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+c.tokens[ProviderGitHub])
+	} else {
+		req.Header.Set("PRIVATE-TOKEN", c.tokens[ProviderGitLab])
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, copaerrors.NewNetworkError(fmt.Sprintf("failed to reach the %s API", repo.Provider), err, "check the repository URL and network connectivity")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError(resp, repo, fmt.Sprintf("%s rejected the pull request from %s to %s", repo.Provider, pr.Head, pr.Base))
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		IID     int    `json:"iid"`
+		WebURL  string `json:"web_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, copaerrors.NewExecutionError("failed to parse the created pull request", err)
+	}
+	if repo.Provider == ProviderGitLab {
+		return &Opened{Number: created.IID, URL: created.WebURL}, nil
+	}
+	return &Opened{Number: created.Number, URL: created.HTMLURL}, nil
+}
+
+// statusError converts an unexpected provider API response into a categorized error
+func statusError(resp *http.Response, repo Repository, message string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return copaerrors.NewAuthError(message, err, fmt.Sprintf("set the %s token to a valid token", repo.Provider))
+	case resp.StatusCode == http.StatusForbidden:
+		return copaerrors.NewAuthError(message, err, fmt.Sprintf("the token needs permission to open pull requests on %s", repo.Path))
+	case resp.StatusCode == http.StatusNotFound:
+		return copaerrors.NewValidationError(message, err, "check the repository URL; private repositories also return not found to tokens without access")
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusUnprocessableEntity:
+		return copaerrors.NewValidationError(message, err, "a pull request for the branch may already exist; set a different branch name")
+	case resp.StatusCode >= 500:
+		return copaerrors.NewNetworkError(message, err)
+	}
+	return copaerrors.NewExecutionError(message, err)
+}
@@ -0,0 +1,74 @@
+package gitpr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bump = PullRequest{Title: "Patch nginx:1.25", Body: "Bumps the image", Head: "copa/nginx", Base: "main"}
+
+func TestOpenPullRequest_GitHub(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/org/deploy/pulls", r.URL.Path)
+		assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"title": bump.Title, "body": bump.Body, "head": bump.Head, "base": bump.Base}, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"number": 7, "html_url": "https://github.com/org/deploy/pull/7"})
+	}))
+	defer srv.Close()
+
+	repo := Repository{Provider: ProviderGitHub, Path: "org/deploy", APIURL: srv.URL}
+	opened, err := New("gh-token", "").OpenPullRequest(context.Background(), repo, bump)
+	require.NoError(t, err)
+	assert.Equal(t, &Opened{Number: 7, URL: "https://github.com/org/deploy/pull/7"}, opened)
+}
+
+func TestOpenPullRequest_GitLab(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/projects/group%2Fsub%2Fapp/merge_requests", r.URL.EscapedPath())
+		assert.Equal(t, "gl-token", r.Header.Get("PRIVATE-TOKEN"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, bump.Head, body["source_branch"])
+		assert.Equal(t, bump.Body, body["description"])
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"iid": 3, "web_url": "https://gitlab.example.com/group/sub/app/-/merge_requests/3"})
+	}))
+	defer srv.Close()
+
+	repo := Repository{Provider: ProviderGitLab, Path: "group/sub/app", APIURL: srv.URL}
+	opened, err := New("", "gl-token").OpenPullRequest(context.Background(), repo, bump)
+	require.NoError(t, err)
+	assert.Equal(t, &Opened{Number: 3, URL: "https://gitlab.example.com/group/sub/app/-/merge_requests/3"}, opened)
+}
+
+func TestOpenPullRequest_Errors(t *testing.T) {
+	tests := []struct {
+		status   int
+		category copaerrors.Category
+	}{
+		{http.StatusUnauthorized, copaerrors.CategoryAuth},
+		{http.StatusForbidden, copaerrors.CategoryAuth},
+		{http.StatusNotFound, copaerrors.CategoryValidation},
+		{http.StatusUnprocessableEntity, copaerrors.CategoryValidation},
+		{http.StatusBadGateway, copaerrors.CategoryNetwork},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rejected", tt.status)
+		}))
+		repo := Repository{Provider: ProviderGitHub, Path: "org/deploy", APIURL: srv.URL}
+		_, err := New("gh-token", "").OpenPullRequest(context.Background(), repo, bump)
+		assert.Equal(t, tt.category, copaerrors.CategoryOf(err), tt.status)
+		srv.Close()
+	}
+}
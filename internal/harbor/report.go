@@ -0,0 +1,166 @@
+// Package harbor fetches the vulnerability reports Harbor has already produced for an image
+// and converts them to Trivy reports, so that report-based patching can skip a second scan.
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+)
+
+// httpTimeout bounds the request for a vulnerability report
+const httpTimeout = 60 * time.Second
+
+// vulnerabilityReportMIME is the prefix of the report type keys in Harbor's vulnerabilities addition
+const vulnerabilityReportMIME = "application/vnd.security.vulnerability.report"
+
+// Settings locate and authenticate to the Harbor API
+type Settings struct {
+	URL      string // Harbor base URL; defaults to https:// and the image's registry host
+	Username string // Robot account or user; anonymous access when empty
+	Password string
+}
+
+// Reference is an image reference split into Harbor's project, repository and artifact reference
+type Reference struct {
+	Host       string
+	Project    string
+	Repository string // Repository within the project, e.g. "team/app"
+	Reference  string // Tag or digest
+}
+
+// ParseReference splits an image reference such as harbor.example.com/library/nginx:1.25 into
+// the parts used by Harbor's API. The tag defaults to latest.
+func ParseReference(image string) (*Reference, error) {
+	name, reference := image, "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+
+	parts := strings.Split(name, "/")
+	if len(parts) < 3 || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("image %s is not a Harbor image reference", image), nil,
+			"use a full reference such as harbor.example.com/project/repository:tag")
+	}
+	return &Reference{Host: parts[0], Project: parts[1], Repository: strings.Join(parts[2:], "/"), Reference: reference}, nil
+}
+
+// Vulnerability is a vulnerability in Harbor's native report format
+type Vulnerability struct {
+	ID          string   `json:"id"`
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	FixVersion  string   `json:"fix_version"`
+	Severity    string   `json:"severity"`
+	Description string   `json:"description"`
+	Links       []string `json:"links"`
+}
+
+// Report is Harbor's native vulnerability report for an artifact
+type Report struct {
+	GeneratedAt string `json:"generated_at"`
+	Scanner     struct {
+		Name    string `json:"name"`
+		Vendor  string `json:"vendor"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// FetchReport gets the vulnerability report of an artifact from Harbor
+func FetchReport(ctx context.Context, settings Settings, ref *Reference) (*Report, error) {
+	base := settings.URL
+	if base == "" {
+		base = "https://" + ref.Host
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(base, "/"))
+	if err != nil || baseURL.Host == "" {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("invalid Harbor URL: %s", base), err)
+	}
+
+	// Harbor requires the slashes of a nested repository name to be URL-encoded twice
+	path := fmt.Sprintf("/api/v2.0/projects/%s/repositories/%s/artifacts/%s/additions/vulnerabilities",
+		url.PathEscape(ref.Project), url.PathEscape(url.PathEscape(ref.Repository)), url.PathEscape(ref.Reference))
+	target, err := url.Parse(baseURL.String() + path)
+	if err != nil {
+		return nil, copaerrors.NewValidationError("invalid Harbor artifact reference", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, copaerrors.NewExecutionError("failed to create Harbor request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if settings.Username != "" {
+		req.SetBasicAuth(settings.Username, settings.Password)
+	}
+
+	resp, err := (&http.Client{Timeout: httpTimeout}).Do(req)
+	if err != nil {
+		return nil, copaerrors.NewNetworkError(fmt.Sprintf("failed to reach Harbor at %s", baseURL.Host), err,
+			"check the Harbor URL and network connectivity")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, copaerrors.NewAuthError("Harbor denied access to the vulnerability report", err,
+				"set COPA_MCP_HARBOR_USERNAME and COPA_MCP_HARBOR_PASSWORD to an account that can read the project")
+		case http.StatusNotFound:
+			return nil, copaerrors.NewValidationError("Harbor has no such artifact", err, "check the project, repository and tag")
+		}
+		return nil, copaerrors.NewNetworkError("Harbor vulnerability report request failed", err)
+	}
+
+	var reports map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
+		return nil, copaerrors.NewExecutionError("failed to parse the Harbor vulnerability report", err)
+	}
+	for mime, raw := range reports {
+		if !strings.HasPrefix(mime, vulnerabilityReportMIME) {
+			continue
+		}
+		var report Report
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return nil, copaerrors.NewExecutionError("failed to parse the Harbor vulnerability report", err)
+		}
+		return &report, nil
+	}
+	return nil, copaerrors.NewValidationError("Harbor has not scanned this artifact", nil,
+		"scan the artifact in Harbor first, or use 'scan-container' instead")
+}
+
+// WriteTrivyReport converts report to a Trivy JSON report in dir, as an OS package report for
+// the distribution osID (an os-release ID) and version. Only vulnerabilities with a fix are
+// included, as in the reports of 'scan-container'. It returns the number of vulnerabilities written.
+func WriteTrivyReport(report *Report, image, osID, osVersion, dir string) (int, error) {
+	vulns := make([]trivy.Vulnerability, 0, len(report.Vulnerabilities))
+	for _, v := range report.Vulnerabilities {
+		vuln := trivy.Vulnerability{
+			ID:               v.ID,
+			Package:          v.Package,
+			InstalledVersion: v.Version,
+			FixedVersion:     v.FixVersion,
+			Severity:         v.Severity,
+			Description:      v.Description,
+		}
+		if len(v.Links) > 0 {
+			vuln.PrimaryURL = v.Links[0]
+		}
+		vulns = append(vulns, vuln)
+	}
+	return trivy.WriteReport(dir, image, osID, osVersion, vulns)
+}
@@ -0,0 +1,134 @@
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+	}{
+		{"harbor.example.com/library/nginx:1.25", Reference{"harbor.example.com", "library", "nginx", "1.25"}},
+		{"harbor.example.com/library/nginx", Reference{"harbor.example.com", "library", "nginx", "latest"}},
+		{"harbor.example.com:8443/team/apps/api:v2", Reference{"harbor.example.com:8443", "team", "apps/api", "v2"}},
+		{"localhost/library/app@sha256:abc", Reference{"localhost", "library", "app", "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+
+	for _, image := range []string{"nginx:latest", "library/nginx", "team/apps/api:v2"} {
+		_, err := ParseReference(image)
+		assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err), image)
+	}
+}
+
+const harborResponse = `{
+  "application/vnd.security.vulnerability.report; version=1.1": {
+    "generated_at": "2024-05-01T10:00:00Z",
+    "scanner": {"name": "Trivy", "vendor": "Aqua Security", "version": "v0.50.1"},
+    "vulnerabilities": [
+      {"id": "CVE-2024-0001", "package": "openssl", "version": "3.0.11-1", "fix_version": "3.0.13-1", "severity": "High", "links": ["https://avd.aquasec.com/nvd/cve-2024-0001"]},
+      {"id": "CVE-2024-0002", "package": "zlib", "version": "1.2.13", "fix_version": "", "severity": "Low"}
+    ]
+  }
+}`
+
+func TestFetchReport(t *testing.T) {
+	var path, user, password string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		user, password, _ = r.BasicAuth()
+		w.Write([]byte(harborResponse))
+	}))
+	defer srv.Close()
+
+	ref := &Reference{Project: "team", Repository: "apps/api", Reference: "v2"}
+	report, err := FetchReport(context.Background(), Settings{URL: srv.URL + "/", Username: "robot$copa", Password: "secret"}, ref)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/api/v2.0/projects/team/repositories/apps%252Fapi/artifacts/v2/additions/vulnerabilities", path)
+	assert.Equal(t, "robot$copa", user)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "Trivy", report.Scanner.Name)
+	require.Len(t, report.Vulnerabilities, 2)
+	assert.Equal(t, "3.0.13-1", report.Vulnerabilities[0].FixVersion)
+}
+
+func TestFetchReport_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		category copaerrors.Category
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"errors":[{"code":"UNAUTHORIZED"}]}`, copaerrors.CategoryAuth},
+		{"not found", http.StatusNotFound, `{"errors":[{"code":"NOT_FOUND"}]}`, copaerrors.CategoryValidation},
+		{"not scanned", http.StatusOK, `{}`, copaerrors.CategoryValidation},
+		{"server error", http.StatusBadGateway, "", copaerrors.CategoryNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := FetchReport(context.Background(), Settings{URL: srv.URL}, &Reference{Project: "library", Repository: "nginx", Reference: "latest"})
+			assert.Equal(t, tt.category, copaerrors.CategoryOf(err), err)
+		})
+	}
+}
+
+func TestWriteTrivyReport(t *testing.T) {
+	var reports map[string]Report
+	require.NoError(t, json.Unmarshal([]byte(harborResponse), &reports))
+	report := reports["application/vnd.security.vulnerability.report; version=1.1"]
+
+	dir := t.TempDir()
+	count, err := WriteTrivyReport(&report, "harbor.example.com/library/app:1.0", "rhel", "9.4", dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	require.NoError(t, err)
+	var written struct {
+		Metadata struct {
+			OS struct{ Family, Name string }
+		}
+		Results []struct {
+			Class           string
+			Vulnerabilities []map[string]string
+		}
+	}
+	require.NoError(t, json.Unmarshal(data, &written))
+
+	assert.Equal(t, "redhat", written.Metadata.OS.Family)
+	assert.Equal(t, "9.4", written.Metadata.OS.Name)
+	require.Len(t, written.Results, 1)
+	assert.Equal(t, "os-pkgs", written.Results[0].Class)
+	assert.Equal(t, []map[string]string{{
+		"VulnerabilityID":  "CVE-2024-0001",
+		"PkgName":          "openssl",
+		"InstalledVersion": "3.0.11-1",
+		"FixedVersion":     "3.0.13-1",
+		"Severity":         "HIGH",
+		"PrimaryURL":       "https://avd.aquasec.com/nvd/cve-2024-0001",
+	}}, written.Results[0].Vulnerabilities)
+}
@@ -0,0 +1,126 @@
+// Package history records the completed patches of the server to a JSON Lines file, one patch per
+// line, so that what was patched, when, and to which digest can be queried across sessions and
+// server restarts. The file lives in the user's state directory by default, e.g.
+// ~/.local/state/copa-mcp/history.jsonl.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// maxLineBytes bounds a line of the history read by Query
+const maxLineBytes = 1 << 20
+
+// mu serializes the appends of the server's tool calls to a history file
+var mu sync.Mutex
+
+// DefaultPath returns copa-mcp/history.jsonl in $XDG_STATE_HOME, ~/.local/state when it is unset,
+// or the local application data directory on Windows
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" && runtime.GOOS == "windows" {
+		var err error
+		if dir, err = os.UserCacheDir(); err != nil {
+			return "", err
+		}
+	} else if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "copa-mcp", "history.jsonl"), nil
+}
+
+// Append appends r to the history at path, creating the file and its directory when they do not
+// exist
+func Append(path string, r types.PatchRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Filter selects the records returned by Query
+type Filter struct {
+	Image string    // Image reference, or repository matching each of its tags and digests; empty for all
+	Since time.Time // Only records at or after this time; zero for all
+}
+
+// Matches reports whether r is selected by f
+func (f Filter) Matches(r types.PatchRecord) bool {
+	if f.Image != "" && r.Image != f.Image && !strings.HasPrefix(r.Image, f.Image+":") && !strings.HasPrefix(r.Image, f.Image+"@") {
+		return false
+	}
+	if !f.Since.IsZero() {
+		t, err := time.Parse(time.RFC3339, r.Time)
+		if err != nil || t.Before(f.Since) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the records of the history at path selected by f, newest first, and a warning for
+// each line that could not be parsed, e.g. one truncated by a crash, which is skipped. A history
+// that does not exist yet has no records.
+func Query(path string, f Filter) ([]types.PatchRecord, []string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var records []types.PatchRecord
+	var warnings []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var r types.PatchRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s:%d, which is not a patch record: %v", path, line, err))
+			continue
+		}
+		if f.Matches(r) {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	slices.Reverse(records)
+	return records, warnings, nil
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+	records, _, err := Query(path, Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, records, "a missing history has no records")
+
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	now := time.Now().UTC().Format(time.RFC3339)
+	require.NoError(t, Append(path, types.PatchRecord{Time: old, Image: "nginx:1.25", PatchedImages: []string{"nginx:1.25-patched"}}))
+	require.NoError(t, Append(path, types.PatchRecord{Time: now, Image: "nginx:1.26", NumFixedVulns: 3}))
+	require.NoError(t, Append(path, types.PatchRecord{Time: now, Image: "nginx-unprivileged:1.26"}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	records, _, err = Query(path, Filter{Image: "nginx"})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "nginx:1.26", records[0].Image, "newest first")
+	assert.Equal(t, 3, records[0].NumFixedVulns)
+
+	records, _, err = Query(path, Filter{Image: "nginx:1.25"})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	records, _, err = Query(path, Filter{Since: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestQuery_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"image\":\"nginx:1.25\"}\n\nnot json\n"), 0o600))
+	records, warnings, err := Query(path, Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 1, "the records around a corrupt line are still returned")
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "history.jsonl:3")
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/var/lib/state")
+	path, err := DefaultPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/var/lib/state", "copa-mcp", "history.jsonl"), path)
+}
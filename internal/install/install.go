@@ -0,0 +1,332 @@
+// Package install downloads pinned releases of the copa and trivy CLIs into a managed bin
+// directory, so that the server runs without copa and trivy installed beforehand, and updates the
+// server binary to its latest release. Each archive is verified against the checksums published
+// with its release.
+package install
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// Pinned releases installed by Install
+const (
+	CopaVersion  = "0.11.1"
+	TrivyVersion = "0.65.0"
+)
+
+const (
+	// httpTimeout bounds the download of a release archive or checksum file
+	httpTimeout = 5 * time.Minute
+	// maxArchiveSize bounds a downloaded archive, and the binary extracted from it
+	maxArchiveSize = 512 << 20
+	// probeTimeout bounds the '<tool> --version' call checking an installed binary
+	probeTimeout = 10 * time.Second
+)
+
+// downloadURL is the base URL of the GitHub release downloads, replaced by tests
+var downloadURL = "https://github.com"
+
+// release describes how a tool is published on GitHub
+type release struct {
+	repository string
+	version    string
+	// asset returns the archive for a host platform, or "" when none is published
+	asset func(goos, goarch string) string
+}
+
+var releases = map[string]release{
+	"copa": {
+		repository: "project-copacetic/copacetic",
+		version:    CopaVersion,
+		asset: func(goos, goarch string) string {
+			if (goos != "linux" && goos != "darwin") || (goarch != "amd64" && goarch != "arm64") {
+				return ""
+			}
+			return fmt.Sprintf("copa_%s_%s_%s.tar.gz", CopaVersion, goos, goarch)
+		},
+	},
+	"trivy": {
+		repository: "aquasecurity/trivy",
+		version:    TrivyVersion,
+		asset: func(goos, goarch string) string {
+			osName := map[string]string{"linux": "Linux", "darwin": "macOS", "windows": "windows"}[goos]
+			archName := map[string]string{"amd64": "64bit", "arm64": "ARM64"}[goarch]
+			if osName == "" || archName == "" {
+				return ""
+			}
+			if goos == "windows" {
+				return fmt.Sprintf("trivy_%s_%s-%s.zip", TrivyVersion, osName, archName)
+			}
+			return fmt.Sprintf("trivy_%s_%s-%s.tar.gz", TrivyVersion, osName, archName)
+		},
+	},
+}
+
+// Tools lists the tools Install knows, in installation order
+var Tools = []string{"copa", "trivy"}
+
+// Dir returns the managed bin directory: dir when set, and copa-mcp/bin in the user cache
+// directory otherwise
+func Dir(dir string) (string, error) {
+	if dir != "" {
+		return filepath.Abs(dir)
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", copaerrors.NewSystemError("failed to locate the user cache directory", err,
+			"set COPA_MCP_BIN_DIR to the directory to install copa and trivy to")
+	}
+	return filepath.Join(cache, "copa-mcp", "bin"), nil
+}
+
+// UsePath puts dir first on the PATH of the process, so that the copa and trivy installed there
+// take precedence over other installations. It does nothing when dir does not exist or already
+// leads the PATH.
+func UsePath(dir string) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+	path := os.Getenv("PATH")
+	if first, _, _ := strings.Cut(path, string(os.PathListSeparator)); first == dir {
+		return
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+}
+
+// Install downloads the pinned release of tool into dir. A binary of the pinned version already in
+// dir is kept unless force is set.
+func Install(ctx context.Context, dir, tool string, force bool) (*types.InstalledTool, error) {
+	rel, ok := releases[tool]
+	if !ok {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("unknown tool: %s", tool), nil,
+			fmt.Sprintf("install one of: %s", strings.Join(Tools, ", ")))
+	}
+	asset := rel.asset(runtime.GOOS, runtime.GOARCH)
+	if asset == "" {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("%s publishes no release for %s/%s", tool, runtime.GOOS, runtime.GOARCH), nil,
+			fmt.Sprintf("install %s from source, or run the server in WSL or a Linux container", tool))
+	}
+
+	path := filepath.Join(dir, executable(tool))
+	result := &types.InstalledTool{Name: tool, Version: rel.version, Path: path}
+	if !force && installedVersion(ctx, path) == rel.version {
+		result.AlreadyInstalled = true
+		return result, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("failed to create %s", dir), err)
+	}
+
+	base := fmt.Sprintf("%s/%s/releases/download/v%s/", downloadURL, rel.repository, rel.version)
+	want, err := checksum(ctx, base+fmt.Sprintf("%s_%s_checksums.txt", tool, rel.version), asset)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := workdir.CreateTemp("download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		archive.Close()
+		os.Remove(archive.Name())
+	}()
+	got, err := download(ctx, base+asset, archive)
+	if err != nil {
+		return nil, err
+	}
+	if got != want {
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", asset, want, got), nil,
+			"retry the download; a persistent mismatch means the archive was tampered with or corrupted in transit")
+	}
+	result.SHA256 = got
+
+	if err := extract(archive, asset, executable(tool), path); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// executable returns the file name of the binary of tool on this host
+func executable(tool string) string {
+	if runtime.GOOS == "windows" {
+		return tool + ".exe"
+	}
+	return tool
+}
+
+// installedVersion returns the version path reports, or "" when it is missing or unrecognized
+func installedVersion(ctx context.Context, path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	v, ok := toolchain.ParseVersion(string(output))
+	if !ok {
+		return ""
+	}
+	return v.String()
+}
+
+// get requests url, returning the response when it succeeded
+func get(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, copaerrors.NewExecutionError("failed to create download request", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := (&http.Client{Timeout: httpTimeout}).Do(req)
+	if err != nil {
+		return nil, copaerrors.NewNetworkError(fmt.Sprintf("failed to download %s", url), err,
+			"check network connectivity to github.com, and HTTPS_PROXY if a proxy is required")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, copaerrors.NewNetworkError(fmt.Sprintf("failed to download %s: %s", url, resp.Status), nil)
+	}
+	return resp, nil
+}
+
+// checksum returns the SHA-256 of asset listed in the checksum file at url
+func checksum(ctx context.Context, url, asset string) (string, error) {
+	resp, err := get(ctx, url, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1<<20))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", copaerrors.NewNetworkError(fmt.Sprintf("failed to read %s", url), err)
+	}
+	return "", copaerrors.NewSystemError(fmt.Sprintf("%s lists no checksum for %s", url, asset), nil)
+}
+
+// download writes the file at url to w, returning its SHA-256
+func download(ctx context.Context, url string, w io.Writer) (string, error) {
+	resp, err := get(ctx, url, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return "", copaerrors.NewNetworkError(fmt.Sprintf("failed to download %s", url), err)
+	}
+	if n > maxArchiveSize {
+		return "", copaerrors.NewSystemError(fmt.Sprintf("%s is larger than %d MB", url, maxArchiveSize>>20), nil)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extract copies the file named name from the archive f, a .tar.gz or .zip named asset, to path
+func extract(f *os.File, asset, name, path string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return copaerrors.NewSystemError("failed to read the downloaded archive", err)
+	}
+
+	if strings.HasSuffix(asset, ".zip") {
+		info, err := f.Stat()
+		if err != nil {
+			return copaerrors.NewSystemError("failed to read the downloaded archive", err)
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return copaerrors.NewSystemError(fmt.Sprintf("failed to open %s", asset), err)
+		}
+		i := slices.IndexFunc(zr.File, func(zf *zip.File) bool { return filepath.Base(zf.Name) == name })
+		if i < 0 {
+			return copaerrors.NewSystemError(fmt.Sprintf("%s does not contain %s", asset, name), nil)
+		}
+		r, err := zr.File[i].Open()
+		if err != nil {
+			return copaerrors.NewSystemError(fmt.Sprintf("failed to extract %s from %s", name, asset), err)
+		}
+		defer r.Close()
+		return writeBinary(r, path)
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return copaerrors.NewSystemError(fmt.Sprintf("failed to open %s", asset), err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return copaerrors.NewSystemError(fmt.Sprintf("%s does not contain %s", asset, name), nil)
+		}
+		if err != nil {
+			return copaerrors.NewSystemError(fmt.Sprintf("failed to extract %s from %s", name, asset), err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+			return writeBinary(tr, path)
+		}
+	}
+}
+
+// writeBinary writes an executable to path through a temporary file in the same directory, so
+// that a failed write leaves any previous binary in place
+func writeBinary(r io.Reader, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return copaerrors.NewSystemError(fmt.Sprintf("failed to write %s", path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(r, maxArchiveSize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxArchiveSize {
+		err = fmt.Errorf("binary is larger than %d MB", maxArchiveSize>>20)
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return copaerrors.NewSystemError(fmt.Sprintf("failed to write %s", path), err)
+	}
+	return nil
+}
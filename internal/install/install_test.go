@@ -0,0 +1,169 @@
+package install
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCopa is the binary in the fake copa release, reporting the pinned version
+var fakeCopa = []byte("#!/bin/sh\necho \"copa version " + CopaVersion + "\"\n")
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "LICENSE", Mode: 0o644, Size: 3, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("MIT"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// fakeReleases serves the copa release for the host platform, with sum as the archive's listed checksum
+// (its real checksum when empty), and returns the number of archive downloads
+func fakeReleases(t *testing.T, sum string) *int {
+	t.Helper()
+	asset := releases["copa"].asset(runtime.GOOS, runtime.GOARCH)
+	if asset == "" {
+		t.Skipf("copa publishes no release for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	archive := tarGz(t, "copa", fakeCopa)
+	if sum == "" {
+		digest := sha256.Sum256(archive)
+		sum = hex.EncodeToString(digest[:])
+	}
+
+	downloads := 0
+	base := fmt.Sprintf("/project-copacetic/copacetic/releases/download/v%s/", CopaVersion)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case base + fmt.Sprintf("copa_%s_checksums.txt", CopaVersion):
+			fmt.Fprintf(w, "%s  copa_%s_other.tar.gz\n%s  %s\n", strings.Repeat("0", 64), CopaVersion, sum, asset)
+		case base + asset:
+			downloads++
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	downloadURL = srv.URL
+	t.Cleanup(func() { downloadURL = "https://github.com" })
+	workdir.Configure(t.TempDir(), 0)
+	t.Cleanup(func() { workdir.Configure("", 0) })
+	return &downloads
+}
+
+func TestInstall(t *testing.T) {
+	downloads := fakeReleases(t, "")
+	dir := filepath.Join(t.TempDir(), "bin")
+
+	installed, err := Install(context.Background(), dir, "copa", false)
+	require.NoError(t, err)
+	assert.Equal(t, "copa", installed.Name)
+	assert.Equal(t, CopaVersion, installed.Version)
+	assert.Equal(t, filepath.Join(dir, executable("copa")), installed.Path)
+	assert.Len(t, installed.SHA256, 64)
+	assert.False(t, installed.AlreadyInstalled)
+	content, err := os.ReadFile(installed.Path)
+	require.NoError(t, err)
+	assert.Equal(t, fakeCopa, content)
+	assert.Equal(t, 1, *downloads)
+
+	// The pinned version reported by the installed binary is kept
+	installed, err = Install(context.Background(), dir, "copa", false)
+	require.NoError(t, err)
+	assert.True(t, installed.AlreadyInstalled)
+	assert.Equal(t, 1, *downloads)
+
+	installed, err = Install(context.Background(), dir, "copa", true)
+	require.NoError(t, err)
+	assert.False(t, installed.AlreadyInstalled)
+	assert.Equal(t, 2, *downloads)
+}
+
+func TestInstall_ChecksumMismatch(t *testing.T) {
+	fakeReleases(t, strings.Repeat("a", 64))
+	dir := t.TempDir()
+
+	_, err := Install(context.Background(), dir, "copa", false)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	assert.NoFileExists(t, filepath.Join(dir, executable("copa")))
+}
+
+func TestInstall_UnknownTool(t *testing.T) {
+	_, err := Install(context.Background(), t.TempDir(), "grype", false)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestReleaseAssets(t *testing.T) {
+	assert.Equal(t, "copa_"+CopaVersion+"_linux_arm64.tar.gz", releases["copa"].asset("linux", "arm64"))
+	assert.Empty(t, releases["copa"].asset("windows", "amd64"))
+	assert.Equal(t, "trivy_"+TrivyVersion+"_Linux-64bit.tar.gz", releases["trivy"].asset("linux", "amd64"))
+	assert.Equal(t, "trivy_"+TrivyVersion+"_macOS-ARM64.tar.gz", releases["trivy"].asset("darwin", "arm64"))
+	assert.Equal(t, "trivy_"+TrivyVersion+"_windows-64bit.zip", releases["trivy"].asset("windows", "amd64"))
+	assert.Empty(t, releases["trivy"].asset("linux", "riscv64"))
+}
+
+func TestExtract_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("trivy.exe")
+	require.NoError(t, err)
+	w.Write([]byte("binary"))
+	require.NoError(t, zw.Close())
+
+	archive := filepath.Join(t.TempDir(), "trivy.zip")
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o600))
+	f, err := os.Open(archive)
+	require.NoError(t, err)
+	defer f.Close()
+
+	path := filepath.Join(t.TempDir(), "trivy.exe")
+	require.NoError(t, extract(f, "trivy_windows-64bit.zip", "trivy.exe", path))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(content))
+
+	err = extract(f, "trivy_windows-64bit.zip", "copa.exe", path)
+	assert.ErrorContains(t, err, "does not contain copa.exe")
+}
+
+func TestUsePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", "/usr/bin")
+
+	UsePath(filepath.Join(dir, "missing"))
+	assert.Equal(t, "/usr/bin", os.Getenv("PATH"))
+
+	UsePath(dir)
+	UsePath(dir)
+	assert.Equal(t, dir+string(os.PathListSeparator)+"/usr/bin", os.Getenv("PATH"))
+}
@@ -0,0 +1,153 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+const (
+	// serverRepository is the GitHub repository the server is released from, see .goreleaser.yml
+	serverRepository = "project-copacetic/mcp-server"
+	// serverBinary is the name of the server binary in the release archives
+	serverBinary = "copacetic-mcp-server"
+	// checksumsAsset is the checksum file goreleaser publishes with each release
+	checksumsAsset = "checksums.txt"
+)
+
+// apiURL is the base URL of the GitHub API, replaced by tests
+var apiURL = "https://api.github.com"
+
+// Release is a published release of the server
+type Release struct {
+	Version      string // Version without the leading v, as set in main.version by goreleaser
+	Archive      string // Name of the archive for this host
+	archiveURL   string
+	checksumsURL string
+}
+
+// LatestRelease returns the latest release of the server, with its archive for this host
+func LatestRelease(ctx context.Context) (*Release, error) {
+	resp, err := get(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", apiURL, serverRepository), "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var latest struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, copaerrors.NewExecutionError("failed to parse the latest release", err)
+	}
+	v, ok := toolchain.ParseVersion(latest.TagName)
+	if !ok {
+		return nil, copaerrors.NewExecutionError(fmt.Sprintf("the latest release has no version: %q", latest.TagName), nil)
+	}
+
+	// Named by the archives name_template of .goreleaser.yml
+	release := &Release{Version: v.String(), Archive: fmt.Sprintf("mcp-server_%s_%s_%s.tar.gz", v, runtime.GOOS, runtime.GOARCH)}
+	for _, asset := range latest.Assets {
+		switch asset.Name {
+		case release.Archive:
+			release.archiveURL = asset.URL
+		case checksumsAsset:
+			release.checksumsURL = asset.URL
+		}
+	}
+	if release.archiveURL == "" {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("release %s has no archive for %s/%s", latest.TagName, runtime.GOOS, runtime.GOARCH), nil)
+	}
+	if release.checksumsURL == "" {
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("release %s has no %s to verify the download against", latest.TagName, checksumsAsset), nil)
+	}
+	return release, nil
+}
+
+// UpdateResult is the outcome of Update
+type UpdateResult struct {
+	Current string // Version running before the update
+	Latest  string // Version of the latest release
+	Path    string // Binary that was, or would be, replaced
+	Updated bool   // True when the binary was replaced
+}
+
+// Update replaces the server binary at path with the latest release when it is newer than the
+// current version, or whenever force is set. With checkOnly, it only reports the latest release.
+// The archive is verified against the release's checksum file before anything is replaced.
+func Update(ctx context.Context, current, path string, checkOnly, force bool) (*UpdateResult, error) {
+	currentVersion, ok := toolchain.ParseVersion(current)
+	if !ok && !force && !checkOnly {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("cannot compare the development build %q with the releases", current), nil,
+			"pass --force to replace it with the latest release")
+	}
+
+	release, err := LatestRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &UpdateResult{Current: current, Latest: release.Version, Path: path}
+	latest, _ := toolchain.ParseVersion(release.Version)
+	if checkOnly || (!force && !currentVersion.Less(latest)) {
+		return result, nil
+	}
+
+	want, err := checksum(ctx, release.checksumsURL, release.Archive)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := workdir.CreateTemp("download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		archive.Close()
+		os.Remove(archive.Name())
+	}()
+	got, err := download(ctx, release.archiveURL, archive)
+	if err != nil {
+		return nil, err
+	}
+	if got != want {
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", release.Archive, want, got), nil,
+			"retry the update; a persistent mismatch means the archive was tampered with or corrupted in transit")
+	}
+
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be replaced on Windows, but it can be renamed
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return nil, copaerrors.NewSystemError(fmt.Sprintf("failed to move %s aside", path), err)
+		}
+	}
+	if err := extract(archive, release.Archive, executable(serverBinary), path); err != nil {
+		return nil, err
+	}
+	result.Updated = true
+	return result, nil
+}
+
+// Executable returns the path of the running server binary, with symbolic links resolved so that
+// Update replaces the binary rather than a link to it
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err == nil {
+		path, err = filepath.EvalSymlinks(path)
+	}
+	if err != nil {
+		return "", copaerrors.NewSystemError("failed to locate the server binary", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,121 @@
+package install
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServerRelease serves release v1.2.0 of the server, with sum as the archive's listed checksum
+// (its real checksum when empty)
+func fakeServerRelease(t *testing.T, sum string) {
+	t.Helper()
+	asset := fmt.Sprintf("mcp-server_1.2.0_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	archive := tarGz(t, executable(serverBinary), []byte("new server"))
+	if sum == "" {
+		digest := sha256.Sum256(archive)
+		sum = hex.EncodeToString(digest[:])
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + serverRepository + "/releases/latest":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v1.2.0",
+				"assets": []map[string]string{
+					{"name": asset, "browser_download_url": srv.URL + "/download/" + asset},
+					{"name": checksumsAsset, "browser_download_url": srv.URL + "/download/" + checksumsAsset},
+				},
+			})
+		case "/download/" + checksumsAsset:
+			fmt.Fprintf(w, "%s  %s\n", sum, asset)
+		case "/download/" + asset:
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	apiURL = srv.URL
+	t.Cleanup(func() { apiURL = "https://api.github.com" })
+	workdir.Configure(t.TempDir(), 0)
+	t.Cleanup(func() { workdir.Configure("", 0) })
+}
+
+// fakeBinary writes the running server binary for the test
+func fakeBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), executable(serverBinary))
+	require.NoError(t, os.WriteFile(path, []byte("old server"), 0o755))
+	return path
+}
+
+func TestUpdate(t *testing.T) {
+	fakeServerRelease(t, "")
+	path := fakeBinary(t)
+
+	result, err := Update(context.Background(), "1.1.3", path, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, &UpdateResult{Current: "1.1.3", Latest: "1.2.0", Path: path, Updated: true}, result)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new server", string(content))
+}
+
+func TestUpdate_UpToDate(t *testing.T) {
+	fakeServerRelease(t, "")
+	path := fakeBinary(t)
+
+	result, err := Update(context.Background(), "1.2.0", path, false, false)
+	require.NoError(t, err)
+	assert.False(t, result.Updated)
+
+	result, err = Update(context.Background(), "1.1.0", path, true, false)
+	require.NoError(t, err)
+	assert.False(t, result.Updated, "checking does not update")
+	assert.Equal(t, "1.2.0", result.Latest)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old server", string(content))
+}
+
+func TestUpdate_DevelopmentBuild(t *testing.T) {
+	fakeServerRelease(t, "")
+	path := fakeBinary(t)
+
+	_, err := Update(context.Background(), "dev", path, false, false)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+
+	result, err := Update(context.Background(), "dev", path, false, true)
+	require.NoError(t, err)
+	assert.True(t, result.Updated)
+}
+
+func TestUpdate_ChecksumMismatch(t *testing.T) {
+	fakeServerRelease(t, "0000000000000000000000000000000000000000000000000000000000000000")
+	path := fakeBinary(t)
+
+	_, err := Update(context.Background(), "1.1.3", path, false, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old server", string(content))
+}
@@ -0,0 +1,255 @@
+// Package joblog keeps the recent log events of each tool call in a bounded in-memory buffer, so
+// that a client attaching after a long scan or patch started, or before it set a log level, can
+// replay what happened instead of missing every event sent before.
+package joblog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/logging"
+	"github.com/project-copacetic/mcp-server/internal/otlp"
+)
+
+const (
+	// MaxJobs bounds the jobs kept; the oldest finished job is dropped first
+	MaxJobs = 50
+	// MaxEvents bounds the events kept per job; the oldest event is dropped first
+	MaxEvents = 500
+)
+
+// Event is a log message emitted during a job
+type Event struct {
+	Seq     int       `json:"seq"` // Position of the event in the job, starting at 1
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Logger  string    `json:"logger,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Summary describes a job without its events
+type Summary struct {
+	ID       string     `json:"id"`
+	Tool     string     `json:"tool"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Failed   bool       `json:"failed,omitempty"`
+	Events   int        `json:"events"`            // Events emitted, including dropped ones
+	Dropped  int        `json:"dropped,omitempty"` // Oldest events dropped from the buffer
+}
+
+// Job is a tool call whose events are buffered
+type Job struct {
+	mu      sync.Mutex
+	summary Summary
+	events  []Event
+	// progressToken is the progress token of the tool call, nil when the client sent none
+	progressToken any
+}
+
+// ID returns the job's identifier
+func (j *Job) ID() string {
+	return j.summary.ID
+}
+
+// Summary returns the job's state
+func (j *Job) Summary() Summary {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.summary
+}
+
+// Events returns the buffered events with a sequence number above since, oldest first
+func (j *Job) Events(since int) []Event {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var events []Event
+	for _, e := range j.events {
+		if e.Seq > since {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Record appends an event, dropping the oldest one when the buffer is full
+func (j *Job) Record(level, logger, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.summary.Events++
+	if len(j.events) == MaxEvents {
+		j.events = j.events[1:]
+		j.summary.Dropped++
+	}
+	j.events = append(j.events, Event{
+		Seq:     j.summary.Events,
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Logger:  logger,
+		Message: message,
+	})
+}
+
+// Finish marks the job as finished, and failed when failed is set
+func (j *Job) Finish(failed bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.summary.Finished = &now
+	j.summary.Failed = failed
+}
+
+// SetProgressToken sets the progress token of the tool call, that Progress notifies
+func (j *Job) SetProgressToken(token any) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progressToken = token
+}
+
+func (j *Job) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.summary.Finished != nil
+}
+
+var (
+	mu sync.Mutex
+	// jobs holds the kept jobs, oldest first. It is process-wide, shared by every session.
+	jobs []*Job
+	next atomic.Int64
+)
+
+// Start registers a job of tool and returns a context carrying it, for Log to record to
+func Start(ctx context.Context, tool string) (context.Context, *Job) {
+	j := &Job{summary: Summary{
+		ID:      fmt.Sprintf("%s-%d", tool, next.Add(1)),
+		Tool:    tool,
+		Started: time.Now().UTC(),
+	}}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(jobs) == MaxJobs {
+		drop := 0
+		for i, old := range jobs {
+			if old.finished() {
+				drop = i
+				break
+			}
+		}
+		jobs = append(jobs[:drop], jobs[drop+1:]...)
+	}
+	jobs = append(jobs, j)
+	return context.WithValue(ctx, jobKey{}, j), j
+}
+
+// Get returns the kept job with id
+func Get(id string) (*Job, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, j := range jobs {
+		if j.ID() == id {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// List describes the kept jobs, oldest first
+func List() []Summary {
+	mu.Lock()
+	kept := append([]*Job(nil), jobs...)
+	mu.Unlock()
+
+	summaries := make([]Summary, 0, len(kept))
+	for _, j := range kept {
+		summaries = append(summaries, j.Summary())
+	}
+	return summaries
+}
+
+type (
+	jobKey        struct{}
+	sessionKey    struct{}
+	quietProgress struct{}
+)
+
+// FromContext returns the job ctx carries
+func FromContext(ctx context.Context) (*Job, bool) {
+	j, ok := ctx.Value(jobKey{}).(*Job)
+	return j, ok
+}
+
+// WithSession returns a copy of ctx carrying the session of a tool call, that Log and Logf send
+// messages to when they are not given one
+func WithSession(ctx context.Context, session *mcp.ServerSession) context.Context {
+	if session == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// Log records params to the job ctx carries, if any, writes them to the server's logger, exports
+// them to the configured OTLP collector and sends them to session, or the session ctx carries.
+// Events are recorded whatever the log level the client set, so they can be replayed by a client
+// that sets one later.
+func Log(ctx context.Context, session *mcp.ServerSession, params *mcp.LoggingMessageParams) {
+	message := fmt.Sprint(params.Data)
+	attributes := map[string]string{"logger": params.Logger}
+	attrs := []any{"logger", params.Logger}
+	if j, ok := FromContext(ctx); ok {
+		j.Record(string(params.Level), params.Logger, message)
+		attributes["job.id"], attributes["tool"] = j.ID(), j.summary.Tool
+		attrs = append(attrs, "job.id", j.ID(), "tool", j.summary.Tool)
+	}
+	slog.Log(ctx, logging.Level(string(params.Level)), message, attrs...)
+	otlp.Emit(otlp.Record{Level: string(params.Level), Body: message, Attributes: attributes})
+	if session == nil {
+		session, _ = ctx.Value(sessionKey{}).(*mcp.ServerSession)
+	}
+	if session != nil {
+		session.Log(ctx, params)
+	}
+}
+
+// Logf logs a message of logger at level, an MCP logging level, with Log to the session ctx
+// carries, if any
+func Logf(ctx context.Context, level mcp.LoggingLevel, logger, format string, args ...any) {
+	Log(ctx, nil, &mcp.LoggingMessageParams{
+		Data:   fmt.Sprintf(format, args...),
+		Level:  level,
+		Logger: logger,
+	})
+}
+
+// WithoutProgress returns a copy of ctx whose progress notifications are dropped, for a step of a
+// job that reports its own progress, e.g. by stage, which the step's notifications would not increase
+func WithoutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quietProgress{}, true)
+}
+
+// Progress sends a progress notification for the job ctx carries, when its tool call has a progress
+// token. progress must increase with each notification of a job; total is 0 when unknown.
+func Progress(ctx context.Context, session *mcp.ServerSession, progress, total float64, message string) {
+	j, ok := FromContext(ctx)
+	if !ok || session == nil || ctx.Value(quietProgress{}) != nil {
+		return
+	}
+	j.mu.Lock()
+	token := j.progressToken
+	j.mu.Unlock()
+	if token == nil {
+		return
+	}
+	session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
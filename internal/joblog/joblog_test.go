@@ -0,0 +1,101 @@
+package joblog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reset clears the kept jobs for the test
+func reset(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	jobs = nil
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		jobs = nil
+		mu.Unlock()
+	})
+}
+
+func TestLog_RecordsWithoutSession(t *testing.T) {
+	reset(t)
+
+	ctx, job := Start(context.Background(), "scan-container")
+	Log(ctx, nil, &mcp.LoggingMessageParams{Data: "scanning", Level: "info", Logger: "trivy"})
+	Log(context.Background(), nil, &mcp.LoggingMessageParams{Data: "not a job", Level: "info"})
+	job.Finish(false)
+
+	got, ok := Get(job.ID())
+	require.True(t, ok)
+	events := got.Events(0)
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, events[0].Seq)
+	assert.Equal(t, "info", events[0].Level)
+	assert.Equal(t, "trivy", events[0].Logger)
+	assert.Equal(t, "scanning", events[0].Message)
+
+	summary := got.Summary()
+	assert.Equal(t, "scan-container", summary.Tool)
+	assert.NotNil(t, summary.Finished)
+	assert.False(t, summary.Failed)
+}
+
+func TestLog_WritesToLogger(t *testing.T) {
+	reset(t)
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	ctx, job := Start(context.Background(), "patch-report-based")
+	Logf(ctx, "warning", "copa", "retrying %s", "alpine:3.19")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "retrying alpine:3.19", record["msg"])
+	assert.Equal(t, "copa", record["logger"])
+	assert.Equal(t, job.ID(), record["job.id"])
+	assert.Equal(t, "patch-report-based", record["tool"])
+}
+
+func TestJob_DropsOldestEvents(t *testing.T) {
+	reset(t)
+
+	_, job := Start(context.Background(), "patch-comprehensive")
+	for range MaxEvents + 10 {
+		job.Record("debug", "copa", "line")
+	}
+
+	events := job.Events(0)
+	require.Len(t, events, MaxEvents)
+	assert.Equal(t, 11, events[0].Seq)
+	assert.Equal(t, 10, job.Summary().Dropped)
+	assert.Equal(t, MaxEvents+10, job.Summary().Events)
+	assert.Len(t, job.Events(MaxEvents+5), 5)
+}
+
+func TestStart_DropsOldestFinishedJob(t *testing.T) {
+	reset(t)
+
+	_, running := Start(context.Background(), "patch-report-based")
+	_, finished := Start(context.Background(), "scan-container")
+	finished.Finish(true)
+	for range MaxJobs - 1 {
+		Start(context.Background(), "scan-container")
+	}
+
+	list := List()
+	require.Len(t, list, MaxJobs)
+	assert.Equal(t, running.ID(), list[0].ID)
+	_, ok := Get(finished.ID())
+	assert.False(t, ok)
+}
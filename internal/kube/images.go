@@ -0,0 +1,135 @@
+// Package kube discovers the images running in a Kubernetes cluster. It drives kubectl, which
+// resolves the kubeconfig or in-cluster service account the same way it does for users.
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// podList is the subset of 'kubectl get pods -o json' used to collect images
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			InitContainers      []container `json:"initContainers"`
+			Containers          []container `json:"containers"`
+			EphemeralContainers []container `json:"ephemeralContainers"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type container struct {
+	Image string `json:"image"`
+}
+
+// ListImages returns the images of the pods in the cluster, with the number of pods running each
+func ListImages(ctx context.Context, params types.ListClusterImagesParams) (*types.ClusterImageList, error) {
+	args := getPodsArgs(params)
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, copaerrors.NewSystemError("kubectl not found", err, "install kubectl and make sure it is on the server's PATH")
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return nil, copaerrors.New(copaerrors.Classify(stderr.String(), copaerrors.CategoryExecution), "listing cluster pods failed",
+			fmt.Errorf("%w\n%s", err, strings.TrimSpace(stderr.String())),
+			"check the kubeconfig, context and RBAC permissions to list pods").
+			WithCommand(cmd.Args, exitCode, stderr.String(), time.Since(start))
+	}
+
+	return aggregateImages(output)
+}
+
+// getPodsArgs returns the kubectl arguments listing the pods selected by params
+func getPodsArgs(params types.ListClusterImagesParams) []string {
+	args := []string{"get", "pods", "--output", "json"}
+	if params.Namespace != "" {
+		args = append(args, "--namespace", params.Namespace)
+	} else {
+		args = append(args, "--all-namespaces")
+	}
+	if params.LabelSelector != "" {
+		args = append(args, "--selector", params.LabelSelector)
+	}
+	if params.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", params.Kubeconfig)
+	}
+	if params.Context != "" {
+		args = append(args, "--context", params.Context)
+	}
+	return args
+}
+
+// aggregateImages counts the pods running each image in a kubectl pod list. Pods that have
+// finished are skipped, and an image used by several containers of a pod counts the pod once.
+func aggregateImages(data []byte) (*types.ClusterImageList, error) {
+	var pods podList
+	if err := json.Unmarshal(data, &pods); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	list := &types.ClusterImageList{Images: []types.ClusterImage{}}
+	index := map[string]int{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		list.Pods++
+
+		seen := map[string]bool{}
+		containers := slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers, pod.Spec.EphemeralContainers)
+		for _, c := range containers {
+			if c.Image == "" || seen[c.Image] {
+				continue
+			}
+			seen[c.Image] = true
+
+			i, ok := index[c.Image]
+			if !ok {
+				i = len(list.Images)
+				index[c.Image] = i
+				list.Images = append(list.Images, types.ClusterImage{Image: c.Image})
+			}
+			image := &list.Images[i]
+			image.Pods++
+			if !slices.Contains(image.Namespaces, pod.Metadata.Namespace) {
+				image.Namespaces = append(image.Namespaces, pod.Metadata.Namespace)
+			}
+		}
+	}
+
+	for i := range list.Images {
+		slices.Sort(list.Images[i].Namespaces)
+	}
+	slices.SortStableFunc(list.Images, func(a, b types.ClusterImage) int {
+		if a.Pods != b.Pods {
+			return b.Pods - a.Pods
+		}
+		return strings.Compare(a.Image, b.Image)
+	})
+	return list, nil
+}
@@ -0,0 +1,65 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPodsArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params types.ListClusterImagesParams
+		want   []string
+	}{
+		{
+			name: "all namespaces",
+			want: []string{"get", "pods", "--output", "json", "--all-namespaces"},
+		},
+		{
+			name:   "namespace with selector and context",
+			params: types.ListClusterImagesParams{Namespace: "web", LabelSelector: "app=api", Kubeconfig: "/tmp/kubeconfig", Context: "prod"},
+			want: []string{"get", "pods", "--output", "json", "--namespace", "web", "--selector", "app=api",
+				"--kubeconfig", "/tmp/kubeconfig", "--context", "prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getPodsArgs(tt.params))
+		})
+	}
+}
+
+func TestAggregateImages(t *testing.T) {
+	pods := `{"items":[
+		{"metadata":{"namespace":"web"},"spec":{"initContainers":[{"image":"busybox:1.36"}],"containers":[{"image":"nginx:1.25"},{"image":"nginx:1.25"}]},"status":{"phase":"Running"}},
+		{"metadata":{"namespace":"api"},"spec":{"containers":[{"image":"nginx:1.25"}]},"status":{"phase":"Pending"}},
+		{"metadata":{"namespace":"jobs"},"spec":{"containers":[{"image":"alpine:3.18"}]},"status":{"phase":"Succeeded"}}
+	]}`
+
+	list, err := aggregateImages([]byte(pods))
+	require.NoError(t, err)
+
+	assert.Equal(t, &types.ClusterImageList{
+		Images: []types.ClusterImage{
+			{Image: "nginx:1.25", Pods: 2, Namespaces: []string{"api", "web"}},
+			{Image: "busybox:1.36", Pods: 1, Namespaces: []string{"web"}},
+		},
+		Pods: 2,
+	}, list)
+}
+
+func TestAggregateImages_NoPods(t *testing.T) {
+	list, err := aggregateImages([]byte(`{"items":[]}`))
+	require.NoError(t, err)
+	assert.Empty(t, list.Images)
+	assert.NotNil(t, list.Images)
+}
+
+func TestAggregateImages_InvalidOutput(t *testing.T) {
+	_, err := aggregateImages([]byte("error: the server doesn't have a resource type"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+// Package logging configures the structured logger the server writes its messages to. The copa,
+// trivy and server packages log with log/slog, so that their messages share one format and level
+// on stderr, and the messages of a tool call are also sent as MCP logging notifications (see joblog).
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats lists the supported log formats
+var Formats = []string{FormatText, FormatJSON}
+
+// Levels lists the supported log levels, most verbose first
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Configure makes the default slog logger write the records at level or above to w in format
+func Configure(w io.Writer, format, level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unsupported log format %q: use one of %s", format, strings.Join(Formats, ", "))
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ParseLevel parses one of Levels, or "warning", case-insensitively. An empty level is info.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unsupported log level %q: use one of %s", level, strings.Join(Levels, ", "))
+}
+
+// ValidateFormat checks that format is one of Formats, or empty for text
+func ValidateFormat(format string) error {
+	if format != "" && !slices.Contains(Formats, strings.ToLower(format)) {
+		return fmt.Errorf("unsupported log format %q: use one of %s", format, strings.Join(Formats, ", "))
+	}
+	return nil
+}
+
+// Level returns the slog level of an MCP logging level (debug, info, notice, warning, error,
+// critical, alert or emergency). Levels slog lacks map to the nearest one.
+func Level(mcpLevel string) slog.Level {
+	switch mcpLevel {
+	case "debug":
+		return slog.LevelDebug
+	case "warning", "warn":
+		return slog.LevelWarn
+	case "error", "critical", "alert", "emergency":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
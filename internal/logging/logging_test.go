@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	var buf bytes.Buffer
+	require.NoError(t, Configure(&buf, FormatJSON, "warn"))
+	slog.Info("skipped")
+	slog.Warn("kept", "logger", "copa")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, "copa", record["logger"])
+
+	buf.Reset()
+	require.NoError(t, Configure(&buf, FormatText, "debug"))
+	slog.Debug("probing")
+	assert.Contains(t, buf.String(), "level=DEBUG msg=probing")
+
+	assert.Error(t, Configure(&buf, "logfmt", "info"))
+	assert.Error(t, Configure(&buf, FormatText, "verbose"))
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, Level("debug"))
+	assert.Equal(t, slog.LevelInfo, Level("notice"))
+	assert.Equal(t, slog.LevelWarn, Level("warning"))
+	assert.Equal(t, slog.LevelError, Level("critical"))
+
+	level, err := ParseLevel("WARNING")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+}
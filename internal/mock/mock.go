@@ -0,0 +1,140 @@
+// Package mock simulates copa, trivy, docker and ctr with stub executables that return canned scan
+// reports, VEX documents and command output, for the server's mock mode and end-to-end tests. The
+// stubs are the running binary itself, installed under each executable's name, so a binary using
+// them must call RunIfStub before anything else.
+package mock
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+const (
+	// DirEnv holds the directory of the installed stubs. The binary runs as a stub only when it is
+	// set and the binary was started under a stub's name.
+	DirEnv = "COPA_MCP_MOCK_STUBS"
+	// FailEnvPrefix, followed by the upper-cased stub name, holds the stderr of a stub made to fail
+	FailEnvPrefix = "COPA_MCP_MOCK_FAIL_"
+	// FailOnceEnvPrefix, followed by the upper-cased stub name and command joined by _ (e.g.
+	// DOCKER_PUSH), holds the stderr of the command's first run, made to fail; later runs succeed
+	FailOnceEnvPrefix = "COPA_MCP_MOCK_FAIL_ONCE_"
+	// DelayEnvPrefix, followed by the upper-cased stub name, holds how long the stub sleeps before
+	// it runs (e.g. 5s), to simulate a slow scan or patch
+	DelayEnvPrefix = "COPA_MCP_MOCK_DELAY_"
+	// callsFile records the arguments of each stub invocation, one JSON object per line
+	callsFile = "calls.jsonl"
+	// failedOncePrefix, followed by the stub name and command, names the file marking the command's
+	// first run failed by FailOnceEnvPrefix
+	failedOncePrefix = "failed-once-"
+	// patchedFile lists the references of the images patched by the copa stub, one per line
+	patchedFile = "patched"
+)
+
+// Executables are the executables simulated by the stubs
+var Executables = []string{"copa", "trivy", "docker", "ctr"}
+
+// call is an invocation of a stub
+type call struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+// RunIfStub runs the stub and exits, when the binary was started as one of the installed stubs
+func RunIfStub() {
+	dir := os.Getenv(DirEnv)
+	if dir == "" {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if slices.Contains(Executables, name) {
+		os.Exit(runStub(dir, name, os.Args[1:], os.Stdout, os.Stderr))
+	}
+}
+
+// Install installs the running binary into dir as each of the stubs
+func Install(dir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating the running binary: %w", err)
+	}
+	suffix := ""
+	if runtime.GOOS == "windows" {
+		suffix = ".exe"
+	}
+	for _, name := range Executables {
+		if err := linkOrCopy(exe, filepath.Join(dir, name+suffix)); err != nil {
+			return fmt.Errorf("installing the %s stub: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Setup installs the stubs into a temporary directory and puts it first on the PATH of the process,
+// so that its subprocesses run the stubs. The returned function removes the directory.
+func Setup() (func(), error) {
+	dir, err := os.MkdirTemp("", "copa-mcp-mock-")
+	if err != nil {
+		return nil, err
+	}
+	if err := Install(dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv(DirEnv, dir)
+	return func() { os.RemoveAll(dir) }, nil
+}
+
+// Calls returns the arguments of each invocation of the stub name installed in dir, in order
+func Calls(dir, name string) ([][]string, error) {
+	f, err := os.Open(filepath.Join(dir, callsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var calls [][]string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var c call
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, err
+		}
+		if c.Name == name {
+			calls = append(calls, c.Args)
+		}
+	}
+	return calls, scanner.Err()
+}
+
+// linkOrCopy hard links src to dst, copying it where links are not supported
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
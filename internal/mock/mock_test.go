@@ -0,0 +1,112 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStub_Trivy(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "report.json")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, runStub(dir, "trivy", []string{"image", "-f", "json", "-o", output, "alpine:3.19"}, &stdout, &stderr), stderr.String())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var doc trivyReport
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "alpine:3.19", doc.ArtifactName)
+	require.Len(t, doc.Results, 1)
+	assert.Len(t, doc.Results[0].Vulnerabilities, len(Vulnerabilities)+1, "unfixed vulnerabilities are reported unless ignored")
+
+	require.Equal(t, 0, runStub(dir, "trivy", []string{"image", "--ignore-unfixed", "-o", output, "alpine:3.19"}, &stdout, &stderr))
+	findings, err := readReports(output)
+	require.NoError(t, err)
+	assert.Len(t, findings, len(Vulnerabilities))
+}
+
+func TestRunStub_CopaWritesVex(t *testing.T) {
+	dir := t.TempDir()
+	reports := filepath.Join(dir, "reports")
+	require.NoError(t, os.Mkdir(reports, 0o700))
+	vex := filepath.Join(dir, "vex.json")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, runStub(dir, "trivy", []string{"image", "--ignore-unfixed", "-o", filepath.Join(reports, "report.json"), "nginx:1.25"}, &stdout, &stderr))
+	require.Equal(t, 0, runStub(dir, "copa", []string{"patch", "--image", "nginx:1.25", "--report", reports, "--output", vex}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "Patched image nginx:1.25")
+
+	data, err := os.ReadFile(vex)
+	require.NoError(t, err)
+	for _, v := range Vulnerabilities {
+		assert.Contains(t, string(data), v.ID)
+	}
+}
+
+func TestRunStub_TrivyScansPatchedImage(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "report.json")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, runStub(dir, "copa", []string{"patch", "--image", "nginx:1.25", "--tag", "1.25-patched"}, &stdout, &stderr), stderr.String())
+	require.Equal(t, 0, runStub(dir, "trivy", []string{"image", "-o", output, "nginx:1.25-patched"}, &stdout, &stderr), stderr.String())
+
+	findings, err := readReports(output)
+	require.NoError(t, err)
+	require.Len(t, findings, 1, "only the vulnerability without a fix remains")
+	assert.Equal(t, Unfixed.ID, findings[0].VulnerabilityID)
+}
+
+func TestRunStub_Fail(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(FailEnvPrefix+"DOCKER", "Cannot connect to the Docker daemon")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, runStub(dir, "docker", []string{"version"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Cannot connect")
+	assert.Empty(t, stdout.String())
+}
+
+func TestRunStub_FailOnce(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(FailOnceEnvPrefix+"DOCKER_PUSH", "504 Gateway Timeout")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runStub(dir, "docker", []string{"version"}, &stdout, &stderr), "other commands run")
+	assert.Equal(t, 1, runStub(dir, "docker", []string{"push", "localhost:5000/app:1.0"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "504 Gateway Timeout")
+	stderr.Reset()
+	assert.Equal(t, 0, runStub(dir, "docker", []string{"push", "localhost:5000/app:1.0"}, &stdout, &stderr), "only the first run fails")
+	assert.Empty(t, stderr.String())
+}
+
+func TestCalls(t *testing.T) {
+	dir := t.TempDir()
+
+	calls, err := Calls(dir, "docker")
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+
+	var stdout, stderr bytes.Buffer
+	runStub(dir, "docker", []string{"pull", "alpine:3.19"}, &stdout, &stderr)
+	runStub(dir, "copa", []string{"--version"}, &stdout, &stderr)
+	runStub(dir, "docker", []string{"version"}, &stdout, &stderr)
+
+	calls, err = Calls(dir, "docker")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"pull", "alpine:3.19"}, {"version"}}, calls)
+}
+
+func TestFlagValue(t *testing.T) {
+	args := []string{"image", "-f", "json", "--output=report.json", "alpine"}
+	assert.Equal(t, "json", flagValue(args, "-f", "--format"))
+	assert.Equal(t, "report.json", flagValue(args, "-o", "--output"))
+	assert.Empty(t, flagValue(args, "--missing"))
+}
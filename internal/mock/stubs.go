@@ -0,0 +1,417 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openvex/go-vex/pkg/vex"
+)
+
+// Canned values reported by the stubs
+const (
+	// CopaVersion is printed by 'copa --version'
+	CopaVersion = "copa version 0.11.1 (mock)"
+	// DockerVersion is printed by 'docker version'
+	DockerVersion = "27.0.0"
+	// ImageSize is the size 'docker image inspect' reports for every image
+	ImageSize = 52428800
+)
+
+// Vulnerability is a vulnerability in the canned scan reports
+type Vulnerability struct {
+	ID               string
+	Package          string
+	Severity         string
+	InstalledVersion string
+	FixedVersion     string
+}
+
+// Vulnerabilities are the fixable vulnerabilities trivy reports for every image, and copa fixes
+var Vulnerabilities = []Vulnerability{
+	{ID: "CVE-2024-0001", Package: "openssl", Severity: "CRITICAL", InstalledVersion: "3.1.4-r0", FixedVersion: "3.1.4-r5"},
+	{ID: "CVE-2024-0002", Package: "openssl", Severity: "HIGH", InstalledVersion: "3.1.4-r0", FixedVersion: "3.1.4-r5"},
+	{ID: "CVE-2024-0003", Package: "busybox", Severity: "MEDIUM", InstalledVersion: "1.36.1-r15", FixedVersion: "1.36.1-r19"},
+}
+
+// Unfixed is the vulnerability without a fix that trivy also reports when unfixed vulnerabilities are not ignored
+var Unfixed = Vulnerability{ID: "CVE-2024-0004", Package: "zlib", Severity: "LOW", InstalledVersion: "1.3-r2"}
+
+// runStub runs the stub name with args, as started by the server, and returns its exit status
+func runStub(dir, name string, args []string, stdout, stderr io.Writer) int {
+	if err := recordCall(dir, name, args); err != nil {
+		fmt.Fprintf(stderr, "%s stub: recording the call failed: %v\n", name, err)
+		return 1
+	}
+	if delay := os.Getenv(DelayEnvPrefix + strings.ToUpper(name)); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s stub: invalid %s%s: %v\n", name, DelayEnvPrefix, strings.ToUpper(name), err)
+			return 1
+		}
+		time.Sleep(d)
+	}
+	if msg := os.Getenv(FailEnvPrefix + strings.ToUpper(name)); msg != "" {
+		fmt.Fprintln(stderr, msg)
+		return 1
+	}
+	if len(args) > 0 {
+		if msg := os.Getenv(FailOnceEnvPrefix + strings.ToUpper(name+"_"+args[0])); msg != "" {
+			// Only the run creating the marker fails
+			marker, err := os.OpenFile(filepath.Join(dir, failedOncePrefix+name+"-"+args[0]), os.O_CREATE|os.O_EXCL, 0o600)
+			if err == nil {
+				marker.Close()
+				fmt.Fprintln(stderr, msg)
+				return 1
+			}
+		}
+	}
+
+	var err error
+	switch name {
+	case "copa":
+		err = copaStub(dir, args, stdout)
+	case "trivy":
+		err = trivyStub(dir, args, stdout)
+	case "docker":
+		err = dockerStub(args, stdout)
+	case "ctr":
+		err = ctrStub(args, stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s stub: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// recordCall appends an invocation to the calls file of dir, read by Calls
+func recordCall(dir, name string, args []string) error {
+	line, err := json.Marshal(call{Name: name, Args: args})
+	if err != nil {
+		return err
+	}
+	return appendLine(filepath.Join(dir, callsFile), string(line))
+}
+
+// appendLine appends line to the file at path, creating it when it does not exist
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+// readLines returns the lines of the file at path, or none when it does not exist
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// patchedRef returns the reference copa tags the patch of image with: its repository with tag, or
+// its tag suffixed with -patched
+func patchedRef(image, tag string) string {
+	repo, sourceTag := image, "latest"
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, sourceTag = repo[:i], repo[i+1:]
+	}
+	if tag == "" {
+		tag = sourceTag + "-patched"
+	}
+	return repo + ":" + tag
+}
+
+// copaStub patches nothing, but writes a VEX document marking the vulnerabilities of the report
+// fixed, and records the patched image so that trivy reports them fixed in it
+func copaStub(dir string, args []string, stdout io.Writer) error {
+	if slices.Contains(args, "--version") {
+		fmt.Fprintln(stdout, CopaVersion)
+		return nil
+	}
+	if len(args) == 0 || args[0] != "patch" {
+		return fmt.Errorf("unsupported command: %v", args)
+	}
+
+	image := flagValue(args, "--image")
+	if image == "" {
+		return fmt.Errorf("--image is required")
+	}
+	if output := flagValue(args, "--output"); output != "" {
+		vulns, err := readReports(flagValue(args, "--report"))
+		if err != nil {
+			return err
+		}
+		if err := writeVex(output, image, vulns); err != nil {
+			return err
+		}
+	}
+	if err := appendLine(filepath.Join(dir, patchedFile), patchedRef(image, flagValue(args, "--tag", "-t"))); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Patched image %s\n", image)
+	return nil
+}
+
+// trivyStub writes the fixture report, or a CycloneDX or SPDX SBOM, to --output or stdout. Images patched
+// by the copa stub only have the vulnerability without a fix.
+func trivyStub(dir string, args []string, stdout io.Writer) error {
+	if slices.Contains(args, "--version") || (len(args) > 0 && args[0] == "version") {
+		fmt.Fprintln(stdout, "Version: 0.60.0")
+		return nil
+	}
+	if len(args) == 0 || args[0] != "image" {
+		return fmt.Errorf("unsupported command: %v", args)
+	}
+
+	image := args[len(args)-1]
+	format := flagValue(args, "-f", "--format")
+	var doc any
+	switch format {
+	case "cyclonedx":
+		doc = sbom(image)
+	case "spdx-json":
+		doc = spdx(image)
+	default:
+		var vulns []Vulnerability
+		if patched, err := readLines(filepath.Join(dir, patchedFile)); err != nil {
+			return err
+		} else if !slices.Contains(patched, image) {
+			vulns = slices.Clone(Vulnerabilities)
+		}
+		if !slices.Contains(args, "--ignore-unfixed") {
+			vulns = append(vulns, Unfixed)
+		}
+		doc = report(image, vulns)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if output := flagValue(args, "-o", "--output"); output != "" {
+		return os.WriteFile(output, data, 0o600)
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+// dockerStub answers the docker commands the server runs as if every image were present locally
+func dockerStub(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command")
+	}
+	switch args[0] {
+	case "version":
+		fmt.Fprintln(stdout, DockerVersion)
+	case "images":
+		fmt.Fprintln(stdout, args[len(args)-1])
+	case "image":
+		if len(args) > 1 && args[1] == "inspect" {
+			fmt.Fprintln(stdout, ImageSize)
+		}
+	case "pull":
+		image := args[len(args)-1]
+		fmt.Fprintf(stdout, "Pulling from %s\nStatus: Downloaded newer image for %s\n", image, image)
+	case "save":
+		output := flagValue(args, "--output", "-o")
+		if output == "" {
+			return fmt.Errorf("--output is required")
+		}
+		return os.WriteFile(output, []byte("mock image archive\n"), 0o600)
+	case "load":
+		fmt.Fprintln(stdout, "Loaded image from mock image archive")
+	case "login":
+		fmt.Fprintln(stdout, "Login Succeeded")
+	case "tag":
+		if len(args) != 3 {
+			return fmt.Errorf("tag requires a source and a target image")
+		}
+	case "push":
+		image := args[len(args)-1]
+		if len(args) < 2 || strings.HasPrefix(image, "-") {
+			return fmt.Errorf("push requires an image")
+		}
+		fmt.Fprintf(stdout, "%s: digest: sha256:%064d size: 1234\n", image, 0)
+	}
+	return nil
+}
+
+// ctrStub answers the ctr image commands the server runs as if every image were present in every
+// namespace
+func ctrStub(args []string, stdout io.Writer) error {
+	if len(args) > 1 && (args[0] == "--namespace" || args[0] == "-n") {
+		args = args[2:]
+	}
+	if len(args) < 3 || args[0] != "images" {
+		return fmt.Errorf("unsupported command: %v", args)
+	}
+	switch args[1] {
+	case "export":
+		return os.WriteFile(args[2], []byte("mock image archive\n"), 0o600)
+	case "import":
+		fmt.Fprintf(stdout, "unpacking images from %s...done\n", args[2])
+	default:
+		return fmt.Errorf("unsupported command: %v", args)
+	}
+	return nil
+}
+
+// flagValue returns the value of the first of names found in args, or "" when none is
+func flagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		for _, name := range names {
+			if arg == name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if value, ok := strings.CutPrefix(arg, name+"="); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+type trivyReport struct {
+	SchemaVersion int           `json:"SchemaVersion"`
+	ArtifactName  string        `json:"ArtifactName"`
+	Results       []trivyResult `json:"Results"`
+}
+
+type trivyResult struct {
+	Target          string         `json:"Target"`
+	Class           string         `json:"Class"`
+	Type            string         `json:"Type"`
+	Vulnerabilities []trivyFinding `json:"Vulnerabilities"`
+}
+
+type trivyFinding struct {
+	VulnerabilityID  string `json:"VulnerabilityID"`
+	PkgName          string `json:"PkgName"`
+	InstalledVersion string `json:"InstalledVersion"`
+	FixedVersion     string `json:"FixedVersion,omitempty"`
+	Severity         string `json:"Severity"`
+}
+
+func report(image string, vulns []Vulnerability) trivyReport {
+	result := trivyResult{Target: image + " (alpine 3.19.0)", Class: "os-pkgs", Type: "alpine"}
+	for _, v := range vulns {
+		result.Vulnerabilities = append(result.Vulnerabilities, trivyFinding{
+			VulnerabilityID:  v.ID,
+			PkgName:          v.Package,
+			InstalledVersion: v.InstalledVersion,
+			FixedVersion:     v.FixedVersion,
+			Severity:         v.Severity,
+		})
+	}
+	return trivyReport{SchemaVersion: 2, ArtifactName: image, Results: []trivyResult{result}}
+}
+
+func sbom(image string) map[string]any {
+	var components []map[string]any
+	for _, v := range Vulnerabilities {
+		components = append(components, map[string]any{
+			"type":    "library",
+			"name":    v.Package,
+			"version": v.InstalledVersion,
+			"purl":    fmt.Sprintf("pkg:apk/alpine/%s@%s", v.Package, v.InstalledVersion),
+		})
+	}
+	return map[string]any{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.5",
+		"metadata":    map[string]any{"component": map[string]any{"type": "container", "name": image}},
+		"components":  components,
+	}
+}
+
+func spdx(image string) map[string]any {
+	var packages []map[string]any
+	for _, v := range Vulnerabilities {
+		packages = append(packages, map[string]any{
+			"SPDXID":      "SPDXRef-Package-" + v.Package,
+			"name":        v.Package,
+			"versionInfo": v.InstalledVersion,
+		})
+	}
+	return map[string]any{
+		"spdxVersion": "SPDX-2.3",
+		"SPDXID":      "SPDXRef-DOCUMENT",
+		"name":        image,
+		"packages":    packages,
+	}
+}
+
+// readReports reads the vulnerabilities of the report at path, a file or a directory of reports
+func readReports(path string) ([]trivyFinding, error) {
+	if path == "" {
+		return nil, fmt.Errorf("--report is required with --output")
+	}
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+
+	var findings []trivyFinding
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var r trivyReport
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		for _, result := range r.Results {
+			findings = append(findings, result.Vulnerabilities...)
+		}
+	}
+	return findings, nil
+}
+
+// writeVex writes an OpenVEX document marking findings fixed in image, as copa does
+func writeVex(path, image string, findings []trivyFinding) error {
+	doc := vex.New()
+	doc.ID = "https://openvex.dev/docs/public/vex-mock"
+	doc.Author = "Project Copacetic"
+	for _, f := range findings {
+		doc.Statements = append(doc.Statements, vex.Statement{
+			Vulnerability: vex.Vulnerability{Name: vex.VulnerabilityID(f.VulnerabilityID)},
+			Products: []vex.Product{{
+				Component:     vex.Component{ID: "pkg:oci/" + image},
+				Subcomponents: []vex.Subcomponent{{Component: vex.Component{ID: fmt.Sprintf("pkg:apk/alpine/%s@%s", f.PkgName, f.FixedVersion)}}},
+			}},
+			Status: vex.StatusFixed,
+		})
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := doc.ToJSON(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
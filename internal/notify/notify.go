@@ -0,0 +1,134 @@
+// Package notify posts the outcome of finished scans and patches to webhooks, so that
+// chat channels and automation can follow long-running operations without polling.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook payload formats
+const (
+	FormatGeneric = "generic" // the Event as JSON
+	FormatSlack   = "slack"   // a Slack incoming webhook message
+)
+
+// Event statuses
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// requestTimeout bounds each webhook request so that a slow endpoint cannot pile up requests
+const requestTimeout = 10 * time.Second
+
+// Webhook is an endpoint notified when an operation finishes
+type Webhook struct {
+	URL    string
+	Format string // FormatGeneric or FormatSlack
+}
+
+// Event describes a finished operation. It is the payload of generic webhooks.
+type Event struct {
+	Tool       string    `json:"tool"`
+	Status     string    `json:"status"`
+	Summary    string    `json:"summary"`
+	Result     any       `json:"result,omitempty"` // The structured result, or the structured error of a failure
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Notifier sends events to a set of webhooks
+type Notifier struct {
+	webhooks []Webhook
+	client   *http.Client
+}
+
+// New creates a Notifier for webhooks
+func New(webhooks []Webhook) *Notifier {
+	return &Notifier{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Webhooks builds the webhook list from generic and Slack webhook URLs
+func Webhooks(genericURLs, slackURLs []string) []Webhook {
+	var webhooks []Webhook
+	for _, url := range genericURLs {
+		webhooks = append(webhooks, Webhook{URL: url, Format: FormatGeneric})
+	}
+	for _, url := range slackURLs {
+		webhooks = append(webhooks, Webhook{URL: url, Format: FormatSlack})
+	}
+	return webhooks
+}
+
+// Enabled reports whether any webhooks are configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.webhooks) > 0
+}
+
+// Send posts event to every webhook, returning the failures joined together
+func (n *Notifier) Send(ctx context.Context, event Event) error {
+	if !n.Enabled() {
+		return nil
+	}
+
+	var errs []error
+	for _, webhook := range n.webhooks {
+		if err := n.post(ctx, webhook, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) post(ctx context.Context, webhook Webhook, event Event) error {
+	body, err := payload(webhook.Format, event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The URL may embed a token (e.g. Slack webhooks), so it is not repeated in the error
+		return fmt.Errorf("%s webhook request failed: %w", webhook.Format, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s webhook returned %s", webhook.Format, resp.Status)
+	}
+	return nil
+}
+
+// payload encodes event in the webhook format
+func payload(format string, event Event) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		icon := ":white_check_mark:"
+		if event.Status == StatusFailed {
+			icon = ":x:"
+		}
+		return json.Marshal(map[string]string{
+			"text": fmt.Sprintf("%s `%s` %s\n```%s```", icon, event.Tool, event.Status, event.Summary),
+		})
+	case FormatGeneric, "":
+		return json.Marshal(event)
+	default:
+		return nil, fmt.Errorf("unsupported webhook format: %s", format)
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	bodies := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	n := New(Webhooks([]string{server.URL + "/generic"}, []string{server.URL + "/slack"}))
+	event := Event{
+		Tool:       "patch-report-based",
+		Status:     StatusSucceeded,
+		Summary:    "successful patched: alpine:3.18",
+		Result:     map[string]any{"numFixedVulns": 3},
+		FinishedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, n.Send(context.Background(), event))
+
+	assert.JSONEq(t, `{"tool":"patch-report-based","status":"succeeded","summary":"successful patched: alpine:3.18",
+		"result":{"numFixedVulns":3},"finishedAt":"2024-01-02T03:04:05Z"}`, string(bodies["/generic"]))
+
+	var slack map[string]string
+	require.NoError(t, json.Unmarshal(bodies["/slack"], &slack))
+	assert.Equal(t, ":white_check_mark: `patch-report-based` succeeded\n```successful patched: alpine:3.18```", slack["text"])
+}
+
+func TestSend_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := New(Webhooks([]string{server.URL}, nil)).Send(context.Background(), Event{Tool: "scan-container", Status: StatusFailed})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestSend_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/services/secret-token"
+	server.Close()
+
+	err := New(Webhooks(nil, []string{url})).Send(context.Background(), Event{Tool: "scan-container"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestEnabled(t *testing.T) {
+	var nilNotifier *Notifier
+	assert.False(t, nilNotifier.Enabled())
+	assert.False(t, New(nil).Enabled())
+	assert.True(t, New(Webhooks([]string{"https://example.com"}, nil)).Enabled())
+}
+
+func TestPayload_UnsupportedFormat(t *testing.T) {
+	_, err := payload("teams", Event{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,341 @@
+// Package otlp exports the server's logs to an OpenTelemetry collector over OTLP/HTTP, so that
+// operations teams can aggregate the logs of many servers without scraping stderr. Records are
+// batched in memory and sent as OTLP JSON to the collector's /v1/logs endpoint.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogsPath is appended to the collector endpoint, as for OTEL_EXPORTER_OTLP_ENDPOINT
+	LogsPath = "/v1/logs"
+	// ServiceName is the service.name resource attribute of the exported records
+	ServiceName = "copa-mcp-server"
+
+	// flushInterval is the longest a record waits before it is sent
+	flushInterval = 5 * time.Second
+	// batchSize is the number of pending records that triggers a send before flushInterval
+	batchSize = 256
+	// maxPending bounds the records kept while the collector is unreachable; the oldest are dropped
+	maxPending = 4096
+	// requestTimeout bounds each export request
+	requestTimeout = 10 * time.Second
+)
+
+// Record is a log record
+type Record struct {
+	Time       time.Time
+	Level      string // MCP log level: debug, info, notice, warning, error, critical, alert or emergency
+	Body       string
+	Attributes map[string]string
+}
+
+// severities maps MCP log levels to OpenTelemetry severity numbers
+var severities = map[string]int{
+	"debug":     5,
+	"info":      9,
+	"notice":    10,
+	"warning":   13,
+	"error":     17,
+	"critical":  18,
+	"alert":     19,
+	"emergency": 21,
+}
+
+// Exporter sends records to a collector in batches
+type Exporter struct {
+	url      string
+	headers  map[string]string
+	resource []keyValue
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending []Record
+	dropped int
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ParseHeaders parses the headers sent with each export, as key=value pairs separated by commas
+// (e.g. "Authorization=Bearer abc,X-Scope-OrgID=team")
+func ParseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, value := range values {
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header %q: expected key=value", value)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// ValidateEndpoint checks that endpoint is the http or https URL of a collector
+func ValidateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", endpoint)
+	}
+	return nil
+}
+
+// New starts an exporter sending to the collector at endpoint (e.g. http://otel-collector:4318)
+// with headers. version is the service.version resource attribute.
+func New(endpoint string, headers map[string]string, version string) *Exporter {
+	host, _ := os.Hostname()
+	e := &Exporter{
+		url:     strings.TrimSuffix(endpoint, "/") + LogsPath,
+		headers: headers,
+		resource: []keyValue{
+			attribute("service.name", ServiceName),
+			attribute("service.version", version),
+			attribute("service.instance.id", host+"-"+strconv.Itoa(os.Getpid())),
+			attribute("host.name", host),
+		},
+		client: &http.Client{Timeout: requestTimeout},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Emit queues r for the next batch. It never blocks on the collector.
+func (e *Exporter) Emit(r Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	e.mu.Lock()
+	if len(e.pending) == maxPending {
+		e.pending = e.pending[1:]
+		e.dropped++
+	}
+	e.pending = append(e.pending, r)
+	full := len(e.pending) >= batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close sends the pending records and stops the exporter, waiting until ctx is done at most
+func (e *Exporter) Close(ctx context.Context) error {
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.flush(ctx)
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		if err := e.flush(ctx); err != nil {
+			slog.Warn(fmt.Sprintf("failed to export logs to %s: %v", e.url, err), "logger", "otlp")
+		}
+		cancel()
+	}
+}
+
+// flush sends the pending records. Records of a failed send are kept for the next one.
+func (e *Exporter) flush(ctx context.Context) error {
+	e.mu.Lock()
+	batch, dropped := e.pending, e.dropped
+	e.pending, e.dropped = nil, 0
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := e.send(ctx, batch, dropped); err != nil {
+		e.mu.Lock()
+		e.pending = append(batch, e.pending...)
+		if excess := len(e.pending) - maxPending; excess > 0 {
+			e.pending = e.pending[excess:]
+			dropped += excess
+		}
+		e.dropped += dropped
+		e.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (e *Exporter) send(ctx context.Context, batch []Record, dropped int) error {
+	records := make([]logRecord, 0, len(batch))
+	for _, r := range batch {
+		records = append(records, newLogRecord(r))
+	}
+	if dropped > 0 {
+		records = append(records, newLogRecord(Record{
+			Time:  time.Now(),
+			Level: "warning",
+			Body:  fmt.Sprintf("%d log records were dropped while the collector was unreachable", dropped),
+		}))
+	}
+
+	body, err := json.Marshal(exportRequest{ResourceLogs: []resourceLogs{{
+		Resource:  resource{Attributes: e.resource},
+		ScopeLogs: []scopeLogs{{Scope: scope{Name: ServiceName}, LogRecords: records}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+var (
+	mu sync.Mutex
+	// exporter is the process-wide exporter set by Configure, nil when export is disabled
+	exporter *Exporter
+)
+
+// Configure sets the process-wide exporter Emit sends to, replacing and closing any previous
+// one. An empty endpoint disables export.
+func Configure(endpoint string, headers map[string]string, version string) {
+	var e *Exporter
+	if endpoint != "" {
+		e = New(endpoint, headers, version)
+	}
+
+	mu.Lock()
+	previous := exporter
+	exporter = e
+	mu.Unlock()
+	if previous != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		previous.Close(ctx)
+	}
+}
+
+// Emit queues r on the process-wide exporter, when one is configured
+func Emit(r Record) {
+	mu.Lock()
+	e := exporter
+	mu.Unlock()
+	if e != nil {
+		e.Emit(r)
+	}
+}
+
+// Flush sends the records pending on the process-wide exporter, e.g. before the process exits
+func Flush(ctx context.Context) error {
+	mu.Lock()
+	e := exporter
+	mu.Unlock()
+	if e == nil {
+		return nil
+	}
+	return e.flush(ctx)
+}
+
+// OTLP JSON encoding of an ExportLogsServiceRequest
+type (
+	exportRequest struct {
+		ResourceLogs []resourceLogs `json:"resourceLogs"`
+	}
+	resourceLogs struct {
+		Resource  resource    `json:"resource"`
+		ScopeLogs []scopeLogs `json:"scopeLogs"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeLogs struct {
+		Scope      scope       `json:"scope"`
+		LogRecords []logRecord `json:"logRecords"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	logRecord struct {
+		TimeUnixNano         string     `json:"timeUnixNano"`
+		ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+		SeverityNumber       int        `json:"severityNumber,omitempty"`
+		SeverityText         string     `json:"severityText,omitempty"`
+		Body                 anyValue   `json:"body"`
+		Attributes           []keyValue `json:"attributes,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+func attribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
+
+func newLogRecord(r Record) logRecord {
+	ts := strconv.FormatInt(r.Time.UnixNano(), 10)
+	rec := logRecord{
+		TimeUnixNano:         ts,
+		ObservedTimeUnixNano: ts,
+		SeverityNumber:       severities[strings.ToLower(r.Level)],
+		SeverityText:         strings.ToUpper(r.Level),
+		Body:                 anyValue{StringValue: r.Body},
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Attributes)) {
+		rec.Attributes = append(rec.Attributes, attribute(key, r.Attributes[key]))
+	}
+	return rec
+}
@@ -0,0 +1,116 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector records the export requests it receives, failing them while down is set
+type collector struct {
+	mu       sync.Mutex
+	down     bool
+	requests []exportRequest
+	headers  []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.URL.Path != LogsPath || c.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header.Clone())
+}
+
+func (c *collector) records() []logRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []logRecord
+	for _, req := range c.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func TestExporter(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	e := New(srv.URL+"/", map[string]string{"Authorization": "Bearer abc"}, "1.2.3")
+	e.Emit(Record{
+		Time:       time.Unix(0, 42),
+		Level:      "warning",
+		Body:       "retrying the scan of alpine:3.19",
+		Attributes: map[string]string{"tool": "scan-container", "job.id": "scan-container-1"},
+	})
+	require.NoError(t, e.Close(context.Background()))
+
+	records := c.records()
+	require.Len(t, records, 1)
+	assert.Equal(t, "42", records[0].TimeUnixNano)
+	assert.Equal(t, 13, records[0].SeverityNumber)
+	assert.Equal(t, "WARNING", records[0].SeverityText)
+	assert.Equal(t, "retrying the scan of alpine:3.19", records[0].Body.StringValue)
+	assert.Equal(t, []keyValue{attribute("job.id", "scan-container-1"), attribute("tool", "scan-container")}, records[0].Attributes)
+
+	assert.Equal(t, "Bearer abc", c.headers[0].Get("Authorization"))
+	resource := c.requests[0].ResourceLogs[0].Resource.Attributes
+	assert.Contains(t, resource, attribute("service.name", ServiceName))
+	assert.Contains(t, resource, attribute("service.version", "1.2.3"))
+}
+
+func TestExporter_KeepsRecordsWhileCollectorIsDown(t *testing.T) {
+	c := &collector{down: true}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	e := New(srv.URL, nil, "dev")
+	e.Emit(Record{Level: "info", Body: "first"})
+	assert.Error(t, e.flush(context.Background()))
+
+	c.mu.Lock()
+	c.down = false
+	c.mu.Unlock()
+	e.Emit(Record{Level: "info", Body: "second"})
+	require.NoError(t, e.Close(context.Background()))
+
+	records := c.records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "first", records[0].Body.StringValue)
+	assert.Equal(t, "second", records[1].Body.StringValue)
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders([]string{"Authorization=Bearer a=b", " X-Scope-OrgID = team "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer a=b", "X-Scope-OrgID": "team"}, headers)
+
+	_, err = ParseHeaders([]string{"Authorization"})
+	assert.Error(t, err)
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	assert.NoError(t, ValidateEndpoint("https://otel.example.com:4318"))
+	assert.Error(t, ValidateEndpoint("otel.example.com:4318"))
+	assert.Error(t, ValidateEndpoint("grpc://otel.example.com:4317"))
+}
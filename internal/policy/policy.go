@@ -0,0 +1,141 @@
+// Package policy evaluates Rego policies over the context of a patch, so that operators can deny
+// pushes, force dry runs or require confirmation of agent-driven patching, and over the context of
+// an image for admission-style checks. It drives the opa CLI,
+// which compiles and evaluates the policies exactly as 'opa eval' does for policy authors.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Rego documents holding the decisions, e.g. 'package copa.patch' with deny, dry_run and confirm rules
+const (
+	PatchQuery = "data.copa.patch" // Decision of a patch, with Input
+	ImageQuery = "data.copa.image" // Verdict of 'evaluate-image', with ImageInput; only deny applies
+)
+
+// Input is the context of a patch, available to policies as input
+type Input struct {
+	Tool         string                `json:"tool"`
+	Image        string                `json:"image"`
+	Registry     string                `json:"registry"`
+	PatchedImage string                `json:"patchedImage"`
+	Push         bool                  `json:"push"`
+	PushTarget   string                `json:"pushTarget,omitempty"`
+	ExportPath   string                `json:"exportPath,omitempty"`
+	Platforms    []string              `json:"platforms,omitempty"`
+	Severity     *types.SeverityCounts `json:"severity,omitempty"`
+}
+
+// ImageInput is the context of an image evaluation, available to policies as input
+type ImageInput struct {
+	Image     string                `json:"image"`
+	Registry  string                `json:"registry"`
+	Signed    bool                  `json:"signed"`
+	Signature string                `json:"signature,omitempty"`
+	Severity  *types.SeverityCounts `json:"severity,omitempty"`
+}
+
+// Decision is the outcome of the policies for a patch or an image
+type Decision struct {
+	Deny    []string `json:"deny"`    // Reasons the patch is denied
+	DryRun  bool     `json:"dry_run"` // The patch must only be simulated
+	Confirm []string `json:"confirm"` // Reasons the patch needs the user's confirmation
+}
+
+// Engine evaluates the policies loaded from a set of Rego files or directories
+type Engine struct {
+	opaPath string
+	paths   []string
+}
+
+// New creates an engine for the policies in paths, files or directories of .rego files
+func New(paths []string) *Engine {
+	return &Engine{opaPath: "opa", paths: paths}
+}
+
+// Enabled reports whether any policy is configured
+func (e *Engine) Enabled() bool {
+	return e != nil && len(e.paths) > 0
+}
+
+// Evaluate returns the decision of the policies for input at query, PatchQuery or ImageQuery.
+// When the policies do not define the queried package, the decision is empty and allows everything.
+func (e *Engine) Evaluate(ctx context.Context, query string, input any) (*Decision, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, copaerrors.NewSystemError("failed to encode the policy input", err)
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, path := range e.paths {
+		args = append(args, "--data", path)
+	}
+	args = append(args, query)
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, e.opaPath, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, copaerrors.NewSystemError("opa not found", err, "install the OPA CLI and make sure it is on the server's PATH")
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		// opa reports compile and evaluation errors on stdout in JSON format
+		message := strings.TrimSpace(stderr.String() + "\n" + string(output))
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("evaluating the policies at %s failed", query), fmt.Errorf("%w\n%s", err, message),
+			"check the policies with 'opa check' and 'opa eval'").
+			WithCommand(cmd.Args, exitCode, message, time.Since(start))
+	}
+	return parseDecision(query, output)
+}
+
+// parseDecision reads the decision from the JSON output of 'opa eval'
+func parseDecision(query string, output []byte) (*Decision, error) {
+	var eval struct {
+		Result []struct {
+			Expressions []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(output, &eval); err != nil {
+		return nil, copaerrors.NewExecutionError("failed to parse the output of opa eval", err)
+	}
+
+	decision := &Decision{}
+	if len(eval.Result) == 0 || len(eval.Result[0].Expressions) == 0 {
+		return decision, nil
+	}
+	if err := json.Unmarshal(eval.Result[0].Expressions[0].Value, decision); err != nil {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("%s is not a valid decision", query), err,
+			"deny and confirm must be sets of messages and dry_run a boolean")
+	}
+	return decision, nil
+}
+
+// Registry returns the registry host of an image reference, docker.io for Docker Hub images
+func Registry(image string) string {
+	host, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+	return host
+}
@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecision(t *testing.T) {
+	decision, err := parseDecision(PatchQuery, []byte(`{"result":[{"expressions":[{"value":{"deny":["pushes to docker.io are not allowed"],"dry_run":true,"confirm":[]},"text":"data.copa.patch"}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Decision{Deny: []string{"pushes to docker.io are not allowed"}, DryRun: true, Confirm: []string{}}, decision)
+
+	decision, err = parseDecision(PatchQuery, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Decision{}, decision)
+
+	_, err = parseDecision(PatchQuery, []byte(`{"result":[{"expressions":[{"value":{"deny":"no"}}]}]}`))
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestRegistry(t *testing.T) {
+	assert.Equal(t, "docker.io", Registry("nginx:1.25"))
+	assert.Equal(t, "docker.io", Registry("bitnami/redis:7"))
+	assert.Equal(t, "ghcr.io", Registry("ghcr.io/org/app:1.0"))
+	assert.Equal(t, "localhost:5000", Registry("localhost:5000/app"))
+	assert.Equal(t, "localhost", Registry("localhost/app"))
+}
+
+func TestEnabled(t *testing.T) {
+	var e *Engine
+	assert.False(t, e.Enabled())
+	assert.False(t, New(nil).Enabled())
+	assert.True(t, New([]string{"policies"}).Enabled())
+}
+
+func TestEvaluate_OPANotFound(t *testing.T) {
+	e := New([]string{"policies"})
+	e.opaPath = "copa-mcp-missing-opa"
+
+	_, err := e.Evaluate(context.Background(), PatchQuery, Input{Image: "nginx:1.25"})
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+}
+
+func TestEvaluate(t *testing.T) {
+	if _, err := exec.LookPath("opa"); err != nil {
+		t.Skip("opa not available, skipping integration test")
+	}
+
+	path := filepath.Join(t.TempDir(), "patch.rego")
+	require.NoError(t, os.WriteFile(path, []byte(`package copa.patch
+
+deny contains msg if {
+	input.push
+	input.registry == "docker.io"
+	msg := "pushes to Docker Hub are not allowed"
+}
+
+dry_run if input.severity.critical == 0
+
+confirm contains "patching production images" if startswith(input.image, "registry.example.com/prod/")
+`), 0o600))
+
+	decision, err := New([]string{path}).Evaluate(context.Background(), PatchQuery, Input{Image: "nginx:1.25", Registry: "docker.io", Push: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pushes to Docker Hub are not allowed"}, decision.Deny)
+	assert.False(t, decision.DryRun)
+	assert.Empty(t, decision.Confirm)
+}
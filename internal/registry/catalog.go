@@ -0,0 +1,280 @@
+// Package registry lists the repositories of a container registry with the distribution catalog API
+// and the tags of a repository, and finds the scan reports attached to images with the OCI referrers API.
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// catalogAuthHints are the remediation hints of a catalog request that was denied
+var catalogAuthHints = []string{
+	"set REGISTRY_TOKEN and REGISTRY_HOST to a token allowed to list the catalog",
+	"or pass the repositories to scan explicitly",
+}
+
+// catalogPageSize is the number of repositories requested per catalog page
+const catalogPageSize = 100
+
+// httpTimeout bounds each request to the registry and its token service
+const httpTimeout = 30 * time.Second
+
+// client calls a registry's API, authenticating when the registry answers with a challenge
+type client struct {
+	http     *http.Client
+	base     *url.URL
+	username string
+	password string
+
+	// authorization is the Authorization header obtained from the last challenge
+	authorization string
+	// authHints are the remediation hints of an authentication error
+	authHints []string
+}
+
+// newClient creates a client for registry, a host (e.g. registry.example.com or localhost:5000)
+// reached over HTTPS, or a URL with an explicit http:// or https:// scheme.
+// Credentials are read from REGISTRY_TOKEN when REGISTRY_HOST names the registry, as for docker login.
+// authHints are returned with authentication errors.
+func newClient(registry string, authHints ...string) (*client, error) {
+	raw := registry
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	base, err := url.Parse(raw)
+	if err != nil || base.Host == "" {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("invalid registry: %s", registry), err,
+			"use a registry host such as registry.example.com or localhost:5000")
+	}
+
+	c := &client{http: &http.Client{Timeout: httpTimeout}, base: base, authHints: authHints}
+	if token := os.Getenv("REGISTRY_TOKEN"); token != "" && os.Getenv("REGISTRY_HOST") == base.Host {
+		c.username, c.password = "_token", token
+	}
+	return c, nil
+}
+
+// Host returns the registry host as used in image references
+func Host(registry string) string {
+	if _, host, found := strings.Cut(registry, "://"); found {
+		return strings.TrimSuffix(host, "/")
+	}
+	return strings.TrimSuffix(registry, "/")
+}
+
+// Catalog lists up to limit repositories of registry, following the catalog's pagination.
+// truncated reports whether the registry has more repositories than were returned.
+func Catalog(ctx context.Context, registry string, limit int) (repositories []string, truncated bool, err error) {
+	c, err := newClient(registry, catalogAuthHints...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	next := fmt.Sprintf("/v2/_catalog?n=%d", catalogPageSize)
+	for next != "" {
+		if len(repositories) >= limit {
+			return repositories[:limit], true, nil
+		}
+
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		link, err := c.getJSON(ctx, next, &page)
+		if err != nil {
+			return nil, false, err
+		}
+		repositories = append(repositories, page.Repositories...)
+		next = nextLink(link)
+	}
+
+	if len(repositories) > limit {
+		return repositories[:limit], true, nil
+	}
+	return repositories, false, nil
+}
+
+// getJSON decodes the JSON response to a GET of path into v and returns the response's Link header
+func (c *client) getJSON(ctx context.Context, path string, v any) (string, error) {
+	resp, err := c.get(ctx, http.MethodGet, path, "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp, "registry catalog request failed")
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", copaerrors.NewExecutionError("failed to parse the registry catalog", err)
+	}
+	return resp.Header.Get("Link"), nil
+}
+
+// get sends a request for path, answering an authentication challenge once.
+// accept, when set, is the Accept header of the request.
+func (c *client) get(ctx context.Context, method, path, accept string) (*http.Response, error) {
+	resp, err := c.do(ctx, method, path, accept)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := c.authorize(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return c.do(ctx, method, path, accept)
+}
+
+func (c *client) do(ctx context.Context, method, path, accept string) (*http.Response, error) {
+	target, err := c.base.Parse(path)
+	if err != nil {
+		return nil, copaerrors.NewExecutionError(fmt.Sprintf("invalid registry URL %s", path), err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, copaerrors.NewExecutionError("failed to create registry request", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, copaerrors.NewNetworkError(fmt.Sprintf("failed to reach registry %s", c.base.Host), err,
+			"check the registry address and network connectivity")
+	}
+	return resp, nil
+}
+
+// authorize answers a Basic or Bearer authentication challenge
+func (c *client) authorize(ctx context.Context, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if c.username == "" {
+			return c.authError(nil)
+		}
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+		return nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, params)
+		if err != nil {
+			return err
+		}
+		c.authorization = "Bearer " + token
+		return nil
+	default:
+		return c.authError(fmt.Errorf("unsupported authentication challenge %q", challenge))
+	}
+}
+
+// fetchToken obtains a bearer token from the token service named in a challenge
+func (c *client) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", c.authError(fmt.Errorf("invalid token realm %q", params["realm"]))
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", copaerrors.NewExecutionError("failed to create token request", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", copaerrors.NewNetworkError(fmt.Sprintf("failed to reach the token service of %s", c.base.Host), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", c.authError(fmt.Errorf("token service returned %s", resp.Status))
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", c.authError(fmt.Errorf("failed to parse token response: %w", err))
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", c.authError(fmt.Errorf("token service returned no token"))
+	}
+	return body.Token, nil
+}
+
+func (c *client) authError(err error) error {
+	return copaerrors.NewAuthError(fmt.Sprintf("registry %s requires authentication", c.base.Host), err, c.authHints...)
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="registry:catalog:*"
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params = map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			params[key] = value
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// nextLink returns the target of a Link header with rel="next", e.g. </v2/_catalog?last=b&n=100>; rel="next"
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, _ := strings.Cut(link, ";")
+		if strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
+
+// statusError converts an unexpected registry response into a categorized error
+func statusError(resp *http.Response, message string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return copaerrors.NewAuthError(message, err, catalogAuthHints...)
+	case http.StatusNotFound:
+		return copaerrors.NewValidationError(message, err, "the registry does not support the catalog API; pass the repositories to scan explicitly")
+	}
+	if resp.StatusCode >= 500 {
+		return copaerrors.NewNetworkError(message, err)
+	}
+	return copaerrors.NewExecutionError(message, err)
+}
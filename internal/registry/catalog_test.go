@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// catalogHandler serves repos in pages of size, like the distribution catalog API
+func catalogHandler(t *testing.T, repos []string, size int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/_catalog", r.URL.Path)
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, repo := range repos {
+				if repo == last {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+size, len(repos))
+		if end < len(repos) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/_catalog?last=%s&n=%d>; rel="next"`, repos[end-1], size))
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": repos[start:end]})
+	}
+}
+
+func TestCatalog_Pagination(t *testing.T) {
+	repos := []string{"a", "b", "c", "d", "e"}
+	srv := httptest.NewServer(catalogHandler(t, repos, 2))
+	defer srv.Close()
+
+	got, truncated, err := Catalog(context.Background(), srv.URL, 10)
+	require.NoError(t, err)
+	assert.Equal(t, repos, got)
+	assert.False(t, truncated)
+
+	got, truncated, err = Catalog(context.Background(), srv.URL, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+	assert.True(t, truncated)
+
+	got, truncated, err = Catalog(context.Background(), srv.URL, 5)
+	require.NoError(t, err)
+	assert.Equal(t, repos, got)
+	assert.False(t, truncated)
+}
+
+func TestCatalog_BearerChallenge(t *testing.T) {
+	var tokenQuery string
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenQuery = r.URL.RawQuery
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+	})
+	mux.HandleFunc("/v2/_catalog", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="registry:catalog:*"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": {"team/app"}})
+	})
+
+	got, _, err := Catalog(context.Background(), srv.URL, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team/app"}, got)
+	assert.Contains(t, tokenQuery, "service=registry.test")
+	assert.Contains(t, tokenQuery, "scope=registry%3Acatalog%3A%2A")
+}
+
+func TestCatalog_BasicChallengeWithoutCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, _, err := Catalog(context.Background(), srv.URL, 10)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryAuth, copaerrors.CategoryOf(err))
+}
+
+func TestCatalog_BasicChallengeWithToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "_token" || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": {"app"}})
+	}))
+	defer srv.Close()
+
+	t.Setenv("REGISTRY_TOKEN", "secret")
+	t.Setenv("REGISTRY_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	got, _, err := Catalog(context.Background(), srv.URL, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app"}, got)
+}
+
+func TestCatalog_Unsupported(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, _, err := Catalog(context.Background(), srv.URL, 10)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestCatalog_InvalidRegistry(t *testing.T) {
+	_, _, err := Catalog(context.Background(), "https://", 10)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="registry:catalog:*"`)
+	assert.Equal(t, "bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "registry:catalog:*",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	assert.Equal(t, "basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "registry"}, params)
+}
+
+func TestNextLink(t *testing.T) {
+	assert.Equal(t, "/v2/_catalog?last=b&n=100", nextLink(`</v2/_catalog?last=b&n=100>; rel="next"`))
+	assert.Empty(t, nextLink(""))
+	assert.Empty(t, nextLink(`</v2/_catalog?n=100>; rel="prev"`))
+}
+
+func TestHost(t *testing.T) {
+	assert.Equal(t, "registry.example.com", Host("registry.example.com"))
+	assert.Equal(t, "localhost:5000", Host("http://localhost:5000/"))
+}
@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Ping checks that registry, a host as used in image references (e.g. docker.io or
+// localhost:5000) or a URL, answers the distribution API. A registry asking for credentials is
+// reachable: pulls and pushes authenticate with the Docker login.
+func Ping(ctx context.Context, registry string) error {
+	if !strings.Contains(registry, "://") {
+		registry = apiRegistry(registry)
+	}
+	c, err := newClient(registry)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/v2/", "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return statusError(resp, fmt.Sprintf("registry %s did not answer the distribution API", c.base.Host))
+	}
+	return nil
+}
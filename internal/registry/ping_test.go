@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	status := http.StatusUnauthorized
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	assert.NoError(t, Ping(context.Background(), strings.TrimPrefix(srv.URL, "http://")), "a registry asking for credentials is reachable")
+	assert.NoError(t, Ping(context.Background(), srv.URL))
+
+	status = http.StatusBadGateway
+	err := Ping(context.Background(), srv.URL)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryNetwork, copaerrors.CategoryOf(err))
+}
+
+func TestPing_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	err := Ping(context.Background(), srv.URL)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryNetwork, copaerrors.CategoryOf(err))
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Platforms returns the platforms of a multi-platform image, as os/arch[/variant], in the order of
+// its index. Attestation manifests, whose platform is unknown/unknown, are left out. It returns nil
+// for a single-platform image.
+func Platforms(ctx context.Context, image string) ([]string, error) {
+	registry, repository, reference, err := splitImage(image)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(registry, referrerAuthHints...)
+	if err != nil {
+		return nil, err
+	}
+
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerV2}, ", ")
+	resp, err := c.get(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.referrerError(resp, fmt.Sprintf("failed to fetch the manifest of %s", image))
+	}
+
+	var index struct {
+		MediaType string `json:"mediaType"`
+		Manifests []struct {
+			Platform *struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+				Variant      string `json:"variant,omitempty"`
+			} `json:"platform,omitempty"`
+		} `json:"manifests"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, copaerrors.NewExecutionError(fmt.Sprintf("failed to parse the manifest of %s", image), err)
+	}
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if mediaType != mediaTypeOCIIndex && mediaType != mediaTypeDockerList && index.MediaType != mediaTypeOCIIndex && index.MediaType != mediaTypeDockerList {
+		return nil, nil
+	}
+
+	var platforms []string
+	for _, m := range index.Manifests {
+		if m.Platform == nil || m.Platform.OS == "unknown" || m.Platform.Architecture == "unknown" {
+			continue
+		}
+		platform := m.Platform.OS + "/" + m.Platform.Architecture
+		if m.Platform.Variant != "" {
+			platform += "/" + m.Platform.Variant
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
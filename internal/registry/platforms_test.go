@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func manifestRegistry(t *testing.T, mediaType string, manifest map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/team/app/manifests/1.0", r.URL.Path)
+		assert.Contains(t, r.Header.Get("Accept"), mediaTypeOCIIndex)
+		w.Header().Set("Content-Type", mediaType)
+		json.NewEncoder(w).Encode(manifest)
+	}))
+}
+
+func TestPlatforms_Index(t *testing.T) {
+	srv := manifestRegistry(t, mediaTypeOCIIndex, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIIndex,
+		"manifests": []map[string]any{
+			{"digest": "sha256:1", "platform": map[string]string{"os": "linux", "architecture": "amd64"}},
+			{"digest": "sha256:2", "platform": map[string]string{"os": "linux", "architecture": "arm", "variant": "v7"}},
+			{"digest": "sha256:3", "platform": map[string]string{"os": "unknown", "architecture": "unknown"}},
+		},
+	})
+	defer srv.Close()
+
+	platforms, err := Platforms(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm/v7"}, platforms)
+}
+
+func TestPlatforms_SingleManifest(t *testing.T) {
+	srv := manifestRegistry(t, mediaTypeDockerV2, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeDockerV2,
+		"layers":        []map[string]any{{"digest": "sha256:1"}},
+	})
+	defer srv.Close()
+
+	platforms, err := Platforms(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Nil(t, platforms)
+}
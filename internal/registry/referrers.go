@@ -0,0 +1,266 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Formats of the scan reports found by FindAttachedReport
+const (
+	FormatTrivy = "trivy" // Trivy JSON report
+	FormatSARIF = "sarif" // SARIF log
+)
+
+const (
+	mediaTypeOCIIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest   = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIArtifact   = "application/vnd.oci.artifact.manifest.v1+json"
+	mediaTypeDockerList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerV2      = "application/vnd.docker.distribution.manifest.v2+json"
+	annotationCreated      = "org.opencontainers.image.created"
+	dockerHubRegistry      = "registry-1.docker.io"
+	maxAttachedReportBytes = 64 << 20
+)
+
+// referrerAuthHints are the remediation hints of a referrer lookup that was denied
+var referrerAuthHints = []string{
+	"set REGISTRY_TOKEN and REGISTRY_HOST to a token allowed to pull the image",
+}
+
+// AttachedReport is a scan report attached to an image as an OCI referrer artifact
+type AttachedReport struct {
+	Digest       string // Digest of the referrer manifest
+	ArtifactType string
+	Format       string // FormatTrivy or FormatSARIF
+	Created      string // Creation time annotation of the referrer, if any
+	Data         []byte // The report
+}
+
+// descriptor is an OCI content descriptor
+type descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// ReportFormat returns the report format of a referrer's artifact type, or "" if it is not a scan report.
+// SARIF logs use their registered media type; Trivy JSON reports have no registered type, so any
+// artifact type naming trivy (e.g. application/vnd.aquasec.trivy.report.v1+json) is accepted.
+func ReportFormat(artifactType string) string {
+	artifactType = strings.ToLower(artifactType)
+	switch {
+	case artifactType == "application/sarif+json":
+		return FormatSARIF
+	case strings.Contains(artifactType, "trivy") && strings.HasSuffix(artifactType, "json"):
+		return FormatTrivy
+	}
+	return ""
+}
+
+// FindAttachedReport returns the most recent scan report attached to image with the OCI referrers
+// API, falling back to the referrers tag schema for registries without it. It returns nil when no
+// report is attached.
+func FindAttachedReport(ctx context.Context, image string) (*AttachedReport, error) {
+	registry, repository, reference, err := splitImage(image)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(registry, referrerAuthHints...)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := reference
+	if !strings.HasPrefix(reference, "sha256:") {
+		if digest, err = c.resolve(ctx, repository, reference); err != nil {
+			return nil, err
+		}
+	}
+
+	referrers, err := c.referrers(ctx, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *descriptor
+	var latestCreated time.Time
+	for i, referrer := range referrers {
+		if ReportFormat(referrer.ArtifactType) == "" {
+			continue
+		}
+		// Without a creation time, later entries are taken to be more recent
+		created, _ := time.Parse(time.RFC3339, referrer.Annotations[annotationCreated])
+		if latest == nil || !created.Before(latestCreated) {
+			latest, latestCreated = &referrers[i], created
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	data, err := c.artifactContent(ctx, repository, latest.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachedReport{
+		Digest:       latest.Digest,
+		ArtifactType: latest.ArtifactType,
+		Format:       ReportFormat(latest.ArtifactType),
+		Created:      latest.Annotations[annotationCreated],
+		Data:         data,
+	}, nil
+}
+
+// splitImage splits an image reference into the registry to contact, the repository and the tag or
+// digest. Docker Hub references are normalized, and local registries are reached over plain HTTP.
+func splitImage(image string) (registry, repository, reference string, err error) {
+	name, reference := image, "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+	if name == "" || reference == "" {
+		return "", "", "", copaerrors.NewValidationError(fmt.Sprintf("invalid image reference: %s", image), nil)
+	}
+
+	host, repository, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		host, repository = "docker.io", name
+	}
+	if (host == "docker.io" || host == "index.docker.io") && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return apiRegistry(host), repository, reference, nil
+}
+
+// apiRegistry returns the registry to contact for the registry host of image references: Docker
+// Hub is normalized, and local registries are reached over plain HTTP
+func apiRegistry(host string) string {
+	if host == "docker.io" || host == "index.docker.io" {
+		return dockerHubRegistry
+	}
+	if hostname, _, _ := strings.Cut(host, ":"); hostname == "localhost" || hostname == "127.0.0.1" {
+		return "http://" + host
+	}
+	return host
+}
+
+// resolve returns the digest of the manifest or index a tag points to
+func (c *client) resolve(ctx context.Context, repository, tag string) (string, error) {
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeOCIManifest, mediaTypeDockerList, mediaTypeDockerV2}, ", ")
+	resp, err := c.get(ctx, http.MethodHead, fmt.Sprintf("/v2/%s/manifests/%s", repository, tag), accept)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.referrerError(resp, fmt.Sprintf("failed to resolve %s:%s", repository, tag))
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", copaerrors.NewExecutionError(fmt.Sprintf("registry %s did not return the digest of %s:%s", c.base.Host, repository, tag), nil)
+	}
+	return digest, nil
+}
+
+// referrers lists the referrers of a manifest. Registries without the referrers API are asked
+// for the index tagged with the referrers tag schema (sha256-<hex>) instead.
+func (c *client) referrers(ctx context.Context, repository, digest string) ([]descriptor, error) {
+	paths := []string{
+		fmt.Sprintf("/v2/%s/referrers/%s", repository, digest),
+		fmt.Sprintf("/v2/%s/manifests/%s", repository, strings.Replace(digest, ":", "-", 1)),
+	}
+	for _, path := range paths {
+		resp, err := c.get(ctx, http.MethodGet, path, mediaTypeOCIIndex)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, c.referrerError(resp, fmt.Sprintf("failed to list the referrers of %s@%s", repository, digest))
+		}
+
+		var index struct {
+			Manifests []descriptor `json:"manifests"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&index)
+		resp.Body.Close()
+		if err != nil {
+			return nil, copaerrors.NewExecutionError("failed to parse the referrers index", err)
+		}
+		return index.Manifests, nil
+	}
+	return nil, nil
+}
+
+// artifactContent returns the first layer of an artifact manifest, which holds an attached report.
+// Artifact manifests of the OCI 1.1 release candidates, which list blobs instead of layers, are also read.
+func (c *client) artifactContent(ctx context.Context, repository, digest string) ([]byte, error) {
+	resp, err := c.get(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/manifests/%s", repository, digest), mediaTypeOCIManifest+", "+mediaTypeOCIArtifact)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Layers []descriptor `json:"layers"`
+		Blobs  []descriptor `json:"blobs"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.referrerError(resp, fmt.Sprintf("failed to fetch the report artifact %s", digest))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&manifest)
+	resp.Body.Close()
+	if err != nil {
+		return nil, copaerrors.NewExecutionError("failed to parse the report artifact manifest", err)
+	}
+	manifest.Layers = append(manifest.Layers, manifest.Blobs...)
+	if len(manifest.Layers) == 0 {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("report artifact %s has no content", digest), nil)
+	}
+
+	resp, err = c.get(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", repository, manifest.Layers[0].Digest), "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.referrerError(resp, fmt.Sprintf("failed to download the report in artifact %s", digest))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachedReportBytes+1))
+	if err != nil {
+		return nil, copaerrors.NewNetworkError("failed to download the attached report", err)
+	}
+	if len(data) > maxAttachedReportBytes {
+		return nil, copaerrors.NewValidationError(fmt.Sprintf("attached report in artifact %s is larger than %d MiB", digest, maxAttachedReportBytes>>20), nil)
+	}
+	return data, nil
+}
+
+// referrerError converts an unexpected registry response during a referrer lookup into a categorized error
+func (c *client) referrerError(resp *http.Response, message string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return copaerrors.NewAuthError(message, err, c.authHints...)
+	case resp.StatusCode == http.StatusNotFound:
+		return copaerrors.NewValidationError(message, err, "check that the image has been pushed to the registry")
+	case resp.StatusCode >= 500:
+		return copaerrors.NewNetworkError(message, err)
+	}
+	return copaerrors.NewExecutionError(message, err)
+}
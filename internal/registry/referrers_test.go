@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const imageDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+// referrersRegistry serves a tagged image with the given referrers. Without referrersAPI, the
+// referrers are only served under the referrers tag schema.
+func referrersRegistry(t *testing.T, referrers []descriptor, referrersAPI bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/team/app/manifests/1.0", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodHead, r.Method)
+		assert.Contains(t, r.Header.Get("Accept"), mediaTypeOCIIndex)
+		w.Header().Set("Docker-Content-Digest", imageDigest)
+	})
+	index := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", mediaTypeOCIIndex)
+		json.NewEncoder(w).Encode(map[string]any{"schemaVersion": 2, "manifests": referrers})
+	}
+	if referrersAPI {
+		mux.HandleFunc("/v2/team/app/referrers/"+imageDigest, index)
+	} else {
+		mux.HandleFunc("/v2/team/app/manifests/"+strings.Replace(imageDigest, ":", "-", 1), index)
+	}
+	for _, referrer := range referrers {
+		mux.HandleFunc("/v2/team/app/manifests/"+referrer.Digest, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{
+				"schemaVersion": 2,
+				"artifactType":  referrer.ArtifactType,
+				"layers":        []descriptor{{MediaType: "application/json", Digest: "sha256:blob-" + referrer.Digest}},
+			})
+		})
+		mux.HandleFunc("/v2/team/app/blobs/sha256:blob-"+referrer.Digest, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"report":"` + referrer.Digest + `"}`))
+		})
+	}
+	return httptest.NewServer(mux)
+}
+
+func TestFindAttachedReport(t *testing.T) {
+	referrers := []descriptor{
+		{Digest: "sha256:old", ArtifactType: "application/vnd.aquasec.trivy.report.v1+json", Annotations: map[string]string{annotationCreated: "2024-05-01T10:00:00Z"}},
+		{Digest: "sha256:new", ArtifactType: "application/sarif+json", Annotations: map[string]string{annotationCreated: "2024-06-01T10:00:00Z"}},
+		{Digest: "sha256:sbom", ArtifactType: "application/vnd.cyclonedx+json", Annotations: map[string]string{annotationCreated: "2024-07-01T10:00:00Z"}},
+	}
+	for _, referrersAPI := range []bool{true, false} {
+		srv := referrersRegistry(t, referrers, referrersAPI)
+		defer srv.Close()
+
+		report, err := FindAttachedReport(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		assert.Equal(t, "sha256:new", report.Digest)
+		assert.Equal(t, FormatSARIF, report.Format)
+		assert.Equal(t, "2024-06-01T10:00:00Z", report.Created)
+		assert.JSONEq(t, `{"report":"sha256:new"}`, string(report.Data))
+	}
+}
+
+func TestFindAttachedReport_None(t *testing.T) {
+	srv := referrersRegistry(t, []descriptor{{Digest: "sha256:sbom", ArtifactType: "application/spdx+json"}}, true)
+	defer srv.Close()
+
+	report, err := FindAttachedReport(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Nil(t, report)
+
+	// Without the referrers API or a referrers tag, nothing is attached
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Docker-Content-Digest", imageDigest)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	report, err = FindAttachedReport(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Nil(t, report)
+}
+
+func TestFindAttachedReport_Denied(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := FindAttachedReport(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryAuth, copaerrors.CategoryOf(err))
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image      string
+		registry   string
+		repository string
+		reference  string
+	}{
+		{"nginx", dockerHubRegistry, "library/nginx", "latest"},
+		{"nginx:1.25", dockerHubRegistry, "library/nginx", "1.25"},
+		{"docker.io/bitnami/redis:7", dockerHubRegistry, "bitnami/redis", "7"},
+		{"ghcr.io/org/team/app@" + imageDigest, "ghcr.io", "org/team/app", imageDigest},
+		{"localhost:5000/app:dev", "http://localhost:5000", "app", "dev"},
+		{"registry.example.com:8443/app", "registry.example.com:8443", "app", "latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			registry, repository, reference, err := splitImage(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.registry, registry)
+			assert.Equal(t, tt.repository, repository)
+			assert.Equal(t, tt.reference, reference)
+		})
+	}
+}
+
+func TestReportFormat(t *testing.T) {
+	assert.Equal(t, FormatSARIF, ReportFormat("application/sarif+json"))
+	assert.Equal(t, FormatTrivy, ReportFormat("application/vnd.aquasec.trivy.report.v1+json"))
+	assert.Equal(t, FormatTrivy, ReportFormat("application/vnd.trivy.report+json"))
+	assert.Empty(t, ReportFormat("application/vnd.cyclonedx+json"))
+	assert.Empty(t, ReportFormat("application/vnd.dev.cosign.artifact.sig.v1+json"))
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of the signatures found by FindSignature
+const (
+	SignatureCosign   = "cosign"
+	SignatureNotation = "notation"
+)
+
+const (
+	artifactTypeCosign   = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	artifactTypeNotation = "application/vnd.cncf.notary.signature"
+)
+
+// Signature is a signature attached to an image
+type Signature struct {
+	Kind   string // SignatureCosign or SignatureNotation
+	Digest string // Digest of the signature manifest, empty for a cosign signature tag
+	Ref    string // Tag of a cosign signature, empty for a referrer
+}
+
+// FindSignature returns a cosign or Notation signature attached to image, as an OCI referrer or as a
+// cosign signature tag (sha256-<hex>.sig). It returns nil when the image is not signed. The signature
+// is only found, not verified: use cosign or notation to verify it against a trusted key.
+func FindSignature(ctx context.Context, image string) (*Signature, error) {
+	registry, repository, reference, err := splitImage(image)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(registry, referrerAuthHints...)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := reference
+	if !strings.HasPrefix(reference, "sha256:") {
+		if digest, err = c.resolve(ctx, repository, reference); err != nil {
+			return nil, err
+		}
+	}
+
+	referrers, err := c.referrers(ctx, repository, digest)
+	if err != nil {
+		return nil, err
+	}
+	for _, referrer := range referrers {
+		switch referrer.ArtifactType {
+		case artifactTypeCosign:
+			return &Signature{Kind: SignatureCosign, Digest: referrer.Digest}, nil
+		case artifactTypeNotation:
+			return &Signature{Kind: SignatureNotation, Digest: referrer.Digest}, nil
+		}
+	}
+
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	resp, err := c.get(ctx, http.MethodHead, fmt.Sprintf("/v2/%s/manifests/%s", repository, tag),
+		strings.Join([]string{mediaTypeOCIManifest, mediaTypeDockerV2}, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &Signature{Kind: SignatureCosign, Ref: tag}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	}
+	return nil, c.referrerError(resp, fmt.Sprintf("failed to look up the cosign signature of %s@%s", repository, digest))
+}
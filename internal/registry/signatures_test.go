@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSignature_Referrer(t *testing.T) {
+	srv := referrersRegistry(t, []descriptor{
+		{Digest: "sha256:report", ArtifactType: "application/sarif+json"},
+		{Digest: "sha256:sig", ArtifactType: artifactTypeNotation},
+	}, true)
+	defer srv.Close()
+
+	signature, err := FindSignature(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, &Signature{Kind: SignatureNotation, Digest: "sha256:sig"}, signature)
+}
+
+func TestFindSignature_CosignTag(t *testing.T) {
+	srv := referrersRegistry(t, nil, true)
+	defer srv.Close()
+	mux := srv.Config.Handler.(*http.ServeMux)
+	tag := strings.Replace(imageDigest, ":", "-", 1) + ".sig"
+	mux.HandleFunc("/v2/team/app/manifests/"+tag, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodHead, r.Method)
+	})
+
+	signature, err := FindSignature(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, &Signature{Kind: SignatureCosign, Ref: tag}, signature)
+}
+
+func TestFindSignature_Unsigned(t *testing.T) {
+	srv := referrersRegistry(t, []descriptor{{Digest: "sha256:report", ArtifactType: "application/sarif+json"}}, false)
+	defer srv.Close()
+
+	signature, err := FindSignature(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Nil(t, signature)
+}
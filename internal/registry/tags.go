@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// maxTags caps the tags listed for a repository; official images have a few thousand
+const maxTags = 20000
+
+// Tags lists the tags of the repository of image, following the tag list's pagination
+func Tags(ctx context.Context, image string) ([]string, error) {
+	registry, repository, _, err := splitImage(image)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(registry, referrerAuthHints...)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	next := fmt.Sprintf("/v2/%s/tags/list?n=%d", repository, catalogPageSize)
+	for next != "" && len(tags) < maxTags {
+		resp, err := c.get(ctx, http.MethodGet, next, "application/json")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, c.referrerError(resp, fmt.Sprintf("failed to list the tags of %s", repository))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		link := resp.Header.Get("Link")
+		resp.Body.Close()
+		if err != nil {
+			return nil, copaerrors.NewExecutionError("failed to parse the tag list", err)
+		}
+		tags = append(tags, page.Tags...)
+		next = nextLink(link)
+	}
+	return tags, nil
+}
+
+// Digest returns the digest of the manifest or index image points to, e.g. to pin a reference to
+// the exact image that was pushed. A reference that already has a digest is returned as is.
+func Digest(ctx context.Context, image string) (string, error) {
+	registry, repository, reference, err := splitImage(image)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		return reference, nil
+	}
+	c, err := newClient(registry, referrerAuthHints...)
+	if err != nil {
+		return "", err
+	}
+	return c.resolve(ctx, repository, reference)
+}
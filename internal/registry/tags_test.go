@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/team/app/tags/list", r.URL.Path)
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/team/app/tags/list?last=1.1&n=100>; rel="next"`)
+			json.NewEncoder(w).Encode(map[string]any{"name": "team/app", "tags": []string{"1.0", "1.1"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"name": "team/app", "tags": []string{"1.2"}})
+	}))
+	defer srv.Close()
+
+	tags, err := Tags(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0", "1.1", "1.2"}, tags)
+}
+
+func TestTags_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := Tags(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
+
+func TestDigest(t *testing.T) {
+	srv := referrersRegistry(t, nil, true)
+	defer srv.Close()
+
+	digest, err := Digest(context.Background(), strings.TrimPrefix(srv.URL, "http://")+"/team/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, imageDigest, digest)
+
+	digest, err = Digest(context.Background(), "ghcr.io/team/app@"+imageDigest)
+	require.NoError(t, err)
+	assert.Equal(t, imageDigest, digest)
+}
@@ -0,0 +1,117 @@
+// Package registryacl enforces the registries an agent may pull images from and push patched
+// images to, so that push-capable credentials handed to the server cannot be used to publish to
+// arbitrary registries such as docker.io, nor to scan or patch images of untrusted origin.
+package registryacl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/policy"
+)
+
+// Rules are allow and deny patterns for source and destination registries. A pattern is a
+// registry host (docker.io, *.azurecr.io) or a repository prefix (registry.corp.internal/team/,
+// registry.corp.internal/*), with path.Match wildcards. Docker Hub images are matched as
+// docker.io/library/nginx.
+type Rules struct {
+	// SourceAllow, when set, are the only registries images may be pulled, scanned or patched from
+	SourceAllow []string
+	// SourceDeny are registries images may not come from, even when allowed by SourceAllow
+	SourceDeny []string
+	// PushAllow, when set, are the only registries patched images may be pushed to
+	PushAllow []string
+	// PushDeny are registries patched images may not be pushed to, even when allowed by PushAllow
+	PushDeny []string
+}
+
+// Enabled reports whether any rule is set
+func (r Rules) Enabled() bool {
+	return len(r.SourceAllow)+len(r.SourceDeny)+len(r.PushAllow)+len(r.PushDeny) > 0
+}
+
+// Validate checks the syntax of the patterns
+func (r Rules) Validate() error {
+	for _, patterns := range [][]string{r.SourceAllow, r.SourceDeny, r.PushAllow, r.PushDeny} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("invalid registry pattern %q", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckSource fails with a policy error when image may not be pulled, scanned or patched
+func (r Rules) CheckSource(image string) error {
+	if pattern, denied := check(image, r.SourceAllow, r.SourceDeny); denied {
+		return copaerrors.NewPolicyError(fmt.Sprintf("images from %s are not allowed%s", policy.Registry(image), reason(pattern, r.SourceAllow)), nil,
+			"use an image from an allowed registry, or ask an operator to review COPA_MCP_SOURCE_ALLOW and COPA_MCP_SOURCE_DENY")
+	}
+	return nil
+}
+
+// CheckPush fails with a policy error when image may not be pushed
+func (r Rules) CheckPush(image string) error {
+	if pattern, denied := check(image, r.PushAllow, r.PushDeny); denied {
+		return copaerrors.NewPolicyError(fmt.Sprintf("pushing %s is not allowed%s", image, reason(pattern, r.PushAllow)), nil,
+			"patch without push, or with exportPath, or ask an operator to review COPA_MCP_PUSH_ALLOW and COPA_MCP_PUSH_DENY")
+	}
+	return nil
+}
+
+// check reports whether image is denied, and the deny pattern it matched if any
+func check(image string, allow, deny []string) (string, bool) {
+	for _, pattern := range deny {
+		if Match(pattern, image) {
+			return pattern, true
+		}
+	}
+	if len(allow) == 0 {
+		return "", false
+	}
+	for _, pattern := range allow {
+		if Match(pattern, image) {
+			return "", false
+		}
+	}
+	return "", true
+}
+
+func reason(pattern string, allow []string) string {
+	if pattern != "" {
+		return fmt.Sprintf(": denied by %s", pattern)
+	}
+	return fmt.Sprintf(": not in %s", strings.Join(allow, ", "))
+}
+
+// Match reports whether pattern matches the registry host or the repository of image
+func Match(pattern, image string) bool {
+	host, repo := policy.Registry(image), Repository(image)
+	pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "/*"), "/")
+	if pattern == host || repo == pattern || strings.HasPrefix(repo, pattern+"/") {
+		return true
+	}
+	if ok, _ := path.Match(pattern, host); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, repo)
+	return ok
+}
+
+// Repository returns the repository of image, without its tag or digest and with its registry
+// host, e.g. docker.io/library/nginx for nginx:1.25
+func Repository(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	host := policy.Registry(name)
+	repo := strings.TrimPrefix(name, host+"/")
+	if host == "docker.io" && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return host + "/" + repo
+}
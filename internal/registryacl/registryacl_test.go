@@ -0,0 +1,74 @@
+package registryacl
+
+import (
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx:1.25":                          "docker.io/library/nginx",
+		"docker.io/nginx":                     "docker.io/library/nginx",
+		"bitnami/redis:7":                     "docker.io/bitnami/redis",
+		"ghcr.io/org/app@sha256:abc":          "ghcr.io/org/app",
+		"localhost:5000/app:1.0":              "localhost:5000/app",
+		"registry.corp.internal/team/app:1.0": "registry.corp.internal/team/app",
+	}
+	for image, want := range tests {
+		assert.Equal(t, want, Repository(image), image)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, image string
+		want           bool
+	}{
+		{"docker.io", "nginx:1.25", true},
+		{"docker.io/library/", "nginx:1.25", true},
+		{"docker.io/library", "bitnami/redis", false},
+		{"registry.corp.internal/*", "registry.corp.internal/team/app:1.0", true},
+		{"registry.corp.internal", "registry.corp.internal.evil.com/app", false},
+		{"ghcr.io/org", "ghcr.io/org/app:1.0", true},
+		{"ghcr.io/org", "ghcr.io/organization/app:1.0", false},
+		{"*.azurecr.io", "myacr.azurecr.io/app:1.0", true},
+		{"ghcr.io/org/*-base", "ghcr.io/org/python-base:3.12", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Match(tt.pattern, tt.image), "%s %s", tt.pattern, tt.image)
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	rules := Rules{SourceAllow: []string{"registry.corp.internal/*", "docker.io/library"}, SourceDeny: []string{"registry.corp.internal/sandbox"}}
+	assert.NoError(t, rules.CheckSource("registry.corp.internal/team/app:1.0"))
+	assert.NoError(t, rules.CheckSource("nginx:1.25"))
+
+	err := rules.CheckSource("ghcr.io/org/app:1.0")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryPolicy, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "not in registry.corp.internal/*, docker.io/library")
+
+	err = rules.CheckSource("registry.corp.internal/sandbox/app:1.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "denied by registry.corp.internal/sandbox")
+
+	assert.NoError(t, Rules{}.CheckSource("anything.example.com/app"))
+}
+
+func TestCheckPush(t *testing.T) {
+	rules := Rules{PushDeny: []string{"docker.io"}}
+	assert.Error(t, rules.CheckPush("nginx:1.25-patched"))
+	assert.Error(t, rules.CheckPush("docker.io/org/app:1.0-patched"))
+	assert.NoError(t, rules.CheckPush("registry.corp.internal/app:1.0-patched"))
+	assert.NoError(t, rules.CheckSource("nginx:1.25"), "push rules do not restrict sources")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Rules{SourceAllow: []string{"registry.corp.internal/*"}, PushDeny: []string{"docker.io"}}.Validate())
+	assert.Error(t, Rules{PushAllow: []string{"ghcr.io/[org"}}.Validate())
+	assert.Error(t, Rules{SourceDeny: []string{" "}}.Validate())
+}
@@ -0,0 +1,133 @@
+// Package sbom reads the packages of CycloneDX SBOMs and diffs them, to show exactly which
+// packages a patch added, upgraded or removed.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Package is a package listed in an SBOM
+type Package struct {
+	Name    string
+	Version string
+	Type    string // purl type, e.g. deb, apk or npm; empty when the component has no purl
+}
+
+// key identifies the package independently of its version
+func (p Package) key() string {
+	return p.Type + "/" + p.Name
+}
+
+// Change is a package whose version differs between two SBOMs
+type Change struct {
+	Package
+	From string
+}
+
+// Diff is the difference between the packages of two SBOMs
+type Diff struct {
+	Added     []Package
+	Removed   []Package
+	Changed   []Change // Package.Version is the new version
+	Unchanged int
+}
+
+// Parse returns the packages of a CycloneDX JSON SBOM, including nested components
+func Parse(data []byte) ([]Package, error) {
+	var bom struct {
+		BOMFormat  string      `json:"bomFormat"`
+		Components []component `json:"components"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return nil, err
+	}
+	if bom.BOMFormat != "CycloneDX" {
+		return nil, fmt.Errorf("not a CycloneDX SBOM (bomFormat %q)", bom.BOMFormat)
+	}
+
+	var packages []Package
+	var walk func([]component)
+	walk = func(components []component) {
+		for _, c := range components {
+			// Operating systems and application metadata are also components; only versioned
+			// libraries and applications are packages
+			if (c.Type == "library" || c.Type == "application") && c.Version != "" {
+				packages = append(packages, Package{Name: c.Name, Version: c.Version, Type: purlType(c.PURL)})
+			}
+			walk(c.Components)
+		}
+	}
+	walk(bom.Components)
+	return packages, nil
+}
+
+type component struct {
+	Type       string      `json:"type"`
+	Name       string      `json:"name"`
+	Version    string      `json:"version"`
+	PURL       string      `json:"purl"`
+	Components []component `json:"components"`
+}
+
+// purlType returns the type of a package URL, e.g. deb for pkg:deb/debian/openssl@3.0.11
+func purlType(purl string) string {
+	rest, found := strings.CutPrefix(purl, "pkg:")
+	if !found {
+		return ""
+	}
+	typ, _, _ := strings.Cut(rest, "/")
+	return typ
+}
+
+// Compare diffs the packages of two SBOMs. A package with one version in each SBOM is changed;
+// otherwise versions only in before are removed and versions only in after are added.
+func Compare(before, after []Package) Diff {
+	versions := func(packages []Package) map[string][]string {
+		m := map[string][]string{}
+		for _, p := range packages {
+			if !slices.Contains(m[p.key()], p.Version) {
+				m[p.key()] = append(m[p.key()], p.Version)
+			}
+		}
+		return m
+	}
+	byKey := map[string]Package{}
+	for _, p := range append(slices.Clone(before), after...) {
+		byKey[p.key()] = p
+	}
+	beforeVersions, afterVersions := versions(before), versions(after)
+
+	var diff Diff
+	for _, key := range slices.Sorted(maps.Keys(byKey)) {
+		p := byKey[key]
+		var removed, added []string
+		for _, v := range beforeVersions[key] {
+			if slices.Contains(afterVersions[key], v) {
+				diff.Unchanged++
+			} else {
+				removed = append(removed, v)
+			}
+		}
+		for _, v := range afterVersions[key] {
+			if !slices.Contains(beforeVersions[key], v) {
+				added = append(added, v)
+			}
+		}
+
+		if len(removed) == 1 && len(added) == 1 {
+			diff.Changed = append(diff.Changed, Change{Package: Package{Name: p.Name, Type: p.Type, Version: added[0]}, From: removed[0]})
+			continue
+		}
+		for _, v := range removed {
+			diff.Removed = append(diff.Removed, Package{Name: p.Name, Type: p.Type, Version: v})
+		}
+		for _, v := range added {
+			diff.Added = append(diff.Added, Package{Name: p.Name, Type: p.Type, Version: v})
+		}
+	}
+	return diff
+}
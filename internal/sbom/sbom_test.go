@@ -0,0 +1,62 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	bom := `{
+  "bomFormat": "CycloneDX",
+  "components": [
+    {"type": "operating-system", "name": "debian", "version": "12.1"},
+    {"type": "library", "name": "openssl", "version": "3.0.9-1", "purl": "pkg:deb/debian/openssl@3.0.9-1?arch=amd64&distro=debian-12.1"},
+    {"type": "application", "name": "app", "version": "1.0.0", "components": [
+      {"type": "library", "name": "lodash", "version": "4.17.20", "purl": "pkg:npm/lodash@4.17.20"}
+    ]},
+    {"type": "library", "name": "unversioned"}
+  ]
+}`
+	packages, err := Parse([]byte(bom))
+	require.NoError(t, err)
+	assert.Equal(t, []Package{
+		{Name: "openssl", Version: "3.0.9-1", Type: "deb"},
+		{Name: "app", Version: "1.0.0"},
+		{Name: "lodash", Version: "4.17.20", Type: "npm"},
+	}, packages)
+
+	_, err = Parse([]byte(`{"spdxVersion": "SPDX-2.3"}`))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	before := []Package{
+		{Name: "openssl", Version: "3.0.9-1", Type: "deb"},
+		{Name: "zlib", Version: "1.2.13", Type: "deb"},
+		{Name: "libfoo", Version: "1.0", Type: "deb"},
+		{Name: "kernel-headers", Version: "5.10", Type: "deb"},
+		{Name: "kernel-headers", Version: "5.15", Type: "deb"},
+	}
+	after := []Package{
+		{Name: "openssl", Version: "3.0.11-1", Type: "deb"},
+		{Name: "zlib", Version: "1.2.13", Type: "deb"},
+		{Name: "libbar", Version: "2.0", Type: "deb"},
+		{Name: "kernel-headers", Version: "5.16", Type: "deb"},
+	}
+
+	assert.Equal(t, Diff{
+		Added: []Package{
+			{Name: "kernel-headers", Version: "5.16", Type: "deb"},
+			{Name: "libbar", Version: "2.0", Type: "deb"},
+		},
+		Removed: []Package{
+			{Name: "kernel-headers", Version: "5.10", Type: "deb"},
+			{Name: "kernel-headers", Version: "5.15", Type: "deb"},
+			{Name: "libfoo", Version: "1.0", Type: "deb"},
+		},
+		Changed:   []Change{{Package: Package{Name: "openssl", Version: "3.0.11-1", Type: "deb"}, From: "3.0.9-1"}},
+		Unchanged: 1,
+	}, Compare(before, after))
+}
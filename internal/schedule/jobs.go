@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/project-copacetic/mcp-server/internal/copa"
+)
+
+// jobNamePattern restricts job names to characters that are safe in resource URIs
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Job is a recurring scan of an image, read from the schedule file
+type Job struct {
+	Name     string       `json:"name"`
+	Image    string       `json:"image"`
+	Platform []string     `json:"platform,omitempty"`
+	Schedule string       `json:"schedule"`
+	Patch    *PatchPolicy `json:"patch,omitempty"`
+
+	schedule Schedule
+}
+
+// PatchPolicy patches the image after a scheduled scan that finds vulnerabilities at or above Threshold
+type PatchPolicy struct {
+	Threshold string `json:"threshold"`
+	Tag       string `json:"patchtag"`
+	Push      bool   `json:"push,omitempty"`
+}
+
+// Triggered counts the vulnerabilities at or above the policy's threshold. severities maps the
+// vulnerability IDs of a scan report to their severity, as returned by trivy.Severities.
+func (p *PatchPolicy) Triggered(severities map[string]string) int {
+	threshold := slices.Index(copa.Severities, p.Threshold)
+	count := 0
+	for _, severity := range severities {
+		if i := slices.Index(copa.Severities, severity); i >= 0 && i <= threshold {
+			count++
+		}
+	}
+	return count
+}
+
+// file is the layout of the schedule file
+type file struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// LoadJobs reads and validates the jobs of a JSON schedule file
+func LoadJobs(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule file: %w", err)
+	}
+
+	var f file
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule file %s: %w", path, err)
+	}
+	if len(f.Jobs) == 0 {
+		return nil, fmt.Errorf("schedule file %s has no jobs", path)
+	}
+
+	names := map[string]bool{}
+	for i := range f.Jobs {
+		job := &f.Jobs[i]
+		if err := job.validate(); err != nil {
+			return nil, fmt.Errorf("schedule file %s: job %d: %w", path, i+1, err)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("schedule file %s: duplicate job name %q", path, job.Name)
+		}
+		names[job.Name] = true
+	}
+	return f.Jobs, nil
+}
+
+// validate checks the job and parses its schedule
+func (j *Job) validate() error {
+	if !jobNamePattern.MatchString(j.Name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, '.', '_' and '-'", j.Name)
+	}
+	if j.Image == "" {
+		return fmt.Errorf("job %q: image is required", j.Name)
+	}
+	for _, platform := range j.Platform {
+		if !copa.IsPlatformSupported(platform) {
+			return fmt.Errorf("job %q: unsupported platform: %s", j.Name, platform)
+		}
+	}
+
+	var err error
+	if j.schedule, err = Parse(j.Schedule); err != nil {
+		return fmt.Errorf("job %q: %w", j.Name, err)
+	}
+
+	if j.Patch != nil {
+		j.Patch.Threshold = strings.ToUpper(j.Patch.Threshold)
+		if !slices.Contains(copa.Severities, j.Patch.Threshold) {
+			return fmt.Errorf("job %q: unsupported patch threshold %q, use one of %s", j.Name, j.Patch.Threshold, strings.Join(copa.Severities, ", "))
+		}
+		if j.Patch.Tag == "" {
+			return fmt.Errorf("job %q: patch requires patchtag", j.Name)
+		}
+	}
+	return nil
+}
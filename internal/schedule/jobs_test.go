@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScheduleFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadJobs(t *testing.T) {
+	path := writeScheduleFile(t, `{"jobs":[
+		{"name":"nginx","image":"nginx:1.25","platform":["linux/amd64"],"schedule":"0 3 * * *","patch":{"threshold":"high","patchtag":"patched","push":true}},
+		{"name":"alpine","image":"alpine:3.18","schedule":"@every 6h"}
+	]}`)
+
+	jobs, err := LoadJobs(path)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "nginx", jobs[0].Name)
+	assert.Equal(t, []string{"linux/amd64"}, jobs[0].Platform)
+	assert.Equal(t, &PatchPolicy{Threshold: "HIGH", Tag: "patched", Push: true}, jobs[0].Patch)
+	assert.NotNil(t, jobs[0].schedule)
+	assert.Nil(t, jobs[1].Patch)
+}
+
+func TestLoadJobs_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no jobs", `{"jobs":[]}`, "has no jobs"},
+		{"unknown field", `{"jobs":[{"name":"a","image":"alpine","schedule":"@daily","tag":"x"}]}`, "unknown field"},
+		{"missing name", `{"jobs":[{"image":"alpine","schedule":"@daily"}]}`, "invalid name"},
+		{"unsafe name", `{"jobs":[{"name":"a/b","image":"alpine","schedule":"@daily"}]}`, "invalid name"},
+		{"missing image", `{"jobs":[{"name":"a","schedule":"@daily"}]}`, "image is required"},
+		{"bad platform", `{"jobs":[{"name":"a","image":"alpine","platform":["windows/amd64"],"schedule":"@daily"}]}`, "unsupported platform"},
+		{"bad schedule", `{"jobs":[{"name":"a","image":"alpine","schedule":"daily"}]}`, "invalid schedule"},
+		{"bad threshold", `{"jobs":[{"name":"a","image":"alpine","schedule":"@daily","patch":{"threshold":"severe","patchtag":"p"}}]}`, "unsupported patch threshold"},
+		{"missing tag", `{"jobs":[{"name":"a","image":"alpine","schedule":"@daily","patch":{"threshold":"HIGH"}}]}`, "requires patchtag"},
+		{"duplicate", `{"jobs":[{"name":"a","image":"alpine","schedule":"@daily"},{"name":"a","image":"nginx","schedule":"@daily"}]}`, "duplicate job name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadJobs(writeScheduleFile(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadJobs_MissingFile(t *testing.T) {
+	_, err := LoadJobs(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestPatchPolicy_Triggered(t *testing.T) {
+	severities := map[string]string{
+		"CVE-1": "CRITICAL",
+		"CVE-2": "HIGH",
+		"CVE-3": "MEDIUM",
+		"CVE-4": "UNKNOWN",
+	}
+	assert.Equal(t, 1, (&PatchPolicy{Threshold: "CRITICAL"}).Triggered(severities))
+	assert.Equal(t, 2, (&PatchPolicy{Threshold: "HIGH"}).Triggered(severities))
+	assert.Equal(t, 3, (&PatchPolicy{Threshold: "LOW"}).Triggered(severities))
+	assert.Equal(t, 0, (&PatchPolicy{Threshold: "HIGH"}).Triggered(map[string]string{"CVE-5": "LOW"}))
+}
@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler runs jobs on their schedules until its context is done
+type Scheduler struct {
+	jobs []Job
+	run  func(context.Context, Job)
+	now  func() time.Time
+}
+
+// New creates a scheduler that calls run for each job when it is due. Runs of the same job
+// never overlap: a run that takes longer than the interval delays the next one.
+func New(jobs []Job, run func(context.Context, Job)) *Scheduler {
+	return &Scheduler{jobs: jobs, run: run, now: time.Now}
+}
+
+// Run blocks until ctx is done and all runs in progress have returned
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+// loop runs a single job each time it is due
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	for {
+		next := job.schedule.Next(s.now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, job)
+	}
+}
+
+// Next returns when the job runs next after t
+func (j Job) Next(t time.Time) time.Time {
+	return j.schedule.Next(t)
+}
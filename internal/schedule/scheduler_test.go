@@ -0,0 +1,51 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	var runs atomic.Int32
+	jobs := []Job{{Name: "a", Image: "alpine", schedule: everySchedule(10 * time.Millisecond)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		New(jobs, func(ctx context.Context, job Job) {
+			assert.Equal(t, "a", job.Name)
+			if runs.Add(1) == 3 {
+				cancel()
+			}
+		}).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestScheduler_StopsWhenNeverDue(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		New([]Job{{Name: "a", schedule: s}}, func(context.Context, Job) { t.Error("job ran") }).Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
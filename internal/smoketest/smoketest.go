@@ -0,0 +1,220 @@
+// Package smoketest tests a patched image before it is pushed. The image is pushed to a temporary
+// registry:2 container on the Docker daemon, and a user-supplied command is run against that copy,
+// so that an image broken by patching never reaches its registry.
+package smoketest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+const (
+	// RegistryImage is the image of the temporary registry
+	RegistryImage = "registry:2"
+	// Placeholder is replaced by the reference of the image under test in the command's arguments
+	Placeholder = "{image}"
+	// ImageEnv names the environment variable holding the reference of the image under test
+	ImageEnv = "SMOKE_TEST_IMAGE"
+	// Timeout bounds the smoke test command
+	Timeout = 10 * time.Minute
+
+	// registryPort is the port the registry listens on in its container
+	registryPort = "5000/tcp"
+	// registryStartTimeout bounds the wait for the registry to accept the image
+	registryStartTimeout = 30 * time.Second
+	// registryRetryInterval is the delay between pushes to a registry that is still starting
+	registryRetryInterval = 500 * time.Millisecond
+	// cleanupTimeout bounds removing the registry, which also happens after the call was canceled
+	cleanupTimeout = 30 * time.Second
+	// maxOutput caps the command output kept in the result
+	maxOutput = 4096
+)
+
+// Run pushes image, from the Docker daemon at host, to a temporary registry and runs command against
+// the copy there. The registry and its tag of the image are removed before Run returns. A command
+// that fails, or runs longer than Timeout, fails the test.
+func Run(ctx context.Context, host, image string, command []string) (*types.SmokeTestResult, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, copaerrors.NewValidationError("smoke test command is empty", nil)
+	}
+
+	id, addr, err := startRegistry(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer removeRegistry(host, id)
+
+	local := LocalReference(addr, image)
+	if err := docker.Tag(ctx, host, image, local); err != nil {
+		return nil, err
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		_, _ = docker.RemoveImages(cleanupCtx, host, []string{local}, false)
+	}()
+	if err := pushWhenReady(ctx, host, local); err != nil {
+		return nil, err
+	}
+
+	return runCommand(ctx, host, local, command)
+}
+
+// startRegistry starts a registry container publishing its port on the daemon's loopback interface,
+// which the daemon trusts without TLS, and returns the container ID and registry address
+func startRegistry(ctx context.Context, host string) (id, addr string, err error) {
+	cmd := exec.CommandContext(ctx, "docker", "run", "--detach", "--rm", "--publish", "127.0.0.1::5000", RegistryImage)
+	cmd.Env = docker.Env(ctx, host)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		return "", "", copaerrors.New(copaerrors.Classify(msg, copaerrors.CategoryExecution),
+			"starting the smoke test registry failed", fmt.Errorf("%w\n%s", err, msg),
+			fmt.Sprintf("the Docker daemon must be able to pull %s", RegistryImage))
+	}
+	id = strings.TrimSpace(string(output))
+
+	cmd = exec.CommandContext(ctx, "docker", "port", id, registryPort)
+	cmd.Env = docker.Env(ctx, host)
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		addr, err = registryAddr(string(output))
+	}
+	if err != nil {
+		removeRegistry(host, id)
+		return "", "", copaerrors.NewExecutionError("finding the port of the smoke test registry failed", fmt.Errorf("%w\n%s", err, strings.TrimSpace(string(output))))
+	}
+	return id, addr, nil
+}
+
+// registryAddr returns the registry address from the output of 'docker port', one host:port per line
+func registryAddr(output string) (string, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	i := strings.LastIndex(line, ":")
+	if i < 0 || i == len(line)-1 {
+		return "", fmt.Errorf("unexpected port mapping %q", line)
+	}
+	return "localhost" + line[i:], nil
+}
+
+// removeRegistry removes the registry container and, with it, the images pushed to it
+func removeRegistry(host, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "docker", "rm", "--force", id)
+	cmd.Env = docker.Env(ctx, host)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		slog.Warn(fmt.Sprintf("removing smoke test registry %s failed: %v", id, err), "logger", "smoketest", "output", strings.TrimSpace(string(output)))
+	}
+}
+
+// pushWhenReady pushes image to the registry, retrying while the registry is starting
+func pushWhenReady(ctx context.Context, host, image string) error {
+	deadline := time.Now().Add(registryStartTimeout)
+	for {
+		err := docker.Push(ctx, host, image)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(registryRetryInterval):
+		}
+	}
+}
+
+// LocalReference returns the reference of image in the registry at addr: the same repository path
+// and tag, without the image's own registry host
+func LocalReference(addr, image string) string {
+	if host, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		image = rest
+	}
+	return addr + "/" + image
+}
+
+// ExpandArgs replaces Placeholder with image in each argument of command
+func ExpandArgs(command []string, image string) []string {
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.ReplaceAll(arg, Placeholder, image)
+	}
+	return args
+}
+
+// runCommand runs command against image, with the daemon at host as the command's DOCKER_HOST
+func runCommand(ctx context.Context, host, image string, command []string) (*types.SmokeTestResult, error) {
+	// Waiting for a slot does not count towards the timeout
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	args := ExpandArgs(command, image)
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = docker.Env(ctx, host)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, ImageEnv+"="+image)
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err = cmd.Run()
+
+	duration := time.Since(start)
+	result := &types.SmokeTestResult{
+		Command:  args,
+		Image:    image,
+		Duration: duration.Round(time.Millisecond).String(),
+		Output:   tail(output.String(), maxOutput),
+	}
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, copaerrors.NewValidationError(fmt.Sprintf("smoke test command not found: %s", args[0]), err,
+				"the command runs on the server; use a program installed there, e.g. docker")
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s: %w", Timeout, err)
+		}
+		return nil, copaerrors.NewExecutionError("smoke test failed", fmt.Errorf("%w\n%s", err, result.Output)).
+			WithCommand(args, exitCode, result.Output, duration)
+	}
+	return result, nil
+}
+
+// tail returns the last limit bytes of s, starting at a line boundary when one is available
+func tail(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= limit {
+		return s
+	}
+	s = s[len(s)-limit:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package smoketest
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx:1.25-patched", "localhost:5001/nginx:1.25-patched"},
+		{"library/nginx:patched", "localhost:5001/library/nginx:patched"},
+		{"ghcr.io/org/app:patched", "localhost:5001/org/app:patched"},
+		{"registry.local:5000/app:patched", "localhost:5001/app:patched"},
+		{"localhost/app:patched", "localhost:5001/app:patched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.expected, LocalReference("localhost:5001", tt.image))
+		})
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	command := []string{"docker", "run", "--rm", "{image}", "sh", "-c", "echo {image}"}
+	args := ExpandArgs(command, "localhost:5001/app:patched")
+
+	assert.Equal(t, []string{"docker", "run", "--rm", "localhost:5001/app:patched", "sh", "-c", "echo localhost:5001/app:patched"}, args)
+	assert.Equal(t, "{image}", command[3], "the command is not modified")
+}
+
+func TestRegistryAddr(t *testing.T) {
+	addr, err := registryAddr("127.0.0.1:49153\n[::1]:49153\n")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:49153", addr)
+
+	_, err = registryAddr("")
+	assert.Error(t, err)
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "short", tail("short\n", 10))
+	assert.Equal(t, "line3\nline4", tail("line1\nline2\nline3\nline4", 12))
+}
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	t.Run("passes", func(t *testing.T) {
+		result, err := runCommand(context.Background(), "", "localhost:5001/app:patched", []string{"sh", "-c", "echo tested {image} $" + ImageEnv})
+		require.NoError(t, err)
+		assert.Equal(t, "tested localhost:5001/app:patched localhost:5001/app:patched", result.Output)
+		assert.Equal(t, "localhost:5001/app:patched", result.Image)
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		_, err := runCommand(context.Background(), "", "localhost:5001/app:patched", []string{"sh", "-c", "echo broken; exit 3"})
+		require.Error(t, err)
+		assert.Equal(t, copaerrors.CategoryExecution, copaerrors.CategoryOf(err))
+		assert.True(t, strings.Contains(err.Error(), "broken"))
+
+		var ce *copaerrors.CopaceticError
+		require.ErrorAs(t, err, &ce)
+		require.NotNil(t, ce.Command)
+		assert.Equal(t, 3, ce.Command.ExitCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := runCommand(context.Background(), "", "app", []string{"copa-mcp-no-such-command"})
+		assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	})
+}
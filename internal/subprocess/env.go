@@ -0,0 +1,98 @@
+package subprocess
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// env holds the variables set in the environment of every subprocess, nil when there are none
+var env map[string]string
+
+// nameRegexp matches the name of an environment variable
+var nameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseEnv parses KEY=value entries, e.g. TRIVY_CACHE_DIR=/cache or NO_PROXY=localhost,.internal
+func ParseEnv(entries []string) (map[string]string, error) {
+	vars := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || !nameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable %q: must be KEY=value", entry)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// SetEnv sets vars in the environment of the copa, trivy, docker and ctr subprocesses started
+// afterwards, over the variables they inherit from the server. Empty vars removes them.
+func SetEnv(vars map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(vars) == 0 {
+		env = nil
+		return
+	}
+	env = maps.Clone(vars)
+}
+
+// CheckOverrides checks that the variables a tool call overrides are set by SetEnv. A call can
+// only change their values, so that it cannot set variables such as LD_PRELOAD or PATH that
+// change which code the server runs.
+func CheckOverrides(overrides map[string]string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	var unknown []string
+	for name := range overrides {
+		if _, ok := env[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+	return copaerrors.NewValidationError(fmt.Sprintf("environment variables not configured on the server: %s", strings.Join(unknown, ", ")), nil,
+		fmt.Sprintf("a call can only override the variables of COPA_MCP_SUBPROCESS_ENV (%s)", strings.Join(slices.Sorted(maps.Keys(env)), ", ")))
+}
+
+type envKey struct{}
+
+// WithEnv returns a copy of ctx whose subprocesses get overrides in place of the values set by
+// SetEnv. Check them with CheckOverrides first.
+func WithEnv(ctx context.Context, overrides map[string]string) context.Context {
+	if len(overrides) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, envKey{}, maps.Clone(overrides))
+}
+
+// Environ returns the environment of a subprocess started for ctx: the server's, with the
+// variables set by SetEnv and the overrides of ctx. It returns nil when there are none, for the
+// subprocess to inherit the server's environment.
+func Environ(ctx context.Context) []string {
+	mu.Lock()
+	vars := env
+	mu.Unlock()
+	overrides, _ := ctx.Value(envKey{}).(map[string]string)
+	if len(vars) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	// exec keeps the last value of a variable set several times
+	environ := os.Environ()
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		environ = append(environ, name+"="+vars[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		environ = append(environ, name+"="+overrides[name])
+	}
+	return environ
+}
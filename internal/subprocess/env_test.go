@@ -0,0 +1,49 @@
+package subprocess
+
+import (
+	"context"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnv(t *testing.T) {
+	vars, err := ParseEnv([]string{"TRIVY_CACHE_DIR=/cache", "NO_PROXY=localhost,.internal", "EMPTY="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TRIVY_CACHE_DIR": "/cache", "NO_PROXY": "localhost,.internal", "EMPTY": ""}, vars)
+
+	for _, entry := range []string{"TRIVY_CACHE_DIR", "=value", "1PROXY=x", "MY-VAR=x"} {
+		_, err := ParseEnv([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	SetEnv(nil)
+	t.Cleanup(func() { SetEnv(nil) })
+	ctx := context.Background()
+	assert.Nil(t, Environ(ctx), "subprocesses inherit the server's environment")
+
+	SetEnv(map[string]string{"TRIVY_CACHE_DIR": "/cache", "HTTPS_PROXY": "http://proxy:3128"})
+	env := Environ(ctx)
+	assert.Equal(t, []string{"HTTPS_PROXY=http://proxy:3128", "TRIVY_CACHE_DIR=/cache"}, env[len(env)-2:])
+
+	ctx = WithEnv(ctx, map[string]string{"TRIVY_CACHE_DIR": "/tmp/cache"})
+	env = Environ(ctx)
+	assert.Equal(t, "TRIVY_CACHE_DIR=/tmp/cache", env[len(env)-1], "the call's value comes last and wins")
+}
+
+func TestCheckOverrides(t *testing.T) {
+	SetEnv(map[string]string{"TRIVY_CACHE_DIR": "/cache"})
+	t.Cleanup(func() { SetEnv(nil) })
+
+	assert.NoError(t, CheckOverrides(nil))
+	assert.NoError(t, CheckOverrides(map[string]string{"TRIVY_CACHE_DIR": "/tmp/cache"}))
+
+	err := CheckOverrides(map[string]string{"TRIVY_CACHE_DIR": "/tmp/cache", "PATH": "/tmp", "LD_PRELOAD": "/tmp/x.so"})
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "LD_PRELOAD, PATH")
+}
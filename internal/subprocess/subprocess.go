@@ -0,0 +1,59 @@
+// Package subprocess limits how many of the server's long-running copa, trivy and docker
+// subprocesses run at once, so a burst of tool calls cannot exhaust the CPU, memory and buildkit
+// capacity of the host. Quick probes such as 'docker version' are not limited, so that they never
+// wait behind a patch.
+package subprocess
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+var (
+	mu    sync.Mutex
+	slots chan struct{} // nil when unlimited
+)
+
+// SetLimit limits the subprocesses started after an Acquire to n at a time, process-wide. A limit
+// of 0 removes it. Subprocesses holding a slot of an earlier limit keep it until they release it.
+func SetLimit(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if n <= 0 {
+		slots = nil
+		return
+	}
+	if slots == nil || cap(slots) != n {
+		slots = make(chan struct{}, n)
+	}
+}
+
+// Limit returns the current limit, 0 when there is none
+func Limit() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return cap(slots)
+}
+
+// Acquire waits for a free slot, returning the function releasing it once the subprocess has
+// exited. It fails only when ctx is done first.
+func Acquire(ctx context.Context) (func(), error) {
+	mu.Lock()
+	s := slots
+	mu.Unlock()
+	if s == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case s <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-s }) }, nil
+	case <-ctx.Done():
+		return nil, copaerrors.NewSystemError(fmt.Sprintf("canceled while waiting for one of the %d subprocess slots", cap(s)), ctx.Err(),
+			"other scans and patches are running; retry later, or raise COPA_MCP_MAX_SUBPROCESSES")
+	}
+}
@@ -0,0 +1,80 @@
+package subprocess
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire_Unlimited(t *testing.T) {
+	SetLimit(0)
+	t.Cleanup(func() { SetLimit(0) })
+
+	for i := 0; i < 10; i++ {
+		_, err := Acquire(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, Limit())
+}
+
+func TestAcquire_WaitsForASlot(t *testing.T) {
+	SetLimit(2)
+	t.Cleanup(func() { SetLimit(0) })
+
+	first, err := Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = Acquire(context.Background())
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := Acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("a third subprocess started past the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first()
+	first() // Releasing twice frees a single slot
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("the released slot was not reused")
+	}
+}
+
+func TestAcquire_Canceled(t *testing.T) {
+	SetLimit(1)
+	t.Cleanup(func() { SetLimit(0) })
+
+	release, err := Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(ctx)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSetLimit(t *testing.T) {
+	t.Cleanup(func() { SetLimit(0) })
+
+	SetLimit(3)
+	assert.Equal(t, 3, Limit())
+	SetLimit(-1)
+	assert.Equal(t, 0, Limit())
+}
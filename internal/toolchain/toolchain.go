@@ -0,0 +1,157 @@
+// Package toolchain detects the versions of the copa and trivy CLIs the server runs, and whether they
+// support the flags the tools rely on, so that a call needing a newer release fails with upgrade
+// guidance instead of an unknown flag error from the CLI.
+package toolchain
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+)
+
+// probeTimeout bounds a '<tool> --version' call
+const probeTimeout = 10 * time.Second
+
+// Version is a major.minor.patch release of a CLI
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is an older release than o
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// ParseVersion returns the first version found in the output of '<tool> --version', e.g.
+// "copa version 0.10.0" or "Version: 0.52.2". ok is false when there is none.
+func ParseVersion(output string) (v Version, ok bool) {
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return Version{}, false
+	}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, true
+}
+
+// Feature is a flag of a CLI the server passes only to releases that have it
+type Feature struct {
+	Tool    string  // CLI having the flag, copa or trivy
+	Flag    string  // Flag the server passes
+	Since   Version // First release with the flag
+	Purpose string  // What the server uses the flag for
+}
+
+func (f Feature) String() string {
+	return fmt.Sprintf("%s %s (%s)", f.Tool, f.Flag, f.Purpose)
+}
+
+// The feature matrix
+var (
+	CopaPlatform  = Feature{Tool: "copa", Flag: "--platform", Since: Version{0, 10, 0}, Purpose: "patching selected platforms of a multi-platform image"}
+	TrivyPlatform = Feature{Tool: "trivy", Flag: "--platform", Since: Version{0, 37, 0}, Purpose: "scanning selected platforms of a multi-platform image"}
+	TrivyImageSrc = Feature{Tool: "trivy", Flag: "--image-src", Since: Version{0, 37, 0}, Purpose: "scanning remote platforms without pulling them"}
+	TrivyPkgTypes = Feature{Tool: "trivy", Flag: "--pkg-types", Since: Version{0, 52, 0}, Purpose: "selecting OS packages, replacing the deprecated --vuln-type"}
+)
+
+// Features lists the feature matrix, in the order reported by Missing
+var Features = []Feature{CopaPlatform, TrivyPlatform, TrivyImageSrc, TrivyPkgTypes}
+
+// installHints points to the installation docs of each tool
+var installHints = map[string]string{
+	"copa":  "install a newer Copacetic release: https://project-copacetic.github.io/copacetic/website/installation",
+	"trivy": "install a newer Trivy release: https://trivy.dev/latest/getting-started/installation/",
+}
+
+type detected struct {
+	version Version
+	ok      bool
+}
+
+var (
+	mu sync.Mutex
+	// cache holds the detected version of each binary path, so a tool is probed once unless it moves
+	cache = map[string]detected{}
+)
+
+// installed is replaced by tests to fake the installed versions
+var installed = detect
+
+// Installed returns the version of the installed tool. ok is false when the tool is not found or
+// its version is not recognized.
+func Installed(ctx context.Context, tool string) (Version, bool) {
+	return installed(ctx, tool)
+}
+
+// detect runs '<tool> --version' once per binary path and caches the result
+func detect(ctx context.Context, tool string) (Version, bool) {
+	path, err := copaexec.LookPath(tool)
+	if err != nil {
+		return Version{}, false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if d, found := cache[path]; found {
+		return d.version, d.ok
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	var d detected
+	if output, err := exec.CommandContext(ctx, path, "--version").Output(); err == nil {
+		d.version, d.ok = ParseVersion(string(output))
+		cache[path] = d
+	}
+	// A failed probe (e.g. a cancelled call) is not cached, and is retried by the next call
+	return d.version, d.ok
+}
+
+// Supports reports whether the installed tool has f. A tool whose version cannot be determined is
+// assumed to have it, leaving any failure to the tool itself.
+func Supports(ctx context.Context, f Feature) bool {
+	v, ok := installed(ctx, f.Tool)
+	return !ok || !v.Less(f.Since)
+}
+
+// Require returns a validation error with upgrade guidance when the installed tool lacks f
+func Require(ctx context.Context, f Feature) error {
+	v, ok := installed(ctx, f.Tool)
+	if !ok || !v.Less(f.Since) {
+		return nil
+	}
+	return copaerrors.NewValidationError(fmt.Sprintf("%s %s does not support %s, which is needed for %s", f.Tool, v, f.Flag, f.Purpose), nil,
+		fmt.Sprintf("upgrade %s to %s or later", f.Tool, f.Since),
+		installHints[f.Tool])
+}
+
+// Missing describes the features of the matrix the installed tools lack, with the release adding each
+func Missing(ctx context.Context) []string {
+	var missing []string
+	for _, f := range Features {
+		if v, ok := installed(ctx, f.Tool); ok && v.Less(f.Since) {
+			missing = append(missing, fmt.Sprintf("%s needs %s %s or later, found %s", f, f.Tool, f.Since, v))
+		}
+	}
+	return missing
+}
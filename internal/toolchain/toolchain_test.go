@@ -0,0 +1,80 @@
+package toolchain
+
+import (
+	"context"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInstalled makes the installed tools report versions for the test; tools not in versions are
+// not found
+func fakeInstalled(t *testing.T, versions map[string]Version) {
+	t.Helper()
+	installed = func(ctx context.Context, tool string) (Version, bool) {
+		v, ok := versions[tool]
+		return v, ok
+	}
+	t.Cleanup(func() { installed = detect })
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   Version
+		ok     bool
+	}{
+		{"copa version 0.10.0", Version{0, 10, 0}, true},
+		{"copa version v0.11.1 (mock)\n", Version{0, 11, 1}, true},
+		{"Version: 0.52.2\nVulnerability DB:\n  Version: 2\n", Version{0, 52, 2}, true},
+		{"copa version dev", Version{}, false},
+		{"", Version{}, false},
+	}
+	for _, tt := range tests {
+		v, ok := ParseVersion(tt.output)
+		assert.Equal(t, tt.ok, ok, tt.output)
+		assert.Equal(t, tt.want, v, tt.output)
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	assert.True(t, Version{0, 9, 9}.Less(Version{0, 10, 0}))
+	assert.True(t, Version{0, 10, 0}.Less(Version{0, 10, 1}))
+	assert.False(t, Version{1, 0, 0}.Less(Version{0, 52, 0}))
+	assert.False(t, Version{0, 10, 0}.Less(Version{0, 10, 0}))
+	assert.Equal(t, "0.10.0", Version{0, 10, 0}.String())
+}
+
+func TestRequire(t *testing.T) {
+	fakeInstalled(t, map[string]Version{"copa": {0, 9, 0}, "trivy": {0, 60, 0}})
+	ctx := context.Background()
+
+	err := Require(ctx, CopaPlatform)
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	assert.Contains(t, err.Error(), "copa 0.9.0 does not support --platform")
+	assert.Contains(t, err.Error(), "upgrade copa to 0.10.0 or later")
+
+	assert.NoError(t, Require(ctx, TrivyPlatform))
+	assert.False(t, Supports(ctx, CopaPlatform))
+	assert.True(t, Supports(ctx, TrivyPkgTypes))
+}
+
+func TestRequire_UnknownVersion(t *testing.T) {
+	fakeInstalled(t, nil)
+
+	// Without a known version the call is left to the tool
+	assert.NoError(t, Require(context.Background(), CopaPlatform))
+	assert.True(t, Supports(context.Background(), TrivyPkgTypes))
+	assert.Empty(t, Missing(context.Background()))
+}
+
+func TestMissing(t *testing.T) {
+	fakeInstalled(t, map[string]Version{"copa": {0, 11, 1}, "trivy": {0, 45, 0}})
+
+	assert.Equal(t, []string{
+		"trivy --pkg-types (selecting OS packages, replacing the deprecated --vuln-type) needs trivy 0.52.0 or later, found 0.45.0",
+	}, Missing(context.Background()))
+}
@@ -0,0 +1,71 @@
+package trivy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/registry"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// importAttachedReport writes the scan report attached to the image in its registry to a report
+// directory, converting SARIF logs to a Trivy report. It returns an empty reportPath when no
+// report is attached.
+func importAttachedReport(ctx context.Context, cc *mcp.ServerSession, params ScanParams) (reportPath, referrer string, err error) {
+	attached, err := registry.FindAttachedReport(ctx, params.Image)
+	if err != nil || attached == nil {
+		return "", "", err
+	}
+	joblog.Log(ctx, cc, &mcp.LoggingMessageParams{
+		Data:   fmt.Sprintf("Reusing %s report %s attached to %s", attached.Format, attached.Digest, params.Image),
+		Level:  "info",
+		Logger: "trivy",
+	})
+
+	reportDir, err := workdir.MkdirTemp("reports-*")
+	if err != nil {
+		return "", "", copaerrors.NewSystemError("failed to create temporary report directory", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(reportDir)
+		}
+	}()
+
+	switch attached.Format {
+	case registry.FormatSARIF:
+		vulns, err := ParseSARIF(attached.Data)
+		if err != nil {
+			return "", "", err
+		}
+		// SARIF logs do not name the distribution, which copa needs to pick a package manager
+		osID, osVersion, err := docker.OSRelease(ctx, params.DockerHost, params.Image)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := WriteReport(reportDir, params.Image, osID, osVersion, vulns); err != nil {
+			return "", "", copaerrors.NewSystemError("failed to write the converted report", err)
+		}
+	default:
+		var report struct {
+			SchemaVersion int `json:"SchemaVersion"`
+			Metadata      struct {
+				OS *reportOS `json:"OS"`
+			} `json:"Metadata"`
+		}
+		if err := json.Unmarshal(attached.Data, &report); err != nil || report.SchemaVersion == 0 || report.Metadata.OS == nil {
+			return "", "", copaerrors.NewValidationError(fmt.Sprintf("attached report %s is not a Trivy JSON report of an OS image", attached.Digest), err)
+		}
+		if err := os.WriteFile(filepath.Join(reportDir, "report.json"), attached.Data, 0o644); err != nil {
+			return "", "", copaerrors.NewSystemError("failed to write the attached report", err)
+		}
+	}
+	return reportDir, attached.Digest, nil
+}
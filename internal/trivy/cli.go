@@ -1,19 +1,32 @@
 package trivy
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+	"github.com/project-copacetic/mcp-server/internal/toolchain"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
 )
 
-// isImageLocal checks if an image exists locally in the Docker daemon
-func isImageLocal(ctx context.Context, image string) bool {
+// isImageLocal checks if an image exists locally in the Docker daemon at dockerHost
+func isImageLocal(ctx context.Context, dockerHost, image string) bool {
 	// Handle edge cases
 	if strings.TrimSpace(image) == "" {
 		return false
@@ -21,6 +34,7 @@ func isImageLocal(ctx context.Context, image string) bool {
 
 	// Use docker images command to check if image exists locally
 	cmd := exec.CommandContext(ctx, "docker", "images", "--format", "{{.Repository}}:{{.Tag}}", image)
+	cmd.Env = docker.Env(ctx, dockerHost)
 	output, err := cmd.Output()
 	if err != nil {
 		// If docker command fails, assume remote
@@ -31,49 +45,64 @@ func isImageLocal(ctx context.Context, image string) bool {
 	return strings.TrimSpace(string(output)) != ""
 }
 
-func Run(ctx context.Context, cc *mcp.ServerSession, image string, platform []string) (reportPath string, err error) {
-	reportPath, err = os.MkdirTemp(os.TempDir(), "reports-*")
+func Run(ctx context.Context, cc *mcp.ServerSession, params ScanParams) (reportPath string, err error) {
+	image, platform := params.Image, params.Platform
+
+	reportDir, err := workdir.MkdirTemp("reports-*")
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary report directory: %w", err)
+		return "", copaerrors.NewSystemError("failed to create temporary report directory", err)
 	}
+	reportPath = reportDir
+	defer func() {
+		// Partial reports from a failed scan must not be used for patching
+		if err != nil {
+			os.RemoveAll(reportDir)
+		}
+	}()
+
 	trivyArgs := []string{
 		"image",
-		"--vuln-type", "os",
+		osPackagesFlag(ctx), "os",
 		"--ignore-unfixed",
 		"-f", "json",
 	}
+	trivyArgs = append(trivyArgs, params.DBOptions().args()...)
+	if len(params.Severity) > 0 {
+		trivyArgs = append(trivyArgs, "--severity", strings.ToUpper(strings.Join(params.Severity, ",")))
+	}
 
 	if len(platform) == 0 {
+		if params.ContainerdNamespace != "" {
+			if err := toolchain.Require(ctx, toolchain.TrivyImageSrc); err != nil {
+				return "", err
+			}
+			trivyArgs = append(trivyArgs, "--image-src", "containerd")
+		}
 		trivyArgs = append(trivyArgs, "-o", filepath.Join(reportPath, "report.json"))
 		trivyArgs = append(trivyArgs, image)
 
-		trivyCmd := exec.Command("trivy", trivyArgs...)
-
-		cc.Log(ctx, &mcp.LoggingMessageParams{
-			Data:   fmt.Sprintf("Executing: %s %s", trivyCmd.Path, strings.Join(trivyCmd.Args[1:], " ")),
-			Level:  "info",
-			Logger: "trivy",
-		})
-		var stderrTrivy strings.Builder
-		trivyCmd.Stderr = &stderrTrivy
-
-		err = trivyCmd.Run()
-		if err != nil {
-			exitCode := ""
-			if exitError, ok := err.(*exec.ExitError); ok {
-				exitCode = fmt.Sprintf(" (exit code %d)", exitError.ExitCode())
-			}
-			errorMsg := fmt.Sprintf("trivy command failed%s: %v\n%s", exitCode, err, stderrTrivy.String())
-			return "", fmt.Errorf("%s", errorMsg)
+		env := containerdEnv(docker.Env(ctx, params.DockerHost), params.ContainerdNamespace)
+		if err := runScan(ctx, cc, trivyArgs, env, image, params.Timeout()); err != nil {
+			return "", err
 		}
 
 		return reportPath, nil
 	}
 
-	for _, p := range platform {
-		args := trivyArgs
+	if err := toolchain.Require(ctx, toolchain.TrivyPlatform); err != nil {
+		return "", err
+	}
+	remote := !isImageLocal(ctx, params.DockerHost, image)
+	if remote {
+		if err := toolchain.Require(ctx, toolchain.TrivyImageSrc); err != nil {
+			return "", err
+		}
+	}
 
-		if !isImageLocal(ctx, image) {
+	err = scanPlatforms(platform, PlatformConcurrency(), func(p string) error {
+		args := slices.Clone(trivyArgs)
+
+		if remote {
 			args = append(args, "--image-src", "remote")
 		}
 
@@ -81,63 +110,293 @@ func Run(ctx context.Context, cc *mcp.ServerSession, image string, platform []st
 		args = append(args, "-o", filepath.Join(reportPath, strings.ReplaceAll(p, "/", "-")+".json"))
 		args = append(args, image)
 
-		trivyCmd := exec.Command("trivy", args...)
+		label := fmt.Sprintf("%s (%s)", image, p)
+		return runScan(ctx, cc, args, docker.Env(ctx, params.DockerHost), label, params.Timeout())
+	})
+	if err != nil {
+		return "", err
+	}
 
-		// Log the command being executed using cc.Log to match copa's pattern
-		cc.Log(ctx, &mcp.LoggingMessageParams{
-			Data:   fmt.Sprintf("Executing: %s %s", trivyCmd.Path, strings.Join(trivyCmd.Args[1:], " ")),
-			Level:  "info",
-			Logger: "trivy",
-		})
-		var stderrTrivy strings.Builder
-		trivyCmd.Stderr = &stderrTrivy
+	return reportPath, nil
+}
 
-		err = trivyCmd.Run()
-		if err != nil {
-			exitCode := ""
-			if exitError, ok := err.(*exec.ExitError); ok {
-				exitCode = fmt.Sprintf(" (exit code %d)", exitError.ExitCode())
+var (
+	concurrencyMu sync.Mutex
+	// concurrency is the number of platforms of an image scanned at once
+	concurrency = 1
+)
+
+// SetPlatformConcurrency makes Run scan up to n platforms of a multi-platform image at once,
+// process-wide. The scans still wait for subprocess slots, so the subprocess limit caps them too.
+func SetPlatformConcurrency(n int) {
+	concurrencyMu.Lock()
+	defer concurrencyMu.Unlock()
+	concurrency = max(n, 1)
+}
+
+// PlatformConcurrency returns the number of platforms of an image scanned at once
+func PlatformConcurrency() int {
+	concurrencyMu.Lock()
+	defer concurrencyMu.Unlock()
+	return concurrency
+}
+
+// scanPlatforms calls scan for each platform, up to limit at a time. Every platform is scanned
+// even when another fails; the errors of the failed platforms are joined in platform order.
+func scanPlatforms(platforms []string, limit int, scan func(platform string) error) error {
+	errs := make([]error, len(platforms))
+	slots := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for i, p := range platforms {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := scan(p); err != nil {
+				errs[i] = fmt.Errorf("platform %s: %w", p, err)
 			}
-			errorMsg := fmt.Sprintf("trivy command failed%s: %v\n%s", exitCode, err, stderrTrivy.String())
-			return "", fmt.Errorf("%s", errorMsg)
-		}
+		}()
 	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
 
-	return reportPath, nil
+// containerdEnv sets the containerd namespace in env, the environment of a trivy command, when
+// namespace is set. trivy reads the namespace of containerd images from the environment only.
+func containerdEnv(env []string, namespace string) []string {
+	if namespace == "" {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(env, "CONTAINERD_NAMESPACE="+namespace)
+}
+
+// runScan runs trivy with args and env to scan label, logging the command, and a heartbeat while
+// it runs. trivy is stopped when it runs longer than timeout, unless timeout is 0; waiting for a
+// subprocess slot does not count.
+func runScan(ctx context.Context, cc *mcp.ServerSession, args, env []string, label string, timeout time.Duration) error {
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	cmd := copaexec.Command(ctx, copaexec.Trivy, args...)
+	cmd.Env = env
+	// Log the command being executed to match copa's pattern
+	joblog.Log(ctx, cc, &mcp.LoggingMessageParams{
+		Data:   fmt.Sprintf("Executing: %s %s", cmd.Path, strings.Join(cmd.Args[1:], " ")),
+		Level:  "info",
+		Logger: "trivy",
+	})
+	var stderr strings.Builder
+	output := &lastLine{}
+	cmd.Stderr = io.MultiWriter(&stderr, output)
+
+	start := time.Now()
+	stop := heartbeat(ctx, cc, label, output)
+	err = cmd.Run()
+	stop()
+	if err != nil {
+		return runError(ctx, err, timeout, "trivy scan of "+label, cmd.Args, stderr.String(), time.Since(start))
+	}
+	return nil
+}
+
+// withTimeout returns ctx bounded by timeout, or ctx itself when timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// runError returns the error of a trivy command, run for what, that failed with err: a timeout
+// error when its ctx, from withTimeout, reached timeout, and a command error otherwise
+func runError(ctx context.Context, err error, timeout time.Duration, what string, args []string, stderr string, duration time.Duration) error {
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return copaerrors.NewTimeoutError(fmt.Sprintf("%s did not finish within %s", what, timeout), err,
+			"pass a larger timeoutSeconds, or raise the server's --tool-timeout, for large images or slow registries").
+			WithCommand(args, -1, stderr, duration)
+	}
+	return commandError(ctx, err, args, stderr, duration)
+}
+
+// ScanJSON scans image for the host platform with the settings of 'scan-container' and returns the
+// JSON report, for callers that do not need a report directory or an MCP session
+func ScanJSON(ctx context.Context, dockerHost, image string) ([]byte, error) {
+	args := append([]string{"image", osPackagesFlag(ctx), "os", "--ignore-unfixed", "-f", "json", "--quiet"}, ServerDBOptions().args()...)
+	cmd := copaexec.Command(ctx, copaexec.Trivy, append(args, image)...)
+	cmd.Env = docker.Env(ctx, dockerHost)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(ctx, err, cmd.Args, stderr.String(), time.Since(start))
+	}
+	return output, nil
+}
+
+// ScanAllJSON scans image for the host platform for the vulnerabilities of every package type,
+// including those without a fix, and returns the JSON report. Unlike the reports of 'scan-container'
+// it is not meant for copa, but lists what remains in an image that copa cannot fix.
+func ScanAllJSON(ctx context.Context, dockerHost, image string) ([]byte, error) {
+	args := append([]string{"image", "--scanners", "vuln", "-f", "json", "--quiet"}, ServerDBOptions().args()...)
+	cmd := copaexec.Command(ctx, copaexec.Trivy, append(args, image)...)
+	cmd.Env = docker.Env(ctx, dockerHost)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(ctx, err, cmd.Args, stderr.String(), time.Since(start))
+	}
+	return output, nil
+}
+
+// osPackagesFlag returns the flag limiting the scan to OS packages: --pkg-types on the trivy releases
+// having it, and --vuln-type, which it deprecates, on older ones
+func osPackagesFlag(ctx context.Context) string {
+	if toolchain.Supports(ctx, toolchain.TrivyPkgTypes) {
+		return toolchain.TrivyPkgTypes.Flag
+	}
+	return "--vuln-type"
+}
+
+// commandError converts a failed trivy invocation into a categorized error that includes the exit code and stderr
+func commandError(ctx context.Context, err error, args []string, stderr string, duration time.Duration) error {
+	if copaexec.Canceled(ctx) {
+		return copaerrors.NewSystemError("trivy was stopped because the request was cancelled", ctx.Err()).
+			WithCommand(args, -1, stderr, duration)
+	}
+	exitCode, message := -1, "trivy command failed"
+	if exitError, ok := err.(*exec.ExitError); ok {
+		exitCode = exitError.ExitCode()
+		message = fmt.Sprintf("trivy command failed (exit code %d)", exitCode)
+	}
+	return copaerrors.New(copaerrors.Classify(stderr, copaerrors.CategoryExecution), message, fmt.Errorf("%v\n%s", err, stderr)).
+		WithCommand(args, exitCode, stderr, duration)
 }
 
 // Scan performs vulnerability scanning and returns detailed scan results
 func Scan(ctx context.Context, cc *mcp.ServerSession, params ScanParams) (*ScanResult, error) {
-	reportPath, err := Run(ctx, cc, params.Image, params.Platform)
-	if err != nil {
-		return nil, fmt.Errorf("vulnerability scan failed: %w", err)
+	start := time.Now()
+	var warnings []string
+	var reportPath, attached string
+	if params.ReuseAttachedReport {
+		var err error
+		reportPath, attached, err = importAttachedReport(ctx, cc, params)
+		switch {
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("could not reuse an attached scan report, so the image was scanned: %v", err))
+		case reportPath == "":
+			warnings = append(warnings, "no scan report is attached to the image, so it was scanned")
+		case len(params.Severity) > 0:
+			// The attached report was scanned without the severity filter, so it is applied here
+			if _, err := FilterReport(reportPath, reportPath, severityFilter(params.Severity)); err != nil {
+				os.RemoveAll(reportPath)
+				return nil, copaerrors.NewSystemError("failed to filter the attached scan report by severity", err)
+			}
+		}
+	}
+	if reportPath == "" {
+		platforms, err := selectPlatforms(ctx, runtime.GOOS, params)
+		switch {
+		case len(platforms) > 0 && !toolchain.Supports(ctx, toolchain.TrivyPlatform):
+			warnings = append(warnings, fmt.Sprintf("the installed trivy does not support %s, so the platform chosen by trivy was scanned; upgrade trivy to %s or later to scan the image's Linux platforms",
+				toolchain.TrivyPlatform.Flag, toolchain.TrivyPlatform.Since))
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("could not list the platforms of the image, so the platform chosen by trivy was scanned: %v", err))
+		case len(platforms) > 0:
+			params.Platform = platforms
+			joblog.Log(ctx, cc, &mcp.LoggingMessageParams{
+				Data:   fmt.Sprintf("No platform requested on a %s host, scanning the image's Linux platforms: %s", runtime.GOOS, strings.Join(platforms, ", ")),
+				Level:  "info",
+				Logger: "trivy",
+			})
+		}
+		if reportPath, err = Run(ctx, cc, params); err != nil {
+			return nil, fmt.Errorf("vulnerability scan failed: %w", err)
+		}
 	}
 
 	// Count vulnerabilities in the report(s)
 	vulnCount, err := countVulnerabilitiesInReport(reportPath)
 	if err != nil {
-		cc.Log(ctx, &mcp.LoggingMessageParams{
-			Data:   fmt.Sprintf("Warning: Could not count vulnerabilities in report: %v", err),
+		warning := fmt.Sprintf("could not count vulnerabilities in report: %v", err)
+		warnings = append(warnings, warning)
+		joblog.Log(ctx, cc, &mcp.LoggingMessageParams{
+			Data:   "Warning: " + warning,
 			Level:  "warn",
 			Logger: "trivy",
 		})
 		vulnCount = 0
 	}
 
+	if params.GitLabReport != "" {
+		dst, err := workdir.OutputPath(params.GitLabReport)
+		if err != nil {
+			os.RemoveAll(reportPath)
+			return nil, err
+		}
+		params.GitLabReport = dst
+		if err := WriteGitLabReport(reportPath, params.Image, dst, start, time.Now()); err != nil {
+			os.RemoveAll(reportPath)
+			return nil, copaerrors.NewSystemError("failed to write GitLab container scanning report", err)
+		}
+	}
+
 	platforms := params.Platform
-	if len(platforms) == 0 {
-		platforms = []string{"host platform"}
+	switch {
+	case attached != "":
+		platforms = []string{}
+	case len(platforms) == 0:
+		platforms = []string{HostPlatform}
 	}
 
 	return &ScanResult{
-		Image:         params.Image,
-		ReportPath:    reportPath,
-		VulnCount:     vulnCount,
-		Platforms:     platforms,
-		ScanCompleted: true,
+		Image:          params.Image,
+		ReportPath:     reportPath,
+		VulnCount:      vulnCount,
+		Platforms:      platforms,
+		ScanCompleted:  true,
+		Duration:       time.Since(start).Round(time.Millisecond).String(),
+		Warnings:       warnings,
+		GitLabReport:   params.GitLabReport,
+		AttachedReport: attached,
 	}, nil
 }
 
+// severityFilter returns a FilterReport function keeping the vulnerabilities of severities
+func severityFilter(severities []string) func(id, severity string) bool {
+	return func(_, severity string) bool {
+		if severity == "" {
+			severity = "UNKNOWN"
+		}
+		return slices.ContainsFunc(severities, func(s string) bool { return strings.EqualFold(s, severity) })
+	}
+}
+
 // countVulnerabilitiesInReport counts total vulnerabilities across all report files
 func countVulnerabilitiesInReport(reportPath string) (int, error) {
 	// Read directory to find all JSON report files
@@ -163,25 +422,16 @@ func countVulnerabilitiesInReport(reportPath string) (int, error) {
 
 // countVulnerabilitiesInFile counts vulnerabilities in a single JSON report file
 func countVulnerabilitiesInFile(filePath string) (int, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read report file: %w", err)
-	}
-
-	var report struct {
-		Results []struct {
-			Vulnerabilities []interface{} `json:"Vulnerabilities"`
-		} `json:"Results"`
-	}
+	count := 0
+	err := eachFileVulnerability(filePath, func(string, vulnerability) { count++ })
+	return count, err
+}
 
-	if err := json.Unmarshal(data, &report); err != nil {
+// CountVulnerabilities counts the vulnerabilities in a Trivy JSON report
+func CountVulnerabilities(data []byte) (int, error) {
+	count := 0
+	if err := eachVulnerability(bytes.NewReader(data), func(string, vulnerability) { count++ }); err != nil {
 		return 0, fmt.Errorf("failed to parse JSON report: %w", err)
 	}
-
-	totalVulns := 0
-	for _, result := range report.Results {
-		totalVulns += len(result.Vulnerabilities)
-	}
-
-	return totalVulns, nil
+	return count, nil
 }
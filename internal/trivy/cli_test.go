@@ -4,9 +4,13 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"sync"
 	"testing"
+	"time"
 
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -32,7 +36,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_LocalImage() {
 	}
 
 	// Test with local image
-	isLocal := isImageLocal(ctx, "alpine:latest")
+	isLocal := isImageLocal(ctx, "", "alpine:latest")
 	suite.True(isLocal, "alpine:latest should be detected as local after pulling")
 }
 
@@ -41,7 +45,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_RemoteImage() {
 
 	// Test with a likely non-existent local image (using a specific digest)
 	nonExistentImage := "alpine@sha256:nonexistent1234567890abcdef1234567890abcdef1234567890abcdef1234567890"
-	isLocal := isImageLocal(ctx, nonExistentImage)
+	isLocal := isImageLocal(ctx, "", nonExistentImage)
 	suite.False(isLocal, "Non-existent image should be detected as remote")
 }
 
@@ -50,7 +54,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_DockerCommandFails() {
 
 	// Test with invalid docker binary path by creating a context where docker would fail
 	// We'll simulate this by using an image name that would cause docker to fail
-	isLocal := isImageLocal(ctx, "")
+	isLocal := isImageLocal(ctx, "", "")
 	suite.False(isLocal, "Empty image name should return false")
 }
 
@@ -59,7 +63,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_NonExistentImage() {
 
 	// Test with an image that definitely doesn't exist locally
 	imageName := "nonexistent/image:definitely-not-local-" + suite.T().Name()
-	isLocal := isImageLocal(ctx, imageName)
+	isLocal := isImageLocal(ctx, "", imageName)
 	suite.False(isLocal, "Non-existent image should be detected as remote")
 }
 
@@ -69,7 +73,7 @@ func (suite *TrivyTestSuite) TestTrivyArgsConstruction() {
 
 	// Test case 1: Remote image (simulated by using non-existent image)
 	remoteImage := "definitely/remote:image"
-	isLocal := isImageLocal(ctx, remoteImage)
+	isLocal := isImageLocal(ctx, "", remoteImage)
 	suite.False(isLocal, "Test image should be detected as remote")
 
 	// Test case 2: We can't easily test a true local image without docker setup
@@ -83,7 +87,7 @@ func BenchmarkIsImageLocal_RemoteImage(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		isImageLocal(ctx, image)
+		isImageLocal(ctx, "", image)
 	}
 }
 
@@ -92,7 +96,7 @@ func BenchmarkIsImageLocal_EmptyImage(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		isImageLocal(ctx, "")
+		isImageLocal(ctx, "", "")
 	}
 }
 
@@ -102,7 +106,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_ContextCancellation() {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	isLocal := isImageLocal(ctx, "alpine:latest")
+	isLocal := isImageLocal(ctx, "", "alpine:latest")
 	// Should handle context cancellation gracefully and return false
 	suite.False(isLocal, "Cancelled context should return false")
 }
@@ -128,7 +132,7 @@ func (suite *TrivyTestSuite) TestTrivyArgumentConstruction_Integration() {
 	image := "alpine:3.17"
 
 	// The key test: verify that --image-src is only added for remote images
-	isLocal := isImageLocal(ctx, image)
+	isLocal := isImageLocal(ctx, "", image)
 
 	// Build expected arguments based on whether image is local
 	var expectedToContainImageSrc bool
@@ -200,7 +204,7 @@ func (suite *TrivyTestSuite) TestIsImageLocal_EdgeCases() {
 
 	for _, tc := range testCases {
 		suite.Run(tc.name, func() {
-			result := isImageLocal(ctx, tc.image)
+			result := isImageLocal(ctx, "", tc.image)
 			suite.Equal(tc.expected, result, "Image: %s", tc.image)
 		})
 	}
@@ -246,12 +250,61 @@ func TestIsImageLocal_TableDriven(t *testing.T) {
 				}
 			}
 
-			result := isImageLocal(ctx, tt.image)
+			result := isImageLocal(ctx, "", tt.image)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+func TestContainerdEnv(t *testing.T) {
+	assert.Nil(t, containerdEnv(nil, ""))
+	assert.Equal(t, []string{"DOCKER_HOST=tcp://h:2376"}, containerdEnv([]string{"DOCKER_HOST=tcp://h:2376"}, ""))
+	assert.Equal(t, []string{"DOCKER_HOST=tcp://h:2376", "CONTAINERD_NAMESPACE=k8s.io"}, containerdEnv([]string{"DOCKER_HOST=tcp://h:2376"}, "k8s.io"))
+
+	// The server's environment is kept when no Docker host is set
+	t.Setenv("COPA_MCP_TEST_ENV", "kept")
+	env := containerdEnv(nil, "k8s.io")
+	assert.Contains(t, env, "COPA_MCP_TEST_ENV=kept")
+	assert.Equal(t, "CONTAINERD_NAMESPACE=k8s.io", env[len(env)-1])
+}
+
+func TestScanPlatforms(t *testing.T) {
+	var mu sync.Mutex
+	var running, peak int
+	var scanned []string
+	err := scanPlatforms([]string{"linux/amd64", "linux/arm64", "linux/arm/v7", "linux/s390x"}, 2, func(p string) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		scanned = append(scanned, p)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if p == "linux/arm64" || p == "linux/s390x" {
+			return copaerrors.NewNetworkError("failed to pull "+p, nil)
+		}
+		return nil
+	})
+
+	assert.Equal(t, 2, peak, "at most limit platforms are scanned at once")
+	assert.Len(t, scanned, 4, "a failed platform does not stop the others")
+	require.Error(t, err)
+	assert.Equal(t, "platform linux/arm64: failed to pull linux/arm64\nplatform linux/s390x: failed to pull linux/s390x", err.Error())
+	assert.Equal(t, copaerrors.CategoryNetwork, copaerrors.CategoryOf(err))
+
+	assert.NoError(t, scanPlatforms([]string{"linux/amd64"}, 0, func(string) error { return nil }))
+}
+
+func TestSetPlatformConcurrency(t *testing.T) {
+	t.Cleanup(func() { SetPlatformConcurrency(1) })
+	SetPlatformConcurrency(3)
+	assert.Equal(t, 3, PlatformConcurrency())
+	SetPlatformConcurrency(0)
+	assert.Equal(t, 1, PlatformConcurrency())
+}
+
 // Run the test suite
 func TestTrivyTestSuite(t *testing.T) {
 	suite.Run(t, new(TrivyTestSuite))
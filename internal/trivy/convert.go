@@ -0,0 +1,113 @@
+package trivy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Vulnerability is an OS package vulnerability found by another scanner, written to a Trivy
+// report by WriteReport so that copa can patch it
+type Vulnerability struct {
+	ID               string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string
+	Severity         string
+	Description      string
+	PrimaryURL       string
+}
+
+// reportFile is the subset of Trivy's JSON report format read by copa
+type reportFile struct {
+	SchemaVersion int        `json:"SchemaVersion"`
+	ArtifactName  string     `json:"ArtifactName"`
+	ArtifactType  string     `json:"ArtifactType"`
+	Metadata      reportMeta `json:"Metadata"`
+	Results       []result   `json:"Results"`
+}
+
+type reportMeta struct {
+	OS reportOS `json:"OS"`
+}
+
+type reportOS struct {
+	Family string `json:"Family"`
+	Name   string `json:"Name"`
+}
+
+type result struct {
+	Target          string          `json:"Target"`
+	Class           string          `json:"Class"`
+	Type            string          `json:"Type"`
+	Vulnerabilities []vulnerability `json:"Vulnerabilities"`
+}
+
+type vulnerability struct {
+	VulnerabilityID  string `json:"VulnerabilityID"`
+	PkgName          string `json:"PkgName"`
+	InstalledVersion string `json:"InstalledVersion"`
+	FixedVersion     string `json:"FixedVersion"`
+	Severity         string `json:"Severity"`
+	Title            string `json:"Title,omitempty"`
+	Description      string `json:"Description,omitempty"`
+	PrimaryURL       string `json:"PrimaryURL,omitempty"`
+}
+
+// osFamilies maps os-release IDs to the OS family names used in Trivy reports, where they differ
+var osFamilies = map[string]string{
+	"rhel":          "redhat",
+	"ol":            "oracle",
+	"amzn":          "amazon",
+	"mariner":       "cbl-mariner",
+	"almalinux":     "alma",
+	"opensuse-leap": "opensuse.leap",
+}
+
+// WriteReport writes vulns to dir/report.json as a Trivy OS package report for the distribution
+// osID (an os-release ID) and version. Only vulnerabilities with a fix are included, as in the
+// reports of 'scan-container'. It returns the number of vulnerabilities written.
+func WriteReport(dir, image, osID, osVersion string, vulns []Vulnerability) (int, error) {
+	family := osID
+	if mapped, ok := osFamilies[osID]; ok {
+		family = mapped
+	}
+
+	res := result{
+		Target:          fmt.Sprintf("%s (%s %s)", image, family, osVersion),
+		Class:           "os-pkgs",
+		Type:            family,
+		Vulnerabilities: []vulnerability{},
+	}
+	for _, v := range vulns {
+		if v.FixedVersion == "" {
+			continue
+		}
+		res.Vulnerabilities = append(res.Vulnerabilities, vulnerability{
+			VulnerabilityID:  v.ID,
+			PkgName:          v.Package,
+			InstalledVersion: v.InstalledVersion,
+			FixedVersion:     v.FixedVersion,
+			Severity:         strings.ToUpper(v.Severity),
+			Description:      v.Description,
+			PrimaryURL:       v.PrimaryURL,
+		})
+	}
+
+	data, err := json.MarshalIndent(reportFile{
+		SchemaVersion: 2,
+		ArtifactName:  image,
+		ArtifactType:  "container_image",
+		Metadata:      reportMeta{OS: reportOS{Family: family, Name: osVersion}},
+		Results:       []result{res},
+	}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), data, 0o644); err != nil {
+		return 0, fmt.Errorf("failed to write report: %w", err)
+	}
+	return len(res.Vulnerabilities), nil
+}
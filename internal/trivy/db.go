@@ -0,0 +1,60 @@
+package trivy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DBOptions control how trivy gets its vulnerability database, e.g. to scan in an air-gapped
+// environment with a pre-populated cache or a mirror of the database
+type DBOptions struct {
+	SkipDBUpdate bool   // scan with the cached database without downloading it
+	OfflineScan  bool   // make no other network requests, e.g. to Maven Central for Java packages
+	DBRepository string // OCI repository the database is downloaded from, e.g. a registry mirror
+}
+
+var (
+	dbMu sync.Mutex
+	// db are the options of the scans that do not override them
+	db DBOptions
+)
+
+// SetDBOptions sets the database options of every scan, process-wide. A scan-container call can
+// override them.
+func SetDBOptions(o DBOptions) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	db = o
+}
+
+// ServerDBOptions returns the database options of the scans that do not override them
+func ServerDBOptions() DBOptions {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	return db
+}
+
+// args returns the trivy flags applying the options
+func (o DBOptions) args() []string {
+	var args []string
+	if o.SkipDBUpdate {
+		args = append(args, "--skip-db-update")
+	}
+	if o.OfflineScan {
+		args = append(args, "--offline-scan")
+	}
+	if o.DBRepository != "" {
+		args = append(args, "--db-repository", o.DBRepository)
+	}
+	return args
+}
+
+// ValidateDBRepository checks that repository, when set, can be passed to trivy's --db-repository
+func ValidateDBRepository(repository string) error {
+	if strings.HasPrefix(repository, "-") || strings.ContainsFunc(repository, unicode.IsSpace) {
+		return fmt.Errorf("invalid database repository: %q", repository)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package trivy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanParams_DBOptions(t *testing.T) {
+	SetDBOptions(DBOptions{SkipDBUpdate: true, DBRepository: "mirror.example.com/trivy-db:2"})
+	t.Cleanup(func() { SetDBOptions(DBOptions{}) })
+
+	// The server's options apply to calls that do not override them
+	o := ScanParams{Image: "nginx"}.DBOptions()
+	assert.Equal(t, []string{"--skip-db-update", "--db-repository", "mirror.example.com/trivy-db:2"}, o.args())
+
+	skip, offline := false, true
+	o = ScanParams{Image: "nginx", SkipDBUpdate: &skip, OfflineScan: &offline, DBRepository: "registry.corp.internal/trivy-db:2"}.DBOptions()
+	assert.Equal(t, []string{"--offline-scan", "--db-repository", "registry.corp.internal/trivy-db:2"}, o.args())
+
+	assert.Empty(t, DBOptions{}.args())
+}
+
+func TestValidateDBRepository(t *testing.T) {
+	assert.NoError(t, ValidateDBRepository(""))
+	assert.NoError(t, ValidateDBRepository("ghcr.io/aquasecurity/trivy-db:2"))
+	assert.Error(t, ValidateDBRepository("--insecure"))
+	assert.Error(t, ValidateDBRepository("mirror.example.com/trivy-db:2 --insecure"))
+}
+
+func TestSBOM_ServerDBOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as trivy")
+	}
+	// The stub trivy logs its arguments and prints them as the SBOM
+	dir := t.TempDir()
+	stub, log := filepath.Join(dir, "trivy"), filepath.Join(dir, "args")
+	require.NoError(t, os.WriteFile(stub, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\necho \"$@\"\n"), 0o755))
+	copaexec.SetPath(copaexec.Trivy, stub)
+	SetDBOptions(DBOptions{SkipDBUpdate: true, DBRepository: "mirror.example.com/trivy-db:2"})
+	t.Cleanup(func() {
+		copaexec.SetPath(copaexec.Trivy, "")
+		SetDBOptions(DBOptions{})
+	})
+
+	bom, err := SBOM(context.Background(), "", "nginx:1.25")
+	require.NoError(t, err)
+	assert.Contains(t, string(bom), "--skip-db-update --db-repository mirror.example.com/trivy-db:2 nginx:1.25")
+
+	require.NoError(t, GenerateSBOM(context.Background(), "", "nginx:1.25", SBOMFormatSPDX, filepath.Join(dir, "sbom.json"), 0))
+	args, err := os.ReadFile(log)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(args)), "\n")
+	assert.Contains(t, lines[len(lines)-1], "--format spdx-json")
+	assert.True(t, strings.HasSuffix(lines[len(lines)-1], "--skip-db-update --db-repository mirror.example.com/trivy-db:2 nginx:1.25"))
+}
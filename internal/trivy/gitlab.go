@@ -0,0 +1,238 @@
+package trivy
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitlabSchemaVersion is the version of GitLab's container scanning report schema that WriteGitLabReport produces
+const gitlabSchemaVersion = "15.0.7"
+
+// gitlabTimeFormat is the timestamp format required by the GitLab security report schemas
+const gitlabTimeFormat = "2006-01-02T15:04:05"
+
+// gitlabReport is the subset of GitLab's container scanning report produced from Trivy reports
+type gitlabReport struct {
+	Version         string                `json:"version"`
+	Scan            gitlabScan            `json:"scan"`
+	Vulnerabilities []gitlabVulnerability `json:"vulnerabilities"`
+	Remediations    []any                 `json:"remediations"`
+}
+
+type gitlabScan struct {
+	Analyzer  gitlabScanner `json:"analyzer"`
+	Scanner   gitlabScanner `json:"scanner"`
+	Type      string        `json:"type"`
+	StartTime string        `json:"start_time"`
+	EndTime   string        `json:"end_time"`
+	Status    string        `json:"status"`
+}
+
+type gitlabScanner struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Vendor  gitlabVendor `json:"vendor"`
+	Version string       `json:"version"`
+}
+
+type gitlabVendor struct {
+	Name string `json:"name"`
+}
+
+type gitlabVulnerability struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution,omitempty"`
+	Location    gitlabLocation     `json:"location"`
+	Identifiers []gitlabIdentifier `json:"identifiers"`
+	Links       []gitlabLink       `json:"links,omitempty"`
+}
+
+type gitlabLocation struct {
+	Dependency      gitlabDependency `json:"dependency"`
+	OperatingSystem string           `json:"operating_system"`
+	Image           string           `json:"image"`
+}
+
+type gitlabDependency struct {
+	Package gitlabPackage `json:"package"`
+	Version string        `json:"version"`
+}
+
+type gitlabPackage struct {
+	Name string `json:"name"`
+}
+
+type gitlabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+type gitlabLink struct {
+	URL string `json:"url"`
+}
+
+// trivyReport is the subset of a Trivy JSON report needed for the GitLab conversion
+type trivyReport struct {
+	Trivy struct {
+		Version string `json:"Version"`
+	} `json:"Trivy"`
+	Metadata struct {
+		OS struct {
+			Family string `json:"Family"`
+			Name   string `json:"Name"`
+		} `json:"OS"`
+	} `json:"Metadata"`
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string   `json:"VulnerabilityID"`
+			PkgName          string   `json:"PkgName"`
+			InstalledVersion string   `json:"InstalledVersion"`
+			FixedVersion     string   `json:"FixedVersion"`
+			Severity         string   `json:"Severity"`
+			Title            string   `json:"Title"`
+			Description      string   `json:"Description"`
+			PrimaryURL       string   `json:"PrimaryURL"`
+			References       []string `json:"References"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// WriteGitLabReport converts the Trivy JSON reports in reportPath into a single GitLab container
+// scanning report at dst, so that GitLab pipelines can show the findings in the security dashboard.
+// A vulnerability found in the same package on several platforms is only reported once.
+func WriteGitLabReport(reportPath, image, dst string, start, end time.Time) error {
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read report directory: %w", err)
+	}
+
+	report := gitlabReport{
+		Version: gitlabSchemaVersion,
+		Scan: gitlabScan{
+			Analyzer:  gitlabScanner{ID: "copacetic-mcp", Name: "Copacetic MCP", Vendor: gitlabVendor{Name: "Project Copacetic"}, Version: "unknown"},
+			Scanner:   gitlabScanner{ID: "trivy", Name: "Trivy", Vendor: gitlabVendor{Name: "Aqua Security"}, Version: "unknown"},
+			Type:      "container_scanning",
+			StartTime: start.UTC().Format(gitlabTimeFormat),
+			EndTime:   end.UTC().Format(gitlabTimeFormat),
+			Status:    "success",
+		},
+		Vulnerabilities: []gitlabVulnerability{},
+		Remediations:    []any{},
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		filePath := filepath.Join(reportPath, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read report file: %w", err)
+		}
+
+		var tr trivyReport
+		if err := json.Unmarshal(data, &tr); err != nil {
+			return fmt.Errorf("failed to parse JSON report %s: %w", filePath, err)
+		}
+		if tr.Trivy.Version != "" {
+			report.Scan.Scanner.Version = tr.Trivy.Version
+		}
+		operatingSystem := strings.TrimSpace(tr.Metadata.OS.Family + " " + tr.Metadata.OS.Name)
+
+		for _, result := range tr.Results {
+			for _, vuln := range result.Vulnerabilities {
+				key := strings.Join([]string{vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion, operatingSystem}, "|")
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				v := gitlabVulnerability{
+					ID:          gitlabID(key),
+					Name:        vuln.VulnerabilityID,
+					Description: vuln.Description,
+					Severity:    gitlabSeverity(vuln.Severity),
+					Location: gitlabLocation{
+						Dependency:      gitlabDependency{Package: gitlabPackage{Name: vuln.PkgName}, Version: vuln.InstalledVersion},
+						OperatingSystem: operatingSystem,
+						Image:           image,
+					},
+					Identifiers: []gitlabIdentifier{{
+						Type:  identifierType(vuln.VulnerabilityID),
+						Name:  vuln.VulnerabilityID,
+						Value: vuln.VulnerabilityID,
+						URL:   vuln.PrimaryURL,
+					}},
+				}
+				if vuln.Title != "" {
+					v.Name = fmt.Sprintf("%s: %s", vuln.VulnerabilityID, vuln.Title)
+				}
+				if vuln.FixedVersion != "" {
+					v.Solution = fmt.Sprintf("Upgrade %s to %s", vuln.PkgName, vuln.FixedVersion)
+				}
+				if vuln.PrimaryURL != "" {
+					v.Links = append(v.Links, gitlabLink{URL: vuln.PrimaryURL})
+				}
+				for _, ref := range vuln.References {
+					if ref != vuln.PrimaryURL {
+						v.Links = append(v.Links, gitlabLink{URL: ref})
+					}
+				}
+				report.Vulnerabilities = append(report.Vulnerabilities, v)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode GitLab report: %w", err)
+	}
+	if err := os.WriteFile(dst, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write GitLab report: %w", err)
+	}
+	return nil
+}
+
+// gitlabID derives a stable UUID-formatted identifier from key, so that GitLab tracks the same
+// finding across pipelines
+func gitlabID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// gitlabSeverity maps a Trivy severity to the severities allowed by the GitLab schema
+func gitlabSeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL":
+		return "Critical"
+	case "HIGH":
+		return "High"
+	case "MEDIUM":
+		return "Medium"
+	case "LOW":
+		return "Low"
+	default:
+		return "Unknown"
+	}
+}
+
+// identifierType returns the GitLab identifier type for a vulnerability ID, e.g. "cve" or "ghsa"
+func identifierType(id string) string {
+	prefix, _, found := strings.Cut(id, "-")
+	if !found {
+		return "trivy"
+	}
+	return strings.ToLower(prefix)
+}
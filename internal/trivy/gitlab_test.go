@@ -0,0 +1,89 @@
+package trivy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGitLabReport(t *testing.T) {
+	dir := t.TempDir()
+	amd64 := `{"Trivy":{"Version":"0.58.1"},"Metadata":{"OS":{"Family":"alpine","Name":"3.18.0"}},"Results":[{"Vulnerabilities":[` +
+		`{"VulnerabilityID":"CVE-2023-0001","PkgName":"openssl","InstalledVersion":"3.1.0-r0","FixedVersion":"3.1.1-r0","Severity":"CRITICAL",` +
+		`"Title":"buffer overflow","PrimaryURL":"https://avd.aquasec.com/nvd/cve-2023-0001","References":["https://avd.aquasec.com/nvd/cve-2023-0001","https://example.com/advisory"]},` +
+		`{"VulnerabilityID":"GHSA-xxxx-yyyy-zzzz","PkgName":"zlib","InstalledVersion":"1.2.13","Severity":"negligible"}]}]}`
+	arm64 := `{"Metadata":{"OS":{"Family":"alpine","Name":"3.18.0"}},"Results":[{"Vulnerabilities":[` +
+		`{"VulnerabilityID":"CVE-2023-0001","PkgName":"openssl","InstalledVersion":"3.1.0-r0","FixedVersion":"3.1.1-r0","Severity":"CRITICAL"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-amd64.json"), []byte(amd64), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-arm64.json"), []byte(arm64), 0o600))
+
+	dst := filepath.Join(t.TempDir(), "gl-container-scanning-report.json")
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, WriteGitLabReport(dir, "alpine:3.18", dst, start, start.Add(time.Minute)))
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	var report gitlabReport
+	require.NoError(t, json.Unmarshal(data, &report))
+
+	assert.Equal(t, gitlabSchemaVersion, report.Version)
+	assert.Equal(t, "container_scanning", report.Scan.Type)
+	assert.Equal(t, "2024-05-01T10:00:00", report.Scan.StartTime)
+	assert.Equal(t, "2024-05-01T10:01:00", report.Scan.EndTime)
+	assert.Equal(t, "0.58.1", report.Scan.Scanner.Version)
+
+	// The vulnerability found on both platforms is reported once
+	require.Len(t, report.Vulnerabilities, 2)
+
+	cve := report.Vulnerabilities[0]
+	assert.Equal(t, "CVE-2023-0001: buffer overflow", cve.Name)
+	assert.Equal(t, "Critical", cve.Severity)
+	assert.Equal(t, "Upgrade openssl to 3.1.1-r0", cve.Solution)
+	assert.Equal(t, gitlabLocation{
+		Dependency:      gitlabDependency{Package: gitlabPackage{Name: "openssl"}, Version: "3.1.0-r0"},
+		OperatingSystem: "alpine 3.18.0",
+		Image:           "alpine:3.18",
+	}, cve.Location)
+	assert.Equal(t, []gitlabIdentifier{{Type: "cve", Name: "CVE-2023-0001", Value: "CVE-2023-0001", URL: "https://avd.aquasec.com/nvd/cve-2023-0001"}}, cve.Identifiers)
+	assert.Equal(t, []gitlabLink{{URL: "https://avd.aquasec.com/nvd/cve-2023-0001"}, {URL: "https://example.com/advisory"}}, cve.Links)
+
+	ghsa := report.Vulnerabilities[1]
+	assert.Equal(t, "GHSA-xxxx-yyyy-zzzz", ghsa.Name)
+	assert.Equal(t, "Unknown", ghsa.Severity)
+	assert.Equal(t, "ghsa", ghsa.Identifiers[0].Type)
+	assert.Empty(t, ghsa.Solution)
+	assert.NotEqual(t, cve.ID, ghsa.ID)
+}
+
+func TestWriteGitLabReport_NoVulnerabilities(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(`{"Results":[]}`), 0o600))
+
+	dst := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, WriteGitLabReport(dir, "alpine:3.18", dst, time.Now(), time.Now()))
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	// GitLab rejects reports without the vulnerabilities array
+	assert.Contains(t, string(data), `"vulnerabilities": []`)
+	assert.Contains(t, string(data), `"version": "unknown"`)
+}
+
+func TestWriteGitLabReport_InvalidReport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte("not json"), 0o600))
+
+	err := WriteGitLabReport(dir, "alpine:3.18", filepath.Join(t.TempDir(), "out.json"), time.Now(), time.Now())
+	assert.Error(t, err)
+}
+
+func TestGitLabID(t *testing.T) {
+	id := gitlabID("CVE-2023-0001|openssl|3.1.0-r0|alpine 3.18.0")
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, id)
+	assert.Equal(t, id, gitlabID("CVE-2023-0001|openssl|3.1.0-r0|alpine 3.18.0"))
+}
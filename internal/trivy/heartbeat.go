@@ -0,0 +1,87 @@
+package trivy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+)
+
+// heartbeatInterval is how often a running scan reports that it is still running, so that MCP hosts
+// do not time out while trivy downloads its database or analyzes a large image without output
+var heartbeatInterval = 30 * time.Second
+
+// ansiEscape matches the terminal escape sequences of trivy's progress bars
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// lastLine records the last non-empty line written to it, e.g. trivy's latest log line or progress bar
+type lastLine struct {
+	mu      sync.Mutex
+	line    string
+	partial string
+}
+
+func (l *lastLine) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Progress bars redraw their line with carriage returns
+	text := strings.ReplaceAll(l.partial+string(p), "\r", "\n")
+	lines := strings.Split(text, "\n")
+	l.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, "")); line != "" {
+			l.line = line
+		}
+	}
+	return len(p), nil
+}
+
+func (l *lastLine) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if partial := strings.TrimSpace(ansiEscape.ReplaceAllString(l.partial, "")); partial != "" {
+		return partial
+	}
+	return l.line
+}
+
+// heartbeat reports every heartbeatInterval that the scan described by label is still running,
+// with the elapsed time and the last line trivy printed, as a log message and as a progress
+// notification when the call has a progress token. It stops when stop is called.
+func heartbeat(ctx context.Context, cc *mcp.ServerSession, label string, output *lastLine) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		start := time.Now()
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			elapsed := time.Since(start).Round(time.Second)
+			msg := fmt.Sprintf("still scanning %s, %s elapsed", label, elapsed)
+			if line := output.String(); line != "" {
+				msg += ": " + line
+			}
+			joblog.Log(ctx, cc, &mcp.LoggingMessageParams{
+				Data:   msg,
+				Level:  "info",
+				Logger: "trivy",
+			})
+			joblog.Progress(ctx, cc, elapsed.Seconds(), 0, msg)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,49 @@
+package trivy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/joblog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastLine(t *testing.T) {
+	var l lastLine
+	l.Write([]byte("INFO Need to update DB\nINFO Downloading DB...\n"))
+	assert.Equal(t, "INFO Downloading DB...", l.String())
+
+	// Progress bars redraw their line, possibly across writes
+	l.Write([]byte("\x1b[2K 1.2 MiB / 50 MiB\r\x1b[2K 12.5 MiB"))
+	l.Write([]byte(" / 50 MiB"))
+	assert.Equal(t, "12.5 MiB / 50 MiB", l.String())
+
+	l.Write([]byte("\nINFO Detected OS: alpine\n\n"))
+	assert.Equal(t, "INFO Detected OS: alpine", l.String())
+}
+
+func TestHeartbeat(t *testing.T) {
+	interval := heartbeatInterval
+	heartbeatInterval = 10 * time.Millisecond
+	t.Cleanup(func() { heartbeatInterval = interval })
+
+	ctx, job := joblog.Start(context.Background(), "scan-container")
+	output := &lastLine{}
+	output.Write([]byte("INFO Downloading DB...\n"))
+
+	stop := heartbeat(ctx, nil, "alpine:3.19 (linux/arm64)", output)
+	require.Eventually(t, func() bool { return len(job.Events(0)) >= 2 }, time.Second, 5*time.Millisecond)
+	stop()
+
+	events := job.Events(0)
+	assert.True(t, strings.HasPrefix(events[0].Message, "still scanning alpine:3.19 (linux/arm64), "), events[0].Message)
+	assert.True(t, strings.HasSuffix(events[0].Message, " elapsed: INFO Downloading DB..."), events[0].Message)
+
+	// No heartbeat follows stop
+	count := len(job.Events(0))
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, job.Events(0), count)
+}
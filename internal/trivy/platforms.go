@@ -0,0 +1,31 @@
+package trivy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/project-copacetic/mcp-server/internal/registry"
+)
+
+// selectPlatforms returns the Linux platforms of a remote multi-platform image, to scan when no
+// platform was requested and the server runs on a goos host other than Linux. Trivy would
+// otherwise pick the host's platform, which on macOS or Windows is not one of the image's, or only
+// the one matching the host's architecture, leaving the report-based patch of the other platforms
+// without a report. It returns nil to let trivy choose, as for local and single-platform images.
+func selectPlatforms(ctx context.Context, goos string, params ScanParams) ([]string, error) {
+	if goos == "linux" || len(params.Platform) > 0 || params.ContainerdNamespace != "" || isImageLocal(ctx, params.DockerHost, params.Image) {
+		return nil, nil
+	}
+
+	platforms, err := registry.Platforms(ctx, params.Image)
+	if err != nil {
+		return nil, err
+	}
+	var linux []string
+	for _, platform := range platforms {
+		if strings.HasPrefix(platform, "linux/") {
+			linux = append(linux, platform)
+		}
+	}
+	return linux, nil
+}
@@ -0,0 +1,52 @@
+package trivy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const indexManifest = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:1", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:2", "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+    {"digest": "sha256:3", "platform": {"os": "windows", "architecture": "amd64"}},
+    {"digest": "sha256:4", "platform": {"os": "unknown", "architecture": "unknown"}}
+  ]
+}`
+
+func TestSelectPlatforms(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+		w.Write([]byte(indexManifest))
+	}))
+	defer srv.Close()
+	image := strings.TrimPrefix(srv.URL, "http://") + "/team/app:1.0"
+
+	platforms, err := selectPlatforms(context.Background(), "darwin", ScanParams{Image: image})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, platforms)
+
+	// Linux hosts, and requested platforms, are left alone
+	platforms, err = selectPlatforms(context.Background(), "linux", ScanParams{Image: image})
+	require.NoError(t, err)
+	assert.Nil(t, platforms)
+	platforms, err = selectPlatforms(context.Background(), "darwin", ScanParams{Image: image, Platform: []string{"linux/amd64"}})
+	require.NoError(t, err)
+	assert.Nil(t, platforms)
+}
+
+func TestSelectPlatforms_RegistryError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := selectPlatforms(context.Background(), "windows", ScanParams{Image: strings.TrimPrefix(srv.URL, "http://") + "/team/app:1.0"})
+	assert.Error(t, err)
+}
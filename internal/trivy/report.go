@@ -0,0 +1,210 @@
+package trivy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Severities returns the severity of each vulnerability in the report directory, keyed by
+// vulnerability ID. Vulnerabilities reported for several platforms are only listed once.
+func Severities(reportPath string) (map[string]string, error) {
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report directory: %w", err)
+	}
+
+	severities := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		err := eachFileVulnerability(filepath.Join(reportPath, entry.Name()), func(_ string, vuln vulnerability) {
+			severities[vuln.VulnerabilityID] = strings.ToUpper(vuln.Severity)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return severities, nil
+}
+
+// SeverityLevels lists the severities trivy reports vulnerabilities with, from highest to lowest
+var SeverityLevels = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// Finding is a vulnerability of a package in a Trivy report
+type Finding struct {
+	ID               string
+	Package          string
+	InstalledVersion string
+	Severity         string // Upper-cased
+	FixedVersion     string // Empty when the vulnerability has no fix
+	Title            string
+	Library          bool // The package is a language library rather than an OS package
+}
+
+// Findings returns the vulnerabilities of a Trivy JSON report
+func Findings(data []byte) ([]Finding, error) {
+	var findings []Finding
+	if err := eachVulnerability(bytes.NewReader(data), appendFinding(&findings)); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// appendFinding returns a function appending each vulnerability passed to it to findings
+func appendFinding(findings *[]Finding) func(class string, vuln vulnerability) {
+	return func(class string, vuln vulnerability) {
+		*findings = append(*findings, Finding{
+			ID:               vuln.VulnerabilityID,
+			Package:          vuln.PkgName,
+			InstalledVersion: vuln.InstalledVersion,
+			Severity:         strings.ToUpper(vuln.Severity),
+			FixedVersion:     vuln.FixedVersion,
+			Title:            vuln.Title,
+			Library:          class == "lang-pkgs",
+		})
+	}
+}
+
+// ReportFindings returns the vulnerabilities of the JSON reports in a report directory
+func ReportFindings(reportPath string) ([]Finding, error) {
+	reports, err := ReportPlatformFindings(reportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, r := range reports {
+		findings = append(findings, r.Findings...)
+	}
+	return findings, nil
+}
+
+// HostPlatform names the platform of the report of a scan without platform, which trivy chose
+const HostPlatform = "host platform"
+
+// PlatformFindings are the vulnerabilities of one JSON report of a report directory
+type PlatformFindings struct {
+	Platform string // e.g. linux/arm64, or HostPlatform
+	Findings []Finding
+}
+
+// ReportPlatformFindings returns the vulnerabilities of each JSON report in a report directory, in
+// the order of their file names. The platform of a report is read from its file name, as written
+// by a multi-platform scan (e.g. linux-arm-v7.json); other reports are of HostPlatform.
+func ReportPlatformFindings(reportPath string) ([]PlatformFindings, error) {
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report directory: %w", err)
+	}
+
+	var reports []PlatformFindings
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		r := PlatformFindings{Platform: reportPlatform(entry.Name())}
+		if err := eachFileVulnerability(filepath.Join(reportPath, entry.Name()), appendFinding(&r.Findings)); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// reportPlatform returns the platform of the report file name, the reverse of the file names
+// written by Run
+func reportPlatform(name string) string {
+	name = strings.TrimSuffix(name, ".json")
+	if goos, _, found := strings.Cut(name, "-"); found && goos == "linux" {
+		return strings.ReplaceAll(name, "-", "/")
+	}
+	return HostPlatform
+}
+
+// ReportSeverities returns the severity of each vulnerability in a Trivy JSON report, keyed by vulnerability ID
+func ReportSeverities(data []byte) (map[string]string, error) {
+	severities := map[string]string{}
+	err := eachVulnerability(bytes.NewReader(data), func(_ string, vuln vulnerability) {
+		severities[vuln.VulnerabilityID] = strings.ToUpper(vuln.Severity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return severities, nil
+}
+
+// FilterReport copies the JSON reports in reportPath to dst, dropping the vulnerabilities for which
+// keep returns false. keep is called with the vulnerability ID and its upper-cased severity. All
+// other report content is copied unchanged. It returns the IDs of the dropped vulnerabilities.
+func FilterReport(reportPath, dst string, keep func(id, severity string) bool) ([]string, error) {
+	entries, err := os.ReadDir(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report directory: %w", err)
+	}
+
+	var dropped []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		filePath := filepath.Join(reportPath, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report file: %w", err)
+		}
+
+		// Decode generically so that fields this package doesn't know about survive the copy
+		var report map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&report); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON report %s: %w", filePath, err)
+		}
+
+		results, _ := report["Results"].([]any)
+		for _, r := range results {
+			result, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			vulns, ok := result["Vulnerabilities"].([]any)
+			if !ok {
+				continue
+			}
+
+			kept := vulns[:0]
+			for _, v := range vulns {
+				vuln, _ := v.(map[string]any)
+				id, _ := vuln["VulnerabilityID"].(string)
+				severity, _ := vuln["Severity"].(string)
+				if keep(id, strings.ToUpper(severity)) {
+					kept = append(kept, v)
+				} else if !slices.Contains(dropped, id) {
+					dropped = append(dropped, id)
+				}
+			}
+			result["Vulnerabilities"] = kept
+		}
+
+		filtered, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode filtered report %s: %w", filePath, err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, entry.Name()), filtered, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write filtered report: %w", err)
+		}
+	}
+
+	slices.Sort(dropped)
+	return dropped, nil
+}
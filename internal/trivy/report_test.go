@@ -0,0 +1,103 @@
+package trivy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeverities(t *testing.T) {
+	dir := t.TempDir()
+	amd64 := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL"},{"VulnerabilityID":"CVE-2023-0002","Severity":"low"}]}]}`
+	arm64 := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-amd64.json"), []byte(amd64), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-arm64.json"), []byte(arm64), 0o600))
+
+	severities, err := Severities(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0001": "CRITICAL", "CVE-2023-0002": "LOW"}, severities)
+}
+
+func TestSeverities_InvalidReport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte("not json"), 0o600))
+
+	_, err := Severities(dir)
+	assert.Error(t, err)
+}
+
+func TestFilterReport(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	report := `{"SchemaVersion":2,"ArtifactName":"alpine:3.18","Results":[{"Target":"alpine","Vulnerabilities":[` +
+		`{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL","InstalledVersion":"1.0"},` +
+		`{"VulnerabilityID":"CVE-2023-0002","Severity":"low"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(src, "report.json"), []byte(report), 0o600))
+
+	dropped, err := FilterReport(src, dst, func(id, severity string) bool {
+		return severity != "LOW"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2023-0002"}, dropped)
+
+	filtered, err := os.ReadFile(filepath.Join(dst, "report.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"SchemaVersion":2,"ArtifactName":"alpine:3.18","Results":[{"Target":"alpine","Vulnerabilities":[`+
+		`{"VulnerabilityID":"CVE-2023-0001","Severity":"CRITICAL","InstalledVersion":"1.0"}]}]}`, string(filtered))
+
+	// The source report is left untouched
+	original, err := os.ReadFile(filepath.Join(src, "report.json"))
+	require.NoError(t, err)
+	assert.Equal(t, report, string(original))
+}
+
+func TestFilterReport_InvalidReport(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "report.json"), []byte("not json"), 0o600))
+
+	_, err := FilterReport(src, t.TempDir(), func(string, string) bool { return true })
+	assert.Error(t, err)
+}
+
+func TestReportSeverities(t *testing.T) {
+	report := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","Severity":"critical"}]},{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0002","Severity":"MEDIUM"}]}]}`
+	severities, err := ReportSeverities([]byte(report))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CVE-2023-0001": "CRITICAL", "CVE-2023-0002": "MEDIUM"}, severities)
+}
+
+func TestFindings(t *testing.T) {
+	report := `{"Results":[
+  {"Class":"os-pkgs","Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","PkgName":"openssl","Severity":"high","FixedVersion":"3.0.11"}]},
+  {"Class":"lang-pkgs","Vulnerabilities":[{"VulnerabilityID":"GHSA-aaaa","PkgName":"lodash","Severity":"CRITICAL"}]}
+]}`
+	findings, err := Findings([]byte(report))
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{ID: "CVE-2023-0001", Package: "openssl", Severity: "HIGH", FixedVersion: "3.0.11"},
+		{ID: "GHSA-aaaa", Package: "lodash", Severity: "CRITICAL", Library: true},
+	}, findings)
+
+	_, err = Findings([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestReportPlatformFindings(t *testing.T) {
+	dir := t.TempDir()
+	amd64 := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0001","PkgName":"openssl","Severity":"CRITICAL"}]}]}`
+	armv7 := `{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2023-0002","PkgName":"zlib","Severity":"low"}]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-amd64.json"), []byte(amd64), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-arm-v7.json"), []byte(armv7), 0o600))
+
+	reports, err := ReportPlatformFindings(dir)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, "linux/amd64", reports[0].Platform)
+	assert.Equal(t, "openssl", reports[0].Findings[0].Package)
+	assert.Equal(t, "linux/arm/v7", reports[1].Platform)
+	assert.Equal(t, "LOW", reports[1].Findings[0].Severity)
+
+	assert.Equal(t, HostPlatform, reportPlatform("report.json"))
+}
@@ -0,0 +1,93 @@
+package trivy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// sarifOSPackageRule is the rule name Trivy gives to OS package vulnerabilities in SARIF logs
+const sarifOSPackageRule = "OsPackageVulnerability"
+
+// sarifLog is the subset of a SARIF log written by Trivy that describes vulnerabilities
+type sarifLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []struct {
+					ID      string `json:"id"`
+					Name    string `json:"name"`
+					HelpURI string `json:"helpUri"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// ParseSARIF returns the OS package vulnerabilities of a SARIF log written by Trivy. Trivy does
+// not record the package versions in SARIF properties, so they are read from the result messages.
+func ParseSARIF(data []byte) ([]Vulnerability, error) {
+	var doc sarifLog
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, copaerrors.NewValidationError("failed to parse SARIF log", err)
+	}
+
+	var vulns []Vulnerability
+	seen := map[string]bool{}
+	for _, run := range doc.Runs {
+		if !strings.EqualFold(run.Tool.Driver.Name, "trivy") {
+			return nil, copaerrors.NewValidationError(fmt.Sprintf("SARIF logs written by %q are not supported", run.Tool.Driver.Name), nil,
+				"attach a report written by Trivy")
+		}
+
+		rules := map[string]int{}
+		for i, rule := range run.Tool.Driver.Rules {
+			rules[rule.ID] = i
+		}
+		for _, res := range run.Results {
+			i, ok := rules[res.RuleID]
+			if !ok || run.Tool.Driver.Rules[i].Name != sarifOSPackageRule {
+				continue
+			}
+
+			fields := sarifMessageFields(res.Message.Text)
+			vuln := Vulnerability{
+				ID:               res.RuleID,
+				Package:          fields["Package"],
+				InstalledVersion: fields["Installed Version"],
+				FixedVersion:     fields["Fixed Version"],
+				Severity:         fields["Severity"],
+				PrimaryURL:       run.Tool.Driver.Rules[i].HelpURI,
+			}
+			// Trivy reports a vulnerability once per location; copa needs it once per package
+			key := vuln.ID + "|" + vuln.Package + "|" + vuln.InstalledVersion
+			if vuln.Package == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			vulns = append(vulns, vuln)
+		}
+	}
+	return vulns, nil
+}
+
+// sarifMessageFields parses the "Key: value" lines of a Trivy SARIF result message, e.g.
+// "Package: openssl\nInstalled Version: 3.0.11-1\nVulnerability CVE-2024-0001\nSeverity: HIGH\nFixed Version: 3.0.13-1"
+func sarifMessageFields(text string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		if key, value, found := strings.Cut(line, ": "); found {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,46 @@
+package trivy
+
+import (
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trivySARIF = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "Trivy", "rules": [
+      {"id": "CVE-2024-0001", "name": "OsPackageVulnerability", "helpUri": "https://avd.aquasec.com/nvd/cve-2024-0001"},
+      {"id": "CVE-2024-0002", "name": "LanguageSpecificPackageVulnerability"}
+    ]}},
+    "results": [
+      {"ruleId": "CVE-2024-0001", "message": {"text": "Package: openssl\nInstalled Version: 3.0.11-1\nVulnerability CVE-2024-0001\nSeverity: HIGH\nFixed Version: 3.0.13-1\nLink: [CVE-2024-0001](https://avd.aquasec.com/nvd/cve-2024-0001)"}},
+      {"ruleId": "CVE-2024-0001", "message": {"text": "Package: openssl\nInstalled Version: 3.0.11-1\nVulnerability CVE-2024-0001\nSeverity: HIGH\nFixed Version: 3.0.13-1"}},
+      {"ruleId": "CVE-2024-0002", "message": {"text": "Package: lodash\nInstalled Version: 4.17.20\nVulnerability CVE-2024-0002\nSeverity: HIGH\nFixed Version: 4.17.21"}}
+    ]
+  }]
+}`
+
+func TestParseSARIF(t *testing.T) {
+	vulns, err := ParseSARIF([]byte(trivySARIF))
+	require.NoError(t, err)
+	assert.Equal(t, []Vulnerability{{
+		ID:               "CVE-2024-0001",
+		Package:          "openssl",
+		InstalledVersion: "3.0.11-1",
+		FixedVersion:     "3.0.13-1",
+		Severity:         "HIGH",
+		PrimaryURL:       "https://avd.aquasec.com/nvd/cve-2024-0001",
+	}}, vulns)
+}
+
+func TestParseSARIF_Unsupported(t *testing.T) {
+	_, err := ParseSARIF([]byte(`{"runs": [{"tool": {"driver": {"name": "Grype"}}}]}`))
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+
+	_, err = ParseSARIF([]byte(`not json`))
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+}
@@ -0,0 +1,81 @@
+package trivy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaexec "github.com/project-copacetic/mcp-server/internal/exec"
+	"github.com/project-copacetic/mcp-server/internal/subprocess"
+)
+
+// SBOM formats written by GenerateSBOM, both as JSON
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSPDX      = "spdx"
+)
+
+// SBOMFormats lists the formats GenerateSBOM writes
+var SBOMFormats = []string{SBOMFormatCycloneDX, SBOMFormatSPDX}
+
+// sbomFormatFlags maps each SBOM format to the value of trivy's --format writing it as JSON
+var sbomFormatFlags = map[string]string{
+	SBOMFormatCycloneDX: "cyclonedx",
+	SBOMFormatSPDX:      "spdx-json",
+}
+
+// GenerateSBOM writes an SBOM of image in format, one of SBOMFormats, to path. The SBOM lists the
+// image's OS and language packages, without vulnerabilities. trivy is stopped when it runs longer
+// than timeout, unless timeout is 0; waiting for a subprocess slot does not count.
+func GenerateSBOM(ctx context.Context, dockerHost, image, format, path string, timeout time.Duration) error {
+	flag, ok := sbomFormatFlags[format]
+	if !ok {
+		return fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	args := append([]string{"image", "--format", flag, "--output", path, "--quiet"}, ServerDBOptions().args()...)
+	cmd := copaexec.Command(ctx, copaexec.Trivy, append(args, image)...)
+	cmd.Env = docker.Env(ctx, dockerHost)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return runError(ctx, err, timeout, "trivy SBOM of "+image, cmd.Args, stderr.String(), time.Since(start))
+	}
+	return nil
+}
+
+// SBOM returns a CycloneDX SBOM of image that also lists its fixable OS package vulnerabilities,
+// as found by the same scan settings as 'scan-container'
+func SBOM(ctx context.Context, dockerHost, image string) ([]byte, error) {
+	args := append([]string{"image", "--format", "cyclonedx", "--scanners", "vuln", osPackagesFlag(ctx), "os", "--ignore-unfixed", "--quiet"},
+		ServerDBOptions().args()...)
+	cmd := copaexec.Command(ctx, copaexec.Trivy, append(args, image)...)
+	cmd.Env = docker.Env(ctx, dockerHost)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	release, err := subprocess.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, commandError(ctx, err, cmd.Args, stderr.String(), time.Since(start))
+	}
+	return output, nil
+}
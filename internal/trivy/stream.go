@@ -0,0 +1,155 @@
+package trivy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// eachVulnerability decodes the Trivy JSON report read from r one vulnerability at a time, calling
+// fn with each and the class of its result, so that reports of large images, which can exceed
+// 100MB, are never held in memory whole. Content other than the results' vulnerabilities is skipped.
+func eachVulnerability(r io.Reader, fn func(class string, vuln vulnerability)) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "Results" {
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := eachArrayElement(dec, func() error { return eachResultVulnerability(dec, fn) }); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// eachFileVulnerability calls eachVulnerability for the Trivy JSON report at path
+func eachFileVulnerability(path string, fn func(class string, vuln vulnerability)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read report file: %w", err)
+	}
+	defer f.Close()
+	if err := eachVulnerability(f, fn); err != nil {
+		return fmt.Errorf("failed to parse JSON report %s: %w", path, err)
+	}
+	return nil
+}
+
+// eachResultVulnerability decodes a result object, calling fn with each of its vulnerabilities.
+// Trivy writes a result's class before its vulnerabilities; only when it does not are they held
+// until the class is known.
+func eachResultVulnerability(dec *json.Decoder, fn func(class string, vuln vulnerability)) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	var (
+		class     string
+		classRead bool
+		pending   []vulnerability
+	)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "Class":
+			if err := dec.Decode(&class); err != nil {
+				return err
+			}
+			classRead = true
+			for _, vuln := range pending {
+				fn(class, vuln)
+			}
+			pending = nil
+		case "Vulnerabilities":
+			err = eachArrayElement(dec, func() error {
+				var vuln vulnerability
+				if err := dec.Decode(&vuln); err != nil {
+					return err
+				}
+				if classRead {
+					fn(class, vuln)
+				} else {
+					pending = append(pending, vuln)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+		}
+	}
+	for _, vuln := range pending {
+		fn(class, vuln)
+	}
+	return expectDelim(dec, '}')
+}
+
+// eachArrayElement calls fn for each element of the array, or null, that is the next value of dec.
+// fn must consume the element.
+func eachArrayElement(dec *json.Decoder, fn func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array, found %v", tok)
+	}
+	for dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token of dec, which must be delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, found %v", delim, tok)
+	}
+	return nil
+}
+
+// skipValue reads past the next value of dec without keeping it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package trivy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEachVulnerability(t *testing.T) {
+	report := `{
+  "SchemaVersion": 2,
+  "Metadata": {"OS": {"Family": "alpine", "Name": "3.19.0"}, "RepoTags": ["alpine:3.19"]},
+  "Results": [
+    {"Target": "alpine:3.19", "Class": "os-pkgs", "Packages": [{"Name": "openssl"}], "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2023-0001", "PkgName": "openssl", "Severity": "HIGH", "CVSS": {"nvd": {"V3Score": 7.5}}}
+    ]},
+    {"Target": "app/package-lock.json", "Vulnerabilities": [{"VulnerabilityID": "GHSA-aaaa", "PkgName": "lodash"}], "Class": "lang-pkgs"},
+    {"Target": "secrets", "Class": "secret", "Vulnerabilities": null}
+  ]
+}`
+	var got []string
+	err := eachVulnerability(strings.NewReader(report), func(class string, vuln vulnerability) {
+		got = append(got, class+"/"+vuln.VulnerabilityID)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"os-pkgs/CVE-2023-0001", "lang-pkgs/GHSA-aaaa"}, got, "the class is known even when it follows the vulnerabilities")
+}
+
+func TestEachVulnerability_InvalidReport(t *testing.T) {
+	for _, report := range []string{"not json", `[]`, `{"Results": {}}`, `{"Results": [{"Vulnerabilities": [1]}]}`, `{"Results": [`} {
+		err := eachVulnerability(strings.NewReader(report), func(string, vulnerability) {})
+		assert.Error(t, err, report)
+	}
+}
+
+func TestCountVulnerabilities(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"Results":[{"Class":"os-pkgs","Vulnerabilities":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"VulnerabilityID":"CVE-2024-%04d","Severity":"LOW"}`, i)
+	}
+	b.WriteString(`]},{"Class":"os-pkgs"}]}`)
+
+	count, err := CountVulnerabilities([]byte(b.String()))
+	require.NoError(t, err)
+	assert.Equal(t, 1000, count)
+
+	count, err = CountVulnerabilities([]byte(`{"Results":null}`))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
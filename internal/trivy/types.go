@@ -1,16 +1,60 @@
 package trivy
 
-// ScanResult - result of a vulnerability scan
+import (
+	"time"
+
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// ScanResult - result of a vulnerability scan, and the structured output of the scan tool.
+// This is a published contract; only add fields.
 type ScanResult struct {
-	Image         string
-	ReportPath    string
-	VulnCount     int
-	Platforms     []string
-	ScanCompleted bool
+	Image          string   `json:"image" jsonschema:"the scanned image reference"`
+	ReportPath     string   `json:"reportPath" jsonschema:"the report directory to pass to 'patch-report-based'"`
+	VulnCount      int      `json:"vulnCount" jsonschema:"total number of fixable vulnerabilities found"`
+	Platforms      []string `json:"platforms" jsonschema:"the scanned platforms"`
+	ScanCompleted  bool     `json:"scanCompleted" jsonschema:"whether the scan completed"`
+	Duration       string   `json:"duration" jsonschema:"how long the scan took"`
+	Warnings       []string `json:"warnings,omitempty" jsonschema:"non-fatal problems encountered during the scan"`
+	GitLabReport   string   `json:"gitlabReport,omitempty" jsonschema:"path of the GitLab container scanning report, when one was requested"`
+	AttachedReport string   `json:"attachedReport,omitempty" jsonschema:"digest of the referrer artifact whose attached scan report was reused instead of scanning the image"`
+	ReportURI      string   `json:"reportUri,omitempty" jsonschema:"URI of the MCP resource serving the report, for clients that cannot read reportPath on the server's filesystem"`
 }
 
 // ScanParams - parameters for scanning container images for vulnerabilities
 type ScanParams struct {
-	Image    string   `json:"image" jsonschema:"the image reference of the container to scan for vulnerabilities"`
-	Platform []string `json:"platform,omitempty" jsonschema:"Target platform(s) for vulnerability scanning (e.g., linux/amd64,linux/arm64). Valid platforms: linux/amd64, linux/arm64, linux/riscv64, linux/ppc64le, linux/s390x, linux/386, linux/arm/v7, linux/arm/v6. If not specified, scans the host platform"`
+	Image               string             `json:"image" jsonschema:"the image reference of the container to scan for vulnerabilities"`
+	Platform            []string           `json:"platform,omitempty" jsonschema:"Target platform(s) for vulnerability scanning (e.g., linux/amd64,linux/arm64). Valid platforms: linux/amd64, linux/arm64, linux/riscv64, linux/ppc64le, linux/s390x, linux/386, linux/arm/v7, linux/arm/v6. If not specified, scans the host platform, or on macOS and Windows hosts the Linux platforms of a remote multi-platform image"`
+	DockerHost          string             `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath          string             `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	TimeoutSeconds      int                `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the scan when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Retry               *types.RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+	GitLabReport        string             `json:"gitlabReport,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to also write the findings to as a GitLab container scanning report (e.g. gl-container-scanning-report.json), for GitLab's security dashboard"`
+	ReuseAttachedReport bool               `json:"reuseAttachedReport,omitempty" jsonschema:"reuse a Trivy JSON or SARIF report attached to the image in its registry as an OCI referrer (e.g. published by the build pipeline) instead of scanning; the image is scanned when none is attached. Cannot be combined with platform"`
+	ContainerdNamespace string             `json:"containerdNamespace,omitempty" jsonschema:"optional containerd namespace (e.g. k8s.io, where the kubelet keeps its images) to scan the image in, for a server running as a node agent, instead of the Docker daemon or the registry. Cannot be combined with platform or reuseAttachedReport"`
+	Severity            []string           `json:"severity,omitempty" jsonschema:"optional: only report vulnerabilities of these severities (CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN), e.g. [CRITICAL, HIGH]; the report passed to 'patch-report-based' then only holds them"`
+	Env                 map[string]string  `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its copa, trivy and docker commands. Only variables the server sets can be overridden"`
+	SkipDBUpdate        *bool              `json:"skipDbUpdate,omitempty" jsonschema:"optional: scan with trivy's cached vulnerability database instead of updating it, e.g. in an air-gapped environment. Defaults to the server setting"`
+	OfflineScan         *bool              `json:"offlineScan,omitempty" jsonschema:"optional: make trivy scan without network requests besides the database download, e.g. for Java packages. Defaults to the server setting"`
+	DBRepository        string             `json:"dbRepository,omitempty" jsonschema:"optional OCI repository to download trivy's vulnerability database from (e.g. registry.corp.internal/aquasec/trivy-db:2), such as a mirror of the public database. Defaults to the server setting"`
+}
+
+// DBOptions returns the database options of the scan: those the call sets, over the server's
+func (p ScanParams) DBOptions() DBOptions {
+	o := ServerDBOptions()
+	if p.SkipDBUpdate != nil {
+		o.SkipDBUpdate = *p.SkipDBUpdate
+	}
+	if p.OfflineScan != nil {
+		o.OfflineScan = *p.OfflineScan
+	}
+	if p.DBRepository != "" {
+		o.DBRepository = p.DBRepository
+	}
+	return o
+}
+
+// Timeout returns the time limit of the scan, 0 for none
+func (p ScanParams) Timeout() time.Duration {
+	return time.Duration(p.TimeoutSeconds) * time.Second
 }
@@ -1,40 +1,711 @@
 package types
 
+// Ver - structured output of the version tool
 type Ver struct {
-	Version string `json:"version" jsonschema:"the version of the copa cli"`
+	Version string   `json:"version" jsonschema:"the version of the copa cli"`
+	Trivy   string   `json:"trivy,omitempty" jsonschema:"the version of the trivy cli, empty when trivy is not found"`
+	Missing []string `json:"missingFeatures,omitempty" jsonschema:"features the installed copa and trivy releases lack, with the release adding each"`
 }
 
+// PatchResult - structured output of the patch tools. This is a published contract; only add fields.
 type PatchResult struct {
-	OriginalImage       string
-	PatchedImage        []string
-	ReportPath          string
-	VexPath             string
-	NumFixedVulns       int
-	UpdatedPackageCount int
-	ScanPerformed       bool
-	VexGenerated        bool
+	OriginalImage       string           `json:"originalImage" jsonschema:"the image reference that was patched"`
+	PatchedImage        []string         `json:"patchedImage" jsonschema:"references of the patched image"`
+	ReportPath          string           `json:"reportPath,omitempty" jsonschema:"the vulnerability report directory used for report-based patching"`
+	VexPath             string           `json:"vexPath,omitempty" jsonschema:"path of the generated VEX document, when it was kept on disk"`
+	VexURI              string           `json:"vexUri,omitempty" jsonschema:"URI of the MCP resource serving the VEX document, when it was kept on disk, for clients that cannot read vexPath on the server's filesystem"`
+	ExportPath          string           `json:"exportPath,omitempty" jsonschema:"path of the tarball the patched image was exported to"`
+	ContainerdNamespace string           `json:"containerdNamespace,omitempty" jsonschema:"the containerd namespace the patched image was imported into"`
+	NumFixedVulns       int              `json:"numFixedVulns" jsonschema:"number of vulnerabilities fixed"`
+	UpdatedPackageCount int              `json:"updatedPackageCount" jsonschema:"number of packages updated"`
+	ScanPerformed       bool             `json:"scanPerformed" jsonschema:"whether the patch was based on a vulnerability scan"`
+	VexGenerated        bool             `json:"vexGenerated" jsonschema:"whether a VEX document was generated"`
+	Platforms           []PlatformResult `json:"platforms,omitempty" jsonschema:"per-platform outcome of a multi-platform patch, when the platforms are known"`
+	Severity            *SeveritySummary `json:"severity,omitempty" jsonschema:"fixed and remaining vulnerabilities by severity, for report-based patching"`
+	Remaining           *RemainingVulns  `json:"remaining,omitempty" jsonschema:"why vulnerabilities remain after a report-based patch"`
+	Description         string           `json:"description,omitempty" jsonschema:"pull request description of the patch drafted by the client's model, when draftDescription was set"`
+	GitOps              *GitOpsSnippets  `json:"gitops,omitempty" jsonschema:"configuration for the GitOps image automation tool named by gitops to deploy the patched tag"`
+	SmokeTest           *SmokeTestResult `json:"smokeTest,omitempty" jsonschema:"the smoke test the patched image passed before it was pushed, when smokeTest was set"`
+	Pinned              *PinnedReference `json:"pinned,omitempty" jsonschema:"the pushed patched image pinned to its digest, with deployment snippets, when push was set"`
+
+	FixedVulnerabilities          []FixedVulnerability `json:"fixedVulnerabilities,omitempty" jsonschema:"the vulnerabilities fixed, capped; see fixedVulnerabilitiesTruncated"`
+	Metrics                       *PatchMetrics        `json:"metrics,omitempty" jsonschema:"timing and resource usage of the patch"`
+	FixedVulnerabilitiesTruncated bool                 `json:"fixedVulnerabilitiesTruncated,omitempty" jsonschema:"true when fixedVulnerabilities was capped; page through the full list with 'list-fixed-vulnerabilities' and vexPath"`
+	Warnings                      []string             `json:"warnings,omitempty" jsonschema:"non-fatal problems encountered during the patch, such as skipped platforms or an unreadable VEX document"`
+	Command                       []string             `json:"command,omitempty" jsonschema:"the copa command and its arguments that were run, or for a dry run would have been run"`
+	DryRun                        bool                 `json:"dryRun,omitempty" jsonschema:"true when the patch was a dry run: the copa command was not run and no image was patched, exported or pushed"`
+}
+
+// GitOpsSnippets - configuration for Flux image automation or Argo CD Image Updater to deploy a patched tag
+type GitOpsSnippets struct {
+	Tool        string            `json:"tool" jsonschema:"flux or argocd"`
+	Manifests   string            `json:"manifests,omitempty" jsonschema:"Flux ImageRepository and ImagePolicy manifests to add to the cluster's Flux configuration"`
+	Marker      string            `json:"marker,omitempty" jsonschema:"the image field with the Flux setter marker, to use in the deployment manifests in place of the image reference"`
+	Annotations map[string]string `json:"annotations,omitempty" jsonschema:"Argo CD Image Updater annotations to add to the Argo CD Application that deploys the image"`
+}
+
+// PinnedReference - a pushed patched image pinned to the digest its tag resolved to, so that
+// deployments run exactly the image that was patched even if the tag is patched again
+type PinnedReference struct {
+	Image     string `json:"image" jsonschema:"the pushed image reference, with its tag"`
+	Digest    string `json:"digest" jsonschema:"the digest the tag resolved to after the push"`
+	Reference string `json:"reference" jsonschema:"the image pinned to its digest, as repository@sha256:..."`
+	Kubectl   string `json:"kubectl" jsonschema:"kubectl command deploying the pinned reference; replace the deployment and container names as needed"`
+	Compose   string `json:"compose" jsonschema:"Docker Compose service snippet deploying the pinned reference"`
+}
+
+// SmokeTestResult - a smoke test the patched image passed in a temporary registry
+type SmokeTestResult struct {
+	Command  []string `json:"command" jsonschema:"the command run, with {image} replaced"`
+	Image    string   `json:"image" jsonschema:"reference of the patched image in the temporary registry the command was run against"`
+	Duration string   `json:"duration" jsonschema:"how long the command ran"`
+	Output   string   `json:"output,omitempty" jsonschema:"the end of the command's combined output"`
+}
+
+// PatchMetrics - timing and resource usage of a patch. Byte counts are omitted when unavailable.
+type PatchMetrics struct {
+	PatchDuration     string `json:"patchDuration" jsonschema:"how long copa ran"`
+	PatchedImageBytes int64  `json:"patchedImageBytes,omitempty" jsonschema:"size of the patched image in the local daemon; omitted when the image was pushed"`
+	ExportedBytes     int64  `json:"exportedBytes,omitempty" jsonschema:"size of the exported image tarball"`
+	PeakTempDiskBytes int64  `json:"peakTempDiskBytes,omitempty" jsonschema:"peak drop in free space on the temp filesystem while patching (includes other processes writing to it)"`
+}
+
+// FixedVulnerability is a vulnerability fixed by a patch
+type FixedVulnerability struct {
+	ID       string   `json:"id" jsonschema:"the vulnerability ID, e.g. CVE-2023-0464"`
+	Aliases  []string `json:"aliases,omitempty" jsonschema:"other IDs of the vulnerability"`
+	Severity string   `json:"severity,omitempty" jsonschema:"severity from the scan report, for report-based patching"`
+	Packages []string `json:"packages,omitempty" jsonschema:"package URLs of the packages updated to fix the vulnerability"`
+}
+
+// ListFixedVulnerabilitiesParams - pages through the vulnerabilities fixed according to a VEX document
+type ListFixedVulnerabilitiesParams struct {
+	VexPath string `json:"vexPath" jsonschema:"path of the OpenVEX document returned by 'patch-report-based'"`
+	Offset  int    `json:"offset,omitempty" jsonschema:"optional: number of vulnerabilities to skip, from nextOffset of the previous page"`
+	Limit   int    `json:"limit,omitempty" jsonschema:"optional: maximum number of vulnerabilities to return (default 100)"`
+}
+
+// FixedVulnerabilityPage - a page of fixed vulnerabilities
+type FixedVulnerabilityPage struct {
+	Vulnerabilities []FixedVulnerability `json:"vulnerabilities" jsonschema:"the vulnerabilities on this page, sorted by ID"`
+	Total           int                  `json:"total" jsonschema:"total number of fixed vulnerabilities"`
+	NextOffset      int                  `json:"nextOffset,omitempty" jsonschema:"offset of the next page, omitted on the last page"`
+}
+
+// ListVulnerabilitiesParams - pages through the vulnerabilities of a scan report
+type ListVulnerabilitiesParams struct {
+	ReportPath  string   `json:"reportPath" jsonschema:"report directory returned by 'scan-container', 'scan-registry' or 'fetch-harbor-report'"`
+	Severity    []string `json:"severity,omitempty" jsonschema:"optional: only list vulnerabilities of these severities (CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN)"`
+	FixableOnly bool     `json:"fixableOnly,omitempty" jsonschema:"optional: only list vulnerabilities with a fixed version"`
+	Package     string   `json:"package,omitempty" jsonschema:"optional: only list vulnerabilities of packages whose name contains this"`
+	SortBy      string   `json:"sortBy,omitempty" jsonschema:"optional: severity (the default, most severe first), id or package"`
+	Offset      int      `json:"offset,omitempty" jsonschema:"optional: number of vulnerabilities to skip, from nextOffset of the previous page"`
+	Limit       int      `json:"limit,omitempty" jsonschema:"optional: maximum number of vulnerabilities to return (default 100)"`
+}
+
+// Vulnerability - a vulnerability of a package in a scan report
+type Vulnerability struct {
+	ID               string `json:"id" jsonschema:"the vulnerability ID, e.g. CVE-2023-0464"`
+	Package          string `json:"package" jsonschema:"the vulnerable package"`
+	InstalledVersion string `json:"installedVersion,omitempty" jsonschema:"the installed version of the package"`
+	FixedVersion     string `json:"fixedVersion,omitempty" jsonschema:"the version fixing the vulnerability, omitted when there is no fix"`
+	Severity         string `json:"severity" jsonschema:"the severity from the scan report"`
+	Title            string `json:"title,omitempty" jsonschema:"a short description of the vulnerability"`
+}
+
+// VulnerabilityPage - a page of the vulnerabilities of a scan report
+type VulnerabilityPage struct {
+	Vulnerabilities []Vulnerability `json:"vulnerabilities" jsonschema:"the vulnerabilities on this page"`
+	Total           int             `json:"total" jsonschema:"total number of vulnerabilities matching the filters"`
+	Counts          SeverityCounts  `json:"counts" jsonschema:"the vulnerabilities matching the filters by severity"`
+	NextOffset      int             `json:"nextOffset,omitempty" jsonschema:"offset of the next page, omitted on the last page"`
+}
+
+// SummarizeReportParams - summarizes the vulnerabilities of a scan report
+type SummarizeReportParams struct {
+	ReportPath  string `json:"reportPath" jsonschema:"report directory returned by 'scan-container', 'scan-registry' or 'fetch-harbor-report'"`
+	TopPackages int    `json:"topPackages,omitempty" jsonschema:"optional: number of most vulnerable packages to return (default 10)"`
+}
+
+// ReportSummary - structured output of the summarize-report tool
+type ReportSummary struct {
+	ReportPath  string            `json:"reportPath" jsonschema:"the summarized report directory"`
+	Total       int               `json:"total" jsonschema:"number of vulnerabilities; a vulnerability of a package reported for several platforms counts once"`
+	Counts      SeverityCounts    `json:"counts" jsonschema:"the vulnerabilities by severity"`
+	Fixable     int               `json:"fixable" jsonschema:"number of vulnerabilities with a fixed version, which patching can fix"`
+	Unfixable   int               `json:"unfixable" jsonschema:"number of vulnerabilities without a fixed version; scans by 'scan-container' leave them out"`
+	TopPackages []PackageSummary  `json:"topPackages" jsonschema:"the packages with the most vulnerabilities, most vulnerable first"`
+	Platforms   []PlatformSummary `json:"platforms" jsonschema:"the vulnerabilities of each platform of the report"`
+}
+
+// PackageSummary - the vulnerabilities of a package in a scan report
+type PackageSummary struct {
+	Package         string         `json:"package" jsonschema:"the vulnerable package"`
+	Vulnerabilities int            `json:"vulnerabilities" jsonschema:"number of vulnerabilities of the package"`
+	Fixable         int            `json:"fixable" jsonschema:"number of them with a fixed version"`
+	Counts          SeverityCounts `json:"counts" jsonschema:"the vulnerabilities of the package by severity"`
+}
+
+// PlatformSummary - the vulnerabilities of one platform of a scan report
+type PlatformSummary struct {
+	Platform  string         `json:"platform" jsonschema:"the platform, e.g. linux/arm64, or 'host platform' for a scan without platform"`
+	Total     int            `json:"total" jsonschema:"number of vulnerabilities of the platform"`
+	Counts    SeverityCounts `json:"counts" jsonschema:"the vulnerabilities of the platform by severity"`
+	Fixable   int            `json:"fixable" jsonschema:"number of them with a fixed version"`
+	Unfixable int            `json:"unfixable" jsonschema:"number of them without a fixed version"`
+}
+
+// SeverityCounts counts vulnerabilities by severity
+type SeverityCounts struct {
+	Critical int `json:"critical" jsonschema:"number of CRITICAL vulnerabilities"`
+	High     int `json:"high" jsonschema:"number of HIGH vulnerabilities"`
+	Medium   int `json:"medium" jsonschema:"number of MEDIUM vulnerabilities"`
+	Low      int `json:"low" jsonschema:"number of LOW vulnerabilities"`
+	Unknown  int `json:"unknown" jsonschema:"number of vulnerabilities with an unknown severity"`
+}
+
+// SeveritySummary breaks down the vulnerabilities of a scan report into those fixed by a patch and those remaining
+type SeveritySummary struct {
+	Fixed     SeverityCounts `json:"fixed" jsonschema:"vulnerabilities fixed by the patch"`
+	Remaining SeverityCounts `json:"remaining" jsonschema:"fixable vulnerabilities in the report that remain after the patch"`
+}
+
+// Sources of the vulnerabilities classified in RemainingVulns
+const (
+	RemainingSourceReport = "report" // the scan report the patch was based on
+	RemainingSourceScan   = "scan"   // a scan of the patched image for every package type, including unfixed vulnerabilities
+)
+
+// RemainingClass is one reason vulnerabilities remain after a patch
+type RemainingClass struct {
+	Count int      `json:"count" jsonschema:"number of vulnerabilities"`
+	IDs   []string `json:"ids,omitempty" jsonschema:"the vulnerability IDs, sorted (at most 50)"`
+}
+
+// RemainingVulns classifies the vulnerabilities a report-based patch did not fix
+type RemainingVulns struct {
+	Source         string         `json:"source" jsonschema:"what was classified: report (the patch's scan report) or scan (a scan of the patched image)"`
+	NoFixAvailable RemainingClass `json:"noFixAvailable" jsonschema:"vulnerabilities the distribution has not released a fix for"`
+	Library        RemainingClass `json:"library" jsonschema:"vulnerabilities of language libraries (e.g. npm, pip, Go modules), which copa cannot patch; rebuild the application with updated dependencies"`
+	Excluded       RemainingClass `json:"excluded" jsonschema:"vulnerabilities left unpatched by excludeCVEs, minSeverity or severity"`
+	NotApplied     RemainingClass `json:"notApplied" jsonschema:"vulnerabilities with a fix that the patch did not apply, e.g. because the package repositories do not offer the fixed version yet"`
+}
+
+// Platform patch statuses reported in PlatformResult
+const (
+	PlatformStatusPatched = "patched"
+	PlatformStatusSkipped = "skipped" // the platform is not supported by copa and was left unchanged
+)
+
+// PlatformResult is the outcome of patching a single platform of a multi-platform image
+type PlatformResult struct {
+	Platform     string `json:"platform" jsonschema:"the platform, e.g. linux/amd64"`
+	PatchedImage string `json:"patchedImage,omitempty" jsonschema:"reference of the patched image"`
+	Status       string `json:"status" jsonschema:"patched, or skipped when copa does not support the platform"`
+	FixedVulns   int    `json:"fixedVulns" jsonschema:"number of vulnerabilities fixed on this platform"`
+	Duration     string `json:"duration,omitempty" jsonschema:"duration of the copa run; all platforms are patched in a single run"`
 }
 
 // ReportBasedPatchParams - patches only vulnerabilities found in an existing vulnerability report
 // NOTE: This requires a vulnerability scan to be run first using the 'scan-container' tool
 type ReportBasedPatchParams struct {
-	Image      string `json:"image" jsonschema:"the image reference of the container being patched"`
-	Tag        string `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
-	Push       bool   `json:"push" jsonschema:"push patched image to destination registry"`
-	ReportPath string `json:"reportPath" jsonschema:"Path to the vulnerability report directory created by the 'scan-container' tool. This must be provided - run 'scan-container' first to generate the report."`
+	Image                string            `json:"image" jsonschema:"the image reference of the container being patched"`
+	Tag                  string            `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
+	Push                 bool              `json:"push,omitempty" jsonschema:"optional: push patched image to destination registry"`
+	Destination          string            `json:"destination,omitempty" jsonschema:"optional repository, without tag (e.g. golden.example.com/team/app), to give the patched image instead of the source repository, e.g. to promote it from a quarantine registry to a golden one. patchtag still sets its tag, and push pushes it there"`
+	DryRun               bool              `json:"dryRun,omitempty" jsonschema:"optional: validate the call and return the copa command it would run in the result's command, without running it, so nothing is patched, exported or pushed"`
+	ReportPath           string            `json:"reportPath" jsonschema:"Path to the vulnerability report directory created by the 'scan-container' tool. This must be provided - run 'scan-container' first to generate the report."`
+	DockerHost           string            `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ExportPath           string            `json:"exportPath,omitempty" jsonschema:"optional file path to save the patched image to as a tarball (loadable with 'docker load', OCI layout compatible) for air-gapped promotion. Cannot be combined with push"`
+	ContainerdNamespace  string            `json:"containerdNamespace,omitempty" jsonschema:"optional containerd namespace (e.g. k8s.io, where the kubelet keeps its images) to read the image from and import the patched image into, for a server running as a node agent. The image is copied into the Docker daemon with ctr instead of being pulled from its registry. Cannot be combined with push"`
+	VexOutput            string            `json:"vexOutput,omitempty" jsonschema:"optional file path to write the generated VEX document to. The document is also returned in the result and as an MCP resource"`
+	VexFormat            string            `json:"vexFormat,omitempty" jsonschema:"optional VEX document format: 'openvex' (default) or 'csaf' for CSAF 2.0 VEX"`
+	VexNotes             string            `json:"vexNotes,omitempty" jsonschema:"optional notes added to every statement of the generated VEX document, e.g. a change ticket ID or approval reference for auditors"`
+	VexAuthor            string            `json:"vexAuthor,omitempty" jsonschema:"optional author recorded in the generated VEX document, e.g. a team or pipeline identity"`
+	KeepReport           *bool             `json:"keepReport,omitempty" jsonschema:"optional: keep the scan report directory created by 'scan-container' after patching. Defaults to the server setting"`
+	MaxRemaining         *int              `json:"maxRemaining,omitempty" jsonschema:"optional vulnerability budget: fail the call if more than this many fixable vulnerabilities at or above maxRemainingSeverity remain after patching"`
+	MaxRemainingSeverity string            `json:"maxRemainingSeverity,omitempty" jsonschema:"optional: lowest severity counted against maxRemaining: CRITICAL, HIGH, MEDIUM or LOW (default LOW)"`
+	ExcludeCVEs          []string          `json:"excludeCVEs,omitempty" jsonschema:"optional vulnerability IDs (e.g. accepted risks) to leave unpatched: they are removed from a copy of the report before patching, and the report itself is not modified"`
+	MinSeverity          string            `json:"minSeverity,omitempty" jsonschema:"optional: only patch vulnerabilities at or above this severity: CRITICAL, HIGH, MEDIUM or LOW. Lower and unknown severities are removed from a copy of the report before patching"`
+	Severity             []string          `json:"severity,omitempty" jsonschema:"optional: only patch vulnerabilities of these severities (CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN), e.g. [CRITICAL, HIGH]. The others are removed from a copy of the report before patching. Cannot be combined with minSeverity"`
+	VexInput             string            `json:"vexInput,omitempty" jsonschema:"optional path to an existing OpenVEX document: vulnerabilities it marks as not_affected or fixed are removed from a copy of the report, so they are neither patched nor reported again"`
+	KeepVex              *bool             `json:"keepVex,omitempty" jsonschema:"optional: keep the generated VEX document on disk and available as an MCP resource after the call. A vexOutput path is always kept. Defaults to the server setting"`
+	ScanRemaining        bool              `json:"scanRemaining,omitempty" jsonschema:"optional: scan the patched image for every package type, including vulnerabilities without a fix, and classify what remains from that scan instead of from the report"`
+	ResultPath           string            `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	GitOps               string            `json:"gitops,omitempty" jsonschema:"optional: also return the configuration for 'flux' image automation or 'argocd' Image Updater to deploy the patched tag, as an alternative to opening pull requests"`
+	DraftDescription     bool              `json:"draftDescription,omitempty" jsonschema:"optional: after a successful patch, ask the client's model through MCP sampling to draft a pull request description of the change (fixed vulnerabilities, updated packages, new image reference)"`
+	SmokeTest            []string          `json:"smokeTest,omitempty" jsonschema:"optional: a command, as its arguments, to smoke test the patched image with before it is pushed. The image is pushed to a temporary local registry:2 container, and its reference there replaces {image} in the arguments and is set in SMOKE_TEST_IMAGE. The patched image is only pushed to its registry when the command exits 0. The command runs on the server, which must allow smoke tests"`
+	TimeoutSeconds       int               `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the copa command when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Retry                *RetryParams      `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+	Env                  map[string]string `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its copa, trivy and docker commands. Only variables the server sets can be overridden"`
 }
 
 // PlatformSelectivePatchParams - patches only specified platforms
 type PlatformSelectivePatchParams struct {
-	Image    string   `json:"image" jsonschema:"the image reference of the container being patched"`
-	Tag      string   `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
-	Push     bool     `json:"push" jsonschema:"push patched image to destination registry"`
-	Platform []string `json:"platform" jsonschema:"Target platform(s) for patching (e.g., linux/amd64,linux/arm64). Valid platforms: linux/amd64, linux/arm64, linux/riscv64, linux/ppc64le, linux/s390x, linux/386, linux/arm/v7, linux/arm/v6. Only specified platforms will be patched, others will be preserved unchanged"`
+	Image               string            `json:"image" jsonschema:"the image reference of the container being patched"`
+	Tag                 string            `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
+	Push                bool              `json:"push,omitempty" jsonschema:"optional: push patched image to destination registry"`
+	Destination         string            `json:"destination,omitempty" jsonschema:"optional repository, without tag (e.g. golden.example.com/team/app), to give the patched image instead of the source repository, e.g. to promote it from a quarantine registry to a golden one. patchtag still sets its tag, and push pushes it there"`
+	DryRun              bool              `json:"dryRun,omitempty" jsonschema:"optional: validate the call and return the copa command it would run in the result's command, without running it, so nothing is patched, exported or pushed"`
+	Platform            []string          `json:"platform" jsonschema:"Target platform(s) for patching (e.g., linux/amd64,linux/arm64). Valid platforms: linux/amd64, linux/arm64, linux/riscv64, linux/ppc64le, linux/s390x, linux/386, linux/arm/v7, linux/arm/v6. Only specified platforms will be patched, others will be preserved unchanged"`
+	DockerHost          string            `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ExportPath          string            `json:"exportPath,omitempty" jsonschema:"optional file path to save the patched image to as a tarball (loadable with 'docker load', OCI layout compatible) for air-gapped promotion. Cannot be combined with push"`
+	ContainerdNamespace string            `json:"containerdNamespace,omitempty" jsonschema:"optional containerd namespace (e.g. k8s.io, where the kubelet keeps its images) to read the image from and import the patched image into, for a server running as a node agent. The image is copied into the Docker daemon with ctr instead of being pulled from its registry. Cannot be combined with push"`
+	ResultPath          string            `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	GitOps              string            `json:"gitops,omitempty" jsonschema:"optional: also return the configuration for 'flux' image automation or 'argocd' Image Updater to deploy the patched tag, as an alternative to opening pull requests"`
+	DraftDescription    bool              `json:"draftDescription,omitempty" jsonschema:"optional: after a successful patch, ask the client's model through MCP sampling to draft a pull request description of the change (fixed vulnerabilities, updated packages, new image reference)"`
+	SmokeTest           []string          `json:"smokeTest,omitempty" jsonschema:"optional: a command, as its arguments, to smoke test the patched image with before it is pushed. The image is pushed to a temporary local registry:2 container, and its reference there replaces {image} in the arguments and is set in SMOKE_TEST_IMAGE. The patched image is only pushed to its registry when the command exits 0. The command runs on the server, which must allow smoke tests"`
+	TimeoutSeconds      int               `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the copa command when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Retry               *RetryParams      `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+	Env                 map[string]string `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its copa, trivy and docker commands. Only variables the server sets can be overridden"`
 }
 
 // ComprehensivePatchParams - patches all available platforms with latest updates
 type ComprehensivePatchParams struct {
-	Image string `json:"image" jsonschema:"the image reference of the container being patched"`
-	Tag   string `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
-	Push  bool   `json:"push" jsonschema:"push patched image to destination registry"`
+	Image               string            `json:"image" jsonschema:"the image reference of the container being patched"`
+	Tag                 string            `json:"patchtag" jsonschema:"the new tag name (not full image reference) for the patched image. Example: 'patched' or 'v1.0-secure', not 'alpine:patched'"`
+	Push                bool              `json:"push,omitempty" jsonschema:"optional: push patched image to destination registry"`
+	Destination         string            `json:"destination,omitempty" jsonschema:"optional repository, without tag (e.g. golden.example.com/team/app), to give the patched image instead of the source repository, e.g. to promote it from a quarantine registry to a golden one. patchtag still sets its tag, and push pushes it there"`
+	DryRun              bool              `json:"dryRun,omitempty" jsonschema:"optional: validate the call and return the copa command it would run in the result's command, without running it, so nothing is patched, exported or pushed"`
+	DockerHost          string            `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ExportPath          string            `json:"exportPath,omitempty" jsonschema:"optional file path to save the patched image to as a tarball (loadable with 'docker load', OCI layout compatible) for air-gapped promotion. Cannot be combined with push"`
+	ContainerdNamespace string            `json:"containerdNamespace,omitempty" jsonschema:"optional containerd namespace (e.g. k8s.io, where the kubelet keeps its images) to read the image from and import the patched image into, for a server running as a node agent. The image is copied into the Docker daemon with ctr instead of being pulled from its registry. Cannot be combined with push"`
+	ResultPath          string            `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	GitOps              string            `json:"gitops,omitempty" jsonschema:"optional: also return the configuration for 'flux' image automation or 'argocd' Image Updater to deploy the patched tag, as an alternative to opening pull requests"`
+	DraftDescription    bool              `json:"draftDescription,omitempty" jsonschema:"optional: after a successful patch, ask the client's model through MCP sampling to draft a pull request description of the change (fixed vulnerabilities, updated packages, new image reference)"`
+	SmokeTest           []string          `json:"smokeTest,omitempty" jsonschema:"optional: a command, as its arguments, to smoke test the patched image with before it is pushed. The image is pushed to a temporary local registry:2 container, and its reference there replaces {image} in the arguments and is set in SMOKE_TEST_IMAGE. The patched image is only pushed to its registry when the command exits 0. The command runs on the server, which must allow smoke tests"`
+	TimeoutSeconds      int               `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the copa command when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Retry               *RetryParams      `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+	Env                 map[string]string `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its copa, trivy and docker commands. Only variables the server sets can be overridden"`
+}
+
+// RetryParams overrides parts of the server's retry policy for a single call; unset fields keep the server setting
+type RetryParams struct {
+	MaxAttempts *int     `json:"maxAttempts,omitempty" jsonschema:"attempts including the first; 1 disables retries"`
+	Backoff     string   `json:"backoff,omitempty" jsonschema:"delay before the first retry, doubled for each further retry (e.g. 10s)"`
+	Categories  []string `json:"categories,omitempty" jsonschema:"error categories to retry: validation, auth, network, execution, system, policy or timeout"`
+}
+
+// ListClusterImagesParams - lists the images of the pods running in a Kubernetes cluster
+type ListClusterImagesParams struct {
+	Namespace     string `json:"namespace,omitempty" jsonschema:"optional namespace to list; all namespaces when empty"`
+	LabelSelector string `json:"labelSelector,omitempty" jsonschema:"optional label selector to filter pods (e.g. app=web)"`
+	Kubeconfig    string `json:"kubeconfig,omitempty" jsonschema:"optional path to a kubeconfig file. Defaults to KUBECONFIG, ~/.kube/config or the in-cluster service account"`
+	Context       string `json:"context,omitempty" jsonschema:"optional kubeconfig context to use"`
+}
+
+// ClusterImage is an image running in a Kubernetes cluster
+type ClusterImage struct {
+	Image      string   `json:"image" jsonschema:"the image reference from the pod spec"`
+	Pods       int      `json:"pods" jsonschema:"number of pods running the image"`
+	Namespaces []string `json:"namespaces" jsonschema:"namespaces of the pods running the image"`
+}
+
+// ClusterImageList - the images running in a Kubernetes cluster, most used first
+type ClusterImageList struct {
+	Images []ClusterImage `json:"images" jsonschema:"the images, sorted by pod count"`
+	Pods   int            `json:"pods" jsonschema:"number of pods inspected"`
+}
+
+// ScanRegistryParams - scans one tag of every repository in a registry, or of a list of repositories
+type ScanRegistryParams struct {
+	Registry        string       `json:"registry,omitempty" jsonschema:"registry whose catalog is walked, e.g. registry.example.com or localhost:5000. Prefix with http:// for a registry without TLS"`
+	Repositories    []string     `json:"repositories,omitempty" jsonschema:"optional repositories to scan instead of the catalog: relative to registry (e.g. team/app), or full repository references (e.g. docker.io/library/nginx) when registry is not set"`
+	Tag             string       `json:"tag,omitempty" jsonschema:"optional tag to scan in each repository (default latest)"`
+	MaxRepositories int          `json:"maxRepositories,omitempty" jsonschema:"optional maximum number of repositories to scan (default 50)"`
+	TimeoutSeconds  int          `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the scan of an image when it runs longer than this many seconds, recording a timeout error for the image. Defaults to the server setting"`
+	DockerHost      string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath      string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry           *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for each scan"`
+}
+
+// HarborReportParams - fetches the vulnerability report Harbor already produced for an image
+type HarborReportParams struct {
+	Image      string       `json:"image" jsonschema:"the Harbor image reference, e.g. harbor.example.com/project/app:1.0"`
+	HarborURL  string       `json:"harborUrl,omitempty" jsonschema:"optional Harbor base URL. Defaults to the server setting, or https:// and the image's registry host"`
+	DockerHost string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry      *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+}
+
+// BaseUpgradeParams - suggests newer tags for a base image, or for the base images of a Dockerfile
+type BaseUpgradeParams struct {
+	Image      string       `json:"image,omitempty" jsonschema:"base image reference to check, e.g. node:18.17-alpine. Set either image or dockerfile"`
+	Dockerfile string       `json:"dockerfile,omitempty" jsonschema:"path of a Dockerfile whose FROM instructions are checked"`
+	Write      bool         `json:"write,omitempty" jsonschema:"rewrite the FROM instructions of the Dockerfile with the suggested tags"`
+	Compare    bool         `json:"compare,omitempty" jsonschema:"scan the current and suggested base images with Trivy and report their fixable vulnerability counts"`
+	DockerHost string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry      *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for the registry requests and scans"`
+}
+
+// BaseUpgrade is the upgrade suggested for one base image
+type BaseUpgrade struct {
+	Image          string   `json:"image" jsonschema:"the current base image reference"`
+	Line           int      `json:"line,omitempty" jsonschema:"line of the FROM instruction in the Dockerfile"`
+	Stage          string   `json:"stage,omitempty" jsonschema:"build stage named by the FROM instruction"`
+	Suggested      string   `json:"suggested,omitempty" jsonschema:"the suggested base image: the newest tag of the same variant and precision"`
+	SameMajor      string   `json:"sameMajor,omitempty" jsonschema:"the newest base image keeping the current major version, when the suggestion changes it"`
+	Newer          []string `json:"newer,omitempty" jsonschema:"newer tags of the same variant, newest first (at most 10)"`
+	CurrentVulns   *int     `json:"currentVulns,omitempty" jsonschema:"fixable vulnerabilities in the current base image, when compare is set"`
+	SuggestedVulns *int     `json:"suggestedVulns,omitempty" jsonschema:"fixable vulnerabilities in the suggested base image, when compare is set"`
+	Reason         string   `json:"reason,omitempty" jsonschema:"why no upgrade is suggested"`
+}
+
+// BaseUpgradeResult - suggested base image upgrades from 'suggest-base-upgrade'
+type BaseUpgradeResult struct {
+	Dockerfile string        `json:"dockerfile,omitempty" jsonschema:"the checked Dockerfile"`
+	Upgrades   []BaseUpgrade `json:"upgrades" jsonschema:"one entry per base image"`
+	Rewritten  bool          `json:"rewritten,omitempty" jsonschema:"true when the Dockerfile was rewritten with the suggested tags"`
+}
+
+// EvaluateImageParams - evaluates an image against the admission policies; unset checks use the server settings
+type EvaluateImageParams struct {
+	Image             string       `json:"image" jsonschema:"the image reference to evaluate"`
+	ReportPath        string       `json:"reportPath,omitempty" jsonschema:"optional report directory from 'scan-container' to count vulnerabilities from, instead of scanning the image"`
+	AllowedRegistries []string     `json:"allowedRegistries,omitempty" jsonschema:"optional registry hosts or repository prefixes the image must come from (e.g. registry.example.com or ghcr.io/org/)"`
+	RequireSignature  *bool        `json:"requireSignature,omitempty" jsonschema:"optional: deny images without a cosign or Notation signature"`
+	MaxCritical       *int         `json:"maxCritical,omitempty" jsonschema:"optional: deny images with more fixable CRITICAL vulnerabilities than this. Negative disables the check"`
+	DockerHost        string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath        string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry             *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for the registry requests and scan"`
+}
+
+// PolicyCheck is the outcome of one admission check
+type PolicyCheck struct {
+	Name   string `json:"name" jsonschema:"the check: registry, signature, critical or rego"`
+	Passed bool   `json:"passed" jsonschema:"whether the image passed the check"`
+	Detail string `json:"detail" jsonschema:"what was checked, or why the image failed"`
+}
+
+// ImageVerdict - admission-style verdict of 'evaluate-image'
+type ImageVerdict struct {
+	Image     string          `json:"image" jsonschema:"the evaluated image reference"`
+	Verdict   string          `json:"verdict" jsonschema:"allow or deny"`
+	Allowed   bool            `json:"allowed" jsonschema:"true when every check passed"`
+	Reasons   []string        `json:"reasons,omitempty" jsonschema:"why the image was denied"`
+	Checks    []PolicyCheck   `json:"checks" jsonschema:"the outcome of each check"`
+	Signature string          `json:"signature,omitempty" jsonschema:"kind of signature attached to the image (cosign or notation), when one was looked up and found"`
+	Severity  *SeverityCounts `json:"severity,omitempty" jsonschema:"fixable vulnerabilities by severity, when they were counted"`
+}
+
+// SBOMDiffParams - diffs the SBOMs of an image and its patched image
+type SBOMDiffParams struct {
+	Image        string       `json:"image" jsonschema:"the original image reference"`
+	PatchedImage string       `json:"patchedImage,omitempty" jsonschema:"the patched image reference. Defaults to the image copa tags with patchtag"`
+	Tag          string       `json:"patchtag,omitempty" jsonschema:"the tag given to the patched image, when patchedImage is not set. Defaults to the source tag suffixed with -patched"`
+	SBOMDir      string       `json:"sbomDir,omitempty" jsonschema:"optional existing directory to keep both CycloneDX SBOMs in, as evidence of the change"`
+	DockerHost   string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath   string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry        *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for generating the SBOMs"`
+}
+
+// PackageVersion is a package added or removed by a patch
+type PackageVersion struct {
+	Name    string `json:"name" jsonschema:"the package name"`
+	Type    string `json:"type,omitempty" jsonschema:"the package type from its purl, e.g. deb, apk or rpm"`
+	Version string `json:"version" jsonschema:"the package version"`
+}
+
+// PackageChange is a package whose version a patch changed
+type PackageChange struct {
+	Name string `json:"name" jsonschema:"the package name"`
+	Type string `json:"type,omitempty" jsonschema:"the package type from its purl, e.g. deb, apk or rpm"`
+	From string `json:"from" jsonschema:"the version in the original image"`
+	To   string `json:"to" jsonschema:"the version in the patched image"`
+}
+
+// SBOMDiff - package changes between an image and its patched image from 'diff-sbom'
+type SBOMDiff struct {
+	Image        string           `json:"image" jsonschema:"the original image reference"`
+	PatchedImage string           `json:"patchedImage" jsonschema:"the patched image reference"`
+	Upgraded     []PackageChange  `json:"upgraded" jsonschema:"packages whose version changed"`
+	Added        []PackageVersion `json:"added" jsonschema:"packages only in the patched image"`
+	Removed      []PackageVersion `json:"removed" jsonschema:"packages only in the original image"`
+	Unchanged    int              `json:"unchanged" jsonschema:"number of packages with the same version in both images"`
+	ImageSBOM    string           `json:"imageSbom,omitempty" jsonschema:"path of the original image's SBOM, when sbomDir is set"`
+	PatchedSBOM  string           `json:"patchedSbom,omitempty" jsonschema:"path of the patched image's SBOM, when sbomDir is set"`
+}
+
+// SBOMParams - generates an SBOM of an image with Trivy
+type SBOMParams struct {
+	Image          string       `json:"image" jsonschema:"the image reference to generate the SBOM of"`
+	Format         string       `json:"format,omitempty" jsonschema:"optional SBOM format, written as JSON: 'cyclonedx' (default) or 'spdx'"`
+	TimeoutSeconds int          `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop trivy when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	DockerHost     string       `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath     string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry          *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for generating the SBOM"`
+}
+
+// SBOM - an SBOM generated by 'generate-sbom'
+type SBOM struct {
+	Image    string `json:"image" jsonschema:"the image reference"`
+	Format   string `json:"format" jsonschema:"the SBOM format: cyclonedx or spdx"`
+	SBOMPath string `json:"sbomPath" jsonschema:"path of the SBOM in the server's temporary directory"`
+	SBOMURI  string `json:"sbomUri" jsonschema:"URI of the MCP resource serving the SBOM, for clients that cannot read sbomPath on the server's filesystem"`
+	Size     int64  `json:"size" jsonschema:"size of the SBOM in bytes"`
+}
+
+// VerifyPatchParams - rescans a patched image and compares it with the scan of its original image
+type VerifyPatchParams struct {
+	Image          string            `json:"image" jsonschema:"the original image reference"`
+	PatchedImage   string            `json:"patchedImage,omitempty" jsonschema:"the patched image reference. Defaults to the image copa tags with patchtag"`
+	Tag            string            `json:"patchtag,omitempty" jsonschema:"the tag given to the patched image, when patchedImage is not set. Defaults to the source tag suffixed with -patched"`
+	ReportPath     string            `json:"reportPath" jsonschema:"the report directory of the scan of the original image, as returned by 'scan-container' and passed to 'patch-report-based'"`
+	Severity       []string          `json:"severity,omitempty" jsonschema:"optional: only rescan for vulnerabilities of these severities (CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN). Pass the severity filter of the scan of the original image, so that the vulnerabilities it left out are not reported as introduced"`
+	DockerHost     string            `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	ResultPath     string            `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	TimeoutSeconds int               `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop the rescan when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Retry          *RetryParams      `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for the rescan"`
+	Env            map[string]string `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its trivy and docker commands. Only variables the server sets can be overridden"`
+}
+
+// PatchVerification - outcome of 'verify-patch'
+type PatchVerification struct {
+	Image            string         `json:"image" jsonschema:"the original image reference"`
+	PatchedImage     string         `json:"patchedImage" jsonschema:"the rescanned patched image reference"`
+	Verified         bool           `json:"verified" jsonschema:"true when the rescan found none of the vulnerabilities of the report and none the original image did not have"`
+	Before           SeverityCounts `json:"before" jsonschema:"vulnerabilities of the report by severity"`
+	After            SeverityCounts `json:"after" jsonschema:"vulnerabilities the rescan found by severity"`
+	Fixed            []string       `json:"fixed" jsonschema:"vulnerabilities of the report the rescan no longer found, sorted"`
+	Remaining        []string       `json:"remaining" jsonschema:"vulnerabilities of the report the rescan still found, sorted"`
+	Introduced       []string       `json:"introduced" jsonschema:"vulnerabilities the rescan found that the report did not have, sorted"`
+	ReportPath       string         `json:"reportPath" jsonschema:"the report directory of the scan of the original image"`
+	RescanReportPath string         `json:"rescanReportPath" jsonschema:"the report directory of the rescan of the patched image"`
+	RescanReportURI  string         `json:"rescanReportUri" jsonschema:"URI of the MCP resource serving the rescan report"`
+	Warnings         []string       `json:"warnings,omitempty" jsonschema:"non-fatal problems encountered during the rescan"`
+}
+
+// PatchHistoryParams - queries the recorded patches
+type PatchHistoryParams struct {
+	Image string `json:"image,omitempty" jsonschema:"optional image reference to list the patches of; a reference without a tag or digest, e.g. nginx, matches every tag of the repository"`
+	Since string `json:"since,omitempty" jsonschema:"optional: only list patches recorded within this duration, e.g. 24h or 168h"`
+	Limit int    `json:"limit,omitempty" jsonschema:"optional maximum number of patches to return, newest first. Defaults to 20"`
+}
+
+// PatchRecord - a completed patch, recorded to the patch history
+type PatchRecord struct {
+	Time                string          `json:"time" jsonschema:"when the patch completed, in RFC 3339 format and UTC"`
+	Tool                string          `json:"tool" jsonschema:"the tool that patched the image"`
+	Image               string          `json:"image" jsonschema:"the image reference that was patched"`
+	PatchedImages       []string        `json:"patchedImages" jsonschema:"references of the patched image"`
+	Digest              string          `json:"digest,omitempty" jsonschema:"the digest the pushed patched tag resolved to, when it was pushed"`
+	NumFixedVulns       int             `json:"numFixedVulns" jsonschema:"number of vulnerabilities fixed"`
+	UpdatedPackageCount int             `json:"updatedPackageCount" jsonschema:"number of packages updated"`
+	Fixed               *SeverityCounts `json:"fixed,omitempty" jsonschema:"vulnerabilities fixed by severity, for report-based patches"`
+	Remaining           *SeverityCounts `json:"remaining,omitempty" jsonschema:"fixable vulnerabilities remaining by severity, for report-based patches"`
+}
+
+// PatchHistory - the recorded patches matching a 'patch-history' query
+type PatchHistory struct {
+	Patches  []PatchRecord `json:"patches" jsonschema:"the matching patches, newest first"`
+	Total    int           `json:"total" jsonschema:"number of matching patches, including those beyond limit"`
+	Path     string        `json:"path" jsonschema:"the history file on the server"`
+	Warnings []string      `json:"warnings,omitempty" jsonschema:"non-fatal problems reading the history, such as corrupt lines that were skipped"`
+}
+
+// ImagePRParams - bumps the references to an image in a Git repository and opens a pull request
+type ImagePRParams struct {
+	Repository   string       `json:"repository" jsonschema:"HTTPS URL of the GitHub or GitLab repository, e.g. https://github.com/org/deploy"`
+	Provider     string       `json:"provider,omitempty" jsonschema:"github or gitlab. Detected from the host for github.com and hosts containing gitlab; set it for GitHub Enterprise and other self-hosted instances"`
+	Image        string       `json:"image" jsonschema:"the original image reference to replace, with its tag or digest, e.g. nginx:1.25"`
+	PatchedImage string       `json:"patchedImage" jsonschema:"the pushed patched image reference. References are rewritten to it pinned by digest"`
+	Files        []string     `json:"files,omitempty" jsonschema:"globs of the files to search, relative to the repository root; ** matches any directories. Defaults to YAML, JSON, Terraform files and Dockerfiles"`
+	BaseBranch   string       `json:"baseBranch,omitempty" jsonschema:"branch to open the pull request against. Defaults to the repository's default branch"`
+	Branch       string       `json:"branch,omitempty" jsonschema:"name of the branch to push the change to. Defaults to copa/ followed by the patched image's name and digest"`
+	Title        string       `json:"title,omitempty" jsonschema:"pull request title. Defaults to a summary of the bump"`
+	Body         string       `json:"body,omitempty" jsonschema:"pull request body, e.g. the description drafted by a patch tool with draftDescription. Defaults to the list of changed files"`
+	DryRun       bool         `json:"dryRun,omitempty" jsonschema:"find and rewrite the references in a temporary clone without pushing or opening a pull request"`
+	ResultPath   string       `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry        *RetryParams `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for the registry, Git and API requests"`
+}
+
+// ImageReferenceFile is a file whose image references were rewritten
+type ImageReferenceFile struct {
+	Path         string `json:"path" jsonschema:"the file path, relative to the repository root"`
+	Replacements int    `json:"replacements" jsonschema:"number of references rewritten in the file"`
+}
+
+// ImagePR - image reference bump opened by 'open-image-pr'
+type ImagePR struct {
+	Repository   string               `json:"repository" jsonschema:"the repository URL"`
+	Image        string               `json:"image" jsonschema:"the original image reference"`
+	PatchedImage string               `json:"patchedImage" jsonschema:"the digest-pinned patched image reference the references were rewritten to"`
+	BaseBranch   string               `json:"baseBranch" jsonschema:"the branch the pull request targets"`
+	Branch       string               `json:"branch,omitempty" jsonschema:"the branch the change was pushed to"`
+	Files        []ImageReferenceFile `json:"files" jsonschema:"the files whose references were rewritten"`
+	DryRun       bool                 `json:"dryRun,omitempty" jsonschema:"true when nothing was pushed"`
+	Number       int                  `json:"number,omitempty" jsonschema:"the pull request number (merge request IID on GitLab)"`
+	URL          string               `json:"url,omitempty" jsonschema:"the pull request URL"`
+}
+
+// FleetImage is the scan outcome of one image in a fleet report
+type FleetImage struct {
+	Image      string          `json:"image" jsonschema:"the scanned image reference"`
+	VulnCount  int             `json:"vulnCount" jsonschema:"number of fixable vulnerabilities found"`
+	Severity   *SeverityCounts `json:"severity,omitempty" jsonschema:"fixable vulnerabilities by severity"`
+	ReportPath string          `json:"reportPath,omitempty" jsonschema:"the report directory to pass to 'patch-report-based'"`
+	Error      string          `json:"error,omitempty" jsonschema:"why the image could not be scanned"`
+}
+
+// FleetReport - aggregated vulnerability report of the images scanned by 'scan-registry'
+type FleetReport struct {
+	Registry  string         `json:"registry,omitempty" jsonschema:"the registry whose repositories were scanned"`
+	Tag       string         `json:"tag" jsonschema:"the tag scanned in each repository"`
+	Images    []FleetImage   `json:"images" jsonschema:"the scanned images, most critical first; failed scans last"`
+	Scanned   int            `json:"scanned" jsonschema:"number of images scanned"`
+	Failed    int            `json:"failed" jsonschema:"number of images that could not be scanned"`
+	Totals    SeverityCounts `json:"totals" jsonschema:"fixable vulnerabilities by severity across all scanned images"`
+	Truncated bool           `json:"truncated,omitempty" jsonschema:"true when the catalog has more repositories than maxRepositories"`
+}
+
+// PullImageParams - pulls an image into the local Docker daemon
+type PullImageParams struct {
+	Image      string `json:"image" jsonschema:"the image reference to pull"`
+	Platform   string `json:"platform,omitempty" jsonschema:"optional platform to pull (e.g. linux/arm64). Defaults to the Docker daemon's platform"`
+	DockerHost string `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+}
+
+// RemoveImageParams - removes local images and/or prunes dangling images
+type RemoveImageParams struct {
+	Images        []string `json:"images,omitempty" jsonschema:"image references or IDs to remove from the local Docker daemon"`
+	Force         bool     `json:"force,omitempty" jsonschema:"force removal of images that are referenced by stopped containers or multiple tags"`
+	PruneDangling bool     `json:"pruneDangling,omitempty" jsonschema:"also remove dangling (untagged) images, such as those left behind by patching"`
+	DockerHost    string   `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+}
+
+// RegistryLoginParams - stores registry credentials for the server's following pulls and pushes
+type RegistryLoginParams struct {
+	Registry   string `json:"registry,omitempty" jsonschema:"registry host to log in to (e.g. ghcr.io, myregistry.azurecr.io or localhost:5000). Defaults to Docker Hub"`
+	Username   string `json:"username,omitempty" jsonschema:"optional user name. Defaults to _token, for registries that accept a token without a user (e.g. GHCR, ACR)"`
+	Password   string `json:"password" jsonschema:"password or access token for the registry"`
+	DockerHost string `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+}
+
+// InstallDependenciesParams - installs the pinned copa and trivy releases into the managed bin directory
+type InstallDependenciesParams struct {
+	Tools []string `json:"tools,omitempty" jsonschema:"optional: the tools to install, copa and/or trivy. Defaults to both"`
+	Force bool     `json:"force,omitempty" jsonschema:"optional: download the pinned release even when it is already installed"`
+}
+
+// InstalledTool - a tool installed by 'install-dependencies'
+type InstalledTool struct {
+	Name             string `json:"name" jsonschema:"the tool, copa or trivy"`
+	Version          string `json:"version" jsonschema:"the pinned release installed"`
+	Path             string `json:"path" jsonschema:"the installed binary"`
+	SHA256           string `json:"sha256,omitempty" jsonschema:"the verified SHA-256 of the downloaded release archive"`
+	AlreadyInstalled bool   `json:"alreadyInstalled,omitempty" jsonschema:"true when the pinned release was already installed and nothing was downloaded"`
+}
+
+// DependencyInstall - structured output of 'install-dependencies'
+type DependencyInstall struct {
+	BinDir string          `json:"binDir" jsonschema:"the managed bin directory, first on the PATH of the tools the server runs"`
+	Tools  []InstalledTool `json:"tools" jsonschema:"the installed tools"`
+}
+
+// RemediateParams - runs the scan, patch, rescan, compare, push and sign stages of 'remediate'
+type RemediateParams struct {
+	Image          string            `json:"image,omitempty" jsonschema:"the image reference of the container to remediate. Required unless resume is set"`
+	Tag            string            `json:"patchtag,omitempty" jsonschema:"optional new tag name (not full image reference) for the patched image, e.g. 'patched'. Defaults to the source tag suffixed with -patched"`
+	Push           bool              `json:"push,omitempty" jsonschema:"optional: push the patched image to its registry once the rescan verified it, and pin it to its digest"`
+	Sign           bool              `json:"sign,omitempty" jsonschema:"optional: sign the pushed image's digest with the server's cosign key. Requires push"`
+	DockerHost     string            `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint for this call (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+	TimeoutSeconds int               `json:"timeoutSeconds,omitempty" jsonschema:"optional: stop each copa and trivy command of the remediation when it runs longer than this many seconds and fail with a timeout error. Defaults to the server setting"`
+	Resume         string            `json:"resume,omitempty" jsonschema:"optional ID of a failed remediation to resume: its completed stages are skipped and the others run again. image and patchtag may be omitted; push and sign must be repeated"`
+	ResultPath     string            `json:"resultPath,omitempty" jsonschema:"optional file path, in the server's working directory or relative to it, to write the structured result (or error) to as JSON, for CI wrappers that only capture the exit status"`
+	Retry          *RetryParams      `json:"retry,omitempty" jsonschema:"optional override of the server's retry policy for this call"`
+	Env            map[string]string `json:"env,omitempty" jsonschema:"optional values for this call of the subprocess environment variables configured on the server (e.g. TRIVY_CACHE_DIR, DOCKER_CONFIG, HTTPS_PROXY), set for its copa, trivy and docker commands. Only variables the server sets can be overridden"`
+}
+
+// Stages of 'remediate', in the order they run
+const (
+	StageScan    = "scan"
+	StagePatch   = "patch"
+	StageRescan  = "rescan"
+	StageCompare = "compare"
+	StagePush    = "push"
+	StageSign    = "sign"
+)
+
+// Statuses of a remediation stage
+const (
+	StageStatusPending   = "pending"
+	StageStatusCompleted = "completed"
+	StageStatusFailed    = "failed"
+	StageStatusSkipped   = "skipped"
+)
+
+// RemediationStage is the outcome of one stage of a remediation
+type RemediationStage struct {
+	Name     string `json:"name" jsonschema:"the stage: scan, patch, rescan, compare, push or sign"`
+	Status   string `json:"status" jsonschema:"pending, completed, failed, or skipped when the stage was not needed or not requested"`
+	Detail   string `json:"detail,omitempty" jsonschema:"what the stage did, or why it was skipped"`
+	Duration string `json:"duration,omitempty" jsonschema:"how long the stage took"`
+	Error    string `json:"error,omitempty" jsonschema:"why the stage failed"`
+}
+
+// Remediation - consolidated report of 'remediate'
+type Remediation struct {
+	ID                  string             `json:"id" jsonschema:"the remediation ID, to pass as resume when a stage failed"`
+	Image               string             `json:"image" jsonschema:"the remediated image reference"`
+	PatchedImage        string             `json:"patchedImage,omitempty" jsonschema:"reference of the patched image"`
+	Verified            bool               `json:"verified" jsonschema:"true when the rescan of the patched image found no fixable vulnerability and none it did not have before"`
+	Stages              []RemediationStage `json:"stages" jsonschema:"the stages, in the order they run"`
+	Before              *SeverityCounts    `json:"before,omitempty" jsonschema:"fixable vulnerabilities of the image by severity"`
+	After               *SeverityCounts    `json:"after,omitempty" jsonschema:"fixable vulnerabilities of the patched image by severity"`
+	Fixed               []string           `json:"fixed,omitempty" jsonschema:"vulnerabilities of the image the rescan no longer found, sorted"`
+	Remaining           []string           `json:"remaining,omitempty" jsonschema:"vulnerabilities of the image the rescan still found, sorted"`
+	Introduced          []string           `json:"introduced,omitempty" jsonschema:"vulnerabilities the rescan found that the image did not have, sorted"`
+	UpdatedPackageCount int                `json:"updatedPackageCount" jsonschema:"number of packages updated by the patch"`
+	ReportPath          string             `json:"reportPath,omitempty" jsonschema:"the report directory of the scan of the image"`
+	RescanReportPath    string             `json:"rescanReportPath,omitempty" jsonschema:"the report directory of the rescan of the patched image"`
+	VexPath             string             `json:"vexPath,omitempty" jsonschema:"path of the VEX document generated by the patch"`
+	Pinned              *PinnedReference   `json:"pinned,omitempty" jsonschema:"the pushed patched image pinned to its digest, with deployment snippets, when push was set"`
+	Signed              bool               `json:"signed,omitempty" jsonschema:"true when the pushed image was signed"`
+	DryRun              bool               `json:"dryRun,omitempty" jsonschema:"true when the patch was a dry run, so nothing after it ran"`
+	Warnings            []string           `json:"warnings,omitempty" jsonschema:"non-fatal problems encountered during the remediation"`
+}
+
+// DoctorParams - checks whether the server's environment is ready to scan and patch images
+type DoctorParams struct {
+	Registries []string `json:"registries,omitempty" jsonschema:"optional registry hosts to check connectivity to (e.g. ghcr.io or localhost:5000). Defaults to Docker Hub"`
+	DockerHost string   `json:"dockerHost,omitempty" jsonschema:"optional Docker daemon endpoint to check (e.g. unix:///run/user/1000/docker.sock or tcp://build-host:2376). Defaults to the server's DOCKER_HOST"`
+}
+
+// DoctorCheck - one check of the doctor tool
+type DoctorCheck struct {
+	Name   string   `json:"name" jsonschema:"what was checked: docker, buildkit, copa, trivy, features, registry:<host> or disk"`
+	Status string   `json:"status" jsonschema:"ok, warning (patches may still work) or failed (scans or patches will fail)"`
+	Detail string   `json:"detail" jsonschema:"what the check found"`
+	Hints  []string `json:"hints,omitempty" jsonschema:"how to fix a warning or failure"`
+}
+
+// DoctorReport - structured output of the doctor tool
+type DoctorReport struct {
+	Ready  bool          `json:"ready" jsonschema:"true when no check failed"`
+	Checks []DoctorCheck `json:"checks" jsonschema:"the checks, in the order they ran"`
+}
+
+// ToolError - structured error returned in a failed tool result so agents can choose a recovery strategy
+type ToolError struct {
+	Category string          `json:"category" jsonschema:"error category: validation, auth, network, execution, system, policy or timeout"`
+	Message  string          `json:"message" jsonschema:"the error message"`
+	Hints    []string        `json:"hints,omitempty" jsonschema:"remediation hints"`
+	Recovery string          `json:"recovery" jsonschema:"suggested recovery strategy for this category"`
+	Command  *CommandFailure `json:"command,omitempty" jsonschema:"details of the failed copa or trivy command, when one caused the error"`
+	Problems []ParamProblem  `json:"problems,omitempty" jsonschema:"every invalid parameter of the call, for validation errors"`
+}
+
+// ParamProblem - an invalid parameter of a tool call
+type ParamProblem struct {
+	Field   string `json:"field" jsonschema:"the parameter name"`
+	Message string `json:"message" jsonschema:"what is wrong with it"`
+}
+
+// CommandFailure - details of the failed external command behind a ToolError
+type CommandFailure struct {
+	Command    string `json:"command" jsonschema:"the command line, with credentials redacted"`
+	ExitCode   int    `json:"exitCode" jsonschema:"the exit code, or -1 if the command did not exit normally"`
+	StderrTail string `json:"stderrTail,omitempty" jsonschema:"the last lines of the command's stderr"`
+	Duration   string `json:"duration" jsonschema:"how long the command ran"`
 }
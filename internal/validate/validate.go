@@ -0,0 +1,375 @@
+// Package validate checks the arguments of a tool call before any subprocess is started,
+// collecting every invalid parameter into a single validation error instead of failing at the
+// first one.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/project-copacetic/mcp-server/internal/containerd"
+	"github.com/project-copacetic/mcp-server/internal/copa"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/gitops"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+)
+
+// imageRegexp matches an image reference: an optional registry host and port, a lowercase
+// repository path, an optional tag and an optional digest
+var imageRegexp = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*|\[[0-9a-fA-F:]+\])(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+	`(?:@[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,})?$`)
+
+// Validator collects the problems of a tool call's arguments
+type Validator struct {
+	problems []copaerrors.Problem
+	hints    []string
+}
+
+// Add records a problem of field
+func (v *Validator) Add(field, format string, args ...any) {
+	v.problems = append(v.problems, copaerrors.Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check records err, when not nil, as a problem of field. The hints of a categorized error are
+// kept for the aggregated error.
+func (v *Validator) Check(field string, err error) {
+	if err == nil {
+		return
+	}
+	var ce *copaerrors.CopaceticError
+	if !errors.As(err, &ce) {
+		v.Add(field, "%v", err)
+		return
+	}
+	message := ce.Message
+	if ce.Err != nil {
+		message = fmt.Sprintf("%s: %v", message, ce.Err)
+	}
+	v.Add(field, "%s", message)
+	for _, hint := range ce.Hints {
+		if !slices.Contains(v.hints, hint) {
+			v.hints = append(v.hints, hint)
+		}
+	}
+}
+
+// Required records a problem when value is empty
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "%s is required", field)
+		return false
+	}
+	return true
+}
+
+// Image checks that image is a required, well-formed image reference
+func (v *Validator) Image(field, image string) {
+	if !v.Required(field, image) {
+		return
+	}
+	if !imageRegexp.MatchString(image) {
+		v.Add(field, "invalid image reference: %s", image)
+		v.hint("image references look like registry.example.com/team/app:1.0 or alpine@sha256:<digest>; repository names are lowercase")
+	}
+}
+
+// Exclusive records a problem when both a and b are set
+func (v *Validator) Exclusive(a string, aSet bool, b string, bSet bool) {
+	if aSet && bSet {
+		v.Add(a, "%s cannot be combined with %s", a, b)
+	}
+}
+
+// Dir checks that the directory a file is to be written to exists
+func (v *Validator) Dir(field, path string) {
+	if path == "" {
+		return
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		v.Add(field, "directory does not exist: %s", filepath.Dir(path))
+	}
+}
+
+// Exists checks that the file or directory at path, when set, exists
+func (v *Validator) Exists(field, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.Add(field, "%s does not exist: %s", field, path)
+	}
+}
+
+func (v *Validator) hint(hint string) {
+	if !slices.Contains(v.hints, hint) {
+		v.hints = append(v.hints, hint)
+	}
+}
+
+// Err returns a validation error listing every recorded problem, or nil when there is none
+func (v *Validator) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return copaerrors.NewInvalidParamsError(v.problems, v.hints...)
+}
+
+// patch checks the parameters shared by the patch tools
+func patch(image, tag, destination string, push bool, dockerHost, exportPath, containerdNamespace, gitOps string) *Validator {
+	v := &Validator{}
+	v.Image("image", image)
+	v.Check("patchtag", copa.ValidateTag(tag))
+	if err := copa.ValidateDestination(destination); err != nil {
+		v.Check("destination", err)
+	} else if destination != "" {
+		v.Image("destination", destination)
+	}
+	v.Check("dockerHost", docker.ValidateHost(dockerHost))
+	v.Exclusive("exportPath", exportPath != "", "push", push)
+	v.Dir("exportPath", exportPath)
+	v.Check("containerdNamespace", containerd.ValidateNamespace(containerdNamespace))
+	v.Exclusive("containerdNamespace", containerdNamespace != "", "push", push)
+	v.Check("gitops", gitops.ValidateTool(gitOps))
+	return v
+}
+
+// Comprehensive checks the parameters of 'patch-comprehensive'
+func Comprehensive(p types.ComprehensivePatchParams) *Validator {
+	v := patch(p.Image, p.Tag, p.Destination, p.Push, p.DockerHost, p.ExportPath, p.ContainerdNamespace, p.GitOps)
+	v.timeout(p.TimeoutSeconds)
+	return v
+}
+
+// PlatformSelective checks the parameters of 'patch-platform-selective'. Unsupported platforms
+// are skipped with a warning by the patch, so only a list without any supported platform is
+// rejected.
+func PlatformSelective(p types.PlatformSelectivePatchParams) *Validator {
+	v := patch(p.Image, p.Tag, p.Destination, p.Push, p.DockerHost, p.ExportPath, p.ContainerdNamespace, p.GitOps)
+	if len(p.Platform) == 0 {
+		v.Add("platform", "platform is required")
+	} else if len(copa.FilterSupportedPlatforms(p.Platform)) == 0 {
+		v.Add("platform", "no supported platforms found in: %v", p.Platform)
+		v.hint(fmt.Sprintf("supported platforms: %s", strings.Join(copa.CopaSupportedPlatforms, ", ")))
+	}
+	v.timeout(p.TimeoutSeconds)
+	return v
+}
+
+// ReportBased checks the parameters of 'patch-report-based'
+func ReportBased(p types.ReportBasedPatchParams) *Validator {
+	v := patch(p.Image, p.Tag, p.Destination, p.Push, p.DockerHost, p.ExportPath, p.ContainerdNamespace, p.GitOps)
+	if v.Required("reportPath", p.ReportPath) {
+		if _, err := os.Stat(p.ReportPath); err != nil {
+			v.Add("reportPath", "report path does not exist: %s", p.ReportPath)
+			v.hint("run 'scan-container' first and pass the report directory it returns")
+		}
+	}
+	v.Check("vexFormat", copa.ValidateVexFormat(p.VexFormat))
+	v.Dir("vexOutput", p.VexOutput)
+	v.Exists("vexInput", p.VexInput)
+	if p.MaxRemaining != nil && *p.MaxRemaining < 0 {
+		v.Add("maxRemaining", "maxRemaining must not be negative")
+	}
+	if p.MaxRemainingSeverity != "" {
+		if p.MaxRemaining == nil {
+			v.Add("maxRemainingSeverity", "maxRemainingSeverity requires maxRemaining")
+		}
+		v.severity("maxRemainingSeverity", p.MaxRemainingSeverity)
+	}
+	v.severity("minSeverity", p.MinSeverity)
+	v.severities("severity", p.Severity)
+	v.Exclusive("severity", len(p.Severity) > 0, "minSeverity", p.MinSeverity != "")
+	v.timeout(p.TimeoutSeconds)
+	return v
+}
+
+// timeout checks that the timeoutSeconds of a call is not negative
+func (v *Validator) timeout(seconds int) {
+	if seconds < 0 {
+		v.Add("timeoutSeconds", "timeoutSeconds must not be negative")
+	}
+}
+
+// severity checks that severity, when set, is one copa patches by
+func (v *Validator) severity(field, severity string) {
+	if severity != "" && !slices.Contains(copa.Severities, strings.ToUpper(severity)) {
+		v.Add(field, "unsupported %s: %s", field, severity)
+		v.hint(fmt.Sprintf("use one of %s", strings.Join(copa.Severities, ", ")))
+	}
+}
+
+// severities checks that each of severities is one trivy reports
+func (v *Validator) severities(field string, severities []string) {
+	for _, severity := range severities {
+		if !slices.Contains(trivy.SeverityLevels, strings.ToUpper(severity)) {
+			v.Add(field, "unsupported severity: %s", severity)
+			v.hint(fmt.Sprintf("use one of %s", strings.Join(trivy.SeverityLevels, ", ")))
+		}
+	}
+}
+
+// Scan checks the parameters of 'scan-container'
+func Scan(p trivy.ScanParams) *Validator {
+	v := &Validator{}
+	v.Image("image", p.Image)
+	for _, platform := range p.Platform {
+		if !copa.IsPlatformSupported(platform) {
+			v.Add("platform", "unsupported platform: %s", platform)
+			v.hint(fmt.Sprintf("supported platforms: %s", strings.Join(copa.CopaSupportedPlatforms, ", ")))
+		}
+	}
+	v.severities("severity", p.Severity)
+	v.timeout(p.TimeoutSeconds)
+	if p.ReuseAttachedReport && len(p.Platform) > 0 {
+		v.Add("reuseAttachedReport", "reuseAttachedReport cannot be combined with platform")
+		v.hint("reports are attached to the image as a whole; omit platform to reuse one")
+	}
+	if p.ContainerdNamespace != "" {
+		v.Check("containerdNamespace", containerd.ValidateNamespace(p.ContainerdNamespace))
+		v.Exclusive("containerdNamespace", true, "platform", len(p.Platform) > 0)
+		v.Exclusive("containerdNamespace", true, "reuseAttachedReport", p.ReuseAttachedReport)
+	}
+	if p.GitLabReport != "" {
+		if path, err := workdir.OutputPath(p.GitLabReport); err != nil {
+			v.Check("gitlabReport", err)
+		} else if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+			v.Add("gitlabReport", "gitlab report directory does not exist: %s", filepath.Dir(path))
+		}
+	}
+	v.Check("dbRepository", trivy.ValidateDBRepository(p.DBRepository))
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	return v
+}
+
+// remediationIDRegexp matches the ID of a remediation, the random suffix of its working directory
+var remediationIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// Remediate checks the parameters of 'remediate'. A resumed remediation takes its image and tag
+// from the run being resumed, so they are only checked for a new one.
+func Remediate(p types.RemediateParams) *Validator {
+	v := &Validator{}
+	if p.Resume != "" {
+		if !remediationIDRegexp.MatchString(p.Resume) {
+			v.Add("resume", "invalid remediation ID: %s", p.Resume)
+			v.hint("pass the ID returned by the failed 'remediate' call")
+		}
+		if p.Image != "" {
+			v.Image("image", p.Image)
+		}
+	} else {
+		v.Image("image", p.Image)
+	}
+	v.Check("patchtag", copa.ValidateTag(p.Tag))
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	v.timeout(p.TimeoutSeconds)
+	if p.Sign && !p.Push {
+		v.Add("sign", "sign requires push")
+		v.hint("images are signed by the digest they were pushed with")
+	}
+	return v
+}
+
+// VerifyPatch checks the parameters of 'verify-patch'
+func VerifyPatch(p types.VerifyPatchParams) *Validator {
+	v := &Validator{}
+	v.Image("image", p.Image)
+	if p.PatchedImage != "" {
+		v.Image("patchedImage", p.PatchedImage)
+	}
+	v.Check("patchtag", copa.ValidateTag(p.Tag))
+	v.Exclusive("patchedImage", p.PatchedImage != "", "patchtag", p.Tag != "")
+	if v.Required("reportPath", p.ReportPath) {
+		if info, err := os.Stat(p.ReportPath); err != nil || !info.IsDir() {
+			v.Add("reportPath", "report directory does not exist: %s", p.ReportPath)
+			v.hint("pass the report directory 'scan-container' returned for the original image")
+		}
+	}
+	v.severities("severity", p.Severity)
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	v.timeout(p.TimeoutSeconds)
+	return v
+}
+
+// SummarizeReport checks the parameters of 'summarize-report'
+func SummarizeReport(p types.SummarizeReportParams) *Validator {
+	v := &Validator{}
+	if v.Required("reportPath", p.ReportPath) {
+		if info, err := os.Stat(p.ReportPath); err != nil || !info.IsDir() {
+			v.Add("reportPath", "report directory does not exist: %s", p.ReportPath)
+			v.hint("pass the report directory returned by 'scan-container'")
+		}
+	}
+	if p.TopPackages < 0 {
+		v.Add("topPackages", "topPackages must not be negative")
+	}
+	return v
+}
+
+// PatchHistory checks the parameters of 'patch-history'
+func PatchHistory(p types.PatchHistoryParams) *Validator {
+	v := &Validator{}
+	if p.Since != "" {
+		if d, err := time.ParseDuration(p.Since); err != nil || d <= 0 {
+			v.Add("since", "invalid duration: %s", p.Since)
+			v.hint("durations look like 24h or 168h")
+		}
+	}
+	if p.Limit < 0 {
+		v.Add("limit", "limit must not be negative")
+	}
+	return v
+}
+
+// SBOM checks the parameters of 'generate-sbom'
+func SBOM(p types.SBOMParams) *Validator {
+	v := &Validator{}
+	v.Image("image", p.Image)
+	if p.Format != "" && !slices.Contains(trivy.SBOMFormats, p.Format) {
+		v.Add("format", "unsupported SBOM format: %s", p.Format)
+		v.hint(fmt.Sprintf("use one of %s", strings.Join(trivy.SBOMFormats, ", ")))
+	}
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	v.timeout(p.TimeoutSeconds)
+	return v
+}
+
+// RegistryLogin checks the parameters of 'registry-login'
+func RegistryLogin(p types.RegistryLoginParams) *Validator {
+	v := &Validator{}
+	v.Required("password", p.Password)
+	v.argument("registry", p.Registry)
+	v.argument("username", p.Username)
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	return v
+}
+
+// Doctor checks the parameters of 'doctor'
+func Doctor(p types.DoctorParams) *Validator {
+	v := &Validator{}
+	for _, registry := range p.Registries {
+		if registry == "" || strings.ContainsFunc(registry, unicode.IsSpace) {
+			v.Add("registries", "invalid registry: %q", registry)
+		}
+	}
+	v.Check("dockerHost", docker.ValidateHost(p.DockerHost))
+	return v
+}
+
+// argument checks that value can be passed to a command as an argument rather than as a flag
+func (v *Validator) argument(field, value string) {
+	if strings.HasPrefix(value, "-") || strings.ContainsFunc(value, unicode.IsSpace) {
+		v.Add(field, "invalid %s: %q", field, value)
+	}
+}
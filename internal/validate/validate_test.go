@@ -0,0 +1,214 @@
+package validate
+
+import (
+	"path/filepath"
+	"testing"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+	"github.com/project-copacetic/mcp-server/internal/workdir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fields returns the fields of the problems of err
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err))
+	var fields []string
+	for _, p := range copaerrors.ProblemsOf(err) {
+		fields = append(fields, p.Field)
+	}
+	return fields
+}
+
+func TestImage(t *testing.T) {
+	for _, image := range []string{
+		"alpine",
+		"alpine:3.19",
+		"library/nginx:1.25",
+		"ghcr.io/team/app:v1.0-rc.1",
+		"localhost:5000/app",
+		"registry.example.com:443/a/b/c@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"app:1.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	} {
+		v := &Validator{}
+		v.Image("image", image)
+		assert.NoError(t, v.Err(), image)
+	}
+
+	for _, image := range []string{"", " ", "Alpine:3.19", "alpine:", "alpine:3.19 --privileged", "app@sha256:xyz", "-app"} {
+		v := &Validator{}
+		v.Image("image", image)
+		assert.Error(t, v.Err(), image)
+	}
+}
+
+func TestReportBased_AggregatesProblems(t *testing.T) {
+	maxRemaining := -1
+	err := ReportBased(types.ReportBasedPatchParams{
+		Image:        "alpine:3.19",
+		Tag:          "alpine:patched",
+		Push:         true,
+		ExportPath:   filepath.Join(t.TempDir(), "patched.tar"),
+		VexFormat:    "spdx",
+		MaxRemaining: &maxRemaining,
+		MinSeverity:  "urgent",
+		GitOps:       "spinnaker",
+	}).Err()
+
+	assert.Equal(t, []string{"patchtag", "exportPath", "gitops", "reportPath", "vexFormat", "maxRemaining", "minSeverity"}, fields(t, err))
+	assert.Contains(t, err.Error(), `pass only the tag name, e.g. "patched" instead of "alpine:patched"`)
+}
+
+func TestReportBased_Severity(t *testing.T) {
+	err := ReportBased(types.ReportBasedPatchParams{
+		Image:       "alpine:3.19",
+		ReportPath:  t.TempDir(),
+		Severity:    []string{"critical", "severe"},
+		MinSeverity: "HIGH",
+	}).Err()
+	assert.Equal(t, []string{"severity", "severity"}, fields(t, err))
+
+	err = ReportBased(types.ReportBasedPatchParams{Image: "alpine:3.19", ReportPath: t.TempDir(), Severity: []string{"CRITICAL", "unknown"}}).Err()
+	assert.NoError(t, err)
+}
+
+func TestReportBased_Valid(t *testing.T) {
+	maxRemaining := 0
+	err := ReportBased(types.ReportBasedPatchParams{
+		Image:                "alpine:3.19",
+		Tag:                  "3.19-patched",
+		ReportPath:           t.TempDir(),
+		MaxRemaining:         &maxRemaining,
+		MaxRemainingSeverity: "high",
+	}).Err()
+	assert.NoError(t, err)
+}
+
+func TestComprehensive_ContainerdNamespace(t *testing.T) {
+	err := Comprehensive(types.ComprehensivePatchParams{Image: "nginx:1.25", ContainerdNamespace: "k8s.io"}).Err()
+	assert.NoError(t, err)
+
+	err = Comprehensive(types.ComprehensivePatchParams{Image: "nginx:1.25", Push: true, ContainerdNamespace: "k8s io"}).Err()
+	assert.Equal(t, []string{"containerdNamespace", "containerdNamespace"}, fields(t, err))
+}
+
+func TestComprehensive_Destination(t *testing.T) {
+	err := Comprehensive(types.ComprehensivePatchParams{Image: "nginx:1.25", Push: true, Destination: "golden.example.com/team/nginx"}).Err()
+	assert.NoError(t, err)
+
+	err = Comprehensive(types.ComprehensivePatchParams{Image: "nginx:1.25", Destination: "golden.example.com/team/nginx:patched"}).Err()
+	assert.Equal(t, []string{"destination"}, fields(t, err))
+
+	err = Comprehensive(types.ComprehensivePatchParams{Image: "nginx:1.25", Destination: "Golden.example.com/Team/nginx"}).Err()
+	assert.Equal(t, []string{"destination"}, fields(t, err))
+}
+
+func TestPlatformSelective(t *testing.T) {
+	err := PlatformSelective(types.PlatformSelectivePatchParams{Image: "nginx:1.25", Platform: []string{"windows/amd64"}}).Err()
+	assert.Equal(t, []string{"platform"}, fields(t, err))
+
+	// Unsupported platforms next to supported ones are skipped by the patch
+	err = PlatformSelective(types.PlatformSelectivePatchParams{Image: "nginx:1.25", Platform: []string{"linux/amd64", "windows/amd64"}}).Err()
+	assert.NoError(t, err)
+}
+
+func TestScan(t *testing.T) {
+	err := Scan(trivy.ScanParams{
+		Platform:            []string{"linux/amd64", "darwin/arm64"},
+		ReuseAttachedReport: true,
+		DockerHost:          "ftp://host",
+		GitLabReport:        filepath.Join(t.TempDir(), "missing", "report.json"),
+	}).Err()
+
+	assert.Equal(t, []string{"image", "platform", "reuseAttachedReport", "gitlabReport", "dockerHost"}, fields(t, err))
+
+	err = Scan(trivy.ScanParams{Image: "nginx:1.25", Platform: []string{"linux/amd64"}, ContainerdNamespace: "k8s.io"}).Err()
+	assert.Equal(t, []string{"containerdNamespace"}, fields(t, err))
+
+	err = Scan(trivy.ScanParams{Image: "nginx:1.25", Severity: []string{"HIGH", "important"}}).Err()
+	assert.Equal(t, []string{"severity"}, fields(t, err))
+
+	err = Scan(trivy.ScanParams{Image: "nginx:1.25", TimeoutSeconds: -1}).Err()
+	assert.Equal(t, []string{"timeoutSeconds"}, fields(t, err))
+
+	err = Scan(trivy.ScanParams{Image: "nginx:1.25", DBRepository: "--insecure"}).Err()
+	assert.Equal(t, []string{"dbRepository"}, fields(t, err))
+}
+
+func TestScan_GitLabReport(t *testing.T) {
+	root := t.TempDir()
+	workdir.Configure(root, 0)
+	t.Cleanup(func() { workdir.Configure("", 0) })
+
+	// The report is written in the working directory, or relative to it
+	assert.NoError(t, Scan(trivy.ScanParams{Image: "nginx:1.25", GitLabReport: "gl-container-scanning-report.json"}).Err())
+	assert.NoError(t, Scan(trivy.ScanParams{Image: "nginx:1.25", GitLabReport: filepath.Join(root, "gl-container-scanning-report.json")}).Err())
+
+	for _, path := range []string{"../gl-container-scanning-report.json", filepath.Join(t.TempDir(), "gl-container-scanning-report.json")} {
+		err := Scan(trivy.ScanParams{Image: "nginx:1.25", GitLabReport: path}).Err()
+		assert.Equal(t, []string{"gitlabReport"}, fields(t, err), path)
+	}
+}
+
+func TestRemediate(t *testing.T) {
+	err := Remediate(types.RemediateParams{Tag: "nginx:patched", Sign: true}).Err()
+	assert.Equal(t, []string{"image", "patchtag", "sign"}, fields(t, err))
+
+	// A resumed remediation takes its image from the saved run
+	assert.NoError(t, Remediate(types.RemediateParams{Resume: "123456"}).Err())
+	err = Remediate(types.RemediateParams{Resume: "../123"}).Err()
+	assert.Equal(t, []string{"resume"}, fields(t, err))
+
+	assert.NoError(t, Remediate(types.RemediateParams{Image: "nginx:1.25", Push: true, Sign: true}).Err())
+	err = Remediate(types.RemediateParams{Image: "nginx:1.25", TimeoutSeconds: -1}).Err()
+	assert.Equal(t, []string{"timeoutSeconds"}, fields(t, err))
+}
+
+func TestVerifyPatch(t *testing.T) {
+	err := VerifyPatch(types.VerifyPatchParams{Image: "nginx:1.25", Tag: "1.25-patched", ReportPath: t.TempDir()}).Err()
+	assert.NoError(t, err)
+
+	err = VerifyPatch(types.VerifyPatchParams{Image: "nginx:1.25", PatchedImage: "nginx:1.25-p", Tag: "p", Severity: []string{"SEVERE"}}).Err()
+	assert.Equal(t, []string{"patchedImage", "reportPath", "severity"}, fields(t, err))
+}
+
+func TestSummarizeReport(t *testing.T) {
+	assert.NoError(t, SummarizeReport(types.SummarizeReportParams{ReportPath: t.TempDir(), TopPackages: 5}).Err())
+
+	err := SummarizeReport(types.SummarizeReportParams{ReportPath: "/nonexistent/reports", TopPackages: -1}).Err()
+	assert.Equal(t, []string{"reportPath", "topPackages"}, fields(t, err))
+}
+
+func TestPatchHistory(t *testing.T) {
+	assert.NoError(t, PatchHistory(types.PatchHistoryParams{Image: "nginx", Since: "168h", Limit: 5}).Err())
+
+	err := PatchHistory(types.PatchHistoryParams{Since: "7d", Limit: -1}).Err()
+	assert.Equal(t, []string{"since", "limit"}, fields(t, err))
+}
+
+func TestSBOM(t *testing.T) {
+	err := SBOM(types.SBOMParams{Image: "nginx:1.25", Format: "spdx"}).Err()
+	assert.NoError(t, err)
+
+	err = SBOM(types.SBOMParams{Image: "nginx:1.25", Format: "swid", DockerHost: "ftp://host", TimeoutSeconds: -1}).Err()
+	assert.Equal(t, []string{"format", "dockerHost", "timeoutSeconds"}, fields(t, err))
+}
+
+func TestRegistryLogin(t *testing.T) {
+	err := RegistryLogin(types.RegistryLoginParams{Registry: "ghcr.io", Password: "s3cret"}).Err()
+	assert.NoError(t, err)
+
+	err = RegistryLogin(types.RegistryLoginParams{Registry: "-p", Username: "ci bot"}).Err()
+	assert.Equal(t, []string{"password", "registry", "username"}, fields(t, err))
+}
+
+func TestDoctor(t *testing.T) {
+	assert.NoError(t, Doctor(types.DoctorParams{Registries: []string{"ghcr.io", "localhost:5000"}}).Err())
+
+	err := Doctor(types.DoctorParams{Registries: []string{""}, DockerHost: "ftp://host"}).Err()
+	assert.Equal(t, []string{"registries", "dockerHost"}, fields(t, err))
+}
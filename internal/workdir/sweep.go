@@ -0,0 +1,131 @@
+package workdir
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is an entry of the root, or of the system temporary directory, left behind by an earlier run
+type Entry struct {
+	Path    string
+	Size    int64 // Size in bytes of its files
+	ModTime time.Time
+	Removed bool // Set when it was older than the maximum age and removed
+}
+
+// ownedPattern matches an entry earlier versions created directly in the system temporary
+// directory, before the root existed. files, when set, is the only pattern the files of a
+// directory may match, so that a directory of another program with a similar name is left alone.
+type ownedPattern struct {
+	glob  string
+	dir   bool
+	files string
+}
+
+var ownedPatterns = []ownedPattern{
+	{glob: "reports-*", dir: true, files: "*.json"},
+	{glob: "report-filtered-*", dir: true, files: "*.json"},
+	{glob: "vex-*", dir: true, files: "*.json"},
+	{glob: "copa-mcp-pr-*", dir: true},
+	{glob: "copa-mcp-summary-*.md"},
+}
+
+// Sweep lists the entries of the root, and the reports, VEX documents and working directories
+// earlier versions left in the system temporary directory, removing those last modified more than
+// maxAge ago. A maxAge of 0 only lists them. Entries of other servers sharing the root are only
+// removed once they are as old. When the root is the system temporary directory itself, only the
+// entries the server creates are considered.
+func Sweep(maxAge time.Duration) ([]Entry, error) {
+	var cutoff time.Time
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+
+	root, tmp := Root(), os.TempDir()
+	var found []Entry
+	var errs []error
+	if !samePath(root, tmp) {
+		entries, err := sweepDir(root, cutoff, func(fs.DirEntry) bool { return true })
+		found = append(found, entries...)
+		errs = append(errs, err)
+	}
+	entries, err := sweepDir(tmp, cutoff, func(entry fs.DirEntry) bool { return owned(tmp, entry) })
+	found = append(found, entries...)
+	errs = append(errs, err)
+	return found, errors.Join(errs...)
+}
+
+// sweepDir lists the entries of dir matching match, removing those modified before cutoff unless
+// it is zero
+func sweepDir(dir string, cutoff time.Time, match func(fs.DirEntry) bool) ([]Entry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var found []Entry
+	var errs []error
+	for _, entry := range entries {
+		if !match(entry) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		e := Entry{Path: path, Size: size(path), ModTime: info.ModTime()}
+		if !cutoff.IsZero() && e.ModTime.Before(cutoff) {
+			if err := os.RemoveAll(path); err != nil {
+				errs = append(errs, err)
+			} else {
+				e.Removed = true
+			}
+		}
+		found = append(found, e)
+	}
+	return found, errors.Join(errs...)
+}
+
+// owned reports whether entry of the system temporary directory tmp was created by the server
+func owned(tmp string, entry fs.DirEntry) bool {
+	for _, p := range ownedPatterns {
+		if ok, _ := filepath.Match(p.glob, entry.Name()); !ok || entry.IsDir() != p.dir {
+			continue
+		}
+		if p.files == "" {
+			return true
+		}
+		files, err := os.ReadDir(filepath.Join(tmp, entry.Name()))
+		if err != nil {
+			return false
+		}
+		for _, f := range files {
+			if ok, _ := filepath.Match(p.files, f.Name()); !ok || !f.Type().IsRegular() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// size returns the size in bytes of the files under path
+func size(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,183 @@
+// Package workdir holds the scan reports, VEX documents and working directories the server creates,
+// under one root directory, so that their disk usage can be bounded by a quota and the ones left
+// behind by crashed runs can be removed when the server starts.
+package workdir
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// defaultName is the directory under the system temporary directory used as root when none is configured
+const defaultName = "copa-mcp"
+
+var (
+	mu    sync.Mutex
+	root  string // Empty for the default root
+	quota int64  // 0 when unlimited
+)
+
+// Configure sets the root directory and its quota in bytes, process-wide. An empty dir uses a
+// copa-mcp directory in the system temporary directory, and a quota of 0 removes it.
+func Configure(dir string, quotaBytes int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	root = dir
+	if root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+	}
+	quota = max(quotaBytes, 0)
+}
+
+// Root returns the root directory, which may not exist yet
+func Root() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if root == "" {
+		return filepath.Join(os.TempDir(), defaultName)
+	}
+	return root
+}
+
+// Dir returns the root directory, creating it if needed
+func Dir() (string, error) {
+	dir := Root()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", copaerrors.NewSystemError(fmt.Sprintf("failed to create the working directory %s", dir), err,
+			"set COPA_MCP_TEMP_DIR to a writable directory")
+	}
+	return dir, nil
+}
+
+// MkdirTemp creates a new directory in the root, as os.MkdirTemp does, unless the root exceeds its quota
+func MkdirTemp(pattern string) (string, error) {
+	dir, err := reserve()
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(dir, pattern)
+}
+
+// CreateTemp creates a new file in the root, as os.CreateTemp does, unless the root exceeds its quota
+func CreateTemp(pattern string) (*os.File, error) {
+	dir, err := reserve()
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, pattern)
+}
+
+// Contains reports whether path is an entry created directly in the root
+func Contains(path string) bool {
+	return path != "" && samePath(filepath.Dir(filepath.Clean(path)), Root())
+}
+
+// OutputPath resolves path, a file a tool call asked the server to write, relative to the root
+// when it is relative, and checks that it stays in the root, even through symbolic links, so that
+// a client cannot overwrite files elsewhere on the server. An existing file is only overwritten
+// when it is a regular one.
+func OutputPath(path string) (string, error) {
+	root := Root()
+	if !filepath.IsAbs(path) {
+		var err error
+		if root, err = Dir(); err != nil {
+			return "", err
+		}
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	outside := copaerrors.NewValidationError(fmt.Sprintf("%s is outside the working directory %s", path, root), nil,
+		"write it under the working directory, or give a path relative to it; the working directory is set by COPA_MCP_TEMP_DIR")
+	if !within(root, path) {
+		return "", outside
+	}
+	// The directory may lead out of the root through a symbolic link
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			realRoot = root
+		}
+		if !within(realRoot, filepath.Join(dir, filepath.Base(path))) {
+			return "", outside
+		}
+	}
+	if info, err := os.Lstat(path); err == nil && !info.Mode().IsRegular() {
+		return "", copaerrors.NewValidationError(fmt.Sprintf("%s exists and is not a regular file", path), nil,
+			"choose a path that does not exist yet or is a regular file")
+	}
+	return path, nil
+}
+
+// within reports whether the clean path is below dir
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// samePath compares two clean paths, ignoring case on Windows, whose file systems do
+func samePath(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// reserve returns the root, once it is known to be below its quota
+func reserve() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	mu.Lock()
+	limit := quota
+	mu.Unlock()
+	if limit == 0 {
+		return dir, nil
+	}
+
+	used, err := Usage()
+	if err != nil {
+		return "", copaerrors.NewSystemError("failed to measure the working directory", err)
+	}
+	if used >= limit {
+		return "", copaerrors.NewSystemError(fmt.Sprintf("working directory %s uses %d MB, reaching its quota of %d MB", dir, used>>20, limit>>20), nil,
+			"remove the scan reports and VEX documents no longer needed, or set keepReport and keepVex to false, or raise COPA_MCP_TEMP_QUOTA_MB")
+	}
+	return dir, nil
+}
+
+// Usage returns the size in bytes of the files under the root
+func Usage() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(Root(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries removed during the walk, or a root not created yet, use no space
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
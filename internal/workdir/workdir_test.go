@@ -0,0 +1,227 @@
+package workdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configure sets the root and quota for the test, restoring the defaults after it
+func configure(t *testing.T, quotaBytes int64) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "work")
+	Configure(dir, quotaBytes)
+	t.Cleanup(func() { Configure("", 0) })
+	return dir
+}
+
+func TestRoot_Default(t *testing.T) {
+	Configure("", 0)
+	assert.Equal(t, filepath.Join(os.TempDir(), defaultName), Root())
+}
+
+func TestMkdirTemp_CreatesRoot(t *testing.T) {
+	root := configure(t, 0)
+
+	dir, err := MkdirTemp("reports-*")
+	require.NoError(t, err)
+	assert.Equal(t, root, filepath.Dir(dir))
+	assert.DirExists(t, dir)
+	assert.True(t, Contains(dir))
+	assert.False(t, Contains(filepath.Join(dir, "report.json")))
+	assert.False(t, Contains(""))
+
+	f, err := CreateTemp("summary-*.md")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, root, filepath.Dir(f.Name()))
+}
+
+func TestMkdirTemp_Quota(t *testing.T) {
+	configure(t, 1024)
+
+	dir, err := MkdirTemp("reports-*")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), make([]byte, 1024), 0o600))
+
+	used, err := Usage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), used)
+
+	_, err = MkdirTemp("vex-*")
+	require.Error(t, err)
+	assert.Equal(t, copaerrors.CategorySystem, copaerrors.CategoryOf(err))
+
+	require.NoError(t, os.RemoveAll(dir))
+	_, err = MkdirTemp("vex-*")
+	assert.NoError(t, err)
+}
+
+func TestUsage_MissingRoot(t *testing.T) {
+	configure(t, 0)
+
+	used, err := Usage()
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
+
+// useTempDir points the system temporary directory at a new directory for the test
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	tmp := t.TempDir()
+	for _, env := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(env, tmp)
+	}
+	return tmp
+}
+
+func TestSweep(t *testing.T) {
+	useTempDir(t)
+	configure(t, 0)
+
+	old, err := MkdirTemp("reports-*")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(old, "report.json"), []byte("{}"), 0o600))
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(old, past, past))
+	recent, err := MkdirTemp("vex-*")
+	require.NoError(t, err)
+
+	entries, err := Sweep(0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.False(t, e.Removed)
+	}
+	assert.DirExists(t, old)
+
+	entries, err = Sweep(24 * time.Hour)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, old, entries[0].Path)
+	assert.True(t, entries[0].Removed)
+	assert.Equal(t, int64(2), entries[0].Size)
+	assert.False(t, entries[1].Removed)
+	assert.NoDirExists(t, old)
+	assert.DirExists(t, recent)
+}
+
+func TestSweep_LegacyTempDir(t *testing.T) {
+	tmp := useTempDir(t)
+	configure(t, 0)
+
+	mkdir := func(name string, files ...string) string {
+		dir := filepath.Join(tmp, name)
+		require.NoError(t, os.Mkdir(dir, 0o700))
+		for _, f := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("{}"), 0o600))
+		}
+		return dir
+	}
+	reports := mkdir("reports-123", "linux-amd64.json")
+	vex := mkdir("vex-456", "vex.json")
+	pr := mkdir("copa-mcp-pr-789", "Dockerfile")
+	summary := filepath.Join(tmp, "copa-mcp-summary-1.md")
+	require.NoError(t, os.WriteFile(summary, []byte("# summary"), 0o600))
+	// Entries of other programs are left alone, even when their names match
+	foreign := mkdir("reports-999", "report.json", "notes.txt")
+	other := mkdir("other-1")
+	past := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{reports, vex, pr, summary, foreign, other} {
+		require.NoError(t, os.Chtimes(path, past, past))
+	}
+
+	entries, err := Sweep(24 * time.Hour)
+	require.NoError(t, err)
+	var removed []string
+	for _, e := range entries {
+		if e.Removed {
+			removed = append(removed, e.Path)
+		}
+	}
+	assert.ElementsMatch(t, []string{reports, vex, pr, summary}, removed)
+	assert.DirExists(t, foreign)
+	assert.DirExists(t, other)
+}
+
+func TestSweep_RootIsTempDir(t *testing.T) {
+	tmp := useTempDir(t)
+	Configure(tmp, 0)
+	t.Cleanup(func() { Configure("", 0) })
+
+	reports := filepath.Join(tmp, "reports-1")
+	require.NoError(t, os.Mkdir(reports, 0o700))
+	other := filepath.Join(tmp, "other-1")
+	require.NoError(t, os.Mkdir(other, 0o700))
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(reports, past, past))
+	require.NoError(t, os.Chtimes(other, past, past))
+
+	entries, err := Sweep(time.Hour)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, reports, entries[0].Path)
+	assert.NoDirExists(t, reports)
+	assert.DirExists(t, other)
+}
+
+func TestSweep_MissingRoot(t *testing.T) {
+	useTempDir(t)
+	configure(t, 0)
+
+	entries, err := Sweep(time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutputPath(t *testing.T) {
+	root := configure(t, 0)
+
+	path, err := OutputPath("result.json")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "result.json"), path)
+	assert.DirExists(t, root, "the root is created for relative paths")
+
+	path, err = OutputPath(filepath.Join(root, "ci", "result.json"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "ci", "result.json"), path)
+
+	home, _ := os.UserHomeDir()
+	for _, outside := range []string{
+		"../result.json",
+		"ci/../../result.json",
+		filepath.Join(filepath.Dir(root), "result.json"),
+		filepath.Join(home, ".docker", "config.json"),
+		root,
+	} {
+		_, err := OutputPath(outside)
+		require.Error(t, err, outside)
+		assert.Equal(t, copaerrors.CategoryValidation, copaerrors.CategoryOf(err), outside)
+	}
+
+	// Only regular files are overwritten
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0o700))
+	_, err = OutputPath("dir")
+	assert.Error(t, err)
+}
+
+func TestOutputPath_Symlink(t *testing.T) {
+	root := configure(t, 0)
+	require.NoError(t, os.MkdirAll(root, 0o700))
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("creating a symbolic link: %v", err)
+	}
+	_, err := OutputPath(filepath.Join("link", "result.json"))
+	assert.Error(t, err, "a directory linking out of the root is rejected")
+
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "target.json"), nil, 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "target.json"), filepath.Join(root, "result.json")))
+	_, err = OutputPath("result.json")
+	assert.Error(t, err, "a link to a file outside the root is not overwritten")
+}
@@ -0,0 +1,179 @@
+// Package copamcp embeds the Copacetic MCP server in other Go programs, as an alternative
+// to running the copa-mcp-server binary. The server still drives the copa, trivy and docker
+// CLIs, which must be installed on the host.
+package copamcp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/config"
+	"github.com/project-copacetic/mcp-server/internal/copamcp"
+	"github.com/project-copacetic/mcp-server/internal/docker"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+	"github.com/project-copacetic/mcp-server/internal/trivy"
+	"github.com/project-copacetic/mcp-server/internal/types"
+)
+
+// Names of the tools registered by NewServer
+const (
+	ToolVersion                  = copamcp.ToolVersion
+	ToolWorkflowGuide            = copamcp.ToolWorkflowGuide
+	ToolScanContainer            = copamcp.ToolScanContainer
+	ToolPullImage                = copamcp.ToolPullImage
+	ToolRemoveImage              = copamcp.ToolRemoveImage
+	ToolRegistryLogin            = copamcp.ToolRegistryLogin
+	ToolPatchComprehensive       = copamcp.ToolPatchComprehensive
+	ToolPatchPlatformSelective   = copamcp.ToolPatchPlatformSelective
+	ToolPatchReportBased         = copamcp.ToolPatchReportBased
+	ToolListFixedVulnerabilities = copamcp.ToolListFixedVulnerabilities
+	ToolListVulnerabilities      = copamcp.ToolListVulnerabilities
+	ToolSummarizeReport          = copamcp.ToolSummarizeReport
+	ToolListClusterImages        = copamcp.ToolListClusterImages
+	ToolScanRegistry             = copamcp.ToolScanRegistry
+	ToolFetchHarborReport        = copamcp.ToolFetchHarborReport
+	ToolSuggestBaseUpgrade       = copamcp.ToolSuggestBaseUpgrade
+	ToolEvaluateImage            = copamcp.ToolEvaluateImage
+	ToolDiffSBOM                 = copamcp.ToolDiffSBOM
+	ToolGenerateSBOM             = copamcp.ToolGenerateSBOM
+	ToolVerifyPatch              = copamcp.ToolVerifyPatch
+	ToolPatchHistory             = copamcp.ToolPatchHistory
+	ToolDoctor                   = copamcp.ToolDoctor
+	ToolOpenImagePR              = copamcp.ToolOpenImagePR
+	ToolInstallDependencies      = copamcp.ToolInstallDependencies
+	ToolRemediate                = copamcp.ToolRemediate
+)
+
+// Server settings
+type (
+	// Config holds server-wide settings, see DefaultConfig and LoadConfig
+	Config = config.Config
+	// RetryPolicy controls how failed scans and patches are retried
+	RetryPolicy = copaerrors.RetryPolicy
+	// ErrorCategory classifies the cause of a failed tool call, see ToolError
+	ErrorCategory = copaerrors.Category
+)
+
+// Tool parameters
+type (
+	ScanParams                     = trivy.ScanParams
+	ComprehensivePatchParams       = types.ComprehensivePatchParams
+	PlatformSelectivePatchParams   = types.PlatformSelectivePatchParams
+	ReportBasedPatchParams         = types.ReportBasedPatchParams
+	RetryParams                    = types.RetryParams
+	PullImageParams                = types.PullImageParams
+	RemoveImageParams              = types.RemoveImageParams
+	RegistryLoginParams            = types.RegistryLoginParams
+	ListFixedVulnerabilitiesParams = types.ListFixedVulnerabilitiesParams
+	ListVulnerabilitiesParams      = types.ListVulnerabilitiesParams
+	SummarizeReportParams          = types.SummarizeReportParams
+	ListClusterImagesParams        = types.ListClusterImagesParams
+	ScanRegistryParams             = types.ScanRegistryParams
+	HarborReportParams             = types.HarborReportParams
+	BaseUpgradeParams              = types.BaseUpgradeParams
+	EvaluateImageParams            = types.EvaluateImageParams
+	SBOMDiffParams                 = types.SBOMDiffParams
+	SBOMParams                     = types.SBOMParams
+	VerifyPatchParams              = types.VerifyPatchParams
+	PatchHistoryParams             = types.PatchHistoryParams
+	DoctorParams                   = types.DoctorParams
+	ImagePRParams                  = types.ImagePRParams
+	InstallDependenciesParams      = types.InstallDependenciesParams
+	RemediateParams                = types.RemediateParams
+)
+
+// Structured tool results, returned as the StructuredContent of a call
+type (
+	VersionResult          = types.Ver
+	ScanResult             = trivy.ScanResult
+	PatchResult            = types.PatchResult
+	PatchMetrics           = types.PatchMetrics
+	PinnedReference        = types.PinnedReference
+	PlatformResult         = types.PlatformResult
+	SeveritySummary        = types.SeveritySummary
+	SeverityCounts         = types.SeverityCounts
+	FixedVulnerability     = types.FixedVulnerability
+	FixedVulnerabilityPage = types.FixedVulnerabilityPage
+	Vulnerability          = types.Vulnerability
+	VulnerabilityPage      = types.VulnerabilityPage
+	ReportSummary          = types.ReportSummary
+	PackageSummary         = types.PackageSummary
+	PlatformSummary        = types.PlatformSummary
+	ClusterImageList       = types.ClusterImageList
+	ClusterImage           = types.ClusterImage
+	FleetReport            = types.FleetReport
+	FleetImage             = types.FleetImage
+	BaseUpgradeResult      = types.BaseUpgradeResult
+	BaseUpgrade            = types.BaseUpgrade
+	ImageVerdict           = types.ImageVerdict
+	PolicyCheck            = types.PolicyCheck
+	SBOM                   = types.SBOM
+	SBOMDiff               = types.SBOMDiff
+	PackageChange          = types.PackageChange
+	PackageVersion         = types.PackageVersion
+	PatchVerification      = types.PatchVerification
+	PatchHistory           = types.PatchHistory
+	PatchRecord            = types.PatchRecord
+	DoctorReport           = types.DoctorReport
+	DoctorCheck            = types.DoctorCheck
+	ImagePR                = types.ImagePR
+	ImageReferenceFile     = types.ImageReferenceFile
+	DependencyInstall      = types.DependencyInstall
+	InstalledTool          = types.InstalledTool
+	Remediation            = types.Remediation
+	RemediationStage       = types.RemediationStage
+	// ToolError is the structured content of a call that failed with IsError set
+	ToolError = types.ToolError
+	// CommandFailure describes the failed copa or trivy command behind a ToolError
+	CommandFailure = types.CommandFailure
+	// ParamProblem is an invalid parameter listed by a validation ToolError
+	ParamProblem = types.ParamProblem
+)
+
+// Options configures NewServer
+type Options struct {
+	// Version is reported to clients in the server implementation info. Defaults to "dev".
+	Version string
+
+	// Config holds the server settings. Nil uses DefaultConfig.
+	Config *Config
+}
+
+// DefaultConfig returns the settings used by the binary when no environment variables or flags are set
+func DefaultConfig() *Config {
+	return config.Default()
+}
+
+// LoadConfig reads the settings from the COPA_MCP_* environment variables, as the binary does
+func LoadConfig() (*Config, error) {
+	return config.Load()
+}
+
+// NewServer creates the Copacetic MCP server with all of its tools registered.
+// Like the binary, it sets DOCKER_HOST for the process when the default Docker socket
+// is absent and one of the configured or well-known sockets exists.
+func NewServer(opts Options) *mcp.Server {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	docker.ConfigureHost(cfg.DockerSockets)
+	return copamcp.NewServer(opts.Version, cfg)
+}
+
+// ServeStdio runs server over standard input and output until ctx is done or the client disconnects
+func ServeStdio(ctx context.Context, server *mcp.Server) error {
+	return server.Run(ctx, &mcp.StdioTransport{})
+}
+
+// NewHTTPHandler serves server over the MCP streamable HTTP transport, for mounting on an existing mux
+func NewHTTPHandler(server *mcp.Server) http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+}
+
+// Connect connects an in-process client to server, so that a program can call the tools
+// directly without a transport. Close the returned session when done.
+func Connect(ctx context.Context, server *mcp.Server) (*mcp.ClientSession, error) {
+	return copamcp.Connect(ctx, server)
+}
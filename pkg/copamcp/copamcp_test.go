@@ -0,0 +1,50 @@
+package copamcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer_RegistersTools(t *testing.T) {
+	ctx := context.Background()
+	session, err := Connect(ctx, NewServer(Options{Version: "test", Config: DefaultConfig()}))
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.ListTools(ctx, nil)
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+	}
+	for _, name := range []string{
+		ToolVersion, ToolWorkflowGuide, ToolScanContainer, ToolPullImage, ToolRemoveImage, ToolRegistryLogin,
+		ToolPatchComprehensive, ToolPatchPlatformSelective, ToolPatchReportBased, ToolListFixedVulnerabilities, ToolListVulnerabilities, ToolListClusterImages,
+		ToolInstallDependencies, ToolRemediate,
+	} {
+		assert.Contains(t, names, name)
+	}
+}
+
+func TestConnect_CallTool(t *testing.T) {
+	ctx := context.Background()
+	session, err := Connect(ctx, NewServer(Options{}))
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: ToolWorkflowGuide, Arguments: map[string]any{}})
+	require.NoError(t, err)
+
+	require.False(t, res.IsError)
+	require.NotEmpty(t, res.Content)
+	assert.Contains(t, res.Content[0].(*mcp.TextContent).Text, ToolPatchReportBased)
+}
+
+func TestNewHTTPHandler(t *testing.T) {
+	assert.NotNil(t, NewHTTPHandler(NewServer(Options{})))
+}
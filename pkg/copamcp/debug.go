@@ -0,0 +1,18 @@
+package copamcp
+
+import (
+	"net/http"
+
+	"github.com/project-copacetic/mcp-server/internal/copamcp"
+)
+
+// DebugPath is the path NewDebugHandler must be mounted at
+const DebugPath = copamcp.DebugPath
+
+// NewDebugHandler serves the net/http/pprof profiles of the process, so that operators can profile
+// the CPU and memory of a server handling heavy batch patching, when mounted at DebugPath next to
+// NewHTTPHandler. Profiles expose the process internals, so every request must authenticate with
+// "Authorization: Bearer <token>"; an empty token is rejected.
+func NewDebugHandler(token string) (http.Handler, error) {
+	return copamcp.NewDebugHandler(token)
+}
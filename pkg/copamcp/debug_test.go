@@ -0,0 +1,37 @@
+package copamcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDebugHandler_RequiresToken(t *testing.T) {
+	_, err := NewDebugHandler("")
+	assert.Error(t, err)
+}
+
+func TestNewDebugHandler_Auth(t *testing.T) {
+	handler, err := NewDebugHandler("secret")
+	require.NoError(t, err)
+
+	for name, auth := range map[string]string{"none": "", "wrong": "Bearer other", "basic": "Basic secret"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, DebugPath, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, DebugPath+"heap?debug=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "heap profile")
+}
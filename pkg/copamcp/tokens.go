@@ -0,0 +1,58 @@
+package copamcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/project-copacetic/mcp-server/internal/copamcp"
+	copaerrors "github.com/project-copacetic/mcp-server/internal/errors"
+)
+
+// Scope limits the tools a client of NewScopedHTTPHandler may call
+type Scope = copamcp.Scope
+
+// Scopes of API tokens, from the most to the least restricted
+const (
+	// ScopeScan allows the read-only tools: scans, vulnerability listings and suggestions
+	ScopeScan = copamcp.ScopeScan
+	// ScopePatchNoPush also allows patching, pulling and removing images, without pushing them
+	ScopePatchNoPush = copamcp.ScopePatchNoPush
+	// ScopeFull allows every tool, including pushes, pull requests and installs
+	ScopeFull = copamcp.ScopeFull
+)
+
+// ParseScope returns the scope named s, e.g. "patch-no-push"
+func ParseScope(s string) (Scope, error) {
+	return copamcp.ParseScope(s)
+}
+
+// APIToken is a bearer token accepted by NewScopedHTTPHandler
+type APIToken = copamcp.APIToken
+
+// NewAPIToken issues a random token of scope, valid for ttl, or without expiry when ttl is 0
+func NewAPIToken(scope Scope, ttl time.Duration) (APIToken, error) {
+	scope, err := ParseScope(string(scope))
+	if err != nil {
+		return APIToken{}, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return APIToken{}, copaerrors.NewSystemError("failed to generate an API token", err)
+	}
+	token := APIToken{Token: hex.EncodeToString(b), Scope: scope}
+	if ttl > 0 {
+		token.Expires = time.Now().Add(ttl)
+	}
+	return token, nil
+}
+
+// NewScopedHTTPHandler serves server over the MCP streamable HTTP transport like NewHTTPHandler,
+// requiring "Authorization: Bearer <token>" with one of tokens. Each tool call is checked against
+// the token's scope, so that e.g. a dashboard holding a ScopeScan token cannot patch or push
+// images even if the token leaks.
+func NewScopedHTTPHandler(server *mcp.Server, tokens []APIToken) (http.Handler, error) {
+	return copamcp.RequireTokens(NewHTTPHandler(server), tokens)
+}
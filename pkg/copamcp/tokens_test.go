@@ -0,0 +1,73 @@
+package copamcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bearer adds an Authorization header to every request
+type bearer string
+
+func (b bearer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+string(b))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewScopedHTTPHandler_Validation(t *testing.T) {
+	server := NewServer(Options{})
+	_, err := NewScopedHTTPHandler(server, nil)
+	assert.Error(t, err)
+	_, err = NewScopedHTTPHandler(server, []APIToken{{Token: "secret", Scope: "admin"}})
+	assert.Error(t, err)
+	_, err = NewAPIToken("admin", 0)
+	assert.Error(t, err)
+}
+
+func TestNewScopedHTTPHandler_EnforcesScopes(t *testing.T) {
+	scan, err := NewAPIToken(ScopeScan, time.Hour)
+	require.NoError(t, err)
+	expired := APIToken{Token: "expired", Scope: ScopeFull, Expires: time.Now().Add(-time.Minute)}
+
+	handler, err := NewScopedHTTPHandler(NewServer(Options{}), []APIToken{scan, expired})
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	for name, token := range map[string]string{"none": "", "unknown": "other", "expired": expired.Token} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+	}
+
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "dashboard", Version: "test"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   srv.URL,
+		HTTPClient: &http.Client{Transport: bearer(scan.Token)},
+	}, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: ToolWorkflowGuide, Arguments: map[string]any{}})
+	require.NoError(t, err)
+	assert.False(t, res.IsError)
+
+	res, err = session.CallTool(ctx, &mcp.CallToolParams{Name: ToolPatchComprehensive, Arguments: map[string]any{"image": "alpine:3.19", "patchtag": "patched"}})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(*mcp.TextContent).Text, `"scan" scope`)
+}